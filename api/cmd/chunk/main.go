@@ -3,12 +3,15 @@ package main
 import (
 	"context"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"strings"
 
 	"github.com/einarsundgren/sikta/internal/config"
 	"github.com/einarsundgren/sikta/internal/database"
+	"github.com/einarsundgren/sikta/internal/encryption"
+	"github.com/einarsundgren/sikta/internal/storage"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -38,7 +41,15 @@ func main() {
 	}
 	defer pool.Close()
 
+	envelope, err := encryption.Load(cfg.EncryptionKeyFile)
+	if err != nil {
+		logger.Error("failed to load encryption keys", "error", err)
+		os.Exit(1)
+	}
 	queries := database.New(pool)
+	if cfg.EncryptText {
+		queries = database.NewEncrypted(pool, envelope)
+	}
 
 	parsedUUID, err := uuid.Parse(docID)
 	if err != nil {
@@ -52,7 +63,22 @@ func main() {
 		os.Exit(1)
 	}
 
-	content, err := os.ReadFile(src.FilePath)
+	store, err := storage.New(cfg.Storage())
+	if err != nil {
+		logger.Error("failed to configure storage", "error", err)
+		os.Exit(1)
+	}
+	if envelope != nil {
+		store = storage.NewEncrypted(store, envelope)
+	}
+
+	file, err := store.Open(ctx, src.FilePath)
+	if err != nil {
+		logger.Error("failed to read file", "error", err)
+		os.Exit(1)
+	}
+	content, err := io.ReadAll(file)
+	file.Close()
 	if err != nil {
 		logger.Error("failed to read file", "error", err)
 		os.Exit(1)
@@ -73,6 +99,7 @@ func main() {
 
 		chapterNum := int32(i + 1)
 		_, err := queries.CreateChunk(ctx, database.CreateChunkParams{
+			ID:                database.PgUUID(uuid.New()),
 			SourceID:          pgSrcID,
 			ChunkIndex:        int32(i),
 			Content:           chapter.Content,
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+
+	"github.com/einarsundgren/sikta/internal/archive"
+	"github.com/einarsundgren/sikta/internal/config"
+	"github.com/einarsundgren/sikta/internal/database"
+	"github.com/einarsundgren/sikta/internal/encryption"
+	"github.com/einarsundgren/sikta/internal/storage"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func main() {
+	// The archive goes to stdout unless a file is given, so logs go to stderr
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+	if len(os.Args) < 2 {
+		logger.Error("usage: export-project <project-id> [archive" + archive.Extension + "]")
+		os.Exit(1)
+	}
+	projectID, err := uuid.Parse(os.Args[1])
+	if err != nil {
+		logger.Error("invalid project ID", "error", err)
+		os.Exit(1)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		logger.Error("failed to load config", "error", err)
+		os.Exit(1)
+	}
+
+	ctx := context.Background()
+
+	pool, err := pgxpool.New(ctx, cfg.DatabaseURL)
+	if err != nil {
+		logger.Error("failed to connect to database", "error", err)
+		os.Exit(1)
+	}
+	defer pool.Close()
+
+	envelope, err := encryption.Load(cfg.EncryptionKeyFile)
+	if err != nil {
+		logger.Error("failed to load encryption keys", "error", err)
+		os.Exit(1)
+	}
+	store, err := storage.New(cfg.Storage())
+	if err != nil {
+		logger.Error("failed to configure storage", "error", err)
+		os.Exit(1)
+	}
+	if envelope != nil {
+		store = storage.NewEncrypted(store, envelope)
+	}
+	queries := database.New(pool)
+	if cfg.EncryptText {
+		queries = database.NewEncrypted(pool, envelope)
+	}
+
+	out := os.Stdout
+	if len(os.Args) > 2 {
+		out, err = os.Create(os.Args[2])
+		if err != nil {
+			logger.Error("failed to create archive file", "error", err)
+			os.Exit(1)
+		}
+	}
+	m, err := archive.Export(ctx, queries, store, projectID, out)
+	if err != nil {
+		logger.Error("export failed", "error", err)
+		os.Exit(1)
+	}
+	if err := out.Close(); err != nil {
+		logger.Error("failed to write archive", "error", err)
+		os.Exit(1)
+	}
+
+	logger.Info("exported project", "project", projectID, "sources", m.Counts.Sources, "chunks", m.Counts.Chunks,
+		"nodes", m.Counts.Nodes, "edges", m.Counts.Edges, "provenance", m.Counts.Provenance,
+		"inconsistencies", m.Counts.Inconsistencies, "files", m.Counts.Files)
+}
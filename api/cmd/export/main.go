@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/einarsundgren/sikta/internal/config"
+	"github.com/einarsundgren/sikta/internal/database"
+	"github.com/einarsundgren/sikta/internal/encryption"
+	"github.com/einarsundgren/sikta/internal/export"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func main() {
+	// The export goes to stdout unless -o is given, so logs go to stderr
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+
+	var names []string
+	for name := range export.Formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	formatName := fs.String("format", "turtle", "export format: "+strings.Join(names, ", "))
+	output := fs.String("o", "", "output file (default stdout)")
+	fs.Parse(os.Args[1:])
+
+	if fs.NArg() < 1 {
+		logger.Error("usage: export [-format name] [-o file] <project-id>")
+		os.Exit(1)
+	}
+	projectID, err := uuid.Parse(fs.Arg(0))
+	if err != nil {
+		logger.Error("invalid project ID", "error", err)
+		os.Exit(1)
+	}
+	format, ok := export.Formats[*formatName]
+	if !ok {
+		logger.Error("unknown format", "format", *formatName, "formats", strings.Join(names, ", "))
+		os.Exit(1)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		logger.Error("failed to load config", "error", err)
+		os.Exit(1)
+	}
+
+	ctx := context.Background()
+
+	pool, err := pgxpool.New(ctx, cfg.DatabaseURL)
+	if err != nil {
+		logger.Error("failed to connect to database", "error", err)
+		os.Exit(1)
+	}
+	defer pool.Close()
+
+	envelope, err := encryption.Load(cfg.EncryptionKeyFile)
+	if err != nil {
+		logger.Error("failed to load encryption keys", "error", err)
+		os.Exit(1)
+	}
+	queries := database.New(pool)
+	if cfg.EncryptText {
+		queries = database.NewEncrypted(pool, envelope)
+	}
+
+	g, err := export.Load(ctx, queries, projectID)
+	if err != nil {
+		logger.Error("failed to load graph", "error", err)
+		os.Exit(1)
+	}
+
+	out := os.Stdout
+	if *output != "" {
+		out, err = os.Create(*output)
+		if err != nil {
+			logger.Error("failed to create output file", "error", err)
+			os.Exit(1)
+		}
+	}
+	if err := format.Write(out, g); err != nil {
+		logger.Error("failed to write export", "error", err)
+		os.Exit(1)
+	}
+	if err := out.Close(); err != nil {
+		logger.Error("failed to write export", "error", err)
+		os.Exit(1)
+	}
+
+	logger.Info("exported graph", "project", projectID, "format", *formatName,
+		"nodes", len(g.Nodes), "edges", len(g.Edges))
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/einarsundgren/sikta/internal/config"
 	"github.com/einarsundgren/sikta/internal/database"
+	"github.com/einarsundgren/sikta/internal/encryption"
 	"github.com/einarsundgren/sikta/internal/extraction"
 	"github.com/einarsundgren/sikta/internal/extraction/claude"
 	"github.com/jackc/pgx/v5/pgtype"
@@ -39,7 +40,15 @@ func main() {
 	}
 	defer pool.Close()
 
+	envelope, err := encryption.Load(cfg.EncryptionKeyFile)
+	if err != nil {
+		logger.Error("failed to load encryption keys", "error", err)
+		os.Exit(1)
+	}
 	queries := database.New(pool)
+	if cfg.EncryptText {
+		queries = database.NewEncrypted(pool, envelope)
+	}
 
 	src, err := findSourceByPath(ctx, queries, docPath)
 	if err != nil {
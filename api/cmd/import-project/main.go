@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+
+	"github.com/einarsundgren/sikta/internal/archive"
+	"github.com/einarsundgren/sikta/internal/config"
+	"github.com/einarsundgren/sikta/internal/database"
+	"github.com/einarsundgren/sikta/internal/encryption"
+	"github.com/einarsundgren/sikta/internal/storage"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func main() {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	cfg, err := config.Load()
+	if err != nil {
+		logger.Error("failed to load config", "error", err)
+		os.Exit(1)
+	}
+
+	if len(os.Args) < 2 {
+		logger.Error("usage: import-project <archive" + archive.Extension + "> [title]")
+		os.Exit(1)
+	}
+	f, err := os.Open(os.Args[1])
+	if err != nil {
+		logger.Error("failed to open archive", "error", err)
+		os.Exit(1)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		logger.Error("failed to read archive", "error", err)
+		os.Exit(1)
+	}
+	var title string
+	if len(os.Args) > 2 {
+		title = os.Args[2]
+	}
+
+	ctx := context.Background()
+
+	pool, err := pgxpool.New(ctx, cfg.DatabaseURL)
+	if err != nil {
+		logger.Error("failed to connect to database", "error", err)
+		os.Exit(1)
+	}
+	defer pool.Close()
+
+	envelope, err := encryption.Load(cfg.EncryptionKeyFile)
+	if err != nil {
+		logger.Error("failed to load encryption keys", "error", err)
+		os.Exit(1)
+	}
+	store, err := storage.New(cfg.Storage())
+	if err != nil {
+		logger.Error("failed to configure storage", "error", err)
+		os.Exit(1)
+	}
+	if envelope != nil {
+		store = storage.NewEncrypted(store, envelope)
+	}
+	queries := database.New(pool)
+	if cfg.EncryptText {
+		queries = database.NewEncrypted(pool, envelope)
+	}
+
+	result, err := archive.Import(ctx, queries, store, f, info.Size(), title)
+	if err != nil {
+		logger.Error("import failed", "error", err)
+		os.Exit(1)
+	}
+	logger.Info("import complete", "project", result.ProjectID, "sources", result.Sources, "chunks", result.Chunks,
+		"nodes", result.Nodes, "edges", result.Edges, "provenance", result.Provenance,
+		"inconsistencies", result.Inconsistencies, "files", result.Files)
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+
+	"github.com/einarsundgren/sikta/internal/config"
+	"github.com/einarsundgren/sikta/internal/database"
+	"github.com/einarsundgren/sikta/internal/encryption"
+	"github.com/einarsundgren/sikta/internal/graph"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func main() {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+
+	cfg, err := config.Load()
+	if err != nil {
+		logger.Error("failed to load config", "error", err)
+		os.Exit(1)
+	}
+
+	if len(os.Args) < 4 {
+		logger.Error("usage: import <project-id> <source-id> <graph.json>")
+		os.Exit(1)
+	}
+	projectID, err := uuid.Parse(os.Args[1])
+	if err != nil {
+		logger.Error("invalid project ID", "error", err)
+		os.Exit(1)
+	}
+	sourceID, err := uuid.Parse(os.Args[2])
+	if err != nil {
+		logger.Error("invalid source ID", "error", err)
+		os.Exit(1)
+	}
+	data, err := os.ReadFile(os.Args[3])
+	if err != nil {
+		logger.Error("failed to read import file", "error", err)
+		os.Exit(1)
+	}
+	in, err := graph.ParseImport(data)
+	if err != nil {
+		logger.Error("failed to parse import file", "error", err)
+		os.Exit(1)
+	}
+
+	ctx := context.Background()
+
+	pool, err := pgxpool.New(ctx, cfg.DatabaseURL)
+	if err != nil {
+		logger.Error("failed to connect to database", "error", err)
+		os.Exit(1)
+	}
+	defer pool.Close()
+
+	envelope, err := encryption.Load(cfg.EncryptionKeyFile)
+	if err != nil {
+		logger.Error("failed to load encryption keys", "error", err)
+		os.Exit(1)
+	}
+	queries := database.New(pool)
+	if cfg.EncryptText {
+		queries = database.NewEncrypted(pool, envelope)
+	}
+
+	importer := graph.NewImporter(queries, graph.NewService(queries, logger), logger)
+	result, err := importer.Import(ctx, projectID, sourceID, in)
+	if err != nil {
+		logger.Error("import failed", "error", err)
+		os.Exit(1)
+	}
+	for _, s := range result.Skipped {
+		logger.Warn("skipped", "reason", s)
+	}
+	logger.Info("import complete", "nodes", result.Nodes, "edges", result.Edges, "provenance", result.Provenance, "skipped", len(result.Skipped))
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/einarsundgren/sikta/internal/config"
 	"github.com/einarsundgren/sikta/internal/database"
+	"github.com/einarsundgren/sikta/internal/encryption"
 	"github.com/einarsundgren/sikta/internal/graph"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -32,7 +33,15 @@ func main() {
 	}
 	defer pool.Close()
 
+	envelope, err := encryption.Load(cfg.EncryptionKeyFile)
+	if err != nil {
+		logger.Error("failed to load encryption keys", "error", err)
+		os.Exit(1)
+	}
 	queries := database.New(pool)
+	if cfg.EncryptText {
+		queries = database.NewEncrypted(pool, envelope)
+	}
 	graphService := graph.NewService(queries, logger)
 	migrator := graph.NewMigrator(queries, graphService, logger)
 
@@ -14,10 +14,12 @@ import (
 
 	"github.com/einarsundgren/sikta/internal/config"
 	"github.com/einarsundgren/sikta/internal/database"
+	"github.com/einarsundgren/sikta/internal/encryption"
 	"github.com/einarsundgren/sikta/internal/extraction"
 	graphhandlers "github.com/einarsundgren/sikta/internal/handlers/graph"
 	"github.com/einarsundgren/sikta/internal/handlers"
 	"github.com/einarsundgren/sikta/internal/middleware"
+	"github.com/einarsundgren/sikta/internal/storage"
 )
 
 func main() {
@@ -44,22 +46,49 @@ func main() {
 	}
 	logger.Info("database connected")
 
+	// Encryption at rest: uploaded files always, chunk text and excerpts
+	// if SIKTA_ENCRYPT_TEXT is set
+	envelope, err := encryption.Load(cfg.EncryptionKeyFile)
+	if err != nil {
+		logger.Error("failed to load encryption keys", "error", err)
+		os.Exit(1)
+	}
+
+	store, err := storage.New(cfg.Storage())
+	if err != nil {
+		logger.Error("failed to configure storage", "error", err)
+		os.Exit(1)
+	}
+	if envelope != nil {
+		store = storage.NewEncrypted(store, envelope)
+	}
+	logger.Info("storage configured", "backend", cfg.StorageBackend, "encrypted", envelope != nil)
+
+	db := database.New(pool)
+	if cfg.EncryptText {
+		db = database.NewEncrypted(pool, envelope)
+	}
+
 	mux := http.NewServeMux()
 	mux.HandleFunc("GET /health", handlers.Health)
 
 	// Document handlers (shared between models)
-	docHandler := handlers.NewDocumentHandler(pool, logger)
+	docHandler := handlers.NewDocumentHandler(db, cfg, store, logger)
 	mux.HandleFunc("POST /api/documents", docHandler.UploadDocument)
 	mux.HandleFunc("GET /api/documents", docHandler.ListDocuments)
 	mux.HandleFunc("GET /api/documents/{id}", docHandler.GetDocument)
 	mux.HandleFunc("GET /api/documents/{id}/status", docHandler.GetDocumentStatus)
+	mux.HandleFunc("POST /api/documents/{id}/versions", docHandler.UploadDocumentVersion)
+	mux.HandleFunc("GET /api/documents/{id}/versions", docHandler.ListDocumentVersions)
+	mux.HandleFunc("GET /api/documents/{id}/diff", docHandler.DiffDocument)
 	mux.HandleFunc("DELETE /api/documents/{id}", docHandler.DeleteDocument)
 
-	// Start background document processor (chunks uploaded documents)
-	stopCh := make(chan struct{})
-	go docHandler.ProcessDocuments(stopCh)
-
-	db := database.New(pool)
+	// Resumable (tus) uploads for large files
+	mux.HandleFunc("POST /api/uploads", docHandler.CreateUpload)
+	mux.HandleFunc("HEAD /api/uploads/{id}", docHandler.HeadUpload)
+	mux.HandleFunc("GET /api/uploads/{id}", docHandler.GetUpload)
+	mux.HandleFunc("PATCH /api/uploads/{id}", docHandler.PatchUpload)
+	mux.HandleFunc("DELETE /api/uploads/{id}", docHandler.DeleteUpload)
 
 	// Project handlers
 	projectHandler := handlers.NewProjectHandler(db, cfg, logger)
@@ -70,11 +99,17 @@ func main() {
 	mux.HandleFunc("DELETE /api/projects/{id}", projectHandler.DeleteProject)
 	mux.HandleFunc("GET /api/projects/{id}/documents", projectHandler.GetProjectDocuments)
 	mux.HandleFunc("POST /api/projects/{id}/documents", projectHandler.AddDocumentToProject)
+	mux.HandleFunc("POST /api/projects/{id}/documents/archive", docHandler.UploadProjectArchive)
 	mux.HandleFunc("GET /api/projects/{id}/graph", projectHandler.GetProjectGraph)
 	mux.HandleFunc("POST /api/projects/{id}/postprocess", projectHandler.RunPostProcessing)
 	mux.HandleFunc("POST /api/projects/{id}/deduplicate", projectHandler.RunDeduplication)
 	mux.HandleFunc("POST /api/projects/{id}/detect-inconsistencies", projectHandler.RunInconsistencyDetection)
 
+	// Whole-project archives
+	projectArchiveHandler := handlers.NewProjectArchiveHandler(db, store, logger)
+	mux.HandleFunc("GET /api/projects/{id}/archive", projectArchiveHandler.ExportProject)
+	mux.HandleFunc("POST /api/projects/import", projectArchiveHandler.ImportProject)
+
 	// Progress tracker for real-time extraction updates
 	progressTracker := extraction.NewProgressTracker()
 
@@ -83,12 +118,24 @@ func main() {
 	mux.HandleFunc("POST /api/documents/{id}/extract", extractionHandler.TriggerExtraction)
 	mux.HandleFunc("GET /api/documents/{id}/extract/progress", extractionHandler.StreamProgress)
 
+	// Background document workers: chunk uploads as Postgres announces them,
+	// then extract them if their project has auto-extract on
+	stopCh := make(chan struct{})
+	workers := handlers.NewSourceWorkers(pool, docHandler, extractionHandler, cfg.Workers, logger)
+	go workers.Run(stopCh)
+
 	// Graph model handlers
 	graphTimelineHandler := graphhandlers.NewTimelineHandler(db, logger)
 	graphEntitiesHandler := graphhandlers.NewEntitiesHandler(db, logger)
 	graphRelationshipsHandler := graphhandlers.NewRelationshipsHandler(db, logger)
 	graphStatusHandler := graphhandlers.NewStatusHandler(db, logger)
 	graphReviewHandler := graphhandlers.NewReviewHandler(db, logger)
+	graphTraversalHandler := graphhandlers.NewTraversalHandler(db, logger)
+	graphQueryHandler := graphhandlers.NewQueryHandler(db, logger)
+	graphExportHandler := graphhandlers.NewExportHandler(db, logger)
+	graphImportHandler := graphhandlers.NewImportHandler(db, logger)
+	graphConflictsHandler := graphhandlers.NewConflictsHandler(db, logger)
+	graphIdentitiesHandler := graphhandlers.NewIdentitiesHandler(db, logger)
 
 	mux.HandleFunc("GET /api/documents/{id}/timeline", graphTimelineHandler.GetTimeline)
 	mux.HandleFunc("GET /api/documents/{id}/entities", graphEntitiesHandler.GetEntities)
@@ -102,6 +149,16 @@ func main() {
 	mux.HandleFunc("PATCH /api/relationships/{id}/review", graphReviewHandler.UpdateEdgeReview)
 	mux.HandleFunc("GET /api/documents/{id}/review-progress", graphReviewHandler.GetReviewProgress)
 
+	mux.HandleFunc("GET /api/projects/{id}/timeline", graphTimelineHandler.GetProjectTimeline)
+	mux.HandleFunc("GET /api/projects/{id}/conflicts", graphConflictsHandler.GetConflicts)
+	mux.HandleFunc("GET /api/projects/{id}/identities", graphIdentitiesHandler.GetIdentities)
+	mux.HandleFunc("GET /api/projects/{id}/graph/nodes/{nodeId}/neighbors", graphTraversalHandler.GetNeighbors)
+	mux.HandleFunc("POST /api/projects/{id}/graph/subgraph", graphTraversalHandler.GetSubgraph)
+	mux.HandleFunc("GET /api/projects/{id}/graph/paths", graphTraversalHandler.GetPaths)
+	mux.HandleFunc("POST /api/projects/{id}/query", graphQueryHandler.RunQuery)
+	mux.HandleFunc("GET /api/projects/{id}/graph/export", graphExportHandler.ExportGraph)
+	mux.HandleFunc("POST /api/projects/{id}/graph/import", graphImportHandler.ImportGraph)
+
 	// Inconsistency handlers (currently only legacy)
 	incHandler := handlers.NewInconsistencyHandler(db, cfg, logger)
 	mux.HandleFunc("GET /api/documents/{id}/inconsistencies", incHandler.GetInconsistencies)
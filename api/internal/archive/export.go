@@ -0,0 +1,163 @@
+package archive
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/einarsundgren/sikta/internal/database"
+	"github.com/einarsundgren/sikta/internal/export"
+	"github.com/einarsundgren/sikta/internal/storage"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Export writes a project's archive to w. A source whose file is missing
+// from storage is archived without it.
+func Export(ctx context.Context, db *database.Queries, store storage.Storage, projectID uuid.UUID, w io.Writer) (*Manifest, error) {
+	g, err := export.Load(ctx, db, projectID)
+	if err != nil {
+		return nil, err
+	}
+	sources, err := db.GetProjectSources(ctx, database.PgUUID(projectID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sources: %w", err)
+	}
+	// Oldest first, so that a source's previous version precedes it
+	sort.SliceStable(sources, func(i, j int) bool {
+		return sources[i].CreatedAt.Time.Before(sources[j].CreatedAt.Time)
+	})
+
+	m := &Manifest{Format: FormatName, Version: Version, ExportedAt: time.Now().UTC(), Project: g.Project}
+	zw := zip.NewWriter(w)
+
+	var archivedSources []archivedSource
+	var chunks []archivedChunk
+	var inconsistencies []archivedInconsistency
+	for _, s := range sources {
+		a := archivedSource{Source: s, Metadata: rawJSON(s.Metadata)}
+		stored, err := copyFile(ctx, store, zw, s)
+		if err != nil {
+			return nil, err
+		}
+		if stored != "" {
+			a.File = stored
+			m.Counts.Files++
+		}
+		archivedSources = append(archivedSources, a)
+
+		sourceChunks, err := db.ListChunksBySource(ctx, s.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list chunks: %w", err)
+		}
+		for _, c := range sourceChunks {
+			chunks = append(chunks, archivedChunk{Chunk: c, Metadata: rawJSON(c.Metadata)})
+		}
+
+		sourceInconsistencies, err := db.ListInconsistenciesBySource(ctx, s.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list inconsistencies: %w", err)
+		}
+		for _, i := range sourceInconsistencies {
+			inconsistencies = append(inconsistencies, archivedInconsistency{Inconsistency: i, Metadata: rawJSON(i.Metadata)})
+		}
+	}
+
+	// Nodes the provenance refers to travel with the project, and so do
+	// edges between its nodes that have no provenance of their own
+	var nodes []archivedNode
+	var nodeIDs []pgtype.UUID
+	for _, n := range append(append([]*database.Node{}, g.Nodes...), g.Referenced...) {
+		nodes = append(nodes, archivedNode{Node: n, Properties: rawJSON(n.Properties)})
+		nodeIDs = append(nodeIDs, n.ID)
+	}
+	projectEdges, err := db.ListEdgesAmongNodes(ctx, nodeIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list edges: %w", err)
+	}
+	var edges []archivedEdge
+	for _, e := range projectEdges {
+		edges = append(edges, archivedEdge{Edge: e, Properties: rawJSON(e.Properties)})
+	}
+	var provenance []archivedProvenance
+	for _, n := range g.Nodes {
+		for _, p := range g.Provenance[n.ID] {
+			provenance = append(provenance, archivedProvenance{Provenance: p, Location: rawJSON(p.Location), ClaimedProperties: rawJSON(p.ClaimedProperties)})
+		}
+	}
+	for _, e := range projectEdges {
+		for _, p := range g.Provenance[e.ID] {
+			provenance = append(provenance, archivedProvenance{Provenance: p, Location: rawJSON(p.Location), ClaimedProperties: rawJSON(p.ClaimedProperties)})
+		}
+	}
+
+	m.Counts.Sources = len(archivedSources)
+	m.Counts.Chunks = len(chunks)
+	m.Counts.Nodes = len(nodes)
+	m.Counts.Edges = len(edges)
+	m.Counts.Provenance = len(provenance)
+	m.Counts.Inconsistencies = len(inconsistencies)
+
+	for _, table := range []struct {
+		name string
+		rows any
+	}{
+		{sourcesFile, archivedSources},
+		{chunksFile, chunks},
+		{nodesFile, nodes},
+		{edgesFile, edges},
+		{provenanceFile, provenance},
+		{inconsistenciesFile, inconsistencies},
+		{manifestFile, m},
+	} {
+		if err := writeJSON(zw, table.name, table.rows); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// copyFile stores a source's file in the archive and returns its entry
+// name, or "" if storage has no such file
+func copyFile(ctx context.Context, store storage.Storage, zw *zip.Writer, s *database.Source) (string, error) {
+	if s.FilePath == "" {
+		return "", nil
+	}
+	rc, err := store.Open(ctx, s.FilePath)
+	if errors.Is(err, storage.ErrNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to open file for source %s: %w", database.UUIDStr(s.ID), err)
+	}
+	defer rc.Close()
+
+	name := filesDir + database.UUIDStr(s.ID) + "/" + path.Base(s.Filename)
+	f, err := zw.Create(name)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(f, rc); err != nil {
+		return "", fmt.Errorf("failed to archive file for source %s: %w", database.UUIDStr(s.ID), err)
+	}
+	return name, nil
+}
+
+func writeJSON(zw *zip.Writer, name string, v any) error {
+	f, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -0,0 +1,398 @@
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+
+	"github.com/einarsundgren/sikta/internal/database"
+	"github.com/einarsundgren/sikta/internal/storage"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ErrInvalid is returned for a file that is not a readable project archive
+var ErrInvalid = errors.New("invalid archive")
+
+// maxTableSize bounds how much of one table entry is read, so that a
+// crafted archive cannot inflate without limit. Tests lower it.
+var maxTableSize int64 = 1 << 30
+
+// ImportResult is the project an archive was restored to
+type ImportResult struct {
+	ProjectID uuid.UUID `json:"project_id"`
+	Counts
+}
+
+// archiveData is an archive's tables
+type archiveData struct {
+	manifest        Manifest
+	sources         []archivedSource
+	chunks          []archivedChunk
+	nodes           []archivedNode
+	edges           []archivedEdge
+	provenance      []archivedProvenance
+	inconsistencies []archivedInconsistency
+}
+
+// Import restores an archive as a new project, titled title or else as the
+// archived project was. Every row gets a new ID, and references between
+// rows, including IDs held in node, edge and inconsistency JSON such as a
+// document node's source_id, follow. Files are stored first and the rows
+// are written in one transaction; on failure the stored files are removed.
+func Import(ctx context.Context, db *database.Queries, store storage.Storage, r io.ReaderAt, size int64, title string) (*ImportResult, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	data, err := readArchive(zr)
+	if err != nil {
+		return nil, err
+	}
+	if title == "" {
+		title = data.manifest.Project.Title
+	}
+
+	ids := newIDs(data)
+
+	var stored []string
+	cleanup := func() {
+		for _, key := range stored {
+			store.Delete(context.WithoutCancel(ctx), key)
+		}
+	}
+	files := make(map[string]*zip.File)
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+	keys := make(map[pgtype.UUID]string)
+	result := &ImportResult{}
+	for _, s := range data.sources {
+		f, ok := files[s.File]
+		if s.File == "" || !ok {
+			continue
+		}
+		key := path.Join("uploads", database.UUIDStr(ids[s.ID])+"_"+path.Base(s.Filename))
+		if err := putFile(ctx, store, key, f); err != nil {
+			cleanup()
+			return nil, err
+		}
+		stored = append(stored, key)
+		keys[s.ID] = key
+		result.Files++
+	}
+
+	err = db.InTx(ctx, func(q *database.Queries) error {
+		project, err := q.CreateProject(ctx, database.CreateProjectParams{
+			Title:       title,
+			Description: data.manifest.Project.Description,
+			AutoExtract: data.manifest.Project.AutoExtract,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create project: %w", err)
+		}
+		result.ProjectID = uuid.UUID(project.ID.Bytes)
+		return restore(ctx, q, data, ids, keys, project.ID, &result.Counts)
+	})
+	if err != nil {
+		cleanup()
+		return nil, err
+	}
+	return result, nil
+}
+
+func readArchive(zr *zip.Reader) (*archiveData, error) {
+	data := &archiveData{}
+	for _, table := range []struct {
+		name string
+		dest any
+	}{
+		{manifestFile, &data.manifest},
+		{sourcesFile, &data.sources},
+		{chunksFile, &data.chunks},
+		{nodesFile, &data.nodes},
+		{edgesFile, &data.edges},
+		{provenanceFile, &data.provenance},
+		{inconsistenciesFile, &data.inconsistencies},
+	} {
+		f, err := zr.Open(table.name)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+		}
+		err = json.NewDecoder(io.LimitReader(f, maxTableSize)).Decode(table.dest)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalid, table.name, err)
+		}
+
+		if table.name == manifestFile {
+			m := data.manifest
+			if m.Format != FormatName || m.Project == nil {
+				return nil, fmt.Errorf("%w: not a project archive", ErrInvalid)
+			}
+			if m.Version > Version {
+				return nil, fmt.Errorf("%w: version %d is newer than this instance reads (%d)", ErrInvalid, m.Version, Version)
+			}
+		}
+	}
+	return data, nil
+}
+
+// newIDs gives every archived row a new ID
+func newIDs(data *archiveData) map[pgtype.UUID]pgtype.UUID {
+	ids := make(map[pgtype.UUID]pgtype.UUID)
+	add := func(id pgtype.UUID) {
+		if id.Valid {
+			ids[id] = database.PgUUID(uuid.New())
+		}
+	}
+	for _, s := range data.sources {
+		add(s.ID)
+	}
+	for _, c := range data.chunks {
+		add(c.ID)
+	}
+	for _, n := range data.nodes {
+		add(n.ID)
+	}
+	for _, e := range data.edges {
+		add(e.ID)
+	}
+	for _, p := range data.provenance {
+		add(p.ID)
+	}
+	for _, i := range data.inconsistencies {
+		add(i.ID)
+	}
+	return ids
+}
+
+func putFile(ctx context.Context, store storage.Storage, key string, f *zip.File) error {
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	defer rc.Close()
+	if err := store.Put(ctx, key, rc, int64(f.UncompressedSize64)); err != nil {
+		return fmt.Errorf("failed to store %s: %w", f.Name, err)
+	}
+	return nil
+}
+
+// restore writes the archived rows under their new IDs. Rows that refer to
+// something missing from the archive are skipped, except that a missing
+// previous version or claimant is dropped.
+func restore(ctx context.Context, q *database.Queries, data *archiveData, ids map[pgtype.UUID]pgtype.UUID, keys map[pgtype.UUID]string, projectID pgtype.UUID, counts *Counts) error {
+	remap := func(id pgtype.UUID) (pgtype.UUID, bool) {
+		n, ok := ids[id]
+		return n, ok
+	}
+	optional := func(id pgtype.UUID) pgtype.UUID {
+		n, _ := remap(id)
+		return n
+	}
+
+	for _, s := range data.sources {
+		filePath := s.FilePath
+		if key, ok := keys[s.ID]; ok {
+			filePath = key
+		}
+		err := q.RestoreSource(ctx, database.RestoreSourceParams{
+			ID:                ids[s.ID],
+			Title:             s.Title,
+			Filename:          s.Filename,
+			FilePath:          filePath,
+			FileType:          s.FileType,
+			TotalPages:        s.TotalPages,
+			UploadStatus:      s.UploadStatus,
+			ErrorMessage:      s.ErrorMessage,
+			IsDemo:            s.IsDemo,
+			Metadata:          remapJSON(s.Metadata, ids),
+			CreatedAt:         s.CreatedAt,
+			UpdatedAt:         s.UpdatedAt,
+			SourceTrust:       s.SourceTrust,
+			TrustReason:       s.TrustReason,
+			ProjectID:         projectID,
+			ContentHash:       s.ContentHash,
+			PreviousVersionID: optional(s.PreviousVersionID),
+			Version:           s.Version,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to restore source: %w", err)
+		}
+		counts.Sources++
+	}
+
+	for _, c := range data.chunks {
+		sourceID, ok := remap(c.SourceID)
+		if !ok {
+			continue
+		}
+		err := q.RestoreChunk(ctx, database.RestoreChunkParams{
+			ID:                ids[c.ID],
+			SourceID:          sourceID,
+			ChunkIndex:        c.ChunkIndex,
+			Content:           c.Content,
+			ChapterTitle:      c.ChapterTitle,
+			ChapterNumber:     c.ChapterNumber,
+			PageStart:         c.PageStart,
+			PageEnd:           c.PageEnd,
+			NarrativePosition: c.NarrativePosition,
+			WordCount:         c.WordCount,
+			CreatedAt:         c.CreatedAt,
+			SectionID:         c.SectionID,
+			SectionName:       c.SectionName,
+			Metadata:          remapJSON(c.Metadata, ids),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to restore chunk: %w", err)
+		}
+		counts.Chunks++
+	}
+
+	for _, n := range data.nodes {
+		err := q.RestoreNode(ctx, database.RestoreNodeParams{
+			ID:         ids[n.ID],
+			NodeType:   n.NodeType,
+			Label:      n.Label,
+			Properties: remapJSON(n.Properties, ids),
+			CreatedAt:  n.CreatedAt,
+			UpdatedAt:  n.UpdatedAt,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to restore node: %w", err)
+		}
+		counts.Nodes++
+	}
+
+	for _, e := range data.edges {
+		source, okSource := remap(e.SourceNode)
+		target, okTarget := remap(e.TargetNode)
+		if !okSource || !okTarget {
+			continue
+		}
+		err := q.RestoreEdge(ctx, database.RestoreEdgeParams{
+			ID:         ids[e.ID],
+			EdgeType:   e.EdgeType,
+			SourceNode: source,
+			TargetNode: target,
+			Properties: remapJSON(e.Properties, ids),
+			IsNegated:  e.IsNegated,
+			CreatedAt:  e.CreatedAt,
+			UpdatedAt:  e.UpdatedAt,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to restore edge: %w", err)
+		}
+		counts.Edges++
+	}
+
+	for _, p := range data.provenance {
+		target, okTarget := remap(p.TargetID)
+		source, okSource := remap(p.SourceID)
+		if !okTarget || !okSource {
+			continue
+		}
+		// Archives written before per-record claims have none
+		claimed := remapJSON(p.ClaimedProperties, ids)
+		if claimed == nil {
+			claimed = []byte("{}")
+		}
+		err := q.RestoreProvenance(ctx, database.RestoreProvenanceParams{
+			ID:                ids[p.ID],
+			TargetType:        p.TargetType,
+			TargetID:          target,
+			SourceID:          source,
+			Excerpt:           p.Excerpt,
+			Location:          jsonbColumn(p.Location),
+			Confidence:        p.Confidence,
+			Trust:             p.Trust,
+			Status:            p.Status,
+			Modality:          p.Modality,
+			ClaimedTimeStart:  p.ClaimedTimeStart,
+			ClaimedTimeEnd:    p.ClaimedTimeEnd,
+			ClaimedTimeText:   p.ClaimedTimeText,
+			ClaimedGeoRegion:  p.ClaimedGeoRegion,
+			ClaimedGeoText:    p.ClaimedGeoText,
+			ClaimedBy:         optional(p.ClaimedBy),
+			CreatedAt:         p.CreatedAt,
+			UpdatedAt:         p.UpdatedAt,
+			ClaimedProperties: claimed,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to restore provenance: %w", err)
+		}
+		counts.Provenance++
+	}
+
+	for _, i := range data.inconsistencies {
+		sourceID, ok := remap(i.SourceID)
+		if !ok {
+			continue
+		}
+		err := q.RestoreInconsistency(ctx, database.RestoreInconsistencyParams{
+			ID:                ids[i.ID],
+			SourceID:          sourceID,
+			InconsistencyType: i.InconsistencyType,
+			Severity:          i.Severity,
+			Title:             i.Title,
+			Description:       i.Description,
+			ResolutionStatus:  i.ResolutionStatus,
+			ResolutionNote:    i.ResolutionNote,
+			Metadata:          remapJSON(i.Metadata, ids),
+			CreatedAt:         i.CreatedAt,
+			UpdatedAt:         i.UpdatedAt,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to restore inconsistency: %w", err)
+		}
+		counts.Inconsistencies++
+	}
+	return nil
+}
+
+// remapJSON returns archived JSON as a JSONB column value with every string
+// that is an archived row's ID replaced by the row's new ID
+func remapJSON(raw json.RawMessage, ids map[pgtype.UUID]pgtype.UUID) []byte {
+	data := jsonbColumn(raw)
+	if data == nil {
+		return nil
+	}
+	// Numbers stay as written rather than passing through float64
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return data
+	}
+	out, err := json.Marshal(remapValue(v, ids))
+	if err != nil {
+		return data
+	}
+	return out
+}
+
+func remapValue(v any, ids map[pgtype.UUID]pgtype.UUID) any {
+	switch v := v.(type) {
+	case string:
+		if id, err := uuid.Parse(v); err == nil {
+			if n, ok := ids[database.PgUUID(id)]; ok {
+				return database.UUIDStr(n)
+			}
+		}
+	case []any:
+		for i := range v {
+			v[i] = remapValue(v[i], ids)
+		}
+	case map[string]any:
+		for k := range v {
+			v[k] = remapValue(v[k], ids)
+		}
+	}
+	return v
+}
@@ -0,0 +1,321 @@
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/einarsundgren/sikta/internal/database"
+	"github.com/einarsundgren/sikta/internal/database/dbtest"
+	"github.com/einarsundgren/sikta/internal/storage"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func testID(n byte) pgtype.UUID {
+	return database.PgUUID(uuid.UUID{0x7a, 0x20, 15: n})
+}
+
+func TestRemapJSON(t *testing.T) {
+	old1, old2, unmapped := testID(1), testID(2), testID(3)
+	new1, new2 := testID(11), testID(12)
+	ids := map[pgtype.UUID]pgtype.UUID{old1: new1, old2: new2}
+	s := database.UUIDStr
+
+	tests := []struct {
+		name string
+		raw  string
+		want string // "" for a null column
+	}{
+		{"empty", "", ""},
+		{"null", "null", ""},
+		{"top-level string", `"` + s(old1) + `"`, `"` + s(new1) + `"`},
+		{
+			name: "nested",
+			raw:  `{"source_id": "` + s(old1) + `", "refs": ["` + s(old2) + `", {"id": "` + s(old1) + `", "tags": [["` + s(old2) + `"]]}]}`,
+			want: `{"refs":["` + s(new2) + `",{"id":"` + s(new1) + `","tags":[["` + s(new2) + `"]]}],"source_id":"` + s(new1) + `"}`,
+		},
+		{
+			name: "unmapped IDs and other strings",
+			raw:  `{"other": "` + s(unmapped) + `", "label": "Anna", "key": "` + s(old1) + `"}`,
+			want: `{"key":"` + s(new1) + `","label":"Anna","other":"` + s(unmapped) + `"}`,
+		},
+		{
+			// Keys are not remapped, only values
+			name: "ID as a key",
+			raw:  `{"` + s(old1) + `": true}`,
+			want: `{"` + s(old1) + `":true}`,
+		},
+		{
+			name: "exact numbers",
+			raw:  `{"population": 12345678901234567890, "ratio": 0.1, "year": 1850, "exp": 1e400}`,
+			want: `{"exp":1e400,"population":12345678901234567890,"ratio":0.1,"year":1850}`,
+		},
+		{"invalid json is kept", `{"a": `, `{"a": `},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := remapJSON(json.RawMessage(tt.raw), ids)
+			if tt.want == "" {
+				if got != nil {
+					t.Errorf("remapJSON() = %s, want nil", got)
+				}
+				return
+			}
+			if string(got) != tt.want {
+				t.Errorf("remapJSON() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewIDs(t *testing.T) {
+	data := &archiveData{
+		sources:    []archivedSource{{Source: &database.Source{ID: testID(1)}}},
+		chunks:     []archivedChunk{{Chunk: &database.Chunk{ID: testID(2)}}},
+		nodes:      []archivedNode{{Node: &database.Node{ID: testID(3)}}, {Node: &database.Node{}}},
+		edges:      []archivedEdge{{Edge: &database.Edge{ID: testID(4)}}},
+		provenance: []archivedProvenance{{Provenance: &database.Provenance{ID: testID(5)}}},
+		inconsistencies: []archivedInconsistency{
+			{Inconsistency: &database.Inconsistency{ID: testID(6)}},
+		},
+	}
+
+	ids := newIDs(data)
+	if len(ids) != 6 {
+		t.Fatalf("newIDs() gave %d IDs, want 6; a row without an ID gets none", len(ids))
+	}
+	seen := make(map[pgtype.UUID]bool)
+	for n := byte(1); n <= 6; n++ {
+		id, ok := ids[testID(n)]
+		if !ok {
+			t.Errorf("row %d has no new ID", n)
+			continue
+		}
+		if !id.Valid || id == testID(n) || seen[id] {
+			t.Errorf("row %d new ID = %v, want a new, unique ID", n, id)
+		}
+		seen[id] = true
+	}
+}
+
+// zipReader returns a zip archive of the given entries
+func zipReader(t *testing.T, entries map[string]string) *zip.Reader {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range entries {
+		f, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return zr
+}
+
+func TestReadArchive(t *testing.T) {
+	// archive returns the entries of an empty archive, changed by edit
+	archive := func(edit func(entries map[string]string)) map[string]string {
+		entries := map[string]string{
+			manifestFile:        `{"format": "sikta-project", "version": 1, "project": {"title": "Bruksarkivet"}}`,
+			sourcesFile:         `[]`,
+			chunksFile:          `[]`,
+			nodesFile:           `[{"id": "` + database.UUIDStr(testID(1)) + `", "node_type": "person", "label": "Anna", "properties": {"born": 1811}}]`,
+			edgesFile:           `[]`,
+			provenanceFile:      `[]`,
+			inconsistenciesFile: `null`,
+		}
+		if edit != nil {
+			edit(entries)
+		}
+		return entries
+	}
+
+	tests := []struct {
+		name         string
+		entries      map[string]string
+		maxTableSize int64
+		wantErr      string
+	}{
+		{name: "valid", entries: archive(nil)},
+		{
+			name:    "wrong format",
+			entries: archive(func(e map[string]string) { e[manifestFile] = `{"format": "sikta-graph", "version": 1, "project": {}}` }),
+			wantErr: "not a project archive",
+		},
+		{
+			name:    "no project",
+			entries: archive(func(e map[string]string) { e[manifestFile] = `{"format": "sikta-project", "version": 1}` }),
+			wantErr: "not a project archive",
+		},
+		{
+			name: "newer version",
+			entries: archive(func(e map[string]string) {
+				e[manifestFile] = `{"format": "sikta-project", "version": 2, "project": {}}`
+			}),
+			wantErr: "version 2 is newer",
+		},
+		{
+			name:    "missing manifest",
+			entries: archive(func(e map[string]string) { delete(e, manifestFile) }),
+			wantErr: manifestFile,
+		},
+		{
+			name:    "missing table",
+			entries: archive(func(e map[string]string) { delete(e, edgesFile) }),
+			wantErr: edgesFile,
+		},
+		{
+			name:    "malformed table",
+			entries: archive(func(e map[string]string) { e[chunksFile] = `{"id": 1}` }),
+			wantErr: chunksFile,
+		},
+		{
+			name: "table larger than the limit",
+			entries: archive(func(e map[string]string) {
+				e[nodesFile] = `[{"node_type": "person", "label": "` + strings.Repeat("Anna ", 100) + `"}]`
+			}),
+			maxTableSize: 256,
+			wantErr:      nodesFile,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.maxTableSize > 0 {
+				defer func(n int64) { maxTableSize = n }(maxTableSize)
+				maxTableSize = tt.maxTableSize
+			}
+
+			data, err := readArchive(zipReader(t, tt.entries))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("readArchive() error = %v", err)
+				}
+				if data.manifest.Project.Title != "Bruksarkivet" || len(data.nodes) != 1 || string(data.nodes[0].Properties) != `{"born": 1811}` {
+					t.Errorf("readArchive() = %+v, want the project and its node", data)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalid) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("readArchive() error = %v, want ErrInvalid mentioning %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExportImport(t *testing.T) {
+	ctx := context.Background()
+	created := pgtype.Timestamptz{Time: time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC), Valid: true}
+	projectID, sourceID, docID, annaID, edgeID, provID, chunkID := testID(1), testID(2), testID(3), testID(4), testID(5), testID(6), testID(7)
+
+	store := storage.NewLocal(t.TempDir())
+	const content = "Mötet öppnades kl. 19."
+	if err := store.Put(ctx, "uploads/protokoll.txt", strings.NewReader(content), int64(len(content))); err != nil {
+		t.Fatal(err)
+	}
+
+	doc := &database.Node{ID: docID, NodeType: database.NodeTypeDocument, Label: "Protokoll", Properties: []byte(`{"source_id": "` + database.UUIDStr(sourceID) + `"}`)}
+	anna := &database.Node{ID: annaID, NodeType: "person", Label: "Anna", Properties: []byte(`{"born": 1811}`)}
+	edge := &database.Edge{ID: edgeID, EdgeType: "mentioned_in", SourceNode: annaID, TargetNode: docID, Properties: []byte(`{}`)}
+	src := dbtest.New()
+	src.Return("GetProject", &database.Project{ID: projectID, Title: "Bruksarkivet", AutoExtract: true}, nil)
+	src.Return("ListProjectNodes", []*database.Node{doc, anna}, nil)
+	src.Return("ListProjectEdges", []*database.Edge{edge}, nil)
+	src.Return("ListProjectProvenance", []*database.Provenance{{
+		ID: provID, TargetType: "node", TargetID: annaID, SourceID: docID, Excerpt: "Anna", Status: "approved",
+		Location: []byte(`{}`), ClaimedProperties: []byte(`{"born": 1811}`), CreatedAt: created,
+	}}, nil)
+	src.Return("GetProjectSources", []*database.Source{{
+		ID: sourceID, Title: "Protokoll", Filename: "protokoll.txt", FilePath: "uploads/protokoll.txt", FileType: "txt",
+		UploadStatus: "ready", ProjectID: projectID, CreatedAt: created, Version: 1,
+	}}, nil)
+	src.Return("ListChunksBySource", []*database.Chunk{{ID: chunkID, SourceID: sourceID, Content: content}}, nil)
+	src.Return("ListEdgesAmongNodes", []*database.Edge{edge}, nil)
+
+	var buf bytes.Buffer
+	m, err := Export(ctx, database.New(src), store, uuid.UUID(projectID.Bytes), &buf)
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	want := Counts{Sources: 1, Chunks: 1, Nodes: 2, Edges: 1, Provenance: 1, Files: 1}
+	if m.Counts != want {
+		t.Errorf("Export() counts = %+v, want %+v", m.Counts, want)
+	}
+
+	dst := dbtest.New()
+	dst.Return("CreateProject", &database.Project{ID: testID(21)}, nil)
+	result, err := Import(ctx, database.New(dst), store, bytes.NewReader(buf.Bytes()), int64(buf.Len()), "")
+	if err != nil {
+		t.Fatalf("Import() error = %v", err)
+	}
+	if result.ProjectID != uuid.UUID(testID(21).Bytes) || result.Counts != want {
+		t.Errorf("Import() = %v %+v, want the new project with %+v", result.ProjectID, result.Counts, want)
+	}
+	if got := dst.Calls("CreateProject")[0].Args[0]; got != "Bruksarkivet" {
+		t.Errorf("project title = %v, want the archived title", got)
+	}
+
+	restored := func(name string) []interface{} {
+		calls := dst.Calls(name)
+		if len(calls) != 1 {
+			t.Fatalf("%s called %d times, want 1", name, len(calls))
+		}
+		return calls[0].Args
+	}
+	newIDs := make(map[string]pgtype.UUID)
+	for _, call := range dst.Calls("RestoreNode") {
+		newIDs[call.Args[2].(string)] = call.Args[0].(pgtype.UUID)
+	}
+
+	source := restored("RestoreSource")
+	newSource := source[0].(pgtype.UUID)
+	if newSource == sourceID || source[14] != testID(21) {
+		t.Errorf("source restored as %v in project %v, want a new ID in the new project", newSource, source[14])
+	}
+	rc, err := store.Open(ctx, source[3].(string))
+	if err != nil {
+		t.Fatalf("restored file %v: %v", source[3], err)
+	}
+	got, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(got) != content {
+		t.Errorf("restored file = %q, want %q", got, content)
+	}
+
+	for _, call := range dst.Calls("RestoreNode") {
+		if call.Args[2] != "Protokoll" {
+			continue
+		}
+		var props struct {
+			SourceID string `json:"source_id"`
+		}
+		json.Unmarshal(call.Args[3].([]byte), &props)
+		if props.SourceID != database.UUIDStr(newSource) {
+			t.Errorf("document node source_id = %s, want the new source ID %s", props.SourceID, database.UUIDStr(newSource))
+		}
+	}
+
+	if e := restored("RestoreEdge"); e[2] != newIDs["Anna"] || e[3] != newIDs["Protokoll"] {
+		t.Errorf("edge joins %v and %v, want the new node IDs", e[2], e[3])
+	}
+	if p := restored("RestoreProvenance"); p[2] != newIDs["Anna"] || p[3] != newIDs["Protokoll"] || p[8] != "approved" {
+		t.Errorf("provenance = %v on %v from %v, want approved on the new node IDs", p[8], p[2], p[3])
+	}
+	if c := restored("RestoreChunk"); c[1] != newSource {
+		t.Errorf("chunk source = %v, want %v", c[1], newSource)
+	}
+}
@@ -3,7 +3,10 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
+
+	"github.com/einarsundgren/sikta/internal/storage"
 )
 
 type Config struct {
@@ -16,6 +19,21 @@ type Config struct {
 	AnthropicModelClassification string
 	AnthropicModelChronology     string
 	PromptDir                    string // Path to prompts directory (default: "" uses hardcoded)
+	PDFExtractor                 string // "auto" (default), "pdftotext" or "native"
+	OCREngine                    string // "auto" (default), "tesseract" or "none"
+	OCRLanguages                 string // tesseract languages, e.g. "swe+eng"
+	MaxUploadSize                int64  // largest accepted upload in bytes (SIKTA_MAX_UPLOAD_MB)
+	StorageBackend               string // where uploads are kept: "local" (default) or "s3"
+	StorageDir                   string // local storage root (default: working directory)
+	S3Endpoint                   string // e.g. http://minio:9000
+	S3Region                     string
+	S3Bucket                     string
+	S3AccessKey                  string
+	S3SecretKey                  string
+	S3PathStyle                  bool // bucket in the URL path, as MinIO expects
+	EncryptionKeyFile            string // master keys for encryption at rest (default: "" means off)
+	EncryptText                  bool   // also encrypt chunk content and provenance excerpts
+	Workers                      int    // document workers chunking uploaded sources (SIKTA_WORKERS)
 }
 
 func Load() (*Config, error) {
@@ -29,6 +47,22 @@ func Load() (*Config, error) {
 		",",
 	)
 
+	maxUploadMB, err := strconv.ParseInt(getEnv("SIKTA_MAX_UPLOAD_MB", "1024"), 10, 64)
+	if err != nil || maxUploadMB <= 0 {
+		return nil, fmt.Errorf("SIKTA_MAX_UPLOAD_MB must be a positive number of megabytes")
+	}
+
+	workers, err := strconv.Atoi(getEnv("SIKTA_WORKERS", "2"))
+	if err != nil || workers <= 0 {
+		return nil, fmt.Errorf("SIKTA_WORKERS must be a positive number")
+	}
+
+	encryptText := getEnv("SIKTA_ENCRYPT_TEXT", "false") == "true"
+	encryptionKeyFile := getEnv("SIKTA_ENCRYPTION_KEY_FILE", "")
+	if encryptText && encryptionKeyFile == "" {
+		return nil, fmt.Errorf("SIKTA_ENCRYPT_TEXT needs SIKTA_ENCRYPTION_KEY_FILE")
+	}
+
 	return &Config{
 		Port:                        getEnv("PORT", "8080"),
 		DatabaseURL:                 databaseURL,
@@ -39,9 +73,38 @@ func Load() (*Config, error) {
 		AnthropicModelClassification: getEnv("ANTHROPIC_MODEL_CLASSIFICATION", "claude-haiku-4-20250514"),
 		AnthropicModelChronology:    getEnv("ANTHROPIC_MODEL_CHRONOLOGY", "claude-sonnet-4-20250514"),
 		PromptDir:                   getEnv("SIKTA_PROMPT_DIR", ""),
+		PDFExtractor:                getEnv("SIKTA_PDF_EXTRACTOR", "auto"),
+		OCREngine:                   getEnv("SIKTA_OCR_ENGINE", "auto"),
+		OCRLanguages:                getEnv("SIKTA_OCR_LANGUAGES", "swe+eng"),
+		MaxUploadSize:               maxUploadMB << 20,
+		StorageBackend:              getEnv("SIKTA_STORAGE", storage.BackendLocal),
+		StorageDir:                  getEnv("SIKTA_STORAGE_DIR", "."),
+		S3Endpoint:                  getEnv("SIKTA_S3_ENDPOINT", ""),
+		S3Region:                    getEnv("SIKTA_S3_REGION", "us-east-1"),
+		S3Bucket:                    getEnv("SIKTA_S3_BUCKET", ""),
+		S3AccessKey:                 getEnv("SIKTA_S3_ACCESS_KEY", os.Getenv("AWS_ACCESS_KEY_ID")),
+		S3SecretKey:                 getEnv("SIKTA_S3_SECRET_KEY", os.Getenv("AWS_SECRET_ACCESS_KEY")),
+		S3PathStyle:                 getEnv("SIKTA_S3_PATH_STYLE", "true") == "true",
+		EncryptionKeyFile:           encryptionKeyFile,
+		EncryptText:                 encryptText,
+		Workers:                     workers,
 	}, nil
 }
 
+// Storage returns the configuration of the storage backend for uploads.
+func (c *Config) Storage() storage.Config {
+	return storage.Config{
+		Backend:   c.StorageBackend,
+		Dir:       c.StorageDir,
+		Endpoint:  c.S3Endpoint,
+		Region:    c.S3Region,
+		Bucket:    c.S3Bucket,
+		AccessKey: c.S3AccessKey,
+		SecretKey: c.S3SecretKey,
+		PathStyle: c.S3PathStyle,
+	}
+}
+
 func getEnv(key, fallback string) string {
 	if v := os.Getenv(key); v != "" {
 		return v
@@ -24,14 +24,16 @@ func (q *Queries) CountChunksBySource(ctx context.Context, sourceID pgtype.UUID)
 
 const createChunk = `-- name: CreateChunk :one
 INSERT INTO chunks (
-    source_id, chunk_index, content, chapter_title,
-    chapter_number, page_start, page_end, narrative_position, word_count
+    id, source_id, chunk_index, content, chapter_title,
+    chapter_number, page_start, page_end, narrative_position, word_count,
+    section_id, section_name, metadata
 )
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
 RETURNING id, chunk_index, chapter_title, chapter_number
 `
 
 type CreateChunkParams struct {
+	ID                pgtype.UUID `json:"id"`
 	SourceID          pgtype.UUID `json:"source_id"`
 	ChunkIndex        int32       `json:"chunk_index"`
 	Content           string      `json:"content"`
@@ -41,6 +43,9 @@ type CreateChunkParams struct {
 	PageEnd           pgtype.Int4 `json:"page_end"`
 	NarrativePosition int32       `json:"narrative_position"`
 	WordCount         pgtype.Int4 `json:"word_count"`
+	SectionID         pgtype.Text `json:"section_id"`
+	SectionName       pgtype.Text `json:"section_name"`
+	Metadata          []byte      `json:"metadata"`
 }
 
 type CreateChunkRow struct {
@@ -52,6 +57,7 @@ type CreateChunkRow struct {
 
 func (q *Queries) CreateChunk(ctx context.Context, arg CreateChunkParams) (*CreateChunkRow, error) {
 	row := q.db.QueryRow(ctx, createChunk,
+		arg.ID,
 		arg.SourceID,
 		arg.ChunkIndex,
 		arg.Content,
@@ -61,6 +67,9 @@ func (q *Queries) CreateChunk(ctx context.Context, arg CreateChunkParams) (*Crea
 		arg.PageEnd,
 		arg.NarrativePosition,
 		arg.WordCount,
+		arg.SectionID,
+		arg.SectionName,
+		arg.Metadata,
 	)
 	var i CreateChunkRow
 	err := row.Scan(
@@ -72,8 +81,17 @@ func (q *Queries) CreateChunk(ctx context.Context, arg CreateChunkParams) (*Crea
 	return &i, err
 }
 
+const deleteChunksBySource = `-- name: DeleteChunksBySource :exec
+DELETE FROM chunks WHERE source_id = $1
+`
+
+func (q *Queries) DeleteChunksBySource(ctx context.Context, sourceID pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, deleteChunksBySource, sourceID)
+	return err
+}
+
 const getChunk = `-- name: GetChunk :one
-SELECT id, source_id, chunk_index, content, chapter_title, chapter_number, page_start, page_end, narrative_position, word_count, created_at FROM chunks WHERE id = $1
+SELECT id, source_id, chunk_index, content, chapter_title, chapter_number, page_start, page_end, narrative_position, word_count, created_at, section_id, section_name, metadata FROM chunks WHERE id = $1
 `
 
 func (q *Queries) GetChunk(ctx context.Context, id pgtype.UUID) (*Chunk, error) {
@@ -91,12 +109,15 @@ func (q *Queries) GetChunk(ctx context.Context, id pgtype.UUID) (*Chunk, error)
 		&i.NarrativePosition,
 		&i.WordCount,
 		&i.CreatedAt,
+		&i.SectionID,
+		&i.SectionName,
+		&i.Metadata,
 	)
 	return &i, err
 }
 
 const listChunksBySource = `-- name: ListChunksBySource :many
-SELECT id, source_id, chunk_index, content, chapter_title, chapter_number, page_start, page_end, narrative_position, word_count, created_at FROM chunks WHERE source_id = $1 ORDER BY chunk_index
+SELECT id, source_id, chunk_index, content, chapter_title, chapter_number, page_start, page_end, narrative_position, word_count, created_at, section_id, section_name, metadata FROM chunks WHERE source_id = $1 ORDER BY chunk_index
 `
 
 func (q *Queries) ListChunksBySource(ctx context.Context, sourceID pgtype.UUID) ([]*Chunk, error) {
@@ -120,6 +141,9 @@ func (q *Queries) ListChunksBySource(ctx context.Context, sourceID pgtype.UUID)
 			&i.NarrativePosition,
 			&i.WordCount,
 			&i.CreatedAt,
+			&i.SectionID,
+			&i.SectionName,
+			&i.Metadata,
 		); err != nil {
 			return nil, err
 		}
@@ -0,0 +1,191 @@
+// Package dbtest provides a fake database.DBTX for testing code built on
+// database.Queries without Postgres. Queries are answered by name, from the
+// "-- name: " comment that starts every generated query.
+package dbtest
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Result answers a query given its arguments. For a query returning rows it
+// returns a row, or a slice of rows for a :many query: a struct whose fields
+// are the query's columns in order, as the generated row types are, or a
+// single value for a one-column query. For an :exec query it may return the
+// number of rows affected as an int64.
+type Result func(args []interface{}) (interface{}, error)
+
+// Call is a query the database was sent.
+type Call struct {
+	Name string
+	Args []interface{}
+}
+
+// DB is a fake database.DBTX. Queries without a result, or whose result is
+// a nil row, return no rows and affect none. It is safe for concurrent use.
+type DB struct {
+	mu      sync.Mutex
+	results map[string]Result
+	calls   []Call
+}
+
+// New returns an empty fake database.
+func New() *DB {
+	return &DB{results: make(map[string]Result)}
+}
+
+// On sets how the named query is answered.
+func (db *DB) On(name string, result Result) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.results[name] = result
+}
+
+// Return answers the named query with the same row or error every time.
+func (db *DB) Return(name string, row interface{}, err error) {
+	db.On(name, func([]interface{}) (interface{}, error) { return row, err })
+}
+
+// Calls returns the queries sent with the given name, oldest first.
+func (db *DB) Calls(name string) []Call {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	var calls []Call
+	for _, c := range db.calls {
+		if c.Name == name {
+			calls = append(calls, c)
+		}
+	}
+	return calls
+}
+
+// run records a query and answers it.
+func (db *DB) run(sql string, args []interface{}) (interface{}, error) {
+	name, _ := strings.CutPrefix(sql, "-- name: ")
+	name, _, _ = strings.Cut(name, " ")
+
+	db.mu.Lock()
+	db.calls = append(db.calls, Call{Name: name, Args: args})
+	result := db.results[name]
+	db.mu.Unlock()
+
+	if result == nil {
+		return nil, nil
+	}
+	v, err := result(args)
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Pointer && rv.IsNil() {
+		// A nil row is no row
+		v = nil
+	}
+	return v, err
+}
+
+func (db *DB) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	v, err := db.run(sql, args)
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	n, _ := v.(int64)
+	return pgconn.NewCommandTag(fmt.Sprintf("UPDATE %d", n)), nil
+}
+
+func (db *DB) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	v, err := db.run(sql, args)
+	if err != nil {
+		return nil, err
+	}
+	rows := &rows{}
+	if v != nil {
+		list := reflect.ValueOf(v)
+		if list.Kind() != reflect.Slice {
+			return nil, fmt.Errorf("dbtest: %T is not a slice of rows", v)
+		}
+		for i := 0; i < list.Len(); i++ {
+			rows.values = append(rows.values, list.Index(i).Interface())
+		}
+	}
+	return rows, nil
+}
+
+func (db *DB) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	v, err := db.run(sql, args)
+	if err == nil && v == nil {
+		err = pgx.ErrNoRows
+	}
+	return row{value: v, err: err}
+}
+
+type row struct {
+	value interface{}
+	err   error
+}
+
+func (r row) Scan(dest ...interface{}) error {
+	if r.err != nil {
+		return r.err
+	}
+	return scan(r.value, dest)
+}
+
+// scan copies a row into dest: a single value, or a struct's fields in
+// order.
+func scan(value interface{}, dest []interface{}) error {
+	v := reflect.Indirect(reflect.ValueOf(value))
+	if len(dest) == 1 {
+		if target := reflect.ValueOf(dest[0]).Elem(); v.Type().AssignableTo(target.Type()) {
+			target.Set(v)
+			return nil
+		}
+	}
+	if v.Kind() != reflect.Struct || v.NumField() != len(dest) {
+		return fmt.Errorf("dbtest: %T does not have %d columns", value, len(dest))
+	}
+	for i, d := range dest {
+		target := reflect.ValueOf(d).Elem()
+		if !v.Field(i).Type().AssignableTo(target.Type()) {
+			return fmt.Errorf("dbtest: column %d of %T is %s, scanned into %s", i, value, v.Field(i).Type(), target.Type())
+		}
+		target.Set(v.Field(i))
+	}
+	return nil
+}
+
+// rows is a result set for Query.
+type rows struct {
+	values []interface{}
+	next   int
+	err    error
+}
+
+func (r *rows) Close()                                       {}
+func (r *rows) Err() error                                   { return r.err }
+func (r *rows) CommandTag() pgconn.CommandTag                { return pgconn.CommandTag{} }
+func (r *rows) FieldDescriptions() []pgconn.FieldDescription { return nil }
+func (r *rows) RawValues() [][]byte                          { return nil }
+func (r *rows) Conn() *pgx.Conn                              { return nil }
+
+func (r *rows) Next() bool {
+	if r.next >= len(r.values) {
+		return false
+	}
+	r.next++
+	return true
+}
+
+func (r *rows) Scan(dest ...interface{}) error {
+	if err := scan(r.values[r.next-1], dest); err != nil {
+		r.err = err
+		return err
+	}
+	return nil
+}
+
+func (r *rows) Values() ([]interface{}, error) {
+	return nil, fmt.Errorf("dbtest: Values is not supported")
+}
@@ -0,0 +1,38 @@
+package dbtest
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Begin starts a fake transaction, so that database.Queries.InTx runs on
+// the database. Queries in the transaction are recorded and answered as the
+// database's are, and rolling it back undoes nothing.
+func (db *DB) Begin(ctx context.Context) (pgx.Tx, error) {
+	return tx{db}, nil
+}
+
+// tx is a fake transaction on a DB.
+type tx struct {
+	*DB
+}
+
+func (tx) Commit(ctx context.Context) error   { return nil }
+func (tx) Rollback(ctx context.Context) error { return nil }
+func (tx) LargeObjects() pgx.LargeObjects     { return pgx.LargeObjects{} }
+func (tx) Conn() *pgx.Conn                    { return nil }
+
+func (tx) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
+	return 0, fmt.Errorf("dbtest: CopyFrom is not supported")
+}
+
+func (tx) SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults {
+	panic("dbtest: SendBatch is not supported")
+}
+
+func (tx) Prepare(ctx context.Context, name, sql string) (*pgconn.StatementDescription, error) {
+	return nil, fmt.Errorf("dbtest: Prepare is not supported")
+}
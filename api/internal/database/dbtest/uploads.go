@@ -0,0 +1,123 @@
+package dbtest
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/einarsundgren/sikta/internal/database"
+)
+
+// Uploads is an in-memory resumable_uploads table.
+type Uploads struct {
+	mu   sync.Mutex
+	rows map[pgtype.UUID]*database.ResumableUpload
+}
+
+// ResumableUploads answers db's resumable upload queries from a new
+// in-memory table, which it returns.
+func ResumableUploads(db *DB) *Uploads {
+	u := &Uploads{rows: make(map[pgtype.UUID]*database.ResumableUpload)}
+	db.On("CreateResumableUpload", u.create)
+	db.On("GetResumableUpload", u.get)
+	db.On("AppendResumableUploadPart", u.appendPart)
+	db.On("FinishResumableUpload", u.finish)
+	db.On("DeleteResumableUpload", u.delete)
+	db.On("ExpireResumableUploads", u.expire)
+	return u
+}
+
+// Backdate moves every upload's creation back by d.
+func (u *Uploads) Backdate(d time.Duration) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	for _, row := range u.rows {
+		row.CreatedAt.Time = row.CreatedAt.Time.Add(-d)
+	}
+}
+
+// copyRow returns a copy of row that later changes to the table leave alone.
+func copyRow(row *database.ResumableUpload) *database.ResumableUpload {
+	c := *row
+	c.Parts = append([]string{}, row.Parts...)
+	return &c
+}
+
+func (u *Uploads) create(args []interface{}) (interface{}, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	row := &database.ResumableUpload{
+		ID:        pgtype.UUID{Bytes: uuid.New(), Valid: true},
+		Filename:  args[0].(string),
+		Length:    args[1].(int64),
+		Metadata:  args[2].([]byte),
+		CreatedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	}
+	u.rows[row.ID] = row
+	return copyRow(row), nil
+}
+
+func (u *Uploads) get(args []interface{}) (interface{}, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	row := u.rows[args[0].(pgtype.UUID)]
+	if row == nil {
+		return nil, nil
+	}
+	return copyRow(row), nil
+}
+
+// appendPart records a part only if the upload is still at the part's
+// offset, as the conditional UPDATE does.
+func (u *Uploads) appendPart(args []interface{}) (interface{}, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	received, part, id, offset := args[0].(int64), args[1].(string), args[2].(pgtype.UUID), args[3].(int64)
+	row := u.rows[id]
+	if row == nil || row.UploadOffset != offset || row.DocumentID.Valid {
+		return nil, nil
+	}
+	row.UploadOffset += received
+	row.Parts = append(row.Parts, part)
+	return copyRow(row), nil
+}
+
+func (u *Uploads) finish(args []interface{}) (interface{}, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	row := u.rows[args[0].(pgtype.UUID)]
+	if row == nil {
+		return nil, nil
+	}
+	row.DocumentID = args[1].(pgtype.UUID)
+	row.Parts = nil
+	return copyRow(row), nil
+}
+
+func (u *Uploads) delete(args []interface{}) (interface{}, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	id := args[0].(pgtype.UUID)
+	row := u.rows[id]
+	if row == nil {
+		return nil, nil
+	}
+	delete(u.rows, id)
+	return row, nil
+}
+
+func (u *Uploads) expire(args []interface{}) (interface{}, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	cutoff := args[0].(pgtype.Timestamptz).Time
+	var expired []*database.ResumableUpload
+	for id, row := range u.rows {
+		if row.CreatedAt.Time.Before(cutoff) {
+			expired = append(expired, row)
+			delete(u.rows, id)
+		}
+	}
+	return expired, nil
+}
@@ -0,0 +1,28 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// QueryJSON runs a query built at run time, such as a compiled graph query,
+// whose single column is a JSON value, and returns the value of each row.
+func (q *Queries) QueryJSON(ctx context.Context, sql string, args ...interface{}) ([]json.RawMessage, error) {
+	rows, err := q.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []json.RawMessage{}
+	for rows.Next() {
+		var i []byte
+		if err := rows.Scan(&i); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -132,6 +132,40 @@ func (q *Queries) ListEdges(ctx context.Context, limit int32) ([]*Edge, error) {
 	return items, nil
 }
 
+const listEdgesByIDs = `-- name: ListEdgesByIDs :many
+SELECT id, edge_type, source_node, target_node, properties, is_negated, created_at, updated_at FROM edges
+WHERE id = ANY($1::uuid[])
+`
+
+func (q *Queries) ListEdgesByIDs(ctx context.Context, ids []pgtype.UUID) ([]*Edge, error) {
+	rows, err := q.db.Query(ctx, listEdgesByIDs, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*Edge{}
+	for rows.Next() {
+		var i Edge
+		if err := rows.Scan(
+			&i.ID,
+			&i.EdgeType,
+			&i.SourceNode,
+			&i.TargetNode,
+			&i.Properties,
+			&i.IsNegated,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listEdgesByNodes = `-- name: ListEdgesByNodes :many
 SELECT id, edge_type, source_node, target_node, properties, is_negated, created_at, updated_at FROM edges
 WHERE (source_node = $1 AND target_node = $2)
@@ -0,0 +1,319 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// FieldCipher encrypts and decrypts sensitive text columns. aad is the
+// additional data a value is bound to; decrypting needs the same aad.
+// DecryptString returns values it did not encrypt unchanged.
+type FieldCipher interface {
+	EncryptString(ctx context.Context, s string, aad []byte) (string, error)
+	DecryptString(ctx context.Context, s string, aad []byte) (string, error)
+}
+
+// encryptedColumn is a column holding sensitive text.
+type encryptedColumn struct {
+	table, column string
+}
+
+var (
+	chunkContent      = encryptedColumn{"chunks", "content"}
+	provenanceExcerpt = encryptedColumn{"provenance", "excerpt"}
+	referenceExcerpt  = encryptedColumn{"source_references", "excerpt"}
+
+	encryptedColumns = []encryptedColumn{chunkContent, provenanceExcerpt, referenceExcerpt}
+)
+
+// fieldAAD binds an encrypted value to its table, column and row, so that a
+// value copied to another row or column fails to decrypt.
+func fieldAAD(col encryptedColumn, id pgtype.UUID) []byte {
+	return []byte(col.table + "." + col.column + "/" + UUIDStr(id))
+}
+
+// encryptedArg is a query argument holding sensitive text: its column, its
+// index and the index of the argument holding the row's ID.
+type encryptedArg struct {
+	column encryptedColumn
+	arg    int
+	id     int
+}
+
+// encryptedArgs lists, by query name, the arguments that hold sensitive
+// text: chunk content and provenance and source reference excerpts.
+// TestEncryptedArgs checks the indexes against the generated queries.
+var encryptedArgs = map[string][]encryptedArg{
+	"CreateChunk":           {{chunkContent, 3, 0}},
+	"CreateProvenance":      {{provenanceExcerpt, 4, 0}},
+	"CreateSourceReference": {{referenceExcerpt, 5, 0}},
+	"RestoreChunk":          {{chunkContent, 3, 0}},
+	"RestoreProvenance":     {{provenanceExcerpt, 4, 0}},
+	"UpdateProvenance":      {{provenanceExcerpt, 1, 0}},
+}
+
+// NewEncrypted returns Queries that encrypt chunk content and excerpts as
+// they are written, and decrypt them as they are read, so callers only ever
+// see plaintext. A nil cipher gives plain Queries.
+func NewEncrypted(db DBTX, fields FieldCipher) *Queries {
+	if fields == nil {
+		return New(db)
+	}
+	return New(&encryptedDB{db: db, fields: fields, columns: &columnSet{}})
+}
+
+type encryptedDB struct {
+	db      DBTX
+	fields  FieldCipher
+	columns *columnSet
+}
+
+func (e *encryptedDB) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	args, err := e.encryptArgs(ctx, sql, args)
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	return e.db.Exec(ctx, sql, args...)
+}
+
+func (e *encryptedDB) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	args, err := e.encryptArgs(ctx, sql, args)
+	if err != nil {
+		return nil, err
+	}
+	// The catalog is read before the query, while the connection is free
+	if err := e.columns.load(ctx, e.db); err != nil {
+		return nil, err
+	}
+	rows, err := e.db.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	return &decryptedRows{Rows: rows, ctx: ctx, fields: e.fields, columns: e.columns}, nil
+}
+
+// QueryRow goes through Query, since decrypting needs the result's field
+// descriptions, which pgx.Row does not give.
+func (e *encryptedDB) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	rows, err := e.Query(ctx, sql, args...)
+	if err != nil {
+		return errRow{err}
+	}
+	return &decryptedRow{rows: rows}
+}
+
+// encryptArgs returns args with the sensitive ones encrypted, going by the
+// "-- name: " comment that starts every generated query.
+func (e *encryptedDB) encryptArgs(ctx context.Context, sql string, args []interface{}) ([]interface{}, error) {
+	name, ok := strings.CutPrefix(sql, "-- name: ")
+	if !ok {
+		return args, nil
+	}
+	name, _, _ = strings.Cut(name, " ")
+	encrypted := encryptedArgs[name]
+	if len(encrypted) == 0 {
+		return args, nil
+	}
+
+	args = append([]interface{}(nil), args...)
+	for _, ea := range encrypted {
+		s, ok := args[ea.arg].(string)
+		if !ok {
+			continue
+		}
+		id, ok := args[ea.id].(pgtype.UUID)
+		if !ok || !id.Valid {
+			return nil, fmt.Errorf("%s: %s.%s needs the row ID to be encrypted", name, ea.column.table, ea.column.column)
+		}
+		enc, err := e.fields.EncryptString(ctx, s, fieldAAD(ea.column, id))
+		if err != nil {
+			return nil, err
+		}
+		args[ea.arg] = enc
+	}
+	return args, nil
+}
+
+// columnSet finds the encrypted columns in query results by table OID and
+// attribute number, which stay the same through aliases, joins and
+// subqueries. It is read from the catalog on first use.
+type columnSet struct {
+	mu      sync.Mutex
+	loaded  bool
+	columns map[columnRef]encryptedColumn
+	ids     map[uint32]uint16 // table OID -> attribute number of id
+}
+
+type columnRef struct {
+	table  uint32
+	attnum uint16
+}
+
+const encryptedColumnsQuery = `SELECT c.oid, c.relname, a.attname, a.attnum
+FROM pg_class c
+JOIN pg_attribute a ON a.attrelid = c.oid
+WHERE c.oid = ANY($1::regclass[])
+  AND a.attname = ANY($2::text[])
+  AND NOT a.attisdropped`
+
+func (cs *columnSet) load(ctx context.Context, db DBTX) error {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	if cs.loaded {
+		return nil
+	}
+
+	var tables []string
+	names := []string{"id"}
+	for _, col := range encryptedColumns {
+		tables = append(tables, col.table)
+		names = append(names, col.column)
+	}
+	rows, err := db.Query(ctx, encryptedColumnsQuery, tables, names)
+	if err != nil {
+		return fmt.Errorf("failed to look up encrypted columns: %w", err)
+	}
+	defer rows.Close()
+
+	columns := make(map[columnRef]encryptedColumn)
+	ids := make(map[uint32]uint16)
+	for rows.Next() {
+		var oid uint32
+		var table, column string
+		var attnum int16
+		if err := rows.Scan(&oid, &table, &column, &attnum); err != nil {
+			return fmt.Errorf("failed to look up encrypted columns: %w", err)
+		}
+		if column == "id" {
+			ids[oid] = uint16(attnum)
+			continue
+		}
+		for _, col := range encryptedColumns {
+			if col.table == table && col.column == column {
+				columns[columnRef{oid, uint16(attnum)}] = col
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to look up encrypted columns: %w", err)
+	}
+	cs.columns, cs.ids, cs.loaded = columns, ids, true
+	return nil
+}
+
+// decryptField is an encrypted column in a result: its index and the index
+// of its row's ID, or -1 if the ID was not selected.
+type decryptField struct {
+	column encryptedColumn
+	index  int
+	id     int
+}
+
+// plan returns the encrypted columns among fields.
+func (cs *columnSet) plan(fields []pgconn.FieldDescription) []decryptField {
+	var plan []decryptField
+	for i, f := range fields {
+		col, ok := cs.columns[columnRef{f.TableOID, f.TableAttributeNumber}]
+		if !ok {
+			continue
+		}
+		df := decryptField{column: col, index: i, id: -1}
+		for j, g := range fields {
+			if g.TableOID == f.TableOID && g.TableAttributeNumber == cs.ids[f.TableOID] {
+				df.id = j
+				break
+			}
+		}
+		plan = append(plan, df)
+	}
+	return plan
+}
+
+type decryptedRows struct {
+	pgx.Rows
+	ctx     context.Context
+	fields  FieldCipher
+	columns *columnSet
+
+	plan    []decryptField
+	planned bool
+}
+
+func (r *decryptedRows) Scan(dest ...interface{}) error {
+	if err := r.Rows.Scan(dest...); err != nil {
+		return err
+	}
+	if !r.planned {
+		r.plan = r.columns.plan(r.FieldDescriptions())
+		r.planned = true
+	}
+	return decryptDest(r.ctx, r.fields, r.plan, dest)
+}
+
+// decryptedRow reads the first row of rows, as pgx does for QueryRow.
+type decryptedRow struct {
+	rows pgx.Rows
+}
+
+func (r *decryptedRow) Scan(dest ...interface{}) error {
+	defer r.rows.Close()
+	if !r.rows.Next() {
+		if err := r.rows.Err(); err != nil {
+			return err
+		}
+		return pgx.ErrNoRows
+	}
+	if err := r.rows.Scan(dest...); err != nil {
+		return err
+	}
+	r.rows.Close()
+	return r.rows.Err()
+}
+
+type errRow struct{ err error }
+
+func (r errRow) Scan(dest ...interface{}) error { return r.err }
+
+// decryptDest decrypts the encrypted columns in plan, which a row was
+// scanned into. Other columns are left alone.
+func decryptDest(ctx context.Context, fields FieldCipher, plan []decryptField, dest []interface{}) error {
+	for _, df := range plan {
+		if df.index >= len(dest) {
+			continue
+		}
+		var text *string
+		switch v := dest[df.index].(type) {
+		case *string:
+			text = v
+		case *pgtype.Text:
+			if v.Valid {
+				text = &v.String
+			}
+		}
+		if text == nil || *text == "" {
+			continue
+		}
+
+		var id pgtype.UUID
+		if df.id >= 0 && df.id < len(dest) {
+			if v, ok := dest[df.id].(*pgtype.UUID); ok {
+				id = *v
+			}
+		}
+		if !id.Valid {
+			return fmt.Errorf("failed to decrypt %s.%s: row ID not selected", df.column.table, df.column.column)
+		}
+		plain, err := fields.DecryptString(ctx, *text, fieldAAD(df.column, id))
+		if err != nil {
+			return err
+		}
+		*text = plain
+	}
+	return nil
+}
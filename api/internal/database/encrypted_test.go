@@ -0,0 +1,191 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var errRecorded = errors.New("recorded")
+
+// recordDB records the last statement sent to it and runs nothing.
+type recordDB struct {
+	sql  string
+	args []interface{}
+}
+
+func (r *recordDB) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	r.sql, r.args = sql, args
+	return pgconn.CommandTag{}, nil
+}
+
+func (r *recordDB) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	r.sql, r.args = sql, args
+	return nil, errRecorded
+}
+
+func (r *recordDB) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	r.sql, r.args = sql, args
+	return errRow{errRecorded}
+}
+
+// testCipher "encrypts" by prefixing the aad, so tests can see what a value
+// was bound to.
+type testCipher struct{}
+
+func (testCipher) EncryptString(ctx context.Context, s string, aad []byte) (string, error) {
+	return "enc(" + string(aad) + "):" + s, nil
+}
+
+func (testCipher) DecryptString(ctx context.Context, s string, aad []byte) (string, error) {
+	if !strings.HasPrefix(s, "enc(") {
+		return s, nil
+	}
+	plain, ok := strings.CutPrefix(s, "enc("+string(aad)+"):")
+	if !ok {
+		return "", errors.New("aad mismatch")
+	}
+	return plain, nil
+}
+
+// TestEncryptedArgs calls each query in encryptedArgs with every text field
+// set to its own name and every UUID field to its own value, and checks that
+// the listed indexes hold the encrypted column and the row ID.
+func TestEncryptedArgs(t *testing.T) {
+	ctx := context.Background()
+	for name, encrypted := range encryptedArgs {
+		t.Run(name, func(t *testing.T) {
+			db := &recordDB{}
+			method := reflect.ValueOf(New(db)).MethodByName(name)
+			if !method.IsValid() {
+				t.Fatalf("Queries has no method %s", name)
+			}
+
+			params := reflect.New(method.Type().In(1)).Elem()
+			uuids := make(map[string]pgtype.UUID)
+			for i := 0; i < params.NumField(); i++ {
+				field := params.Type().Field(i)
+				switch params.Field(i).Interface().(type) {
+				case string:
+					params.Field(i).SetString(field.Name)
+				case pgtype.Text:
+					params.Field(i).Set(reflect.ValueOf(PgText(field.Name)))
+				case pgtype.UUID:
+					id := PgUUID(uuid.New())
+					uuids[field.Name] = id
+					params.Field(i).Set(reflect.ValueOf(id))
+				}
+			}
+			method.Call([]reflect.Value{reflect.ValueOf(ctx), params})
+
+			if !strings.HasPrefix(db.sql, "-- name: "+name+" ") {
+				t.Fatalf("%s ran %q", name, db.sql)
+			}
+			for _, ea := range encrypted {
+				field := strings.ToUpper(ea.column.column[:1]) + ea.column.column[1:]
+				if ea.arg >= len(db.args) || db.args[ea.arg] != field {
+					t.Errorf("argument %d is not %s", ea.arg, field)
+				}
+				if ea.id >= len(db.args) || db.args[ea.id] != uuids["ID"] {
+					t.Errorf("argument %d is not ID", ea.id)
+				}
+			}
+
+			if t.Failed() {
+				return
+			}
+
+			e := &encryptedDB{db: db, fields: testCipher{}, columns: &columnSet{}}
+			args, err := e.encryptArgs(ctx, db.sql, db.args)
+			if err != nil {
+				t.Fatalf("encryptArgs() error = %v", err)
+			}
+			for _, ea := range encrypted {
+				want := "enc(" + ea.column.table + "." + ea.column.column + "/" + UUIDStr(uuids["ID"]) + "):" + db.args[ea.arg].(string)
+				if args[ea.arg] != want {
+					t.Errorf("argument %d = %q, want %q", ea.arg, args[ea.arg], want)
+				}
+			}
+		})
+	}
+}
+
+func TestEncryptArgsNeedsRowID(t *testing.T) {
+	db := &recordDB{}
+	New(db).CreateChunk(context.Background(), CreateChunkParams{Content: "text"})
+
+	e := &encryptedDB{db: db, fields: testCipher{}, columns: &columnSet{}}
+	if _, err := e.encryptArgs(context.Background(), db.sql, db.args); err == nil {
+		t.Error("encryptArgs() without a row ID succeeded")
+	}
+}
+
+func TestDecryptDest(t *testing.T) {
+	ctx := context.Background()
+	columns := &columnSet{
+		loaded: true,
+		columns: map[columnRef]encryptedColumn{
+			{100, 6}: referenceExcerpt,
+			{200, 4}: chunkContent,
+		},
+		ids: map[uint32]uint16{100: 1, 200: 1},
+	}
+	// As ListSourceReferencesByClaim, plus a column of an unencrypted table
+	fields := []pgconn.FieldDescription{
+		{Name: "id", TableOID: 100, TableAttributeNumber: 1},
+		{Name: "excerpt", TableOID: 100, TableAttributeNumber: 6},
+		{Name: "chapter_title", TableOID: 200, TableAttributeNumber: 5},
+		{Name: "content", TableOID: 300, TableAttributeNumber: 4},
+	}
+	plan := columns.plan(fields)
+	if len(plan) != 1 || plan[0].index != 1 || plan[0].id != 0 {
+		t.Fatalf("plan() = %+v", plan)
+	}
+
+	id := PgUUID(uuid.New())
+	other := PgUUID(uuid.New())
+	encrypt := func(id pgtype.UUID, s string) string {
+		enc, _ := testCipher{}.EncryptString(ctx, s, fieldAAD(referenceExcerpt, id))
+		return enc
+	}
+
+	t.Run("decrypts listed columns only", func(t *testing.T) {
+		rowID, excerpt := id, encrypt(id, "the excerpt")
+		title := PgText(encrypt(id, "not a listed column"))
+		content := encrypt(id, "other table")
+		dest := []interface{}{&rowID, &excerpt, &title, &content}
+		if err := decryptDest(ctx, testCipher{}, plan, dest); err != nil {
+			t.Fatal(err)
+		}
+		if excerpt != "the excerpt" {
+			t.Errorf("excerpt = %q", excerpt)
+		}
+		if title.String != encrypt(id, "not a listed column") || content != encrypt(id, "other table") {
+			t.Errorf("unlisted columns were changed: %q, %q", title.String, content)
+		}
+	})
+
+	t.Run("value moved to another row", func(t *testing.T) {
+		rowID, excerpt := other, encrypt(id, "the excerpt")
+		var title pgtype.Text
+		var content string
+		if err := decryptDest(ctx, testCipher{}, plan, []interface{}{&rowID, &excerpt, &title, &content}); err == nil {
+			t.Error("decryptDest() succeeded for a value from another row")
+		}
+	})
+
+	t.Run("row ID not selected", func(t *testing.T) {
+		plan := columns.plan(fields[1:])
+		excerpt := encrypt(id, "the excerpt")
+		if err := decryptDest(ctx, testCipher{}, plan, []interface{}{&excerpt, new(pgtype.Text), new(string)}); err == nil {
+			t.Error("decryptDest() succeeded without the row ID")
+		}
+	})
+}
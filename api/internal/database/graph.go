@@ -24,6 +24,7 @@ const (
 	NodeTypeValue         = "value"
 	NodeTypeInconsistency = "inconsistency"
 	NodeTypeReviewAction  = "review_action"
+	NodeTypeMessage       = "message"
 )
 
 // Edge type constants — open strings, not a closed enum.
@@ -39,6 +40,9 @@ const (
 	EdgeTypeApproves    = "approves"
 	EdgeTypeRejects     = "rejects"
 	EdgeTypeHasValue    = "has_value"
+	EdgeTypeSentBy      = "sent_by"
+	EdgeTypeSentTo      = "sent_to"
+	EdgeTypeRepliesTo   = "replies_to"
 )
 
 // Modality constants — open strings, not a closed enum.
@@ -76,14 +80,18 @@ type EdgeWithProvenance struct {
 
 // Location represents where in a source something was found
 type Location struct {
-	Page         int    `json:"page,omitempty"`
-	Section      string `json:"section,omitempty"`
-	Chapter      string `json:"chapter,omitempty"`
-	Paragraph    int    `json:"paragraph,omitempty"`
-	CharStart    int    `json:"char_start,omitempty"`
-	CharEnd      int    `json:"char_end,omitempty"`
-	PositionType string `json:"position_type,omitempty"` // "narrative" or "chronological"
-	Position     int    `json:"position,omitempty"`
+	Page         int       `json:"page,omitempty"`
+	Section      string    `json:"section,omitempty"`
+	Chapter      string    `json:"chapter,omitempty"`
+	Paragraph    int       `json:"paragraph,omitempty"`
+	CharStart    int       `json:"char_start,omitempty"`
+	CharEnd      int       `json:"char_end,omitempty"`
+	PositionType string    `json:"position_type,omitempty"` // "narrative" or "chronological"
+	Position     int       `json:"position,omitempty"`
+	Sheet        string    `json:"sheet,omitempty"`  // tabular sources
+	Row          int       `json:"row,omitempty"`    // 1-based spreadsheet or table row
+	Column       string    `json:"column,omitempty"` // header of the source column
+	BBox         []float64 `json:"bbox,omitempty"`   // PDF table cell [x0 y0 x1 y1], points from the page's top-left
 }
 
 // Properties helper methods for Node
@@ -132,6 +140,37 @@ func (e *Edge) GetProperty(key string, defaultValue interface{}) interface{} {
 	return defaultValue
 }
 
+// Metadata helper methods for Chunk
+
+// GetMetadata retrieves a chunk metadata value by key
+func (c *Chunk) GetMetadata(key string, defaultValue interface{}) interface{} {
+	if c.Metadata == nil {
+		return defaultValue
+	}
+	var result map[string]interface{}
+	if err := json.Unmarshal(c.Metadata, &result); err != nil {
+		return defaultValue
+	}
+	if val, ok := result[key]; ok {
+		return val
+	}
+	return defaultValue
+}
+
+// ReferenceDate returns the date the chunk's text was written, if known (e.g.
+// an email's Date header). Relative times in the text resolve against it.
+func (c *Chunk) ReferenceDate() (time.Time, bool) {
+	s, ok := c.GetMetadata("date", "").(string)
+	if !ok || s == "" {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
 // Location helper methods for Provenance
 
 // GetLocation parses the location JSONB into a Location struct
@@ -14,6 +14,7 @@ type Project struct {
 	Description pgtype.Text        `json:"description"`
 	CreatedAt   pgtype.Timestamptz `json:"created_at"`
 	UpdatedAt   pgtype.Timestamptz `json:"updated_at"`
+	AutoExtract bool               `json:"auto_extract"`
 }
 
 type Chunk struct {
@@ -28,6 +29,9 @@ type Chunk struct {
 	NarrativePosition int32              `json:"narrative_position"`
 	WordCount         pgtype.Int4        `json:"word_count"`
 	CreatedAt         pgtype.Timestamptz `json:"created_at"`
+	SectionID         pgtype.Text        `json:"section_id"`
+	SectionName       pgtype.Text        `json:"section_name"`
+	Metadata          []byte             `json:"metadata"`
 }
 
 type Claim struct {
@@ -118,24 +122,25 @@ type Node struct {
 }
 
 type Provenance struct {
-	ID               pgtype.UUID        `json:"id"`
-	TargetType       string             `json:"target_type"`
-	TargetID         pgtype.UUID        `json:"target_id"`
-	SourceID         pgtype.UUID        `json:"source_id"`
-	Excerpt          string             `json:"excerpt"`
-	Location         []byte             `json:"location"`
-	Confidence       float32            `json:"confidence"`
-	Trust            float32            `json:"trust"`
-	Status           string             `json:"status"`
-	Modality         string             `json:"modality"`
-	ClaimedTimeStart pgtype.Timestamptz `json:"claimed_time_start"`
-	ClaimedTimeEnd   pgtype.Timestamptz `json:"claimed_time_end"`
-	ClaimedTimeText  pgtype.Text        `json:"claimed_time_text"`
-	ClaimedGeoRegion pgtype.Text        `json:"claimed_geo_region"`
-	ClaimedGeoText   pgtype.Text        `json:"claimed_geo_text"`
-	ClaimedBy        pgtype.UUID        `json:"claimed_by"`
-	CreatedAt        pgtype.Timestamptz `json:"created_at"`
-	UpdatedAt        pgtype.Timestamptz `json:"updated_at"`
+	ID                pgtype.UUID        `json:"id"`
+	TargetType        string             `json:"target_type"`
+	TargetID          pgtype.UUID        `json:"target_id"`
+	SourceID          pgtype.UUID        `json:"source_id"`
+	Excerpt           string             `json:"excerpt"`
+	Location          []byte             `json:"location"`
+	Confidence        float32            `json:"confidence"`
+	Trust             float32            `json:"trust"`
+	Status            string             `json:"status"`
+	Modality          string             `json:"modality"`
+	ClaimedTimeStart  pgtype.Timestamptz `json:"claimed_time_start"`
+	ClaimedTimeEnd    pgtype.Timestamptz `json:"claimed_time_end"`
+	ClaimedTimeText   pgtype.Text        `json:"claimed_time_text"`
+	ClaimedGeoRegion  pgtype.Text        `json:"claimed_geo_region"`
+	ClaimedGeoText    pgtype.Text        `json:"claimed_geo_text"`
+	ClaimedBy         pgtype.UUID        `json:"claimed_by"`
+	CreatedAt         pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt         pgtype.Timestamptz `json:"updated_at"`
+	ClaimedProperties []byte             `json:"claimed_properties"`
 }
 
 type Relationship struct {
@@ -161,21 +166,26 @@ type SchemaVersion struct {
 }
 
 type Source struct {
-	ID           pgtype.UUID        `json:"id"`
-	Title        string             `json:"title"`
-	Filename     string             `json:"filename"`
-	FilePath     string             `json:"file_path"`
-	FileType     string             `json:"file_type"`
-	TotalPages   pgtype.Int4        `json:"total_pages"`
-	UploadStatus string             `json:"upload_status"`
-	ErrorMessage pgtype.Text        `json:"error_message"`
-	IsDemo       bool               `json:"is_demo"`
-	Metadata     []byte             `json:"metadata"`
-	CreatedAt    pgtype.Timestamptz `json:"created_at"`
-	UpdatedAt    pgtype.Timestamptz `json:"updated_at"`
-	SourceTrust  pgtype.Float4      `json:"source_trust"`
-	TrustReason  pgtype.Text        `json:"trust_reason"`
-	ProjectID    pgtype.UUID        `json:"project_id"`
+	ID                pgtype.UUID        `json:"id"`
+	Title             string             `json:"title"`
+	Filename          string             `json:"filename"`
+	FilePath          string             `json:"file_path"`
+	FileType          string             `json:"file_type"`
+	TotalPages        pgtype.Int4        `json:"total_pages"`
+	UploadStatus      string             `json:"upload_status"`
+	ErrorMessage      pgtype.Text        `json:"error_message"`
+	IsDemo            bool               `json:"is_demo"`
+	Metadata          []byte             `json:"metadata"`
+	CreatedAt         pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt         pgtype.Timestamptz `json:"updated_at"`
+	SourceTrust       pgtype.Float4      `json:"source_trust"`
+	TrustReason       pgtype.Text        `json:"trust_reason"`
+	ProjectID         pgtype.UUID        `json:"project_id"`
+	ContentHash       pgtype.Text        `json:"content_hash"`
+	PreviousVersionID pgtype.UUID        `json:"previous_version_id"`
+	Version           int32              `json:"version"`
+	ClaimedAt         pgtype.Timestamptz `json:"claimed_at"`
+	ExtractionStatus  pgtype.Text        `json:"extraction_status"`
 }
 
 type SourceReference struct {
@@ -189,3 +199,26 @@ type SourceReference struct {
 	CharEnd        pgtype.Int4        `json:"char_end"`
 	CreatedAt      pgtype.Timestamptz `json:"created_at"`
 }
+
+type IdentityCluster struct {
+	ProjectID pgtype.UUID `json:"project_id"`
+	NodeID    pgtype.UUID `json:"node_id"`
+	ClusterID pgtype.UUID `json:"cluster_id"`
+}
+
+type IdentityClusterState struct {
+	ProjectID  pgtype.UUID        `json:"project_id"`
+	Stale      bool               `json:"stale"`
+	ComputedAt pgtype.Timestamptz `json:"computed_at"`
+}
+
+type ResumableUpload struct {
+	ID           pgtype.UUID        `json:"id"`
+	Filename     string             `json:"filename"`
+	Length       int64              `json:"length"`
+	UploadOffset int64              `json:"upload_offset"`
+	Metadata     []byte             `json:"metadata"`
+	Parts        []string           `json:"parts"`
+	DocumentID   pgtype.UUID        `json:"document_id"`
+	CreatedAt    pgtype.Timestamptz `json:"created_at"`
+}
@@ -139,6 +139,38 @@ func (q *Queries) ListNodes(ctx context.Context, limit int32) ([]*Node, error) {
 	return items, nil
 }
 
+const listNodesByIDs = `-- name: ListNodesByIDs :many
+SELECT id, node_type, label, properties, created_at, updated_at FROM nodes
+WHERE id = ANY($1::uuid[])
+`
+
+func (q *Queries) ListNodesByIDs(ctx context.Context, ids []pgtype.UUID) ([]*Node, error) {
+	rows, err := q.db.Query(ctx, listNodesByIDs, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*Node{}
+	for rows.Next() {
+		var i Node
+		if err := rows.Scan(
+			&i.ID,
+			&i.NodeType,
+			&i.Label,
+			&i.Properties,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listNodesBySource = `-- name: ListNodesBySource :many
 SELECT DISTINCT n.id, n.node_type, n.label, n.properties, n.created_at, n.updated_at
 FROM nodes n
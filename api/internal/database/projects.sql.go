@@ -12,18 +12,19 @@ import (
 )
 
 const createProject = `-- name: CreateProject :one
-INSERT INTO projects (title, description)
-VALUES ($1, $2)
-RETURNING id, title, description, created_at, updated_at
+INSERT INTO projects (title, description, auto_extract)
+VALUES ($1, $2, $3)
+RETURNING id, title, description, created_at, updated_at, auto_extract
 `
 
 type CreateProjectParams struct {
 	Title       string      `json:"title"`
 	Description pgtype.Text `json:"description"`
+	AutoExtract bool        `json:"auto_extract"`
 }
 
 func (q *Queries) CreateProject(ctx context.Context, arg CreateProjectParams) (*Project, error) {
-	row := q.db.QueryRow(ctx, createProject, arg.Title, arg.Description)
+	row := q.db.QueryRow(ctx, createProject, arg.Title, arg.Description, arg.AutoExtract)
 	var i Project
 	err := row.Scan(
 		&i.ID,
@@ -31,6 +32,7 @@ func (q *Queries) CreateProject(ctx context.Context, arg CreateProjectParams) (*
 		&i.Description,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.AutoExtract,
 	)
 	return &i, err
 }
@@ -45,7 +47,7 @@ func (q *Queries) DeleteProject(ctx context.Context, id pgtype.UUID) error {
 }
 
 const getProject = `-- name: GetProject :one
-SELECT id, title, description, created_at, updated_at FROM projects WHERE id = $1
+SELECT id, title, description, created_at, updated_at, auto_extract FROM projects WHERE id = $1
 `
 
 func (q *Queries) GetProject(ctx context.Context, id pgtype.UUID) (*Project, error) {
@@ -57,12 +59,13 @@ func (q *Queries) GetProject(ctx context.Context, id pgtype.UUID) (*Project, err
 		&i.Description,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.AutoExtract,
 	)
 	return &i, err
 }
 
 const getProjectSources = `-- name: GetProjectSources :many
-SELECT id, title, filename, file_path, file_type, total_pages, upload_status, error_message, is_demo, metadata, created_at, updated_at, source_trust, trust_reason, project_id FROM sources WHERE project_id = $1 ORDER BY created_at DESC
+SELECT id, title, filename, file_path, file_type, total_pages, upload_status, error_message, is_demo, metadata, created_at, updated_at, source_trust, trust_reason, project_id, content_hash, previous_version_id, version, claimed_at, extraction_status FROM sources WHERE project_id = $1 ORDER BY created_at DESC
 `
 
 func (q *Queries) GetProjectSources(ctx context.Context, projectID pgtype.UUID) ([]*Source, error) {
@@ -90,6 +93,11 @@ func (q *Queries) GetProjectSources(ctx context.Context, projectID pgtype.UUID)
 			&i.SourceTrust,
 			&i.TrustReason,
 			&i.ProjectID,
+			&i.ContentHash,
+			&i.PreviousVersionID,
+			&i.Version,
+			&i.ClaimedAt,
+			&i.ExtractionStatus,
 		); err != nil {
 			return nil, err
 		}
@@ -126,7 +134,7 @@ func (q *Queries) GetProjectStats(ctx context.Context, projectID pgtype.UUID) (*
 }
 
 const listProjects = `-- name: ListProjects :many
-SELECT id, title, description, created_at, updated_at FROM projects ORDER BY created_at DESC
+SELECT id, title, description, created_at, updated_at, auto_extract FROM projects ORDER BY created_at DESC
 `
 
 func (q *Queries) ListProjects(ctx context.Context) ([]*Project, error) {
@@ -144,6 +152,7 @@ func (q *Queries) ListProjects(ctx context.Context) ([]*Project, error) {
 			&i.Description,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.AutoExtract,
 		); err != nil {
 			return nil, err
 		}
@@ -160,7 +169,7 @@ UPDATE sources
 SET project_id = $2,
     updated_at = NOW()
 WHERE id = $1
-RETURNING id, title, filename, file_path, file_type, total_pages, upload_status, error_message, is_demo, metadata, created_at, updated_at, source_trust, trust_reason, project_id
+RETURNING id, title, filename, file_path, file_type, total_pages, upload_status, error_message, is_demo, metadata, created_at, updated_at, source_trust, trust_reason, project_id, content_hash, previous_version_id, version, claimed_at, extraction_status
 `
 
 type SetSourceProjectParams struct {
@@ -187,6 +196,11 @@ func (q *Queries) SetSourceProject(ctx context.Context, arg SetSourceProjectPara
 		&i.SourceTrust,
 		&i.TrustReason,
 		&i.ProjectID,
+		&i.ContentHash,
+		&i.PreviousVersionID,
+		&i.Version,
+		&i.ClaimedAt,
+		&i.ExtractionStatus,
 	)
 	return &i, err
 }
@@ -195,19 +209,26 @@ const updateProject = `-- name: UpdateProject :one
 UPDATE projects
 SET title = $2,
     description = $3,
+    auto_extract = COALESCE($4, auto_extract),
     updated_at = NOW()
 WHERE id = $1
-RETURNING id, title, description, created_at, updated_at
+RETURNING id, title, description, created_at, updated_at, auto_extract
 `
 
 type UpdateProjectParams struct {
 	ID          pgtype.UUID `json:"id"`
 	Title       string      `json:"title"`
 	Description pgtype.Text `json:"description"`
+	AutoExtract pgtype.Bool `json:"auto_extract"`
 }
 
 func (q *Queries) UpdateProject(ctx context.Context, arg UpdateProjectParams) (*Project, error) {
-	row := q.db.QueryRow(ctx, updateProject, arg.ID, arg.Title, arg.Description)
+	row := q.db.QueryRow(ctx, updateProject,
+		arg.ID,
+		arg.Title,
+		arg.Description,
+		arg.AutoExtract,
+	)
 	var i Project
 	err := row.Scan(
 		&i.ID,
@@ -215,6 +236,7 @@ func (q *Queries) UpdateProject(ctx context.Context, arg UpdateProjectParams) (*
 		&i.Description,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.AutoExtract,
 	)
 	return &i, err
 }
@@ -123,35 +123,38 @@ func (q *Queries) CountProvenanceByStatus(ctx context.Context) (*CountProvenance
 
 const createProvenance = `-- name: CreateProvenance :one
 INSERT INTO provenance (
-    target_type, target_id, source_id, excerpt, location,
+    id, target_type, target_id, source_id, excerpt, location,
     confidence, trust, status, modality,
     claimed_time_start, claimed_time_end, claimed_time_text,
-    claimed_geo_region, claimed_geo_text, claimed_by
+    claimed_geo_region, claimed_geo_text, claimed_by, claimed_properties
 )
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
-RETURNING id, target_type, target_id, source_id, excerpt, location, confidence, trust, status, modality, claimed_time_start, claimed_time_end, claimed_time_text, claimed_geo_region, claimed_geo_text, claimed_by, created_at, updated_at
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
+RETURNING id, target_type, target_id, source_id, excerpt, location, confidence, trust, status, modality, claimed_time_start, claimed_time_end, claimed_time_text, claimed_geo_region, claimed_geo_text, claimed_by, created_at, updated_at, claimed_properties
 `
 
 type CreateProvenanceParams struct {
-	TargetType       string             `json:"target_type"`
-	TargetID         pgtype.UUID        `json:"target_id"`
-	SourceID         pgtype.UUID        `json:"source_id"`
-	Excerpt          string             `json:"excerpt"`
-	Location         []byte             `json:"location"`
-	Confidence       float32            `json:"confidence"`
-	Trust            float32            `json:"trust"`
-	Status           string             `json:"status"`
-	Modality         string             `json:"modality"`
-	ClaimedTimeStart pgtype.Timestamptz `json:"claimed_time_start"`
-	ClaimedTimeEnd   pgtype.Timestamptz `json:"claimed_time_end"`
-	ClaimedTimeText  pgtype.Text        `json:"claimed_time_text"`
-	ClaimedGeoRegion pgtype.Text        `json:"claimed_geo_region"`
-	ClaimedGeoText   pgtype.Text        `json:"claimed_geo_text"`
-	ClaimedBy        pgtype.UUID        `json:"claimed_by"`
+	ID                pgtype.UUID        `json:"id"`
+	TargetType        string             `json:"target_type"`
+	TargetID          pgtype.UUID        `json:"target_id"`
+	SourceID          pgtype.UUID        `json:"source_id"`
+	Excerpt           string             `json:"excerpt"`
+	Location          []byte             `json:"location"`
+	Confidence        float32            `json:"confidence"`
+	Trust             float32            `json:"trust"`
+	Status            string             `json:"status"`
+	Modality          string             `json:"modality"`
+	ClaimedTimeStart  pgtype.Timestamptz `json:"claimed_time_start"`
+	ClaimedTimeEnd    pgtype.Timestamptz `json:"claimed_time_end"`
+	ClaimedTimeText   pgtype.Text        `json:"claimed_time_text"`
+	ClaimedGeoRegion  pgtype.Text        `json:"claimed_geo_region"`
+	ClaimedGeoText    pgtype.Text        `json:"claimed_geo_text"`
+	ClaimedBy         pgtype.UUID        `json:"claimed_by"`
+	ClaimedProperties []byte             `json:"claimed_properties"`
 }
 
 func (q *Queries) CreateProvenance(ctx context.Context, arg CreateProvenanceParams) (*Provenance, error) {
 	row := q.db.QueryRow(ctx, createProvenance,
+		arg.ID,
 		arg.TargetType,
 		arg.TargetID,
 		arg.SourceID,
@@ -167,6 +170,7 @@ func (q *Queries) CreateProvenance(ctx context.Context, arg CreateProvenancePara
 		arg.ClaimedGeoRegion,
 		arg.ClaimedGeoText,
 		arg.ClaimedBy,
+		arg.ClaimedProperties,
 	)
 	var i Provenance
 	err := row.Scan(
@@ -188,6 +192,7 @@ func (q *Queries) CreateProvenance(ctx context.Context, arg CreateProvenancePara
 		&i.ClaimedBy,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ClaimedProperties,
 	)
 	return &i, err
 }
@@ -203,7 +208,7 @@ func (q *Queries) DeleteProvenance(ctx context.Context, id pgtype.UUID) error {
 }
 
 const getProvenance = `-- name: GetProvenance :one
-SELECT id, target_type, target_id, source_id, excerpt, location, confidence, trust, status, modality, claimed_time_start, claimed_time_end, claimed_time_text, claimed_geo_region, claimed_geo_text, claimed_by, created_at, updated_at FROM provenance
+SELECT id, target_type, target_id, source_id, excerpt, location, confidence, trust, status, modality, claimed_time_start, claimed_time_end, claimed_time_text, claimed_geo_region, claimed_geo_text, claimed_by, created_at, updated_at, claimed_properties FROM provenance
 WHERE id = $1
 `
 
@@ -229,12 +234,13 @@ func (q *Queries) GetProvenance(ctx context.Context, id pgtype.UUID) (*Provenanc
 		&i.ClaimedBy,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ClaimedProperties,
 	)
 	return &i, err
 }
 
 const listProvenanceBySource = `-- name: ListProvenanceBySource :many
-SELECT id, target_type, target_id, source_id, excerpt, location, confidence, trust, status, modality, claimed_time_start, claimed_time_end, claimed_time_text, claimed_geo_region, claimed_geo_text, claimed_by, created_at, updated_at FROM provenance
+SELECT id, target_type, target_id, source_id, excerpt, location, confidence, trust, status, modality, claimed_time_start, claimed_time_end, claimed_time_text, claimed_geo_region, claimed_geo_text, claimed_by, created_at, updated_at, claimed_properties FROM provenance
 WHERE source_id = $1
 ORDER BY created_at DESC
 `
@@ -267,6 +273,7 @@ func (q *Queries) ListProvenanceBySource(ctx context.Context, sourceID pgtype.UU
 			&i.ClaimedBy,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.ClaimedProperties,
 		); err != nil {
 			return nil, err
 		}
@@ -279,7 +286,7 @@ func (q *Queries) ListProvenanceBySource(ctx context.Context, sourceID pgtype.UU
 }
 
 const listProvenanceByStatus = `-- name: ListProvenanceByStatus :many
-SELECT id, target_type, target_id, source_id, excerpt, location, confidence, trust, status, modality, claimed_time_start, claimed_time_end, claimed_time_text, claimed_geo_region, claimed_geo_text, claimed_by, created_at, updated_at FROM provenance
+SELECT id, target_type, target_id, source_id, excerpt, location, confidence, trust, status, modality, claimed_time_start, claimed_time_end, claimed_time_text, claimed_geo_region, claimed_geo_text, claimed_by, created_at, updated_at, claimed_properties FROM provenance
 WHERE status = $1
 ORDER BY created_at DESC
 `
@@ -312,6 +319,7 @@ func (q *Queries) ListProvenanceByStatus(ctx context.Context, status string) ([]
 			&i.ClaimedBy,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.ClaimedProperties,
 		); err != nil {
 			return nil, err
 		}
@@ -324,7 +332,7 @@ func (q *Queries) ListProvenanceByStatus(ctx context.Context, status string) ([]
 }
 
 const listProvenanceByTarget = `-- name: ListProvenanceByTarget :many
-SELECT id, target_type, target_id, source_id, excerpt, location, confidence, trust, status, modality, claimed_time_start, claimed_time_end, claimed_time_text, claimed_geo_region, claimed_geo_text, claimed_by, created_at, updated_at FROM provenance
+SELECT id, target_type, target_id, source_id, excerpt, location, confidence, trust, status, modality, claimed_time_start, claimed_time_end, claimed_time_text, claimed_geo_region, claimed_geo_text, claimed_by, created_at, updated_at, claimed_properties FROM provenance
 WHERE target_type = $1 AND target_id = $2
 ORDER BY created_at DESC
 `
@@ -362,6 +370,7 @@ func (q *Queries) ListProvenanceByTarget(ctx context.Context, arg ListProvenance
 			&i.ClaimedBy,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.ClaimedProperties,
 		); err != nil {
 			return nil, err
 		}
@@ -387,7 +396,7 @@ SET excerpt = $2,
     claimed_geo_region = $11,
     claimed_geo_text = $12
 WHERE id = $1
-RETURNING id, target_type, target_id, source_id, excerpt, location, confidence, trust, status, modality, claimed_time_start, claimed_time_end, claimed_time_text, claimed_geo_region, claimed_geo_text, claimed_by, created_at, updated_at
+RETURNING id, target_type, target_id, source_id, excerpt, location, confidence, trust, status, modality, claimed_time_start, claimed_time_end, claimed_time_text, claimed_geo_region, claimed_geo_text, claimed_by, created_at, updated_at, claimed_properties
 `
 
 type UpdateProvenanceParams struct {
@@ -440,6 +449,7 @@ func (q *Queries) UpdateProvenance(ctx context.Context, arg UpdateProvenancePara
 		&i.ClaimedBy,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ClaimedProperties,
 	)
 	return &i, err
 }
@@ -448,7 +458,7 @@ const updateProvenanceStatus = `-- name: UpdateProvenanceStatus :one
 UPDATE provenance
 SET status = $2
 WHERE id = $1
-RETURNING id, target_type, target_id, source_id, excerpt, location, confidence, trust, status, modality, claimed_time_start, claimed_time_end, claimed_time_text, claimed_geo_region, claimed_geo_text, claimed_by, created_at, updated_at
+RETURNING id, target_type, target_id, source_id, excerpt, location, confidence, trust, status, modality, claimed_time_start, claimed_time_end, claimed_time_text, claimed_geo_region, claimed_geo_text, claimed_by, created_at, updated_at, claimed_properties
 `
 
 type UpdateProvenanceStatusParams struct {
@@ -478,6 +488,7 @@ func (q *Queries) UpdateProvenanceStatus(ctx context.Context, arg UpdateProvenan
 		&i.ClaimedBy,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ClaimedProperties,
 	)
 	return &i, err
 }
@@ -15,6 +15,14 @@ func PgUUID(u uuid.UUID) pgtype.UUID {
 	return pgtype.UUID{Bytes: u, Valid: true}
 }
 
+// PgUUIDPtr converts a *uuid.UUID to pgtype.UUID.
+func PgUUIDPtr(u *uuid.UUID) pgtype.UUID {
+	if u == nil {
+		return pgtype.UUID{}
+	}
+	return pgtype.UUID{Bytes: *u, Valid: true}
+}
+
 // UUIDStr converts a pgtype.UUID to string.
 func UUIDStr(u pgtype.UUID) string {
 	return uuid.UUID(u.Bytes).String()
@@ -56,3 +64,11 @@ func PgTimePtr(t *time.Time) pgtype.Timestamptz {
 	}
 	return pgtype.Timestamptz{Time: *t, Valid: true}
 }
+
+// PgBoolPtr converts a *bool to pgtype.Bool.
+func PgBoolPtr(b *bool) pgtype.Bool {
+	if b == nil {
+		return pgtype.Bool{}
+	}
+	return pgtype.Bool{Bool: *b, Valid: true}
+}
@@ -5,7 +5,6 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
-	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 // Repository handles database operations for sources and chunks.
@@ -14,26 +13,73 @@ type Repository struct {
 	ctx     context.Context
 }
 
-// NewRepository creates a new repository.
-func NewRepository(db *pgxpool.Pool, ctx context.Context) *Repository {
+// NewRepository creates a new repository on top of queries.
+func NewRepository(queries *Queries, ctx context.Context) *Repository {
 	return &Repository{
-		queries: New(db),
+		queries: queries,
 		ctx:     ctx,
 	}
 }
 
-// CreateSource creates a new source record.
-func (r *Repository) CreateSource(title, filename, filePath, fileType, uploadStatus string, isDemo bool) (*Source, error) {
-	return r.queries.CreateSource(r.ctx, CreateSourceParams{
+// CreateSource creates a new source record in projectID, or in no project
+// if it is nil. contentHash is the hex SHA-256 of the file, or empty if
+// unknown. metadata is the source's initial metadata, or nil; trust, if not
+// nil, is its trust (0–1) given for trustReason. It returns pgx.ErrNoRows if
+// the project already has a source with the same hash.
+func (r *Repository) CreateSource(title, filename, filePath, fileType, uploadStatus string, isDemo bool, contentHash string, projectID *uuid.UUID, metadata []byte, trust *float32, trustReason string) (*Source, error) {
+	params := CreateSourceParams{
 		Title:        title,
 		Filename:     filename,
 		FilePath:     filePath,
 		FileType:     fileType,
 		UploadStatus: uploadStatus,
 		IsDemo:       isDemo,
+		ContentHash:  PgTextPtr(nonEmpty(contentHash)),
+		ProjectID:    PgUUIDPtr(projectID),
+		Metadata:     metadata,
+	}
+	if trust != nil {
+		params.SourceTrust = pgtype.Float4{Float32: *trust, Valid: true}
+		params.TrustReason = PgText(trustReason)
+	}
+	return r.queries.CreateSource(r.ctx, params)
+}
+
+// CreateSourceVersion creates a source record as the next version of prev.
+// The new version keeps the title and project of the one it replaces. It
+// returns pgx.ErrNoRows if the project already has a source with the same
+// hash.
+func (r *Repository) CreateSourceVersion(prev *Source, filename, filePath, fileType, contentHash string) (*Source, error) {
+	return r.queries.CreateSourceVersion(r.ctx, CreateSourceVersionParams{
+		Title:             prev.Title,
+		Filename:          filename,
+		FilePath:          filePath,
+		FileType:          fileType,
+		UploadStatus:      "uploaded",
+		IsDemo:            prev.IsDemo,
+		ContentHash:       PgTextPtr(nonEmpty(contentHash)),
+		PreviousVersionID: prev.ID,
+		Version:           prev.Version + 1,
+		ProjectID:         prev.ProjectID,
 	})
 }
 
+// GetSourceByContentHash returns the source in projectID, or in no project
+// if it is nil, whose file has the given hash, skipping sources that failed
+// to process.
+func (r *Repository) GetSourceByContentHash(contentHash string, projectID *uuid.UUID) (*Source, error) {
+	return r.queries.GetSourceByContentHash(r.ctx, GetSourceByContentHashParams{
+		ContentHash: PgText(contentHash),
+		ProjectID:   PgUUIDPtr(projectID),
+	})
+}
+
+// ListSourceVersions returns every version of the source's version chain,
+// oldest first.
+func (r *Repository) ListSourceVersions(id uuid.UUID) ([]*Source, error) {
+	return r.queries.ListSourceVersions(r.ctx, PgUUID(id))
+}
+
 // UpdateSourceStatus updates a source's upload status.
 func (r *Repository) UpdateSourceStatus(id uuid.UUID, status string, errorMessage *string) (*Source, error) {
 	var errMsg pgtype.Text
@@ -47,6 +93,14 @@ func (r *Repository) UpdateSourceStatus(id uuid.UUID, status string, errorMessag
 	})
 }
 
+// UpdateSourceMetadata merges keys into a source's metadata.
+func (r *Repository) UpdateSourceMetadata(id uuid.UUID, metadata []byte) error {
+	return r.queries.UpdateSourceMetadata(r.ctx, UpdateSourceMetadataParams{
+		ID:       PgUUID(id),
+		Metadata: metadata,
+	})
+}
+
 // UpdateSourceTotalPages updates a source's total page count.
 func (r *Repository) UpdateSourceTotalPages(id uuid.UUID, totalPages int32) error {
 	return r.queries.UpdateSourceTotalPages(r.ctx, UpdateSourceTotalPagesParams{
@@ -55,14 +109,27 @@ func (r *Repository) UpdateSourceTotalPages(id uuid.UUID, totalPages int32) erro
 	})
 }
 
+// UpdateSourceTrust sets a source's trust (0–1) and the reason for it.
+func (r *Repository) UpdateSourceTrust(id uuid.UUID, trust float32, reason string) error {
+	return r.queries.UpdateSourceTrust(r.ctx, UpdateSourceTrustParams{
+		ID:          PgUUID(id),
+		SourceTrust: pgtype.Float4{Float32: trust, Valid: true},
+		TrustReason: PgText(reason),
+	})
+}
+
 // CreateChunk creates a new chunk record.
-func (r *Repository) CreateChunk(sourceID uuid.UUID, chunkIndex int32, content string, chapterTitle *string, chapterNumber *int32, pageStart, pageEnd *int32, narrativePosition int32, wordCount *int32) (*CreateChunkRow, error) {
+func (r *Repository) CreateChunk(sourceID uuid.UUID, chunkIndex int32, content string, chapterTitle *string, chapterNumber *int32, pageStart, pageEnd *int32, narrativePosition int32, wordCount *int32, sectionID, sectionName *string, metadata []byte) (*CreateChunkRow, error) {
 	params := CreateChunkParams{
+		ID:                PgUUID(uuid.New()),
 		SourceID:          PgUUID(sourceID),
 		ChunkIndex:        chunkIndex,
 		Content:           content,
 		ChapterTitle:      PgTextPtr(chapterTitle),
 		NarrativePosition: narrativePosition,
+		SectionID:         PgTextPtr(sectionID),
+		SectionName:       PgTextPtr(sectionName),
+		Metadata:          metadata,
 	}
 	if chapterNumber != nil {
 		params.ChapterNumber = pgtype.Int4{Int32: *chapterNumber, Valid: true}
@@ -103,3 +170,10 @@ func (r *Repository) GetChunkCount(sourceID uuid.UUID) (int64, error) {
 func (r *Repository) DeleteSource(id uuid.UUID) error {
 	return r.queries.DeleteSource(r.ctx, PgUUID(id))
 }
+
+func nonEmpty(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
@@ -13,13 +13,14 @@ import (
 
 const createSourceReference = `-- name: CreateSourceReference :one
 INSERT INTO source_references (
-    chunk_id, claim_id, entity_id, relationship_id, excerpt, char_start, char_end
+    id, chunk_id, claim_id, entity_id, relationship_id, excerpt, char_start, char_end
 )
-VALUES ($1, $2, $3, $4, $5, $6, $7)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 RETURNING id, chunk_id, claim_id, entity_id, relationship_id, excerpt, char_start, char_end, created_at
 `
 
 type CreateSourceReferenceParams struct {
+	ID             pgtype.UUID `json:"id"`
 	ChunkID        pgtype.UUID `json:"chunk_id"`
 	ClaimID        pgtype.UUID `json:"claim_id"`
 	EntityID       pgtype.UUID `json:"entity_id"`
@@ -31,6 +32,7 @@ type CreateSourceReferenceParams struct {
 
 func (q *Queries) CreateSourceReference(ctx context.Context, arg CreateSourceReferenceParams) (*SourceReference, error) {
 	row := q.db.QueryRow(ctx, createSourceReference,
+		arg.ID,
 		arg.ChunkID,
 		arg.ClaimID,
 		arg.EntityID,
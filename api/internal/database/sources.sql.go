@@ -11,21 +11,124 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const claimQueuedExtraction = `-- name: ClaimQueuedExtraction :one
+UPDATE sources
+SET extraction_status = 'running',
+    claimed_at = NOW()
+WHERE id = (
+    SELECT id FROM sources
+    WHERE extraction_status = 'queued'
+       OR (extraction_status = 'running' AND claimed_at < $1)
+    ORDER BY updated_at
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, title, filename, file_path, file_type, total_pages, upload_status, error_message, is_demo, metadata, created_at, updated_at, source_trust, trust_reason, project_id, content_hash, previous_version_id, version, claimed_at, extraction_status
+`
+
+// Marks the oldest queued extraction as running and returns its source,
+// skipping locked rows as ClaimUploadedSource does. An extraction whose
+// worker last sent a heartbeat before stale_before is claimed again.
+func (q *Queries) ClaimQueuedExtraction(ctx context.Context, staleBefore pgtype.Timestamptz) (*Source, error) {
+	row := q.db.QueryRow(ctx, claimQueuedExtraction, staleBefore)
+	var i Source
+	err := row.Scan(
+		&i.ID,
+		&i.Title,
+		&i.Filename,
+		&i.FilePath,
+		&i.FileType,
+		&i.TotalPages,
+		&i.UploadStatus,
+		&i.ErrorMessage,
+		&i.IsDemo,
+		&i.Metadata,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.SourceTrust,
+		&i.TrustReason,
+		&i.ProjectID,
+		&i.ContentHash,
+		&i.PreviousVersionID,
+		&i.Version,
+		&i.ClaimedAt,
+		&i.ExtractionStatus,
+	)
+	return &i, err
+}
+
+const claimUploadedSource = `-- name: ClaimUploadedSource :one
+UPDATE sources
+SET upload_status = 'processing',
+    claimed_at = NOW(),
+    updated_at = NOW()
+WHERE id = (
+    SELECT id FROM sources
+    WHERE upload_status = 'uploaded'
+       OR (upload_status = 'processing' AND claimed_at < $1)
+    ORDER BY created_at
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, title, filename, file_path, file_type, total_pages, upload_status, error_message, is_demo, metadata, created_at, updated_at, source_trust, trust_reason, project_id, content_hash, previous_version_id, version, claimed_at, extraction_status
+`
+
+// Marks the oldest uploaded source as processing and returns it. Sources
+// locked by another worker's claim are skipped, so no two workers get the
+// same source. A source whose worker last sent a heartbeat before
+// stale_before is claimed again.
+func (q *Queries) ClaimUploadedSource(ctx context.Context, staleBefore pgtype.Timestamptz) (*Source, error) {
+	row := q.db.QueryRow(ctx, claimUploadedSource, staleBefore)
+	var i Source
+	err := row.Scan(
+		&i.ID,
+		&i.Title,
+		&i.Filename,
+		&i.FilePath,
+		&i.FileType,
+		&i.TotalPages,
+		&i.UploadStatus,
+		&i.ErrorMessage,
+		&i.IsDemo,
+		&i.Metadata,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.SourceTrust,
+		&i.TrustReason,
+		&i.ProjectID,
+		&i.ContentHash,
+		&i.PreviousVersionID,
+		&i.Version,
+		&i.ClaimedAt,
+		&i.ExtractionStatus,
+	)
+	return &i, err
+}
+
 const createSource = `-- name: CreateSource :one
-INSERT INTO sources (title, filename, file_path, file_type, upload_status, is_demo)
-VALUES ($1, $2, $3, $4, $5, $6)
-RETURNING id, title, filename, file_path, file_type, total_pages, upload_status, error_message, is_demo, metadata, created_at, updated_at, source_trust, trust_reason, project_id
+INSERT INTO sources (title, filename, file_path, file_type, upload_status, is_demo, content_hash, project_id, metadata, source_trust, trust_reason)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+ON CONFLICT (project_id, content_hash) WHERE content_hash IS NOT NULL AND upload_status <> 'error' DO NOTHING
+RETURNING id, title, filename, file_path, file_type, total_pages, upload_status, error_message, is_demo, metadata, created_at, updated_at, source_trust, trust_reason, project_id, content_hash, previous_version_id, version, claimed_at, extraction_status
 `
 
 type CreateSourceParams struct {
-	Title        string `json:"title"`
-	Filename     string `json:"filename"`
-	FilePath     string `json:"file_path"`
-	FileType     string `json:"file_type"`
-	UploadStatus string `json:"upload_status"`
-	IsDemo       bool   `json:"is_demo"`
+	Title        string        `json:"title"`
+	Filename     string        `json:"filename"`
+	FilePath     string        `json:"file_path"`
+	FileType     string        `json:"file_type"`
+	UploadStatus string        `json:"upload_status"`
+	IsDemo       bool          `json:"is_demo"`
+	ContentHash  pgtype.Text   `json:"content_hash"`
+	ProjectID    pgtype.UUID   `json:"project_id"`
+	Metadata     []byte        `json:"metadata"`
+	SourceTrust  pgtype.Float4 `json:"source_trust"`
+	TrustReason  pgtype.Text   `json:"trust_reason"`
 }
 
+// Returns no row if the project already has a source with the content hash.
+// Metadata and trust are written with the row, so a worker woken by the
+// insert never sees the source without them.
 func (q *Queries) CreateSource(ctx context.Context, arg CreateSourceParams) (*Source, error) {
 	row := q.db.QueryRow(ctx, createSource,
 		arg.Title,
@@ -34,6 +137,11 @@ func (q *Queries) CreateSource(ctx context.Context, arg CreateSourceParams) (*So
 		arg.FileType,
 		arg.UploadStatus,
 		arg.IsDemo,
+		arg.ContentHash,
+		arg.ProjectID,
+		arg.Metadata,
+		arg.SourceTrust,
+		arg.TrustReason,
 	)
 	var i Source
 	err := row.Scan(
@@ -52,6 +160,71 @@ func (q *Queries) CreateSource(ctx context.Context, arg CreateSourceParams) (*So
 		&i.SourceTrust,
 		&i.TrustReason,
 		&i.ProjectID,
+		&i.ContentHash,
+		&i.PreviousVersionID,
+		&i.Version,
+		&i.ClaimedAt,
+		&i.ExtractionStatus,
+	)
+	return &i, err
+}
+
+const createSourceVersion = `-- name: CreateSourceVersion :one
+INSERT INTO sources (title, filename, file_path, file_type, upload_status, is_demo, content_hash, previous_version_id, version, project_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+ON CONFLICT (project_id, content_hash) WHERE content_hash IS NOT NULL AND upload_status <> 'error' DO NOTHING
+RETURNING id, title, filename, file_path, file_type, total_pages, upload_status, error_message, is_demo, metadata, created_at, updated_at, source_trust, trust_reason, project_id, content_hash, previous_version_id, version, claimed_at, extraction_status
+`
+
+type CreateSourceVersionParams struct {
+	Title             string      `json:"title"`
+	Filename          string      `json:"filename"`
+	FilePath          string      `json:"file_path"`
+	FileType          string      `json:"file_type"`
+	UploadStatus      string      `json:"upload_status"`
+	IsDemo            bool        `json:"is_demo"`
+	ContentHash       pgtype.Text `json:"content_hash"`
+	PreviousVersionID pgtype.UUID `json:"previous_version_id"`
+	Version           int32       `json:"version"`
+	ProjectID         pgtype.UUID `json:"project_id"`
+}
+
+// Returns no row if the project already has a source with the content hash.
+func (q *Queries) CreateSourceVersion(ctx context.Context, arg CreateSourceVersionParams) (*Source, error) {
+	row := q.db.QueryRow(ctx, createSourceVersion,
+		arg.Title,
+		arg.Filename,
+		arg.FilePath,
+		arg.FileType,
+		arg.UploadStatus,
+		arg.IsDemo,
+		arg.ContentHash,
+		arg.PreviousVersionID,
+		arg.Version,
+		arg.ProjectID,
+	)
+	var i Source
+	err := row.Scan(
+		&i.ID,
+		&i.Title,
+		&i.Filename,
+		&i.FilePath,
+		&i.FileType,
+		&i.TotalPages,
+		&i.UploadStatus,
+		&i.ErrorMessage,
+		&i.IsDemo,
+		&i.Metadata,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.SourceTrust,
+		&i.TrustReason,
+		&i.ProjectID,
+		&i.ContentHash,
+		&i.PreviousVersionID,
+		&i.Version,
+		&i.ClaimedAt,
+		&i.ExtractionStatus,
 	)
 	return &i, err
 }
@@ -65,8 +238,20 @@ func (q *Queries) DeleteSource(ctx context.Context, id pgtype.UUID) error {
 	return err
 }
 
+const finishSourceExtraction = `-- name: FinishSourceExtraction :exec
+UPDATE sources
+SET extraction_status = NULL,
+    claimed_at = NULL
+WHERE id = $1
+`
+
+func (q *Queries) FinishSourceExtraction(ctx context.Context, id pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, finishSourceExtraction, id)
+	return err
+}
+
 const getSource = `-- name: GetSource :one
-SELECT id, title, filename, file_path, file_type, total_pages, upload_status, error_message, is_demo, metadata, created_at, updated_at, source_trust, trust_reason, project_id FROM sources WHERE id = $1
+SELECT id, title, filename, file_path, file_type, total_pages, upload_status, error_message, is_demo, metadata, created_at, updated_at, source_trust, trust_reason, project_id, content_hash, previous_version_id, version, claimed_at, extraction_status FROM sources WHERE id = $1
 `
 
 func (q *Queries) GetSource(ctx context.Context, id pgtype.UUID) (*Source, error) {
@@ -88,12 +273,120 @@ func (q *Queries) GetSource(ctx context.Context, id pgtype.UUID) (*Source, error
 		&i.SourceTrust,
 		&i.TrustReason,
 		&i.ProjectID,
+		&i.ContentHash,
+		&i.PreviousVersionID,
+		&i.Version,
+		&i.ClaimedAt,
+		&i.ExtractionStatus,
+	)
+	return &i, err
+}
+
+const getSourceByContentHash = `-- name: GetSourceByContentHash :one
+SELECT id, title, filename, file_path, file_type, total_pages, upload_status, error_message, is_demo, metadata, created_at, updated_at, source_trust, trust_reason, project_id, content_hash, previous_version_id, version, claimed_at, extraction_status FROM sources
+WHERE content_hash = $1 AND project_id IS NOT DISTINCT FROM $2 AND upload_status <> 'error'
+`
+
+type GetSourceByContentHashParams struct {
+	ContentHash pgtype.Text `json:"content_hash"`
+	ProjectID   pgtype.UUID `json:"project_id"`
+}
+
+// Sources are deduplicated per project; a NULL project is the sources in none.
+func (q *Queries) GetSourceByContentHash(ctx context.Context, arg GetSourceByContentHashParams) (*Source, error) {
+	row := q.db.QueryRow(ctx, getSourceByContentHash, arg.ContentHash, arg.ProjectID)
+	var i Source
+	err := row.Scan(
+		&i.ID,
+		&i.Title,
+		&i.Filename,
+		&i.FilePath,
+		&i.FileType,
+		&i.TotalPages,
+		&i.UploadStatus,
+		&i.ErrorMessage,
+		&i.IsDemo,
+		&i.Metadata,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.SourceTrust,
+		&i.TrustReason,
+		&i.ProjectID,
+		&i.ContentHash,
+		&i.PreviousVersionID,
+		&i.Version,
+		&i.ClaimedAt,
+		&i.ExtractionStatus,
 	)
 	return &i, err
 }
 
+const listenSourceStatus = `-- name: ListenSourceStatus :exec
+LISTEN source_status
+`
+
+func (q *Queries) ListenSourceStatus(ctx context.Context) error {
+	_, err := q.db.Exec(ctx, listenSourceStatus)
+	return err
+}
+
+const listSourceVersions = `-- name: ListSourceVersions :many
+WITH RECURSIVE back AS (
+    SELECT s.id, s.previous_version_id FROM sources s WHERE s.id = $1
+    UNION
+    SELECT p.id, p.previous_version_id FROM sources p JOIN back b ON p.id = b.previous_version_id
+), chain AS (
+    SELECT b.id FROM back b WHERE b.previous_version_id IS NULL
+    UNION
+    SELECT n.id FROM sources n JOIN chain c ON n.previous_version_id = c.id
+)
+SELECT s.id, s.title, s.filename, s.file_path, s.file_type, s.total_pages, s.upload_status, s.error_message, s.is_demo, s.metadata, s.created_at, s.updated_at, s.source_trust, s.trust_reason, s.project_id, s.content_hash, s.previous_version_id, s.version, s.claimed_at, s.extraction_status FROM sources s JOIN chain c ON s.id = c.id
+ORDER BY s.version, s.created_at
+`
+
+func (q *Queries) ListSourceVersions(ctx context.Context, id pgtype.UUID) ([]*Source, error) {
+	rows, err := q.db.Query(ctx, listSourceVersions, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []*Source{}
+	for rows.Next() {
+		var i Source
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Filename,
+			&i.FilePath,
+			&i.FileType,
+			&i.TotalPages,
+			&i.UploadStatus,
+			&i.ErrorMessage,
+			&i.IsDemo,
+			&i.Metadata,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.SourceTrust,
+			&i.TrustReason,
+			&i.ProjectID,
+			&i.ContentHash,
+			&i.PreviousVersionID,
+			&i.Version,
+			&i.ClaimedAt,
+			&i.ExtractionStatus,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listSources = `-- name: ListSources :many
-SELECT id, title, filename, file_path, file_type, total_pages, upload_status, error_message, is_demo, metadata, created_at, updated_at, source_trust, trust_reason, project_id FROM sources ORDER BY created_at DESC
+SELECT id, title, filename, file_path, file_type, total_pages, upload_status, error_message, is_demo, metadata, created_at, updated_at, source_trust, trust_reason, project_id, content_hash, previous_version_id, version, claimed_at, extraction_status FROM sources ORDER BY created_at DESC
 `
 
 func (q *Queries) ListSources(ctx context.Context) ([]*Source, error) {
@@ -121,6 +414,11 @@ func (q *Queries) ListSources(ctx context.Context) ([]*Source, error) {
 			&i.SourceTrust,
 			&i.TrustReason,
 			&i.ProjectID,
+			&i.ContentHash,
+			&i.PreviousVersionID,
+			&i.Version,
+			&i.ClaimedAt,
+			&i.ExtractionStatus,
 		); err != nil {
 			return nil, err
 		}
@@ -132,13 +430,54 @@ func (q *Queries) ListSources(ctx context.Context) ([]*Source, error) {
 	return items, nil
 }
 
+const queueSourceExtraction = `-- name: QueueSourceExtraction :exec
+UPDATE sources
+SET extraction_status = 'queued'
+WHERE id = $1
+`
+
+// Queues a source for extraction by a document worker.
+func (q *Queries) QueueSourceExtraction(ctx context.Context, id pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, queueSourceExtraction, id)
+	return err
+}
+
+const touchSourceClaim = `-- name: TouchSourceClaim :exec
+UPDATE sources
+SET claimed_at = NOW()
+WHERE id = $1
+`
+
+// Heartbeat of the worker holding a source.
+func (q *Queries) TouchSourceClaim(ctx context.Context, id pgtype.UUID) error {
+	_, err := q.db.Exec(ctx, touchSourceClaim, id)
+	return err
+}
+
+const updateSourceMetadata = `-- name: UpdateSourceMetadata :exec
+UPDATE sources
+SET metadata   = COALESCE(metadata, '{}'::jsonb) || $2,
+    updated_at = NOW()
+WHERE id = $1
+`
+
+type UpdateSourceMetadataParams struct {
+	ID       pgtype.UUID `json:"id"`
+	Metadata []byte      `json:"metadata"`
+}
+
+func (q *Queries) UpdateSourceMetadata(ctx context.Context, arg UpdateSourceMetadataParams) error {
+	_, err := q.db.Exec(ctx, updateSourceMetadata, arg.ID, arg.Metadata)
+	return err
+}
+
 const updateSourceStatus = `-- name: UpdateSourceStatus :one
 UPDATE sources
 SET upload_status = $2,
     error_message = $3,
     updated_at    = NOW()
 WHERE id = $1
-RETURNING id, title, filename, file_path, file_type, total_pages, upload_status, error_message, is_demo, metadata, created_at, updated_at, source_trust, trust_reason, project_id
+RETURNING id, title, filename, file_path, file_type, total_pages, upload_status, error_message, is_demo, metadata, created_at, updated_at, source_trust, trust_reason, project_id, content_hash, previous_version_id, version, claimed_at, extraction_status
 `
 
 type UpdateSourceStatusParams struct {
@@ -166,6 +505,11 @@ func (q *Queries) UpdateSourceStatus(ctx context.Context, arg UpdateSourceStatus
 		&i.SourceTrust,
 		&i.TrustReason,
 		&i.ProjectID,
+		&i.ContentHash,
+		&i.PreviousVersionID,
+		&i.Version,
+		&i.ClaimedAt,
+		&i.ExtractionStatus,
 	)
 	return &i, err
 }
@@ -186,3 +530,22 @@ func (q *Queries) UpdateSourceTotalPages(ctx context.Context, arg UpdateSourceTo
 	_, err := q.db.Exec(ctx, updateSourceTotalPages, arg.ID, arg.TotalPages)
 	return err
 }
+
+const updateSourceTrust = `-- name: UpdateSourceTrust :exec
+UPDATE sources
+SET source_trust = $2,
+    trust_reason = $3,
+    updated_at   = NOW()
+WHERE id = $1
+`
+
+type UpdateSourceTrustParams struct {
+	ID          pgtype.UUID   `json:"id"`
+	SourceTrust pgtype.Float4 `json:"source_trust"`
+	TrustReason pgtype.Text   `json:"trust_reason"`
+}
+
+func (q *Queries) UpdateSourceTrust(ctx context.Context, arg UpdateSourceTrustParams) error {
+	_, err := q.db.Exec(ctx, updateSourceTrust, arg.ID, arg.SourceTrust, arg.TrustReason)
+	return err
+}
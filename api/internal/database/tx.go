@@ -0,0 +1,39 @@
+package database
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// beginner is a DBTX that can start a transaction, as a pool, a connection
+// or a transaction (as a savepoint) can
+type beginner interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// InTx runs fn with Queries in a transaction, committing if fn returns nil
+// and rolling back otherwise. The Queries given to fn encrypt and decrypt
+// as q does.
+func (q *Queries) InTx(ctx context.Context, fn func(*Queries) error) error {
+	db, wrap := q.db, New
+	if e, ok := db.(*encryptedDB); ok {
+		db = e.db
+		wrap = func(tx DBTX) *Queries { return New(&encryptedDB{db: tx, fields: e.fields, columns: e.columns}) }
+	}
+	b, ok := db.(beginner)
+	if !ok {
+		return errors.New("database handle cannot begin a transaction")
+	}
+
+	tx, err := b.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	if err := fn(wrap(tx)); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,123 @@
+package document
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
+// MaxArchiveFiles is the most documents a bulk upload archive may hold.
+const MaxArchiveFiles = 500
+
+// ArchiveManifest describes the documents of a bulk upload archive, in the
+// format of the corpora manifests (corpora/*/manifest.json). Only the
+// document list is read.
+type ArchiveManifest struct {
+	Documents []ArchiveDocument `json:"documents"`
+}
+
+// ArchiveDocument is a manifest entry. Every field but Filename is optional.
+type ArchiveDocument struct {
+	ID       string   `json:"id"`
+	Filename string   `json:"filename"`
+	Title    string   `json:"title"`
+	Type     string   `json:"type"`
+	Trust    *float64 `json:"trust"`
+	Date     string   `json:"date"` // YYYY-MM-DD or RFC 3339
+}
+
+// UploadArchive is a zip of documents to upload together.
+type UploadArchive struct {
+	Files    []*zip.File
+	Manifest *ArchiveManifest // nil if the archive has no manifest.json
+}
+
+// ReadUploadArchive lists the documents in a zip archive and reads its
+// manifest.json, if any, from the archive root. Directories, hidden files
+// and macOS resource forks are skipped.
+func ReadUploadArchive(r io.ReaderAt, size int64) (*UploadArchive, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read zip archive: %w", err)
+	}
+
+	archive := &UploadArchive{}
+	for _, f := range zr.File {
+		name := strings.TrimPrefix(f.Name, "./")
+		base := path.Base(name)
+		if f.FileInfo().IsDir() || strings.HasPrefix(base, ".") || strings.HasPrefix(name, "__MACOSX/") {
+			continue
+		}
+		if name == "manifest.json" {
+			if archive.Manifest, err = readArchiveManifest(f); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		archive.Files = append(archive.Files, f)
+	}
+
+	if len(archive.Files) == 0 {
+		return nil, fmt.Errorf("archive contains no documents")
+	}
+	if len(archive.Files) > MaxArchiveFiles {
+		return nil, fmt.Errorf("archive contains %d documents, at most %d are allowed", len(archive.Files), MaxArchiveFiles)
+	}
+	return archive, nil
+}
+
+func readArchiveManifest(f *zip.File) (*ArchiveManifest, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open manifest.json: %w", err)
+	}
+	defer rc.Close()
+
+	var m ArchiveManifest
+	if err := json.NewDecoder(io.LimitReader(rc, 10<<20)).Decode(&m); err != nil {
+		return nil, fmt.Errorf("invalid manifest.json: %w", err)
+	}
+	for _, d := range m.Documents {
+		if d.Trust != nil && (*d.Trust < 0 || *d.Trust > 1) {
+			return nil, fmt.Errorf("manifest.json: trust of %s must be between 0 and 1", d.Filename)
+		}
+		if _, ok := d.ReferenceDate(); d.Date != "" && !ok {
+			return nil, fmt.Errorf("manifest.json: invalid date %q for %s", d.Date, d.Filename)
+		}
+	}
+	return &m, nil
+}
+
+// Lookup returns the manifest entry for a file in the archive, matched on
+// its path or, failing that, its base name.
+func (m *ArchiveManifest) Lookup(name string) *ArchiveDocument {
+	if m == nil {
+		return nil
+	}
+	name = strings.TrimPrefix(name, "./")
+	for i := range m.Documents {
+		if m.Documents[i].Filename == name {
+			return &m.Documents[i]
+		}
+	}
+	for i := range m.Documents {
+		if path.Base(m.Documents[i].Filename) == path.Base(name) {
+			return &m.Documents[i]
+		}
+	}
+	return nil
+}
+
+// ReferenceDate returns the document's date, if the manifest gives one.
+func (d *ArchiveDocument) ReferenceDate() (time.Time, bool) {
+	for _, layout := range []string{"2006-01-02", time.RFC3339} {
+		if t, err := time.Parse(layout, d.Date); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
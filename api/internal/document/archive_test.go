@@ -0,0 +1,74 @@
+package document
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+)
+
+func TestReadUploadArchive(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	files := map[string]string{
+		"manifest.json": `{"corpus": "brf", "documents": [
+			{"id": "A1", "filename": "A1-protocol.txt", "trust": 0.95, "date": "2023-03-15"},
+			{"id": "A2", "filename": "docs/A2-offert.txt", "title": "Offert NorrBygg", "trust": 0.9}
+		]}`,
+		"docs/A1-protocol.txt":            "Protokoll",
+		"docs/A2-offert.txt":              "Offert",
+		"docs/.DS_Store":                  "",
+		"__MACOSX/docs/._A1-protocol.txt": "",
+	}
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if _, err := zw.Create("docs/"); err != nil {
+		t.Fatal(err)
+	}
+	zw.Close()
+
+	archive, err := ReadUploadArchive(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("ReadUploadArchive() error = %v", err)
+	}
+	if len(archive.Files) != 2 {
+		t.Fatalf("files = %d, want 2", len(archive.Files))
+	}
+	if archive.Manifest == nil || len(archive.Manifest.Documents) != 2 {
+		t.Fatalf("manifest = %+v", archive.Manifest)
+	}
+
+	a1 := archive.Manifest.Lookup("docs/A1-protocol.txt")
+	if a1 == nil || a1.ID != "A1" || a1.Trust == nil || *a1.Trust != 0.95 {
+		t.Errorf("Lookup(A1) = %+v", a1)
+	}
+	if date, ok := a1.ReferenceDate(); !ok || date.Format("2006-01-02") != "2023-03-15" {
+		t.Errorf("A1 date = %v, %v", date, ok)
+	}
+	if a2 := archive.Manifest.Lookup("docs/A2-offert.txt"); a2 == nil || a2.Title != "Offert NorrBygg" {
+		t.Errorf("Lookup(A2) = %+v", a2)
+	}
+	if other := archive.Manifest.Lookup("docs/other.txt"); other != nil {
+		t.Errorf("Lookup(other) = %+v, want nil", other)
+	}
+	var none *ArchiveManifest
+	if none.Lookup("A1-protocol.txt") != nil {
+		t.Error("Lookup on nil manifest should return nil")
+	}
+
+	// Trust outside 0–1 is rejected
+	buf.Reset()
+	zw = zip.NewWriter(&buf)
+	w, _ := zw.Create("manifest.json")
+	w.Write([]byte(`{"documents": [{"filename": "a.txt", "trust": 2}]}`))
+	w, _ = zw.Create("a.txt")
+	w.Write([]byte("a"))
+	zw.Close()
+	if _, err := ReadUploadArchive(bytes.NewReader(buf.Bytes()), int64(buf.Len())); err == nil {
+		t.Error("expected error for trust above 1")
+	}
+}
@@ -21,6 +21,13 @@ type Chunk struct {
 	SectionID   string // e.g., "§5", "Section 3", "3.1"
 	SectionName string // e.g., "Beslut om upphandling", "Financial Summary"
 
+	// Markdown only — heading breadcrumb from the top level down, e.g.
+	// ["Section 3.2", "Payments"]. Nil for other formats.
+	HeadingPath []string
+
+	// Format-specific metadata stored with the chunk (e.g. email headers).
+	Metadata map[string]interface{}
+
 	// PDF only — nil for TXT files.
 	PageStart *int
 	PageEnd   *int
@@ -44,8 +51,8 @@ func DetectChunkStrategy(content string) ChunkStrategy {
 	// Short documents: no splitting needed (unless they have explicit structure)
 	if wordCount < 3000 {
 		// Still check for explicit section/chapter markers even in short docs
-		if hasSectionMarkers(content) {
-			return &SectionChunker{}
+		if grammar := DetectSectionGrammar(content); grammar != nil {
+			return &SectionChunker{Grammar: grammar}
 		}
 		if hasChapterMarkers(content) {
 			return &ChapterChunker{}
@@ -54,8 +61,8 @@ func DetectChunkStrategy(content string) ChunkStrategy {
 	}
 
 	// Long documents: check for structural markers
-	if hasSectionMarkers(content) {
-		return &SectionChunker{}
+	if grammar := DetectSectionGrammar(content); grammar != nil {
+		return &SectionChunker{Grammar: grammar}
 	}
 
 	if hasChapterMarkers(content) {
@@ -66,25 +73,6 @@ func DetectChunkStrategy(content string) ChunkStrategy {
 	return &FallbackChunker{}
 }
 
-// hasSectionMarkers checks for protocol/section-style formatting
-func hasSectionMarkers(content string) bool {
-	// Swedish section markers: §5, § 5
-	if strings.Contains(content, "§") {
-		return true
-	}
-	// English section markers: Section 5, SECTION 5
-	sectionPattern := regexp.MustCompile(`(?i)^section\s+\d+`)
-	if sectionPattern.MatchString(content) {
-		return true
-	}
-	// Numbered headers: 1. Title, 2. Title, 3.1 Title
-	numberedPattern := regexp.MustCompile(`(?m)^\d+\.\d*\s+[A-ZÄÖÅ]`)
-	if numberedPattern.MatchString(content) {
-		return true
-	}
-	return false
-}
-
 // hasChapterMarkers checks for novel-style chapter formatting
 func hasChapterMarkers(content string) bool {
 	// Chapter patterns: Chapter 1, CHAPTER I, Kapitel 1
@@ -109,29 +97,36 @@ func (c *WholeDocChunker) Chunk(content string) []Chunk {
 	}}
 }
 
-// SectionChunker splits on § markers and numbered headers
-type SectionChunker struct{}
+// SectionChunker splits on the headings of a section grammar (§ paragraphs,
+// report headings, legal clauses). A nil Grammar is detected from the content.
+type SectionChunker struct {
+	Grammar SectionGrammar
+}
 
 func (c *SectionChunker) Name() string { return "section" }
 
 func (c *SectionChunker) Chunk(content string) []Chunk {
-	// Pattern matches: §5, § 5, §5 Title, 5. Title, 5.1 Title
-	sectionPattern := regexp.MustCompile(`(?m)^(\s*§\s*\d+|\d+\.\d*\s+[A-ZÄÖÅ])`)
+	grammar := c.Grammar
+	if grammar == nil {
+		grammar = DetectSectionGrammar(content)
+	}
+	if grammar == nil {
+		return (&WholeDocChunker{}).Chunk(content)
+	}
 
-	// Find all section start positions
-	matches := sectionPattern.FindAllStringIndex(content, -1)
-	if len(matches) == 0 {
+	headings := grammar.Headings(content)
+	if len(headings) == 0 {
 		// No sections found, return as whole doc
 		return (&WholeDocChunker{}).Chunk(content)
 	}
 
 	var chunks []Chunk
 
-	for i, match := range matches {
-		start := match[0]
+	for i, heading := range headings {
+		start := heading.Offset
 		var end int
-		if i+1 < len(matches) {
-			end = matches[i+1][0]
+		if i+1 < len(headings) {
+			end = headings[i+1].Offset
 		} else {
 			end = len(content)
 		}
@@ -141,32 +136,18 @@ func (c *SectionChunker) Chunk(content string) []Chunk {
 			continue // Skip empty or tiny sections
 		}
 
-		// Extract section ID from the match
-		firstLine := sectionContent
-		if nl := strings.Index(sectionContent, "\n"); nl != -1 {
-			firstLine = sectionContent[:nl]
-		}
-
-		sectionID := strings.TrimSpace(firstLine)
-		var sectionName string
-		if colon := strings.Index(firstLine, ":"); colon != -1 {
-			sectionName = strings.TrimSpace(firstLine[colon+1:])
-		} else if space := strings.Index(firstLine, " "); space != -1 && space < len(firstLine)-1 {
-			// Extract name after the section number
-			rest := firstLine[space+1:]
-			// Skip leading dots or spaces
-			rest = strings.TrimLeft(rest, ". ")
-			if len(rest) > 0 {
-				sectionName = rest
-			}
+		sectionName := heading.Name
+		chapterTitle := sectionName
+		if chapterTitle == "" {
+			chapterTitle = heading.ID
 		}
 
 		chunks = append(chunks, Chunk{
 			ChunkIndex:        len(chunks),
 			Content:           sectionContent,
-			ChapterTitle:      sectionName,
+			ChapterTitle:      chapterTitle,
 			ChapterNumber:     0,
-			SectionID:         sectionID,
+			SectionID:         heading.ID,
 			SectionName:       sectionName,
 			NarrativePosition: len(chunks),
 			PageStart:         nil,
@@ -175,8 +156,8 @@ func (c *SectionChunker) Chunk(content string) []Chunk {
 	}
 
 	// Handle content before first section (preamble)
-	if len(matches) > 0 && matches[0][0] > 100 {
-		preamble := strings.TrimSpace(content[:matches[0][0]])
+	if headings[0].Offset > 100 {
+		preamble := strings.TrimSpace(content[:headings[0].Offset])
 		if len(preamble) >= 50 {
 			// Insert preamble as first chunk
 			preambleChunk := Chunk{
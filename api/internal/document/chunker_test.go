@@ -150,3 +150,77 @@ func TestFallbackChunker(t *testing.T) {
 		}
 	}
 }
+
+func TestDetectSectionGrammar(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+		ids     []string
+	}{
+		{
+			name:    "Swedish protocol",
+			content: "Protokoll\n\n§ 1. Mötets öppnande\nText.\n\n§2 Val av justerare\nText.\n",
+			want:    "swedish_protocol",
+			ids:     []string{"Protokoll", "§1", "§2"},
+		},
+		{
+			name:    "Swedish protocol keyword headings",
+			content: "PROTOKOLL\n\nÄrende\nFasaden.\n\nBeslut:\nStyrelsen beslutade att anta offerten.\n",
+			want:    "swedish_protocol",
+			ids:     []string{"Protokoll", "Ärende", "Beslut"},
+		},
+		{
+			name:    "Beslut inside a paragraph keeps the paragraph ID",
+			content: "§4 Ekonomi\nText.\n\n§5 Fasad\nText.\n\nBeslut\nAtt anta offerten.\n",
+			want:    "swedish_protocol",
+			ids:     []string{"§4", "§5", "§5"},
+		},
+		{
+			name:    "A single paragraph sign is not a protocol",
+			content: "Enligt stadgarna\n§ 5\ngäller följande.\n",
+			want:    "",
+		},
+		{
+			name:    "Police report",
+			content: "TEKNISK UNDERSÖKNING\n\nÄrende: 5000-K1-24\n\nKamera 1 (Infart):\n  20:14 — Bil.\n\nKamera 2 (Ramp):\n  23:47 — Person.\n",
+			want:    "swedish_police_report",
+			ids:     []string{"TEKNISK UNDERSÖKNING", "Kamera 1", "Kamera 2"},
+		},
+		{
+			name:    "English legal",
+			content: "AGREEMENT\n\nSection 1. Definitions\nText.\n\nSection 2 — Term\nText.\n",
+			want:    "english_legal",
+			ids:     []string{"Section 1", "Section 2"},
+		},
+		{
+			name:    "Year at line start is not a heading",
+			content: "Revisionsberättelse för\n2023. Inga anmärkningar.\n",
+			want:    "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := DetectSectionGrammar(tt.content)
+			if tt.want == "" {
+				if g != nil {
+					t.Fatalf("DetectSectionGrammar() = %s, want none", g.Name())
+				}
+				return
+			}
+			if g == nil || g.Name() != tt.want {
+				t.Fatalf("DetectSectionGrammar() = %v, want %s", g, tt.want)
+			}
+			headings := g.Headings(tt.content)
+			if len(headings) != len(tt.ids) {
+				t.Fatalf("Headings() returned %d headings, want %d", len(headings), len(tt.ids))
+			}
+			for i, h := range headings {
+				if h.ID != tt.ids[i] {
+					t.Errorf("heading %d ID = %q, want %q", i, h.ID, tt.ids[i])
+				}
+			}
+		})
+	}
+}
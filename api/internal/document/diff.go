@@ -0,0 +1,142 @@
+package document
+
+import "strings"
+
+// Chunk diff statuses.
+const (
+	DiffUnchanged = "unchanged"
+	DiffModified  = "modified"
+	DiffAdded     = "added"
+	DiffRemoved   = "removed"
+)
+
+// ChunkDiff describes how one chunk changed between two versions of a
+// source. Indexes are positions in the chunk lists that were compared; -1
+// marks the side a chunk is missing from.
+type ChunkDiff struct {
+	Status   string     `json:"status"`
+	OldIndex int        `json:"old_index"`
+	NewIndex int        `json:"new_index"`
+	Lines    []LineDiff `json:"lines,omitempty"` // modified chunks only
+}
+
+// LineDiff is one line of a modified chunk: Op is "=" for a kept line, "-"
+// for a removed one and "+" for an added one.
+type LineDiff struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+// DiffChunks compares the chunk texts of two versions of a source. Chunks
+// with identical text (ignoring surrounding whitespace) are matched in order
+// as unchanged; between two matches, the remaining chunks are paired up by
+// position as modified, and any left over are added or removed. The result
+// follows the order of the new version, with removed chunks placed where
+// they used to be.
+func DiffChunks(before, after []string) []ChunkDiff {
+	trim := func(s []string) []string {
+		out := make([]string, len(s))
+		for i, t := range s {
+			out[i] = strings.TrimSpace(t)
+		}
+		return out
+	}
+	a, b := trim(before), trim(after)
+	anchors := lcs(len(a), len(b), func(i, j int) bool { return a[i] == b[j] })
+	anchors = append(anchors, [2]int{len(a), len(b)})
+
+	var diffs []ChunkDiff
+	i, j := 0, 0
+	for _, anchor := range anchors {
+		for ; i < anchor[0] && j < anchor[1]; i, j = i+1, j+1 {
+			diffs = append(diffs, ChunkDiff{
+				Status:   DiffModified,
+				OldIndex: i,
+				NewIndex: j,
+				Lines:    DiffLines(before[i], after[j]),
+			})
+		}
+		for ; i < anchor[0]; i++ {
+			diffs = append(diffs, ChunkDiff{Status: DiffRemoved, OldIndex: i, NewIndex: -1})
+		}
+		for ; j < anchor[1]; j++ {
+			diffs = append(diffs, ChunkDiff{Status: DiffAdded, OldIndex: -1, NewIndex: j})
+		}
+		if anchor[0] < len(a) {
+			diffs = append(diffs, ChunkDiff{Status: DiffUnchanged, OldIndex: anchor[0], NewIndex: anchor[1]})
+			i, j = anchor[0]+1, anchor[1]+1
+		}
+	}
+	return diffs
+}
+
+// DiffLines returns a line diff of two texts.
+func DiffLines(before, after string) []LineDiff {
+	a, b := strings.Split(before, "\n"), strings.Split(after, "\n")
+	anchors := lcs(len(a), len(b), func(i, j int) bool { return a[i] == b[j] })
+	anchors = append(anchors, [2]int{len(a), len(b)})
+
+	var lines []LineDiff
+	i, j := 0, 0
+	for _, anchor := range anchors {
+		for ; i < anchor[0]; i++ {
+			lines = append(lines, LineDiff{Op: "-", Text: a[i]})
+		}
+		for ; j < anchor[1]; j++ {
+			lines = append(lines, LineDiff{Op: "+", Text: b[j]})
+		}
+		if anchor[0] < len(a) {
+			lines = append(lines, LineDiff{Op: "=", Text: a[i]})
+			i, j = i+1, j+1
+		}
+	}
+	return lines
+}
+
+// lcs returns the index pairs of a longest common subsequence of two
+// sequences of length n and m, in order.
+func lcs(n, m int, equal func(i, j int) bool) [][2]int {
+	// length[i][j] is the LCS length of the suffixes starting at i and j
+	length := make([][]int, n+1)
+	for i := range length {
+		length[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			switch {
+			case equal(i, j):
+				length[i][j] = length[i+1][j+1] + 1
+			case length[i+1][j] >= length[i][j+1]:
+				length[i][j] = length[i+1][j]
+			default:
+				length[i][j] = length[i][j+1]
+			}
+		}
+	}
+
+	var pairs [][2]int
+	for i, j := 0, 0; i < n && j < m; {
+		switch {
+		case equal(i, j):
+			pairs = append(pairs, [2]int{i, j})
+			i, j = i+1, j+1
+		case length[i+1][j] >= length[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	return pairs
+}
+
+// ChangedChunks returns the indexes of the new version's chunks whose text
+// differs from the old version: modified and added chunks.
+func ChangedChunks(diffs []ChunkDiff) []int {
+	var changed []int
+	for _, d := range diffs {
+		if d.Status == DiffModified || d.Status == DiffAdded {
+			changed = append(changed, d.NewIndex)
+		}
+	}
+	return changed
+}
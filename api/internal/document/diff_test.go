@@ -0,0 +1,56 @@
+package document
+
+import "testing"
+
+func TestDiffChunks(t *testing.T) {
+	before := []string{
+		"Kapitel 1\nAnna kom hem.",
+		"Kapitel 2\nHon ringde polisen.\nDet var sent.",
+		"Kapitel 3\nIngen svarade.",
+		"Kapitel 4\nSlut.",
+	}
+	after := []string{
+		"Kapitel 1\nAnna kom hem.",
+		"Kapitel 2\nHon ringde polisen.\nDet var tidigt.",
+		"Kapitel 4\nSlut.\n",
+		"Kapitel 5\nEpilog.",
+	}
+
+	diffs := DiffChunks(before, after)
+	want := []ChunkDiff{
+		{Status: DiffUnchanged, OldIndex: 0, NewIndex: 0},
+		{Status: DiffModified, OldIndex: 1, NewIndex: 1},
+		{Status: DiffRemoved, OldIndex: 2, NewIndex: -1},
+		{Status: DiffUnchanged, OldIndex: 3, NewIndex: 2},
+		{Status: DiffAdded, OldIndex: -1, NewIndex: 3},
+	}
+	if len(diffs) != len(want) {
+		t.Fatalf("diffs = %+v, want %d", diffs, len(want))
+	}
+	for i, w := range want {
+		d := diffs[i]
+		if d.Status != w.Status || d.OldIndex != w.OldIndex || d.NewIndex != w.NewIndex {
+			t.Errorf("diff %d = %s %d→%d, want %s %d→%d", i, d.Status, d.OldIndex, d.NewIndex, w.Status, w.OldIndex, w.NewIndex)
+		}
+	}
+
+	lines := diffs[1].Lines
+	wantLines := []LineDiff{
+		{Op: "=", Text: "Kapitel 2"},
+		{Op: "=", Text: "Hon ringde polisen."},
+		{Op: "-", Text: "Det var sent."},
+		{Op: "+", Text: "Det var tidigt."},
+	}
+	if len(lines) != len(wantLines) {
+		t.Fatalf("lines = %+v, want %+v", lines, wantLines)
+	}
+	for i := range wantLines {
+		if lines[i] != wantLines[i] {
+			t.Errorf("line %d = %+v, want %+v", i, lines[i], wantLines[i])
+		}
+	}
+
+	if changed := ChangedChunks(diffs); len(changed) != 2 || changed[0] != 1 || changed[1] != 3 {
+		t.Errorf("ChangedChunks() = %v, want [1 3]", changed)
+	}
+}
@@ -0,0 +1,523 @@
+package document
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DOCX files are parsed in pure Go: the OOXML parts are read straight from
+// the zip and rendered to Markdown (headings, lists, pipe tables, footnotes),
+// which MarkdownChunker then splits. Page numbers come from the page breaks
+// Word records when it last laid out the document, so they are hints only.
+
+var blockHeadingStyle = regexp.MustCompile(`(?i)^heading ?([1-9])$`)
+
+// docxStyle is the subset of a w:style entry needed to detect headings.
+type docxStyle struct {
+	ID   string `xml:"styleId,attr"`
+	Type string `xml:"type,attr"`
+	Name struct {
+		Val string `xml:"val,attr"`
+	} `xml:"name"`
+	BasedOn struct {
+		Val string `xml:"val,attr"`
+	} `xml:"basedOn"`
+	OutlineLvl *struct {
+		Val string `xml:"val,attr"`
+	} `xml:"pPr>outlineLvl"`
+}
+
+// ParseDOCX extracts a DOCX file as Markdown text. The returned table maps
+// character offsets to page numbers and is nil when the file carries no
+// page-break information.
+func ParseDOCX(filePath string) (string, map[int]int, error) {
+	zr, err := zip.OpenReader(filePath)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to open DOCX: %w", err)
+	}
+	defer zr.Close()
+
+	parts := make(map[string]*zip.File)
+	for _, f := range zr.File {
+		parts[f.Name] = f
+	}
+
+	body, ok := parts["word/document.xml"]
+	if !ok {
+		return "", nil, fmt.Errorf("not a DOCX file: word/document.xml missing")
+	}
+
+	headingLevels := map[string]int{}
+	if f, ok := parts["word/styles.xml"]; ok {
+		if headingLevels, err = readDOCXStyles(f); err != nil {
+			return "", nil, fmt.Errorf("failed to read DOCX styles: %w", err)
+		}
+	}
+
+	var footnotes []textBlock
+	if f, ok := parts["word/footnotes.xml"]; ok {
+		if footnotes, err = readDOCXFootnotes(f); err != nil {
+			return "", nil, fmt.Errorf("failed to read DOCX footnotes: %w", err)
+		}
+	}
+
+	rc, err := body.Open()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read DOCX body: %w", err)
+	}
+	defer rc.Close()
+
+	p, err := parseDOCXBody(rc, headingLevels)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to parse DOCX body: %w", err)
+	}
+
+	// Word writes a rendered break after every explicit one, so explicit
+	// breaks are only used for files that were never laid out by Word.
+	if !p.rendered {
+		for i := range p.blocks {
+			p.blocks[i].page = p.blocks[i].hard
+		}
+	}
+
+	text, offsetToPage := renderBlocks(p.blocks, footnotes)
+	return text, offsetToPage, nil
+}
+
+// ParseDOCXWithChunks parses a DOCX file into heading-structured chunks with
+// page hints where the file provides them.
+func ParseDOCXWithChunks(filePath string) ([]Chunk, error) {
+	text, offsetToPage, err := ParseDOCX(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	chunks, err := ParseMarkdown(text)
+	if err != nil {
+		return nil, err
+	}
+
+	if offsetToPage != nil {
+		for i := range chunks {
+			startPage, endPage := getPageRange(chunks[i].Content, text, offsetToPage)
+			chunks[i].PageStart = &startPage
+			chunks[i].PageEnd = &endPage
+		}
+	}
+
+	return chunks, nil
+}
+
+// readDOCXStyles maps paragraph style IDs to heading levels (1-based).
+func readDOCXStyles(f *zip.File) (map[string]int, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	var doc struct {
+		Styles []docxStyle `xml:"style"`
+	}
+	if err := xml.NewDecoder(rc).Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	byID := make(map[string]docxStyle, len(doc.Styles))
+	for _, s := range doc.Styles {
+		byID[s.ID] = s
+	}
+
+	var level func(id string, depth int) int
+	level = func(id string, depth int) int {
+		s, ok := byID[id]
+		if !ok || depth > 10 {
+			return 0
+		}
+		if s.OutlineLvl != nil {
+			if n, err := strconv.Atoi(s.OutlineLvl.Val); err == nil && n < 9 {
+				return n + 1
+			}
+		}
+		if strings.EqualFold(s.Name.Val, "title") {
+			return 1
+		}
+		if m := blockHeadingStyle.FindStringSubmatch(s.Name.Val); m != nil {
+			return int(m[1][0] - '0')
+		}
+		if s.BasedOn.Val != "" {
+			return level(s.BasedOn.Val, depth+1)
+		}
+		return 0
+	}
+
+	levels := make(map[string]int)
+	for _, s := range doc.Styles {
+		if s.Type != "paragraph" {
+			continue
+		}
+		if l := level(s.ID, 0); l > 0 {
+			levels[s.ID] = l
+		}
+	}
+	return levels, nil
+}
+
+// readDOCXFootnotes returns the document's footnotes as paragraphs, skipping
+// the separator entries Word stores alongside them.
+func readDOCXFootnotes(f *zip.File) ([]textBlock, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	var notes []textBlock
+	dec := xml.NewDecoder(rc)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return notes, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		se, ok := tok.(xml.StartElement)
+		if !ok || se.Name.Local != "footnote" {
+			continue
+		}
+		if t := attr(se, "type"); t == "separator" || t == "continuationSeparator" || t == "continuationNotice" {
+			if err := dec.Skip(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		id := attr(se, "id")
+		p := &docxParser{dec: dec, headingLevels: map[string]int{}}
+		if err := p.parseContainer("footnote"); err != nil {
+			return nil, err
+		}
+		var texts []string
+		for _, b := range p.blocks {
+			if b.text != "" {
+				texts = append(texts, b.text)
+			}
+		}
+		if len(texts) > 0 {
+			notes = append(notes, textBlock{kind: blockFootnote, text: fmt.Sprintf("[^%s]: %s", id, strings.Join(texts, " "))})
+		}
+	}
+}
+
+type blockKind int
+
+const (
+	blockParagraph blockKind = iota
+	blockHeading
+	blockListItem
+	blockTable
+	blockFootnote
+)
+
+// textBlock is one unit of a structured document (DOCX, HTML) before it is
+// rendered to Markdown.
+type textBlock struct {
+	kind  blockKind
+	text  string
+	level int        // heading level or list indent
+	rows  [][]string // table cells
+	page  int        // page the block starts on, from rendered page breaks
+	hard  int        // page the block starts on, from explicit page breaks
+}
+
+// docxParser walks document.xml tokens, collecting blocks.
+type docxParser struct {
+	dec           *xml.Decoder
+	headingLevels map[string]int
+	blocks        []textBlock
+	page          int
+	hard          int
+	rendered      bool // saw w:lastRenderedPageBreak
+}
+
+func parseDOCXBody(r io.Reader, headingLevels map[string]int) (*docxParser, error) {
+	p := &docxParser{dec: xml.NewDecoder(r), headingLevels: headingLevels, page: 1, hard: 1}
+	for {
+		tok, err := p.dec.Token()
+		if err == io.EOF {
+			return p, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if se, ok := tok.(xml.StartElement); ok && se.Name.Local == "body" {
+			if err := p.parseContainer("body"); err != nil {
+				return nil, err
+			}
+			return p, nil
+		}
+	}
+}
+
+// parseContainer reads block-level content (paragraphs and tables) until the
+// closing tag of the named element.
+func (p *docxParser) parseContainer(end string) error {
+	for {
+		tok, err := p.dec.Token()
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "p":
+				if err := p.parseParagraph(); err != nil {
+					return err
+				}
+			case "tbl":
+				page, hard := p.page, p.hard
+				rows, err := p.parseTable()
+				if err != nil {
+					return err
+				}
+				if len(rows) > 0 {
+					p.blocks = append(p.blocks, textBlock{kind: blockTable, rows: rows, page: page, hard: hard})
+				}
+			case "sectPr":
+				if err := p.dec.Skip(); err != nil {
+					return err
+				}
+			}
+		case xml.EndElement:
+			if t.Name.Local == end {
+				return nil
+			}
+		}
+	}
+}
+
+// parseParagraph reads one w:p element and appends it as a block.
+func (p *docxParser) parseParagraph() error {
+	var text strings.Builder
+	startPage, startHard := p.page, p.hard
+	styleID := ""
+	outline := -1
+	listLevel := -1
+
+	for {
+		tok, err := p.dec.Token()
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "pStyle":
+				styleID = attr(t, "val")
+			case "outlineLvl":
+				if n, err := strconv.Atoi(attr(t, "val")); err == nil && n < 9 {
+					outline = n
+				}
+			case "numPr":
+				listLevel = 0
+			case "ilvl":
+				if n, err := strconv.Atoi(attr(t, "val")); err == nil {
+					listLevel = n
+				}
+			case "t":
+				var s string
+				if err := p.dec.DecodeElement(&s, &t); err != nil {
+					return err
+				}
+				text.WriteString(s)
+			case "tab":
+				text.WriteString("\t")
+			case "br", "cr":
+				if attr(t, "type") == "page" {
+					p.hard++
+					if strings.TrimSpace(text.String()) == "" {
+						startHard = p.hard
+					}
+				} else {
+					text.WriteString("\n")
+				}
+			case "lastRenderedPageBreak":
+				p.page++
+				p.rendered = true
+				if strings.TrimSpace(text.String()) == "" {
+					startPage = p.page
+				}
+			case "footnoteReference":
+				fmt.Fprintf(&text, "[^%s]", attr(t, "id"))
+			case "delText", "instrText":
+				// Deleted tracked changes and field codes (TOC, PAGEREF, ...)
+				// are not content.
+				if err := p.dec.Skip(); err != nil {
+					return err
+				}
+			}
+		case xml.EndElement:
+			if t.Name.Local != "p" {
+				continue
+			}
+			content := strings.TrimSpace(text.String())
+			if content == "" {
+				return nil
+			}
+			block := textBlock{kind: blockParagraph, text: content, page: startPage, hard: startHard}
+			if level, ok := p.headingLevels[styleID]; ok {
+				block.kind, block.level = blockHeading, level
+			} else if outline >= 0 {
+				block.kind, block.level = blockHeading, outline+1
+			} else if m := blockHeadingStyle.FindStringSubmatch(styleID); m != nil {
+				block.kind, block.level = blockHeading, int(m[1][0]-'0')
+			} else if listLevel >= 0 {
+				block.kind, block.level = blockListItem, listLevel
+			}
+			p.blocks = append(p.blocks, block)
+			return nil
+		}
+	}
+}
+
+// parseTable reads one w:tbl element. Nested tables are flattened into the
+// text of the enclosing cell.
+func (p *docxParser) parseTable() ([][]string, error) {
+	var rows [][]string
+	for {
+		tok, err := p.dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "tr":
+				rows = append(rows, nil)
+			case "tc":
+				cell := &docxParser{dec: p.dec, headingLevels: p.headingLevels, page: p.page, hard: p.hard}
+				if err := cell.parseContainer("tc"); err != nil {
+					return nil, err
+				}
+				p.page, p.hard = cell.page, cell.hard
+				p.rendered = p.rendered || cell.rendered
+				var texts []string
+				for _, b := range cell.blocks {
+					if b.kind == blockTable {
+						texts = append(texts, flattenRows(b.rows))
+					} else {
+						texts = append(texts, b.text)
+					}
+				}
+				if len(rows) == 0 {
+					rows = append(rows, nil)
+				}
+				rows[len(rows)-1] = append(rows[len(rows)-1], strings.Join(texts, " "))
+			}
+		case xml.EndElement:
+			if t.Name.Local == "tbl" {
+				return rows, nil
+			}
+		}
+	}
+}
+
+// renderBlocks writes blocks as Markdown and records the offset where each new
+// page begins. The table is nil when every block is on page 1 (or has no page).
+func renderBlocks(blocks, footnotes []textBlock) (string, map[int]int) {
+	var b strings.Builder
+	offsetToPage := map[int]int{0: 1}
+	page := 1
+
+	for i, block := range blocks {
+		if i > 0 {
+			if block.kind == blockListItem && blocks[i-1].kind == blockListItem {
+				b.WriteString("\n")
+			} else {
+				b.WriteString("\n\n")
+			}
+		}
+		if block.page > page {
+			page = block.page
+			offsetToPage[b.Len()] = page
+		}
+
+		switch block.kind {
+		case blockHeading:
+			level := block.level
+			if level > 6 {
+				level = 6
+			}
+			b.WriteString(strings.Repeat("#", level) + " " + strings.Join(strings.Fields(block.text), " "))
+		case blockListItem:
+			b.WriteString(strings.Repeat("  ", block.level) + "- " + strings.ReplaceAll(block.text, "\n", " "))
+		case blockTable:
+			writePipeTable(&b, block.rows)
+		default:
+			b.WriteString(block.text)
+		}
+	}
+
+	if len(footnotes) > 0 {
+		b.WriteString("\n\n# Footnotes")
+		for _, note := range footnotes {
+			b.WriteString("\n\n" + note.text)
+		}
+	}
+
+	if len(offsetToPage) == 1 {
+		return b.String(), nil
+	}
+	return b.String(), offsetToPage
+}
+
+// writePipeTable renders rows as a Markdown pipe table, using the first row
+// as the header.
+func writePipeTable(b *strings.Builder, rows [][]string) {
+	width := 0
+	for _, row := range rows {
+		if len(row) > width {
+			width = len(row)
+		}
+	}
+	for i, row := range rows {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString("|")
+		for c := 0; c < width; c++ {
+			cell := ""
+			if c < len(row) {
+				cell = strings.ReplaceAll(strings.Join(strings.Fields(row[c]), " "), "|", `\|`)
+			}
+			b.WriteString(" " + cell + " |")
+		}
+		if i == 0 {
+			b.WriteString("\n|" + strings.Repeat("---|", width))
+		}
+	}
+}
+
+// flattenRows joins a nested table's cells into plain text.
+func flattenRows(rows [][]string) string {
+	var lines []string
+	for _, row := range rows {
+		lines = append(lines, strings.Join(row, " ; "))
+	}
+	return strings.Join(lines, " / ")
+}
+
+// attr returns the value of the attribute with the given local name.
+func attr(se xml.StartElement, local string) string {
+	for _, a := range se.Attr {
+		if a.Name.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
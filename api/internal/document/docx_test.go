@@ -0,0 +1,82 @@
+package document
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseDOCXWithChunks(t *testing.T) {
+	const w = `xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"`
+	parts := map[string]string{
+		"word/styles.xml": `<w:styles ` + w + `>
+<w:style w:type="paragraph" w:styleId="Rubrik1"><w:name w:val="heading 1"/></w:style>
+<w:style w:type="paragraph" w:styleId="Rubrik2"><w:name w:val="heading 2"/></w:style>
+</w:styles>`,
+		"word/document.xml": `<w:document ` + w + `><w:body>
+<w:p><w:pPr><w:pStyle w:val="Rubrik1"/></w:pPr><w:r><w:t>Due diligence</w:t></w:r></w:p>
+<w:p><w:pPr><w:pStyle w:val="Rubrik2"/></w:pPr><w:r><w:t>Contracts</w:t></w:r></w:p>
+<w:p><w:r><w:t xml:space="preserve">The supplier agreement </w:t></w:r><w:r><w:t>expires in 2025.</w:t><w:footnoteReference w:id="1"/></w:r></w:p>
+<w:p><w:pPr><w:numPr><w:ilvl w:val="0"/></w:numPr></w:pPr><w:r><w:t>Change of control clause</w:t></w:r></w:p>
+<w:tbl><w:tr><w:tc><w:p><w:r><w:t>Party</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>Value</w:t></w:r></w:p></w:tc></w:tr>
+<w:tr><w:tc><w:p><w:r><w:t>Acme AB</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>2 MSEK</w:t></w:r></w:p></w:tc></w:tr></w:tbl>
+<w:p><w:pPr><w:pStyle w:val="Rubrik2"/></w:pPr><w:r><w:lastRenderedPageBreak/><w:t>Employees</w:t></w:r></w:p>
+<w:p><w:r><w:t>Twelve employees transfer with the business.</w:t></w:r></w:p>
+<w:sectPr/></w:body></w:document>`,
+		"word/footnotes.xml": `<w:footnotes ` + w + `>
+<w:footnote w:type="separator" w:id="-1"><w:p><w:r><w:separator/></w:r></w:p></w:footnote>
+<w:footnote w:id="1"><w:p><w:r><w:t>Per the 2019 amendment.</w:t></w:r></w:p></w:footnote>
+</w:footnotes>`,
+	}
+
+	path := filepath.Join(t.TempDir(), "dd.docx")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	for name, body := range parts {
+		pw, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pw.Write([]byte(body))
+	}
+	zw.Close()
+	f.Close()
+
+	if ft, err := DetectFileType(path); err != nil || ft != "docx" {
+		t.Fatalf("DetectFileType() = %q, %v, want docx", ft, err)
+	}
+
+	chunks, err := ParseDOCXWithChunks(path)
+	if err != nil {
+		t.Fatalf("ParseDOCXWithChunks() error = %v", err)
+	}
+
+	wantTitles := []string{"Due diligence > Contracts", "Due diligence > Employees", "Footnotes"}
+	if len(chunks) != len(wantTitles) {
+		t.Fatalf("ParseDOCXWithChunks() returned %d chunks, want %d", len(chunks), len(wantTitles))
+	}
+	for i, want := range wantTitles {
+		if chunks[i].ChapterTitle != want {
+			t.Errorf("chunk %d title = %q, want %q", i, chunks[i].ChapterTitle, want)
+		}
+	}
+
+	contracts := chunks[0].Content
+	for _, want := range []string{"The supplier agreement expires in 2025.[^1]", "- Change of control clause", "| Party | Value |\n|---|---|\n| Acme AB | 2 MSEK |"} {
+		if !strings.Contains(contracts, want) {
+			t.Errorf("chunk 0 missing %q:\n%s", want, contracts)
+		}
+	}
+	if !strings.Contains(chunks[2].Content, "[^1]: Per the 2019 amendment.") {
+		t.Errorf("footnote missing: %q", chunks[2].Content)
+	}
+
+	if chunks[0].PageStart == nil || *chunks[0].PageStart != 1 || chunks[1].PageStart == nil || *chunks[1].PageStart != 2 {
+		t.Errorf("page hints = %v, %v, want 1 and 2", chunks[0].PageStart, chunks[1].PageStart)
+	}
+}
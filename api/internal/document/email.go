@@ -0,0 +1,468 @@
+package document
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// EmailAddress is one mailbox from a From/To/Cc header.
+type EmailAddress struct {
+	Name  string `json:"name,omitempty"`
+	Email string `json:"email"`
+}
+
+// Label returns the display name, or the address if there is none.
+func (a EmailAddress) Label() string {
+	if a.Name != "" {
+		return a.Name
+	}
+	return a.Email
+}
+
+// EmailMessage is a parsed RFC 5322 message reduced to what ingestion needs.
+type EmailMessage struct {
+	MessageID  string
+	InReplyTo  string
+	References []string
+	ThreadID   string // Message-ID of the thread root, set by ThreadEmails
+	From       EmailAddress
+	To         []EmailAddress
+	Cc         []EmailAddress
+	Date       time.Time
+	Subject    string
+	Body       string
+}
+
+// maxEmailLine bounds a single mbox line (long base64 bodies without wraps).
+const maxEmailLine = 10 * 1024 * 1024
+
+var (
+	mboxFromLine = regexp.MustCompile(`^>+From `)
+	msgIDPattern = regexp.MustCompile(`<[^<>\s]+>`)
+	htmlBreak    = regexp.MustCompile(`(?i)<br\s*/?>|</p>|</div>|</li>|</tr>`)
+	htmlTag      = regexp.MustCompile(`(?s)<(script|style)[^>]*>.*?</(script|style)>|<[^>]+>`)
+	blankLines   = regexp.MustCompile(`\n{3,}`)
+)
+
+// ParseEML parses a single RFC 5322 message.
+func ParseEML(r io.Reader) (*EmailMessage, error) {
+	msg, err := mail.ReadMessage(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read email: %w", err)
+	}
+
+	dec := new(mime.WordDecoder)
+	decode := func(s string) string {
+		if out, err := dec.DecodeHeader(s); err == nil {
+			return strings.TrimSpace(out)
+		}
+		return strings.TrimSpace(s)
+	}
+
+	em := &EmailMessage{
+		MessageID:  firstMessageID(msg.Header.Get("Message-ID")),
+		InReplyTo:  firstMessageID(msg.Header.Get("In-Reply-To")),
+		References: msgIDPattern.FindAllString(msg.Header.Get("References"), -1),
+		Subject:    decode(msg.Header.Get("Subject")),
+	}
+
+	if from := parseAddresses(msg.Header.Get("From")); len(from) > 0 {
+		em.From = from[0]
+	}
+	em.To = parseAddresses(msg.Header.Get("To"))
+	em.Cc = parseAddresses(msg.Header.Get("Cc"))
+
+	if date, err := msg.Header.Date(); err == nil {
+		em.Date = date
+	}
+
+	body, err := readBody(msg.Header.Get("Content-Type"), msg.Header.Get("Content-Transfer-Encoding"), msg.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read email body: %w", err)
+	}
+	em.Body = strings.TrimSpace(body)
+
+	return em, nil
+}
+
+// ParseMBOX splits an mbox file into messages. Both mboxo and mboxrd
+// ">From " quoting is undone. A message that cannot be parsed is skipped
+// and named in the warnings.
+func ParseMBOX(r io.Reader) ([]*EmailMessage, []string, error) {
+	var messages []*EmailMessage
+	warnings, err := ScanMBOX(r, func(m *EmailMessage) {
+		messages = append(messages, m)
+	})
+	if err != nil {
+		return nil, warnings, err
+	}
+	if len(messages) == 0 {
+		return nil, warnings, fmt.Errorf("no messages found in mbox")
+	}
+	return messages, warnings, nil
+}
+
+// ScanMBOX calls fn with each message of an mbox file in turn, holding only
+// the message being read. Messages that cannot be parsed are skipped, each
+// with a warning; only a failure to read the file is an error.
+func ScanMBOX(r io.Reader, fn func(*EmailMessage)) ([]string, error) {
+	var warnings []string
+	var current bytes.Buffer
+	inMessage := false
+	n := 0
+
+	flush := func() {
+		if !inMessage || current.Len() == 0 {
+			return
+		}
+		n++
+		msg, err := ParseEML(bytes.NewReader(current.Bytes()))
+		current.Reset()
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("message %d skipped: %v", n, err))
+			return
+		}
+		fn(msg)
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxEmailLine)
+	prevBlank := true
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "From ") && prevBlank {
+			flush()
+			inMessage = true
+			prevBlank = false
+			continue
+		}
+		if inMessage {
+			if mboxFromLine.MatchString(line) {
+				line = line[1:]
+			}
+			current.WriteString(line)
+			current.WriteString("\r\n")
+		}
+		prevBlank = strings.TrimSpace(line) == ""
+	}
+	if err := scanner.Err(); err != nil {
+		return warnings, fmt.Errorf("failed to read mbox: %w", err)
+	}
+	flush()
+	return warnings, nil
+}
+
+// ThreadEmails sets ThreadID on each message to the Message-ID of its thread
+// root, following In-Reply-To and References. Messages whose parent is not in
+// the set start at the oldest referenced ID, so replies to the same missing
+// message still share a thread.
+func ThreadEmails(messages []*EmailMessage) {
+	parent := make(map[string]string)
+	for _, m := range messages {
+		if m.MessageID == "" {
+			continue
+		}
+		if p := m.Parent(); p != "" && p != m.MessageID {
+			parent[m.MessageID] = p
+		}
+		// References lists ancestors oldest first.
+		for i := 1; i < len(m.References); i++ {
+			if _, ok := parent[m.References[i]]; !ok && m.References[i] != m.References[i-1] {
+				parent[m.References[i]] = m.References[i-1]
+			}
+		}
+	}
+
+	for _, m := range messages {
+		root := m.MessageID
+		if root == "" {
+			root = m.Parent()
+		}
+		seen := map[string]bool{}
+		for root != "" && !seen[root] {
+			seen[root] = true
+			p, ok := parent[root]
+			if !ok {
+				break
+			}
+			root = p
+		}
+		m.ThreadID = root
+	}
+}
+
+// Parent returns the Message-ID this message replies to.
+func (m *EmailMessage) Parent() string {
+	if m.InReplyTo != "" {
+		return m.InReplyTo
+	}
+	if len(m.References) > 0 {
+		return m.References[len(m.References)-1]
+	}
+	return ""
+}
+
+// Metadata returns the header fields stored on the message's chunk.
+func (m *EmailMessage) Metadata() map[string]interface{} {
+	meta := map[string]interface{}{
+		"message_id": m.MessageID,
+		"subject":    m.Subject,
+		"from":       m.From,
+		"to":         m.To,
+	}
+	if len(m.Cc) > 0 {
+		meta["cc"] = m.Cc
+	}
+	if !m.Date.IsZero() {
+		meta["date"] = m.Date.Format(time.RFC3339)
+	}
+	if m.InReplyTo != "" {
+		meta["in_reply_to"] = m.InReplyTo
+	}
+	if len(m.References) > 0 {
+		meta["references"] = m.References
+	}
+	if m.ThreadID != "" {
+		meta["thread_id"] = m.ThreadID
+	}
+	return meta
+}
+
+// EmailChunks turns messages into one chunk each. The chunk text repeats the
+// key headers so extraction sees who wrote to whom and when.
+func EmailChunks(messages []*EmailMessage) []Chunk {
+	ThreadEmails(messages)
+
+	chunks := make([]Chunk, 0, len(messages))
+	for i, m := range messages {
+		chunks = append(chunks, emailChunk(i, m))
+	}
+	return chunks
+}
+
+// emailChunk returns the chunk for the i-th message
+func emailChunk(i int, m *EmailMessage) Chunk {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\n", formatAddresses([]EmailAddress{m.From}))
+	if len(m.To) > 0 {
+		fmt.Fprintf(&b, "To: %s\n", formatAddresses(m.To))
+	}
+	if len(m.Cc) > 0 {
+		fmt.Fprintf(&b, "Cc: %s\n", formatAddresses(m.Cc))
+	}
+	if !m.Date.IsZero() {
+		fmt.Fprintf(&b, "Date: %s\n", m.Date.Format("2006-01-02 15:04 -0700"))
+	}
+	fmt.Fprintf(&b, "Subject: %s\n\n%s", m.Subject, m.Body)
+
+	title := m.Subject
+	if title == "" {
+		title = "(no subject)"
+	}
+
+	return Chunk{
+		ChunkIndex:        i,
+		Content:           strings.TrimSpace(b.String()),
+		ChapterTitle:      title,
+		ChapterNumber:     0,
+		SectionID:         m.MessageID,
+		SectionName:       m.Subject,
+		Metadata:          m.Metadata(),
+		NarrativePosition: i,
+		PageStart:         nil,
+		PageEnd:           nil,
+	}
+}
+
+// ParseEMLWithChunks parses a single .eml message into one chunk.
+func ParseEMLWithChunks(r io.Reader) ([]Chunk, error) {
+	msg, err := ParseEML(r)
+	if err != nil {
+		return nil, err
+	}
+	return EmailChunks([]*EmailMessage{msg}), nil
+}
+
+// ParseMBOXWithChunks parses an mbox file into one chunk per message. Each
+// body is kept only in its chunk; threading runs afterwards over the
+// headers. Warnings name the messages that were skipped.
+func ParseMBOXWithChunks(r io.Reader) ([]Chunk, []string, error) {
+	var chunks []Chunk
+	var headers []*EmailMessage
+	warnings, err := ScanMBOX(r, func(m *EmailMessage) {
+		chunks = append(chunks, emailChunk(len(chunks), m))
+		h := *m
+		h.Body = ""
+		headers = append(headers, &h)
+	})
+	if err != nil {
+		return nil, warnings, err
+	}
+	if len(chunks) == 0 {
+		return nil, warnings, fmt.Errorf("no messages found in mbox")
+	}
+
+	ThreadEmails(headers)
+	for i, h := range headers {
+		if h.ThreadID != "" {
+			chunks[i].Metadata["thread_id"] = h.ThreadID
+		}
+	}
+	return chunks, warnings, nil
+}
+
+// readBody returns the text of a message body, preferring text/plain parts
+// and falling back to tag-stripped text/html.
+func readBody(contentType, transferEncoding string, body io.Reader) (string, error) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType, params = "text/plain", map[string]string{}
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+		var plain, html string
+		for {
+			part, err := mr.NextPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return "", err
+			}
+			if disp, _, _ := mime.ParseMediaType(part.Header.Get("Content-Disposition")); disp == "attachment" {
+				continue
+			}
+			text, err := readBody(part.Header.Get("Content-Type"), part.Header.Get("Content-Transfer-Encoding"), part)
+			if err != nil {
+				return "", err
+			}
+			partType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+			if partType == "text/html" {
+				if html == "" {
+					html = text
+				}
+			} else if plain == "" {
+				plain = text
+			}
+		}
+		if plain != "" {
+			return plain, nil
+		}
+		return html, nil
+	}
+
+	if !strings.HasPrefix(mediaType, "text/") {
+		return "", nil
+	}
+
+	switch strings.ToLower(strings.TrimSpace(transferEncoding)) {
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, &lineStripper{r: body})
+	}
+
+	raw, err := io.ReadAll(body)
+	if err != nil {
+		return "", err
+	}
+
+	text := decodeCharset(raw, params["charset"])
+	if mediaType == "text/html" {
+		text = htmlToText(text)
+	}
+	return strings.ReplaceAll(text, "\r\n", "\n"), nil
+}
+
+// decodeCharset converts Latin-1 family bodies to UTF-8. Other charsets are
+// passed through unchanged.
+func decodeCharset(raw []byte, charset string) string {
+	switch strings.ToLower(charset) {
+	case "iso-8859-1", "latin1", "windows-1252", "iso-8859-15":
+		runes := make([]rune, len(raw))
+		for i, b := range raw {
+			runes[i] = rune(b)
+		}
+		return string(runes)
+	}
+	return string(raw)
+}
+
+// htmlToText strips tags from an HTML body.
+func htmlToText(s string) string {
+	s = htmlBreak.ReplaceAllString(s, "\n")
+	s = htmlTag.ReplaceAllString(s, "")
+	r := strings.NewReplacer("&nbsp;", " ", "&amp;", "&", "&lt;", "<", "&gt;", ">", "&quot;", `"`, "&#39;", "'")
+	s = r.Replace(s)
+	return blankLines.ReplaceAllString(s, "\n\n")
+}
+
+// lineStripper drops CR/LF so base64 bodies split across lines decode.
+type lineStripper struct {
+	r io.Reader
+}
+
+func (l *lineStripper) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	j := 0
+	for _, c := range p[:n] {
+		if c != '\r' && c != '\n' {
+			p[j] = c
+			j++
+		}
+	}
+	return j, err
+}
+
+// parseAddresses parses an address list header, tolerating malformed entries.
+func parseAddresses(header string) []EmailAddress {
+	if strings.TrimSpace(header) == "" {
+		return nil
+	}
+	parser := mail.AddressParser{WordDecoder: new(mime.WordDecoder)}
+	list, err := parser.ParseList(header)
+	if err != nil {
+		// Fall back to splitting on commas and keeping what parses.
+		for _, part := range strings.Split(header, ",") {
+			if addr, err := parser.Parse(part); err == nil {
+				list = append(list, addr)
+			}
+		}
+	}
+	out := make([]EmailAddress, 0, len(list))
+	for _, a := range list {
+		out = append(out, EmailAddress{Name: a.Name, Email: strings.ToLower(a.Address)})
+	}
+	return out
+}
+
+func formatAddresses(addrs []EmailAddress) string {
+	parts := make([]string, len(addrs))
+	for i, a := range addrs {
+		if a.Name != "" {
+			parts[i] = fmt.Sprintf("%s <%s>", a.Name, a.Email)
+		} else {
+			parts[i] = a.Email
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// firstMessageID extracts the first <id> from a header value.
+func firstMessageID(header string) string {
+	if id := msgIDPattern.FindString(header); id != "" {
+		return id
+	}
+	return strings.TrimSpace(header)
+}
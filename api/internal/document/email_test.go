@@ -0,0 +1,134 @@
+package document
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseMBOXWithChunks(t *testing.T) {
+	mbox := "From anna@example.se Mon Sep 16 09:12:00 2024\n" +
+		"Message-ID: <1@example.se>\n" +
+		"From: Anna Berg <Anna@example.se>\n" +
+		"To: Jonas Ek <jonas@example.se>\n" +
+		"Date: Mon, 16 Sep 2024 09:12:00 +0200\n" +
+		"Subject: =?utf-8?q?M=C3=B6te_om_avtalet?=\n" +
+		"Content-Type: text/plain; charset=utf-8\n" +
+		"Content-Transfer-Encoding: quoted-printable\n" +
+		"\n" +
+		"Vi ses i morgon kl 10.\n" +
+		">From the minutes: agreed.\n" +
+		"\n" +
+		"From jonas@example.se Mon Sep 16 10:00:00 2024\n" +
+		"Message-ID: <2@example.se>\n" +
+		"In-Reply-To: <1@example.se>\n" +
+		"References: <1@example.se>\n" +
+		"From: jonas@example.se\n" +
+		"To: Anna Berg <anna@example.se>\n" +
+		"Cc: legal@example.se\n" +
+		"Date: Mon, 16 Sep 2024 10:00:00 +0200\n" +
+		"Subject: Re: Möte om avtalet\n" +
+		"Content-Type: multipart/alternative; boundary=XX\n" +
+		"\n" +
+		"--XX\n" +
+		"Content-Type: text/html\n" +
+		"\n" +
+		"<p>Bra, <b>tack</b>.</p>\n" +
+		"--XX\n" +
+		"Content-Type: text/plain\n" +
+		"\n" +
+		"Bra, tack.\n" +
+		"--XX--\n"
+
+	chunks, warnings, err := ParseMBOXWithChunks(strings.NewReader(mbox))
+	if err != nil {
+		t.Fatalf("ParseMBOXWithChunks() error = %v", err)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("warnings = %v, want none", warnings)
+	}
+	if len(chunks) != 2 {
+		t.Fatalf("ParseMBOXWithChunks() returned %d chunks, want 2", len(chunks))
+	}
+
+	first := chunks[0]
+	if first.ChapterTitle != "Möte om avtalet" {
+		t.Errorf("subject = %q, want decoded subject", first.ChapterTitle)
+	}
+	if from := first.Metadata["from"].(EmailAddress); from.Email != "anna@example.se" || from.Name != "Anna Berg" {
+		t.Errorf("from = %+v", from)
+	}
+	if first.Metadata["date"] != "2024-09-16T09:12:00+02:00" {
+		t.Errorf("date = %v", first.Metadata["date"])
+	}
+	if !strings.Contains(first.Content, "From the minutes: agreed.") {
+		t.Errorf("mboxrd quoting not undone: %q", first.Content)
+	}
+
+	reply := chunks[1]
+	if reply.Metadata["in_reply_to"] != "<1@example.se>" || reply.Metadata["thread_id"] != "<1@example.se>" {
+		t.Errorf("reply threading = %v / %v", reply.Metadata["in_reply_to"], reply.Metadata["thread_id"])
+	}
+	if cc := reply.Metadata["cc"].([]EmailAddress); len(cc) != 1 || cc[0].Email != "legal@example.se" {
+		t.Errorf("cc = %+v", cc)
+	}
+	if !strings.HasSuffix(reply.Content, "Bra, tack.") {
+		t.Errorf("reply body should prefer text/plain: %q", reply.Content)
+	}
+}
+
+func TestParseMBOXSkipsMalformedMessage(t *testing.T) {
+	mbox := "From anna@example.se Mon Sep 16 09:12:00 2024\n" +
+		"Message-ID: <1@example.se>\n" +
+		"From: anna@example.se\n" +
+		"Subject: Avtalet\n" +
+		"\n" +
+		"Första.\n" +
+		"\n" +
+		"From broken@example.se Mon Sep 16 09:30:00 2024\n" +
+		"this line is not a header\n" +
+		"Subject: Trasig\n" +
+		"\n" +
+		"Andra.\n" +
+		"\n" +
+		"From jonas@example.se Mon Sep 16 10:00:00 2024\n" +
+		"Message-ID: <3@example.se>\n" +
+		"References: <0@example.se> <1@example.se>\n" +
+		"From: jonas@example.se\n" +
+		"Subject: Re: Avtalet\n" +
+		"\n" +
+		"Tredje.\n"
+
+	chunks, warnings, err := ParseMBOXWithChunks(strings.NewReader(mbox))
+	if err != nil {
+		t.Fatalf("ParseMBOXWithChunks() error = %v", err)
+	}
+	if len(chunks) != 2 {
+		t.Fatalf("ParseMBOXWithChunks() returned %d chunks, want 2", len(chunks))
+	}
+	if len(warnings) != 1 || !strings.HasPrefix(warnings[0], "message 2 skipped") {
+		t.Errorf("warnings = %q, want message 2 skipped", warnings)
+	}
+	for i, c := range chunks {
+		if c.ChunkIndex != i || c.NarrativePosition != i {
+			t.Errorf("chunk %d index = %d, position = %d", i, c.ChunkIndex, c.NarrativePosition)
+		}
+	}
+	if !strings.HasSuffix(chunks[1].Content, "Tredje.") {
+		t.Errorf("second chunk = %q, want the third message", chunks[1].Content)
+	}
+	// Threading runs after the scan, over every message kept
+	for i, c := range chunks {
+		if c.Metadata["thread_id"] != "<0@example.se>" {
+			t.Errorf("chunk %d thread_id = %v, want <0@example.se>", i, c.Metadata["thread_id"])
+		}
+	}
+
+	messages, warnings, err := ParseMBOX(strings.NewReader(mbox))
+	if err != nil || len(messages) != 2 || len(warnings) != 1 {
+		t.Errorf("ParseMBOX() = %d messages, %q, %v; want 2, one warning, nil", len(messages), warnings, err)
+	}
+
+	if _, _, err := ParseMBOXWithChunks(strings.NewReader(mbox[strings.Index(mbox, "From broken"):strings.Index(mbox, "From jonas")])); err == nil {
+		t.Error("ParseMBOXWithChunks() with only a malformed message: error = nil")
+	}
+}
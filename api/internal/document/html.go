@@ -0,0 +1,627 @@
+package document
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// HTML pages are reduced to their main content and rendered to Markdown so
+// headings become chunk boundaries through MarkdownChunker. The parser is a
+// small tolerant tree builder: saved web pages are rarely well-formed, and
+// only block structure and text matter here.
+
+// HTMLPage is the readable content of one web page.
+type HTMLPage struct {
+	Title        string
+	CanonicalURL string
+	URL          string // retrieval URL, for pages read from a web archive
+	Markdown     string
+}
+
+// Metadata returns the page fields stored as source or chunk metadata.
+func (p *HTMLPage) Metadata() map[string]interface{} {
+	meta := map[string]interface{}{}
+	if p.Title != "" {
+		meta["title"] = p.Title
+	}
+	if p.CanonicalURL != "" {
+		meta["canonical_url"] = p.CanonicalURL
+	}
+	if p.URL != "" {
+		meta["url"] = p.URL
+	}
+	return meta
+}
+
+var (
+	htmlVoidElements = map[string]bool{
+		"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+		"input": true, "link": true, "meta": true, "param": true, "source": true, "track": true, "wbr": true,
+	}
+	htmlRawTextElements = map[string]bool{"script": true, "style": true, "textarea": true, "title": true, "noscript": true}
+
+	// htmlBoilerplateElements never hold article content.
+	htmlBoilerplateElements = map[string]bool{
+		"nav": true, "header": true, "footer": true, "aside": true, "form": true, "script": true,
+		"style": true, "noscript": true, "iframe": true, "svg": true, "button": true, "select": true,
+		"template": true, "dialog": true, "menu": true, "head": true, "title": true,
+	}
+
+	htmlBlockElements = map[string]bool{
+		"address": true, "article": true, "blockquote": true, "dd": true, "div": true, "dl": true, "dt": true,
+		"fieldset": true, "figcaption": true, "figure": true, "h1": true, "h2": true, "h3": true, "h4": true,
+		"h5": true, "h6": true, "hr": true, "li": true, "main": true, "ol": true, "p": true, "pre": true,
+		"section": true, "table": true, "ul": true,
+	}
+
+	htmlBoilerplateClass = regexp.MustCompile(`(?i)(^|[\s_-])(nav|navbar|menu|footer|header|sidebar|cookie|consent|banner|share|sharing|social|comments?|related|breadcrumbs?|advert|ads|promo|newsletter|subscribe|paywall|popup|modal)($|[\s_-])`)
+	htmlCharset          = regexp.MustCompile(`(?i)<meta[^>]+charset=["']?([\w-]+)`)
+	htmlWhitespace       = regexp.MustCompile(`\s+`)
+)
+
+// htmlNode is an element or text node.
+type htmlNode struct {
+	tag      string // empty for text nodes
+	attrs    map[string]string
+	text     string
+	children []*htmlNode
+	parent   *htmlNode
+}
+
+// ParseHTML extracts the title, canonical URL and main content of a page.
+func ParseHTML(content string) *HTMLPage {
+	if !utf8.ValidString(content) {
+		if m := htmlCharset.FindStringSubmatch(content); m != nil {
+			content = decodeCharset([]byte(content), m[1])
+		}
+	}
+
+	root := parseHTMLTree(content)
+	page := &HTMLPage{}
+
+	if title := findFirst(root, func(n *htmlNode) bool { return n.tag == "title" }); title != nil {
+		page.Title = collapseSpace(textContent(title))
+	}
+	for _, n := range findAll(root, func(n *htmlNode) bool { return n.tag == "meta" || n.tag == "link" }) {
+		switch {
+		case n.tag == "link" && hasToken(n.attrs["rel"], "canonical") && page.CanonicalURL == "":
+			page.CanonicalURL = strings.TrimSpace(n.attrs["href"])
+		case n.tag == "meta" && n.attrs["property"] == "og:url" && page.CanonicalURL == "":
+			page.CanonicalURL = strings.TrimSpace(n.attrs["content"])
+		case n.tag == "meta" && n.attrs["property"] == "og:title" && page.Title == "":
+			page.Title = collapseSpace(n.attrs["content"])
+		}
+	}
+
+	body := findFirst(root, func(n *htmlNode) bool { return n.tag == "body" })
+	if body == nil {
+		body = root
+	}
+	stripBoilerplate(body)
+
+	r := &htmlRenderer{}
+	r.render(mainContent(body))
+	r.flush()
+	page.Markdown, _ = renderBlocks(r.blocks, nil)
+
+	return page
+}
+
+// mainContent picks the element holding the article: the largest <article>,
+// then <main>, then the element with the most paragraph text, then body.
+func mainContent(body *htmlNode) *htmlNode {
+	var best *htmlNode
+	bestLen := 0
+	for _, n := range findAll(body, func(n *htmlNode) bool { return n.tag == "article" }) {
+		if l := len(textContent(n)); l > bestLen {
+			best, bestLen = n, l
+		}
+	}
+	if best != nil {
+		return best
+	}
+
+	if main := findFirst(body, func(n *htmlNode) bool { return n.tag == "main" || n.attrs["role"] == "main" }); main != nil {
+		return main
+	}
+
+	bestScore := 0
+	for _, n := range findAll(body, func(n *htmlNode) bool { return n.tag != "" }) {
+		score := 0
+		for _, c := range n.children {
+			if c.tag == "p" {
+				score += len(textContent(c))
+			}
+		}
+		if score > bestScore {
+			best, bestScore = n, score
+		}
+	}
+	// A short winner is probably a teaser box; keep the whole body instead.
+	if best != nil && bestScore >= 500 {
+		return best
+	}
+	return body
+}
+
+// stripBoilerplate removes navigation, chrome and link farms in place.
+func stripBoilerplate(n *htmlNode) {
+	kept := n.children[:0]
+	for _, c := range n.children {
+		if c.tag != "" && isBoilerplate(c) {
+			continue
+		}
+		stripBoilerplate(c)
+		kept = append(kept, c)
+	}
+	n.children = kept
+}
+
+func isBoilerplate(n *htmlNode) bool {
+	// An article's own header holds its headline and byline.
+	if n.tag == "header" && hasAncestor(n, "article") {
+		return false
+	}
+	if htmlBoilerplateElements[n.tag] {
+		return true
+	}
+	if n.attrs["hidden"] != "" || n.attrs["aria-hidden"] == "true" {
+		return true
+	}
+	switch n.attrs["role"] {
+	case "navigation", "banner", "contentinfo", "complementary", "search":
+		return true
+	}
+	if n.tag == "article" || n.tag == "main" || n.tag == "body" {
+		return false
+	}
+	if htmlBoilerplateClass.MatchString(n.attrs["class"]) || htmlBoilerplateClass.MatchString(n.attrs["id"]) {
+		return true
+	}
+	// Lists and boxes that are mostly links are menus.
+	if n.tag == "ul" || n.tag == "ol" || n.tag == "div" {
+		total := len(collapseSpace(textContent(n)))
+		if total > 0 && total < 2000 {
+			linked := 0
+			for _, a := range findAll(n, func(c *htmlNode) bool { return c.tag == "a" }) {
+				linked += len(collapseSpace(textContent(a)))
+			}
+			return float64(linked)/float64(total) > 0.6
+		}
+	}
+	return false
+}
+
+// htmlRenderer turns an element tree into Markdown blocks.
+type htmlRenderer struct {
+	blocks    []textBlock
+	inline    strings.Builder
+	listDepth int
+	inList    bool // inline buffer is a list item
+}
+
+func (r *htmlRenderer) flush() {
+	text := strings.TrimSpace(r.inline.String())
+	r.inline.Reset()
+	if text == "" {
+		r.inList = false
+		return
+	}
+	kind, level := blockParagraph, 0
+	if r.inList {
+		kind, level = blockListItem, r.listDepth-1
+	}
+	r.blocks = append(r.blocks, textBlock{kind: kind, text: text, level: level})
+	r.inList = false
+}
+
+func (r *htmlRenderer) render(n *htmlNode) {
+	if n.tag == "" {
+		r.inline.WriteString(htmlWhitespace.ReplaceAllString(n.text, " "))
+		return
+	}
+
+	switch n.tag {
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		r.flush()
+		if text := collapseSpace(textContent(n)); text != "" {
+			r.blocks = append(r.blocks, textBlock{kind: blockHeading, text: text, level: int(n.tag[1] - '0')})
+		}
+		return
+	case "br":
+		r.inline.WriteString("\n")
+		return
+	case "pre":
+		r.flush()
+		if code := strings.Trim(textContent(n), "\n"); strings.TrimSpace(code) != "" {
+			r.blocks = append(r.blocks, textBlock{kind: blockParagraph, text: "```\n" + code + "\n```"})
+		}
+		return
+	case "table":
+		r.flush()
+		if rows := tableRows(n); len(rows) > 0 {
+			r.blocks = append(r.blocks, textBlock{kind: blockTable, rows: rows})
+		}
+		return
+	case "blockquote":
+		r.flush()
+		inner := &htmlRenderer{}
+		for _, c := range n.children {
+			inner.render(c)
+		}
+		inner.flush()
+		for _, b := range inner.blocks {
+			r.blocks = append(r.blocks, textBlock{kind: blockParagraph, text: "> " + strings.ReplaceAll(b.text, "\n", "\n> ")})
+		}
+		return
+	case "ul", "ol":
+		r.flush()
+		r.listDepth++
+		for _, c := range n.children {
+			r.render(c)
+		}
+		r.flush()
+		r.listDepth--
+		return
+	case "li":
+		r.flush()
+		r.inList = r.listDepth > 0
+		for _, c := range n.children {
+			if c.tag == "ul" || c.tag == "ol" {
+				r.flush()
+			}
+			r.render(c)
+		}
+		r.flush()
+		return
+	case "img":
+		return
+	}
+
+	block := htmlBlockElements[n.tag]
+	if block {
+		r.flush()
+	}
+	for _, c := range n.children {
+		r.render(c)
+	}
+	if block {
+		r.flush()
+	}
+}
+
+// tableRows collects cell text row by row, ignoring nested tables' structure.
+func tableRows(table *htmlNode) [][]string {
+	var rows [][]string
+	var walk func(n *htmlNode)
+	walk = func(n *htmlNode) {
+		for _, c := range n.children {
+			switch c.tag {
+			case "tr":
+				var row []string
+				for _, cell := range c.children {
+					if cell.tag == "td" || cell.tag == "th" {
+						row = append(row, collapseSpace(textContent(cell)))
+					}
+				}
+				if len(row) > 0 {
+					rows = append(rows, row)
+				}
+			case "table":
+				// nested table: its text is already part of the parent cell
+			default:
+				walk(c)
+			}
+		}
+	}
+	walk(table)
+	return rows
+}
+
+// parseHTMLTree builds a node tree, recovering from unclosed and stray tags.
+func parseHTMLTree(s string) *htmlNode {
+	root := &htmlNode{tag: "#document", attrs: map[string]string{}}
+	stack := []*htmlNode{root}
+	top := func() *htmlNode { return stack[len(stack)-1] }
+
+	appendChild := func(n *htmlNode) {
+		n.parent = top()
+		n.parent.children = append(n.parent.children, n)
+	}
+	// closeTo pops up to and including the innermost open tag, unless a
+	// boundary element is reached first.
+	closeTo := func(tag string, boundaries ...string) bool {
+		for i := len(stack) - 1; i > 0; i-- {
+			if stack[i].tag == tag {
+				stack = stack[:i]
+				return true
+			}
+			for _, b := range boundaries {
+				if stack[i].tag == b {
+					return false
+				}
+			}
+		}
+		return false
+	}
+
+	i := 0
+	for i < len(s) {
+		if s[i] != '<' {
+			end := strings.IndexByte(s[i:], '<')
+			if end == -1 {
+				end = len(s) - i
+			}
+			appendChild(&htmlNode{text: html.UnescapeString(s[i : i+end])})
+			i += end
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(s[i:], "<!--"):
+			end := strings.Index(s[i+4:], "-->")
+			if end == -1 {
+				return root
+			}
+			i += 4 + end + 3
+			continue
+		case strings.HasPrefix(s[i:], "<!") || strings.HasPrefix(s[i:], "<?"):
+			end := strings.IndexByte(s[i:], '>')
+			if end == -1 {
+				return root
+			}
+			i += end + 1
+			continue
+		case strings.HasPrefix(s[i:], "</"):
+			end := strings.IndexByte(s[i:], '>')
+			if end == -1 {
+				return root
+			}
+			name := strings.ToLower(strings.TrimSpace(s[i+2 : i+end]))
+			closeTo(name)
+			i += end + 1
+			continue
+		}
+
+		name, attrs, selfClosing, next := parseTag(s, i)
+		if name == "" {
+			appendChild(&htmlNode{text: "<"})
+			i++
+			continue
+		}
+		i = next
+
+		// Implied end tags
+		switch {
+		case name == "li":
+			closeTo("li", "ul", "ol")
+		case name == "tr":
+			closeTo("tr", "table")
+		case name == "td" || name == "th":
+			if !closeTo("td", "tr", "table") {
+				closeTo("th", "tr", "table")
+			}
+		case name == "dt" || name == "dd":
+			if !closeTo("dt", "dl") {
+				closeTo("dd", "dl")
+			}
+		case name == "option":
+			closeTo("option", "select")
+		}
+		if htmlBlockElements[name] && top().tag == "p" {
+			stack = stack[:len(stack)-1]
+		}
+
+		n := &htmlNode{tag: name, attrs: attrs}
+		appendChild(n)
+
+		if htmlRawTextElements[name] && !selfClosing {
+			end := indexFold(s[i:], "</"+name)
+			if end == -1 {
+				end = len(s) - i
+			}
+			text := s[i : i+end]
+			if name == "title" || name == "textarea" {
+				text = html.UnescapeString(text)
+			}
+			n.children = []*htmlNode{{text: text, parent: n}}
+			i += end
+			if gt := strings.IndexByte(s[i:], '>'); gt != -1 {
+				i += gt + 1
+			}
+			continue
+		}
+
+		if !selfClosing && !htmlVoidElements[name] {
+			stack = append(stack, n)
+		}
+	}
+
+	return root
+}
+
+// parseTag reads a start tag at s[i]. It returns an empty name if s[i] does
+// not start a tag.
+func parseTag(s string, i int) (name string, attrs map[string]string, selfClosing bool, next int) {
+	j := i + 1
+	for j < len(s) && (isASCIILetter(s[j]) || (j > i+1 && s[j] >= '0' && s[j] <= '9') || s[j] == '-' && j > i+1) {
+		j++
+	}
+	if j == i+1 {
+		return "", nil, false, i
+	}
+	name = strings.ToLower(s[i+1 : j])
+	attrs = map[string]string{}
+
+	for j < len(s) {
+		for j < len(s) && isSpace(s[j]) {
+			j++
+		}
+		if j >= len(s) {
+			break
+		}
+		if s[j] == '>' {
+			return name, attrs, selfClosing, j + 1
+		}
+		if s[j] == '/' {
+			selfClosing = true
+			j++
+			continue
+		}
+		selfClosing = false
+
+		k := j
+		for k < len(s) && !isSpace(s[k]) && s[k] != '=' && s[k] != '>' && s[k] != '/' {
+			k++
+		}
+		key := strings.ToLower(s[j:k])
+		j = k
+		for j < len(s) && isSpace(s[j]) {
+			j++
+		}
+		val := ""
+		if j < len(s) && s[j] == '=' {
+			j++
+			for j < len(s) && isSpace(s[j]) {
+				j++
+			}
+			if j < len(s) && (s[j] == '"' || s[j] == '\'') {
+				q := s[j]
+				end := strings.IndexByte(s[j+1:], q)
+				if end == -1 {
+					end = len(s) - j - 1
+				}
+				val = s[j+1 : j+1+end]
+				j += end + 2
+			} else {
+				k := j
+				for k < len(s) && !isSpace(s[k]) && s[k] != '>' {
+					k++
+				}
+				val = s[j:k]
+				j = k
+			}
+		}
+		if key != "" {
+			attrs[key] = html.UnescapeString(val)
+		} else {
+			j++
+		}
+	}
+	return name, attrs, selfClosing, len(s)
+}
+
+func textContent(n *htmlNode) string {
+	if n.tag == "" {
+		return n.text
+	}
+	var b strings.Builder
+	for _, c := range n.children {
+		if c.tag == "br" {
+			b.WriteString("\n")
+			continue
+		}
+		if htmlBlockElements[c.tag] {
+			b.WriteString("\n")
+		}
+		b.WriteString(textContent(c))
+	}
+	return b.String()
+}
+
+func findFirst(n *htmlNode, match func(*htmlNode) bool) *htmlNode {
+	for _, c := range n.children {
+		if c.tag == "" {
+			continue
+		}
+		if match(c) {
+			return c
+		}
+		if found := findFirst(c, match); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func findAll(n *htmlNode, match func(*htmlNode) bool) []*htmlNode {
+	var out []*htmlNode
+	for _, c := range n.children {
+		if c.tag == "" {
+			continue
+		}
+		if match(c) {
+			out = append(out, c)
+		}
+		out = append(out, findAll(c, match)...)
+	}
+	return out
+}
+
+func hasAncestor(n *htmlNode, tag string) bool {
+	for p := n.parent; p != nil; p = p.parent {
+		if p.tag == tag {
+			return true
+		}
+	}
+	return false
+}
+
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func hasToken(list, token string) bool {
+	for _, t := range strings.Fields(strings.ToLower(list)) {
+		if t == token {
+			return true
+		}
+	}
+	return false
+}
+
+func indexFold(s, substr string) int {
+	return strings.Index(strings.ToLower(s), strings.ToLower(substr))
+}
+
+func isASCIILetter(c byte) bool { return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' }
+
+func isSpace(c byte) bool { return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' }
+
+// HTMLChunks chunks pages on their headings. Each chunk carries the page's
+// title and URLs as metadata; with several pages (a web archive) the chunk
+// titles are prefixed with the page title.
+func HTMLChunks(pages []*HTMLPage) []Chunk {
+	var chunks []Chunk
+	for _, page := range pages {
+		if strings.TrimSpace(page.Markdown) == "" {
+			continue
+		}
+		for _, c := range (&MarkdownChunker{}).Chunk(page.Markdown) {
+			if len(pages) > 1 && page.Title != "" && !strings.HasPrefix(c.ChapterTitle, page.Title) {
+				if c.ChapterTitle == "" || c.ChapterTitle == "Preamble" {
+					c.ChapterTitle = page.Title
+				} else {
+					c.ChapterTitle = page.Title + " > " + c.ChapterTitle
+				}
+			}
+			c.Metadata = page.Metadata()
+			c.ChunkIndex = len(chunks)
+			c.NarrativePosition = len(chunks)
+			chunks = append(chunks, c)
+		}
+	}
+	return chunks
+}
+
+// ParseHTMLWithChunks parses one HTML page into heading-structured chunks.
+func ParseHTMLWithChunks(content string) ([]Chunk, *HTMLPage) {
+	page := ParseHTML(content)
+	chunks := HTMLChunks([]*HTMLPage{page})
+	if len(chunks) == 0 {
+		chunks = (&WholeDocChunker{}).Chunk(page.Markdown)
+	}
+	return chunks, page
+}
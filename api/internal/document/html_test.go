@@ -0,0 +1,56 @@
+package document
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseHTMLWithChunks(t *testing.T) {
+	page := `<!DOCTYPE html>
+<html><head>
+<title>Styrelsen byter fasadentreprenör | GP</title>
+<link rel="canonical" href="https://www.gp.se/nyheter/fasad-123">
+</head><body>
+<header class="site-header"><a href="/">GP</a></header>
+<nav><ul><li><a href="/nyheter">Nyheter</a><li><a href="/sport">Sport</a></ul></nav>
+<div class="cookie-banner">Vi använder kakor.</div>
+<article>
+<header><h1>Styrelsen byter fasadentreprenör</h1></header>
+<p>Bostadsrättsföreningen Göta har sagt upp avtalet med Bygg &amp; Fasad AB.
+<h2>Budget</h2>
+<p>Kostnaden beräknas till 650 000 kr.</p>
+<table><tr><th>Post</th><th>Belopp</th><tr><td>Fasad</td><td>650 000</td></table>
+</article>
+<div class="related"><a href="/a">Läs också</a></div>
+<footer>© GP</footer>
+</body></html>`
+
+	chunks, meta := ParseHTMLWithChunks(page)
+
+	if meta.Title != "Styrelsen byter fasadentreprenör | GP" || meta.CanonicalURL != "https://www.gp.se/nyheter/fasad-123" {
+		t.Errorf("page metadata = %+v", meta)
+	}
+
+	wantTitles := []string{"Styrelsen byter fasadentreprenör", "Styrelsen byter fasadentreprenör > Budget"}
+	if len(chunks) != len(wantTitles) {
+		t.Fatalf("ParseHTMLWithChunks() returned %d chunks, want %d: %+v", len(chunks), len(wantTitles), chunks)
+	}
+	for i, want := range wantTitles {
+		if chunks[i].ChapterTitle != want {
+			t.Errorf("chunk %d title = %q, want %q", i, chunks[i].ChapterTitle, want)
+		}
+	}
+
+	all := chunks[0].Content + chunks[1].Content
+	for _, boilerplate := range []string{"Nyheter", "kakor", "Läs också", "© GP"} {
+		if strings.Contains(all, boilerplate) {
+			t.Errorf("boilerplate %q kept in content", boilerplate)
+		}
+	}
+	if !strings.Contains(chunks[0].Content, "Bygg & Fasad AB") {
+		t.Errorf("entities not decoded: %q", chunks[0].Content)
+	}
+	if !strings.Contains(chunks[1].Content, "| Post | Belopp |\n|---|---|\n| Fasad | 650 000 |") {
+		t.Errorf("table not rendered: %q", chunks[1].Content)
+	}
+}
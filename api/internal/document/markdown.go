@@ -0,0 +1,280 @@
+package document
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	mdATXHeading    = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?[ \t]*$`)
+	mdSetextMarker  = regexp.MustCompile(`^ {0,3}(=+|-+)[ \t]*$`)
+	mdFenceOpen     = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})")
+	mdListItem      = regexp.MustCompile(`^[ \t]*(?:[-*+]|\d{1,9}[.)])(?:[ \t]+|$)`)
+	mdTableDelimRow = regexp.MustCompile(`^[ \t]*\|?[ \t]*:?-+:?[ \t]*(?:\|[ \t]*:?-+:?[ \t]*)*\|?[ \t]*$`)
+)
+
+type mdBlockKind int
+
+const (
+	mdParagraph mdBlockKind = iota
+	mdHeading
+	mdList
+	mdTable
+	mdCode
+)
+
+// mdBlock is one top-level Markdown block. Blocks are the unit of splitting:
+// a chunk boundary never falls inside a block.
+type mdBlock struct {
+	kind  mdBlockKind
+	text  string
+	level int    // heading level, 1–6
+	title string // heading text
+}
+
+// MarkdownChunker splits Markdown on its heading structure. Each heading
+// section becomes a chunk titled with its heading breadcrumb
+// ("Section 3.2 > Payments"). Tables, lists and fenced code blocks are never
+// split; sections over the word budget are divided between blocks.
+type MarkdownChunker struct{}
+
+func (c *MarkdownChunker) Name() string { return "markdown" }
+
+func (c *MarkdownChunker) Chunk(content string) []Chunk {
+	blocks := parseMarkdownBlocks(content)
+
+	hasHeadings := false
+	for _, b := range blocks {
+		if b.kind == mdHeading {
+			hasHeadings = true
+			break
+		}
+	}
+
+	var chunks []Chunk
+	var path []string
+	var levels []int
+	var section []mdBlock
+
+	flushSection := func() {
+		if !hasBody(section) {
+			section = nil
+			return
+		}
+		title := strings.Join(path, " > ")
+		sectionName := ""
+		if len(path) > 0 {
+			sectionName = path[len(path)-1]
+		} else if hasHeadings {
+			title = "Preamble"
+		}
+		for _, part := range splitBlocks(section) {
+			chunks = append(chunks, Chunk{
+				ChunkIndex:        len(chunks),
+				Content:           part,
+				ChapterTitle:      title,
+				ChapterNumber:     0,
+				SectionID:         strings.Join(path, " > "),
+				SectionName:       sectionName,
+				HeadingPath:       append([]string(nil), path...),
+				NarrativePosition: len(chunks),
+				PageStart:         nil,
+				PageEnd:           nil,
+			})
+		}
+		section = nil
+	}
+
+	for _, b := range blocks {
+		if b.kind == mdHeading {
+			flushSection()
+			for len(levels) > 0 && levels[len(levels)-1] >= b.level {
+				levels = levels[:len(levels)-1]
+				path = path[:len(path)-1]
+			}
+			levels = append(levels, b.level)
+			path = append(path, b.title)
+		}
+		section = append(section, b)
+	}
+	flushSection()
+
+	if len(chunks) == 0 {
+		return (&WholeDocChunker{}).Chunk(content)
+	}
+	return chunks
+}
+
+// hasBody reports whether a section has content besides its heading.
+func hasBody(blocks []mdBlock) bool {
+	for _, b := range blocks {
+		if b.kind != mdHeading {
+			return true
+		}
+	}
+	return false
+}
+
+// splitBlocks joins a section's blocks into one or more chunk texts, starting
+// a new chunk when the next block would push it over maxWords. A single block
+// larger than the budget (e.g. a long table) is kept whole, and a heading
+// always stays with the block that follows it.
+func splitBlocks(blocks []mdBlock) []string {
+	var parts []string
+	var current []string
+	currentWords := 0
+	currentHasBody := false
+
+	for _, b := range blocks {
+		words := WordCount(b.text)
+		if currentHasBody && currentWords+words > maxWords {
+			parts = append(parts, strings.Join(current, "\n\n"))
+			current = nil
+			currentWords = 0
+			currentHasBody = false
+		}
+		current = append(current, b.text)
+		currentWords += words
+		if b.kind != mdHeading {
+			currentHasBody = true
+		}
+	}
+	if len(current) > 0 {
+		parts = append(parts, strings.Join(current, "\n\n"))
+	}
+	return parts
+}
+
+// parseMarkdownBlocks splits Markdown into top-level blocks: ATX and setext
+// headings, fenced code, tables, lists and paragraphs.
+func parseMarkdownBlocks(content string) []mdBlock {
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+
+	var blocks []mdBlock
+	var para []string
+
+	flushPara := func() {
+		if len(para) > 0 {
+			blocks = append(blocks, mdBlock{kind: mdParagraph, text: strings.Join(para, "\n")})
+			para = nil
+		}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+
+		switch {
+		case strings.TrimSpace(line) == "":
+			flushPara()
+
+		case mdFenceOpen.MatchString(line):
+			flushPara()
+			fence := mdFenceOpen.FindStringSubmatch(line)[1]
+			start := i
+			for i+1 < len(lines) {
+				i++
+				if isClosingFence(lines[i], fence) {
+					break
+				}
+			}
+			blocks = append(blocks, mdBlock{kind: mdCode, text: strings.Join(lines[start:i+1], "\n")})
+
+		case mdATXHeading.MatchString(line):
+			flushPara()
+			m := mdATXHeading.FindStringSubmatch(line)
+			blocks = append(blocks, mdBlock{
+				kind:  mdHeading,
+				text:  strings.TrimSpace(line),
+				level: len(m[1]),
+				title: stripClosingHashes(m[2]),
+			})
+
+		case len(para) > 0 && mdSetextMarker.MatchString(line):
+			level := 2
+			if strings.Contains(line, "=") {
+				level = 1
+			}
+			blocks = append(blocks, mdBlock{
+				kind:  mdHeading,
+				text:  strings.Join(append(para, line), "\n"),
+				level: level,
+				title: strings.Join(strings.Fields(strings.Join(para, " ")), " "),
+			})
+			para = nil
+
+		case strings.Contains(line, "|") && i+1 < len(lines) && mdTableDelimRow.MatchString(lines[i+1]) && strings.Contains(lines[i+1], "-"):
+			flushPara()
+			start := i
+			i++ // delimiter row
+			for i+1 < len(lines) && strings.TrimSpace(lines[i+1]) != "" && strings.Contains(lines[i+1], "|") {
+				i++
+			}
+			blocks = append(blocks, mdBlock{kind: mdTable, text: strings.Join(lines[start:i+1], "\n")})
+
+		case mdListItem.MatchString(line) && !mdSetextMarker.MatchString(line):
+			flushPara()
+			start := i
+			for i+1 < len(lines) {
+				next := lines[i+1]
+				if strings.TrimSpace(next) == "" {
+					// A blank line continues the list only if more items or
+					// indented continuation lines follow.
+					j := i + 1
+					for j < len(lines) && strings.TrimSpace(lines[j]) == "" {
+						j++
+					}
+					if j < len(lines) && (mdListItem.MatchString(lines[j]) || isIndented(lines[j])) {
+						i = j
+						continue
+					}
+					break
+				}
+				if mdATXHeading.MatchString(next) || mdFenceOpen.MatchString(next) && !isIndented(next) {
+					break
+				}
+				i++
+			}
+			blocks = append(blocks, mdBlock{kind: mdList, text: strings.Join(lines[start:i+1], "\n")})
+
+		default:
+			para = append(para, line)
+		}
+	}
+	flushPara()
+
+	return blocks
+}
+
+// isClosingFence reports whether line closes a fence opened with open.
+func isClosingFence(line, open string) bool {
+	trimmed := strings.TrimSpace(line)
+	if !strings.HasPrefix(trimmed, open[:1]) {
+		return false
+	}
+	run := len(trimmed) - len(strings.TrimLeft(trimmed, open[:1]))
+	return run >= len(open) && strings.TrimSpace(trimmed[run:]) == ""
+}
+
+// stripClosingHashes removes an optional closing sequence ("## Title ##").
+func stripClosingHashes(s string) string {
+	trimmed := strings.TrimRight(s, "#")
+	if trimmed == "" || strings.HasSuffix(trimmed, " ") || strings.HasSuffix(trimmed, "\t") {
+		s = trimmed
+	}
+	return strings.TrimSpace(s)
+}
+
+func isIndented(line string) bool {
+	return strings.HasPrefix(line, "  ") || strings.HasPrefix(line, "\t")
+}
+
+// ParseMarkdown parses a Markdown file into heading-structured chunks.
+func ParseMarkdown(content string) ([]Chunk, error) {
+	if !utf8.ValidString(content) {
+		return nil, fmt.Errorf("invalid UTF-8 encoding")
+	}
+
+	return (&MarkdownChunker{}).Chunk(content), nil
+}
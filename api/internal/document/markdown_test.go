@@ -0,0 +1,49 @@
+package document
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMarkdownChunker(t *testing.T) {
+	content := "# Agreement\n\nIntro text.\n\n## Section 3.2\n\n### Payments\n\nFees are due monthly.\n\n| Item | Fee |\n|------|-----|\n| Rent | 100 |\n\n| Late | 10 |\n\n```\n# not a heading\n```\n\n## Termination\n\n- Either party\n- may terminate\n\n  with notice.\n"
+
+	chunks := (&MarkdownChunker{}).Chunk(content)
+
+	wantTitles := []string{"Agreement", "Agreement > Section 3.2 > Payments", "Agreement > Termination"}
+	if len(chunks) != len(wantTitles) {
+		t.Fatalf("MarkdownChunker returned %d chunks, want %d", len(chunks), len(wantTitles))
+	}
+	for i, want := range wantTitles {
+		if chunks[i].ChapterTitle != want {
+			t.Errorf("chunk %d title = %q, want %q", i, chunks[i].ChapterTitle, want)
+		}
+	}
+
+	payments := chunks[1]
+	if len(payments.HeadingPath) != 3 || payments.SectionName != "Payments" {
+		t.Errorf("chunk 1 heading path = %v, section name = %q", payments.HeadingPath, payments.SectionName)
+	}
+	if !strings.Contains(payments.Content, "| Item | Fee |\n|------|-----|\n| Rent | 100 |") {
+		t.Errorf("table was split: %q", payments.Content)
+	}
+	if !strings.Contains(payments.Content, "# not a heading") {
+		t.Errorf("code fence content missing from chunk: %q", payments.Content)
+	}
+	if !strings.Contains(chunks[2].Content, "with notice.") {
+		t.Errorf("list continuation missing from chunk: %q", chunks[2].Content)
+	}
+}
+
+func TestMarkdownChunkerNeverSplitsTable(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("# Data\n\n| Name | Notes |\n|---|---|\n")
+	for i := 0; i < 800; i++ {
+		b.WriteString("| row | some words in the notes column |\n")
+	}
+
+	chunks := (&MarkdownChunker{}).Chunk(b.String())
+	if len(chunks) != 1 {
+		t.Fatalf("MarkdownChunker returned %d chunks for a single oversized table, want 1", len(chunks))
+	}
+}
@@ -0,0 +1,224 @@
+package document
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"unicode"
+)
+
+// OCR engines.
+const (
+	OCREngineAuto      = "auto"      // tesseract when installed, otherwise none
+	OCREngineTesseract = "tesseract" // local tesseract binary
+	OCREngineNone      = "none"      // image-only pages are left empty
+)
+
+// OCREngine recognises the words in a page image.
+type OCREngine interface {
+	Name() string
+	Recognize(img OCRImage) ([]OCRWord, error)
+}
+
+// OCRImage is an encoded image ("png", "jpg", "jp2" or "tiff") handed to an
+// OCR engine, with its size in pixels.
+type OCRImage struct {
+	Data          []byte
+	Format        string
+	Width, Height int
+}
+
+// OCRWord is a recognised word with its box in image pixels. Words with the
+// same Line were read as one text line.
+type OCRWord struct {
+	Text       string
+	Confidence float64 // 0–1
+	Line       int
+	Left, Top  int
+	Width      int
+	Height     int
+}
+
+// NewOCREngine returns the named engine. auto yields tesseract when it is
+// installed; none, or auto without tesseract, yields a nil engine.
+func NewOCREngine(name, languages string) (OCREngine, error) {
+	switch name {
+	case OCREngineNone:
+		return nil, nil
+	case OCREngineTesseract:
+		t, err := NewTesseractOCR(languages)
+		if err != nil {
+			return nil, err
+		}
+		return t, nil
+	case OCREngineAuto, "":
+		if t, err := NewTesseractOCR(languages); err == nil {
+			return t, nil
+		}
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unknown OCR engine: %s", name)
+}
+
+// PDFOptions selects how PDF text is extracted.
+type PDFOptions struct {
+	Extractor string    // see PDFExtractorAuto
+	OCR       OCREngine // nil leaves image-only pages empty
+}
+
+// PDFOCRPage records a page whose text was read by OCR. It is stored in
+// chunk metadata under "ocr" so claims from the page can carry the OCR
+// confidence.
+type PDFOCRPage struct {
+	Page       int          `json:"page"`
+	Engine     string       `json:"engine"`
+	Confidence float64      `json:"confidence"`          // mean word confidence, 0–1
+	Uncertain  []PDFOCRWord `json:"uncertain,omitempty"` // words below ocrUncertain
+}
+
+// PDFOCRWord is a recognised word with its box on the page.
+type PDFOCRWord struct {
+	Text       string  `json:"text"`
+	Confidence float64 `json:"confidence"`
+	BBox       PDFBox  `json:"bbox"`
+}
+
+const (
+	ocrUncertain     = 0.6  // words below this confidence are listed per page
+	ocrMinPageText   = 10   // pages with fewer non-space characters may be scans
+	ocrImageCoverage = 0.25 // share of the page an image must cover to be a scan
+)
+
+// textless reports whether a page has (almost) no text, as scans do.
+func (p *pdfPage) textless() bool {
+	chars := 0
+	for _, g := range p.glyphs {
+		for _, r := range g.text {
+			if !unicode.IsSpace(r) {
+				chars++
+			}
+		}
+	}
+	return chars < ocrMinPageText
+}
+
+// scan returns the image a page was scanned into: pages with (almost) no
+// text that are largely covered by an image.
+func (p *pdfPage) scan() (pdfImage, bool) {
+	if !p.textless() || p.width <= 0 || p.height <= 0 {
+		return pdfImage{}, false
+	}
+
+	var best pdfImage
+	bestArea := 0.0
+	for _, img := range p.images {
+		if area := (img.box[2] - img.box[0]) * (img.box[3] - img.box[1]); area > bestArea {
+			best, bestArea = img, area
+		}
+	}
+	return best, bestArea >= p.width*p.height*ocrImageCoverage
+}
+
+// ocrPages runs OCR over the scanned pages, replacing their glyphs with
+// the recognised words. Pages that could not be read are reported as
+// warnings.
+func ocrPages(pages []*pdfPage, engine OCREngine) ([]PDFOCRPage, []string) {
+	var ocr []PDFOCRPage
+	var warnings []string
+	for _, page := range pages {
+		img, ok := page.scan()
+		if !ok {
+			continue
+		}
+		if engine == nil {
+			warnings = append(warnings, fmt.Sprintf("page %d has no text layer and OCR is disabled", page.number))
+			continue
+		}
+		data, err := img.ocrImage()
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("page %d: cannot read scanned image: %v", page.number, err))
+			continue
+		}
+		words, err := engine.Recognize(data)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("page %d: OCR failed: %v", page.number, err))
+			continue
+		}
+		ocr = append(ocr, page.placeOCRWords(img, data, words, engine.Name()))
+	}
+	return ocr, warnings
+}
+
+// placeOCRWords maps recognised words from image pixels onto the page and
+// stores them as the page's glyphs. Words of a line share one baseline so
+// the layout keeps them together.
+func (p *pdfPage) placeOCRWords(img pdfImage, data OCRImage, words []OCRWord, engine string) PDFOCRPage {
+	sx := (img.box[2] - img.box[0]) / float64(data.Width)
+	sy := (img.box[3] - img.box[1]) / float64(data.Height)
+	left := img.box[0]
+	top := p.height - img.box[3] // image top edge, from the page's top
+
+	type lineInfo struct {
+		bottom  int
+		heights []int
+		size    float64 // median word height in points
+	}
+	lines := make(map[int]*lineInfo)
+	for _, w := range words {
+		l := lines[w.Line]
+		if l == nil {
+			l = &lineInfo{}
+			lines[w.Line] = l
+		}
+		l.bottom = max(l.bottom, w.Top+w.Height)
+		l.heights = append(l.heights, w.Height)
+	}
+	for _, l := range lines {
+		sort.Ints(l.heights)
+		l.size = float64(l.heights[len(l.heights)/2]) * sy
+	}
+
+	result := PDFOCRPage{Page: p.number, Engine: engine}
+	p.glyphs = p.glyphs[:0]
+	total := 0.0
+	for _, w := range words {
+		l := lines[w.Line]
+		bottom := top + float64(l.bottom)*sy
+		p.glyphs = append(p.glyphs, pdfGlyph{
+			x:    left + float64(w.Left)*sx,
+			y:    p.height - bottom + l.size*0.2,
+			w:    float64(w.Width) * sx,
+			size: l.size,
+			text: w.Text,
+		})
+
+		total += w.Confidence
+		if w.Confidence < ocrUncertain {
+			result.Uncertain = append(result.Uncertain, PDFOCRWord{
+				Text:       w.Text,
+				Confidence: w.Confidence,
+				BBox: PDFBox{
+					pdfRound(left + float64(w.Left)*sx),
+					pdfRound(top + float64(w.Top)*sy),
+					pdfRound(left + float64(w.Left+w.Width)*sx),
+					pdfRound(top + float64(w.Top+w.Height)*sy),
+				},
+			})
+		}
+	}
+	if len(words) > 0 {
+		result.Confidence = math.Round(total/float64(len(words))*1000) / 1000
+	}
+	return result
+}
+
+// chunkOCRPages returns the OCR records of the pages a chunk spans.
+func chunkOCRPages(startPage, endPage int, ocr []PDFOCRPage) []PDFOCRPage {
+	var found []PDFOCRPage
+	for _, p := range ocr {
+		if p.Page >= startPage && p.Page <= endPage {
+			found = append(found, p)
+		}
+	}
+	return found
+}
@@ -0,0 +1,110 @@
+package document
+
+import (
+	"bytes"
+	"fmt"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// mockOCR returns fixed words for every image it is given.
+type mockOCR struct {
+	words  []OCRWord
+	images []OCRImage
+}
+
+func (m *mockOCR) Name() string { return "mock" }
+
+func (m *mockOCR) Recognize(img OCRImage) ([]OCRWord, error) {
+	m.images = append(m.images, img)
+	return m.words, nil
+}
+
+func TestParsePDFScannedPageOCR(t *testing.T) {
+	stream := func(dict, data string) string {
+		return fmt.Sprintf("<< %s /Length %d >>\nstream\n%s\nendstream", dict, len(data), data)
+	}
+	objects := []string{
+		1: `<< /Type /Catalog /Pages 2 0 R >>`,
+		2: `<< /Type /Pages /Kids [3 0 R 5 0 R] /Count 2 /MediaBox [0 0 612 792] >>`,
+		3: `<< /Type /Page /Parent 2 0 R /Contents 4 0 R /Resources << /Font << /F1 7 0 R >> >> >>`,
+		4: stream("", `BT /F1 12 Tf 72 720 Td (F\366runders\366kningsprotokoll) Tj ET`),
+		5: `<< /Type /Page /Parent 2 0 R /Contents 6 0 R /Resources << /XObject << /Im1 8 0 R >> >> >>`,
+		6: stream("", `q 400 0 0 400 100 300 cm /Im1 Do Q`),
+		7: `<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>`,
+		8: stream("/Type /XObject /Subtype /Image /Width 8 /Height 4 /ColorSpace /DeviceGray /BitsPerComponent 8", strings.Repeat("\xff", 32)),
+	}
+	var pdf strings.Builder
+	pdf.WriteString("%PDF-1.4\n")
+	for num, body := range objects {
+		if body != "" {
+			fmt.Fprintf(&pdf, "%d 0 obj\n%s\nendobj\n", num, body)
+		}
+	}
+	pdf.WriteString("trailer\n<< /Root 1 0 R /Size 9 >>\n%%EOF\n")
+
+	path := filepath.Join(t.TempDir(), "anmalan.pdf")
+	if err := os.WriteFile(path, []byte(pdf.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without an engine the scanned page is reported
+	_, warnings, err := ParsePDFWithChunks(path, PDFOptions{Extractor: PDFExtractorNative})
+	if err != nil {
+		t.Fatalf("ParsePDFWithChunks() error = %v", err)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "page 2 has no text layer") {
+		t.Errorf("warnings = %q, want page 2 reported", warnings)
+	}
+
+	// The image is 8×4 pixels drawn at 400×400 points: a pixel is 50×100 points
+	engine := &mockOCR{words: []OCRWord{
+		{Text: "Polisanmälan", Confidence: 0.95, Line: 0, Left: 0, Top: 0, Width: 4, Height: 1},
+		{Text: "2024-03-01", Confidence: 0.41, Line: 0, Left: 5, Top: 0, Width: 3, Height: 1},
+		{Text: "Misstänkt", Confidence: 0.9, Line: 1, Left: 0, Top: 1, Width: 3, Height: 1},
+		{Text: "stöld.", Confidence: 0.88, Line: 1, Left: 4, Top: 1, Width: 2, Height: 1},
+	}}
+	chunks, warnings, err := ParsePDFWithChunks(path, PDFOptions{Extractor: PDFExtractorNative, OCR: engine})
+	if err != nil {
+		t.Fatalf("ParsePDFWithChunks() error = %v", err)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("warnings = %q, want none", warnings)
+	}
+
+	if len(engine.images) != 1 || engine.images[0].Format != "png" {
+		t.Fatalf("OCR images = %+v, want one PNG", engine.images)
+	}
+	img, err := png.Decode(bytes.NewReader(engine.images[0].Data))
+	if err != nil {
+		t.Fatalf("png.Decode() error = %v", err)
+	}
+	if b := img.Bounds(); b.Dx() != 8 || b.Dy() != 4 {
+		t.Errorf("image size = %v, want 8×4", b)
+	}
+
+	var content strings.Builder
+	var ocr []PDFOCRPage
+	for _, c := range chunks {
+		content.WriteString(c.Content)
+		if pages, ok := c.Metadata["ocr"].([]PDFOCRPage); ok {
+			ocr = append(ocr, pages...)
+		}
+	}
+	for _, want := range []string{"Förundersökningsprotokoll", "Polisanmälan 2024-03-01\nMisstänkt stöld."} {
+		if !strings.Contains(content.String(), want) {
+			t.Errorf("content = %q, want it to contain %q", content.String(), want)
+		}
+	}
+
+	if len(ocr) != 1 || ocr[0].Page != 2 || ocr[0].Engine != "mock" || ocr[0].Confidence != 0.785 {
+		t.Fatalf("ocr metadata = %+v, want page 2 at 0.785", ocr)
+	}
+	uncertain := ocr[0].Uncertain
+	if len(uncertain) != 1 || uncertain[0].Text != "2024-03-01" || uncertain[0].BBox != (PDFBox{350, 92, 500, 192}) {
+		t.Errorf("uncertain words = %+v", uncertain)
+	}
+}
@@ -1,92 +1,216 @@
 package document
 
 import (
+	"archive/zip"
 	"bytes"
+	"compress/gzip"
+	"encoding/xml"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
-	"regexp"
 	"strconv"
 	"strings"
 )
 
-// PageMarker is inserted during PDF text extraction to mark page boundaries.
-const PageMarker = "\n[[[PAGE %d]]]\n"
+// PDF text extractors.
+const (
+	PDFExtractorAuto      = "auto"      // pdftotext when installed, otherwise native
+	PDFExtractorPdftotext = "pdftotext" // word positions from poppler's pdftotext -bbox
+	PDFExtractorNative    = "native"    // built-in Go extractor
+)
+
+// pdfDocument is the extracted text of a PDF, the offsets where its pages
+// start, the tables detected on them and the pages read by OCR.
+type pdfDocument struct {
+	text         string
+	offsetToPage map[int]int
+	tables       []PDFTable
+	ocr          []PDFOCRPage
+	warnings     []string
+}
 
-// ParsePDF extracts text from a PDF file using pdftotext.
+// ParsePDF extracts text from a PDF file, using pdftotext when it is
+// installed and the native extractor otherwise.
 func ParsePDF(filePath string) (string, map[int]int, error) {
-	// Check if pdftotext is available
-	if _, err := exec.LookPath("pdftotext"); err != nil {
-		return "", nil, fmt.Errorf("pdftotext not found: %w (install poppler-utils)", err)
-	}
+	return ParsePDFWith(filePath, PDFExtractorAuto)
+}
 
-	// Get total page count first
-	pageCount, err := getPDFPageCount(filePath)
+// ParsePDFWith extracts text from a PDF file with the given extractor. It
+// returns the text and a table from character offset to the page starting
+// there, for use with getPageRange. Tables are rendered as Markdown pipe
+// tables.
+func ParsePDFWith(filePath, extractor string) (string, map[int]int, error) {
+	doc, err := extractPDF(filePath, PDFOptions{Extractor: extractor})
 	if err != nil {
-		return "", nil, fmt.Errorf("failed to get PDF page count: %w", err)
+		return "", nil, err
 	}
+	return doc.text, doc.offsetToPage, nil
+}
 
-	// Extract text with page markers
-	var textWithMarkers strings.Builder
-	for page := 1; page <= pageCount; page++ {
-		// Extract single page
-		cmd := exec.Command("pdftotext", "-layout", "-f", strconv.Itoa(page), "-l", strconv.Itoa(page), filePath, "-")
-		output, err := cmd.Output()
-		if err != nil {
-			return "", nil, fmt.Errorf("failed to extract page %d: %w", page, err)
+// extractPDF positions the text of every page with the chosen extractor,
+// reads scanned pages with the OCR engine and lays the pages out,
+// detecting tables on the way.
+func extractPDF(filePath string, opts PDFOptions) (*pdfDocument, error) {
+	var pages []*pdfPage
+	var err error
+	switch opts.Extractor {
+	case PDFExtractorPdftotext:
+		pages, err = pdfPagesPdftotext(filePath)
+	case PDFExtractorNative:
+		pages, err = pdfPagesNative(filePath)
+	case PDFExtractorAuto, "":
+		if _, lookErr := exec.LookPath("pdftotext"); lookErr == nil {
+			pages, err = pdfPagesPdftotext(filePath)
+		} else {
+			pages, err = pdfPagesNative(filePath)
 		}
-
-		// Add page marker before content
-		fmt.Fprintf(&textWithMarkers, PageMarker, page)
-		textWithMarkers.Write(output)
-		textWithMarkers.WriteString("\n")
+	default:
+		return nil, fmt.Errorf("unknown PDF extractor: %s", opts.Extractor)
+	}
+	if err != nil {
+		return nil, err
 	}
 
-	// Build character offset to page number lookup table
-	offsetToPage := buildOffsetTable(textWithMarkers.String())
-
-	// Remove page markers from final output
-	cleanText := regexp.MustCompile(`\n\[\[\[PAGE \d+\]\]\]\n`).ReplaceAllString(textWithMarkers.String(), "\n")
+	ocr, warnings := ocrPages(pages, opts.OCR)
 
-	return cleanText, offsetToPage, nil
+	doc := layoutPDF(pages)
+	doc.ocr = ocr
+	doc.warnings = warnings
+	return doc, nil
 }
 
-// getPDFPageCount returns the total number of pages in a PDF.
-func getPDFPageCount(filePath string) (int, error) {
-	cmd := exec.Command("pdftotext", "-layout", filePath, "-")
-	output, err := cmd.Output()
-	if err != nil {
-		return 0, err
+// attachPDFImages adds the image placements pdftotext does not report,
+// parsing the file natively when some page has no text, so scans among
+// the pages can be found.
+func attachPDFImages(filePath string, pages []*pdfPage) {
+	need := false
+	for _, p := range pages {
+		if p.textless() {
+			need = true
+		}
+	}
+	if !need {
+		return
 	}
 
-	// Count page markers in the output
-	re := regexp.MustCompile(`\[\[\[PAGE \d+\]\]\]`)
-	matches := re.FindAllString(string(output), -1)
-	return len(matches), nil
+	native, err := pdfPagesNative(filePath)
+	if err != nil || len(native) != len(pages) {
+		return // the text is still usable; scans just go unnoticed
+	}
+	for i, p := range pages {
+		p.images = native[i].images
+	}
 }
 
-// buildOffsetTable creates a lookup table from character offset to page number.
-func buildOffsetTable(text string) map[int]int {
-	table := make(map[int]int)
+// pdfPagesPdftotext runs pdftotext once over the whole file and reads the
+// word boxes it reports for each page.
+func pdfPagesPdftotext(filePath string) ([]*pdfPage, error) {
+	if _, err := exec.LookPath("pdftotext"); err != nil {
+		return nil, fmt.Errorf("pdftotext not found: %w (install poppler-utils)", err)
+	}
+
+	output, err := exec.Command("pdftotext", "-bbox", filePath, "-").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract PDF text: %w", err)
+	}
 
-	re := regexp.MustCompile(`\[\[\[PAGE (\d+)\]\]\]`)
+	pages, err := parsePDFBBox(bytes.NewReader(output))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pdftotext output: %w", err)
+	}
+	attachPDFImages(filePath, pages)
+	return pages, nil
+}
 
-	// Initialize: before first page marker, assume page 1
-	table[0] = 1
+// pdfPagesNative positions the text with the built-in parser.
+func pdfPagesNative(filePath string) ([]*pdfPage, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF: %w", err)
+	}
 
-	// Find all page markers and record their positions
-	matches := re.FindAllStringSubmatchIndex(text, -1)
-	for _, match := range matches {
-		// match[0] is start of full match, match[1] is end
-		// match[2] is start of capture group, match[3] is end
-		pageNumStr := text[match[2]:match[3]]
-		pageNum, _ := strconv.Atoi(pageNumStr)
+	pages, err := extractPDFPages(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract PDF text: %w", err)
+	}
+	return pages, nil
+}
 
-		// Record that from this offset onward, we're on this page
-		table[match[0]] = pageNum
+// parsePDFBBox reads the XHTML written by pdftotext -bbox: one <page> per
+// page with a <word> per word, boxed in top-left coordinates. Each word
+// becomes one glyph with its baseline estimated from the box.
+func parsePDFBBox(r io.Reader) ([]*pdfPage, error) {
+	dec := xml.NewDecoder(r)
+	dec.Strict = false
+	dec.Entity = xml.HTMLEntity
+
+	var pages []*pdfPage
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		se, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch se.Name.Local {
+		case "page":
+			width, _ := strconv.ParseFloat(attr(se, "width"), 64)
+			height, _ := strconv.ParseFloat(attr(se, "height"), 64)
+			pages = append(pages, &pdfPage{number: len(pages) + 1, width: width, height: height})
+		case "word":
+			if len(pages) == 0 {
+				continue
+			}
+			var text string
+			if err := dec.DecodeElement(&text, &se); err != nil {
+				return nil, err
+			}
+			var box [4]float64
+			for i, name := range []string{"xMin", "yMin", "xMax", "yMax"} {
+				box[i], _ = strconv.ParseFloat(attr(se, name), 64)
+			}
+			page := pages[len(pages)-1]
+			size := box[3] - box[1]
+			page.glyphs = append(page.glyphs, pdfGlyph{
+				x:    box[0],
+				y:    page.height - box[3] + size*0.2,
+				w:    box[2] - box[0],
+				size: size,
+				text: text,
+			})
+		}
 	}
+	return pages, nil
+}
 
-	return table
+// layoutPDF lays out each page and concatenates them, recording the offset
+// where each page starts and moving table offsets into document text.
+func layoutPDF(pages []*pdfPage) *pdfDocument {
+	var b strings.Builder
+	doc := &pdfDocument{offsetToPage: map[int]int{0: 1}}
+	for i, page := range pages {
+		if i > 0 {
+			b.WriteString("\n\n")
+		}
+		start := b.Len()
+		doc.offsetToPage[start] = i + 1
+
+		text, tables := page.layout()
+		for _, t := range tables {
+			t.start += start
+			t.end += start
+			doc.tables = append(doc.tables, t)
+		}
+		b.WriteString(strings.TrimRight(text, "\n"))
+	}
+	doc.text = b.String()
+	return doc
 }
 
 // getPageRange returns the start and end page numbers for a chunk of text.
@@ -109,10 +233,7 @@ func getPageRange(text string, fullText string, offsetToPage map[int]int) (start
 	chunkEndOffset := chunkOffset + len(text)
 	endPage = startPage
 	for offset, page := range offsetToPage {
-		if offset >= chunkEndOffset {
-			break
-		}
-		if offset <= chunkEndOffset && page > endPage {
+		if offset < chunkEndOffset && page > endPage {
 			endPage = page
 		}
 	}
@@ -126,17 +247,22 @@ func getPageRange(text string, fullText string, offsetToPage map[int]int) (start
 }
 
 // ParsePDFWithChunks extracts text from a PDF and splits it into chapter-based chunks with page info.
-func ParsePDFWithChunks(filePath string) ([]Chunk, error) {
-	// Extract text with page markers
-	text, offsetToPage, err := ParsePDF(filePath)
+// Tables detected in a chunk are stored in its metadata under "tables", so
+// claims read from a cell can be traced to its page and bounding box; pages
+// read by OCR are stored under "ocr" with their confidence. The returned
+// warnings name pages that had no text and could not be read.
+func ParsePDFWithChunks(filePath string, opts PDFOptions) ([]Chunk, []string, error) {
+	// Extract text with page offsets
+	doc, err := extractPDF(filePath, opts)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	text, offsetToPage := doc.text, doc.offsetToPage
 
 	// Parse chapters using the same logic as TXT
 	chunks, err := ParseTXT(text)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Add page information to each chunk
@@ -144,27 +270,49 @@ func ParsePDFWithChunks(filePath string) ([]Chunk, error) {
 		startPage, endPage := getPageRange(chunks[i].Content, text, offsetToPage)
 		chunks[i].PageStart = &startPage
 		chunks[i].PageEnd = &endPage
+
+		if tables := chunkTables(chunks[i].Content, text, doc.tables); len(tables) > 0 {
+			setChunkMetadata(&chunks[i], "tables", tables)
+		}
+		if ocr := chunkOCRPages(startPage, endPage, doc.ocr); len(ocr) > 0 {
+			setChunkMetadata(&chunks[i], "ocr", ocr)
+		}
 	}
 
-	return chunks, nil
+	return chunks, doc.warnings, nil
 }
 
-// ReadPDF reads a PDF file and returns the raw text content.
-func ReadPDF(filePath string) (string, error) {
-	if _, err := exec.LookPath("pdftotext"); err != nil {
-		return "", fmt.Errorf("pdftotext not found: %w (install poppler-utils)", err)
+func setChunkMetadata(chunk *Chunk, key string, value interface{}) {
+	if chunk.Metadata == nil {
+		chunk.Metadata = make(map[string]interface{})
 	}
+	chunk.Metadata[key] = value
+}
 
-	cmd := exec.Command("pdftotext", "-layout", filePath, "-")
-	output, err := cmd.Output()
-	if err != nil {
-		return "", fmt.Errorf("failed to extract PDF text: %w", err)
+// chunkTables returns the tables whose rendered text overlaps the chunk.
+func chunkTables(chunk, fullText string, tables []PDFTable) []PDFTable {
+	start := strings.Index(fullText, chunk)
+	if start == -1 {
+		return nil
 	}
+	end := start + len(chunk)
 
-	return string(output), nil
+	var found []PDFTable
+	for _, t := range tables {
+		if t.start < end && t.end > start {
+			found = append(found, t)
+		}
+	}
+	return found
+}
+
+// ReadPDF reads a PDF file and returns the raw text content.
+func ReadPDF(filePath string) (string, error) {
+	text, _, err := ParsePDF(filePath)
+	return text, err
 }
 
-// DetectFileType determines if a file is TXT or PDF based on its content.
+// DetectFileType determines the file type (pdf, docx, xlsx, warc, txt) from its content.
 func DetectFileType(filePath string) (string, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
@@ -184,12 +332,67 @@ func DetectFileType(filePath string) (string, error) {
 		return "pdf", nil
 	}
 
+	// Web archives, plain or gzip-compressed
+	if bytes.HasPrefix(buffer, []byte("WARC/")) {
+		return "warc", nil
+	}
+	if bytes.HasPrefix(buffer, []byte{0x1f, 0x8b}) {
+		return detectGzipType(filePath)
+	}
+
+	// ZIP-based formats (OOXML) are told apart by their parts
+	if bytes.HasPrefix(buffer, []byte("PK\x03\x04")) {
+		return detectZipType(filePath)
+	}
+
 	// Check if it's valid UTF-8 text (likely TXT)
 	if utf8Valid := isValidUTF8(buffer); utf8Valid {
 		return "txt", nil
 	}
 
-	return "", fmt.Errorf("unable to detect file type (not PDF or valid UTF-8 text)")
+	return "", fmt.Errorf("unable to detect file type (not PDF, DOCX or valid UTF-8 text)")
+}
+
+// detectGzipType identifies a gzip-compressed file by its decompressed header.
+func detectGzipType(filePath string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return "", fmt.Errorf("failed to open gzip stream: %w", err)
+	}
+	defer gz.Close()
+
+	header := make([]byte, 5)
+	if _, err := io.ReadFull(gz, header); err == nil && string(header) == "WARC/" {
+		return "warc", nil
+	}
+
+	return "", fmt.Errorf("unsupported gzip-compressed file type")
+}
+
+// detectZipType identifies a ZIP-based document format by its parts.
+func detectZipType(filePath string) (string, error) {
+	zr, err := zip.OpenReader(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open ZIP container: %w", err)
+	}
+	defer zr.Close()
+
+	for _, f := range zr.File {
+		switch f.Name {
+		case "word/document.xml":
+			return "docx", nil
+		case "xl/workbook.xml":
+			return "xlsx", nil
+		}
+	}
+
+	return "", fmt.Errorf("unsupported ZIP-based file type")
 }
 
 // isValidUTF8 checks if a byte slice is valid UTF-8.
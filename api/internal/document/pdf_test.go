@@ -0,0 +1,103 @@
+package document
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParsePDFNative(t *testing.T) {
+	deflate := func(s string) string {
+		var buf bytes.Buffer
+		zw := zlib.NewWriter(&buf)
+		zw.Write([]byte(s))
+		zw.Close()
+		return buf.String()
+	}
+	stream := func(dict, data string) string {
+		return fmt.Sprintf("<< %s /Length %d >>\nstream\n%s\nendstream", dict, len(data), data)
+	}
+
+	page1 := deflate(`BT /F1 12 Tf 72 720 Td (Styrelsem\366te) Tj 0 -14 Td [(Beslut om fasad) -250 (renovering)] TJ ET`)
+	page2 := `BT /F2 10 Tf 1 0 0 1 72 700 Tm <0001000200030004> Tj ET`
+	cmap := `/CIDInit /ProcSet findresource begin
+begincmap
+1 begincodespacerange <0000> <FFFF> endcodespacerange
+1 beginbfchar <0001> <0042> endbfchar
+1 beginbfrange <0002> <0004> <0061> endbfrange
+endcmap`
+	objStm := deflate(`5 0 << /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>`)
+
+	objects := []string{
+		1:  `<< /Type /Catalog /Pages 2 0 R >>`,
+		2:  `<< /Type /Pages /Kids [3 0 R 6 0 R] /Count 2 /MediaBox [0 0 612 792] /Resources << /Font << /F1 5 0 R /F2 7 0 R >> >> >>`,
+		3:  `<< /Type /Page /Parent 2 0 R /Contents 4 0 R >>`,
+		4:  stream("/Filter /FlateDecode", page1),
+		6:  `<< /Type /Page /Parent 2 0 R /Contents 8 0 R >>`,
+		7:  `<< /Type /Font /Subtype /Type0 /BaseFont /Arial /Encoding /Identity-H /DescendantFonts [9 0 R] /ToUnicode 11 0 R >>`,
+		8:  stream("", page2),
+		9:  `<< /Type /Font /Subtype /CIDFontType2 /BaseFont /Arial /DW 500 >>`,
+		10: stream("/Type /ObjStm /N 1 /First 4 /Filter /FlateDecode", objStm),
+		11: stream("", cmap),
+	}
+	var pdf strings.Builder
+	pdf.WriteString("%PDF-1.5\n")
+	for num, body := range objects {
+		if body != "" {
+			fmt.Fprintf(&pdf, "%d 0 obj\n%s\nendobj\n", num, body)
+		}
+	}
+	pdf.WriteString("trailer\n<< /Root 1 0 R /Size 12 >>\n%%EOF\n")
+
+	path := filepath.Join(t.TempDir(), "protokoll.pdf")
+	if err := os.WriteFile(path, []byte(pdf.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	text, offsetToPage, err := ParsePDFWith(path, PDFExtractorNative)
+	if err != nil {
+		t.Fatalf("ParsePDFWith() error = %v", err)
+	}
+	for _, want := range []string{"Styrelsemöte\nBeslut om fasad renovering", "Babc"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("text = %q, want it to contain %q", text, want)
+		}
+	}
+	if page := offsetToPage[strings.Index(text, "Babc")]; page != 2 {
+		t.Errorf("offsetToPage at page 2 text = %d, want 2", page)
+	}
+
+	chunks, _, err := ParsePDFWithChunks(path, PDFOptions{Extractor: PDFExtractorNative})
+	if err != nil {
+		t.Fatalf("ParsePDFWithChunks() error = %v", err)
+	}
+	last := chunks[len(chunks)-1]
+	if *chunks[0].PageStart != 1 || *last.PageEnd != 2 {
+		t.Errorf("page range = %d–%d, want 1–2", *chunks[0].PageStart, *last.PageEnd)
+	}
+}
+
+func TestInflatePDFLimit(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	zw.Write(make([]byte, 1<<20))
+	zw.Close()
+
+	if out, err := inflatePDF(buf.Bytes(), 1<<20); err != nil || len(out) != 1<<20 {
+		t.Fatalf("inflatePDF() at the limit = %d bytes, %v", len(out), err)
+	}
+	if _, err := inflatePDF(buf.Bytes(), 1<<20-1); !errors.Is(err, errPDFInflateLimit) {
+		t.Errorf("inflatePDF() past the stream limit error = %v, want errPDFInflateLimit", err)
+	}
+
+	// The document limit applies across streams
+	f := &pdfFile{inflated: maxPDFInflated - 1<<19}
+	if _, err := f.inflate(buf.Bytes()); !errors.Is(err, errPDFInflateLimit) || f.inflateErr == nil {
+		t.Errorf("inflate() past the document limit error = %v, want errPDFInflateLimit", err)
+	}
+}
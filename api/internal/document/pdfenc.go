@@ -0,0 +1,148 @@
+package document
+
+import (
+	"strconv"
+	"strings"
+)
+
+// winAnsiNames lists the glyph names of WinAnsiEncoding (Windows-1252)
+// from code 32 upwards; "" marks an unused code.
+var winAnsiNames = strings.Fields(`
+space exclam quotedbl numbersign dollar percent ampersand quotesingle
+parenleft parenright asterisk plus comma hyphen period slash
+zero one two three four five six seven eight nine colon semicolon less equal greater question
+at A B C D E F G H I J K L M N O P Q R S T U V W X Y Z
+bracketleft backslash bracketright asciicircum underscore
+grave a b c d e f g h i j k l m n o p q r s t u v w x y z
+braceleft bar braceright asciitilde -
+Euro - quotesinglbase florin quotedblbase ellipsis dagger daggerdbl
+circumflex perthousand Scaron guilsinglleft OE - Zcaron -
+- quoteleft quoteright quotedblleft quotedblright bullet endash emdash
+tilde trademark scaron guilsinglright oe - zcaron Ydieresis
+space exclamdown cent sterling currency yen brokenbar section
+dieresis copyright ordfeminine guillemotleft logicalnot hyphen registered macron
+degree plusminus twosuperior threesuperior acute mu paragraph periodcentered
+cedilla onesuperior ordmasculine guillemotright onequarter onehalf threequarters questiondown
+Agrave Aacute Acircumflex Atilde Adieresis Aring AE Ccedilla
+Egrave Eacute Ecircumflex Edieresis Igrave Iacute Icircumflex Idieresis
+Eth Ntilde Ograve Oacute Ocircumflex Otilde Odieresis multiply
+Oslash Ugrave Uacute Ucircumflex Udieresis Yacute Thorn germandbls
+agrave aacute acircumflex atilde adieresis aring ae ccedilla
+egrave eacute ecircumflex edieresis igrave iacute icircumflex idieresis
+eth ntilde ograve oacute ocircumflex otilde odieresis divide
+oslash ugrave uacute ucircumflex udieresis yacute thorn ydieresis`)
+
+// cp1252High holds the characters of Windows-1252 codes 0x80–0x9F.
+const cp1252High = "€\x00‚ƒ„…†‡ˆ‰Š‹Œ\x00Ž\x00\x00‘’“”•–—˜™š›œ\x00žŸ"
+
+// macRomanHigh holds the characters of MacRomanEncoding codes 0x80–0xFF.
+const macRomanHigh = "ÄÅÇÉÑÖÜáàâäãåçéèêëíìîïñóòôöõúùûü†°¢£§•¶ß®©™´¨≠ÆØ∞±≤≥¥µ∂∑∏π∫ªºΩæø" +
+	"¿¡¬√ƒ≈∆«»… ÀÃÕŒœ–—“”‘’÷◊ÿŸ⁄€‹›ﬁﬂ‡·‚„‰ÂÊÁËÈÍÎÏÌÓÔÒÚÛÙıˆ˜¯˘˙˚¸˝˛ˇ"
+
+var (
+	pdfWinAnsiEncoding  [256]string
+	pdfMacRomanEncoding [256]string
+	pdfStandardEncoding [256]string
+	pdfGlyphNames       = map[string]string{}
+)
+
+func init() {
+	high := []rune(cp1252High)
+	for i, name := range winAnsiNames {
+		code := 32 + i
+		if name == "-" {
+			continue
+		}
+		var r rune
+		switch {
+		case code < 0x80:
+			r = rune(code)
+		case code < 0xA0:
+			r = high[code-0x80]
+		default:
+			r = rune(code)
+		}
+		pdfWinAnsiEncoding[code] = string(r)
+		if _, seen := pdfGlyphNames[name]; !seen {
+			pdfGlyphNames[name] = string(r)
+		}
+	}
+	pdfGlyphNames["space"] = " "
+	pdfGlyphNames["hyphen"] = "-"
+	for name, text := range map[string]string{
+		"fi": "fi", "fl": "fl", "ff": "ff", "ffi": "ffi", "ffl": "ffl",
+		"dotlessi": "ı", "minus": "−", "fraction": "⁄", "Lslash": "Ł", "lslash": "ł",
+		"nbspace": " ", "sfthyphen": "-", "nonbreakingspace": " ", "quotesingle": "'",
+		"Delta": "∆", "Omega": "Ω", "pi": "π", "mu1": "µ", "periodcentered": "·",
+	} {
+		pdfGlyphNames[name] = text
+	}
+
+	for i, r := range []rune(macRomanHigh) {
+		pdfMacRomanEncoding[0x80+i] = string(r)
+	}
+	for code := 32; code < 0x7F; code++ {
+		pdfMacRomanEncoding[code] = string(rune(code))
+		pdfStandardEncoding[code] = string(rune(code))
+	}
+	pdfStandardEncoding['\''] = "’"
+	pdfStandardEncoding['`'] = "‘"
+	for code, text := range map[int]string{
+		0xA1: "¡", 0xA2: "¢", 0xA3: "£", 0xA7: "§", 0xAA: "“", 0xAB: "«", 0xAE: "fi", 0xAF: "fl",
+		0xB1: "–", 0xB7: "•", 0xBA: "”", 0xBB: "»", 0xBC: "…", 0xD0: "—", 0xE1: "Æ", 0xE9: "Ø",
+		0xEA: "Œ", 0xF1: "æ", 0xF5: "ı", 0xF9: "ø", 0xFA: "œ", 0xFB: "ß",
+	} {
+		pdfStandardEncoding[code] = text
+	}
+}
+
+// pdfNamedEncoding returns a copy of a predefined simple-font encoding.
+func pdfNamedEncoding(name pdfName) [256]string {
+	switch name {
+	case "WinAnsiEncoding":
+		return pdfWinAnsiEncoding
+	case "MacRomanEncoding":
+		return pdfMacRomanEncoding
+	}
+	return pdfStandardEncoding
+}
+
+// glyphNameText maps a glyph name from an encoding's Differences array to
+// text: known names, "uniXXXX" and "uXXXX[XX]" forms, suffixed variants
+// ("a.sc") and underscore ligatures ("f_i").
+func glyphNameText(name string) string {
+	if text, ok := pdfGlyphNames[name]; ok {
+		return text
+	}
+	if base, _, found := strings.Cut(name, "."); found && base != "" {
+		return glyphNameText(base)
+	}
+	if strings.Contains(name, "_") {
+		var b strings.Builder
+		for _, part := range strings.Split(name, "_") {
+			b.WriteString(glyphNameText(part))
+		}
+		return b.String()
+	}
+	if strings.HasPrefix(name, "uni") && len(name) >= 7 && (len(name)-3)%4 == 0 {
+		var units []uint16
+		for i := 3; i+4 <= len(name); i += 4 {
+			v, err := strconv.ParseUint(name[i:i+4], 16, 16)
+			if err != nil {
+				return ""
+			}
+			units = append(units, uint16(v))
+		}
+		var b []byte
+		for _, u := range units {
+			b = append(b, byte(u>>8), byte(u))
+		}
+		return utf16BEText(b)
+	}
+	if strings.HasPrefix(name, "u") && len(name) >= 5 && len(name) <= 7 {
+		if v, err := strconv.ParseUint(name[1:], 16, 32); err == nil {
+			return expandLigatures(string(rune(v)))
+		}
+	}
+	return ""
+}
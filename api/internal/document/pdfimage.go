@@ -0,0 +1,299 @@
+package document
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+)
+
+// ocrImage returns the image in a format OCR engines read: JPEG and JPEG
+// 2000 data as stored, CCITT fax data wrapped in a TIFF, and raw samples
+// encoded as PNG.
+func (img pdfImage) ocrImage() (OCRImage, error) {
+	f, s := img.file, img.stream
+	width := pdfInt(f.resolve(s.dict["Width"]))
+	height := pdfInt(f.resolve(s.dict["Height"]))
+	if width <= 0 || height <= 0 {
+		return OCRImage{}, fmt.Errorf("image has no size")
+	}
+
+	filters, params := pdfFilters(s.dict)
+	var last pdfName
+	var lastParms pdfDict
+	if n := len(filters); n > 0 {
+		switch filters[n-1] {
+		case pdfName("DCTDecode"), pdfName("DCT"), pdfName("JPXDecode"),
+			pdfName("CCITTFaxDecode"), pdfName("CCF"), pdfName("JBIG2Decode"):
+			last = filters[n-1].(pdfName)
+			if n-1 < len(params) {
+				lastParms = f.dict(params[n-1])
+			}
+			filters = filters[:n-1]
+		}
+	}
+	data, err := f.applyFilters(s.data, filters, params)
+	if err != nil {
+		return OCRImage{}, err
+	}
+
+	out := OCRImage{Data: data, Width: width, Height: height}
+	switch last {
+	case "DCTDecode", "DCT":
+		out.Format = "jpg"
+	case "JPXDecode":
+		out.Format = "jp2"
+	case "CCITTFaxDecode", "CCF":
+		out.Format = "tiff"
+		out.Data = ccittTIFF(data, width, height, lastParms)
+	case "JBIG2Decode":
+		return OCRImage{}, fmt.Errorf("JBIG2 images are not supported")
+	default:
+		decoded, err := img.samples(data, width, height)
+		if err != nil {
+			return OCRImage{}, err
+		}
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, decoded); err != nil {
+			return OCRImage{}, fmt.Errorf("failed to encode image: %w", err)
+		}
+		out.Format = "png"
+		out.Data = buf.Bytes()
+	}
+	return out, nil
+}
+
+// samples converts raw image samples to an image. Gray, RGB, CMYK,
+// ICC-based and indexed colour spaces are supported at 1–16 bits per
+// component; an inverted /Decode on one-component images is honoured.
+func (img pdfImage) samples(data []byte, width, height int) (image.Image, error) {
+	f, s := img.file, img.stream
+	bpc := pdfInt(f.resolve(s.dict["BitsPerComponent"]))
+	if bpc == 0 {
+		bpc = 8
+	}
+	mask := f.resolve(s.dict["ImageMask"]) == true
+	if mask {
+		bpc = 1
+	}
+
+	comps, baseComps := 1, 1
+	var palette []byte
+	if !mask {
+		var err error
+		comps, palette, baseComps, err = pdfColorSpace(f, f.resolve(s.dict["ColorSpace"]))
+		if err != nil {
+			return nil, err
+		}
+	}
+	switch bpc {
+	case 1, 2, 4, 8, 16:
+	default:
+		return nil, fmt.Errorf("unsupported bits per component: %d", bpc)
+	}
+
+	invert := false
+	if d := f.array(s.dict["Decode"]); len(d) >= 2 && comps == 1 && palette == nil {
+		invert = pdfNumber(f.resolve(d[0]), 0) > pdfNumber(f.resolve(d[1]), 1)
+	}
+
+	rowBytes := (width*comps*bpc + 7) / 8
+	if len(data) < rowBytes*height {
+		data = append(data, make([]byte, rowBytes*height-len(data))...)
+	}
+	maxVal := (1 << bpc) - 1
+	sample := func(row []byte, i int) int {
+		switch bpc {
+		case 8:
+			return int(row[i])
+		case 16:
+			return int(row[2*i])<<8 | int(row[2*i+1])
+		}
+		bit := i * bpc
+		return int(row[bit/8]>>(8-bpc-bit%8)) & maxVal
+	}
+	scale := func(v int) uint8 {
+		return uint8(v * 255 / maxVal)
+	}
+
+	if comps == 1 && (palette == nil || baseComps == 1) {
+		out := image.NewGray(image.Rect(0, 0, width, height))
+		for y := 0; y < height; y++ {
+			row := data[y*rowBytes:]
+			for x := 0; x < width; x++ {
+				v := sample(row, x)
+				var g uint8
+				switch {
+				case palette != nil:
+					if v < len(palette) {
+						g = palette[v]
+					}
+				default:
+					g = scale(v)
+				}
+				if invert {
+					g = 255 - g
+				}
+				out.Pix[y*out.Stride+x] = g
+			}
+		}
+		return out, nil
+	}
+
+	out := image.NewRGBA(image.Rect(0, 0, width, height))
+	px := make([]uint8, baseComps)
+	for y := 0; y < height; y++ {
+		row := data[y*rowBytes:]
+		for x := 0; x < width; x++ {
+			if palette != nil {
+				i := sample(row, x) * baseComps
+				for c := range px {
+					px[c] = 0
+					if i+c < len(palette) {
+						px[c] = palette[i+c]
+					}
+				}
+			} else {
+				for c := range px {
+					px[c] = scale(sample(row, x*comps+c))
+				}
+			}
+			out.Set(x, y, pdfColor(px))
+		}
+	}
+	return out, nil
+}
+
+// pdfColor converts gray, RGB or CMYK components to a colour.
+func pdfColor(px []uint8) color.Color {
+	switch len(px) {
+	case 1:
+		return color.Gray{Y: px[0]}
+	case 3:
+		return color.RGBA{R: px[0], G: px[1], B: px[2], A: 255}
+	}
+	return color.CMYK{C: px[0], M: px[1], Y: px[2], K: px[3]}
+}
+
+// pdfColorSpace returns the number of components per sample of an image
+// colour space. For indexed spaces it also returns the lookup table and the
+// number of components per entry.
+func pdfColorSpace(f *pdfFile, cs interface{}) (comps int, palette []byte, baseComps int, err error) {
+	switch v := cs.(type) {
+	case nil:
+		return 1, nil, 1, nil
+	case pdfName:
+		switch v {
+		case "DeviceGray", "G", "CalGray":
+			return 1, nil, 1, nil
+		case "DeviceRGB", "RGB", "CalRGB":
+			return 3, nil, 3, nil
+		case "DeviceCMYK", "CMYK":
+			return 4, nil, 4, nil
+		}
+	case pdfArray:
+		if len(v) == 0 {
+			break
+		}
+		switch v[0] {
+		case pdfName("ICCBased"):
+			if len(v) > 1 {
+				if s, ok := f.resolve(v[1]).(*pdfStream); ok {
+					n := pdfInt(f.resolve(s.dict["N"]))
+					if n == 1 || n == 3 || n == 4 {
+						return n, nil, n, nil
+					}
+				}
+			}
+		case pdfName("CalGray"), pdfName("CalRGB"), pdfName("Lab"):
+			n := 3
+			if v[0] == pdfName("CalGray") {
+				n = 1
+			}
+			return n, nil, n, nil
+		case pdfName("Indexed"), pdfName("I"):
+			if len(v) < 4 {
+				break
+			}
+			_, _, base, err := pdfColorSpace(f, f.resolve(v[1]))
+			if err != nil {
+				return 0, nil, 0, err
+			}
+			var lookup []byte
+			switch l := f.resolve(v[3]).(type) {
+			case pdfString:
+				lookup = []byte(l)
+			case *pdfStream:
+				if lookup, err = decodePDFStream(l); err != nil {
+					return 0, nil, 0, err
+				}
+			}
+			return 1, lookup, base, nil
+		}
+	}
+	return 0, nil, 0, fmt.Errorf("unsupported colour space %v", cs)
+}
+
+// ccittTIFF wraps CCITT fax data in a single-strip TIFF so it can be read
+// by image tools. BlackIs1 is ignored: it only changes how decoded bits are
+// painted, and OCR engines read either polarity.
+func ccittTIFF(data []byte, width, height int, parms pdfDict) []byte {
+	k := pdfInt(parms["K"])
+	if rows := pdfInt(parms["Rows"]); rows > 0 {
+		height = rows
+	}
+	if cols := pdfInt(parms["Columns"]); cols > 0 {
+		width = cols
+	}
+	type entry struct {
+		tag, kind uint16
+		value     uint32
+	}
+	const short, long = 3, 4
+	entries := []entry{
+		{256, long, uint32(width)},
+		{257, long, uint32(height)},
+		{258, short, 1},
+		{259, short, 4}, // Group 4
+		{262, short, 0}, // WhiteIsZero, as fax runs are coded
+		{273, long, 0},  // strip offset, set below
+		{277, short, 1},
+		{278, long, uint32(height)},
+		{279, long, uint32(len(data))},
+	}
+	if k >= 0 {
+		entries[3].value = 3 // Group 3
+		t4 := uint32(0)
+		if k > 0 {
+			t4 = 1 // 2-D coding
+		}
+		if parms["EncodedByteAlign"] == true {
+			t4 |= 4
+		}
+		entries = append(entries, entry{292, long, t4})
+	}
+	offset := uint32(8 + 2 + 12*len(entries) + 4)
+	entries[5].value = offset
+
+	var buf bytes.Buffer
+	buf.WriteString("II")
+	binary.Write(&buf, binary.LittleEndian, uint16(42))
+	binary.Write(&buf, binary.LittleEndian, uint32(8))
+	binary.Write(&buf, binary.LittleEndian, uint16(len(entries)))
+	for _, e := range entries {
+		binary.Write(&buf, binary.LittleEndian, e.tag)
+		binary.Write(&buf, binary.LittleEndian, e.kind)
+		binary.Write(&buf, binary.LittleEndian, uint32(1))
+		if e.kind == short {
+			binary.Write(&buf, binary.LittleEndian, uint16(e.value))
+			binary.Write(&buf, binary.LittleEndian, uint16(0))
+		} else {
+			binary.Write(&buf, binary.LittleEndian, e.value)
+		}
+	}
+	binary.Write(&buf, binary.LittleEndian, uint32(0))
+	buf.Write(data)
+	return buf.Bytes()
+}
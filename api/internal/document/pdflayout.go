@@ -0,0 +1,303 @@
+package document
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// PDFBox is a rectangle [x0 y0 x1 y1] in points with the origin at the
+// top-left corner of the page, ready to overlay on a rendered page.
+type PDFBox [4]float64
+
+// PDFTable is a table detected on a PDF page from the alignment of its text.
+// It is rendered into the page text as a Markdown pipe table; the cells keep
+// their positions so claims read from them can point back at the page.
+type PDFTable struct {
+	Page   int       `json:"page"`
+	BBox   PDFBox    `json:"bbox"`
+	Header []string  `json:"header"`
+	Cells  []PDFCell `json:"cells"`
+
+	start, end int // byte range of the rendered table in the document text
+}
+
+// PDFCell is one non-empty table cell. Row 1 is the header row.
+type PDFCell struct {
+	Row    int    `json:"row"`
+	Column int    `json:"column"` // 0-based index into the table header
+	Text   string `json:"text"`
+	BBox   PDFBox `json:"bbox"`
+}
+
+// Table detection thresholds, as fractions of the font size.
+const (
+	pdfGutter        = 1.0 // horizontal gap that separates two cells
+	pdfTableRowGap   = 2.5 // vertical gap that ends a table
+	pdfMaxCellLength = 40  // mean cell length (runes) above which a "table" is prose columns
+)
+
+// pdfSegment is a run of glyphs on a line with no gutter inside it.
+type pdfSegment struct {
+	x0, x1 float64
+	text   string
+}
+
+// segments splits a line at gaps wide enough to be table gutters.
+func (l pdfLine) segments() []pdfSegment {
+	var segs []pdfSegment
+	start := 0
+	for i := 1; i <= len(l.glyphs); i++ {
+		if i < len(l.glyphs) {
+			prev, g := l.glyphs[i-1], l.glyphs[i]
+			if g.x-(prev.x+prev.w) <= l.size*pdfGutter {
+				continue
+			}
+		}
+		part := pdfLine{y: l.y, size: l.size, glyphs: l.glyphs[start:i]}
+		if text := strings.TrimSpace(part.text()); text != "" {
+			last := part.glyphs[len(part.glyphs)-1]
+			segs = append(segs, pdfSegment{x0: part.glyphs[0].x, x1: last.x + last.w, text: text})
+		}
+		start = i
+	}
+	return segs
+}
+
+// box returns a segment's bounding box in top-left page coordinates.
+func (l pdfLine) box(s pdfSegment, pageHeight float64) PDFBox {
+	return PDFBox{
+		pdfRound(s.x0),
+		pdfRound(pageHeight - (l.y + l.size*0.8)),
+		pdfRound(s.x1),
+		pdfRound(pageHeight - (l.y - l.size*0.2)),
+	}
+}
+
+func pdfRound(v float64) float64 {
+	return math.Round(v*10) / 10
+}
+
+// union returns the smallest box containing both boxes.
+func (b PDFBox) union(o PDFBox) PDFBox {
+	return PDFBox{math.Min(b[0], o[0]), math.Min(b[1], o[1]), math.Max(b[2], o[2]), math.Max(b[3], o[3])}
+}
+
+// pdfTableBlock is a run of lines that may form a table. Lines with a
+// single indented segment between table rows are wrapped cell text.
+type pdfTableBlock struct {
+	first, last int // line indexes, inclusive
+}
+
+// tableBlocks finds runs of consecutive lines that have at least two
+// segments, allowing wrapped cell lines inside a run.
+func tableBlocks(lines []pdfLine, segs [][]pdfSegment) []pdfTableBlock {
+	var blocks []pdfTableBlock
+	for i := 0; i < len(lines); {
+		if len(segs[i]) < 2 {
+			i++
+			continue
+		}
+		left := segs[i][0].x0
+		j := i
+		for j+1 < len(lines) {
+			next := j + 1
+			if lines[j].y-lines[next].y > math.Max(lines[j].size, lines[next].size)*pdfTableRowGap {
+				break
+			}
+			if len(segs[next]) >= 2 {
+				left = math.Min(left, segs[next][0].x0)
+				j = next
+				continue
+			}
+			// A wrapped cell: one indented segment followed by another row
+			if len(segs[next]) == 1 && segs[next][0].x0 > left+lines[next].size &&
+				next+1 < len(lines) && len(segs[next+1]) >= 2 &&
+				lines[next].y-lines[next+1].y <= math.Max(lines[next].size, lines[next+1].size)*pdfTableRowGap {
+				j = next
+				continue
+			}
+			break
+		}
+		if j > i {
+			blocks = append(blocks, pdfTableBlock{first: i, last: j})
+		}
+		i = j + 1
+	}
+	return blocks
+}
+
+// pdfColumn is the horizontal extent of a table column.
+type pdfColumn struct {
+	x0, x1 float64
+}
+
+// tableColumns merges the segments of a block's rows into columns: segments
+// whose horizontal extents overlap belong to the same column, which covers
+// left-, right- and centre-aligned cells alike.
+func tableColumns(rows [][]pdfSegment) []pdfColumn {
+	var all []pdfSegment
+	for _, row := range rows {
+		all = append(all, row...)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].x0 < all[j].x0 })
+
+	var cols []pdfColumn
+	for _, s := range all {
+		if n := len(cols); n > 0 && s.x0 <= cols[n-1].x1 {
+			cols[n-1].x1 = math.Max(cols[n-1].x1, s.x1)
+			continue
+		}
+		cols = append(cols, pdfColumn{x0: s.x0, x1: s.x1})
+	}
+	return cols
+}
+
+// columnOf returns the column a segment falls in, or the nearest one.
+func columnOf(cols []pdfColumn, s pdfSegment) int {
+	best, bestDist := 0, math.Inf(1)
+	for i, c := range cols {
+		if s.x0 < c.x1 && s.x1 > c.x0 {
+			return i
+		}
+		dist := math.Min(math.Abs(s.x0-c.x1), math.Abs(c.x0-s.x1))
+		if dist < bestDist {
+			best, bestDist = i, dist
+		}
+	}
+	return best
+}
+
+// table builds a table from a block, or returns false if the block's text
+// does not line up in columns like a table does.
+func (p *pdfPage) table(lines []pdfLine, segs [][]pdfSegment, block pdfTableBlock) (*PDFTable, bool) {
+	var rowSegs [][]pdfSegment
+	for i := block.first; i <= block.last; i++ {
+		if len(segs[i]) >= 2 {
+			rowSegs = append(rowSegs, segs[i])
+		}
+	}
+	cols := tableColumns(rowSegs)
+	if len(cols) < 2 {
+		return nil, false
+	}
+
+	type cell struct {
+		text string
+		box  PDFBox
+		set  bool
+	}
+	var grid [][]cell
+	runes, cells := 0, 0
+	for i := block.first; i <= block.last; i++ {
+		if len(segs[i]) >= 2 {
+			grid = append(grid, make([]cell, len(cols)))
+		}
+		row := grid[len(grid)-1]
+		for _, s := range segs[i] {
+			c := &row[columnOf(cols, s)]
+			box := lines[i].box(s, p.height)
+			if c.set {
+				c.text += " " + s.text
+				c.box = c.box.union(box)
+			} else {
+				*c = cell{text: s.text, box: box, set: true}
+			}
+			runes += utf8.RuneCountInString(s.text)
+			cells++
+		}
+	}
+
+	filled := 0
+	for _, row := range grid {
+		n := 0
+		for _, c := range row {
+			if c.set {
+				n++
+			}
+		}
+		if n >= 2 {
+			filled++
+		}
+	}
+	if filled < 2 || runes/cells > pdfMaxCellLength {
+		return nil, false
+	}
+
+	t := &PDFTable{Page: p.number}
+	for r, row := range grid {
+		for c, cl := range row {
+			if r == 0 {
+				t.Header = append(t.Header, cl.text)
+			}
+			if !cl.set {
+				continue
+			}
+			t.Cells = append(t.Cells, PDFCell{Row: r + 1, Column: c, Text: cl.text, BBox: cl.box})
+			if len(t.Cells) == 1 {
+				t.BBox = cl.box
+			} else {
+				t.BBox = t.BBox.union(cl.box)
+			}
+		}
+	}
+	return t, true
+}
+
+// rows returns the table's cell texts row by row, for rendering.
+func (t *PDFTable) rows() [][]string {
+	var rows [][]string
+	for _, c := range t.Cells {
+		for len(rows) < c.Row {
+			rows = append(rows, make([]string, len(t.Header)))
+		}
+		rows[c.Row-1][c.Column] = c.Text
+	}
+	return rows
+}
+
+// layout renders the page as plain text with detected tables written as
+// Markdown pipe tables. A vertical gap of more than about one and a half
+// lines starts a new paragraph. Table offsets are relative to the page text.
+func (p *pdfPage) layout() (string, []PDFTable) {
+	lines := p.lines()
+	segs := make([][]pdfSegment, len(lines))
+	for i, line := range lines {
+		segs[i] = line.segments()
+	}
+
+	tableAt := make(map[int]*PDFTable)
+	end := make(map[int]int)
+	for _, block := range tableBlocks(lines, segs) {
+		if t, ok := p.table(lines, segs, block); ok {
+			tableAt[block.first] = t
+			end[block.first] = block.last
+		}
+	}
+
+	var b strings.Builder
+	var tables []PDFTable
+	afterTable := false
+	for i := 0; i < len(lines); i++ {
+		t := tableAt[i]
+		if i > 0 {
+			b.WriteByte('\n')
+			gap := lines[i-1].y - lines[i].y
+			if t != nil || afterTable || gap > math.Max(lines[i].size, lines[i-1].size)*1.9 {
+				b.WriteByte('\n')
+			}
+		}
+		afterTable = t != nil
+		if t == nil {
+			b.WriteString(lines[i].text())
+			continue
+		}
+		t.start = b.Len()
+		writePipeTable(&b, t.rows())
+		t.end = b.Len()
+		tables = append(tables, *t)
+		i = end[i]
+	}
+	return b.String(), tables
+}
@@ -0,0 +1,71 @@
+package document
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestPDFTableLayout(t *testing.T) {
+	word := func(x0, top, x1 float64, text string) string {
+		return fmt.Sprintf(`<word xMin="%.1f" yMin="%.1f" xMax="%.1f" yMax="%.1f">%s</word>`, x0, top, x1, top+12, text)
+	}
+	words := []string{
+		word(56, 60, 90, "Offert"), word(93, 60, 108, "A2"),
+		word(56, 100, 120, "Beskrivning"), word(300, 100, 330, "Antal"), word(450, 100, 490, "Belopp"),
+		word(56, 115, 140, "Fasadrenovering"), word(320, 115, 326, "1"),
+		word(440, 115, 458, "125"), word(461, 115, 479, "000"), word(482, 115, 490, "kr"),
+		word(56, 130, 100, "Ställning"), word(314, 130, 326, "12"),
+		word(452, 130, 458, "8"), word(461, 130, 479, "400"), word(482, 130, 490, "kr"),
+		word(70, 145, 95, "inkl."), word(98, 145, 140, "transport"),
+		word(56, 160, 85, "Moms"), word(308, 160, 318, "25"), word(320, 160, 326, "%"),
+		word(446, 160, 458, "33"), word(461, 160, 479, "350"), word(482, 160, 490, "kr"),
+		word(56, 200, 110, "Betalning"), word(113, 200, 135, "inom"), word(138, 200, 150, "30"), word(153, 200, 190, "dagar."),
+	}
+	xhtml := `<!DOCTYPE html PUBLIC "-//W3C//DTD XHTML 1.0 Transitional//EN" "http://www.w3.org/TR/xhtml1/DTD/xhtml1-transitional.dtd">
+<html xmlns="http://www.w3.org/1999/xhtml"><head><title></title></head><body><doc>
+<page width="595.0" height="842.0">` + strings.Join(words, "\n") + `</page>
+</doc></body></html>`
+
+	pages, err := parsePDFBBox(strings.NewReader(xhtml))
+	if err != nil {
+		t.Fatalf("parsePDFBBox() error = %v", err)
+	}
+	doc := layoutPDF(pages)
+
+	wantTable := "| Beskrivning | Antal | Belopp |\n|---|---|---|\n" +
+		"| Fasadrenovering | 1 | 125 000 kr |\n" +
+		"| Ställning inkl. transport | 12 | 8 400 kr |\n" +
+		"| Moms | 25 % | 33 350 kr |"
+	want := "Offert A2\n\n" + wantTable + "\n\nBetalning inom 30 dagar."
+	if doc.text != want {
+		t.Fatalf("text = %q, want %q", doc.text, want)
+	}
+
+	if len(doc.tables) != 1 {
+		t.Fatalf("tables = %d, want 1", len(doc.tables))
+	}
+	table := doc.tables[0]
+	if got := doc.text[table.start:table.end]; got != wantTable {
+		t.Errorf("table range = %q", got)
+	}
+	if table.Page != 1 || strings.Join(table.Header, ",") != "Beskrivning,Antal,Belopp" {
+		t.Errorf("table page/header = %d %v", table.Page, table.Header)
+	}
+	var amount *PDFCell
+	for i, c := range table.Cells {
+		if c.Text == "125 000 kr" {
+			amount = &table.Cells[i]
+		}
+	}
+	if amount == nil || amount.Row != 2 || amount.Column != 2 || amount.BBox != (PDFBox{440, 115, 490, 127}) {
+		t.Errorf("amount cell = %+v", amount)
+	}
+
+	if got := chunkTables("Betalning inom 30 dagar.", doc.text, doc.tables); len(got) != 0 {
+		t.Errorf("chunkTables() outside the table = %d tables", len(got))
+	}
+	if got := chunkTables(doc.text, doc.text, doc.tables); len(got) != 1 {
+		t.Errorf("chunkTables() over the document = %d tables, want 1", len(got))
+	}
+}
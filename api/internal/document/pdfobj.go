@@ -0,0 +1,845 @@
+package document
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+)
+
+// PDF object model used by the native extractor. Numbers are float64,
+// strings are raw bytes and streams keep their encoded data until decoded.
+type (
+	pdfName    string
+	pdfString  string
+	pdfKeyword string
+	pdfArray   []interface{}
+	pdfDict    map[pdfName]interface{}
+)
+
+type pdfRef struct{ num, gen int }
+
+type pdfStream struct {
+	dict pdfDict
+	data []byte
+	file *pdfFile
+}
+
+func isPDFSpace(c byte) bool {
+	return c == 0 || c == '\t' || c == '\n' || c == '\f' || c == '\r' || c == ' '
+}
+
+func isPDFDelim(c byte) bool {
+	switch c {
+	case '(', ')', '<', '>', '[', ']', '{', '}', '/', '%':
+		return true
+	}
+	return false
+}
+
+// pdfLexer reads PDF tokens and objects from a byte slice.
+type pdfLexer struct {
+	data []byte
+	pos  int
+	refs bool // parse "n g R" as references (off in content streams)
+}
+
+func (l *pdfLexer) skipSpace() {
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		if isPDFSpace(c) {
+			l.pos++
+			continue
+		}
+		if c == '%' {
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+			continue
+		}
+		return
+	}
+}
+
+// token returns the next primitive. Array and dictionary delimiters are
+// returned as keywords; io.EOF marks the end of data.
+func (l *pdfLexer) token() (interface{}, error) {
+	l.skipSpace()
+	if l.pos >= len(l.data) {
+		return nil, io.EOF
+	}
+	c := l.data[l.pos]
+	switch {
+	case c == '/':
+		return l.name(), nil
+	case c == '(':
+		return l.literalString(), nil
+	case c == '<':
+		if l.pos+1 < len(l.data) && l.data[l.pos+1] == '<' {
+			l.pos += 2
+			return pdfKeyword("<<"), nil
+		}
+		return l.hexString(), nil
+	case c == '>':
+		if l.pos+1 < len(l.data) && l.data[l.pos+1] == '>' {
+			l.pos += 2
+			return pdfKeyword(">>"), nil
+		}
+		l.pos++
+		return pdfKeyword(">"), nil
+	case c == '[' || c == ']' || c == '{' || c == '}' || c == ')':
+		l.pos++
+		return pdfKeyword(string(c)), nil
+	}
+
+	start := l.pos
+	for l.pos < len(l.data) && !isPDFSpace(l.data[l.pos]) && !isPDFDelim(l.data[l.pos]) {
+		l.pos++
+	}
+	word := string(l.data[start:l.pos])
+	if n, err := strconv.ParseFloat(word, 64); err == nil && (word[0] < 'A' || word[0] > 'z') {
+		return n, nil
+	}
+	switch word {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+	return pdfKeyword(word), nil
+}
+
+func (l *pdfLexer) name() pdfName {
+	l.pos++ // '/'
+	var b []byte
+	for l.pos < len(l.data) && !isPDFSpace(l.data[l.pos]) && !isPDFDelim(l.data[l.pos]) {
+		c := l.data[l.pos]
+		if c == '#' && l.pos+2 < len(l.data) {
+			if v, err := strconv.ParseUint(string(l.data[l.pos+1:l.pos+3]), 16, 8); err == nil {
+				b = append(b, byte(v))
+				l.pos += 3
+				continue
+			}
+		}
+		b = append(b, c)
+		l.pos++
+	}
+	return pdfName(b)
+}
+
+func (l *pdfLexer) literalString() pdfString {
+	l.pos++ // '('
+	var b []byte
+	depth := 1
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return pdfString(b)
+			}
+		case '\\':
+			if l.pos >= len(l.data) {
+				continue
+			}
+			e := l.data[l.pos]
+			l.pos++
+			switch e {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r':
+				if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+					l.pos++
+				}
+				continue
+			case '\n':
+				continue
+			default:
+				if e >= '0' && e <= '7' {
+					v := int(e - '0')
+					for i := 0; i < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; i++ {
+						v = v*8 + int(l.data[l.pos]-'0')
+						l.pos++
+					}
+					c = byte(v)
+				} else {
+					c = e
+				}
+			}
+		}
+		b = append(b, c)
+	}
+	return pdfString(b)
+}
+
+func (l *pdfLexer) hexString() pdfString {
+	l.pos++ // '<'
+	var digits []byte
+	for l.pos < len(l.data) && l.data[l.pos] != '>' {
+		c := l.data[l.pos]
+		if (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F') {
+			digits = append(digits, c)
+		}
+		l.pos++
+	}
+	l.pos++ // '>'
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	out := make([]byte, len(digits)/2)
+	hex.Decode(out, digits)
+	return pdfString(out)
+}
+
+// object parses one complete object. Streams are not handled here since
+// their length may need resolving; see pdfFile.parseAt.
+func (l *pdfLexer) object() (interface{}, error) {
+	tok, err := l.token()
+	if err != nil {
+		return nil, err
+	}
+	return l.complete(tok)
+}
+
+// complete finishes parsing an object whose first token has been read.
+func (l *pdfLexer) complete(tok interface{}) (interface{}, error) {
+	switch t := tok.(type) {
+	case pdfKeyword:
+		switch t {
+		case "[":
+			var arr pdfArray
+			for {
+				next, err := l.token()
+				if err != nil {
+					return arr, err
+				}
+				if next == pdfKeyword("]") {
+					return arr, nil
+				}
+				obj, err := l.complete(next)
+				if err != nil {
+					return arr, err
+				}
+				arr = append(arr, obj)
+			}
+		case "<<":
+			dict := pdfDict{}
+			for {
+				next, err := l.token()
+				if err != nil {
+					return dict, err
+				}
+				if next == pdfKeyword(">>") {
+					return dict, nil
+				}
+				key, ok := next.(pdfName)
+				if !ok {
+					continue // tolerate junk between entries
+				}
+				val, err := l.object()
+				if err != nil {
+					return dict, err
+				}
+				if val == pdfKeyword(">>") {
+					return dict, nil
+				}
+				dict[key] = val
+			}
+		}
+		return t, nil
+	case float64:
+		if !l.refs {
+			return t, nil
+		}
+		// "n g R" is a reference; look ahead without consuming otherwise.
+		save := l.pos
+		gen, err := l.token()
+		if g, ok := gen.(float64); ok && err == nil {
+			if r, err := l.token(); err == nil && r == pdfKeyword("R") {
+				return pdfRef{num: int(t), gen: int(g)}, nil
+			}
+		}
+		l.pos = save
+		return t, nil
+	}
+	return tok, nil
+}
+
+// pdfObjHeader matches the start of an indirect object definition.
+var pdfObjHeader = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+
+// pdfTrailer matches a classic trailer dictionary.
+var pdfTrailer = regexp.MustCompile(`trailer\s*<<`)
+
+// pdfFile gives random access to the objects of a PDF. Rather than trusting
+// the cross-reference table, which is often damaged in scanned or edited
+// files, it indexes every "n g obj" header in the file, later definitions
+// replacing earlier ones as incremental updates do, and then indexes the
+// contents of object streams.
+type pdfFile struct {
+	data       []byte
+	offsets    map[int]int
+	compressed map[int]pdfCompressedLoc
+	cache      map[int]interface{}
+	trailers   []pdfDict
+	inflated   int   // decompressed Flate bytes, counted against maxPDFInflated
+	inflateErr error // set once a stream exceeds an inflate limit
+}
+
+type pdfCompressedLoc struct {
+	stream int
+	offset int // byte offset of the object in the decoded stream
+}
+
+func openPDF(data []byte) (*pdfFile, error) {
+	f := &pdfFile{
+		data:       data,
+		offsets:    make(map[int]int),
+		compressed: make(map[int]pdfCompressedLoc),
+		cache:      make(map[int]interface{}),
+	}
+
+	var objStreams []int
+	pos := 0
+	for pos < len(data) {
+		loc := pdfObjHeader.FindSubmatchIndex(data[pos:])
+		if loc == nil {
+			break
+		}
+		start := pos + loc[0]
+		num, _ := strconv.Atoi(string(data[pos+loc[2] : pos+loc[3]]))
+		if start > 0 && !isPDFSpace(data[start-1]) && !isPDFDelim(data[start-1]) {
+			pos += loc[1]
+			continue
+		}
+		f.offsets[num] = start
+
+		obj, end, err := f.parseAt(start, false)
+		if err != nil {
+			pos += loc[1]
+			continue
+		}
+		if s, ok := obj.(*pdfStream); ok {
+			switch s.dict["Type"] {
+			case pdfName("ObjStm"):
+				objStreams = append(objStreams, num)
+			case pdfName("XRef"):
+				f.trailers = append(f.trailers, s.dict)
+			}
+		}
+		pos = end
+	}
+
+	for _, loc := range pdfTrailer.FindAllIndex(data, -1) {
+		l := &pdfLexer{data: data, pos: loc[1] - 2, refs: true}
+		if d, err := l.object(); err == nil {
+			if dict, ok := d.(pdfDict); ok {
+				f.trailers = append(f.trailers, dict)
+			}
+		}
+	}
+
+	for _, num := range objStreams {
+		f.indexObjectStream(num)
+	}
+
+	if len(f.offsets) == 0 {
+		return nil, fmt.Errorf("no PDF objects found")
+	}
+	return f, nil
+}
+
+// parseAt parses the indirect object whose header starts at offset,
+// returning it and the offset just past it. With resolve set, an indirect
+// stream /Length is looked up; otherwise the stream end is found by
+// searching for "endstream".
+func (f *pdfFile) parseAt(offset int, resolve bool) (interface{}, int, error) {
+	l := &pdfLexer{data: f.data, pos: offset, refs: true}
+	for i := 0; i < 3; i++ { // "n g obj"
+		if _, err := l.token(); err != nil {
+			return nil, 0, err
+		}
+	}
+	obj, err := l.object()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	dict, ok := obj.(pdfDict)
+	if !ok {
+		return obj, l.pos, nil
+	}
+	save := l.pos
+	if tok, err := l.token(); err != nil || tok != pdfKeyword("stream") {
+		l.pos = save
+		return obj, l.pos, nil
+	}
+
+	// Stream data starts after the EOL following "stream".
+	if l.pos < len(f.data) && f.data[l.pos] == '\r' {
+		l.pos++
+	}
+	if l.pos < len(f.data) && f.data[l.pos] == '\n' {
+		l.pos++
+	}
+	start := l.pos
+
+	length := -1
+	switch v := dict["Length"].(type) {
+	case float64:
+		length = int(v)
+	case pdfRef:
+		if resolve {
+			if n, ok := f.resolve(v).(float64); ok {
+				length = int(n)
+			}
+		}
+	}
+	end := -1
+	if length >= 0 && start+length <= len(f.data) {
+		rest := bytes.TrimLeft(f.data[start+length:min(len(f.data), start+length+32)], "\r\n\t \x00")
+		if bytes.HasPrefix(rest, []byte("endstream")) {
+			end = start + length
+		}
+	}
+	if end < 0 {
+		idx := bytes.Index(f.data[start:], []byte("endstream"))
+		if idx < 0 {
+			return nil, 0, fmt.Errorf("unterminated stream")
+		}
+		end = start + idx
+		// Drop the EOL that precedes "endstream".
+		for end > start && (f.data[end-1] == '\n' || f.data[end-1] == '\r') {
+			end--
+		}
+	}
+
+	after := max(0, bytes.Index(f.data[end:], []byte("endstream")))
+	return &pdfStream{dict: dict, data: f.data[start:end], file: f}, end + after + len("endstream"), nil
+}
+
+// indexObjectStream records the objects stored in an object stream.
+// Objects defined directly in the file take precedence.
+func (f *pdfFile) indexObjectStream(num int) {
+	s, ok := f.object(num).(*pdfStream)
+	if !ok {
+		return
+	}
+	data, err := decodePDFStream(s)
+	if err != nil {
+		return
+	}
+	n := pdfInt(s.dict["N"])
+	first := pdfInt(s.dict["First"])
+	l := &pdfLexer{data: data}
+	for i := 0; i < n; i++ {
+		a, err1 := l.token()
+		b, err2 := l.token()
+		if err1 != nil || err2 != nil {
+			return
+		}
+		objNum, off := pdfInt(a), pdfInt(b)
+		if _, direct := f.offsets[objNum]; !direct {
+			f.compressed[objNum] = pdfCompressedLoc{stream: num, offset: first + off}
+		}
+	}
+}
+
+// object returns indirect object num, or nil if it does not exist.
+func (f *pdfFile) object(num int) interface{} {
+	if obj, ok := f.cache[num]; ok {
+		return obj
+	}
+	f.cache[num] = nil // guards against reference cycles
+
+	var obj interface{}
+	if offset, ok := f.offsets[num]; ok {
+		obj, _, _ = f.parseAt(offset, true)
+	} else if loc, ok := f.compressed[num]; ok {
+		if s, ok := f.object(loc.stream).(*pdfStream); ok {
+			if data, err := decodePDFStream(s); err == nil && loc.offset < len(data) {
+				l := &pdfLexer{data: data, pos: loc.offset, refs: true}
+				obj, _ = l.object()
+			}
+		}
+	}
+	f.cache[num] = obj
+	return obj
+}
+
+// resolve follows references until a direct object is reached.
+func (f *pdfFile) resolve(obj interface{}) interface{} {
+	for i := 0; i < 32; i++ {
+		ref, ok := obj.(pdfRef)
+		if !ok {
+			return obj
+		}
+		obj = f.object(ref.num)
+	}
+	return nil
+}
+
+func (f *pdfFile) dict(obj interface{}) pdfDict {
+	switch v := f.resolve(obj).(type) {
+	case pdfDict:
+		return v
+	case *pdfStream:
+		return v.dict
+	}
+	return nil
+}
+
+func (f *pdfFile) array(obj interface{}) pdfArray {
+	a, _ := f.resolve(obj).(pdfArray)
+	return a
+}
+
+// catalog returns the document catalog.
+func (f *pdfFile) catalog() (pdfDict, error) {
+	for i := len(f.trailers) - 1; i >= 0; i-- {
+		if _, ok := f.trailers[i]["Encrypt"]; ok {
+			return nil, fmt.Errorf("encrypted PDFs are not supported")
+		}
+	}
+	for i := len(f.trailers) - 1; i >= 0; i-- {
+		if root := f.dict(f.trailers[i]["Root"]); root != nil {
+			return root, nil
+		}
+	}
+	// No usable trailer: take the last catalog object in the file.
+	best := -1
+	for num := range f.offsets {
+		if num > best && f.dict(pdfRef{num: num})["Type"] == pdfName("Catalog") {
+			best = num
+		}
+	}
+	if best < 0 {
+		return nil, fmt.Errorf("PDF catalog not found")
+	}
+	return f.dict(pdfRef{num: best}), nil
+}
+
+func pdfInt(obj interface{}) int {
+	if n, ok := obj.(float64); ok {
+		return int(n)
+	}
+	return 0
+}
+
+func pdfNumber(obj interface{}, fallback float64) float64 {
+	if n, ok := obj.(float64); ok {
+		return n
+	}
+	return fallback
+}
+
+// decodePDFStream applies a stream's filters. Image-only filters (DCT, JPX,
+// CCITT, JBIG2) are not decoded and yield an error.
+func decodePDFStream(s *pdfStream) ([]byte, error) {
+	filters, params := pdfFilters(s.dict)
+	return s.file.applyFilters(s.data, filters, params)
+}
+
+// pdfFilters returns a stream's filters with their decode parameters.
+func pdfFilters(dict pdfDict) (filters, params pdfArray) {
+	switch f := dict["Filter"].(type) {
+	case pdfName:
+		filters = pdfArray{f}
+		params = pdfArray{dict["DecodeParms"]}
+	case pdfArray:
+		filters = f
+		if p, ok := dict["DecodeParms"].(pdfArray); ok {
+			params = p
+		}
+	}
+	return filters, params
+}
+
+// applyFilters decodes data through a chain of filters.
+func (f *pdfFile) applyFilters(data []byte, filters, params pdfArray) ([]byte, error) {
+	for i, filter := range filters {
+		var parms pdfDict
+		if i < len(params) {
+			parms, _ = params[i].(pdfDict)
+		}
+		var err error
+		switch filter {
+		case pdfName("FlateDecode"), pdfName("Fl"):
+			data, err = f.inflate(data)
+			if err == nil {
+				data, err = unpredictPDF(data, parms)
+			}
+		case pdfName("LZWDecode"), pdfName("LZW"):
+			early := 1
+			if v, ok := parms["EarlyChange"].(float64); ok {
+				early = int(v)
+			}
+			data = lzwDecodePDF(data, early)
+			data, err = unpredictPDF(data, parms)
+		case pdfName("ASCIIHexDecode"), pdfName("AHx"):
+			data = []byte((&pdfLexer{data: append(append([]byte("<"), data...), '>')}).hexString())
+		case pdfName("ASCII85Decode"), pdfName("A85"):
+			data, err = ascii85DecodePDF(data)
+		case pdfName("RunLengthDecode"), pdfName("RL"):
+			data = runLengthDecodePDF(data)
+		default:
+			err = fmt.Errorf("unsupported filter %v", filter)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// Limits on decompressed Flate data. A few kilobytes of zeros inflate to
+// gigabytes, so a stream or document that decompresses past these is
+// rejected rather than read into memory.
+const (
+	maxPDFStreamInflated = 256 << 20
+	maxPDFInflated       = 1 << 30
+)
+
+// errPDFInflateLimit is returned when a Flate stream decompresses past
+// maxPDFStreamInflated, or the document past maxPDFInflated.
+var errPDFInflateLimit = errors.New("decompressed PDF data exceeds size limit")
+
+// inflate decompresses zlib data, keeping whatever could be read from
+// truncated or corrupt streams, and counts it against the document limit.
+func (f *pdfFile) inflate(data []byte) ([]byte, error) {
+	limit := maxPDFStreamInflated
+	if remaining := maxPDFInflated - f.inflated; remaining < limit {
+		limit = remaining
+	}
+	out, err := inflatePDF(data, limit)
+	if errors.Is(err, errPDFInflateLimit) {
+		f.inflateErr = err
+	}
+	f.inflated += len(out)
+	return out, err
+}
+
+// inflatePDF decompresses zlib data, keeping whatever could be read from
+// truncated or corrupt streams. It fails if the data inflates past limit.
+func inflatePDF(data []byte, limit int) ([]byte, error) {
+	zr, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid Flate stream: %w", err)
+	}
+	defer zr.Close()
+	out, err := io.ReadAll(io.LimitReader(zr, int64(limit)+1))
+	if len(out) > limit {
+		return nil, errPDFInflateLimit
+	}
+	if err != nil && len(out) == 0 {
+		return nil, fmt.Errorf("invalid Flate stream: %w", err)
+	}
+	return out, nil
+}
+
+// unpredictPDF reverses PNG (>= 10) and TIFF (2) predictors.
+func unpredictPDF(data []byte, parms pdfDict) ([]byte, error) {
+	predictor := pdfInt(parms["Predictor"])
+	if predictor < 2 {
+		return data, nil
+	}
+	colors := max(1, pdfInt(parms["Colors"]))
+	bpc := pdfInt(parms["BitsPerComponent"])
+	if bpc == 0 {
+		bpc = 8
+	}
+	columns := max(1, pdfInt(parms["Columns"]))
+	bpp := max(1, colors*bpc/8)
+	rowLen := (colors*bpc*columns + 7) / 8
+
+	if predictor == 2 {
+		if bpc != 8 {
+			return data, nil
+		}
+		for row := 0; row+rowLen <= len(data); row += rowLen {
+			for i := bpp; i < rowLen; i++ {
+				data[row+i] += data[row+i-bpp]
+			}
+		}
+		return data, nil
+	}
+
+	var out []byte
+	prev := make([]byte, rowLen)
+	for pos := 0; pos+1+rowLen <= len(data); pos += 1 + rowLen {
+		kind := data[pos]
+		row := append([]byte(nil), data[pos+1:pos+1+rowLen]...)
+		for i := range row {
+			var left, up, upLeft byte
+			if i >= bpp {
+				left = row[i-bpp]
+				upLeft = prev[i-bpp]
+			}
+			up = prev[i]
+			switch kind {
+			case 1:
+				row[i] += left
+			case 2:
+				row[i] += up
+			case 3:
+				row[i] += byte((int(left) + int(up)) / 2)
+			case 4:
+				row[i] += paeth(left, up, upLeft)
+			}
+		}
+		out = append(out, row...)
+		prev = row
+	}
+	return out, nil
+}
+
+func paeth(a, b, c byte) byte {
+	p := int(a) + int(b) - int(c)
+	pa, pb, pc := abs(p-int(a)), abs(p-int(b)), abs(p-int(c))
+	if pa <= pb && pa <= pc {
+		return a
+	}
+	if pb <= pc {
+		return b
+	}
+	return c
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// lzwDecodePDF decodes PDF's MSB-first LZW variant, which switches code
+// width one code early unless EarlyChange is 0.
+func lzwDecodePDF(data []byte, early int) []byte {
+	var out []byte
+	table := make([][]byte, 258, 4096)
+	reset := func() {
+		table = table[:258]
+		for i := 0; i < 256; i++ {
+			table[i] = []byte{byte(i)}
+		}
+	}
+	reset()
+
+	width := 9
+	var bits uint32
+	nbits := 0
+	var prev []byte
+	for _, b := range data {
+		bits = bits<<8 | uint32(b)
+		nbits += 8
+		for nbits >= width {
+			code := int(bits>>(nbits-width)) & (1<<width - 1)
+			nbits -= width
+			switch {
+			case code == 256:
+				reset()
+				width = 9
+				prev = nil
+				continue
+			case code == 257:
+				return out
+			}
+			var entry []byte
+			if code < len(table) {
+				entry = table[code]
+			} else if prev != nil {
+				entry = append(append([]byte(nil), prev...), prev[0])
+			} else {
+				return out
+			}
+			out = append(out, entry...)
+			if prev != nil && len(table) < 4096 {
+				table = append(table, append(append([]byte(nil), prev...), entry[0]))
+			}
+			prev = entry
+			if len(table)+early >= 1<<width && width < 12 {
+				width++
+			}
+		}
+	}
+	return out
+}
+
+func ascii85DecodePDF(data []byte) ([]byte, error) {
+	var out []byte
+	var group [5]byte
+	n := 0
+	for _, c := range data {
+		switch {
+		case c == '~':
+			goto done
+		case c == 'z' && n == 0:
+			out = append(out, 0, 0, 0, 0)
+		case c >= '!' && c <= 'u':
+			group[n] = c - '!'
+			n++
+			if n == 5 {
+				v := uint32(0)
+				for _, g := range group {
+					v = v*85 + uint32(g)
+				}
+				out = append(out, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+				n = 0
+			}
+		case isPDFSpace(c):
+		default:
+			return nil, fmt.Errorf("invalid ASCII85 byte %q", c)
+		}
+	}
+done:
+	if n > 1 {
+		for i := n; i < 5; i++ {
+			group[i] = 84
+		}
+		v := uint32(0)
+		for _, g := range group {
+			v = v*85 + uint32(g)
+		}
+		tail := []byte{byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v)}
+		out = append(out, tail[:n-1]...)
+	}
+	return out, nil
+}
+
+func runLengthDecodePDF(data []byte) []byte {
+	var out []byte
+	for i := 0; i < len(data); {
+		n := int(data[i])
+		i++
+		switch {
+		case n < 128:
+			end := min(len(data), i+n+1)
+			out = append(out, data[i:end]...)
+			i = end
+		case n > 128 && i < len(data):
+			out = append(out, bytes.Repeat(data[i:i+1], 257-n)...)
+			i++
+		default:
+			return out
+		}
+	}
+	return out
+}
@@ -0,0 +1,735 @@
+package document
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"unicode/utf16"
+)
+
+// pdfGlyph is one decoded character code placed on the page. Coordinates
+// are in default user space (points, origin bottom-left); y is the baseline.
+type pdfGlyph struct {
+	x, y, w, size float64
+	text          string
+}
+
+// pdfPage is the text content of one page.
+type pdfPage struct {
+	number        int
+	width, height float64
+	glyphs        []pdfGlyph
+	images        []pdfImage
+}
+
+// pdfImage is an image XObject drawn on a page. box is the area it covers
+// in default user space [x0 y0 x1 y1].
+type pdfImage struct {
+	file   *pdfFile
+	stream *pdfStream
+	box    [4]float64
+}
+
+// pdfMatrix is an affine transform [a b c d e f].
+type pdfMatrix [6]float64
+
+var pdfIdentity = pdfMatrix{1, 0, 0, 1, 0, 0}
+
+// mul returns m × n (apply m, then n).
+func (m pdfMatrix) mul(n pdfMatrix) pdfMatrix {
+	return pdfMatrix{
+		m[0]*n[0] + m[1]*n[2],
+		m[0]*n[1] + m[1]*n[3],
+		m[2]*n[0] + m[3]*n[2],
+		m[2]*n[1] + m[3]*n[3],
+		m[4]*n[0] + m[5]*n[2] + n[4],
+		m[4]*n[1] + m[5]*n[3] + n[5],
+	}
+}
+
+func pdfMatrixFrom(arr pdfArray) (pdfMatrix, bool) {
+	if len(arr) != 6 {
+		return pdfIdentity, false
+	}
+	var m pdfMatrix
+	for i, v := range arr {
+		n, ok := v.(float64)
+		if !ok {
+			return pdfIdentity, false
+		}
+		m[i] = n
+	}
+	return m, true
+}
+
+// extractPDFPages reads the text of every page of a PDF without external
+// tools. Text is decoded through each font's ToUnicode map or its simple
+// encoding, and glyph positions are tracked so lines can be rebuilt.
+func extractPDFPages(data []byte) ([]*pdfPage, error) {
+	f, err := openPDF(data)
+	if err != nil {
+		return nil, err
+	}
+	catalog, err := f.catalog()
+	if err != nil {
+		return nil, err
+	}
+
+	var pages []*pdfPage
+	visited := make(map[interface{}]bool)
+	var walk func(node interface{}, inherited pdfDict, depth int)
+	walk = func(node interface{}, inherited pdfDict, depth int) {
+		if ref, ok := node.(pdfRef); ok {
+			if visited[ref] {
+				return
+			}
+			visited[ref] = true
+		}
+		dict := f.dict(node)
+		if dict == nil || depth > 64 {
+			return
+		}
+
+		attrs := pdfDict{}
+		for k, v := range inherited {
+			attrs[k] = v
+		}
+		for _, key := range []pdfName{"Resources", "MediaBox", "CropBox"} {
+			if v, ok := dict[key]; ok {
+				attrs[key] = v
+			}
+		}
+
+		if kids := f.array(dict["Kids"]); kids != nil && dict["Type"] != pdfName("Page") {
+			for _, kid := range kids {
+				walk(kid, attrs, depth+1)
+			}
+			return
+		}
+
+		page := &pdfPage{number: len(pages) + 1, width: 612, height: 792}
+		if box := f.array(attrs["MediaBox"]); len(box) == 4 {
+			page.width = pdfNumber(f.resolve(box[2]), 612) - pdfNumber(f.resolve(box[0]), 0)
+			page.height = pdfNumber(f.resolve(box[3]), 792) - pdfNumber(f.resolve(box[1]), 0)
+		}
+
+		var content []byte
+		switch c := f.resolve(dict["Contents"]).(type) {
+		case *pdfStream:
+			content, _ = decodePDFStream(c)
+		case pdfArray:
+			for _, part := range c {
+				if s, ok := f.resolve(part).(*pdfStream); ok {
+					if d, err := decodePDFStream(s); err == nil {
+						content = append(append(content, d...), '\n')
+					}
+				}
+			}
+		}
+
+		in := &pdfInterpreter{file: f, page: page, fonts: make(map[interface{}]*pdfFont)}
+		in.run(content, f.dict(attrs["Resources"]), pdfIdentity, 0)
+		pages = append(pages, page)
+	}
+	walk(catalog["Pages"], pdfDict{}, 0)
+
+	if f.inflateErr != nil {
+		return nil, f.inflateErr
+	}
+	if len(pages) == 0 {
+		return nil, fmt.Errorf("PDF has no pages")
+	}
+	return pages, nil
+}
+
+// pdfTextState is the part of the graphics state that affects text.
+type pdfTextState struct {
+	font                                *pdfFont
+	size, charSpace, wordSpace, leading float64
+	scale, rise                         float64
+}
+
+type pdfGraphicsState struct {
+	ctm  pdfMatrix
+	text pdfTextState
+}
+
+// pdfInterpreter runs a page's content stream, recording glyphs.
+type pdfInterpreter struct {
+	file  *pdfFile
+	page  *pdfPage
+	fonts map[interface{}]*pdfFont
+}
+
+func (in *pdfInterpreter) run(content []byte, resources pdfDict, ctm pdfMatrix, depth int) {
+	if depth > 8 {
+		return
+	}
+	f := in.file
+	gs := pdfGraphicsState{ctm: ctm, text: pdfTextState{scale: 100}}
+	var stack []pdfGraphicsState
+	var tm, tlm pdfMatrix
+	var operands []interface{}
+
+	num := func(i int) float64 {
+		if i < len(operands) {
+			return pdfNumber(operands[i], 0)
+		}
+		return 0
+	}
+	nextLine := func(tx, ty float64) {
+		tlm = pdfMatrix{1, 0, 0, 1, tx, ty}.mul(tlm)
+		tm = tlm
+	}
+	show := func(s pdfString) {
+		ts := &gs.text
+		if ts.font == nil {
+			return
+		}
+		for _, code := range ts.font.decode([]byte(s)) {
+			trm := pdfMatrix{ts.size * ts.scale / 100, 0, 0, ts.size, 0, ts.rise}.mul(tm).mul(gs.ctm)
+			tx := code.width*ts.size + ts.charSpace
+			if code.space {
+				tx += ts.wordSpace
+			}
+			tx *= ts.scale / 100
+			device := tm.mul(gs.ctm)
+			size := ts.size * math.Hypot(device[2], device[3])
+			if code.text != "" {
+				in.page.glyphs = append(in.page.glyphs, pdfGlyph{
+					x:    trm[4],
+					y:    trm[5],
+					w:    tx * math.Hypot(device[0], device[1]),
+					size: size,
+					text: code.text,
+				})
+			}
+			tm = pdfMatrix{1, 0, 0, 1, tx, 0}.mul(tm)
+		}
+	}
+
+	l := &pdfLexer{data: content}
+	for {
+		tok, err := l.token()
+		if err == io.EOF {
+			break
+		}
+		op, isOp := tok.(pdfKeyword)
+		if !isOp || op == "[" || op == "<<" {
+			obj, _ := l.complete(tok)
+			operands = append(operands, obj)
+			continue
+		}
+
+		switch op {
+		case "q":
+			stack = append(stack, gs)
+		case "Q":
+			if n := len(stack); n > 0 {
+				gs = stack[n-1]
+				stack = stack[:n-1]
+			}
+		case "cm":
+			if m, ok := pdfMatrixFrom(pdfArray(operands)); ok {
+				gs.ctm = m.mul(gs.ctm)
+			}
+		case "BT":
+			tm, tlm = pdfIdentity, pdfIdentity
+		case "Tf":
+			if len(operands) == 2 {
+				if name, ok := operands[0].(pdfName); ok {
+					gs.text.font = in.font(f.dict(resources["Font"])[name])
+				}
+				gs.text.size = num(1)
+			}
+		case "Tc":
+			gs.text.charSpace = num(0)
+		case "Tw":
+			gs.text.wordSpace = num(0)
+		case "Tz":
+			gs.text.scale = num(0)
+		case "TL":
+			gs.text.leading = num(0)
+		case "Ts":
+			gs.text.rise = num(0)
+		case "Td":
+			nextLine(num(0), num(1))
+		case "TD":
+			gs.text.leading = -num(1)
+			nextLine(num(0), num(1))
+		case "Tm":
+			if m, ok := pdfMatrixFrom(pdfArray(operands)); ok {
+				tm, tlm = m, m
+			}
+		case "T*":
+			nextLine(0, -gs.text.leading)
+		case "Tj":
+			if len(operands) > 0 {
+				if s, ok := operands[0].(pdfString); ok {
+					show(s)
+				}
+			}
+		case "'":
+			nextLine(0, -gs.text.leading)
+			if len(operands) > 0 {
+				if s, ok := operands[0].(pdfString); ok {
+					show(s)
+				}
+			}
+		case "\"":
+			gs.text.wordSpace, gs.text.charSpace = num(0), num(1)
+			nextLine(0, -gs.text.leading)
+			if len(operands) > 2 {
+				if s, ok := operands[2].(pdfString); ok {
+					show(s)
+				}
+			}
+		case "TJ":
+			if len(operands) == 0 {
+				break
+			}
+			arr, _ := operands[0].(pdfArray)
+			for _, item := range arr {
+				switch v := item.(type) {
+				case pdfString:
+					show(v)
+				case float64:
+					tx := -v / 1000 * gs.text.size * gs.text.scale / 100
+					tm = pdfMatrix{1, 0, 0, 1, tx, 0}.mul(tm)
+				}
+			}
+		case "Do":
+			if len(operands) == 0 {
+				break
+			}
+			name, _ := operands[0].(pdfName)
+			xobj, ok := f.resolve(f.dict(resources["XObject"])[name]).(*pdfStream)
+			if ok && xobj.dict["Subtype"] == pdfName("Image") {
+				in.page.images = append(in.page.images, pdfImage{file: f, stream: xobj, box: unitSquare(gs.ctm)})
+				break
+			}
+			if !ok || xobj.dict["Subtype"] != pdfName("Form") {
+				break
+			}
+			data, err := decodePDFStream(xobj)
+			if err != nil {
+				break
+			}
+			formRes := f.dict(xobj.dict["Resources"])
+			if formRes == nil {
+				formRes = resources
+			}
+			m, _ := pdfMatrixFrom(f.array(xobj.dict["Matrix"]))
+			in.run(data, formRes, m.mul(gs.ctm), depth+1)
+		case "BI":
+			skipInlineImage(l)
+		}
+		operands = operands[:0]
+	}
+}
+
+// unitSquare returns the bounding box of the unit square under m, which is
+// where an image is painted.
+func unitSquare(m pdfMatrix) [4]float64 {
+	box := [4]float64{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+	for _, c := range [][2]float64{{0, 0}, {1, 0}, {0, 1}, {1, 1}} {
+		x := c[0]*m[0] + c[1]*m[2] + m[4]
+		y := c[0]*m[1] + c[1]*m[3] + m[5]
+		box[0], box[1] = math.Min(box[0], x), math.Min(box[1], y)
+		box[2], box[3] = math.Max(box[2], x), math.Max(box[3], y)
+	}
+	return box
+}
+
+// skipInlineImage moves past inline image data ("BI ... ID <data> EI").
+func skipInlineImage(l *pdfLexer) {
+	for {
+		tok, err := l.token()
+		if err != nil || tok == pdfKeyword("ID") {
+			break
+		}
+	}
+	l.pos++ // single whitespace after ID
+	for l.pos+2 < len(l.data) {
+		if l.data[l.pos] == 'E' && l.data[l.pos+1] == 'I' &&
+			isPDFSpace(l.data[l.pos-1]) && (isPDFSpace(l.data[l.pos+2]) || isPDFDelim(l.data[l.pos+2])) {
+			l.pos += 2
+			return
+		}
+		l.pos++
+	}
+	l.pos = len(l.data)
+}
+
+// pdfFont maps character codes to text and advance widths.
+type pdfFont struct {
+	toUnicode    *pdfCMap
+	encoding     *[256]string // simple fonts
+	composite    bool
+	ucs2         bool // composite font whose codes are UTF-16
+	widths       map[int]float64
+	defaultWidth float64
+	widthScale   float64 // glyph space → text space
+}
+
+// pdfCode is a decoded character code. width is in text space units per
+// unit of font size.
+type pdfCode struct {
+	text  string
+	width float64
+	space bool // single-byte code 32, which word spacing applies to
+}
+
+func (in *pdfInterpreter) font(ref interface{}) *pdfFont {
+	if font, ok := in.fonts[ref]; ok {
+		return font
+	}
+	font := loadPDFFont(in.file, in.file.dict(ref))
+	in.fonts[ref] = font
+	return font
+}
+
+func loadPDFFont(f *pdfFile, dict pdfDict) *pdfFont {
+	font := &pdfFont{widths: make(map[int]float64), defaultWidth: 500, widthScale: 0.001}
+	if dict == nil {
+		return font
+	}
+
+	if s, ok := f.resolve(dict["ToUnicode"]).(*pdfStream); ok {
+		if data, err := decodePDFStream(s); err == nil {
+			font.toUnicode = parsePDFCMap(data)
+		}
+	}
+
+	if dict["Subtype"] == pdfName("Type0") {
+		font.composite = true
+		if enc, ok := dict["Encoding"].(pdfName); ok {
+			font.ucs2 = strings.Contains(string(enc), "UCS2") || strings.Contains(string(enc), "UTF16")
+		}
+		font.defaultWidth = 1000
+		if desc := f.array(dict["DescendantFonts"]); len(desc) > 0 {
+			d := f.dict(desc[0])
+			font.defaultWidth = pdfNumber(f.resolve(d["DW"]), 1000)
+			w := f.array(d["W"])
+			for i := 0; i < len(w); {
+				first := pdfInt(f.resolve(w[i]))
+				if i+1 < len(w) {
+					if list := f.array(w[i+1]); list != nil {
+						for j, v := range list {
+							font.widths[first+j] = pdfNumber(f.resolve(v), font.defaultWidth)
+						}
+						i += 2
+						continue
+					}
+				}
+				if i+2 < len(w) {
+					last := pdfInt(f.resolve(w[i+1]))
+					width := pdfNumber(f.resolve(w[i+2]), font.defaultWidth)
+					for c := first; c <= last && c-first < 65536; c++ {
+						font.widths[c] = width
+					}
+				}
+				i += 3
+			}
+		}
+		return font
+	}
+
+	// Simple font: one byte per code.
+	if base, ok := dict["BaseFont"].(pdfName); ok && strings.Contains(string(base), "Courier") {
+		font.defaultWidth = 600
+	}
+	if desc := f.dict(dict["FontDescriptor"]); desc != nil {
+		if mw := pdfNumber(f.resolve(desc["MissingWidth"]), 0); mw > 0 {
+			font.defaultWidth = mw
+		}
+	}
+	if dict["Subtype"] == pdfName("Type3") {
+		if m := f.array(dict["FontMatrix"]); len(m) == 6 {
+			font.widthScale = pdfNumber(f.resolve(m[0]), 0.001)
+		}
+	}
+	firstChar := pdfInt(f.resolve(dict["FirstChar"]))
+	for i, v := range f.array(dict["Widths"]) {
+		font.widths[firstChar+i] = pdfNumber(f.resolve(v), font.defaultWidth)
+	}
+
+	enc := pdfStandardEncoding
+	switch e := f.resolve(dict["Encoding"]).(type) {
+	case pdfName:
+		enc = pdfNamedEncoding(e)
+	case pdfDict:
+		if base, ok := e["BaseEncoding"].(pdfName); ok {
+			enc = pdfNamedEncoding(base)
+		}
+		code := 0
+		for _, item := range f.array(e["Differences"]) {
+			switch v := f.resolve(item).(type) {
+			case float64:
+				code = int(v)
+			case pdfName:
+				if code >= 0 && code < 256 {
+					enc[code] = glyphNameText(string(v))
+				}
+				code++
+			}
+		}
+	}
+	font.encoding = &enc
+	return font
+}
+
+func (font *pdfFont) decode(s []byte) []pdfCode {
+	var codes []pdfCode
+	for i := 0; i < len(s); {
+		n := 1
+		if font.composite {
+			n = 2
+		}
+		if font.toUnicode != nil {
+			n = font.toUnicode.codeLength(s[i:], n)
+		}
+		if i+n > len(s) {
+			n = len(s) - i
+		}
+		raw := s[i : i+n]
+		i += n
+
+		code := 0
+		for _, b := range raw {
+			code = code<<8 | int(b)
+		}
+
+		var text string
+		var ok bool
+		if font.toUnicode != nil {
+			text, ok = font.toUnicode.chars[string(raw)]
+		}
+		if !ok {
+			switch {
+			case font.composite && font.ucs2:
+				text = string(utf16.Decode([]uint16{uint16(code)}))
+			case font.composite:
+				text = "" // CID without a Unicode mapping
+			case font.encoding != nil && code < 256:
+				text = font.encoding[code]
+			}
+		}
+
+		width, ok := font.widths[code]
+		if !ok {
+			width = font.defaultWidth
+		}
+		codes = append(codes, pdfCode{
+			text:  text,
+			width: width * font.widthScale,
+			space: n == 1 && code == 32,
+		})
+	}
+	return codes
+}
+
+// pdfCMap is a parsed ToUnicode CMap.
+type pdfCMap struct {
+	spaces []pdfCodeSpace
+	chars  map[string]string // raw code bytes → text
+}
+
+type pdfCodeSpace struct {
+	lo, hi []byte
+}
+
+// codeLength returns the byte length of the code at the start of s, using
+// the codespace ranges, or fallback if none match.
+func (c *pdfCMap) codeLength(s []byte, fallback int) int {
+	for _, cs := range c.spaces {
+		n := len(cs.lo)
+		if n == 0 || n > len(s) {
+			continue
+		}
+		match := true
+		for i := 0; i < n; i++ {
+			if s[i] < cs.lo[i] || s[i] > cs.hi[i] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return n
+		}
+	}
+	return fallback
+}
+
+// parsePDFCMap reads codespace ranges and bfchar/bfrange mappings.
+func parsePDFCMap(data []byte) *pdfCMap {
+	cm := &pdfCMap{chars: make(map[string]string)}
+	l := &pdfLexer{data: data}
+	var operands []interface{}
+	mode := ""
+	for {
+		tok, err := l.token()
+		if err != nil {
+			break
+		}
+		kw, isKw := tok.(pdfKeyword)
+		if !isKw || kw == "[" {
+			obj, _ := l.complete(tok)
+			operands = append(operands, obj)
+			continue
+		}
+		switch kw {
+		case "begincodespacerange", "beginbfchar", "beginbfrange":
+			mode = string(kw)
+		case "endcodespacerange":
+			for i := 0; i+1 < len(operands); i += 2 {
+				lo, _ := operands[i].(pdfString)
+				hi, _ := operands[i+1].(pdfString)
+				if len(lo) == len(hi) && len(lo) > 0 {
+					cm.spaces = append(cm.spaces, pdfCodeSpace{lo: []byte(lo), hi: []byte(hi)})
+				}
+			}
+			mode = ""
+		case "endbfchar":
+			for i := 0; i+1 < len(operands); i += 2 {
+				src, _ := operands[i].(pdfString)
+				dst, _ := operands[i+1].(pdfString)
+				cm.chars[string(src)] = utf16BEText([]byte(dst))
+			}
+			mode = ""
+		case "endbfrange":
+			for i := 0; i+2 < len(operands); i += 3 {
+				lo, _ := operands[i].(pdfString)
+				hi, _ := operands[i+1].(pdfString)
+				cm.addRange([]byte(lo), []byte(hi), operands[i+2])
+			}
+			mode = ""
+		}
+		if mode == "" || kw == pdfKeyword(mode) {
+			operands = operands[:0]
+		}
+	}
+	return cm
+}
+
+// addRange expands a bfrange entry. The destination is either a start
+// string whose last byte increments, or an array of strings.
+func (cm *pdfCMap) addRange(lo, hi []byte, dst interface{}) {
+	if len(lo) == 0 || len(lo) != len(hi) {
+		return
+	}
+	start, end := 0, 0
+	for i := range lo {
+		start = start<<8 | int(lo[i])
+		end = end<<8 | int(hi[i])
+	}
+	if end < start || end-start > 65535 {
+		return
+	}
+	for code := start; code <= end; code++ {
+		key := make([]byte, len(lo))
+		for i, c := len(lo)-1, code; i >= 0; i, c = i-1, c>>8 {
+			key[i] = byte(c)
+		}
+		switch d := dst.(type) {
+		case pdfString:
+			b := []byte(d)
+			if len(b) == 0 {
+				continue
+			}
+			b = append([]byte(nil), b...)
+			// Increment the final UTF-16 code unit.
+			v := int(b[len(b)-1]) + code - start
+			b[len(b)-1] = byte(v)
+			if len(b) >= 2 {
+				b[len(b)-2] += byte(v >> 8)
+			}
+			cm.chars[string(key)] = utf16BEText(b)
+		case pdfArray:
+			if i := code - start; i < len(d) {
+				if s, ok := d[i].(pdfString); ok {
+					cm.chars[string(key)] = utf16BEText([]byte(s))
+				}
+			}
+		}
+	}
+}
+
+func utf16BEText(b []byte) string {
+	if len(b) == 1 {
+		return string(rune(b[0]))
+	}
+	units := make([]uint16, 0, len(b)/2)
+	for i := 0; i+1 < len(b); i += 2 {
+		units = append(units, uint16(b[i])<<8|uint16(b[i+1]))
+	}
+	return expandLigatures(string(utf16.Decode(units)))
+}
+
+// expandLigatures replaces presentation-form ligatures with plain letters
+// so extracted text stays searchable.
+func expandLigatures(s string) string {
+	if !strings.ContainsAny(s, "ﬀﬁﬂﬃﬄ") {
+		return s
+	}
+	return pdfLigatures.Replace(s)
+}
+
+var pdfLigatures = strings.NewReplacer("ﬀ", "ff", "ﬁ", "fi", "ﬂ", "fl", "ﬃ", "ffi", "ﬄ", "ffl")
+
+// pdfLine is a row of glyphs sharing a baseline.
+type pdfLine struct {
+	y, size float64
+	glyphs  []pdfGlyph
+}
+
+// lines groups a page's glyphs into lines, top to bottom, each sorted left
+// to right. Duplicate glyphs drawn for fake bold are dropped.
+func (p *pdfPage) lines() []pdfLine {
+	glyphs := append([]pdfGlyph(nil), p.glyphs...)
+	sort.SliceStable(glyphs, func(i, j int) bool { return glyphs[i].y > glyphs[j].y })
+
+	var lines []pdfLine
+	for _, g := range glyphs {
+		n := len(lines)
+		if n > 0 && math.Abs(lines[n-1].y-g.y) <= math.Max(lines[n-1].size, g.size)*0.4 {
+			lines[n-1].glyphs = append(lines[n-1].glyphs, g)
+			lines[n-1].size = math.Max(lines[n-1].size, g.size)
+			continue
+		}
+		lines = append(lines, pdfLine{y: g.y, size: g.size, glyphs: []pdfGlyph{g}})
+	}
+
+	for i := range lines {
+		gs := lines[i].glyphs
+		sort.SliceStable(gs, func(a, b int) bool { return gs[a].x < gs[b].x })
+		kept := gs[:0]
+		for _, g := range gs {
+			if k := len(kept); k > 0 && kept[k-1].text == g.text && math.Abs(kept[k-1].x-g.x) < g.size*0.1 {
+				continue
+			}
+			kept = append(kept, g)
+		}
+		lines[i].glyphs = kept
+	}
+	return lines
+}
+
+// text joins a line's glyphs, inserting a space where the gap between
+// glyphs is wider than a fraction of the font size.
+func (l pdfLine) text() string {
+	var b strings.Builder
+	for i, g := range l.glyphs {
+		if i > 0 {
+			prev := l.glyphs[i-1]
+			gap := g.x - (prev.x + prev.w)
+			if gap > g.size*0.15 && !strings.HasSuffix(prev.text, " ") && !strings.HasPrefix(g.text, " ") {
+				b.WriteByte(' ')
+			}
+		}
+		b.WriteString(g.text)
+	}
+	return strings.TrimRight(b.String(), " ")
+}
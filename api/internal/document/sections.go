@@ -0,0 +1,229 @@
+package document
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// SectionHeading is one section boundary found by a SectionGrammar.
+type SectionHeading struct {
+	Offset int    // byte offset of the heading line in the content
+	ID     string // e.g., "§5", "Section 3", "3.1", "HÄNDELSEFÖRLOPP"
+	Name   string // heading text after the ID, empty if the ID is the whole heading
+}
+
+// SectionGrammar recognises one family of section markers (protocol paragraphs,
+// report headings, legal clauses, ...). Grammars are consulted in registration
+// order by DetectSectionGrammar; the first one that claims a document wins.
+type SectionGrammar interface {
+	Name() string
+	// Detect reports whether the content is structured by this grammar.
+	Detect(content string) bool
+	// Headings returns the section headings in document order.
+	Headings(content string) []SectionHeading
+}
+
+var sectionGrammars []SectionGrammar
+
+// RegisterSectionGrammar adds a grammar to the registry. Grammars registered
+// later are consulted after the built-in ones.
+func RegisterSectionGrammar(g SectionGrammar) {
+	sectionGrammars = append(sectionGrammars, g)
+}
+
+// SectionGrammars returns the registered grammars in detection order.
+func SectionGrammars() []SectionGrammar {
+	return append([]SectionGrammar(nil), sectionGrammars...)
+}
+
+// DetectSectionGrammar returns the first registered grammar that recognises
+// the content, or nil if the document has no known section structure.
+func DetectSectionGrammar(content string) SectionGrammar {
+	for _, g := range sectionGrammars {
+		if g.Detect(content) {
+			return g
+		}
+	}
+	return nil
+}
+
+// SectionGrammarByName looks up a registered grammar by name.
+func SectionGrammarByName(name string) SectionGrammar {
+	for _, g := range sectionGrammars {
+		if g.Name() == name {
+			return g
+		}
+	}
+	return nil
+}
+
+func init() {
+	RegisterSectionGrammar(swedishProtocolGrammar)
+	RegisterSectionGrammar(swedishPoliceReportGrammar)
+	RegisterSectionGrammar(markdownHeadingGrammar)
+	RegisterSectionGrammar(englishLegalGrammar)
+}
+
+// PatternGrammar is a line-oriented SectionGrammar driven by a regular
+// expression. The pattern must be multi-line anchored and may use the named
+// groups "id" and "name"; if "id" is absent the whole trimmed line is the ID.
+type PatternGrammar struct {
+	GrammarName string
+	Pattern     *regexp.Regexp
+	MinHeadings int            // headings required before Detect succeeds (default 2)
+	Requires    *regexp.Regexp // optional cue that must also appear in the content
+	NormalizeID func(id string) string
+}
+
+// Name implements SectionGrammar.
+func (g *PatternGrammar) Name() string { return g.GrammarName }
+
+// Detect implements SectionGrammar.
+func (g *PatternGrammar) Detect(content string) bool {
+	if g.Requires != nil && !g.Requires.MatchString(content) {
+		return false
+	}
+	min := g.MinHeadings
+	if min == 0 {
+		min = 2
+	}
+	return len(g.Pattern.FindAllStringIndex(content, min)) >= min
+}
+
+// Headings implements SectionGrammar.
+func (g *PatternGrammar) Headings(content string) []SectionHeading {
+	var headings []SectionHeading
+	for _, m := range g.Pattern.FindAllStringSubmatchIndex(content, -1) {
+		heading := SectionHeading{Offset: m[0]}
+		if id, ok := g.group(content, m, "id"); ok {
+			heading.ID = id
+		} else {
+			heading.ID = strings.TrimSpace(content[m[0]:m[1]])
+		}
+		if name, ok := g.group(content, m, "name"); ok {
+			heading.Name = strings.TrimLeft(name, ".:—–- ")
+		}
+		if g.NormalizeID != nil {
+			heading.ID = g.NormalizeID(heading.ID)
+		}
+		headings = append(headings, heading)
+	}
+	return headings
+}
+
+// group returns the first participating subexpression with the given name.
+// Patterns may reuse a name across alternatives.
+func (g *PatternGrammar) group(content string, m []int, name string) (string, bool) {
+	for i, n := range g.Pattern.SubexpNames() {
+		if n == name && m[2*i] >= 0 {
+			return strings.TrimSpace(content[m[2*i]:m[2*i+1]]), true
+		}
+	}
+	return "", false
+}
+
+// swedishProtocolGrammar matches board/meeting protocols: "§5 Titel", "§ 5.",
+// "Ärende 3: Titel", "Punkt 4 Titel" and the headings "Protokoll", "Beslut"
+// and "Ärende" on a line of their own. Numbered att-satser and years in
+// running text ("2023. Inga anmärkningar") are not headings.
+var swedishProtocolGrammar = &protocolGrammar{&PatternGrammar{
+	GrammarName: "swedish_protocol",
+	Pattern:     regexp.MustCompile(`(?m)^[ \t]*(?:(?P<id>§[ \t]*\d{1,3}[a-z]?|(?:Ärende|Punkt)[ \t]+\d{1,3})[ \t]*[.:]?(?:[ \t]+(?P<name>[^\n]*))?|(?P<id>(?i:protokoll|beslut|ärende))[ \t]*:?[ \t]*)$`),
+	MinHeadings: 2,
+	NormalizeID: func(id string) string {
+		if strings.HasPrefix(id, "§") {
+			return "§" + strings.TrimSpace(strings.TrimPrefix(id, "§"))
+		}
+		id = strings.Join(strings.Fields(id), " ")
+		if !strings.ContainsAny(id, "0123456789") {
+			// Keyword headings: "BESLUT" and "beslut" are "Beslut"
+			r := []rune(strings.ToLower(id))
+			return strings.ToUpper(string(r[0])) + string(r[1:])
+		}
+		return id
+	},
+}}
+
+// protocolGrammar keeps keyword headings inside a numbered paragraph in that
+// paragraph: a "Beslut" line under "§5" starts a new section with ID "§5"
+// and name "Beslut", so everything in the paragraph maps to "§5".
+type protocolGrammar struct {
+	*PatternGrammar
+}
+
+// Headings implements SectionGrammar.
+func (g *protocolGrammar) Headings(content string) []SectionHeading {
+	headings := g.PatternGrammar.Headings(content)
+	var paragraph string
+	for i, h := range headings {
+		if strings.ContainsAny(h.ID, "0123456789") {
+			paragraph = h.ID
+			continue
+		}
+		if paragraph != "" {
+			headings[i] = SectionHeading{Offset: h.Offset, ID: paragraph, Name: h.ID}
+		}
+	}
+	return headings
+}
+
+// swedishPoliceReportGrammar matches Swedish police and forensic reports:
+// all-caps headings ("HÄNDELSEFÖRLOPP", "OBSERVATIONER:") and numbered
+// device/exhibit blocks ("Kamera 2 (Ramp ...):"). Only applies to documents
+// carrying a case reference field, which keeps it away from novels that use
+// all-caps chapter titles.
+var swedishPoliceReportGrammar = &PatternGrammar{
+	GrammarName: "swedish_police_report",
+	Pattern:     regexp.MustCompile(`(?m)^(?:(?P<id>[A-ZÄÖÅÉ][A-ZÄÖÅÉ \-—]{3,}[A-ZÄÖÅÉ]):?[ \t]*$|(?P<id>(?:Kamera|Bilaga|Bild|Fråga|Beslag)[ \t]+\d+)\b[^\n]*:[ \t]*$)`),
+	Requires:    regexp.MustCompile(`(?m)^(?:Ärende|Ärendenr|Diarienr|K-nummer)[ \t]*:`),
+	MinHeadings: 2,
+}
+
+// markdownHeadingGrammar matches ATX Markdown headings ("## Payments"). The
+// heading text is the section ID.
+var markdownHeadingGrammar = &PatternGrammar{
+	GrammarName: "markdown_headings",
+	Pattern:     regexp.MustCompile(`(?m)^#{1,6}[ \t]+(?P<id>[^\n#]+?)[ \t#]*$`),
+	MinHeadings: 2,
+}
+
+// englishLegalGrammar matches contracts, minutes and reports: "Section 5",
+// "ARTICLE 3 — Term", "Clause 4.2" and numbered headings ("3. FEES",
+// "2.1 Payment terms").
+var englishLegalGrammar = &PatternGrammar{
+	GrammarName: "english_legal",
+	Pattern:     regexp.MustCompile(`(?m)^[ \t]*(?P<id>(?i:section|article|clause)[ \t]+\d{1,3}(?:\.\d{1,3})*|\d{1,2}(?:\.\d{1,2})*\.?)[ \t]*[.:—–-]?[ \t]+(?P<name>[A-ZÄÖÅ][^\n]{0,80})$`),
+	MinHeadings: 2,
+	NormalizeID: func(id string) string {
+		return strings.TrimSuffix(strings.Join(strings.Fields(id), " "), ".")
+	},
+}
+
+// SectionIndex maps character offsets in a document to the section that
+// contains them. It is used to attribute excerpts to sections after the fact.
+type SectionIndex struct {
+	headings []SectionHeading
+}
+
+// BuildSectionIndex detects the section grammar of content and indexes its
+// headings. Returns an empty index if no grammar applies.
+func BuildSectionIndex(content string) *SectionIndex {
+	g := DetectSectionGrammar(content)
+	if g == nil {
+		return &SectionIndex{}
+	}
+	headings := g.Headings(content)
+	sort.SliceStable(headings, func(i, j int) bool { return headings[i].Offset < headings[j].Offset })
+	return &SectionIndex{headings: headings}
+}
+
+// At returns the heading of the section containing offset, or false if the
+// offset precedes the first heading.
+func (idx *SectionIndex) At(offset int) (SectionHeading, bool) {
+	i := sort.Search(len(idx.headings), func(i int) bool { return idx.headings[i].Offset > offset })
+	if i == 0 {
+		return SectionHeading{}, false
+	}
+	return idx.headings[i-1], true
+}
@@ -0,0 +1,507 @@
+package document
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// rowsPerChunk caps the number of table rows rendered into one chunk.
+const rowsPerChunk = 100
+
+// Table is one sheet of a tabular source. The first non-empty row is the
+// header; Rows holds the data rows below it.
+type Table struct {
+	Sheet  string // sheet name; empty for CSV
+	Header []string
+	Rows   []TableRow
+}
+
+// TableRow is a data row with its 1-based row number in the sheet, so
+// provenance can point back at the exact spreadsheet cell.
+type TableRow struct {
+	Number int
+	Cells  []string
+}
+
+// Cell returns the value in column i, or "" if the row is short.
+func (r TableRow) Cell(i int) string {
+	if i < 0 || i >= len(r.Cells) {
+		return ""
+	}
+	return r.Cells[i]
+}
+
+// Column returns the index of the named header column (case-insensitive),
+// or -1.
+func (t *Table) Column(name string) int {
+	name = strings.TrimSpace(name)
+	for i, h := range t.Header {
+		if strings.EqualFold(strings.TrimSpace(h), name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// IsTabularType reports whether a source file type is parsed as tables.
+func IsTabularType(fileType string) bool {
+	return fileType == "csv" || fileType == "xlsx"
+}
+
+// ParseTables reads a CSV or XLSX file into tables.
+func ParseTables(filePath, fileType string) ([]*Table, error) {
+	switch fileType {
+	case "csv":
+		file, err := os.Open(filePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open file: %w", err)
+		}
+		defer file.Close()
+		t, err := ParseCSV(file)
+		if err != nil {
+			return nil, err
+		}
+		return []*Table{t}, nil
+	case "xlsx":
+		return ParseXLSX(filePath)
+	}
+	return nil, fmt.Errorf("not a tabular file type: %s", fileType)
+}
+
+// ParseTablesWithChunks reads a CSV or XLSX file and renders its rows as
+// chunks of Markdown tables.
+func ParseTablesWithChunks(filePath, fileType string) ([]Chunk, []*Table, error) {
+	tables, err := ParseTables(filePath, fileType)
+	if err != nil {
+		return nil, nil, err
+	}
+	return TableChunks(tables), tables, nil
+}
+
+// TableChunks renders tables as pipe-table chunks of at most rowsPerChunk
+// rows, repeating the header in each. Chunk metadata records the sheet and
+// row range so the reader can be linked back to the spreadsheet.
+func TableChunks(tables []*Table) []Chunk {
+	var chunks []Chunk
+	for _, t := range tables {
+		if len(t.Header) == 0 {
+			continue
+		}
+		title := t.Sheet
+		if title == "" {
+			title = "Table"
+		}
+		for start := 0; start < len(t.Rows) || start == 0; start += rowsPerChunk {
+			end := start + rowsPerChunk
+			if end > len(t.Rows) {
+				end = len(t.Rows)
+			}
+			rows := [][]string{t.Header}
+			for _, r := range t.Rows[start:end] {
+				rows = append(rows, r.Cells)
+			}
+			var b strings.Builder
+			writePipeTable(&b, rows)
+
+			meta := map[string]interface{}{"columns": t.Header}
+			if t.Sheet != "" {
+				meta["sheet"] = t.Sheet
+			}
+			if end > start {
+				meta["row_start"] = t.Rows[start].Number
+				meta["row_end"] = t.Rows[end-1].Number
+			}
+
+			chunks = append(chunks, Chunk{
+				ChunkIndex:        len(chunks),
+				Content:           b.String(),
+				ChapterTitle:      title,
+				SectionID:         t.Sheet,
+				SectionName:       t.Sheet,
+				Metadata:          meta,
+				NarrativePosition: len(chunks),
+			})
+			if end >= len(t.Rows) {
+				break
+			}
+		}
+	}
+	return chunks
+}
+
+// ParseCSV reads a delimited text table. The delimiter (comma, semicolon or
+// tab) is sniffed from the first line; Swedish spreadsheet exports use
+// semicolons and are often Windows-1252 rather than UTF-8.
+func ParseCSV(r io.Reader) (*Table, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV: %w", err)
+	}
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	text := string(data)
+	if !utf8.Valid(data) {
+		text = decodeCharset(data, "windows-1252")
+	}
+
+	cr := csv.NewReader(strings.NewReader(text))
+	cr.Comma = sniffDelimiter(text)
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+
+	var records [][]string
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse CSV: %w", err)
+		}
+		records = append(records, rec)
+	}
+
+	return newTable("", records), nil
+}
+
+// sniffDelimiter picks the most frequent candidate delimiter outside quotes
+// on the first line.
+func sniffDelimiter(text string) rune {
+	line, _, _ := strings.Cut(text, "\n")
+	counts := map[rune]int{}
+	inQuotes := false
+	for _, c := range line {
+		switch {
+		case c == '"':
+			inQuotes = !inQuotes
+		case !inQuotes && (c == ',' || c == ';' || c == '\t'):
+			counts[c]++
+		}
+	}
+	best := ','
+	for _, c := range []rune{';', '\t'} {
+		if counts[c] > counts[best] {
+			best = c
+		}
+	}
+	return best
+}
+
+// newTable builds a table from raw rows: leading empty rows are skipped, the
+// first non-empty row becomes the header and blank data rows are dropped.
+// Row numbers are the 1-based positions in records.
+func newTable(sheet string, records [][]string) *Table {
+	t := &Table{Sheet: sheet}
+	for i, rec := range records {
+		cells := make([]string, len(rec))
+		empty := true
+		for j, c := range rec {
+			cells[j] = strings.TrimSpace(c)
+			if cells[j] != "" {
+				empty = false
+			}
+		}
+		if empty {
+			continue
+		}
+		if t.Header == nil {
+			t.Header = cells
+			continue
+		}
+		t.Rows = append(t.Rows, TableRow{Number: i + 1, Cells: cells})
+	}
+	for i, h := range t.Header {
+		if h == "" {
+			t.Header[i] = columnName(i)
+		}
+	}
+	return t
+}
+
+// ParseXLSX reads every worksheet of an Excel workbook. Shared strings,
+// inline strings, booleans and cached formula results are read as text;
+// numbers in date-formatted cells are converted to ISO dates.
+func ParseXLSX(filePath string) ([]*Table, error) {
+	zr, err := zip.OpenReader(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open XLSX: %w", err)
+	}
+	defer zr.Close()
+
+	parts := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		parts[f.Name] = f
+	}
+
+	var workbook struct {
+		Sheets []struct {
+			Name string `xml:"name,attr"`
+			RID  string `xml:"id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := decodePart(parts, "xl/workbook.xml", &workbook); err != nil {
+		return nil, err
+	}
+
+	var rels struct {
+		Relationships []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := decodePart(parts, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return nil, err
+	}
+	targets := make(map[string]string, len(rels.Relationships))
+	for _, rel := range rels.Relationships {
+		target := rel.Target
+		if strings.HasPrefix(target, "/") {
+			target = strings.TrimPrefix(target, "/")
+		} else {
+			target = path.Join("xl", target)
+		}
+		targets[rel.ID] = target
+	}
+
+	shared, err := readSharedStrings(parts)
+	if err != nil {
+		return nil, err
+	}
+	dateStyles, err := readDateStyles(parts)
+	if err != nil {
+		return nil, err
+	}
+
+	var tables []*Table
+	for _, sheet := range workbook.Sheets {
+		target, ok := targets[sheet.RID]
+		if !ok {
+			continue
+		}
+		records, err := readWorksheet(parts, target, shared, dateStyles)
+		if err != nil {
+			return nil, fmt.Errorf("sheet %q: %w", sheet.Name, err)
+		}
+		t := newTable(sheet.Name, records)
+		if len(t.Header) > 0 {
+			tables = append(tables, t)
+		}
+	}
+
+	if len(tables) == 0 {
+		return nil, fmt.Errorf("workbook has no non-empty sheets")
+	}
+	return tables, nil
+}
+
+// decodePart unmarshals a required XML part of an OOXML package.
+func decodePart(parts map[string]*zip.File, name string, v interface{}) error {
+	f, ok := parts[name]
+	if !ok {
+		return fmt.Errorf("missing %s", name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", name, err)
+	}
+	defer rc.Close()
+	if err := xml.NewDecoder(bufio.NewReader(rc)).Decode(v); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", name, err)
+	}
+	return nil
+}
+
+// xlsxText is a string item that is either plain text or rich-text runs.
+type xlsxText struct {
+	T    string `xml:"t"`
+	Runs []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (x xlsxText) String() string {
+	var b strings.Builder
+	b.WriteString(x.T)
+	for _, r := range x.Runs {
+		b.WriteString(r.T)
+	}
+	return b.String()
+}
+
+func readSharedStrings(parts map[string]*zip.File) ([]string, error) {
+	if _, ok := parts["xl/sharedStrings.xml"]; !ok {
+		return nil, nil
+	}
+	var sst struct {
+		Items []xlsxText `xml:"si"`
+	}
+	if err := decodePart(parts, "xl/sharedStrings.xml", &sst); err != nil {
+		return nil, err
+	}
+	out := make([]string, len(sst.Items))
+	for i, item := range sst.Items {
+		out[i] = item.String()
+	}
+	return out, nil
+}
+
+var (
+	// xlsxDateFormat matches custom number formats that render dates.
+	xlsxDateFormat = regexp.MustCompile(`[dDyY]`)
+	// xlsxFormatLiteral matches quoted literals and [colour]/[locale]
+	// sections, which are ignored when looking for date tokens.
+	xlsxFormatLiteral = regexp.MustCompile(`"[^"]*"|\[[^\]]*\]`)
+)
+
+// readDateStyles returns which cell style indexes use a date number format.
+func readDateStyles(parts map[string]*zip.File) (map[int]bool, error) {
+	if _, ok := parts["xl/styles.xml"]; !ok {
+		return nil, nil
+	}
+	var styles struct {
+		NumFmts []struct {
+			ID   int    `xml:"numFmtId,attr"`
+			Code string `xml:"formatCode,attr"`
+		} `xml:"numFmts>numFmt"`
+		CellXfs []struct {
+			NumFmtID int `xml:"numFmtId,attr"`
+		} `xml:"cellXfs>xf"`
+	}
+	if err := decodePart(parts, "xl/styles.xml", &styles); err != nil {
+		return nil, err
+	}
+
+	dateFmts := make(map[int]bool)
+	for id := 14; id <= 22; id++ {
+		dateFmts[id] = true
+	}
+	for _, f := range styles.NumFmts {
+		code := xlsxFormatLiteral.ReplaceAllString(f.Code, "")
+		dateFmts[f.ID] = xlsxDateFormat.MatchString(code)
+	}
+
+	out := make(map[int]bool)
+	for i, xf := range styles.CellXfs {
+		if dateFmts[xf.NumFmtID] {
+			out[i] = true
+		}
+	}
+	return out, nil
+}
+
+type xlsxCell struct {
+	Ref    string   `xml:"r,attr"`
+	Type   string   `xml:"t,attr"`
+	Style  int      `xml:"s,attr"`
+	Value  string   `xml:"v"`
+	Inline xlsxText `xml:"is"`
+}
+
+// readWorksheet returns a sheet's cell values as rows indexed by their row
+// numbers (row 1 is records[0]).
+func readWorksheet(parts map[string]*zip.File, name string, shared []string, dateStyles map[int]bool) ([][]string, error) {
+	var ws struct {
+		Rows []struct {
+			Number int        `xml:"r,attr"`
+			Cells  []xlsxCell `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := decodePart(parts, name, &ws); err != nil {
+		return nil, err
+	}
+
+	var records [][]string
+	for i, row := range ws.Rows {
+		num := row.Number
+		if num == 0 {
+			num = i + 1
+		}
+		for len(records) < num {
+			records = append(records, nil)
+		}
+		var cells []string
+		for j, c := range row.Cells {
+			col := j
+			if c.Ref != "" {
+				col = columnIndex(c.Ref)
+			}
+			for len(cells) <= col {
+				cells = append(cells, "")
+			}
+			cells[col] = cellValue(c, shared, dateStyles)
+		}
+		records[num-1] = cells
+	}
+	return records, nil
+}
+
+func cellValue(c xlsxCell, shared []string, dateStyles map[int]bool) string {
+	switch c.Type {
+	case "s":
+		idx, err := strconv.Atoi(strings.TrimSpace(c.Value))
+		if err != nil || idx < 0 || idx >= len(shared) {
+			return ""
+		}
+		return shared[idx]
+	case "inlineStr":
+		return c.Inline.String()
+	case "b":
+		if c.Value == "1" {
+			return "TRUE"
+		}
+		return "FALSE"
+	case "str", "e":
+		return c.Value
+	}
+	if dateStyles[c.Style] {
+		if f, err := strconv.ParseFloat(c.Value, 64); err == nil {
+			return excelDate(f)
+		}
+	}
+	return c.Value
+}
+
+// excelDate converts a 1900-system serial date to ISO 8601.
+func excelDate(serial float64) string {
+	base := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	days := math.Floor(serial)
+	secs := math.Round((serial - days) * 86400)
+	t := base.AddDate(0, 0, int(days)).Add(time.Duration(secs) * time.Second)
+	if secs == 0 {
+		return t.Format("2006-01-02")
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
+
+// columnIndex converts a cell reference ("C12") to a 0-based column index.
+func columnIndex(ref string) int {
+	col := 0
+	for _, c := range ref {
+		if c < 'A' || c > 'Z' {
+			break
+		}
+		col = col*26 + int(c-'A'+1)
+	}
+	return col - 1
+}
+
+// columnName converts a 0-based column index to its letter name ("A", "AB").
+func columnName(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
@@ -0,0 +1,123 @@
+package document
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseCSVAndInferMapping(t *testing.T) {
+	// Windows-1252 export with semicolons and Swedish number formatting
+	csv := "Fakturanr;Fakturadatum;Leverant\xf6r;Avser;Belopp\r\n" +
+		"1001;2023-03-01;Sundsvalls M\xe5leri AB;Fasadm\xe5lning;12 500,00 kr\r\n" +
+		";;;;\r\n" +
+		"1002;2023-03-15;Sundsvalls M\xe5leri AB;St\xe4llning;(1 200)\r\n"
+
+	table, err := ParseCSV(strings.NewReader(csv))
+	if err != nil {
+		t.Fatalf("ParseCSV() error = %v", err)
+	}
+	if len(table.Header) != 5 || table.Header[2] != "Leverantör" {
+		t.Fatalf("Header = %q", table.Header)
+	}
+	if len(table.Rows) != 2 || table.Rows[1].Number != 4 {
+		t.Fatalf("Rows = %+v, want 2 rows with the second on line 4", table.Rows)
+	}
+
+	mapping, err := InferTableMapping(table)
+	if err != nil {
+		t.Fatalf("InferTableMapping() error = %v", err)
+	}
+	if err := mapping.Validate(); err != nil {
+		t.Fatalf("inferred mapping invalid: %v", err)
+	}
+	if len(mapping.Edges) != 1 || mapping.Edges[0].EdgeType != "has_value" {
+		t.Fatalf("Edges = %+v, want one has_value edge", mapping.Edges)
+	}
+
+	rows, err := mapping.Apply(table)
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("Apply() = %d rows, want 2", len(rows))
+	}
+	var value MappedNode
+	for _, n := range rows[0].Nodes {
+		if n.NodeType == "value" {
+			value = n
+		}
+	}
+	if value.Properties["value"] != 12500.0 || value.Properties["unit"] != "kr" {
+		t.Errorf("value properties = %v, want 12500 kr", value.Properties)
+	}
+	if value.Properties["fakturanr"] != "1001" || value.Properties["avser"] != "Fasadmålning" {
+		t.Errorf("row context = %v, want invoice number and description", value.Properties)
+	}
+	if value.Time != "2023-03-01" {
+		t.Errorf("Time = %q, want 2023-03-01", value.Time)
+	}
+	if n, _, _ := ParseTableNumber("(1 200)"); n != -1200 {
+		t.Errorf("ParseTableNumber((1 200)) = %v, want -1200", n)
+	}
+
+	chunks := TableChunks([]*Table{table})
+	if len(chunks) != 1 || chunks[0].Metadata["row_end"] != 4 {
+		t.Fatalf("TableChunks() = %+v", chunks)
+	}
+}
+
+func TestParseXLSX(t *testing.T) {
+	const ns = `xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"`
+	parts := map[string]string{
+		"xl/workbook.xml": `<workbook ` + ns + `><sheets><sheet name="Reskontra" sheetId="1" r:id="rId1"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="worksheet" Target="worksheets/sheet1.xml"/></Relationships>`,
+		"xl/sharedStrings.xml": `<sst ` + ns + `><si><t>Datum</t></si><si><t>Leverantör</t></si><si><t>Belopp</t></si><si><r><t>Acme </t></r><r><t>AB</t></r></si></sst>`,
+		"xl/styles.xml":        `<styleSheet ` + ns + `><cellXfs><xf numFmtId="0"/><xf numFmtId="14"/></cellXfs></styleSheet>`,
+		"xl/worksheets/sheet1.xml": `<worksheet ` + ns + `><sheetData>
+<row r="2"><c r="A2" t="s"><v>0</v></c><c r="B2" t="s"><v>1</v></c><c r="C2" t="s"><v>2</v></c></row>
+<row r="3"><c r="A3" s="1"><v>45000</v></c><c r="B3" t="s"><v>3</v></c><c r="D3" t="inlineStr"><is><t>note</t></is></c><c r="C3"><v>9800.5</v></c></row>
+</sheetData></worksheet>`,
+	}
+
+	path := filepath.Join(t.TempDir(), "ledger.xlsx")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	for name, body := range parts {
+		pw, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pw.Write([]byte(body))
+	}
+	zw.Close()
+	f.Close()
+
+	if ft, err := DetectFileType(path); err != nil || ft != "xlsx" {
+		t.Fatalf("DetectFileType() = %q, %v, want xlsx", ft, err)
+	}
+
+	tables, err := ParseXLSX(path)
+	if err != nil {
+		t.Fatalf("ParseXLSX() error = %v", err)
+	}
+	if len(tables) != 1 || tables[0].Sheet != "Reskontra" {
+		t.Fatalf("tables = %+v", tables)
+	}
+	row := tables[0].Rows[0]
+	if row.Number != 3 {
+		t.Errorf("row number = %d, want 3", row.Number)
+	}
+	want := []string{"2023-03-15", "Acme AB", "9800.5", "note"}
+	for i, w := range want {
+		if row.Cell(i) != w {
+			t.Errorf("cell %d = %q, want %q", i, row.Cell(i), w)
+		}
+	}
+}
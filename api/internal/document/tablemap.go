@@ -0,0 +1,421 @@
+package document
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TableMapping turns table rows into graph nodes and edges without an LLM.
+// Each node mapping reads its label from one column; edges connect the nodes
+// produced from the same row. A mapping is supplied by the user on upload or
+// inferred from the header with InferTableMapping.
+//
+//	{
+//	  "nodes": [
+//	    {"key": "supplier", "column": "Leverantör", "node_type": "organization"},
+//	    {"key": "amount", "column": "Belopp", "node_type": "value",
+//	     "properties": {"invoice_number": "Fakturanr"}, "time_column": "Fakturadatum"}
+//	  ],
+//	  "edges": [{"from": "supplier", "to": "amount", "edge_type": "has_value"}]
+//	}
+type TableMapping struct {
+	Sheets []string           `json:"sheets,omitempty"` // limit to these sheets; empty means all
+	Nodes  []TableNodeMapping `json:"nodes"`
+	Edges  []TableEdgeMapping `json:"edges,omitempty"`
+}
+
+// TableNodeMapping maps one column to a node per row. Nodes other than
+// values are shared between rows with the same label, so a supplier that
+// appears on fifty invoices is one organization node.
+type TableNodeMapping struct {
+	Key        string            `json:"key"`
+	Column     string            `json:"column"`
+	NodeType   string            `json:"node_type"`
+	Properties map[string]string `json:"properties,omitempty"` // property name → column
+	TimeColumn string            `json:"time_column,omitempty"`
+}
+
+// TableEdgeMapping connects two node mappings within a row.
+type TableEdgeMapping struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	EdgeType string `json:"edge_type"`
+}
+
+// Validate checks that the mapping is usable.
+func (m *TableMapping) Validate() error {
+	if len(m.Nodes) == 0 {
+		return fmt.Errorf("table mapping has no nodes")
+	}
+	keys := make(map[string]bool, len(m.Nodes))
+	for i, n := range m.Nodes {
+		if n.Key == "" || n.Column == "" || n.NodeType == "" {
+			return fmt.Errorf("node mapping %d needs key, column and node_type", i)
+		}
+		if keys[n.Key] {
+			return fmt.Errorf("duplicate node mapping key %q", n.Key)
+		}
+		keys[n.Key] = true
+	}
+	for i, e := range m.Edges {
+		if !keys[e.From] || !keys[e.To] {
+			return fmt.Errorf("edge mapping %d refers to an unknown node key", i)
+		}
+		if e.EdgeType == "" {
+			return fmt.Errorf("edge mapping %d needs edge_type", i)
+		}
+	}
+	return nil
+}
+
+// appliesTo reports whether the mapping covers a sheet.
+func (m *TableMapping) appliesTo(sheet string) bool {
+	if len(m.Sheets) == 0 {
+		return true
+	}
+	for _, s := range m.Sheets {
+		if strings.EqualFold(s, sheet) {
+			return true
+		}
+	}
+	return false
+}
+
+// MappedRow is the nodes and edges produced from one table row.
+type MappedRow struct {
+	Sheet string
+	Row   int
+	Nodes []MappedNode
+	Edges []TableEdgeMapping
+}
+
+// MappedNode is a node read from one cell. Time holds the raw value of the
+// mapping's time column, if any.
+type MappedNode struct {
+	Key        string
+	NodeType   string
+	Label      string
+	Column     string
+	Cell       string
+	Properties map[string]interface{}
+	Time       string
+}
+
+// Apply resolves the mapping against a table. Cells that are empty produce
+// no node, and edges are kept only when both ends exist in the row.
+func (m *TableMapping) Apply(t *Table) ([]MappedRow, error) {
+	if !m.appliesTo(t.Sheet) {
+		return nil, nil
+	}
+
+	cols := make(map[string]int)
+	resolve := func(name string) (int, error) {
+		if i, ok := cols[name]; ok {
+			return i, nil
+		}
+		i := t.Column(name)
+		if i < 0 {
+			return -1, fmt.Errorf("column %q not found in %s", name, tableName(t))
+		}
+		cols[name] = i
+		return i, nil
+	}
+	for _, n := range m.Nodes {
+		if _, err := resolve(n.Column); err != nil {
+			return nil, err
+		}
+		for _, c := range n.Properties {
+			if _, err := resolve(c); err != nil {
+				return nil, err
+			}
+		}
+		if n.TimeColumn != "" {
+			if _, err := resolve(n.TimeColumn); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var out []MappedRow
+	for _, row := range t.Rows {
+		mr := MappedRow{Sheet: t.Sheet, Row: row.Number}
+		present := make(map[string]bool)
+		for _, n := range m.Nodes {
+			cell := row.Cell(cols[n.Column])
+			if cell == "" {
+				continue
+			}
+			node := MappedNode{
+				Key:        n.Key,
+				NodeType:   n.NodeType,
+				Label:      cell,
+				Column:     t.Header[cols[n.Column]],
+				Cell:       cell,
+				Properties: map[string]interface{}{},
+			}
+			if n.NodeType == "value" {
+				node.Label = node.Column + ": " + cell
+				node.Properties["raw"] = cell
+				if v, unit, ok := ParseTableNumber(cell); ok {
+					node.Properties["value"] = v
+					if unit != "" {
+						node.Properties["unit"] = unit
+					}
+				}
+			}
+			for prop, col := range n.Properties {
+				if v := row.Cell(cols[col]); v != "" {
+					node.Properties[prop] = v
+				}
+			}
+			if n.TimeColumn != "" {
+				node.Time = row.Cell(cols[n.TimeColumn])
+			}
+			mr.Nodes = append(mr.Nodes, node)
+			present[n.Key] = true
+		}
+		for _, e := range m.Edges {
+			if present[e.From] && present[e.To] {
+				mr.Edges = append(mr.Edges, e)
+			}
+		}
+		if len(mr.Nodes) > 0 {
+			out = append(out, mr)
+		}
+	}
+	return out, nil
+}
+
+func tableName(t *Table) string {
+	if t.Sheet == "" {
+		return "table"
+	}
+	return "sheet " + t.Sheet
+}
+
+// Header patterns used to infer a mapping, Swedish and English.
+var (
+	headerOrganization = regexp.MustCompile(`(?i)leverantör|supplier|vendor|företag|company|organi[sz]ation|kund|customer|motpart|counterpart|betalningsmottagare|payee|mottagare|firma|bolag|entreprenör|contractor`)
+	headerPerson       = regexp.MustCompile(`(?i)^(namn|name)$|person|kontakt|contact|ansvarig|attest|signer|medlem|member|boende|tenant|ägare|owner`)
+	headerPlace        = regexp.MustCompile(`(?i)adress|address|^ort$|city|plats|location|fastighet|property`)
+	headerValue        = regexp.MustCompile(`(?i)belopp|amount|summa|^sum|total|pris|price|kostnad|cost|moms|vat|saldo|balance|debet|debit|kredit|credit|avgift|fee`)
+	headerTime         = regexp.MustCompile(`(?i)datum|date|förfallo|^due|period|^tid$|^time$`)
+	headerDescription  = regexp.MustCompile(`(?i)beskrivning|description|^text$|avser|specifikation|meddelande|kommentar|comment|note`)
+	headerIdentifier   = regexp.MustCompile(`(?i)(nr|no)\.?$|(^|[^a-z])id([^a-z]|$)|nummer|number|referens|reference|^ref|ocr|konto|account|verifikat`)
+)
+
+// InferTableMapping guesses a mapping for one table from its header and
+// cell contents. Organization, person and place columns become entity nodes;
+// amount columns become value nodes linked from the first entity column by
+// has_value edges. A date column supplies the claimed time, and identifier
+// and description columns (invoice numbers, accounts, "Avser") are copied
+// onto the value nodes as properties. The mapping is limited to the table's
+// sheet.
+func InferTableMapping(t *Table) (*TableMapping, error) {
+	m := &TableMapping{}
+	if t.Sheet != "" {
+		m.Sheets = []string{t.Sheet}
+	}
+	var entities, values []string
+	var timeColumn string
+	rowContext := map[string]string{}
+
+	for i, h := range t.Header {
+		key := mappingKey(h, i)
+		switch {
+		case headerIdentifier.MatchString(h), headerDescription.MatchString(h):
+			rowContext[key] = h
+		case headerTime.MatchString(h) || columnMostly(t, i, isTableDate):
+			if timeColumn == "" {
+				timeColumn = h
+			}
+		case headerOrganization.MatchString(h):
+			m.Nodes = append(m.Nodes, TableNodeMapping{Key: key, Column: h, NodeType: "organization"})
+			entities = append(entities, key)
+		case headerPerson.MatchString(h):
+			m.Nodes = append(m.Nodes, TableNodeMapping{Key: key, Column: h, NodeType: "person"})
+			entities = append(entities, key)
+		case headerPlace.MatchString(h):
+			m.Nodes = append(m.Nodes, TableNodeMapping{Key: key, Column: h, NodeType: "place"})
+			entities = append(entities, key)
+		case headerValue.MatchString(h) || columnMostly(t, i, isTableNumber):
+			m.Nodes = append(m.Nodes, TableNodeMapping{Key: key, Column: h, NodeType: "value"})
+			values = append(values, key)
+		}
+	}
+
+	if len(m.Nodes) == 0 {
+		return nil, fmt.Errorf("could not infer a column mapping for %s", tableName(t))
+	}
+
+	// Row context goes on the values, or on the entities when the table has
+	// no amounts.
+	carriers := values
+	if len(carriers) == 0 {
+		carriers = entities
+	}
+	for i := range m.Nodes {
+		for _, key := range carriers {
+			if m.Nodes[i].Key != key {
+				continue
+			}
+			m.Nodes[i].TimeColumn = timeColumn
+			if len(rowContext) > 0 {
+				m.Nodes[i].Properties = rowContext
+			}
+		}
+	}
+
+	if len(entities) > 0 {
+		for _, v := range values {
+			m.Edges = append(m.Edges, TableEdgeMapping{From: entities[0], To: v, EdgeType: "has_value"})
+		}
+	}
+
+	return m, nil
+}
+
+// mappingKey derives a node key from a header name.
+func mappingKey(header string, i int) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(header) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case r == 'å' || r == 'ä':
+			b.WriteRune('a')
+		case r == 'ö':
+			b.WriteRune('o')
+		case b.Len() > 0 && !strings.HasSuffix(b.String(), "_"):
+			b.WriteRune('_')
+		}
+	}
+	key := strings.Trim(b.String(), "_")
+	if key == "" {
+		key = "col_" + strconv.Itoa(i+1)
+	}
+	return key
+}
+
+// columnMostly reports whether at least 80% of a column's non-empty cells
+// satisfy pred.
+func columnMostly(t *Table, col int, pred func(string) bool) bool {
+	total, hits := 0, 0
+	for _, r := range t.Rows {
+		cell := r.Cell(col)
+		if cell == "" {
+			continue
+		}
+		total++
+		if pred(cell) {
+			hits++
+		}
+	}
+	return total > 0 && hits*5 >= total*4
+}
+
+func isTableNumber(s string) bool {
+	_, _, ok := ParseTableNumber(s)
+	return ok
+}
+
+func isTableDate(s string) bool {
+	_, ok := ParseTableDate(s)
+	return ok
+}
+
+var tableCurrency = regexp.MustCompile(`(?i)^(sek|kr\.?|kronor|eur|€|usd|\$|:-)$`)
+
+// ParseTableNumber parses an amount as written in ledgers: "12 500,00 kr",
+// "1,234.50", "-350", "(1 200)". A trailing or leading currency is returned
+// as the unit. A lone comma is read as the Swedish decimal separator.
+func ParseTableNumber(s string) (float64, string, bool) {
+	s = strings.TrimSpace(strings.NewReplacer(" ", " ", " ", " ", "−", "-").Replace(s))
+	if s == "" {
+		return 0, "", false
+	}
+
+	negative := false
+	if strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") {
+		negative = true
+		s = strings.TrimSpace(s[1 : len(s)-1])
+	}
+
+	unit := ""
+	fields := strings.Fields(s)
+	if len(fields) > 1 {
+		if tableCurrency.MatchString(fields[len(fields)-1]) {
+			unit = fields[len(fields)-1]
+			fields = fields[:len(fields)-1]
+		} else if tableCurrency.MatchString(fields[0]) {
+			unit = fields[0]
+			fields = fields[1:]
+		}
+	}
+	s = strings.Join(fields, "")
+	if strings.HasSuffix(s, ":-") {
+		unit, s = ":-", strings.TrimSuffix(s, ":-")
+	}
+	for _, sym := range []string{"€", "$"} {
+		if strings.HasPrefix(s, sym) || strings.HasSuffix(s, sym) {
+			unit, s = sym, strings.Trim(s, sym)
+		}
+	}
+	if strings.HasPrefix(s, "-") {
+		negative = !negative
+		s = s[1:]
+	}
+
+	comma, dot := strings.Count(s, ","), strings.Count(s, ".")
+	switch {
+	case comma > 0 && dot > 0:
+		if strings.LastIndex(s, ",") > strings.LastIndex(s, ".") {
+			s = strings.ReplaceAll(s, ".", "")
+			s = strings.Replace(s, ",", ".", 1)
+		} else {
+			s = strings.ReplaceAll(s, ",", "")
+		}
+	case comma == 1:
+		s = strings.Replace(s, ",", ".", 1)
+	case comma > 1:
+		s = strings.ReplaceAll(s, ",", "")
+	case dot > 1:
+		s = strings.ReplaceAll(s, ".", "")
+	}
+
+	if s == "" || strings.IndexFunc(s, func(r rune) bool { return (r < '0' || r > '9') && r != '.' }) >= 0 {
+		return 0, "", false
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, "", false
+	}
+	if negative {
+		v = -v
+	}
+	return v, unit, true
+}
+
+var tableDateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"2006/01/02",
+	"2006.01.02",
+	"02.01.2006",
+}
+
+// ParseTableDate parses a date cell in ISO or Swedish/European notation.
+func ParseTableDate(s string) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	for _, layout := range tableDateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
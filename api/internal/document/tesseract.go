@@ -0,0 +1,115 @@
+package document
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TesseractOCR recognises text with a locally installed tesseract binary.
+type TesseractOCR struct {
+	Languages string        // tesseract -l value, e.g. "swe+eng"; empty uses its default
+	Timeout   time.Duration // per page
+}
+
+// NewTesseractOCR returns a tesseract engine, or an error if tesseract is
+// not installed.
+func NewTesseractOCR(languages string) (*TesseractOCR, error) {
+	if _, err := exec.LookPath("tesseract"); err != nil {
+		return nil, fmt.Errorf("tesseract not found: %w (install tesseract-ocr)", err)
+	}
+	return &TesseractOCR{
+		Languages: languages,
+		Timeout:   2 * time.Minute,
+	}, nil
+}
+
+// Name returns the engine name recorded with OCR results.
+func (t *TesseractOCR) Name() string {
+	return OCREngineTesseract
+}
+
+// Recognize writes the image to a temporary file and reads tesseract's TSV
+// output for it.
+func (t *TesseractOCR) Recognize(img OCRImage) ([]OCRWord, error) {
+	tmp, err := os.CreateTemp("", "sikta-ocr-*."+img.Format)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(img.Data); err != nil {
+		tmp.Close()
+		return nil, fmt.Errorf("failed to write image: %w", err)
+	}
+	tmp.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), t.Timeout)
+	defer cancel()
+
+	args := []string{tmp.Name(), "stdout"}
+	if t.Languages != "" {
+		args = append(args, "-l", t.Languages)
+	}
+	args = append(args, "tsv")
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "tesseract", args...)
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("tesseract failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return parseTesseractTSV(bytes.NewReader(output))
+}
+
+// parseTesseractTSV reads the words (level 5 rows) from tesseract's TSV
+// output. Lines are numbered by their block, paragraph and line numbers;
+// confidences are scaled from 0–100 to 0–1.
+func parseTesseractTSV(r io.Reader) ([]OCRWord, error) {
+	var words []OCRWord
+	lineIDs := make(map[string]int)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) < 12 || fields[0] != "5" {
+			continue
+		}
+		text := strings.TrimSpace(fields[11])
+		conf, err := strconv.ParseFloat(fields[10], 64)
+		if text == "" || err != nil || conf < 0 {
+			continue
+		}
+
+		var box [4]int
+		for i := range box {
+			box[i], _ = strconv.Atoi(fields[6+i])
+		}
+		key := strings.Join(fields[1:5], ".")
+		line, ok := lineIDs[key]
+		if !ok {
+			line = len(lineIDs)
+			lineIDs[key] = line
+		}
+		words = append(words, OCRWord{
+			Text:       text,
+			Confidence: conf / 100,
+			Line:       line,
+			Left:       box[0],
+			Top:        box[1],
+			Width:      box[2],
+			Height:     box[3],
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read tesseract output: %w", err)
+	}
+	return words, nil
+}
@@ -0,0 +1,34 @@
+package document
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseTesseractTSV(t *testing.T) {
+	tsv := "level\tpage_num\tblock_num\tpar_num\tline_num\tword_num\tleft\ttop\twidth\theight\tconf\ttext\n" +
+		"1\t1\t0\t0\t0\t0\t0\t0\t800\t400\t-1\t\n" +
+		"4\t1\t1\t1\t1\t0\t10\t10\t300\t20\t-1\t\n" +
+		"5\t1\t1\t1\t1\t1\t10\t10\t120\t20\t96.5\tPolisanmälan\n" +
+		"5\t1\t1\t1\t1\t2\t140\t10\t80\t20\t41\t2024-03-01\n" +
+		"5\t1\t1\t1\t2\t1\t10\t40\t90\t20\t90\tMisstänkt\n" +
+		"5\t1\t1\t1\t2\t2\t110\t40\t60\t20\t-1\t \n"
+
+	words, err := parseTesseractTSV(strings.NewReader(tsv))
+	if err != nil {
+		t.Fatalf("parseTesseractTSV() error = %v", err)
+	}
+	want := []OCRWord{
+		{Text: "Polisanmälan", Confidence: 0.965, Line: 0, Left: 10, Top: 10, Width: 120, Height: 20},
+		{Text: "2024-03-01", Confidence: 0.41, Line: 0, Left: 140, Top: 10, Width: 80, Height: 20},
+		{Text: "Misstänkt", Confidence: 0.9, Line: 1, Left: 10, Top: 40, Width: 90, Height: 20},
+	}
+	if len(words) != len(want) {
+		t.Fatalf("words = %+v, want %d", words, len(want))
+	}
+	for i := range want {
+		if words[i] != want[i] {
+			t.Errorf("word %d = %+v, want %+v", i, words[i], want[i])
+		}
+	}
+}
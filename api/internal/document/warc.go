@@ -0,0 +1,145 @@
+package document
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+)
+
+// ParseWARC reads the HTML pages captured in a WARC file (plain or
+// gzip-compressed). Response and resource records with an HTML payload
+// become pages; everything else (requests, metadata, images) is skipped.
+func ParseWARC(r io.Reader) ([]*HTMLPage, error) {
+	br := bufio.NewReader(r)
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open gzip WARC: %w", err)
+		}
+		defer gz.Close()
+		br = bufio.NewReader(gz)
+	}
+
+	tp := textproto.NewReader(br)
+	var pages []*HTMLPage
+
+	for {
+		line, err := tp.ReadLine()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read WARC record: %w", err)
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, "WARC/") {
+			return nil, fmt.Errorf("invalid WARC record header: %q", line)
+		}
+
+		header, err := tp.ReadMIMEHeader()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read WARC headers: %w", err)
+		}
+		length, err := strconv.ParseInt(header.Get("Content-Length"), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid WARC Content-Length: %w", err)
+		}
+		block := make([]byte, length)
+		if _, err := io.ReadFull(br, block); err != nil {
+			return nil, fmt.Errorf("failed to read WARC record body: %w", err)
+		}
+
+		payload, err := warcHTMLPayload(header, block)
+		if err != nil {
+			return nil, err
+		}
+		if payload == "" {
+			continue
+		}
+
+		page := ParseHTML(payload)
+		page.URL = header.Get("WARC-Target-URI")
+		if page.CanonicalURL == "" {
+			page.CanonicalURL = page.URL
+		}
+		pages = append(pages, page)
+	}
+
+	if len(pages) == 0 {
+		return nil, fmt.Errorf("no HTML pages found in WARC")
+	}
+	return pages, nil
+}
+
+// warcHTMLPayload returns the HTML carried by a record, or "" if the record
+// is not an HTML page.
+func warcHTMLPayload(header textproto.MIMEHeader, block []byte) (string, error) {
+	contentType := header.Get("Content-Type")
+
+	switch header.Get("WARC-Type") {
+	case "response":
+		if !strings.HasPrefix(contentType, "application/http") {
+			return "", nil
+		}
+		resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(block)), nil)
+		if err != nil {
+			return "", fmt.Errorf("failed to parse archived HTTP response: %w", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || !isHTMLType(resp.Header.Get("Content-Type")) {
+			return "", nil
+		}
+
+		var body io.Reader = resp.Body
+		if strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+			gz, err := gzip.NewReader(resp.Body)
+			if err != nil {
+				return "", fmt.Errorf("failed to decompress archived response: %w", err)
+			}
+			defer gz.Close()
+			body = gz
+		}
+		data, err := io.ReadAll(body)
+		if err != nil {
+			return "", fmt.Errorf("failed to read archived response: %w", err)
+		}
+		return decodeHTMLCharset(data, resp.Header.Get("Content-Type")), nil
+
+	case "resource":
+		if !isHTMLType(contentType) {
+			return "", nil
+		}
+		return decodeHTMLCharset(block, contentType), nil
+	}
+
+	return "", nil
+}
+
+func isHTMLType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mediaType == "text/html" || mediaType == "application/xhtml+xml")
+}
+
+func decodeHTMLCharset(data []byte, contentType string) string {
+	_, params, _ := mime.ParseMediaType(contentType)
+	return decodeCharset(data, params["charset"])
+}
+
+// ParseWARCWithChunks parses a web archive into chunks, one page after
+// another, and returns the pages for source metadata.
+func ParseWARCWithChunks(r io.Reader) ([]Chunk, []*HTMLPage, error) {
+	pages, err := ParseWARC(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	return HTMLChunks(pages), pages, nil
+}
@@ -0,0 +1,34 @@
+package document
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestParseWARC(t *testing.T) {
+	html := "<html><head><title>Protokoll</title></head><body><main><h1>Protokoll</h1><p>Mötet öppnades.</p></main></body></html>"
+	httpResp := "HTTP/1.1 200 OK\r\nContent-Type: text/html; charset=utf-8\r\n\r\n" + html
+	request := "GET /p HTTP/1.1\r\nHost: example.se\r\n\r\n"
+
+	record := func(warcType, contentType, body string) string {
+		return "WARC/1.0\r\nWARC-Type: " + warcType + "\r\nWARC-Target-URI: https://example.se/p\r\n" +
+			"Content-Type: " + contentType + "\r\nContent-Length: " + strconv.Itoa(len(body)) + "\r\n\r\n" + body + "\r\n\r\n"
+	}
+	warc := record("request", "application/http; msgtype=request", request) +
+		record("response", "application/http; msgtype=response", httpResp)
+
+	pages, err := ParseWARC(strings.NewReader(warc))
+	if err != nil {
+		t.Fatalf("ParseWARC() error = %v", err)
+	}
+	if len(pages) != 1 {
+		t.Fatalf("ParseWARC() returned %d pages, want 1", len(pages))
+	}
+	if pages[0].URL != "https://example.se/p" || pages[0].Title != "Protokoll" {
+		t.Errorf("page = %+v", pages[0])
+	}
+	if !strings.Contains(pages[0].Markdown, "# Protokoll\n\nMötet öppnades.") {
+		t.Errorf("markdown = %q", pages[0].Markdown)
+	}
+}
@@ -0,0 +1,345 @@
+package encryption
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+const (
+	// streamMagic starts every encrypted file.
+	streamMagic = "SIKTAENC\x01"
+	// fieldPrefix starts every encrypted text field.
+	fieldPrefix = "enc:v1:"
+	// segmentSize is the plaintext size of each sealed segment of a file.
+	segmentSize = 64 << 10
+	// noncePrefixSize is the random part of a segment nonce; the rest is
+	// the segment counter and the last-segment flag.
+	noncePrefixSize = 7
+)
+
+// ErrDecrypt is returned for data that fails authentication.
+var ErrDecrypt = errors.New("failed to decrypt")
+
+// Envelope encrypts files and text fields under data keys wrapped by a
+// KeyProvider.
+//
+// Each file gets its own data key. Text fields share one data key per
+// process, so that storing a chunk costs no call to the KeyProvider; the
+// wrapped key is stored with every field all the same. Unwrapped keys are
+// cached, so each is unwrapped once.
+type Envelope struct {
+	keys KeyProvider
+
+	mu        sync.Mutex
+	fieldKey  *dataKey
+	unwrapped map[string]cipher.AEAD // header -> data key
+}
+
+// dataKey is a data key ready for use, with the header that names it in
+// stored data: the master key ID and the wrapped key.
+type dataKey struct {
+	aead   cipher.AEAD
+	header []byte
+}
+
+// NewEnvelope returns an Envelope whose data keys are wrapped by keys.
+func NewEnvelope(keys KeyProvider) *Envelope {
+	return &Envelope{keys: keys, unwrapped: make(map[string]cipher.AEAD)}
+}
+
+// Load returns an Envelope with master keys from keyFile, or nil if keyFile
+// is empty, meaning encryption is off.
+func Load(keyFile string) (*Envelope, error) {
+	if keyFile == "" {
+		return nil, nil
+	}
+	keys, err := LoadKeyFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	return NewEnvelope(keys), nil
+}
+
+func (e *Envelope) newDataKey(ctx context.Context) (*dataKey, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	keyID, wrapped, err := e.keys.WrapKey(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap data key: %w", err)
+	}
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	header := []byte{byte(len(keyID))}
+	header = append(header, keyID...)
+	header = binary.BigEndian.AppendUint16(header, uint16(len(wrapped)))
+	header = append(header, wrapped...)
+	return &dataKey{aead: aead, header: header}, nil
+}
+
+// readDataKey reads a data key header from r and unwraps the key.
+func (e *Envelope) readDataKey(ctx context.Context, r io.Reader) (cipher.AEAD, error) {
+	var idLen [1]byte
+	if _, err := io.ReadFull(r, idLen[:]); err != nil {
+		return nil, fmt.Errorf("%w: truncated header", ErrDecrypt)
+	}
+	keyID := make([]byte, idLen[0])
+	var wrappedLen [2]byte
+	if _, err := io.ReadFull(r, keyID); err != nil {
+		return nil, fmt.Errorf("%w: truncated header", ErrDecrypt)
+	}
+	if _, err := io.ReadFull(r, wrappedLen[:]); err != nil {
+		return nil, fmt.Errorf("%w: truncated header", ErrDecrypt)
+	}
+	wrapped := make([]byte, binary.BigEndian.Uint16(wrappedLen[:]))
+	if _, err := io.ReadFull(r, wrapped); err != nil {
+		return nil, fmt.Errorf("%w: truncated header", ErrDecrypt)
+	}
+
+	cacheKey := string(keyID) + "\x00" + string(wrapped)
+	e.mu.Lock()
+	aead, ok := e.unwrapped[cacheKey]
+	e.mu.Unlock()
+	if ok {
+		return aead, nil
+	}
+
+	key, err := e.keys.UnwrapKey(ctx, string(keyID), wrapped)
+	if err != nil {
+		return nil, err
+	}
+	if aead, err = newGCM(key); err != nil {
+		return nil, err
+	}
+	e.mu.Lock()
+	e.unwrapped[cacheKey] = aead
+	e.mu.Unlock()
+	return aead, nil
+}
+
+// EncryptWriter returns a writer that encrypts what is written to it under
+// a new data key and writes the result to w. Close writes the last segment;
+// it does not close w.
+//
+// The plaintext is sealed in segments, each with a nonce made of a random
+// prefix, the segment number and a last-segment flag, so that a file can be
+// decrypted as a stream and reordered, dropped or truncated segments fail
+// authentication.
+func (e *Envelope) EncryptWriter(ctx context.Context, w io.Writer) (io.WriteCloser, error) {
+	key, err := e.newDataKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	prefix := make([]byte, noncePrefixSize)
+	if _, err := rand.Read(prefix); err != nil {
+		return nil, err
+	}
+
+	header := append([]byte(streamMagic), key.header...)
+	header = append(header, prefix...)
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	return &encryptWriter{w: w, aead: key.aead, prefix: prefix}, nil
+}
+
+type encryptWriter struct {
+	w       io.Writer
+	aead    cipher.AEAD
+	prefix  []byte
+	buf     []byte
+	segment uint32
+	closed  bool
+}
+
+func (ew *encryptWriter) Write(p []byte) (int, error) {
+	if ew.closed {
+		return 0, fmt.Errorf("write to closed encrypt writer")
+	}
+	ew.buf = append(ew.buf, p...)
+	// A full segment is only sealed once more data follows it, since the
+	// last segment is sealed differently
+	for len(ew.buf) > segmentSize {
+		if err := ew.seal(ew.buf[:segmentSize], false); err != nil {
+			return 0, err
+		}
+		ew.buf = append(ew.buf[:0], ew.buf[segmentSize:]...)
+	}
+	return len(p), nil
+}
+
+func (ew *encryptWriter) Close() error {
+	if ew.closed {
+		return nil
+	}
+	ew.closed = true
+	return ew.seal(ew.buf, true)
+}
+
+func (ew *encryptWriter) seal(plaintext []byte, last bool) error {
+	sealed := ew.aead.Seal(nil, segmentNonce(ew.prefix, ew.segment, last), plaintext, nil)
+	ew.segment++
+	_, err := ew.w.Write(sealed)
+	return err
+}
+
+func segmentNonce(prefix []byte, segment uint32, last bool) []byte {
+	nonce := make([]byte, 0, noncePrefixSize+5)
+	nonce = append(nonce, prefix...)
+	nonce = binary.BigEndian.AppendUint32(nonce, segment)
+	if last {
+		return append(nonce, 1)
+	}
+	return append(nonce, 0)
+}
+
+// DecryptReader returns a reader of the plaintext of r. Data that was never
+// encrypted is passed through unchanged, so files stored before encryption
+// was turned on stay readable.
+func (e *Envelope) DecryptReader(ctx context.Context, r io.Reader) (io.Reader, error) {
+	br := bufio.NewReaderSize(r, segmentSize+64)
+	magic, err := br.Peek(len(streamMagic))
+	if err != nil || string(magic) != streamMagic {
+		// Too short or no magic: plaintext
+		return br, nil
+	}
+	br.Discard(len(streamMagic))
+
+	aead, err := e.readDataKey(ctx, br)
+	if err != nil {
+		return nil, err
+	}
+	prefix := make([]byte, noncePrefixSize)
+	if _, err := io.ReadFull(br, prefix); err != nil {
+		return nil, fmt.Errorf("%w: truncated header", ErrDecrypt)
+	}
+	return &decryptReader{r: br, aead: aead, prefix: prefix, sealed: make([]byte, segmentSize+aead.Overhead())}, nil
+}
+
+type decryptReader struct {
+	r       *bufio.Reader
+	aead    cipher.AEAD
+	prefix  []byte
+	sealed  []byte
+	plain   []byte
+	segment uint32
+	done    bool
+}
+
+func (dr *decryptReader) Read(p []byte) (int, error) {
+	for len(dr.plain) == 0 {
+		if dr.done {
+			return 0, io.EOF
+		}
+		if err := dr.next(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, dr.plain)
+	dr.plain = dr.plain[n:]
+	return n, nil
+}
+
+// next reads and opens the next segment. A segment is the last one if it
+// is short or nothing follows it.
+func (dr *decryptReader) next() error {
+	n, err := io.ReadFull(dr.r, dr.sealed)
+	last := false
+	switch {
+	case err == io.ErrUnexpectedEOF || err == io.EOF:
+		last = true
+	case err != nil:
+		return err
+	default:
+		if _, err := dr.r.Peek(1); err == io.EOF {
+			last = true
+		}
+	}
+
+	plain, err := dr.aead.Open(dr.sealed[:0:0], segmentNonce(dr.prefix, dr.segment, last), dr.sealed[:n], nil)
+	if err != nil {
+		return fmt.Errorf("%w: segment %d", ErrDecrypt, dr.segment)
+	}
+	dr.segment++
+	dr.plain = plain
+	dr.done = last
+	return nil
+}
+
+// EncryptString encrypts a text field, bound to aad: the same aad must be
+// given to decrypt it. The empty string is kept as it is.
+func (e *Envelope) EncryptString(ctx context.Context, s string, aad []byte) (string, error) {
+	if s == "" {
+		return s, nil
+	}
+
+	e.mu.Lock()
+	key := e.fieldKey
+	e.mu.Unlock()
+	if key == nil {
+		var err error
+		if key, err = e.newDataKey(ctx); err != nil {
+			return "", err
+		}
+		e.mu.Lock()
+		if e.fieldKey == nil {
+			e.fieldKey = key
+		}
+		key = e.fieldKey
+		e.mu.Unlock()
+	}
+
+	nonce := make([]byte, key.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	data := append(bytes.Clone(key.header), nonce...)
+	data = key.aead.Seal(data, nonce, []byte(s), aad)
+	return fieldPrefix + base64.RawStdEncoding.EncodeToString(data), nil
+}
+
+// DecryptString decrypts a text field encrypted by EncryptString with the
+// same aad. Other strings are returned unchanged, so rows written before
+// encryption was turned on stay readable.
+func (e *Envelope) DecryptString(ctx context.Context, s string, aad []byte) (string, error) {
+	if !IsEncryptedString(s) {
+		return s, nil
+	}
+	data, err := base64.RawStdEncoding.DecodeString(s[len(fieldPrefix):])
+	if err != nil {
+		return "", fmt.Errorf("%w: invalid field encoding", ErrDecrypt)
+	}
+	r := bytes.NewReader(data)
+	aead, err := e.readDataKey(ctx, r)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(r, nonce); err != nil {
+		return "", fmt.Errorf("%w: truncated field", ErrDecrypt)
+	}
+	plain, err := aead.Open(nil, nonce, data[len(data)-r.Len():], aad)
+	if err != nil {
+		return "", fmt.Errorf("%w: field", ErrDecrypt)
+	}
+	return string(plain), nil
+}
+
+// IsEncryptedString reports whether s is an encrypted text field.
+func IsEncryptedString(s string) bool {
+	return strings.HasPrefix(s, fieldPrefix)
+}
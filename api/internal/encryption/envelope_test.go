@@ -0,0 +1,187 @@
+package encryption
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testKeys writes a key file with one key per ID and loads it.
+func testKeys(t *testing.T, ids ...string) *FileKeys {
+	t.Helper()
+	var lines []string
+	for _, id := range ids {
+		key := make([]byte, 32)
+		rand.Read(key)
+		lines = append(lines, id+" "+base64.StdEncoding.EncodeToString(key))
+	}
+	path := filepath.Join(t.TempDir(), "keys")
+	if err := os.WriteFile(path, []byte("# test keys\n"+strings.Join(lines, "\n")+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := LoadKeyFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keys
+}
+
+func encryptBytes(t *testing.T, env *Envelope, plain []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := env.EncryptWriter(context.Background(), &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Odd write sizes, so segments are cut across writes
+	for p := plain; len(p) > 0; {
+		n := min(len(p), 10007)
+		if _, err := w.Write(p[:n]); err != nil {
+			t.Fatal(err)
+		}
+		p = p[n:]
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func decryptBytes(env *Envelope, sealed []byte) ([]byte, error) {
+	r, err := env.DecryptReader(context.Background(), bytes.NewReader(sealed))
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+func TestEnvelopeRoundTrip(t *testing.T) {
+	env := NewEnvelope(testKeys(t, "k1"))
+	sizes := []int{0, 1, segmentSize - 1, segmentSize, segmentSize + 1, 2 * segmentSize, 3*segmentSize + 17}
+	for _, size := range sizes {
+		plain := make([]byte, size)
+		rand.Read(plain)
+		sealed := encryptBytes(t, env, plain)
+		if size > 16 && bytes.Contains(sealed, plain[:min(size, 64)]) {
+			t.Errorf("size %d: plaintext appears in the output", size)
+		}
+		got, err := decryptBytes(env, sealed)
+		if err != nil {
+			t.Fatalf("size %d: decrypt error = %v", size, err)
+		}
+		if !bytes.Equal(got, plain) {
+			t.Errorf("size %d: round trip changed the data", size)
+		}
+	}
+}
+
+func TestEnvelopeTamper(t *testing.T) {
+	env := NewEnvelope(testKeys(t, "k1"))
+	plain := make([]byte, 2*segmentSize)
+	rand.Read(plain)
+	sealed := encryptBytes(t, env, plain)
+	segment := segmentSize + 16 // sealed size of a full segment
+	header := len(sealed) - 2*segment
+
+	tests := []struct {
+		name   string
+		sealed []byte
+	}{
+		// A file cut at a segment boundary must not pass for a shorter one
+		{"truncated at segment boundary", sealed[:header+segment]},
+		{"truncated mid-segment", sealed[:header+segment+100]},
+		{"last segment dropped to a tag", sealed[:header+segment+16]},
+		{"segments only, header cut", sealed[:header-3]},
+		{"segments swapped", append(append(bytes.Clone(sealed[:header]), sealed[header+segment:]...), sealed[header:header+segment]...)},
+		{"byte flipped", func() []byte {
+			b := bytes.Clone(sealed)
+			b[header+segment+5] ^= 1
+			return b
+		}()},
+		{"byte appended", append(bytes.Clone(sealed), 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decryptBytes(env, tt.sealed)
+			if err == nil {
+				t.Fatalf("decrypt succeeded with %d bytes", len(got))
+			}
+			if !errors.Is(err, ErrDecrypt) {
+				t.Errorf("error = %v, want ErrDecrypt", err)
+			}
+		})
+	}
+}
+
+func TestEnvelopePlaintextPassthrough(t *testing.T) {
+	env := NewEnvelope(testKeys(t, "k1"))
+	for _, plain := range []string{"", "short", "a file stored before encryption was turned on"} {
+		got, err := decryptBytes(env, []byte(plain))
+		if err != nil || string(got) != plain {
+			t.Errorf("DecryptReader(%q) = %q, %v", plain, got, err)
+		}
+	}
+}
+
+func TestEnvelopeKeyRotation(t *testing.T) {
+	old := testKeys(t, "old")
+	sealed := encryptBytes(t, NewEnvelope(old), []byte("sealed before the rotation"))
+
+	// The new key file lists the new key first and keeps the old one
+	rotated := testKeys(t, "new")
+	rotated.keys["old"] = old.keys["old"]
+	got, err := decryptBytes(NewEnvelope(rotated), sealed)
+	if err != nil || string(got) != "sealed before the rotation" {
+		t.Errorf("decrypt after rotation = %q, %v", got, err)
+	}
+
+	if _, err := decryptBytes(NewEnvelope(testKeys(t, "new")), sealed); err == nil {
+		t.Error("decrypt without the old key succeeded")
+	}
+}
+
+func TestEnvelopeString(t *testing.T) {
+	ctx := context.Background()
+	env := NewEnvelope(testKeys(t, "k1"))
+	aad := []byte("chunks.content/0b0e4c4e-7d0e-4a58-a1a6-3b2f0c1d9e11")
+
+	enc, err := env.EncryptString(ctx, "Protokoll 5 § 12 – beslut", aad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsEncryptedString(enc) || strings.Contains(enc, "beslut") {
+		t.Fatalf("EncryptString() = %q", enc)
+	}
+	if got, err := env.DecryptString(ctx, enc, aad); err != nil || got != "Protokoll 5 § 12 – beslut" {
+		t.Errorf("DecryptString() = %q, %v", got, err)
+	}
+
+	// Another process with the same master key can read it
+	other := NewEnvelope(env.keys)
+	if got, err := other.DecryptString(ctx, enc, aad); err != nil || got != "Protokoll 5 § 12 – beslut" {
+		t.Errorf("DecryptString() in another envelope = %q, %v", got, err)
+	}
+
+	for _, wrong := range [][]byte{nil, []byte("chunks.content/other-row"), []byte("provenance.excerpt/0b0e4c4e-7d0e-4a58-a1a6-3b2f0c1d9e11")} {
+		if _, err := env.DecryptString(ctx, enc, wrong); !errors.Is(err, ErrDecrypt) {
+			t.Errorf("DecryptString() with aad %q error = %v, want ErrDecrypt", wrong, err)
+		}
+	}
+	if _, err := env.DecryptString(ctx, enc[:len(enc)-4], aad); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("DecryptString() of a truncated field error = %v, want ErrDecrypt", err)
+	}
+
+	if enc, err := env.EncryptString(ctx, "", aad); err != nil || enc != "" {
+		t.Errorf("EncryptString(\"\") = %q, %v", enc, err)
+	}
+	if got, err := env.DecryptString(ctx, "written before encryption", aad); err != nil || got != "written before encryption" {
+		t.Errorf("DecryptString() of plaintext = %q, %v", got, err)
+	}
+}
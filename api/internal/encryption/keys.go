@@ -0,0 +1,114 @@
+// Package encryption provides envelope encryption for data at rest: each
+// file or text field is encrypted with AES-256-GCM under a data key, and the
+// data key is stored beside it wrapped by a master key that never leaves the
+// KeyProvider.
+package encryption
+
+import (
+	"bufio"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// KeyProvider wraps and unwraps data keys with master keys it holds, as a
+// KMS does. Implementations backed by a KMS only need these two calls.
+type KeyProvider interface {
+	// WrapKey encrypts a data key under the current master key and returns
+	// the master key's ID with the wrapped key.
+	WrapKey(ctx context.Context, dataKey []byte) (keyID string, wrapped []byte, err error)
+	// UnwrapKey decrypts a data key wrapped under the named master key.
+	UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error)
+}
+
+// FileKeys is a KeyProvider holding master keys read from a local key file.
+type FileKeys struct {
+	current string
+	keys    map[string]cipher.AEAD
+}
+
+// LoadKeyFile reads master keys from a file of "<key-id> <base64 key>"
+// lines, each key 32 random bytes (e.g. from openssl rand -base64 32). The
+// first key wraps new data keys; the rest are kept to unwrap data keys
+// wrapped before a rotation. Blank lines and # comments are skipped.
+func LoadKeyFile(path string) (*FileKeys, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open key file: %w", err)
+	}
+	defer f.Close()
+
+	fk := &FileKeys{keys: make(map[string]cipher.AEAD)}
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) != 2 || len(fields[0]) > 255 {
+			return nil, fmt.Errorf("key file line %d: expected \"<key-id> <base64 key>\"", line)
+		}
+		id := fields[0]
+		key, err := base64.StdEncoding.DecodeString(fields[1])
+		if err != nil || len(key) != 32 {
+			return nil, fmt.Errorf("key file line %d: key must be 32 bytes, base64-encoded", line)
+		}
+		if _, ok := fk.keys[id]; ok {
+			return nil, fmt.Errorf("key file line %d: duplicate key ID %s", line, id)
+		}
+		if fk.keys[id], err = newGCM(key); err != nil {
+			return nil, err
+		}
+		if fk.current == "" {
+			fk.current = id
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+	if fk.current == "" {
+		return nil, fmt.Errorf("key file %s holds no keys", path)
+	}
+	return fk, nil
+}
+
+// WrapKey implements KeyProvider.
+func (fk *FileKeys) WrapKey(ctx context.Context, dataKey []byte) (string, []byte, error) {
+	aead := fk.keys[fk.current]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", nil, err
+	}
+	return fk.current, aead.Seal(nonce, nonce, dataKey, []byte(fk.current)), nil
+}
+
+// UnwrapKey implements KeyProvider.
+func (fk *FileKeys) UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	aead, ok := fk.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("unknown master key %q", keyID)
+	}
+	if len(wrapped) < aead.NonceSize() {
+		return nil, fmt.Errorf("wrapped data key is truncated")
+	}
+	n := aead.NonceSize()
+	dataKey, err := aead.Open(nil, wrapped[:n], wrapped[n:], []byte(keyID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key with %q: %w", keyID, err)
+	}
+	return dataKey, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -324,7 +324,7 @@ func (em *EventMatcher) findBestEventMatch(manifestEvt *ManifestEvent, extractio
 				MatchScore:        1.0,
 				EntitiesMatched:   len(manifestEvt.Entities),
 				EntitiesTotal:     len(manifestEvt.Entities),
-				SourceMatch:       true, // Assume source matches if labels match
+				SourceMatch:       sourceMatches(manifestEvt, node),
 				TemporalOverlap:   true,
 				IsCorrect:         true,
 				IsHallucination:   false,
@@ -345,7 +345,7 @@ func (em *EventMatcher) findBestEventMatch(manifestEvt *ManifestEvent, extractio
 					MatchScore:        score,
 					EntitiesMatched:   len(manifestEvt.Entities),
 					EntitiesTotal:     len(manifestEvt.Entities),
-					SourceMatch:       true,
+					SourceMatch:       sourceMatches(manifestEvt, node),
 					TemporalOverlap:   true,
 					IsCorrect:         true,
 					IsHallucination:   false,
@@ -388,7 +388,7 @@ func (em *EventMatcher) findBestEventMatch(manifestEvt *ManifestEvent, extractio
 					MatchScore:        score,
 					EntitiesMatched:   len(manifestEvt.Entities),
 					EntitiesTotal:     len(manifestEvt.Entities),
-					SourceMatch:       true,
+					SourceMatch:       sourceMatches(manifestEvt, node),
 					TemporalOverlap:   true,
 					IsCorrect:         true,
 					IsHallucination:   false,
@@ -400,6 +400,25 @@ func (em *EventMatcher) findBestEventMatch(manifestEvt *ManifestEvent, extractio
 	return bestMatch
 }
 
+// sourceMatches reports whether an extracted event came from the manifest
+// event's source document and section. Without source data on either side
+// the source is assumed to match.
+func sourceMatches(manifestEvt *ManifestEvent, node *ExtractedNode) bool {
+	if doc, ok := node.Properties["source_doc"].(string); ok && manifestEvt.SourceDoc != "" && doc != manifestEvt.SourceDoc {
+		return false
+	}
+	section, ok := node.Properties["source_section"].(string)
+	if !ok || manifestEvt.SourceSection == "" {
+		return true
+	}
+	return normalizeSection(section) == normalizeSection(manifestEvt.SourceSection)
+}
+
+// normalizeSection makes section IDs comparable: "§ 5" and "§5" are equal.
+func normalizeSection(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), ""))
+}
+
 // normalizeText normalizes text for comparison: lowercase, trim, remove titles
 func normalizeText(s string) string {
 	// Convert to lowercase
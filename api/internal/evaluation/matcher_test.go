@@ -176,3 +176,27 @@ func TestNormalizeText(t *testing.T) {
 		}
 	}
 }
+
+// TestSourceMatches tests source document and section scoring of event matches
+func TestSourceMatches(t *testing.T) {
+	manifestEvt := &ManifestEvent{ID: "V1", SourceDoc: "A1", SourceSection: "§5"}
+
+	tests := []struct {
+		name       string
+		properties map[string]interface{}
+		expected   bool
+	}{
+		{"no source data", nil, true},
+		{"same section", map[string]interface{}{"source_doc": "A1", "source_section": "§ 5"}, true},
+		{"other section", map[string]interface{}{"source_doc": "A1", "source_section": "§6"}, false},
+		{"other document", map[string]interface{}{"source_doc": "A2", "source_section": "§5"}, false},
+		{"document only", map[string]interface{}{"source_doc": "A1"}, true},
+	}
+
+	for _, tt := range tests {
+		node := &ExtractedNode{ID: "node-1", NodeType: "event", Label: "Fuktinspektion", Properties: tt.properties}
+		if result := sourceMatches(manifestEvt, node); result != tt.expected {
+			t.Errorf("%s: sourceMatches() = %v, want %v", tt.name, result, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package export
+
+import (
+	"bufio"
+	"io"
+	"strings"
+
+	"github.com/einarsundgren/sikta/internal/database"
+)
+
+// WriteDOT writes the graph in Graphviz DOT. Nodes and edges carry every
+// attribute; Graphviz ignores the ones it does not know.
+func WriteDOT(w io.Writer, g *Graph) error {
+	nodes, edges := nodeTable(g), edgeTable(g)
+	bw := bufio.NewWriter(w)
+
+	bw.WriteString("digraph " + dotQuote(g.Project.Title) + " {\n")
+	for i, n := range g.Nodes {
+		bw.WriteString("  " + dotQuote(database.UUIDStr(n.ID)))
+		writeDOTAttributes(bw, nodes.Attributes, nodes.Rows[i])
+		bw.WriteString(";\n")
+	}
+	for i, e := range g.Edges {
+		bw.WriteString("  " + dotQuote(database.UUIDStr(e.SourceNode)) + " -> " + dotQuote(database.UUIDStr(e.TargetNode)))
+		row := make(map[string]any)
+		for k, v := range edges.Rows[i] {
+			row[k] = v
+		}
+		row["id"] = database.UUIDStr(e.ID)
+		// Graphviz draws label; edge_type stays as its own attribute too
+		if _, ok := row["label"]; !ok {
+			row["label"] = e.EdgeType
+		}
+		writeDOTAttributes(bw, append([]attribute{{"id", typeString}, {"label", typeString}}, edges.Attributes...), row)
+		bw.WriteString(";\n")
+	}
+	bw.WriteString("}\n")
+	return bw.Flush()
+}
+
+func writeDOTAttributes(bw *bufio.Writer, attributes []attribute, row map[string]any) {
+	var parts []string
+	seen := make(map[string]bool)
+	for _, a := range attributes {
+		v, ok := row[a.Name]
+		if !ok || seen[a.Name] {
+			continue
+		}
+		seen[a.Name] = true
+		parts = append(parts, dotQuote(a.Name)+"="+dotQuote(formatValue(v)))
+	}
+	if len(parts) > 0 {
+		bw.WriteString(" [" + strings.Join(parts, ", ") + "]")
+	}
+}
+
+// dotQuote returns s as a DOT quoted string
+func dotQuote(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", "")
+	return `"` + r.Replace(s) + `"`
+}
@@ -0,0 +1,299 @@
+// Package export writes a project's graph in formats other tools read.
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/einarsundgren/sikta/internal/database"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Graph is a project's nodes and edges with their provenance
+type Graph struct {
+	Project    *database.Project
+	Nodes      []*database.Node
+	Edges      []*database.Edge
+	Provenance map[pgtype.UUID][]*database.Provenance // by node or edge ID
+
+	// Referenced are the nodes provenance points at that are not among
+	// Nodes: the documents claims come from and whoever made them
+	Referenced []*database.Node
+}
+
+// Load reads a project's graph: the nodes and edges with provenance from
+// its documents, every such provenance record, the nodes at either end of
+// its edges, and the documents and claimants the records refer to.
+func Load(ctx context.Context, db *database.Queries, projectID uuid.UUID) (*Graph, error) {
+	id := database.PgUUID(projectID)
+	project, err := db.GetProject(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get project: %w", err)
+	}
+	nodes, err := db.ListProjectNodes(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	edges, err := db.ListProjectEdges(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list edges: %w", err)
+	}
+	records, err := db.ListProjectProvenance(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list provenance: %w", err)
+	}
+
+	g := &Graph{
+		Project:    project,
+		Nodes:      nodes,
+		Edges:      edges,
+		Provenance: make(map[pgtype.UUID][]*database.Provenance),
+	}
+	for _, p := range records {
+		g.Provenance[p.TargetID] = append(g.Provenance[p.TargetID], p)
+	}
+
+	have := make(map[pgtype.UUID]bool, len(nodes))
+	for _, n := range nodes {
+		have[n.ID] = true
+	}
+	var missing []pgtype.UUID
+	for _, p := range records {
+		for _, ref := range []pgtype.UUID{p.SourceID, p.ClaimedBy} {
+			if ref.Valid && !have[ref] {
+				have[ref] = true
+				missing = append(missing, ref)
+			}
+		}
+	}
+	if len(missing) > 0 {
+		g.Referenced, err = db.ListNodesByIDs(ctx, missing)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list referenced nodes: %w", err)
+		}
+	}
+	return g, nil
+}
+
+// Format is an export format
+type Format struct {
+	ContentType string
+	Extension   string
+	Write       func(w io.Writer, g *Graph) error
+}
+
+// Formats are the export formats by name
+var Formats = map[string]Format{
+	"graphml":   {ContentType: "application/graphml+xml", Extension: ".graphml", Write: WriteGraphML},
+	"gexf":      {ContentType: "application/gexf+xml", Extension: ".gexf", Write: WriteGEXF},
+	"dot":       {ContentType: "text/vnd.graphviz", Extension: ".dot", Write: WriteDOT},
+	"neo4j-csv": {ContentType: "application/zip", Extension: ".zip", Write: WriteNeo4jCSV},
+	"jsonld":    {ContentType: "application/ld+json", Extension: ".jsonld", Write: WriteJSONLD},
+	"turtle":    {ContentType: "text/turtle", Extension: ".ttl", Write: WriteTurtle},
+}
+
+// bestProvenance returns the record that stands for a node or edge when
+// its provenance is flattened to one set of values: the one with the
+// highest trust times confidence, preferring records that are not rejected.
+func bestProvenance(records []*database.Provenance) *database.Provenance {
+	var best *database.Provenance
+	for _, p := range records {
+		if best == nil || rank(p) > rank(best) {
+			best = p
+		}
+	}
+	return best
+}
+
+func rank(p *database.Provenance) float32 {
+	if p.IsRejected() {
+		return p.EffectiveConfidence() - 1
+	}
+	return p.EffectiveConfidence()
+}
+
+// Attribute types, named as in GraphML
+const (
+	typeString  = "string"
+	typeDouble  = "double"
+	typeBoolean = "boolean"
+	typeInt     = "int"
+)
+
+// attribute is a column of flattened node or edge data
+type attribute struct {
+	Name string
+	Type string
+}
+
+// table holds nodes or edges flattened to scalar attributes: built-in
+// columns, the best provenance record's fields, then properties. Each row
+// maps attribute names to string, float64, bool or int values; missing
+// values are absent.
+type table struct {
+	Attributes []attribute
+	Rows       []map[string]any
+}
+
+var provenanceAttributes = []attribute{
+	{"confidence", typeDouble},
+	{"trust", typeDouble},
+	{"modality", typeString},
+	{"status", typeString},
+	{"excerpt", typeString},
+	{"provenance_count", typeInt},
+}
+
+func nodeTable(g *Graph) *table {
+	t := &table{Attributes: append([]attribute{{"node_type", typeString}, {"label", typeString}}, provenanceAttributes...)}
+	props := make([]map[string]any, len(g.Nodes))
+	for i, n := range g.Nodes {
+		row := map[string]any{"node_type": n.NodeType, "label": n.Label}
+		addProvenance(row, g.Provenance[n.ID])
+		t.Rows = append(t.Rows, row)
+		props[i] = properties(n.Properties)
+	}
+	t.addProperties(props)
+	return t
+}
+
+func edgeTable(g *Graph) *table {
+	t := &table{Attributes: append([]attribute{{"edge_type", typeString}, {"is_negated", typeBoolean}}, provenanceAttributes...)}
+	props := make([]map[string]any, len(g.Edges))
+	for i, e := range g.Edges {
+		row := map[string]any{"edge_type": e.EdgeType, "is_negated": e.IsNegated}
+		addProvenance(row, g.Provenance[e.ID])
+		t.Rows = append(t.Rows, row)
+		props[i] = properties(e.Properties)
+	}
+	t.addProperties(props)
+	return t
+}
+
+func addProvenance(row map[string]any, records []*database.Provenance) {
+	row["provenance_count"] = len(records)
+	best := bestProvenance(records)
+	if best == nil {
+		return
+	}
+	row["confidence"] = widen(best.Confidence)
+	row["trust"] = widen(best.Trust)
+	row["modality"] = best.Modality
+	row["status"] = best.Status
+	if best.Excerpt != "" {
+		row["excerpt"] = best.Excerpt
+	}
+}
+
+// addProperties adds property columns in name order, typed boolean or
+// double if every value is, else string. Nested values become JSON text; a
+// property named id or like a built-in column gets a "property_" prefix.
+func (t *table) addProperties(props []map[string]any) {
+	// Every format gives nodes and edges an id of their own
+	taken := map[string]bool{"id": true}
+	for _, a := range t.Attributes {
+		taken[a.Name] = true
+	}
+	types := make(map[string]string)
+	for _, p := range props {
+		for k, v := range p {
+			types[k] = mergeType(types[k], v)
+		}
+	}
+	keys := make([]string, 0, len(types))
+	for k := range types {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		name := k
+		if taken[name] {
+			name = "property_" + k
+		}
+		t.Attributes = append(t.Attributes, attribute{Name: name, Type: types[k]})
+		for i, p := range props {
+			if v, ok := p[k]; ok {
+				t.Rows[i][name] = scalar(v, types[k])
+			}
+		}
+	}
+}
+
+func mergeType(current string, v any) string {
+	var t string
+	switch v.(type) {
+	case bool:
+		t = typeBoolean
+	case float64:
+		t = typeDouble
+	default:
+		t = typeString
+	}
+	if current == "" || current == t {
+		return t
+	}
+	return typeString
+}
+
+func scalar(v any, typ string) any {
+	if typ != typeString {
+		return v
+	}
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return jsonText(v)
+}
+
+// jsonText renders a nested property value as JSON, leaving <, > and &
+// as they are rather than escaping them for HTML
+func jsonText(v any) string {
+	var b strings.Builder
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(v)
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// properties decodes a JSONB properties column, dropping nulls
+func properties(data []byte) map[string]any {
+	props := make(map[string]any)
+	if len(data) > 0 {
+		_ = json.Unmarshal(data, &props)
+	}
+	for k, v := range props {
+		if v == nil {
+			delete(props, k)
+		}
+	}
+	return props
+}
+
+// widen converts a float32 to the float64 with the same shortest decimal
+// form, so 0.8 is written as 0.8 rather than 0.800000011920929
+func widen(f float32) float64 {
+	v, _ := strconv.ParseFloat(strconv.FormatFloat(float64(f), 'g', -1, 32), 64)
+	return v
+}
+
+// formatValue renders an attribute value as text
+func formatValue(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case int:
+		return strconv.Itoa(v)
+	}
+	return fmt.Sprint(v)
+}
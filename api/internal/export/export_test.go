@@ -0,0 +1,274 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/einarsundgren/sikta/internal/database"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+func testID(n byte) pgtype.UUID {
+	return database.PgUUID(uuid.UUID{0x5b, 0x1c, 0x8f, 0x8e, 15: n})
+}
+
+func testTime(s string) pgtype.Timestamptz {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		panic(err)
+	}
+	return pgtype.Timestamptz{Time: t, Valid: true}
+}
+
+// Names that need escaping in every format: quotes, markup, ampersands,
+// newlines and non-ASCII letters
+const (
+	annaLabel   = "Anna \"Annie\" Öberg <vd> & co\nandra raden"
+	excerpt     = "'Anna' sa: \"ja\" <b>& nej</b>\r\n\tslut"
+	projectName = "Räkenskaper \"2021\" <utkast> & noter\nrad två"
+)
+
+// testGraph is a small project: a person and a company joined by two
+// edges, one negated, with provenance from a document claimed by a witness
+func testGraph() *Graph {
+	anna := &database.Node{
+		ID:         testID(1),
+		NodeType:   "person",
+		Label:      annaLabel,
+		Properties: []byte(`{"role": "vd & ägare", "age": 52, "id": "x<1>", "label": "alias", "tags": ["a", "ö"], "nested": {"k": "<v>"}, "gone": null}`),
+	}
+	company := &database.Node{
+		ID:         testID(2),
+		NodeType:   "företag",
+		Label:      "Bygg & Måleri AB",
+		Properties: []byte(`{"org_nr": "556677-8899", "age": "gammal"}`),
+	}
+	document := &database.Node{ID: testID(3), NodeType: "document", Label: `Protokoll § 5 "slut"`, Properties: []byte(`{}`)}
+	witness := &database.Node{ID: testID(4), NodeType: "person", Label: "Åsa <källa>", Properties: []byte(`{}`)}
+
+	worksAt := &database.Edge{
+		ID:         testID(5),
+		EdgeType:   "works_at",
+		SourceNode: anna.ID,
+		TargetNode: company.ID,
+		Properties: []byte(`{"since": "2019", "modality": "heltid"}`),
+	}
+	owns := &database.Edge{
+		ID:         testID(6),
+		EdgeType:   "äger \"andel\"",
+		SourceNode: company.ID,
+		TargetNode: anna.ID,
+		Properties: []byte(`{"share": 0.5}`),
+		IsNegated:  true,
+	}
+
+	return &Graph{
+		Project:    &database.Project{ID: testID(0), Title: projectName},
+		Nodes:      []*database.Node{anna, company},
+		Edges:      []*database.Edge{worksAt, owns},
+		Referenced: []*database.Node{document, witness},
+		Provenance: map[pgtype.UUID][]*database.Provenance{
+			anna.ID: {{
+				ID:               testID(7),
+				TargetType:       "node",
+				TargetID:         anna.ID,
+				SourceID:         document.ID,
+				Excerpt:          excerpt,
+				Location:         []byte(`{"page": 3}`),
+				Confidence:       0.8,
+				Trust:            0.9,
+				Status:           string(database.StatusApproved),
+				Modality:         database.ModalityAsserted,
+				ClaimedTimeStart: testTime("2021-03-01T00:00:00Z"),
+				ClaimedTimeText:  pgtype.Text{String: "våren 2021", Valid: true},
+				ClaimedBy:        witness.ID,
+				CreatedAt:        testTime("2024-05-06T07:08:09Z"),
+			}},
+			worksAt.ID: {
+				{
+					ID:               testID(8),
+					TargetType:       "edge",
+					TargetID:         worksAt.ID,
+					SourceID:         document.ID,
+					Excerpt:          "anställd sedan 2019",
+					Confidence:       0.6,
+					Trust:            1,
+					Status:           string(database.StatusPending),
+					Modality:         database.ModalityAsserted,
+					ClaimedGeoRegion: pgtype.Text{String: "SE-Y", Valid: true},
+					ClaimedGeoText:   pgtype.Text{String: "i Sundsvall & Härnösand", Valid: true},
+				},
+				// Rejected, so it loses to the pending record despite its
+				// higher confidence
+				{
+					ID:         testID(9),
+					TargetType: "edge",
+					TargetID:   worksAt.ID,
+					SourceID:   document.ID,
+					Confidence: 0.95,
+					Trust:      1,
+					Status:     string(database.StatusRejected),
+					Modality:   database.ModalityHypothetical,
+				},
+			},
+		},
+	}
+}
+
+// gexfDate is the export date GEXF writes, which changes daily
+var gexfDate = regexp.MustCompile(`lastmodifieddate="\d{4}-\d{2}-\d{2}"`)
+
+func TestFormatsGolden(t *testing.T) {
+	for _, name := range []string{"graphml", "gexf", "dot", "neo4j-csv", "jsonld", "turtle"} {
+		format := Formats[name]
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := format.Write(&buf, testGraph()); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			if name != "neo4j-csv" {
+				out := gexfDate.ReplaceAll(buf.Bytes(), []byte(`lastmodifieddate="2000-01-01"`))
+				golden(t, "graph"+format.Extension, out)
+				return
+			}
+
+			zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+			if err != nil {
+				t.Fatalf("zip.NewReader() error = %v", err)
+			}
+			if len(zr.File) != 2 {
+				t.Fatalf("zip holds %d files, want nodes.csv and relationships.csv", len(zr.File))
+			}
+			for _, f := range zr.File {
+				r, err := f.Open()
+				if err != nil {
+					t.Fatal(err)
+				}
+				data, err := io.ReadAll(r)
+				r.Close()
+				if err != nil {
+					t.Fatal(err)
+				}
+				golden(t, "graph-"+f.Name, data)
+			}
+		})
+	}
+}
+
+// golden compares got with testdata/name, or rewrites the file with -update
+func golden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.MkdirAll("testdata", 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run go test -update to create it)", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s differs from the golden file; run go test -update and review the diff\ngot:\n%s", name, got)
+	}
+}
+
+// TestXMLRoundTrip decodes the XML formats and checks the awkward values
+// read back unchanged
+func TestXMLRoundTrip(t *testing.T) {
+	for _, write := range []func(io.Writer, *Graph) error{WriteGraphML, WriteGEXF} {
+		var buf bytes.Buffer
+		if err := write(&buf, testGraph()); err != nil {
+			t.Fatal(err)
+		}
+		values := make(map[string]bool)
+		dec := xml.NewDecoder(&buf)
+		for {
+			tok, err := dec.Token()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("output is not well-formed XML: %v", err)
+			}
+			switch tok := tok.(type) {
+			case xml.StartElement:
+				for _, a := range tok.Attr {
+					values[a.Value] = true
+				}
+			case xml.CharData:
+				values[string(tok)] = true
+			}
+		}
+		for _, want := range []string{annaLabel, excerpt, projectName, "vd & ägare", "anställd sedan 2019"} {
+			if !values[want] {
+				t.Errorf("%q did not survive a round trip", want)
+			}
+		}
+	}
+}
+
+func TestJSONLDRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteJSONLD(&buf, testGraph()); err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Graph []map[string]any `json:"@graph"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("output is not JSON: %v", err)
+	}
+	labels := make(map[string]any)
+	for _, obj := range doc.Graph {
+		labels[obj["@id"].(string)] = obj["rdfs:label"]
+	}
+	if got := labels[resource(testID(1))]; got != annaLabel {
+		t.Errorf("label = %q, want %q", got, annaLabel)
+	}
+	if got := labels[resource(testID(0))]; got != projectName {
+		t.Errorf("project label = %q, want %q", got, projectName)
+	}
+}
+
+func TestLocalName(t *testing.T) {
+	tests := []struct {
+		in    string
+		class bool
+		want  string
+	}{
+		{"involved_in", false, "involvedIn"},
+		{"involved_in", true, "InvolvedIn"},
+		{"Works At", false, "worksAt"},
+		{"företag", true, "Företag"},
+		{"äger \"andel\"", false, "ägerAndel"},
+		{"ª-µ-º", false, "p"},
+		{"2021_report", false, "p2021Report"},
+		{"2021", true, "Type2021"},
+		{"", true, "Type"},
+	}
+	for _, tt := range tests {
+		if got := localName(tt.in, tt.class); got != tt.want {
+			t.Errorf("localName(%q, %v) = %q, want %q", tt.in, tt.class, got, tt.want)
+		}
+		if !plainName(localName(tt.in, tt.class)) {
+			t.Errorf("localName(%q, %v) is not a plain name", tt.in, tt.class)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package export
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+
+	"github.com/einarsundgren/sikta/internal/database"
+)
+
+type graphmlDoc struct {
+	XMLName xml.Name     `xml:"graphml"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	Keys    []graphmlKey `xml:"key"`
+	Graph   graphmlGraph `xml:"graph"`
+}
+
+type graphmlKey struct {
+	ID   string `xml:"id,attr"`
+	For  string `xml:"for,attr"`
+	Name string `xml:"attr.name,attr"`
+	Type string `xml:"attr.type,attr"`
+}
+
+type graphmlGraph struct {
+	ID          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Data        []graphmlData `xml:"data"`
+	Nodes       []graphmlNode `xml:"node"`
+	Edges       []graphmlEdge `xml:"edge"`
+}
+
+type graphmlNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphmlData `xml:"data"`
+}
+
+type graphmlEdge struct {
+	ID     string        `xml:"id,attr"`
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphmlData `xml:"data"`
+}
+
+type graphmlData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+// WriteGraphML writes the graph as GraphML, for yEd, Gephi and most graph
+// libraries. Node and edge attributes are GraphML keys.
+func WriteGraphML(w io.Writer, g *Graph) error {
+	nodes, edges := nodeTable(g), edgeTable(g)
+	doc := graphmlDoc{
+		Xmlns: "http://graphml.graphdrawing.org/xmlns",
+		Keys:  []graphmlKey{{ID: "title", For: "graph", Name: "title", Type: typeString}},
+		Graph: graphmlGraph{
+			ID:          database.UUIDStr(g.Project.ID),
+			EdgeDefault: "directed",
+			Data:        []graphmlData{{Key: "title", Value: g.Project.Title}},
+		},
+	}
+	for i, a := range nodes.Attributes {
+		doc.Keys = append(doc.Keys, graphmlKey{ID: fmt.Sprintf("n%d", i), For: "node", Name: a.Name, Type: a.Type})
+	}
+	for i, a := range edges.Attributes {
+		doc.Keys = append(doc.Keys, graphmlKey{ID: fmt.Sprintf("e%d", i), For: "edge", Name: a.Name, Type: a.Type})
+	}
+
+	for i, n := range g.Nodes {
+		doc.Graph.Nodes = append(doc.Graph.Nodes, graphmlNode{
+			ID:   database.UUIDStr(n.ID),
+			Data: graphmlValues("n", nodes.Attributes, nodes.Rows[i]),
+		})
+	}
+	for i, e := range g.Edges {
+		doc.Graph.Edges = append(doc.Graph.Edges, graphmlEdge{
+			ID:     database.UUIDStr(e.ID),
+			Source: database.UUIDStr(e.SourceNode),
+			Target: database.UUIDStr(e.TargetNode),
+			Data:   graphmlValues("e", edges.Attributes, edges.Rows[i]),
+		})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func graphmlValues(prefix string, attributes []attribute, row map[string]any) []graphmlData {
+	var data []graphmlData
+	for i, a := range attributes {
+		if v, ok := row[a.Name]; ok {
+			data = append(data, graphmlData{Key: fmt.Sprintf("%s%d", prefix, i), Value: formatValue(v)})
+		}
+	}
+	return data
+}
@@ -0,0 +1,73 @@
+package export
+
+import (
+	"encoding/json"
+	"io"
+	"strconv"
+)
+
+// WriteJSONLD writes the graph as JSON-LD, one object per resource in
+// @graph, with the same triples as WriteTurtle
+func WriteJSONLD(w io.Writer, g *Graph) error {
+	context := make(map[string]string, len(rdfPrefixes))
+	for _, p := range rdfPrefixes {
+		context[p.Prefix] = p.IRI
+	}
+
+	var objects []map[string]any
+	for _, s := range groupTriples(rdfTriples(g)) {
+		obj := map[string]any{"@id": jsonldIRI(s.IRI)}
+		for _, p := range s.Predicates {
+			var values []any
+			key := jsonldIRI(p)
+			if p == nsRDF+"type" {
+				key = "@type"
+				for _, o := range s.Objects[p] {
+					values = append(values, jsonldIRI(o.IRI))
+				}
+			} else {
+				for _, o := range s.Objects[p] {
+					values = append(values, jsonldValue(o))
+				}
+			}
+			if len(values) == 1 {
+				obj[key] = values[0]
+			} else {
+				obj[key] = values
+			}
+		}
+		objects = append(objects, obj)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(map[string]any{"@context": context, "@graph": objects})
+}
+
+func jsonldIRI(s string) string {
+	if name, ok := compact(s); ok {
+		return name
+	}
+	return s
+}
+
+// jsonldValue renders an object as a JSON-LD value: a node reference, a
+// native string or boolean, inline JSON, or a typed value
+func jsonldValue(t term) any {
+	switch {
+	case t.IRI != "":
+		return map[string]string{"@id": jsonldIRI(t.IRI)}
+	case t.Datatype == "":
+		return t.Value
+	case t.Datatype == nsXSD+"boolean":
+		return t.Value == "true"
+	case t.Datatype == nsRDF+"JSON":
+		return map[string]any{"@value": json.RawMessage(t.Value), "@type": "@json"}
+	case t.Datatype == nsXSD+"double":
+		if f, err := strconv.ParseFloat(t.Value, 64); err == nil {
+			return map[string]any{"@value": f, "@type": "xsd:double"}
+		}
+	}
+	return map[string]string{"@value": t.Value, "@type": jsonldIRI(t.Datatype)}
+}
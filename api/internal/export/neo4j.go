@@ -0,0 +1,81 @@
+package export
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"io"
+
+	"github.com/einarsundgren/sikta/internal/database"
+)
+
+// neo4jTypes maps attribute types to neo4j-admin import header types
+var neo4jTypes = map[string]string{
+	typeString:  "",
+	typeDouble:  ":double",
+	typeBoolean: ":boolean",
+	typeInt:     ":int",
+}
+
+// WriteNeo4jCSV writes the graph as a zip of nodes.csv and
+// relationships.csv with neo4j-admin import headers:
+//
+//	neo4j-admin database import full --multiline-fields=true \
+//		--nodes=nodes.csv --relationships=relationships.csv
+//
+// A node's type is its label and an edge's type its relationship type.
+func WriteNeo4jCSV(w io.Writer, g *Graph) error {
+	nodes, edges := nodeTable(g), edgeTable(g)
+	zw := zip.NewWriter(w)
+
+	f, err := zw.Create("nodes.csv")
+	if err != nil {
+		return err
+	}
+	cw := csv.NewWriter(f)
+	cw.Write(append(append([]string{"id:ID"}, neo4jHeader(nodes.Attributes)...), ":LABEL"))
+	for i, n := range g.Nodes {
+		record := append([]string{database.UUIDStr(n.ID)}, neo4jValues(nodes.Attributes, nodes.Rows[i])...)
+		cw.Write(append(record, n.NodeType))
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return err
+	}
+
+	f, err = zw.Create("relationships.csv")
+	if err != nil {
+		return err
+	}
+	cw = csv.NewWriter(f)
+	cw.Write(append([]string{":START_ID", ":END_ID", ":TYPE", "id"}, neo4jHeader(edges.Attributes)...))
+	for i, e := range g.Edges {
+		record := []string{database.UUIDStr(e.SourceNode), database.UUIDStr(e.TargetNode), e.EdgeType, database.UUIDStr(e.ID)}
+		cw.Write(append(record, neo4jValues(edges.Attributes, edges.Rows[i])...))
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
+
+func neo4jHeader(attributes []attribute) []string {
+	header := make([]string, len(attributes))
+	for i, a := range attributes {
+		header[i] = a.Name + neo4jTypes[a.Type]
+	}
+	return header
+}
+
+// neo4jValues renders a row; missing values are empty, which neo4j-admin
+// imports as no property
+func neo4jValues(attributes []attribute, row map[string]any) []string {
+	values := make([]string, len(attributes))
+	for i, a := range attributes {
+		if v, ok := row[a.Name]; ok {
+			values[i] = formatValue(v)
+		}
+	}
+	return values
+}
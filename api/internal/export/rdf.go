@@ -0,0 +1,312 @@
+package export
+
+import (
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/einarsundgren/sikta/internal/database"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Namespaces used by the linked-data formats
+const (
+	nsRDF   = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	nsRDFS  = "http://www.w3.org/2000/01/rdf-schema#"
+	nsXSD   = "http://www.w3.org/2001/XMLSchema#"
+	nsPROV  = "http://www.w3.org/ns/prov#"
+	nsSikta = "urn:sikta:vocab:"
+)
+
+// rdfPrefixes are the prefixes written in Turtle and the JSON-LD context
+var rdfPrefixes = []struct{ Prefix, IRI string }{
+	{"rdf", nsRDF},
+	{"rdfs", nsRDFS},
+	{"xsd", nsXSD},
+	{"prov", nsPROV},
+	{"sikta", nsSikta},
+}
+
+// term is an RDF resource or literal
+type term struct {
+	IRI      string // set for resources
+	Value    string // lexical form of a literal
+	Datatype string // literal datatype; empty for plain strings
+}
+
+type triple struct {
+	Subject   string
+	Predicate string
+	Object    term
+}
+
+func iri(s string) term { return term{IRI: s} }
+
+func literal(v, datatype string) term { return term{Value: v, Datatype: datatype} }
+
+func resource(id pgtype.UUID) string { return "urn:uuid:" + database.UUIDStr(id) }
+
+// reserved are vocabulary terms the exporter uses itself; a property with
+// one of these names gets a "property" prefix
+var reserved = map[string]bool{
+	"nodeType": true, "edgeType": true, "negated": true, "target": true,
+	"modality": true, "confidence": true, "trust": true, "status": true, "excerpt": true,
+	"location": true, "claimedTimeStart": true, "claimedTimeEnd": true, "claimedTimeText": true,
+	"claimedGeoRegion": true, "claimedGeoText": true,
+}
+
+// rdfTriples maps the graph to RDF. Nodes are resources typed by node type;
+// an edge is a direct triple between its nodes, left out if negated, plus a
+// reified rdf:Statement carrying its type, negation and properties. Every
+// provenance record is a PROV-O entity reifying what it claims: derived
+// from its document, attributed to its claimant, and annotated with
+// modality, confidence, trust, review status and the claimed time and place.
+func rdfTriples(g *Graph) []triple {
+	var ts []triple
+	add := func(s, p string, o term) { ts = append(ts, triple{s, p, o}) }
+
+	project := resource(g.Project.ID)
+	add(project, nsRDF+"type", iri(nsSikta+"Project"))
+	add(project, nsRDFS+"label", literal(g.Project.Title, ""))
+
+	documents := make(map[pgtype.UUID]bool)
+	agents := make(map[pgtype.UUID]bool)
+	for _, records := range g.Provenance {
+		for _, p := range records {
+			documents[p.SourceID] = true
+			if p.ClaimedBy.Valid {
+				agents[p.ClaimedBy] = true
+			}
+		}
+	}
+
+	classes := make(map[pgtype.UUID]string)
+	for _, nodes := range [][]*database.Node{g.Nodes, g.Referenced} {
+		for _, n := range nodes {
+			s := resource(n.ID)
+			classes[n.ID] = nsSikta + localName(n.NodeType, true)
+			add(s, nsRDF+"type", iri(classes[n.ID]))
+			if documents[n.ID] {
+				add(s, nsRDF+"type", iri(nsPROV+"Entity"))
+			}
+			if agents[n.ID] {
+				add(s, nsRDF+"type", iri(nsPROV+"Agent"))
+			}
+			add(s, nsRDFS+"label", literal(n.Label, ""))
+			add(s, nsSikta+"nodeType", literal(n.NodeType, ""))
+			addProperties(add, s, n.Properties)
+		}
+	}
+
+	edges := make(map[pgtype.UUID]*database.Edge, len(g.Edges))
+	for _, e := range g.Edges {
+		edges[e.ID] = e
+		predicate := nsSikta + localName(e.EdgeType, false)
+		if !e.IsNegated {
+			add(resource(e.SourceNode), predicate, iri(resource(e.TargetNode)))
+		}
+		s := resource(e.ID)
+		add(s, nsRDF+"type", iri(nsRDF+"Statement"))
+		add(s, nsRDF+"subject", iri(resource(e.SourceNode)))
+		add(s, nsRDF+"predicate", iri(predicate))
+		add(s, nsRDF+"object", iri(resource(e.TargetNode)))
+		add(s, nsSikta+"edgeType", literal(e.EdgeType, ""))
+		add(s, nsSikta+"negated", boolean(e.IsNegated))
+		addProperties(add, s, e.Properties)
+	}
+
+	// Records follow the order of their nodes and edges
+	var targets []pgtype.UUID
+	for _, n := range g.Nodes {
+		targets = append(targets, n.ID)
+	}
+	for _, e := range g.Edges {
+		targets = append(targets, e.ID)
+	}
+	for _, target := range targets {
+		for _, p := range g.Provenance[target] {
+			s := resource(p.ID)
+			add(s, nsRDF+"type", iri(nsRDF+"Statement"))
+			add(s, nsRDF+"type", iri(nsPROV+"Entity"))
+			if e, ok := edges[target]; ok {
+				add(s, nsRDF+"subject", iri(resource(e.SourceNode)))
+				add(s, nsRDF+"predicate", iri(nsSikta+localName(e.EdgeType, false)))
+				add(s, nsRDF+"object", iri(resource(e.TargetNode)))
+			} else {
+				add(s, nsRDF+"subject", iri(resource(target)))
+				add(s, nsRDF+"predicate", iri(nsRDF+"type"))
+				add(s, nsRDF+"object", iri(classes[target]))
+			}
+			add(s, nsSikta+"target", iri(resource(target)))
+			add(s, nsPROV+"wasDerivedFrom", iri(resource(p.SourceID)))
+			if p.ClaimedBy.Valid {
+				add(s, nsPROV+"wasAttributedTo", iri(resource(p.ClaimedBy)))
+			}
+			if p.CreatedAt.Valid {
+				add(s, nsPROV+"generatedAtTime", dateTime(p.CreatedAt.Time))
+			}
+			add(s, nsSikta+"modality", literal(p.Modality, ""))
+			add(s, nsSikta+"confidence", double(widen(p.Confidence)))
+			add(s, nsSikta+"trust", double(widen(p.Trust)))
+			add(s, nsSikta+"status", literal(p.Status, ""))
+			if p.Excerpt != "" {
+				add(s, nsSikta+"excerpt", literal(p.Excerpt, ""))
+			}
+			if loc := strings.TrimSpace(string(p.Location)); loc != "" && loc != "null" && loc != "{}" {
+				add(s, nsSikta+"location", literal(loc, nsRDF+"JSON"))
+			}
+			if p.ClaimedTimeStart.Valid {
+				add(s, nsSikta+"claimedTimeStart", dateTime(p.ClaimedTimeStart.Time))
+			}
+			if p.ClaimedTimeEnd.Valid {
+				add(s, nsSikta+"claimedTimeEnd", dateTime(p.ClaimedTimeEnd.Time))
+			}
+			for _, text := range []struct {
+				name  string
+				value pgtype.Text
+			}{
+				{"claimedTimeText", p.ClaimedTimeText},
+				{"claimedGeoRegion", p.ClaimedGeoRegion},
+				{"claimedGeoText", p.ClaimedGeoText},
+			} {
+				if text.value.Valid && text.value.String != "" {
+					add(s, nsSikta+text.name, literal(text.value.String, ""))
+				}
+			}
+		}
+	}
+	return ts
+}
+
+// addProperties adds a JSONB properties column as sikta: values. Arrays
+// become one value per element; objects and nested arrays become rdf:JSON.
+func addProperties(add func(s, p string, o term), s string, data []byte) {
+	props := properties(data)
+	keys := make([]string, 0, len(props))
+	for k := range props {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		name := localName(k, false)
+		if reserved[name] {
+			name = localName("property_"+k, false)
+		}
+		values, ok := props[k].([]any)
+		if !ok {
+			values = []any{props[k]}
+		}
+		for _, v := range values {
+			if v != nil {
+				add(s, nsSikta+name, propertyTerm(v))
+			}
+		}
+	}
+}
+
+func propertyTerm(v any) term {
+	switch v := v.(type) {
+	case string:
+		return literal(v, "")
+	case float64:
+		return double(v)
+	case bool:
+		return boolean(v)
+	}
+	return literal(jsonText(v), nsRDF+"JSON")
+}
+
+func double(f float64) term { return literal(formatValue(f), nsXSD+"double") }
+
+func boolean(b bool) term { return literal(formatValue(b), nsXSD+"boolean") }
+
+func dateTime(t time.Time) term { return literal(t.UTC().Format(time.RFC3339Nano), nsXSD+"dateTime") }
+
+// localName turns a node type, edge type or property key into a vocabulary
+// name: PascalCase for classes, camelCase otherwise, keeping only letters
+// and digits, so "involved_in" becomes involvedIn and the class of
+// "företag" Företag
+func localName(s string, class bool) string {
+	var b strings.Builder
+	upper := class
+	for _, r := range s {
+		if !nameRune(r) {
+			upper = b.Len() > 0 || class
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+		} else if b.Len() == 0 {
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+		upper = false
+	}
+	name := b.String()
+	if name == "" || unicode.IsDigit(rune(name[0])) {
+		if class {
+			return "Type" + name
+		}
+		return "p" + name
+	}
+	return name
+}
+
+// compact shortens an IRI to prefix:local where the local part is a plain
+// name, else returns ok false
+func compact(s string) (string, bool) {
+	for _, p := range rdfPrefixes {
+		local, found := strings.CutPrefix(s, p.IRI)
+		if found && plainName(local) {
+			return p.Prefix + ":" + local, true
+		}
+	}
+	return "", false
+}
+
+func plainName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r := range s {
+		if !nameRune(r) && r != '_' || i == 0 && r >= '0' && r <= '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// nameRune reports whether r may appear in a local name: an ASCII letter or
+// digit, or a letter from U+00C0 on, which Turtle's prefixed names allow
+func nameRune(r rune) bool {
+	if r <= unicode.MaxASCII {
+		return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9'
+	}
+	return r >= 0xC0 && unicode.IsLetter(r)
+}
+
+// rdfSubject is a subject's triples grouped by predicate, in first-seen order
+type rdfSubject struct {
+	IRI        string
+	Predicates []string
+	Objects    map[string][]term
+}
+
+func groupTriples(ts []triple) []*rdfSubject {
+	var subjects []*rdfSubject
+	bySubject := make(map[string]*rdfSubject)
+	for _, t := range ts {
+		s, ok := bySubject[t.Subject]
+		if !ok {
+			s = &rdfSubject{IRI: t.Subject, Objects: make(map[string][]term)}
+			bySubject[t.Subject] = s
+			subjects = append(subjects, s)
+		}
+		if _, ok := s.Objects[t.Predicate]; !ok {
+			s.Predicates = append(s.Predicates, t.Predicate)
+		}
+		s.Objects[t.Predicate] = append(s.Objects[t.Predicate], t.Object)
+	}
+	return subjects
+}
@@ -0,0 +1,63 @@
+package export
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// WriteTurtle writes the graph as RDF Turtle. See rdfTriples for how nodes,
+// edges and provenance map to RDF.
+func WriteTurtle(w io.Writer, g *Graph) error {
+	bw := bufio.NewWriter(w)
+	for _, p := range rdfPrefixes {
+		bw.WriteString("@prefix " + p.Prefix + ": <" + p.IRI + "> .\n")
+	}
+
+	for _, s := range groupTriples(rdfTriples(g)) {
+		bw.WriteString("\n" + turtleIRI(s.IRI))
+		for i, p := range s.Predicates {
+			if i > 0 {
+				bw.WriteString(" ;")
+			}
+			predicate := turtleIRI(p)
+			if p == nsRDF+"type" {
+				predicate = "a"
+			}
+			bw.WriteString("\n    " + predicate + " ")
+			for j, o := range s.Objects[p] {
+				if j > 0 {
+					bw.WriteString(", ")
+				}
+				bw.WriteString(turtleTerm(o))
+			}
+		}
+		bw.WriteString(" .\n")
+	}
+	return bw.Flush()
+}
+
+func turtleIRI(s string) string {
+	if name, ok := compact(s); ok {
+		return name
+	}
+	return "<" + s + ">"
+}
+
+func turtleTerm(t term) string {
+	switch {
+	case t.IRI != "":
+		return turtleIRI(t.IRI)
+	case t.Datatype == nsXSD+"boolean":
+		return t.Value
+	case t.Datatype != "":
+		return turtleString(t.Value) + "^^" + turtleIRI(t.Datatype)
+	}
+	return turtleString(t.Value)
+}
+
+// turtleString returns s as a Turtle quoted string
+func turtleString(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+	return `"` + r.Replace(s) + `"`
+}
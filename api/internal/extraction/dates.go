@@ -13,6 +13,12 @@ type DateNormalizer struct {
 	ReferenceTime time.Time
 }
 
+// ReferenceDateNote tells the model when the text was written. Both
+// extraction prompts include it for chunks with a reference date.
+func ReferenceDateNote(ref time.Time) string {
+	return fmt.Sprintf("REFERENCE DATE: This text was written on %s. Resolve relative times (\"yesterday\", \"last week\") against this date.", ref.Format("2006-01-02"))
+}
+
 // NewDateNormalizer creates a new date normalizer.
 func NewDateNormalizer() *DateNormalizer {
 	return &DateNormalizer{
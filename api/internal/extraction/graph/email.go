@@ -0,0 +1,287 @@
+package extraction
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/einarsundgren/sikta/internal/database"
+	"github.com/einarsundgren/sikta/internal/document"
+	"github.com/einarsundgren/sikta/internal/graph"
+	"github.com/google/uuid"
+)
+
+// emailMetadata is the chunk metadata written for EML/MBOX messages.
+type emailMetadata struct {
+	MessageID  string                  `json:"message_id"`
+	Subject    string                  `json:"subject"`
+	From       document.EmailAddress   `json:"from"`
+	To         []document.EmailAddress `json:"to"`
+	Cc         []document.EmailAddress `json:"cc"`
+	Date       string                  `json:"date"`
+	InReplyTo  string                  `json:"in_reply_to"`
+	References []string                `json:"references"`
+	ThreadID   string                  `json:"thread_id"`
+}
+
+// parent returns the Message-ID this message replies to.
+func (m emailMetadata) parent() string {
+	if m.InReplyTo != "" {
+		return m.InReplyTo
+	}
+	if len(m.References) > 0 {
+		return m.References[len(m.References)-1]
+	}
+	return ""
+}
+
+// emailGraph tracks nodes created from email headers within one source.
+type emailGraph struct {
+	messages map[string]uuid.UUID // Message-ID → message node
+	people   map[string]uuid.UUID // address → person node
+}
+
+// storeEmailStructure creates message and person nodes from email headers,
+// linked by sent_by/sent_to edges, and replies_to edges between messages
+// that are part of the same thread. Header facts need no LLM, so they are
+// stored with full confidence. Person labels are added to entityLabelToID so
+// extracted edges can attach to correspondents.
+func (s *GraphService) storeEmailStructure(ctx context.Context, chunks []*database.Chunk, docNodeID uuid.UUID, entityLabelToID map[string]uuid.UUID) error {
+	eg := &emailGraph{
+		messages: make(map[string]uuid.UUID),
+		people:   make(map[string]uuid.UUID),
+	}
+
+	type storedMessage struct {
+		nodeID uuid.UUID
+		meta   emailMetadata
+		chunk  *database.Chunk
+	}
+	var stored []storedMessage
+
+	for _, chunk := range chunks {
+		meta, ok := chunkEmailMetadata(chunk)
+		if !ok {
+			continue
+		}
+
+		msgID, err := s.storeMessageNode(ctx, chunk, meta, docNodeID)
+		if err != nil {
+			return fmt.Errorf("failed to store message %s: %w", meta.MessageID, err)
+		}
+		if meta.MessageID != "" {
+			eg.messages[meta.MessageID] = msgID
+		}
+		stored = append(stored, storedMessage{nodeID: msgID, meta: meta, chunk: chunk})
+
+		if meta.From.Email != "" {
+			personID, err := s.emailPerson(ctx, eg, meta.From, chunk, docNodeID, "From")
+			if err != nil {
+				return err
+			}
+			entityLabelToID[meta.From.Label()] = personID
+			if err := s.storeHeaderEdge(ctx, database.EdgeTypeSentBy, msgID, personID, nil, chunk, docNodeID, "From: "+meta.From.Email); err != nil {
+				return err
+			}
+		}
+
+		recipients := []struct {
+			header string
+			addrs  []document.EmailAddress
+		}{{"To", meta.To}, {"Cc", meta.Cc}}
+		for _, r := range recipients {
+			header := r.header
+			for _, addr := range r.addrs {
+				if addr.Email == "" {
+					continue
+				}
+				personID, err := s.emailPerson(ctx, eg, addr, chunk, docNodeID, header)
+				if err != nil {
+					return err
+				}
+				entityLabelToID[addr.Label()] = personID
+				props := map[string]interface{}{"field": strings.ToLower(header)}
+				if err := s.storeHeaderEdge(ctx, database.EdgeTypeSentTo, msgID, personID, props, chunk, docNodeID, header+": "+addr.Email); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	// Thread edges are added once every message has a node.
+	for _, m := range stored {
+		parent := m.meta.parent()
+		parentID, ok := eg.messages[parent]
+		if !ok || parentID == m.nodeID {
+			continue
+		}
+		props := map[string]interface{}{}
+		if m.meta.ThreadID != "" {
+			props["thread_id"] = m.meta.ThreadID
+		}
+		if err := s.storeHeaderEdge(ctx, database.EdgeTypeRepliesTo, m.nodeID, parentID, props, m.chunk, docNodeID, "In-Reply-To: "+parent); err != nil {
+			return err
+		}
+	}
+
+	if len(stored) > 0 {
+		s.logger.Info("stored email structure",
+			"messages", len(stored),
+			"people", len(eg.people))
+	}
+
+	return nil
+}
+
+// storeMessageNode creates the node for one email message. The message date
+// is the node's claimed time.
+func (s *GraphService) storeMessageNode(ctx context.Context, chunk *database.Chunk, meta emailMetadata, docNodeID uuid.UUID) (uuid.UUID, error) {
+	label := meta.Subject
+	if label == "" {
+		label = "(no subject)"
+	}
+
+	props := map[string]interface{}{
+		"message_id": meta.MessageID,
+		"subject":    meta.Subject,
+	}
+	if meta.ThreadID != "" {
+		props["thread_id"] = meta.ThreadID
+	}
+	if meta.Date != "" {
+		props["date"] = meta.Date
+	}
+
+	nodeID, err := s.graph.CreateNode(ctx, graph.CreateNodeParams{
+		NodeType:   database.NodeTypeMessage,
+		Label:      label,
+		Properties: props,
+	})
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	var claimedStart *time.Time
+	if t, ok := chunk.ReferenceDate(); ok {
+		claimedStart = &t
+	}
+
+	_, err = s.graph.CreateProvenance(ctx, graph.CreateProvenanceParams{
+		TargetType:        "node",
+		TargetID:          nodeID,
+		SourceID:          docNodeID,
+		Excerpt:           "Subject: " + meta.Subject,
+		Location:          emailLocation(chunk),
+		Confidence:        1.0,
+		Trust:             1.0,
+		Modality:          database.ModalityAsserted,
+		Status:            database.StatusPending,
+		ClaimedTimeStart:  claimedStart,
+		ClaimedTimeText:   meta.Date,
+		ClaimedProperties: props,
+	})
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	return nodeID, nil
+}
+
+// emailPerson returns the person node for an address, creating it on first
+// sight. Each further appearance adds provenance.
+func (s *GraphService) emailPerson(ctx context.Context, eg *emailGraph, addr document.EmailAddress, chunk *database.Chunk, docNodeID uuid.UUID, header string) (uuid.UUID, error) {
+	props := map[string]interface{}{"email": addr.Email}
+	if addr.Name != "" {
+		props["name"] = addr.Name
+	}
+	personID, ok := eg.people[addr.Email]
+	if !ok {
+		var err error
+		personID, err = s.graph.CreateNode(ctx, graph.CreateNodeParams{
+			NodeType:   database.NodeTypePerson,
+			Label:      addr.Label(),
+			Properties: props,
+		})
+		if err != nil {
+			return uuid.Nil, fmt.Errorf("failed to store person %s: %w", addr.Email, err)
+		}
+		eg.people[addr.Email] = personID
+	}
+
+	excerpt := fmt.Sprintf("%s: %s", header, addr.Email)
+	if addr.Name != "" {
+		excerpt = fmt.Sprintf("%s: %s <%s>", header, addr.Name, addr.Email)
+	}
+
+	_, err := s.graph.CreateProvenance(ctx, graph.CreateProvenanceParams{
+		TargetType:        "node",
+		TargetID:          personID,
+		SourceID:          docNodeID,
+		Excerpt:           excerpt,
+		Location:          emailLocation(chunk),
+		Confidence:        1.0,
+		Trust:             1.0,
+		Modality:          database.ModalityAsserted,
+		Status:            database.StatusPending,
+		ClaimedProperties: props,
+	})
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to store provenance for %s: %w", addr.Email, err)
+	}
+
+	return personID, nil
+}
+
+// storeHeaderEdge creates an edge backed by an email header.
+func (s *GraphService) storeHeaderEdge(ctx context.Context, edgeType string, from, to uuid.UUID, props map[string]interface{}, chunk *database.Chunk, docNodeID uuid.UUID, excerpt string) error {
+	edgeID, err := s.graph.CreateEdge(ctx, graph.CreateEdgeParams{
+		EdgeType:   edgeType,
+		SourceNode: from,
+		TargetNode: to,
+		Properties: props,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store %s edge: %w", edgeType, err)
+	}
+
+	_, err = s.graph.CreateProvenance(ctx, graph.CreateProvenanceParams{
+		TargetType:        "edge",
+		TargetID:          edgeID,
+		SourceID:          docNodeID,
+		Excerpt:           excerpt,
+		Location:          emailLocation(chunk),
+		Confidence:        1.0,
+		Trust:             1.0,
+		Modality:          database.ModalityAsserted,
+		Status:            database.StatusPending,
+		ClaimedProperties: props,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store provenance for %s edge: %w", edgeType, err)
+	}
+	return nil
+}
+
+// chunkEmailMetadata decodes email headers from chunk metadata.
+func chunkEmailMetadata(chunk *database.Chunk) (emailMetadata, bool) {
+	var meta emailMetadata
+	if chunk.Metadata == nil {
+		return meta, false
+	}
+	if err := json.Unmarshal(chunk.Metadata, &meta); err != nil {
+		return meta, false
+	}
+	if meta.MessageID == "" && meta.From.Email == "" {
+		return meta, false
+	}
+	return meta, true
+}
+
+func emailLocation(chunk *database.Chunk) database.Location {
+	return database.Location{
+		Chapter: chunk.ChapterTitle.String,
+		Section: chunk.SectionID.String,
+	}
+}
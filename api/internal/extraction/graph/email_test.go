@@ -0,0 +1,231 @@
+package extraction
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"slices"
+	"sort"
+	"testing"
+
+	"github.com/einarsundgren/sikta/internal/database"
+	"github.com/einarsundgren/sikta/internal/graph"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// fakeGraph records what extraction stores, numbering IDs in order
+type fakeGraph struct {
+	nodes      map[uuid.UUID]graph.CreateNodeParams
+	edges      []graph.CreateEdgeParams
+	provenance []graph.CreateProvenanceParams
+	next       byte
+}
+
+func newFakeGraph() *fakeGraph {
+	return &fakeGraph{nodes: make(map[uuid.UUID]graph.CreateNodeParams)}
+}
+
+func (f *fakeGraph) id() uuid.UUID {
+	f.next++
+	return uuid.UUID{15: f.next}
+}
+
+func (f *fakeGraph) CreateNode(_ context.Context, params graph.CreateNodeParams) (uuid.UUID, error) {
+	id := f.id()
+	f.nodes[id] = params
+	return id, nil
+}
+
+func (f *fakeGraph) CreateEdge(_ context.Context, params graph.CreateEdgeParams) (uuid.UUID, error) {
+	f.edges = append(f.edges, params)
+	return f.id(), nil
+}
+
+func (f *fakeGraph) CreateProvenance(_ context.Context, params graph.CreateProvenanceParams) (uuid.UUID, error) {
+	f.provenance = append(f.provenance, params)
+	return f.id(), nil
+}
+
+// label returns the label of a stored node
+func (f *fakeGraph) label(id uuid.UUID) string {
+	return f.nodes[id].Label
+}
+
+// edgesOf returns "source -> target" for each edge of a type, by label
+func (f *fakeGraph) edgesOf(edgeType string) []string {
+	var result []string
+	for _, e := range f.edges {
+		if e.EdgeType == edgeType {
+			result = append(result, f.label(e.SourceNode)+" -> "+f.label(e.TargetNode))
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// emailChunk is a chunk with email metadata as document.EmailChunks writes it
+func emailChunk(t *testing.T, meta map[string]any) *database.Chunk {
+	t.Helper()
+	data, err := json.Marshal(meta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	subject, _ := meta["subject"].(string)
+	return &database.Chunk{
+		Metadata:     data,
+		ChapterTitle: pgtype.Text{String: subject, Valid: true},
+	}
+}
+
+func storeEmails(t *testing.T, chunks ...*database.Chunk) (*fakeGraph, map[string]uuid.UUID) {
+	t.Helper()
+	g := newFakeGraph()
+	s := &GraphService{graph: g, logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	labels := make(map[string]uuid.UUID)
+	if err := s.storeEmailStructure(context.Background(), chunks, uuid.UUID{0: 1}, labels); err != nil {
+		t.Fatalf("storeEmailStructure() error = %v", err)
+	}
+	return g, labels
+}
+
+func TestStoreEmailStructureThreading(t *testing.T) {
+	anna := map[string]string{"name": "Anna Berg", "email": "anna@example.se"}
+	tests := []struct {
+		name    string
+		chunks  []map[string]any
+		replies []string
+	}{
+		{
+			name: "in-reply-to",
+			chunks: []map[string]any{
+				{"message_id": "<1@x>", "subject": "Avtal", "from": anna},
+				{"message_id": "<2@x>", "subject": "Re: Avtal", "from": anna, "in_reply_to": "<1@x>"},
+			},
+			replies: []string{"Re: Avtal -> Avtal"},
+		},
+		{
+			name: "in-reply-to wins over references",
+			chunks: []map[string]any{
+				{"message_id": "<1@x>", "subject": "Avtal", "from": anna},
+				{"message_id": "<2@x>", "subject": "Re: Avtal", "from": anna},
+				{"message_id": "<3@x>", "subject": "Re: Re: Avtal", "from": anna,
+					"in_reply_to": "<1@x>", "references": []string{"<1@x>", "<2@x>"}},
+			},
+			replies: []string{"Re: Re: Avtal -> Avtal"},
+		},
+		{
+			name: "last reference without in-reply-to",
+			chunks: []map[string]any{
+				{"message_id": "<1@x>", "subject": "Avtal", "from": anna},
+				{"message_id": "<2@x>", "subject": "Re: Avtal", "from": anna},
+				{"message_id": "<3@x>", "subject": "Re: Re: Avtal", "from": anna,
+					"references": []string{"<1@x>", "<2@x>"}},
+			},
+			replies: []string{"Re: Re: Avtal -> Re: Avtal"},
+		},
+		{
+			name: "reply stored before its parent",
+			chunks: []map[string]any{
+				{"message_id": "<2@x>", "subject": "Re: Avtal", "from": anna, "in_reply_to": "<1@x>"},
+				{"message_id": "<1@x>", "subject": "Avtal", "from": anna},
+			},
+			replies: []string{"Re: Avtal -> Avtal"},
+		},
+		{
+			name: "message replying to itself",
+			chunks: []map[string]any{
+				{"message_id": "<1@x>", "subject": "Avtal", "from": anna, "in_reply_to": "<1@x>"},
+			},
+		},
+		{
+			name: "parent outside the mailbox",
+			chunks: []map[string]any{
+				{"message_id": "<2@x>", "subject": "Re: Avtal", "from": anna, "in_reply_to": "<1@x>"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var chunks []*database.Chunk
+			for _, meta := range tt.chunks {
+				chunks = append(chunks, emailChunk(t, meta))
+			}
+			g, _ := storeEmails(t, chunks...)
+			got := g.edgesOf(database.EdgeTypeRepliesTo)
+			if len(got) != len(tt.replies) {
+				t.Fatalf("replies_to = %q, want %q", got, tt.replies)
+			}
+			for i := range got {
+				if got[i] != tt.replies[i] {
+					t.Errorf("replies_to[%d] = %q, want %q", i, got[i], tt.replies[i])
+				}
+			}
+		})
+	}
+}
+
+func TestStoreEmailStructureParticipants(t *testing.T) {
+	anna := map[string]string{"name": "Anna Berg", "email": "anna@example.se"}
+	jonas := map[string]string{"email": "jonas@example.se"}
+	legal := map[string]string{"name": "Juristen", "email": "legal@example.se"}
+
+	g, labels := storeEmails(t,
+		emailChunk(t, map[string]any{
+			"message_id": "<1@x>", "subject": "Avtal", "from": anna,
+			"to": []any{jonas}, "cc": []any{legal}, "thread_id": "<1@x>",
+		}),
+		emailChunk(t, map[string]any{
+			"message_id": "<2@x>", "subject": "Re: Avtal", "from": jonas,
+			"to": []any{anna, map[string]string{"email": ""}}, "in_reply_to": "<1@x>", "thread_id": "<1@x>",
+		}),
+		emailChunk(t, map[string]any{"subject": "not an email"}),
+	)
+
+	var messages, people int
+	for _, n := range g.nodes {
+		switch n.NodeType {
+		case database.NodeTypeMessage:
+			messages++
+		case database.NodeTypePerson:
+			people++
+		}
+	}
+	if messages != 2 || people != 3 {
+		t.Errorf("stored %d messages and %d people, want 2 and 3", messages, people)
+	}
+
+	if got, want := g.edgesOf(database.EdgeTypeSentBy), []string{"Avtal -> Anna Berg", "Re: Avtal -> jonas@example.se"}; !slices.Equal(got, want) {
+		t.Errorf("sent_by = %q, want %q", got, want)
+	}
+	if got, want := g.edgesOf(database.EdgeTypeSentTo), []string{"Avtal -> Juristen", "Avtal -> jonas@example.se", "Re: Avtal -> Anna Berg"}; !slices.Equal(got, want) {
+		t.Errorf("sent_to = %q, want %q", got, want)
+	}
+	for _, e := range g.edges {
+		if e.EdgeType == database.EdgeTypeSentTo && g.label(e.TargetNode) == "Juristen" && e.Properties["field"] != "cc" {
+			t.Errorf("cc edge field = %v, want cc", e.Properties["field"])
+		}
+		if e.EdgeType == database.EdgeTypeRepliesTo && e.Properties["thread_id"] != "<1@x>" {
+			t.Errorf("replies_to thread_id = %v, want <1@x>", e.Properties["thread_id"])
+		}
+	}
+
+	// Each appearance of a person adds provenance to the same node
+	annaID := labels["Anna Berg"]
+	var annaRecords int
+	for _, p := range g.provenance {
+		if p.TargetType == "node" && p.TargetID == annaID {
+			annaRecords++
+		}
+	}
+	if annaRecords != 2 {
+		t.Errorf("Anna has %d provenance records, want 2", annaRecords)
+	}
+	for _, label := range []string{"Anna Berg", "jonas@example.se", "Juristen"} {
+		if _, ok := labels[label]; !ok {
+			t.Errorf("entityLabelToID has no %q", label)
+		}
+	}
+}
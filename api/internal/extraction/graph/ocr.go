@@ -0,0 +1,56 @@
+package extraction
+
+import (
+	"encoding/json"
+	"strings"
+	"unicode"
+
+	"github.com/einarsundgren/sikta/internal/database"
+	"github.com/einarsundgren/sikta/internal/document"
+)
+
+// ocrTrust returns the trust of a claim read on the given page of a chunk.
+// Pages read by OCR pass on their mean word confidence, lowered to that of
+// the least certain word the excerpt quotes; pages with a text layer keep
+// full trust. Page 0 means the chunk's first page.
+func ocrTrust(chunk *database.Chunk, excerpt string, page int) float32 {
+	if chunk.Metadata == nil {
+		return 1.0
+	}
+	var meta struct {
+		OCR []document.PDFOCRPage `json:"ocr"`
+	}
+	if err := json.Unmarshal(chunk.Metadata, &meta); err != nil || len(meta.OCR) == 0 {
+		return 1.0
+	}
+	if page == 0 && chunk.PageStart.Valid {
+		page = int(chunk.PageStart.Int32)
+	}
+
+	quoted := make(map[string]bool)
+	for _, w := range strings.Fields(excerpt) {
+		quoted[ocrToken(w)] = true
+	}
+
+	for _, p := range meta.OCR {
+		if p.Page != page {
+			continue
+		}
+		trust := p.Confidence
+		for _, w := range p.Uncertain {
+			if quoted[ocrToken(w.Text)] && w.Confidence < trust {
+				trust = w.Confidence
+			}
+		}
+		return float32(trust)
+	}
+	return 1.0
+}
+
+// ocrToken normalises a word for matching: lower case, without the
+// punctuation around it.
+func ocrToken(s string) string {
+	return strings.ToLower(strings.TrimFunc(s, func(r rune) bool {
+		return unicode.IsPunct(r) || unicode.IsSymbol(r)
+	}))
+}
@@ -0,0 +1,72 @@
+package extraction
+
+import (
+	"encoding/json"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/einarsundgren/sikta/internal/database"
+	"github.com/einarsundgren/sikta/internal/document"
+)
+
+// minCellMatch is the shortest cell text matched against an excerpt;
+// shorter cells ("1", "st") would match almost anything.
+const minCellMatch = 3
+
+// chunkPDFTables decodes the PDF tables stored in chunk metadata.
+func chunkPDFTables(chunk *database.Chunk) []document.PDFTable {
+	if chunk.Metadata == nil {
+		return nil
+	}
+	var meta struct {
+		Tables []document.PDFTable `json:"tables"`
+	}
+	if err := json.Unmarshal(chunk.Metadata, &meta); err != nil {
+		return nil
+	}
+	return meta.Tables
+}
+
+// tableCellLocation points a location at the PDF table cell quoted in an
+// excerpt: the page, the cell's bounding box, its row and column header.
+// The longest quoted cell wins, body rows before the header. The location
+// is left as is when no cell is quoted.
+func tableCellLocation(chunk *database.Chunk, excerpt string, location *database.Location) {
+	excerpt = normalizeCell(excerpt)
+	if excerpt == "" {
+		return
+	}
+
+	var best *document.PDFCell
+	var bestTable *document.PDFTable
+	bestLen := 0
+	tables := chunkPDFTables(chunk)
+	for ti := range tables {
+		t := &tables[ti]
+		for ci := range t.Cells {
+			c := &t.Cells[ci]
+			text := normalizeCell(c.Text)
+			n := utf8.RuneCountInString(text)
+			if n < minCellMatch || !strings.Contains(excerpt, text) {
+				continue
+			}
+			if n > bestLen || (n == bestLen && best.Row == 1 && c.Row > 1) {
+				best, bestTable, bestLen = c, t, n
+			}
+		}
+	}
+	if best == nil {
+		return
+	}
+
+	location.Page = bestTable.Page
+	location.BBox = best.BBox[:]
+	location.Row = best.Row
+	if best.Column < len(bestTable.Header) {
+		location.Column = bestTable.Header[best.Column]
+	}
+}
+
+func normalizeCell(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
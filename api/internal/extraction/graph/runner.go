@@ -10,6 +10,7 @@ import (
 	"regexp"
 	"strings"
 
+	"github.com/einarsundgren/sikta/internal/document"
 	"github.com/einarsundgren/sikta/internal/extraction/claude"
 	"github.com/google/uuid"
 )
@@ -221,6 +222,9 @@ func (r *Runner) extractFromDocument(ctx context.Context, doc Document, systemPr
 	// Chunk the document (simple paragraph-based chunking)
 	chunks := r.chunkDocument(doc.Content)
 
+	// Index section headings so excerpts can be attributed to "§5" etc.
+	sections := document.BuildSectionIndex(doc.Content)
+
 	docResult := DocumentExtraction{
 		DocumentID: doc.ID,
 		Filename:   doc.Filename,
@@ -246,6 +250,9 @@ func (r *Runner) extractFromDocument(ctx context.Context, doc Document, systemPr
 			}
 			nodes[j].Properties["source_doc"] = doc.ID
 			nodes[j].Properties["source_file"] = doc.Filename
+			if section, ok := sectionForExcerpt(sections, doc.Content, nodes[j].Excerpt); ok {
+				nodes[j].Properties["source_section"] = section
+			}
 		}
 
 		docResult.Nodes = append(docResult.Nodes, nodes...)
@@ -344,6 +351,23 @@ func (r *Runner) extractFromChunk(ctx context.Context, chunk, systemPrompt, fews
 	return resp.Nodes, resp.Edges, nil
 }
 
+// sectionForExcerpt returns the ID of the section an excerpt was taken from.
+func sectionForExcerpt(sections *document.SectionIndex, content, excerpt string) (string, bool) {
+	excerpt = strings.TrimSpace(excerpt)
+	if excerpt == "" {
+		return "", false
+	}
+	offset := strings.Index(content, excerpt)
+	if offset == -1 {
+		return "", false
+	}
+	heading, ok := sections.At(offset)
+	if !ok {
+		return "", false
+	}
+	return heading.ID, true
+}
+
 func min(a, b int) int {
 	if a < b {
 		return a
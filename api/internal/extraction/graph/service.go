@@ -8,8 +8,11 @@ import (
 	"time"
 
 	"github.com/einarsundgren/sikta/internal/database"
+	"github.com/einarsundgren/sikta/internal/document"
+	dates "github.com/einarsundgren/sikta/internal/extraction"
 	"github.com/einarsundgren/sikta/internal/extraction/claude"
 	"github.com/einarsundgren/sikta/internal/graph"
+	"github.com/einarsundgren/sikta/internal/storage"
 	"github.com/google/uuid"
 )
 
@@ -17,34 +20,46 @@ import (
 type GraphService struct {
 	db           *database.Queries
 	claude       *claude.Client
-	graph        *graph.Service
+	graph        graphWriter
+	tables       *graph.TableMapper
 	logger       *slog.Logger
 	model        string
 	promptLoader *PromptLoader
+	store        storage.Storage
 }
 
-// NewGraphService creates a new graph extraction service
-func NewGraphService(db *database.Queries, claude *claude.Client, graph *graph.Service, logger *slog.Logger, model string, promptLoader *PromptLoader) *GraphService {
+// graphWriter is the part of graph.Service extraction stores results with
+type graphWriter interface {
+	CreateNode(ctx context.Context, params graph.CreateNodeParams) (uuid.UUID, error)
+	CreateEdge(ctx context.Context, params graph.CreateEdgeParams) (uuid.UUID, error)
+	CreateProvenance(ctx context.Context, params graph.CreateProvenanceParams) (uuid.UUID, error)
+}
+
+// NewGraphService creates a new graph extraction service. store holds the
+// uploaded files that tabular sources are read from.
+func NewGraphService(db *database.Queries, claude *claude.Client, graphService *graph.Service, logger *slog.Logger, model string, promptLoader *PromptLoader, store storage.Storage) *GraphService {
 	return &GraphService{
 		db:           db,
 		claude:       claude,
-		graph:        graph,
+		graph:        graphService,
+		tables:       graph.NewTableMapper(db, graphService, logger),
 		logger:       logger,
 		model:        model,
 		promptLoader: promptLoader,
+		store:        store,
 	}
 }
 
 // ExtractionProgress tracks extraction progress for graph extraction
 type GraphExtractionProgress struct {
-	DocumentID             string
-	TotalChunks            int
-	ProcessedChunks        int
-	NodesExtracted        int
-	EdgesExtracted        int
-	CurrentChunk           int
-	Status                 string
-	Error                  string
+	DocumentID      string
+	TotalChunks     int
+	ProcessedChunks int
+	NodesExtracted  int
+	EdgesExtracted  int
+	CurrentChunk    int
+	Status          string
+	Error           string
 }
 
 // ProgressCallback is called with progress updates
@@ -60,6 +75,15 @@ func (s *GraphService) ExtractDocumentToGraph(ctx context.Context, sourceID stri
 		return fmt.Errorf("failed to get document node: %w", err)
 	}
 
+	// Tables are mapped column by column, without the LLM
+	source, err := s.db.GetSource(ctx, database.PgUUID(parseUUID(sourceID)))
+	if err != nil {
+		return fmt.Errorf("failed to get source: %w", err)
+	}
+	if document.IsTabularType(source.FileType) {
+		return s.extractTablesToGraph(ctx, source, progressCb)
+	}
+
 	// Get chunks
 	chunks, err := s.db.ListChunksBySource(ctx, database.PgUUID(parseUUID(sourceID)))
 	if err != nil {
@@ -72,6 +96,11 @@ func (s *GraphService) ExtractDocumentToGraph(ctx context.Context, sourceID stri
 	// Track entity labels for edge creation
 	entityLabelToID := make(map[string]uuid.UUID)
 
+	// Email sources: messages, correspondents and threads come from headers
+	if err := s.storeEmailStructure(ctx, chunks, docNodeID, entityLabelToID); err != nil {
+		s.logger.Error("failed to store email structure", "source_id", sourceID, "error", err)
+	}
+
 	for i, chunk := range chunks {
 		s.logger.Info("processing chunk for graph extraction", "index", i, "chapter", chunk.ChapterTitle.String)
 
@@ -102,8 +131,8 @@ func (s *GraphService) ExtractDocumentToGraph(ctx context.Context, sourceID stri
 
 			// Track entity labels for edge creation
 			if node.NodeType == "person" || node.NodeType == "place" ||
-			   node.NodeType == "organization" || node.NodeType == "object" ||
-			   node.NodeType == "event" {
+				node.NodeType == "organization" || node.NodeType == "object" ||
+				node.NodeType == "event" {
 				entityLabelToID[node.Label] = nodeID
 			}
 		}
@@ -124,8 +153,8 @@ func (s *GraphService) ExtractDocumentToGraph(ctx context.Context, sourceID stri
 				ProcessedChunks: i + 1,
 				NodesExtracted:  len(entityLabelToID),
 				EdgesExtracted:  len(edges),
-				CurrentChunk:   i,
-				Status:         "processing",
+				CurrentChunk:    i,
+				Status:          "processing",
 			})
 		}
 
@@ -144,6 +173,40 @@ func (s *GraphService) ExtractDocumentToGraph(ctx context.Context, sourceID stri
 	return nil
 }
 
+// extractTablesToGraph maps a CSV/XLSX source's rows to nodes and edges
+// using its column mapping.
+func (s *GraphService) extractTablesToGraph(ctx context.Context, source *database.Source, progressCb ProgressCallback) error {
+	filePath, release, err := s.store.LocalPath(ctx, source.FilePath)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer release()
+
+	tables, err := document.ParseTables(filePath, source.FileType)
+	if err != nil {
+		return fmt.Errorf("failed to parse tables: %w", err)
+	}
+
+	mappings, _, err := s.tables.Mappings(source, tables)
+	if err != nil {
+		return err
+	}
+	result, err := s.tables.MapSource(ctx, source, tables, mappings)
+	if err != nil {
+		return err
+	}
+
+	if progressCb != nil {
+		progressCb(GraphExtractionProgress{
+			DocumentID:     database.UUIDStr(source.ID),
+			NodesExtracted: result.Nodes,
+			EdgesExtracted: result.Edges,
+			Status:         "complete",
+		})
+	}
+	return nil
+}
+
 // getDocumentNode gets or creates the document node for a source
 func (s *GraphService) getDocumentNode(ctx context.Context, sourceID string) (uuid.UUID, error) {
 	// Try to find existing document node
@@ -210,6 +273,9 @@ func (s *GraphService) extractFromChunk(ctx context.Context, chunk *database.Chu
 	}
 
 	userMessage := fmt.Sprintf("%s\n\n%s", fewShotPrompt, chunk.Content)
+	if ref, ok := chunk.ReferenceDate(); ok {
+		userMessage = fmt.Sprintf("%s\n\n%s\n\n%s", fewShotPrompt, dates.ReferenceDateNote(ref), chunk.Content)
+	}
 
 	apiResp, err := s.claude.SendSystemPrompt(ctx, systemPrompt, userMessage, s.model)
 	if err != nil {
@@ -257,10 +323,12 @@ func (s *GraphService) storeExtractedNode(ctx context.Context, node ExtractedNod
 	// Build location
 	location := database.Location{
 		Chapter: chunk.ChapterTitle.String,
+		Section: chunk.SectionID.String,
 	}
 	if chunk.PageStart.Valid {
 		location.Page = int(chunk.PageStart.Int32)
 	}
+	tableCellLocation(chunk, node.Excerpt, &location)
 
 	// Determine modality
 	modality := database.ModalityAsserted
@@ -281,22 +349,32 @@ func (s *GraphService) storeExtractedNode(ctx context.Context, node ExtractedNod
 		}
 	}
 
+	// Relative times ("three days ago") resolve against the chunk's date
+	if claimedStart == nil && node.ClaimedTimeText != "" {
+		if ref, ok := chunk.ReferenceDate(); ok {
+			if t, err := dates.NewDateNormalizer().ResolveRelativeDate(node.ClaimedTimeText, ref); err == nil {
+				claimedStart = &t
+			}
+		}
+	}
+
 	// Create provenance
 	_, err = s.graph.CreateProvenance(ctx, graph.CreateProvenanceParams{
-		TargetType:       "node",
-		TargetID:         nodeID,
-		SourceID:         docNodeID,
-		Excerpt:          node.Excerpt,
-		Location:         location,
-		Confidence:       float32(node.Confidence),
-		Trust:            1.0, // Would come from source trust
-		Modality:         modality,
-		Status:           database.StatusPending,
-		ClaimedTimeStart: claimedStart,
-		ClaimedTimeEnd:   claimedEnd,
-		ClaimedTimeText:  node.ClaimedTimeText,
-		ClaimedGeoRegion: node.ClaimedGeoRegion,
-		ClaimedGeoText:   node.ClaimedGeoText,
+		TargetType:        "node",
+		TargetID:          nodeID,
+		SourceID:          docNodeID,
+		Excerpt:           node.Excerpt,
+		Location:          location,
+		Confidence:        float32(node.Confidence),
+		Trust:             ocrTrust(chunk, node.Excerpt, location.Page),
+		Modality:          modality,
+		Status:            database.StatusPending,
+		ClaimedTimeStart:  claimedStart,
+		ClaimedTimeEnd:    claimedEnd,
+		ClaimedTimeText:   node.ClaimedTimeText,
+		ClaimedGeoRegion:  node.ClaimedGeoRegion,
+		ClaimedGeoText:    node.ClaimedGeoText,
+		ClaimedProperties: node.Properties,
 	})
 
 	return nodeID, nil
@@ -337,19 +415,22 @@ func (s *GraphService) storeExtractedEdge(ctx context.Context, edge ExtractedEdg
 	// Build location
 	location := database.Location{
 		Chapter: chunk.ChapterTitle.String,
+		Section: chunk.SectionID.String,
 	}
+	tableCellLocation(chunk, edge.Excerpt, &location)
 
 	// Create provenance for the edge
 	_, err = s.graph.CreateProvenance(ctx, graph.CreateProvenanceParams{
-		TargetType: "edge",
-		TargetID:   edgeID,
-		SourceID:   docNodeID,
-		Excerpt:    edge.Excerpt,
-		Location:   location,
-		Confidence: float32(edge.Confidence),
-		Trust:      1.0,
-		Modality:   modality,
-		Status:     database.StatusPending,
+		TargetType:        "edge",
+		TargetID:          edgeID,
+		SourceID:          docNodeID,
+		Excerpt:           edge.Excerpt,
+		Location:          location,
+		Confidence:        float32(edge.Confidence),
+		Trust:             ocrTrust(chunk, edge.Excerpt, location.Page),
+		Modality:          modality,
+		Status:            database.StatusPending,
+		ClaimedProperties: edge.Properties,
 	})
 
 	return edgeID, nil
@@ -10,7 +10,9 @@ import (
 	"time"
 
 	"github.com/einarsundgren/sikta/internal/database"
+	"github.com/einarsundgren/sikta/internal/document"
 	"github.com/einarsundgren/sikta/internal/extraction/claude"
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
@@ -57,14 +59,54 @@ func (s *Service) GetChunkCount(ctx context.Context, sourceID string) (int, erro
 	return len(chunks), nil
 }
 
+// ChangedChunks returns the chunks of a document whose text differs from
+// its previous version. A document without a previous version has every
+// chunk changed.
+func (s *Service) ChangedChunks(ctx context.Context, sourceID string) ([]*database.Chunk, error) {
+	chunks, err := s.db.ListChunksBySource(ctx, database.PgUUID(parseUUID(sourceID)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chunks: %w", err)
+	}
+	src, err := s.db.GetSource(ctx, database.PgUUID(parseUUID(sourceID)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get source: %w", err)
+	}
+	if !src.PreviousVersionID.Valid {
+		return chunks, nil
+	}
+	previous, err := s.db.ListChunksBySource(ctx, src.PreviousVersionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get previous version chunks: %w", err)
+	}
+
+	before := make([]string, len(previous))
+	for i, c := range previous {
+		before[i] = c.Content
+	}
+	after := make([]string, len(chunks))
+	for i, c := range chunks {
+		after[i] = c.Content
+	}
+	var changed []*database.Chunk
+	for _, i := range document.ChangedChunks(document.DiffChunks(before, after)) {
+		changed = append(changed, chunks[i])
+	}
+	return changed, nil
+}
+
 // ExtractDocument extracts events, entities, and relationships from a document.
 func (s *Service) ExtractDocument(ctx context.Context, sourceID string, progressCb ProgressCallback) error {
-	s.logger.Info("starting extraction", "source_id", sourceID)
-
 	chunks, err := s.db.ListChunksBySource(ctx, database.PgUUID(parseUUID(sourceID)))
 	if err != nil {
 		return fmt.Errorf("failed to get chunks: %w", err)
 	}
+	return s.ExtractChunks(ctx, sourceID, chunks, progressCb)
+}
+
+// ExtractChunks extracts events, entities, and relationships from the given
+// chunks of a document.
+func (s *Service) ExtractChunks(ctx context.Context, sourceID string, chunks []*database.Chunk, progressCb ProgressCallback) error {
+	s.logger.Info("starting extraction", "source_id", sourceID)
 
 	totalChunks := len(chunks)
 	s.logger.Info("processing chunks", "total", totalChunks)
@@ -125,6 +167,9 @@ func (s *Service) ExtractDocument(ctx context.Context, sourceID string, progress
 // extractFromChunk extracts data from a single chunk.
 func (s *Service) extractFromChunk(ctx context.Context, chunk *database.Chunk) (*ExtractionResponse, error) {
 	userMessage := fmt.Sprintf("%s\n\n%s", FewShotExample1, chunk.Content)
+	if ref, ok := chunk.ReferenceDate(); ok {
+		userMessage = fmt.Sprintf("%s\n\n%s\n\n%s", FewShotExample1, ReferenceDateNote(ref), chunk.Content)
+	}
 
 	apiResp, err := s.claude.SendSystemPrompt(ctx, ExtractionSystemPrompt, userMessage, s.model)
 	if err != nil {
@@ -235,6 +280,7 @@ func (s *Service) storeEntity(ctx context.Context, sourceID pgtype.UUID, chunk *
 
 	if entity.Excerpt != "" {
 		_, _ = s.db.CreateSourceReference(ctx, database.CreateSourceReferenceParams{
+			ID:             database.PgUUID(uuid.New()),
 			ChunkID:        chunk.ID,
 			ClaimID:        pgtype.UUID{},
 			EntityID:       created.ID,
@@ -268,6 +314,7 @@ func (s *Service) storeEvent(ctx context.Context, sourceID pgtype.UUID, chunk *d
 
 	if event.Excerpt != "" {
 		_, _ = s.db.CreateSourceReference(ctx, database.CreateSourceReferenceParams{
+			ID:             database.PgUUID(uuid.New()),
 			ChunkID:        chunk.ID,
 			ClaimID:        created.ID,
 			EntityID:       pgtype.UUID{},
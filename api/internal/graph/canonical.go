@@ -0,0 +1,187 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/einarsundgren/sikta/internal/database"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// CanonicalNode is a node standing for its identity cluster, or for itself
+// if it is in none. Node is a copy of the canonical member with the other
+// members' aliases and missing properties merged in.
+type CanonicalNode struct {
+	Node       *database.Node
+	Members    []pgtype.UUID // canonical member first
+	Provenance []*database.Provenance
+}
+
+// CanonicalEdge is one or more edges of the same type and polarity between
+// the same canonical nodes. Edge is a copy of the oldest, rewired.
+type CanonicalEdge struct {
+	Edge       *database.Edge
+	Members    []pgtype.UUID // oldest first
+	Provenance []*database.Provenance
+}
+
+// CanonicalGraph is a project's graph with each identity cluster collapsed
+// into its canonical node. It is computed when read; the stored graph is
+// left as it is.
+type CanonicalGraph struct {
+	Nodes     []*CanonicalNode
+	Edges     []*CanonicalEdge
+	Documents map[pgtype.UUID]*database.Node // provenance documents by ID
+}
+
+// GetCanonicalGraph returns the project's graph with identity clusters
+// collapsed, their canonical members picked by strategy. Members' aliases
+// and labels become the canonical node's aliases, their provenance is
+// summed, and edges are rewired to the canonical nodes. Edges that end up
+// joining a node to itself, such as the same_as edges that formed the
+// cluster, are dropped.
+func (v *Views) GetCanonicalGraph(ctx context.Context, projectID uuid.UUID, strategy ViewStrategy) (*CanonicalGraph, error) {
+	rows, err := v.identityClusterRows(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	p, err := v.loadProject(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	edges, err := v.db.ListProjectEdges(ctx, database.PgUUID(projectID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get edges: %w", err)
+	}
+	return canonicalize(p, edges, identityClusters(rows, p, strategy)), nil
+}
+
+// canonicalize collapses a project's graph by its identity clusters
+func canonicalize(p *projectGraph, edges []*database.Edge, clusters []IdentityCluster) *CanonicalGraph {
+	canonicalOf := make(map[pgtype.UUID]pgtype.UUID)
+	members := make(map[pgtype.UUID][]*database.Node)
+	for _, c := range clusters {
+		members[c.Canonical.ID] = append(members[c.Canonical.ID], c.Canonical)
+		for _, m := range c.Members {
+			canonicalOf[m.ID] = c.Canonical.ID
+			if m.ID != c.Canonical.ID {
+				members[c.Canonical.ID] = append(members[c.Canonical.ID], m)
+			}
+		}
+	}
+	resolve := func(id pgtype.UUID) pgtype.UUID {
+		if c, ok := canonicalOf[id]; ok {
+			return c
+		}
+		return id
+	}
+
+	g := &CanonicalGraph{Documents: p.documents}
+	for _, n := range p.nodes {
+		if resolve(n.ID) != n.ID {
+			continue // collapsed into its canonical node
+		}
+		group := members[n.ID]
+		if group == nil {
+			group = []*database.Node{n}
+		}
+		g.Nodes = append(g.Nodes, mergeNodes(group, p.provenance))
+	}
+
+	type edgeKey struct {
+		edgeType       string
+		source, target pgtype.UUID
+		negated        bool
+	}
+	merged := make(map[edgeKey]*CanonicalEdge)
+	for _, e := range edges {
+		source, target := resolve(e.SourceNode), resolve(e.TargetNode)
+		if source == target && e.SourceNode != e.TargetNode {
+			continue
+		}
+		key := edgeKey{e.EdgeType, source, target, e.IsNegated}
+		ce := merged[key]
+		if ce == nil {
+			rewired := *e
+			rewired.SourceNode, rewired.TargetNode = source, target
+			ce = &CanonicalEdge{Edge: &rewired}
+			merged[key] = ce
+			g.Edges = append(g.Edges, ce)
+		} else {
+			ce.Edge.Properties = mergeProperties(ce.Edge.Properties, e.Properties)
+		}
+		ce.Members = append(ce.Members, e.ID)
+		ce.Provenance = append(ce.Provenance, p.provenance[e.ID]...)
+	}
+	return g
+}
+
+// mergeNodes collapses a cluster, canonical member first, into one node
+func mergeNodes(group []*database.Node, provenance map[pgtype.UUID][]*database.Provenance) *CanonicalNode {
+	node := *group[0]
+	cn := &CanonicalNode{Node: &node}
+
+	var aliases []string
+	seen := map[string]bool{strings.ToLower(node.Label): true}
+	addAlias := func(a string) {
+		key := strings.ToLower(strings.TrimSpace(a))
+		if key != "" && !seen[key] {
+			seen[key] = true
+			aliases = append(aliases, strings.TrimSpace(a))
+		}
+	}
+	for i, m := range group {
+		if i > 0 {
+			addAlias(m.Label)
+			node.Properties = mergeProperties(node.Properties, m.Properties)
+		}
+		if raw, ok := m.GetProperty("aliases", nil).([]interface{}); ok {
+			for _, item := range raw {
+				if s, ok := item.(string); ok {
+					addAlias(s)
+				}
+			}
+		}
+		cn.Members = append(cn.Members, m.ID)
+		cn.Provenance = append(cn.Provenance, provenance[m.ID]...)
+	}
+
+	if len(group) > 1 {
+		props := make(map[string]interface{})
+		if len(node.Properties) > 0 {
+			_ = json.Unmarshal(node.Properties, &props)
+		}
+		if len(aliases) > 0 {
+			props["aliases"] = aliases
+		}
+		if data, err := json.Marshal(props); err == nil {
+			node.Properties = data
+		}
+	}
+	return cn
+}
+
+// mergeProperties adds the properties of other that base lacks
+func mergeProperties(base, other []byte) []byte {
+	into := make(map[string]interface{})
+	from := make(map[string]interface{})
+	if len(base) > 0 {
+		_ = json.Unmarshal(base, &into)
+	}
+	if len(other) > 0 {
+		_ = json.Unmarshal(other, &from)
+	}
+	for k, v := range from {
+		if _, ok := into[k]; !ok {
+			into[k] = v
+		}
+	}
+	data, err := json.Marshal(into)
+	if err != nil {
+		return base
+	}
+	return data
+}
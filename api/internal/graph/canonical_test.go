@@ -0,0 +1,225 @@
+package graph
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/einarsundgren/sikta/internal/database"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// canonicalFixture is a project where nodes 1, 2 and 3 are one person,
+// node 1 canonical, working at node 4. Node 5 is unrelated and knows
+// itself.
+func canonicalFixture() (*projectGraph, []*database.Edge, []IdentityCluster) {
+	node := func(n byte, label, properties string) *database.Node {
+		return &database.Node{ID: testID(n), NodeType: database.NodeTypePerson, Label: label, Properties: []byte(properties)}
+	}
+	anna := node(1, "Anna Öberg", `{"role": "vd", "aliases": ["Annie"]}`)
+	initial := node(2, "A. Öberg", `{"role": "ägare", "born": "1811", "aliases": ["annie", "Anna"]}`)
+	lower := node(3, "anna öberg", `{}`)
+	company := node(4, "Bygg AB", `{"org_nr": "556677-8899"}`)
+	kalle := node(5, "Kalle", `{}`)
+
+	edge := func(id, source, target byte, edgeType, properties string) *database.Edge {
+		return &database.Edge{
+			ID:         testID(id),
+			EdgeType:   edgeType,
+			SourceNode: testID(source),
+			TargetNode: testID(target),
+			Properties: []byte(properties),
+		}
+	}
+	negated := edge(13, 1, 4, "works_at", `{}`)
+	negated.IsNegated = true
+	edges := []*database.Edge{
+		edge(10, 1, 2, database.EdgeTypeSameAs, `{}`),
+		edge(11, 2, 4, "works_at", `{"since": "2019"}`),
+		edge(12, 3, 4, "works_at", `{"since": "2020", "title": "vd"}`),
+		negated,
+		edge(14, 4, 3, "employs", `{}`),
+		edge(15, 5, 5, "knows", `{}`),
+		edge(16, 2, 3, database.EdgeTypeSameAs, `{}`),
+	}
+
+	record := func(id, target byte) *database.Provenance {
+		return &database.Provenance{ID: testID(100 + id), TargetID: testID(target), SourceID: testID(50), Location: []byte(`{}`)}
+	}
+	p := &projectGraph{
+		nodes: []*database.Node{anna, initial, lower, company, kalle},
+		provenance: map[pgtype.UUID][]*database.Provenance{
+			testID(1):  {record(1, 1)},
+			testID(2):  {record(2, 2), record(3, 2)},
+			testID(3):  {record(4, 3)},
+			testID(11): {record(5, 11)},
+			testID(12): {record(6, 12)},
+			testID(16): {record(7, 16)},
+		},
+		documents: map[pgtype.UUID]*database.Node{},
+	}
+	clusters := []IdentityCluster{{ID: testID(1), Members: []*database.Node{anna, initial, lower}, Canonical: anna}}
+	return p, edges, clusters
+}
+
+func ids(ns ...byte) []pgtype.UUID {
+	var result []pgtype.UUID
+	for _, n := range ns {
+		result = append(result, testID(n))
+	}
+	return result
+}
+
+func provenanceIDs(records []*database.Provenance) []pgtype.UUID {
+	var result []pgtype.UUID
+	for _, p := range records {
+		result = append(result, p.ID)
+	}
+	return result
+}
+
+func TestCanonicalizeNodes(t *testing.T) {
+	p, edges, clusters := canonicalFixture()
+	g := canonicalize(p, edges, clusters)
+
+	var got []pgtype.UUID
+	for _, n := range g.Nodes {
+		got = append(got, n.Node.ID)
+	}
+	if want := ids(1, 4, 5); !reflect.DeepEqual(got, want) {
+		t.Fatalf("nodes = %v, want %v", got, want)
+	}
+
+	anna := g.Nodes[0]
+	if anna.Node.Label != "Anna Öberg" {
+		t.Errorf("Label = %q, want the canonical member's", anna.Node.Label)
+	}
+	if want := ids(1, 2, 3); !reflect.DeepEqual(anna.Members, want) {
+		t.Errorf("Members = %v, want %v", anna.Members, want)
+	}
+	if got, want := provenanceIDs(anna.Provenance), ids(101, 102, 103, 104); !reflect.DeepEqual(got, want) {
+		t.Errorf("Provenance = %v, want %v", got, want)
+	}
+
+	var props map[string]any
+	if err := json.Unmarshal(anna.Node.Properties, &props); err != nil {
+		t.Fatalf("Properties = %s: %v", anna.Node.Properties, err)
+	}
+	// Aliases are members' aliases and labels, first spelling kept, without
+	// the canonical label in any case
+	wantProps := map[string]any{
+		"role":    "vd",
+		"born":    "1811",
+		"aliases": []any{"Annie", "A. Öberg", "Anna"},
+	}
+	if !reflect.DeepEqual(props, wantProps) {
+		t.Errorf("Properties = %v, want %v", props, wantProps)
+	}
+
+	if company := g.Nodes[1]; !reflect.DeepEqual(company.Members, ids(4)) || string(company.Node.Properties) != `{"org_nr": "556677-8899"}` {
+		t.Errorf("unclustered node = %+v, want it unchanged", company)
+	}
+	if string(p.nodes[0].Properties) != `{"role": "vd", "aliases": ["Annie"]}` {
+		t.Errorf("stored canonical member changed: %s", p.nodes[0].Properties)
+	}
+}
+
+func TestCanonicalizeEdges(t *testing.T) {
+	p, edges, clusters := canonicalFixture()
+	g := canonicalize(p, edges, clusters)
+
+	type want struct {
+		edgeType       string
+		source, target byte
+		negated        bool
+		members        []pgtype.UUID
+		provenance     []pgtype.UUID
+	}
+	// Both same_as edges collapse into self-loops and are dropped; the
+	// works_at edges from two members merge; node 5's own loop stays
+	wants := []want{
+		{"works_at", 1, 4, false, ids(11, 12), ids(105, 106)},
+		{"works_at", 1, 4, true, ids(13), nil},
+		{"employs", 4, 1, false, ids(14), nil},
+		{"knows", 5, 5, false, ids(15), nil},
+	}
+	if len(g.Edges) != len(wants) {
+		for _, e := range g.Edges {
+			t.Logf("edge %s %v -> %v", e.Edge.EdgeType, e.Edge.SourceNode, e.Edge.TargetNode)
+		}
+		t.Fatalf("len(Edges) = %d, want %d", len(g.Edges), len(wants))
+	}
+	for i, w := range wants {
+		e := g.Edges[i]
+		if e.Edge.EdgeType != w.edgeType || e.Edge.SourceNode != testID(w.source) || e.Edge.TargetNode != testID(w.target) || e.Edge.IsNegated != w.negated {
+			t.Errorf("edge %d = %s %v -> %v negated %v, want %s %d -> %d negated %v", i,
+				e.Edge.EdgeType, e.Edge.SourceNode, e.Edge.TargetNode, e.Edge.IsNegated,
+				w.edgeType, w.source, w.target, w.negated)
+		}
+		if !reflect.DeepEqual(e.Members, w.members) {
+			t.Errorf("edge %d Members = %v, want %v", i, e.Members, w.members)
+		}
+		if got := provenanceIDs(e.Provenance); !reflect.DeepEqual(got, w.provenance) {
+			t.Errorf("edge %d Provenance = %v, want %v", i, got, w.provenance)
+		}
+	}
+
+	// The merged edge is a copy of the oldest, keeping its values and
+	// adding the others' missing properties
+	merged := g.Edges[0].Edge
+	if merged.ID != testID(11) {
+		t.Errorf("merged edge ID = %v, want the oldest member's", merged.ID)
+	}
+	var props map[string]any
+	if err := json.Unmarshal(merged.Properties, &props); err != nil {
+		t.Fatalf("Properties = %s: %v", merged.Properties, err)
+	}
+	if want := map[string]any{"since": "2019", "title": "vd"}; !reflect.DeepEqual(props, want) {
+		t.Errorf("merged Properties = %v, want %v", props, want)
+	}
+	if edges[1].SourceNode != testID(2) || string(edges[1].Properties) != `{"since": "2019"}` {
+		t.Errorf("stored edge changed: %+v", edges[1])
+	}
+}
+
+func TestCanonicalizeWithoutClusters(t *testing.T) {
+	p, edges, _ := canonicalFixture()
+	g := canonicalize(p, edges, nil)
+	if len(g.Nodes) != len(p.nodes) {
+		t.Errorf("len(Nodes) = %d, want %d", len(g.Nodes), len(p.nodes))
+	}
+	if len(g.Edges) != len(edges) {
+		t.Errorf("len(Edges) = %d, want %d", len(g.Edges), len(edges))
+	}
+	for i, n := range g.Nodes {
+		if string(n.Node.Properties) != string(p.nodes[i].Properties) {
+			t.Errorf("node %d Properties = %s, want %s", i, n.Node.Properties, p.nodes[i].Properties)
+		}
+	}
+}
+
+func TestMergeProperties(t *testing.T) {
+	tests := []struct {
+		name        string
+		base, other string
+		want        map[string]any
+	}{
+		{"base wins", `{"a": 1, "b": 2}`, `{"b": 3, "c": 4}`, map[string]any{"a": 1.0, "b": 2.0, "c": 4.0}},
+		{"empty base", ``, `{"c": 4}`, map[string]any{"c": 4.0}},
+		{"empty other", `{"a": 1}`, ``, map[string]any{"a": 1.0}},
+		{"null kept from base", `{"a": null}`, `{"a": 1}`, map[string]any{"a": nil}},
+		{"invalid other", `{"a": 1}`, `not json`, map[string]any{"a": 1.0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got map[string]any
+			data := mergeProperties([]byte(tt.base), []byte(tt.other))
+			if err := json.Unmarshal(data, &got); err != nil {
+				t.Fatalf("mergeProperties() = %s: %v", data, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergeProperties() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package graph
+
+import (
+	"context"
+
+	"github.com/einarsundgren/sikta/internal/database"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Conflict is an event or entity whose sources disagree on when, where or
+// what its properties are. Each kind lists every claimed value, strongest
+// first, with the records asserting it; a kind the sources agree on, or
+// that none claims, is empty. Properties holds only disputed keys.
+type Conflict struct {
+	Node       *database.Node
+	Time       []ValueSupport
+	Geo        []ValueSupport
+	Properties map[string][]ValueSupport
+}
+
+// ConflictView is a project's conflicts and the document nodes their
+// provenance comes from
+type ConflictView struct {
+	Conflicts []Conflict
+	Documents map[pgtype.UUID]*database.Node // by ID
+}
+
+// conflictNodeTypes are the node types the conflict view covers
+var conflictNodeTypes = map[string]bool{
+	database.NodeTypeEvent:        true,
+	database.NodeTypePerson:       true,
+	database.NodeTypePlace:        true,
+	database.NodeTypeOrganization: true,
+	database.NodeTypeObject:       true,
+}
+
+// GetConflicts returns the project's events and entities whose assertion
+// provenance claims more than one time, place or value of a property,
+// grouped by value as the majority strategy groups them. Only nodes of nodeType are returned if it
+// is set.
+func (v *Views) GetConflicts(ctx context.Context, projectID uuid.UUID, nodeType string) (*ConflictView, error) {
+	p, err := v.loadProject(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	view := &ConflictView{Documents: p.documents}
+	for _, node := range p.nodes {
+		if !conflictNodeTypes[node.NodeType] || (nodeType != "" && node.NodeType != nodeType) {
+			continue
+		}
+		if c, ok := conflict(node, ResolveMajority(p.provenance[node.ID])); ok {
+			view.Conflicts = append(view.Conflicts, c)
+		}
+	}
+	return view, nil
+}
+
+// conflict returns the claims of a node's majority resolution that have
+// more than one side, and whether there are any
+func conflict(node *database.Node, m *Majority) (Conflict, bool) {
+	c := Conflict{Node: node, Time: disagreement(m.Time), Geo: disagreement(m.Geo)}
+	for k, r := range m.Properties {
+		if sides := disagreement(r); sides != nil {
+			if c.Properties == nil {
+				c.Properties = make(map[string][]ValueSupport)
+			}
+			c.Properties[k] = sides
+		}
+	}
+	return c, c.Time != nil || c.Geo != nil || c.Properties != nil
+}
+
+// disagreement returns every side of a resolution, or nil if there is only
+// one
+func disagreement(r *Resolution) []ValueSupport {
+	if r == nil || len(r.Dissenting) == 0 {
+		return nil
+	}
+	return append([]ValueSupport{r.Winner}, r.Dissenting...)
+}
@@ -0,0 +1,112 @@
+package graph
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/einarsundgren/sikta/internal/database"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestConflict(t *testing.T) {
+	geo := func(p *database.Provenance, region string) *database.Provenance {
+		p.ClaimedGeoRegion = pgtype.Text{String: region, Valid: true}
+		return p
+	}
+
+	tests := []struct {
+		name       string
+		records    []*database.Provenance
+		want       bool
+		geo        int
+		properties map[string]int // sides per disputed key
+	}{
+		{
+			name: "agreement",
+			records: []*database.Provenance{
+				claim(1, 1, 1, `{"role": "vd"}`),
+				claim(2, 2, 1, `{"role": "VD"}`),
+			},
+		},
+		{
+			name: "property dispute",
+			records: []*database.Provenance{
+				claim(1, 1, 1, `{"role": "vd", "born": "1811"}`),
+				claim(2, 2, 1, `{"role": "ägare", "born": "1811"}`),
+				claim(3, 3, 1, `{"role": "styrelse"}`),
+			},
+			want:       true,
+			properties: map[string]int{"role": 3},
+		},
+		{
+			name: "place and property disputes",
+			records: []*database.Provenance{
+				geo(claim(1, 1, 1, `{"born": "1811"}`), "SE-Y"),
+				geo(claim(2, 2, 1, `{"born": "1812"}`), "SE-Z"),
+			},
+			want:       true,
+			geo:        2,
+			properties: map[string]int{"born": 2},
+		},
+		{
+			name: "place dispute only",
+			records: []*database.Provenance{
+				geo(claim(1, 1, 1, `{}`), "SE-Y"),
+				geo(claim(2, 2, 1, `{}`), "SE-Z"),
+			},
+			want: true,
+			geo:  2,
+		},
+		{
+			name: "rejected claim is not a side",
+			records: []*database.Provenance{
+				claim(1, 1, 1, `{"role": "vd"}`),
+				rejected(claim(2, 2, 1, `{"role": "ägare"}`)),
+			},
+		},
+		{
+			name: "rejected claim is left out of a dispute",
+			records: []*database.Provenance{
+				claim(1, 1, 1, `{"role": "vd"}`),
+				claim(2, 2, 1, `{"role": "ägare"}`),
+				rejected(claim(3, 3, 1, `{"role": "styrelse"}`)),
+			},
+			want:       true,
+			properties: map[string]int{"role": 2},
+		},
+	}
+
+	node := &database.Node{ID: testID(1), NodeType: database.NodeTypePerson}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, ok := conflict(node, ResolveMajority(tt.records))
+			if ok != tt.want {
+				t.Fatalf("conflict() ok = %v, want %v", ok, tt.want)
+			}
+			if c.Time != nil {
+				t.Errorf("Time = %v, want nil", c.Time)
+			}
+			if len(c.Geo) != tt.geo {
+				t.Errorf("len(Geo) = %d, want %d", len(c.Geo), tt.geo)
+			}
+			var keys []string
+			for k := range c.Properties {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			if len(keys) != len(tt.properties) {
+				t.Fatalf("disputed properties = %v, want %v", keys, tt.properties)
+			}
+			for _, k := range keys {
+				if got, want := len(c.Properties[k]), tt.properties[k]; got != want {
+					t.Errorf("len(Properties[%q]) = %d, want %d", k, got, want)
+				}
+				for _, side := range c.Properties[k] {
+					if side.Value.Property != k {
+						t.Errorf("Properties[%q] side claims %q", k, side.Value.Property)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,314 @@
+package graph
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/einarsundgren/sikta/internal/database"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// MinSameAsConfidence is the least confidence an unreviewed same_as edge
+// needs to join two nodes into one identity. Approved edges join them
+// regardless and rejected or negated ones never do.
+const MinSameAsConfidence = 0.5
+
+// IdentityCluster is a set of nodes same_as edges join, transitively, and
+// the member a view strategy picked to stand for them
+type IdentityCluster struct {
+	ID        pgtype.UUID // lowest member ID; the same under every strategy
+	Members   []*database.Node
+	Canonical *database.Node
+}
+
+// IdentityClusters returns a project's identity clusters of two or more
+// nodes with the canonical member strategy picks. Clusters are read from
+// the cached table, which is recomputed first if a same_as edge or its
+// review has changed since it was.
+func (v *Views) IdentityClusters(ctx context.Context, projectID uuid.UUID, strategy ViewStrategy) ([]IdentityCluster, error) {
+	rows, err := v.identityClusterRows(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	p, err := v.loadProject(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	return identityClusters(rows, p, strategy), nil
+}
+
+// identityClusters groups the cached cluster rows into clusters of the
+// project's nodes and picks each one's canonical member
+func identityClusters(rows []*database.IdentityCluster, p *projectGraph, strategy ViewStrategy) []IdentityCluster {
+	nodes := make(map[pgtype.UUID]*database.Node, len(p.nodes))
+	for _, n := range p.nodes {
+		nodes[n.ID] = n
+	}
+
+	var clusters []IdentityCluster
+	for _, row := range rows {
+		n := nodes[row.NodeID]
+		if n == nil {
+			continue
+		}
+		if len(clusters) == 0 || clusters[len(clusters)-1].ID != row.ClusterID {
+			clusters = append(clusters, IdentityCluster{ID: row.ClusterID})
+		}
+		c := &clusters[len(clusters)-1]
+		c.Members = append(c.Members, n)
+	}
+	for i := range clusters {
+		clusters[i].Canonical = canonicalMember(clusters[i].Members, p.provenance, strategy)
+	}
+	return clusters
+}
+
+// ResolveIdentity returns the canonical node of the entity's identity
+// cluster in the project under strategy, or the entity itself if no
+// same_as edge joins it to another node
+func (v *Views) ResolveIdentity(ctx context.Context, projectID, entityID uuid.UUID, strategy ViewStrategy) (uuid.UUID, error) {
+	rows, err := v.identityClusterRows(ctx, projectID)
+	if err != nil {
+		return entityID, err
+	}
+	id := database.PgUUID(entityID)
+	var clusterID pgtype.UUID
+	for _, row := range rows {
+		if row.NodeID == id {
+			clusterID = row.ClusterID
+			break
+		}
+	}
+	if !clusterID.Valid {
+		return entityID, nil
+	}
+
+	var memberIDs []pgtype.UUID
+	for _, row := range rows {
+		if row.ClusterID == clusterID {
+			memberIDs = append(memberIDs, row.NodeID)
+		}
+	}
+	members, err := v.db.ListNodesByIDs(ctx, memberIDs)
+	if err != nil {
+		return entityID, fmt.Errorf("failed to get cluster members: %w", err)
+	}
+	provenance := make(map[pgtype.UUID][]*database.Provenance, len(members))
+	for _, m := range members {
+		provenance[m.ID], err = v.db.ListProvenanceByTarget(ctx, database.ListProvenanceByTargetParams{
+			TargetType: "node",
+			TargetID:   m.ID,
+		})
+		if err != nil {
+			return entityID, fmt.Errorf("failed to get provenance: %w", err)
+		}
+	}
+
+	canonical := canonicalMember(members, provenance, strategy)
+	if canonical == nil {
+		return entityID, nil
+	}
+	return uuid.UUID(canonical.ID.Bytes), nil
+}
+
+// RefreshIdentityClusters recomputes a project's cached identity clusters
+// from its same_as edges
+func (v *Views) RefreshIdentityClusters(ctx context.Context, projectID uuid.UUID) error {
+	id := database.PgUUID(projectID)
+	return v.db.InTx(ctx, func(q *database.Queries) error {
+		// Marking the clusters fresh first locks the state row, so an edge
+		// changed while they are computed marks them stale again afterwards
+		// rather than being overwritten
+		if err := q.MarkIdentityClustersFresh(ctx, id); err != nil {
+			return fmt.Errorf("failed to mark identity clusters fresh: %w", err)
+		}
+		edges, err := q.ListProjectSameAsEdges(ctx, id)
+		if err != nil {
+			return fmt.Errorf("failed to list same_as edges: %w", err)
+		}
+		if err := q.DeleteIdentityClusters(ctx, id); err != nil {
+			return fmt.Errorf("failed to clear identity clusters: %w", err)
+		}
+
+		clusters := clusterSameAs(edges)
+		for _, members := range clusters {
+			for _, m := range members {
+				err := q.CreateIdentityClusterMember(ctx, database.CreateIdentityClusterMemberParams{
+					ProjectID: id,
+					NodeID:    m,
+					ClusterID: members[0],
+				})
+				if err != nil {
+					return fmt.Errorf("failed to store identity cluster: %w", err)
+				}
+			}
+		}
+		v.logger.Info("identity clusters refreshed", "project", projectID, "edges", len(edges), "clusters", len(clusters))
+		return nil
+	})
+}
+
+// identityClusterRows returns the cached cluster table for a project,
+// refreshing it first if it is stale or was never computed
+func (v *Views) identityClusterRows(ctx context.Context, projectID uuid.UUID) ([]*database.IdentityCluster, error) {
+	id := database.PgUUID(projectID)
+	state, err := v.db.GetIdentityClusterState(ctx, id)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("failed to get identity cluster state: %w", err)
+	}
+	if err != nil || state.Stale {
+		if err := v.RefreshIdentityClusters(ctx, projectID); err != nil {
+			return nil, err
+		}
+	}
+	rows, err := v.db.ListIdentityClusters(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list identity clusters: %w", err)
+	}
+	return rows, nil
+}
+
+// clusterSameAs joins the nodes of the accepted same_as edges with
+// union-find and returns the clusters, each sorted by node ID
+func clusterSameAs(edges []*database.ListProjectSameAsEdgesRow) [][]pgtype.UUID {
+	parent := make(map[pgtype.UUID]pgtype.UUID)
+	var find func(pgtype.UUID) pgtype.UUID
+	find = func(n pgtype.UUID) pgtype.UUID {
+		p, ok := parent[n]
+		if !ok {
+			parent[n] = n
+			return n
+		}
+		if p == n {
+			return n
+		}
+		root := find(p)
+		parent[n] = root
+		return root
+	}
+
+	for _, e := range edges {
+		if !sameAsAccepted(e) {
+			continue
+		}
+		a, b := find(e.SourceNode), find(e.TargetNode)
+		if a == b {
+			continue
+		}
+		// The lower ID becomes the root, so that roots are stable
+		if lessUUID(b, a) {
+			a, b = b, a
+		}
+		parent[b] = a
+	}
+
+	byRoot := make(map[pgtype.UUID][]pgtype.UUID)
+	for n := range parent {
+		root := find(n)
+		byRoot[root] = append(byRoot[root], n)
+	}
+	var clusters [][]pgtype.UUID
+	for _, members := range byRoot {
+		if len(members) < 2 {
+			continue
+		}
+		sort.Slice(members, func(i, j int) bool { return lessUUID(members[i], members[j]) })
+		clusters = append(clusters, members)
+	}
+	sort.Slice(clusters, func(i, j int) bool { return lessUUID(clusters[i][0], clusters[j][0]) })
+	return clusters
+}
+
+// sameAsAccepted reports whether a same_as edge joins its nodes
+func sameAsAccepted(e *database.ListProjectSameAsEdgesRow) bool {
+	switch {
+	case e.IsNegated || e.Rejected:
+		return false
+	case e.Approved:
+		return true
+	}
+	return sameAsConfidence(e.Properties) >= MinSameAsConfidence
+}
+
+// sameAsConfidence reads a same_as edge's confidence from its properties.
+// An edge without one was asserted outright.
+func sameAsConfidence(properties []byte) float32 {
+	e := database.Edge{Properties: properties}
+	if c, ok := e.GetProperty("confidence", nil).(float64); ok {
+		return float32(c)
+	}
+	return 1
+}
+
+// canonicalMember picks the node that stands for an identity cluster:
+//   - single_source: the oldest node
+//   - human_decided: a node with approved provenance, by trust weight
+//   - majority: the node most trust-weighted distinct sources assert
+//   - otherwise: the node with the highest trust * confidence record
+//
+// Ties go to the older node.
+func canonicalMember(members []*database.Node, provenance map[pgtype.UUID][]*database.Provenance, strategy ViewStrategy) *database.Node {
+	type candidate struct {
+		node     *database.Node
+		approved bool
+		score    float32
+	}
+	candidates := make([]candidate, len(members))
+	for i, m := range members {
+		c := candidate{node: m}
+		records := assertionOnly(provenance[m.ID])
+		switch strategy {
+		case ViewStrategyMajority:
+			trust := make(map[pgtype.UUID]float32)
+			for _, p := range records {
+				if p.Trust > trust[p.SourceID] {
+					trust[p.SourceID] = p.Trust
+				}
+			}
+			for _, t := range trust {
+				c.score += t
+			}
+		case ViewStrategySingleSource:
+		default:
+			if best := strongest(records); best != nil {
+				c.score = best.Trust * best.Confidence
+			}
+		}
+		for _, p := range records {
+			if p.Status == string(database.StatusApproved) {
+				c.approved = true
+			}
+		}
+		candidates[i] = c
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if strategy == ViewStrategyHumanDecided && a.approved != b.approved {
+			return a.approved
+		}
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		if !a.node.CreatedAt.Time.Equal(b.node.CreatedAt.Time) {
+			return a.node.CreatedAt.Time.Before(b.node.CreatedAt.Time)
+		}
+		return lessUUID(a.node.ID, b.node.ID)
+	})
+	if len(candidates) == 0 {
+		return nil
+	}
+	return candidates[0].node
+}
+
+func lessUUID(a, b pgtype.UUID) bool {
+	return bytes.Compare(a.Bytes[:], b.Bytes[:]) < 0
+}
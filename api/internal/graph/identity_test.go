@@ -0,0 +1,201 @@
+package graph
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/einarsundgren/sikta/internal/database"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// sameAs is a same_as edge between test nodes a and b
+func sameAs(a, b byte, properties string) *database.ListProjectSameAsEdgesRow {
+	return &database.ListProjectSameAsEdgesRow{
+		ID:         testID(200 + a*10 + b),
+		SourceNode: testID(a),
+		TargetNode: testID(b),
+		Properties: []byte(properties),
+	}
+}
+
+func TestClusterSameAs(t *testing.T) {
+	approved := func(e *database.ListProjectSameAsEdgesRow) *database.ListProjectSameAsEdgesRow {
+		e.Approved = true
+		return e
+	}
+	rejected := func(e *database.ListProjectSameAsEdgesRow) *database.ListProjectSameAsEdgesRow {
+		e.Rejected = true
+		return e
+	}
+	negated := func(e *database.ListProjectSameAsEdgesRow) *database.ListProjectSameAsEdgesRow {
+		e.IsNegated = true
+		return e
+	}
+
+	tests := []struct {
+		name  string
+		edges []*database.ListProjectSameAsEdgesRow
+		want  [][]byte
+	}{
+		{
+			name: "no edges",
+		},
+		{
+			name:  "one edge",
+			edges: []*database.ListProjectSameAsEdgesRow{sameAs(2, 1, `{}`)},
+			want:  [][]byte{{1, 2}},
+		},
+		{
+			name: "transitive",
+			edges: []*database.ListProjectSameAsEdgesRow{
+				sameAs(3, 4, `{}`),
+				sameAs(1, 3, `{}`),
+				sameAs(4, 2, `{}`),
+			},
+			want: [][]byte{{1, 2, 3, 4}},
+		},
+		{
+			name: "separate clusters by lowest member",
+			edges: []*database.ListProjectSameAsEdgesRow{
+				sameAs(5, 4, `{}`),
+				sameAs(3, 1, `{}`),
+			},
+			want: [][]byte{{1, 3}, {4, 5}},
+		},
+		{
+			name: "cycles and repeated edges",
+			edges: []*database.ListProjectSameAsEdgesRow{
+				sameAs(1, 2, `{}`),
+				sameAs(2, 1, `{}`),
+				sameAs(1, 2, `{}`),
+				sameAs(2, 3, `{}`),
+				sameAs(3, 1, `{}`),
+			},
+			want: [][]byte{{1, 2, 3}},
+		},
+		{
+			name: "confidence threshold",
+			edges: []*database.ListProjectSameAsEdgesRow{
+				sameAs(1, 2, `{"confidence": 0.5}`),
+				sameAs(3, 4, `{"confidence": 0.49}`),
+				sameAs(5, 6, `{"confidence": 0.9}`),
+			},
+			want: [][]byte{{1, 2}, {5, 6}},
+		},
+		{
+			name: "approved joins below the threshold",
+			edges: []*database.ListProjectSameAsEdgesRow{
+				approved(sameAs(1, 2, `{"confidence": 0.1}`)),
+			},
+			want: [][]byte{{1, 2}},
+		},
+		{
+			name: "rejected and negated never join",
+			edges: []*database.ListProjectSameAsEdgesRow{
+				rejected(sameAs(1, 2, `{"confidence": 1}`)),
+				negated(approved(sameAs(3, 4, `{}`))),
+				sameAs(4, 5, `{}`),
+			},
+			want: [][]byte{{4, 5}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var want [][]pgtype.UUID
+			for _, cluster := range tt.want {
+				var members []pgtype.UUID
+				for _, n := range cluster {
+					members = append(members, testID(n))
+				}
+				want = append(want, members)
+			}
+			if got := clusterSameAs(tt.edges); !reflect.DeepEqual(got, want) {
+				t.Errorf("clusterSameAs() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestCanonicalMember(t *testing.T) {
+	node := func(n byte, created int) *database.Node {
+		return &database.Node{
+			ID:        testID(n),
+			CreatedAt: pgtype.Timestamptz{Time: time.Date(2024, 1, created, 0, 0, 0, 0, time.UTC), Valid: true},
+		}
+	}
+	record := func(source byte, trust, confidence float32, status database.ReviewStatus) *database.Provenance {
+		return &database.Provenance{
+			SourceID:   testID(source),
+			Trust:      trust,
+			Confidence: confidence,
+			Status:     string(status),
+			Location:   []byte(`{}`),
+		}
+	}
+
+	// Node 1 is oldest with one weak record. Node 2 has the strongest
+	// single record. Node 3 is asserted by the most sources. Node 4 is the
+	// only one approved.
+	members := []*database.Node{node(3, 3), node(2, 2), node(1, 1), node(4, 4)}
+	provenance := map[pgtype.UUID][]*database.Provenance{
+		testID(1): {record(10, 0.2, 0.5, database.StatusPending)},
+		testID(2): {
+			record(11, 1, 0.95, database.StatusPending),
+			record(11, 1, 0.9, database.StatusPending),
+		},
+		testID(3): {
+			record(12, 0.6, 0.5, database.StatusPending),
+			record(13, 0.6, 0.5, database.StatusPending),
+			record(14, 0.6, 0.5, database.StatusPending),
+		},
+		testID(4): {record(15, 0.3, 0.3, database.StatusApproved)},
+	}
+
+	tests := []struct {
+		strategy ViewStrategy
+		want     byte
+	}{
+		{ViewStrategySingleSource, 1},
+		{ViewStrategyTrustWeighted, 2},
+		{ViewStrategyMajority, 3},
+		{ViewStrategyHumanDecided, 4},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.strategy), func(t *testing.T) {
+			got := canonicalMember(members, provenance, tt.strategy)
+			if got == nil || got.ID != testID(tt.want) {
+				t.Errorf("canonicalMember() = %v, want node %d", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("ties go to the older node, then the lower ID", func(t *testing.T) {
+		tied := []*database.Node{node(7, 2), node(6, 2), node(5, 3)}
+		equal := map[pgtype.UUID][]*database.Provenance{
+			testID(5): {record(10, 1, 1, database.StatusPending)},
+			testID(6): {record(11, 1, 1, database.StatusPending)},
+			testID(7): {record(12, 1, 1, database.StatusPending)},
+		}
+		if got := canonicalMember(tied, equal, ViewStrategyMajority); got.ID != testID(6) {
+			t.Errorf("canonicalMember() = %v, want node 6", got.ID)
+		}
+	})
+
+	t.Run("ordering records do not count", func(t *testing.T) {
+		ordering := record(10, 1, 1, database.StatusApproved)
+		ordering.Location = []byte(`{"position_type": "narrative", "position": 1}`)
+		got := canonicalMember([]*database.Node{node(1, 1), node(2, 2)}, map[pgtype.UUID][]*database.Provenance{
+			testID(1): {ordering},
+			testID(2): {record(11, 0.5, 0.5, database.StatusPending)},
+		}, ViewStrategyHumanDecided)
+		if got.ID != testID(2) {
+			t.Errorf("canonicalMember() = %v, want node 2", got.ID)
+		}
+	})
+
+	if got := canonicalMember(nil, nil, ViewStrategyMajority); got != nil {
+		t.Errorf("canonicalMember(nil) = %v, want nil", got)
+	}
+}
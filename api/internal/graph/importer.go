@@ -0,0 +1,305 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/einarsundgren/sikta/internal/database"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// ImportData is a graph to import. It reads both the GetProjectGraph
+// response ({"nodes": [...], "edges": [...]}) and a sikta-eval
+// ExtractionResult, whose nodes and edges sit under "Documents".
+type ImportData struct {
+	Nodes     []ImportNode     `json:"nodes"`
+	Edges     []ImportEdge     `json:"edges"`
+	Documents []ImportDocument `json:"documents"`
+}
+
+// ImportDocument is one document's extraction in an ExtractionResult,
+// which has untagged field names
+type ImportDocument struct {
+	DocumentID string
+	Filename   string
+	Nodes      []ImportNode
+	Edges      []ImportEdge
+}
+
+// ImportNode is a node to import. The provenance fields are optional; a
+// node without confidence is taken as certain.
+type ImportNode struct {
+	ID               string         `json:"id"`
+	NodeType         string         `json:"node_type"`
+	Label            string         `json:"label"`
+	Properties       map[string]any `json:"properties"`
+	Confidence       *float32       `json:"confidence"`
+	Modality         string         `json:"modality"`
+	Excerpt          string         `json:"excerpt"`
+	ClaimedTimeStart string         `json:"claimed_time_start"`
+	ClaimedTimeEnd   string         `json:"claimed_time_end"`
+	ClaimedTimeText  string         `json:"claimed_time_text"`
+	ClaimedGeoRegion string         `json:"claimed_geo_region"`
+	ClaimedGeoText   string         `json:"claimed_geo_text"`
+}
+
+// ImportEdge is an edge to import. SourceNode and TargetNode are node IDs
+// from the same file or, as in extraction output, node labels.
+type ImportEdge struct {
+	ID         string         `json:"id"`
+	EdgeType   string         `json:"edge_type"`
+	SourceNode string         `json:"source_node"`
+	TargetNode string         `json:"target_node"`
+	Properties map[string]any `json:"properties"`
+	IsNegated  bool           `json:"is_negated"`
+	Confidence *float32       `json:"confidence"`
+	Modality   string         `json:"modality"`
+	Excerpt    string         `json:"excerpt"`
+}
+
+// ImportResult counts what an import created and lists what it skipped
+type ImportResult struct {
+	Nodes      int      `json:"nodes"`
+	Edges      int      `json:"edges"`
+	Provenance int      `json:"provenance"`
+	Skipped    []string `json:"skipped,omitempty"`
+}
+
+// Importer loads nodes, edges and provenance from JSON into a project
+type Importer struct {
+	db     *database.Queries
+	graph  *Service
+	logger *slog.Logger
+}
+
+// NewImporter creates a new importer
+func NewImporter(db *database.Queries, graph *Service, logger *slog.Logger) *Importer {
+	return &Importer{
+		db:     db,
+		graph:  graph,
+		logger: logger,
+	}
+}
+
+// ParseImport decodes an import file in either supported shape
+func ParseImport(data []byte) (*ImportData, error) {
+	var in ImportData
+	if err := json.Unmarshal(data, &in); err != nil {
+		return nil, fmt.Errorf("invalid import file: %w", err)
+	}
+	if len(in.Nodes) == 0 && len(in.Edges) == 0 && len(in.Documents) == 0 {
+		return nil, fmt.Errorf("invalid import file: no nodes, edges or documents")
+	}
+	return &in, nil
+}
+
+// Import creates the data's nodes and edges in a project, each with a
+// pending provenance record from a source's document node. Every element
+// gets a new ID; edges are rewired through the old IDs or, failing that,
+// through node labels in the same document. An ExtractionResult document
+// goes under the project source with the same filename if there is one,
+// else under sourceID. Document nodes in the data stand for the source
+// they are imported under and are not copied. The import runs in one
+// transaction, so a failure leaves the project as it was.
+func (i *Importer) Import(ctx context.Context, projectID, sourceID uuid.UUID, in *ImportData) (*ImportResult, error) {
+	var result *ImportResult
+	err := i.db.InTx(ctx, func(q *database.Queries) error {
+		tx := NewImporter(q, NewService(q, i.logger), i.logger)
+		var err error
+		result, err = tx.importData(ctx, projectID, sourceID, in)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	i.logger.Info("graph imported", "project", projectID, "nodes", result.Nodes, "edges", result.Edges,
+		"provenance", result.Provenance, "skipped", len(result.Skipped))
+	return result, nil
+}
+
+func (i *Importer) importData(ctx context.Context, projectID, sourceID uuid.UUID, in *ImportData) (*ImportResult, error) {
+	sources, err := i.db.GetProjectSources(ctx, database.PgUUID(projectID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get project sources: %w", err)
+	}
+	var fallback *database.Source
+	byFilename := make(map[string]*database.Source)
+	for _, s := range sources {
+		byFilename[s.Filename] = s
+		if s.ID == database.PgUUID(sourceID) {
+			fallback = s
+		}
+	}
+	if fallback == nil {
+		return nil, fmt.Errorf("source %s is not in project %s", sourceID, projectID)
+	}
+
+	result := &ImportResult{}
+	if len(in.Nodes) > 0 || len(in.Edges) > 0 {
+		if err := i.importDocument(ctx, fallback, in.Nodes, in.Edges, result); err != nil {
+			return nil, err
+		}
+	}
+	for _, doc := range in.Documents {
+		source := fallback
+		if s, ok := byFilename[doc.Filename]; ok && doc.Filename != "" {
+			source = s
+		}
+		if err := i.importDocument(ctx, source, doc.Nodes, doc.Edges, result); err != nil {
+			return nil, fmt.Errorf("document %s: %w", doc.DocumentID, err)
+		}
+	}
+	return result, nil
+}
+
+// importDocument imports one set of nodes and edges under a source. Old
+// IDs and labels resolve only within the set.
+func (i *Importer) importDocument(ctx context.Context, source *database.Source, nodes []ImportNode, edges []ImportEdge, result *ImportResult) error {
+	docNodeID, err := i.documentNode(ctx, source)
+	if err != nil {
+		return err
+	}
+	trust := float32(1.0)
+	if source.SourceTrust.Valid {
+		trust = source.SourceTrust.Float32
+	}
+
+	ids := make(map[string]uuid.UUID)
+	labels := make(map[string]uuid.UUID)
+	for _, n := range nodes {
+		if n.NodeType == database.NodeTypeDocument {
+			if n.ID != "" {
+				ids[n.ID] = docNodeID
+			}
+			continue
+		}
+		if n.NodeType == "" || n.Label == "" {
+			result.Skipped = append(result.Skipped, fmt.Sprintf("node %q: node_type and label are required", n.Label))
+			continue
+		}
+
+		nodeID, err := i.graph.CreateNode(ctx, CreateNodeParams{
+			NodeType:   n.NodeType,
+			Label:      n.Label,
+			Properties: n.Properties,
+		})
+		if err != nil {
+			return err
+		}
+		result.Nodes++
+		if n.ID != "" {
+			ids[n.ID] = nodeID
+		}
+		labels[n.Label] = nodeID
+
+		_, err = i.graph.CreateProvenance(ctx, CreateProvenanceParams{
+			TargetType:        "node",
+			TargetID:          nodeID,
+			SourceID:          docNodeID,
+			Excerpt:           n.Excerpt,
+			Confidence:        importConfidence(n.Confidence),
+			Trust:             trust,
+			Modality:          importModality(n.Modality),
+			Status:            database.StatusPending,
+			ClaimedTimeStart:  parseClaimedTime(n.ClaimedTimeStart),
+			ClaimedTimeEnd:    parseClaimedTime(n.ClaimedTimeEnd),
+			ClaimedTimeText:   n.ClaimedTimeText,
+			ClaimedGeoRegion:  n.ClaimedGeoRegion,
+			ClaimedGeoText:    n.ClaimedGeoText,
+			ClaimedProperties: n.Properties,
+		})
+		if err != nil {
+			return err
+		}
+		result.Provenance++
+	}
+
+	resolve := func(ref string) (uuid.UUID, bool) {
+		if id, ok := ids[ref]; ok {
+			return id, true
+		}
+		id, ok := labels[ref]
+		return id, ok
+	}
+	for _, e := range edges {
+		from, okFrom := resolve(e.SourceNode)
+		to, okTo := resolve(e.TargetNode)
+		if !okFrom || !okTo || e.EdgeType == "" {
+			result.Skipped = append(result.Skipped, fmt.Sprintf("edge %s %q -> %q: unknown node or missing edge_type", e.EdgeType, e.SourceNode, e.TargetNode))
+			continue
+		}
+
+		edgeID, err := i.graph.CreateEdge(ctx, CreateEdgeParams{
+			EdgeType:   e.EdgeType,
+			SourceNode: from,
+			TargetNode: to,
+			Properties: e.Properties,
+			IsNegated:  e.IsNegated,
+		})
+		if err != nil {
+			return err
+		}
+		result.Edges++
+
+		_, err = i.graph.CreateProvenance(ctx, CreateProvenanceParams{
+			TargetType:        "edge",
+			TargetID:          edgeID,
+			SourceID:          docNodeID,
+			Excerpt:           e.Excerpt,
+			Confidence:        importConfidence(e.Confidence),
+			Trust:             trust,
+			Modality:          importModality(e.Modality),
+			Status:            database.StatusPending,
+			ClaimedProperties: e.Properties,
+		})
+		if err != nil {
+			return err
+		}
+		result.Provenance++
+	}
+	return nil
+}
+
+// documentNode returns a source's document node, creating it if the source
+// has not been extracted yet
+func (i *Importer) documentNode(ctx context.Context, source *database.Source) (uuid.UUID, error) {
+	node, err := i.db.GetDocumentNodeByLegacySourceID(ctx, database.UUIDStr(source.ID))
+	if err == nil {
+		return uuid.UUID(node.ID.Bytes), nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return uuid.Nil, fmt.Errorf("failed to get document node: %w", err)
+	}
+	return NewMigrator(i.db, i.graph, i.logger).MigrateSourceToNode(ctx, source)
+}
+
+func importConfidence(c *float32) float32 {
+	if c == nil {
+		return 1.0
+	}
+	return *c
+}
+
+func importModality(m string) string {
+	if m == "" {
+		return database.ModalityAsserted
+	}
+	return m
+}
+
+// parseClaimedTime parses a YYYY-MM-DD or RFC3339 time, ignoring anything
+// else; the free-text form is kept in ClaimedTimeText
+func parseClaimedTime(s string) *time.Time {
+	for _, layout := range []string{"2006-01-02", time.RFC3339} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return &t
+		}
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+package graph
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/einarsundgren/sikta/internal/database"
+	"github.com/einarsundgren/sikta/internal/database/dbtest"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestParseImport(t *testing.T) {
+	tests := []struct {
+		name      string
+		data      string
+		nodes     int
+		edges     int
+		documents int
+		wantErr   bool
+	}{
+		{
+			name:  "project graph",
+			data:  `{"nodes": [{"id": "n1", "node_type": "person", "label": "Anna"}], "edges": [{"edge_type": "knows", "source_node": "n1", "target_node": "n1"}]}`,
+			nodes: 1,
+			edges: 1,
+		},
+		{
+			// ExtractionResult field names are untagged
+			name:      "extraction result",
+			data:      `{"Documents": [{"DocumentID": "d1", "Filename": "brev.txt", "Nodes": [{"node_type": "person", "label": "Anna"}], "Edges": []}]}`,
+			documents: 1,
+		},
+		{name: "empty", data: `{"nodes": [], "edges": []}`, wantErr: true},
+		{name: "not an object", data: `[]`, wantErr: true},
+		{name: "invalid json", data: `{"nodes": [`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseImport([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseImport() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(got.Nodes) != tt.nodes || len(got.Edges) != tt.edges || len(got.Documents) != tt.documents {
+				t.Errorf("ParseImport() = %d nodes, %d edges, %d documents, want %d, %d, %d",
+					len(got.Nodes), len(got.Edges), len(got.Documents), tt.nodes, tt.edges, tt.documents)
+			}
+			if tt.documents > 0 && (got.Documents[0].Filename != "brev.txt" || len(got.Documents[0].Nodes) != 1) {
+				t.Errorf("ParseImport() document = %+v, want brev.txt with one node", got.Documents[0])
+			}
+		})
+	}
+}
+
+// importDB is a fake database for importing under a source whose document
+// node is testID(50). Created nodes get IDs from testID(60) by label,
+// created edges from testID(80).
+func importDB(labels ...string) *dbtest.DB {
+	db := dbtest.New()
+	db.Return("GetDocumentNodeByLegacySourceID", &database.Node{ID: testID(50), NodeType: database.NodeTypeDocument}, nil)
+	db.On("CreateNode", func(args []interface{}) (interface{}, error) {
+		for i, label := range labels {
+			if label == args[1] {
+				return &database.Node{ID: testID(60 + byte(i)), NodeType: args[0].(string), Label: label}, nil
+			}
+		}
+		return nil, nil
+	})
+	edges := 0
+	db.On("CreateEdge", func(args []interface{}) (interface{}, error) {
+		edges++
+		return &database.Edge{ID: testID(80 + byte(edges)), EdgeType: args[0].(string), SourceNode: args[1].(pgtype.UUID), TargetNode: args[2].(pgtype.UUID)}, nil
+	})
+	db.Return("CreateProvenance", &database.Provenance{ID: testID(99)}, nil)
+	return db
+}
+
+func TestImportDocument(t *testing.T) {
+	db := importDB("Anna", "Jonas")
+	svc := testService(db)
+	i := NewImporter(svc.db, svc, svc.logger)
+
+	confidence := float32(0.6)
+	nodes := []ImportNode{
+		{ID: "n1", NodeType: "person", Label: "Anna"},
+		{ID: "d1", NodeType: database.NodeTypeDocument, Label: "brev.txt"},
+		{NodeType: "person", Label: "Jonas", Confidence: &confidence, Modality: database.ModalityHypothetical, ClaimedTimeStart: "1850-03-01"},
+		{NodeType: "", Label: "utan typ"},
+	}
+	edges := []ImportEdge{
+		{EdgeType: "knows", SourceNode: "n1", TargetNode: "Jonas"},       // ID, then label
+		{EdgeType: "mentioned_in", SourceNode: "Anna", TargetNode: "d1"}, // the document stands for the source
+		{EdgeType: "knows", SourceNode: "n1", TargetNode: "Okänd"},
+		{SourceNode: "n1", TargetNode: "Jonas"},
+	}
+	source := &database.Source{ID: testID(40), SourceTrust: pgtype.Float4{Float32: 0.7, Valid: true}}
+
+	result := &ImportResult{}
+	if err := i.importDocument(context.Background(), source, nodes, edges, result); err != nil {
+		t.Fatalf("importDocument() error = %v", err)
+	}
+	if result.Nodes != 2 || result.Edges != 2 || result.Provenance != 4 || len(result.Skipped) != 3 {
+		t.Errorf("importDocument() = %+v, want 2 nodes, 2 edges, 4 provenance, 3 skipped", result)
+	}
+	if n := len(db.Calls("CreateNode")); n != 2 {
+		t.Errorf("CreateNode called %d times, want 2; document nodes are not copied", n)
+	}
+
+	anna, jonas, docNode := testID(60), testID(61), testID(50)
+	wantEdges := [][2]pgtype.UUID{{anna, jonas}, {anna, docNode}}
+	for n, call := range db.Calls("CreateEdge") {
+		if got := [2]pgtype.UUID{call.Args[1].(pgtype.UUID), call.Args[2].(pgtype.UUID)}; got != wantEdges[n] {
+			t.Errorf("edge %d joins %v, want %v", n, got, wantEdges[n])
+		}
+	}
+
+	prov := db.Calls("CreateProvenance")
+	if len(prov) != 4 {
+		t.Fatalf("CreateProvenance called %d times, want 4", len(prov))
+	}
+	tests := []struct {
+		name       string
+		args       []interface{}
+		confidence float32
+		modality   string
+		timeStart  bool
+	}{
+		{"node without provenance fields", prov[0].Args, 1, database.ModalityAsserted, false},
+		{"node with provenance fields", prov[1].Args, 0.6, database.ModalityHypothetical, true},
+		{"edge", prov[2].Args, 1, database.ModalityAsserted, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.args[3]; got != docNode {
+				t.Errorf("source = %v, want the document node", got)
+			}
+			if tt.args[6] != tt.confidence || tt.args[7] != float32(0.7) {
+				t.Errorf("confidence, trust = %v, %v, want %v, 0.7", tt.args[6], tt.args[7], tt.confidence)
+			}
+			if tt.args[8] != string(database.StatusPending) || tt.args[9] != tt.modality {
+				t.Errorf("status, modality = %v, %v, want pending, %s", tt.args[8], tt.args[9], tt.modality)
+			}
+			if got := tt.args[10].(pgtype.Timestamptz).Valid; got != tt.timeStart {
+				t.Errorf("claimed time start set = %v, want %v", got, tt.timeStart)
+			}
+		})
+	}
+}
+
+func TestImportDefaults(t *testing.T) {
+	c := float32(0.4)
+	if got := importConfidence(nil); got != 1 {
+		t.Errorf("importConfidence(nil) = %v, want 1", got)
+	}
+	if got := importConfidence(&c); got != 0.4 {
+		t.Errorf("importConfidence(0.4) = %v, want 0.4", got)
+	}
+	if got := importModality(""); got != database.ModalityAsserted {
+		t.Errorf("importModality(\"\") = %q, want %q", got, database.ModalityAsserted)
+	}
+	if got := importModality(database.ModalityDenied); got != database.ModalityDenied {
+		t.Errorf("importModality(%q) = %q, want it unchanged", database.ModalityDenied, got)
+	}
+}
+
+func TestParseClaimedTime(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Time // zero for no time
+	}{
+		{"1850-03-01", time.Date(1850, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{"1850-03-01T14:30:00Z", time.Date(1850, 3, 1, 14, 30, 0, 0, time.UTC)},
+		{"1850-03-01T14:30:00+01:00", time.Date(1850, 3, 1, 13, 30, 0, 0, time.UTC)},
+		{"", time.Time{}},
+		{"1850", time.Time{}},
+		{"våren 1850", time.Time{}},
+		{"01/03/1850", time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got := parseClaimedTime(tt.in)
+			if tt.want.IsZero() {
+				if got != nil {
+					t.Errorf("parseClaimedTime(%q) = %v, want nil", tt.in, got)
+				}
+				return
+			}
+			if got == nil || !got.Equal(tt.want) {
+				t.Errorf("parseClaimedTime(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
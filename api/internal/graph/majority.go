@@ -0,0 +1,258 @@
+package graph
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/einarsundgren/sikta/internal/database"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ClaimedValue is what a provenance record asserts about its target beyond
+// its existence: a time range, a place, or the value of a property
+type ClaimedValue struct {
+	TimeStart *time.Time
+	TimeEnd   *time.Time
+	TimeText  string
+	GeoRegion string
+	GeoText   string
+
+	Property      string // property key, set with PropertyValue
+	PropertyValue any    // decoded JSON value
+}
+
+// ValueSupport is one value asserted about a target and who asserts it.
+// Weight is the sum over the distinct sources asserting the value of the
+// highest trust among each source's records for it.
+type ValueSupport struct {
+	Value      ClaimedValue
+	Sources    []pgtype.UUID // document nodes, in order of first assertion
+	Weight     float32
+	Provenance []*database.Provenance
+}
+
+// Resolution is the winning value of one kind of claim and the values that
+// lost to it. Values hold only the fields of their kind.
+type Resolution struct {
+	Winner     ValueSupport
+	Dissenting []ValueSupport // by descending weight
+}
+
+// Majority is the majority resolution of a target's claims. Time, place and
+// each property are resolved separately, so a source that gives only a date
+// neither supports nor disputes a place. A kind no record asserts is nil;
+// Properties holds only keys some record asserts.
+type Majority struct {
+	Time       *Resolution
+	Geo        *Resolution
+	Properties map[string]*Resolution
+}
+
+// ResolveMajority groups assertion provenance records by the time range,
+// place and property values they claim, and picks for each the value backed
+// by the most trust-weighted distinct sources. Ties go to the value with
+// more sources, then to the one asserted first. Rejected records abstain.
+func ResolveMajority(provenance []*database.Provenance) *Majority {
+	var records []*database.Provenance
+	for _, p := range assertionOnly(provenance) {
+		if !p.IsRejected() {
+			records = append(records, p)
+		}
+	}
+	m := &Majority{
+		Time: resolve(records, claimedTime),
+		Geo:  resolve(records, claimedGeo),
+	}
+
+	claims := make(map[*database.Provenance]map[string]any, len(records))
+	var keys []string
+	seen := make(map[string]bool)
+	for _, p := range records {
+		props := claimedProperties(p)
+		claims[p] = props
+		for k := range props {
+			if !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+	for _, k := range keys {
+		r := resolve(records, func(p *database.Provenance) (ClaimedValue, string, bool) {
+			return claimedProperty(k, claims[p])
+		})
+		if r == nil {
+			continue
+		}
+		if m.Properties == nil {
+			m.Properties = make(map[string]*Resolution)
+		}
+		m.Properties[k] = r
+	}
+	return m
+}
+
+// Provenance returns the strongest record behind the winning value: the
+// time if one was claimed, otherwise the place. It is nil if neither was.
+func (m *Majority) Provenance() *database.Provenance {
+	switch {
+	case m.Time != nil:
+		return strongest(m.Time.Winner.Provenance)
+	case m.Geo != nil:
+		return strongest(m.Geo.Winner.Provenance)
+	}
+	return nil
+}
+
+// claimedTime returns the time a record claims and a key equal for records
+// claiming the same time. Dates decide the key when given, so "June 1811"
+// and "early summer 1811" agree if they were normalized to the same range.
+func claimedTime(p *database.Provenance) (ClaimedValue, string, bool) {
+	var c ClaimedValue
+	var key string
+	if p.ClaimedTimeStart.Valid {
+		t := p.ClaimedTimeStart.Time
+		c.TimeStart = &t
+		key = t.UTC().Format(time.RFC3339)
+	}
+	if p.ClaimedTimeEnd.Valid {
+		t := p.ClaimedTimeEnd.Time
+		c.TimeEnd = &t
+		key += "/" + t.UTC().Format(time.RFC3339)
+	}
+	if p.ClaimedTimeText.Valid {
+		c.TimeText = strings.TrimSpace(p.ClaimedTimeText.String)
+	}
+	if key == "" {
+		key = "text:" + normalizeClaim(c.TimeText)
+	}
+	return c, key, c.TimeStart != nil || c.TimeEnd != nil || c.TimeText != ""
+}
+
+// claimedGeo returns the place a record claims, keyed by region when given
+func claimedGeo(p *database.Provenance) (ClaimedValue, string, bool) {
+	var c ClaimedValue
+	if p.ClaimedGeoRegion.Valid {
+		c.GeoRegion = strings.TrimSpace(p.ClaimedGeoRegion.String)
+	}
+	if p.ClaimedGeoText.Valid {
+		c.GeoText = strings.TrimSpace(p.ClaimedGeoText.String)
+	}
+	key := "region:" + normalizeClaim(c.GeoRegion)
+	if c.GeoRegion == "" {
+		key = "text:" + normalizeClaim(c.GeoText)
+	}
+	return c, key, c.GeoRegion != "" || c.GeoText != ""
+}
+
+// claimedProperties decodes the property values a record claims, leaving
+// out nulls. A record whose claims don't decode claims nothing.
+func claimedProperties(p *database.Provenance) map[string]any {
+	if len(p.ClaimedProperties) == 0 {
+		return nil
+	}
+	var props map[string]any
+	if err := json.Unmarshal(p.ClaimedProperties, &props); err != nil {
+		return nil
+	}
+	for k, v := range props {
+		if v == nil {
+			delete(props, k)
+		}
+	}
+	return props
+}
+
+// claimedProperty returns the value claimed for one property. Strings agree
+// when they differ only in case and spacing; other values when their JSON
+// is equal, so 1 and 1.0 agree and objects compare key by key.
+func claimedProperty(key string, props map[string]any) (ClaimedValue, string, bool) {
+	v, ok := props[key]
+	if !ok {
+		return ClaimedValue{}, "", false
+	}
+	c := ClaimedValue{Property: key, PropertyValue: v}
+	if s, isString := v.(string); isString {
+		return c, "text:" + normalizeClaim(s), strings.TrimSpace(s) != ""
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return c, "", false
+	}
+	return c, "json:" + string(data), true
+}
+
+// resolve groups records by the value claim extracts and ranks the groups
+func resolve(records []*database.Provenance, claim func(*database.Provenance) (ClaimedValue, string, bool)) *Resolution {
+	type group struct {
+		support ValueSupport
+		trust   map[pgtype.UUID]float32 // highest trust per source
+		first   time.Time               // earliest record
+	}
+	var groups []*group
+	byKey := make(map[string]*group)
+	for _, p := range records {
+		value, key, ok := claim(p)
+		if !ok {
+			continue
+		}
+		g := byKey[key]
+		if g == nil {
+			g = &group{support: ValueSupport{Value: value}, trust: make(map[pgtype.UUID]float32), first: p.CreatedAt.Time}
+			byKey[key] = g
+			groups = append(groups, g)
+		}
+		g.support.Provenance = append(g.support.Provenance, p)
+		if p.CreatedAt.Time.Before(g.first) {
+			g.first = p.CreatedAt.Time
+		}
+		if t, seen := g.trust[p.SourceID]; !seen {
+			g.support.Sources = append(g.support.Sources, p.SourceID)
+			g.trust[p.SourceID] = p.Trust
+		} else if p.Trust > t {
+			g.trust[p.SourceID] = p.Trust
+		}
+	}
+	if len(groups) == 0 {
+		return nil
+	}
+
+	for _, g := range groups {
+		for _, t := range g.trust {
+			g.support.Weight += t
+		}
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		a, b := groups[i], groups[j]
+		if a.support.Weight != b.support.Weight {
+			return a.support.Weight > b.support.Weight
+		}
+		if len(a.support.Sources) != len(b.support.Sources) {
+			return len(a.support.Sources) > len(b.support.Sources)
+		}
+		return a.first.Before(b.first)
+	})
+
+	r := &Resolution{Winner: groups[0].support}
+	for _, g := range groups[1:] {
+		r.Dissenting = append(r.Dissenting, g.support)
+	}
+	return r
+}
+
+// strongest returns the record with the highest trust * confidence
+func strongest(provenance []*database.Provenance) *database.Provenance {
+	var best *database.Provenance
+	for _, p := range provenance {
+		if best == nil || p.Trust*p.Confidence > best.Trust*best.Confidence {
+			best = p
+		}
+	}
+	return best
+}
+
+func normalizeClaim(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
@@ -0,0 +1,189 @@
+package graph
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/einarsundgren/sikta/internal/database"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func testID(n byte) pgtype.UUID {
+	return database.PgUUID(uuid.UUID{0x5b, 0x1c, 0x8f, 0x8e, 15: n})
+}
+
+// claim is a provenance record from document source, created at minute n,
+// claiming the given properties
+func claim(n, source byte, trust float32, props string) *database.Provenance {
+	return &database.Provenance{
+		ID:                testID(100 + n),
+		SourceID:          testID(source),
+		Trust:             trust,
+		Confidence:        1,
+		Location:          []byte(`{}`),
+		ClaimedProperties: []byte(props),
+		CreatedAt:         pgtype.Timestamptz{Time: time.Date(2024, 1, 1, 0, int(n), 0, 0, time.UTC), Valid: true},
+	}
+}
+
+// rejected marks a provenance record as rejected in review
+func rejected(p *database.Provenance) *database.Provenance {
+	p.Status = string(database.StatusRejected)
+	return p
+}
+
+func TestResolveMajorityProperties(t *testing.T) {
+	tests := []struct {
+		name       string
+		records    []*database.Provenance
+		key        string
+		winner     any
+		sources    int
+		dissenting []any
+	}{
+		{
+			name: "two sources outvote one",
+			records: []*database.Provenance{
+				claim(1, 1, 1, `{"born": "1811"}`),
+				claim(2, 2, 1, `{"born": "1812"}`),
+				claim(3, 3, 1, `{"born": "1812"}`),
+			},
+			key:        "born",
+			winner:     "1812",
+			sources:    2,
+			dissenting: []any{"1811"},
+		},
+		{
+			name: "one source counts once",
+			records: []*database.Provenance{
+				claim(1, 1, 1, `{"role": "vd"}`),
+				claim(2, 1, 1, `{"role": "vd"}`),
+				claim(3, 2, 0.8, `{"role": "ägare"}`),
+				claim(4, 3, 0.8, `{"role": "ägare"}`),
+			},
+			key:        "role",
+			winner:     "ägare",
+			sources:    2,
+			dissenting: []any{"vd"},
+		},
+		{
+			name: "trust outweighs count",
+			records: []*database.Provenance{
+				claim(1, 1, 0.3, `{"role": "vd"}`),
+				claim(2, 2, 0.3, `{"role": "vd"}`),
+				claim(3, 3, 0.9, `{"role": "ägare"}`),
+			},
+			key:        "role",
+			winner:     "ägare",
+			sources:    1,
+			dissenting: []any{"vd"},
+		},
+		{
+			name: "strings agree across case and spacing",
+			records: []*database.Provenance{
+				claim(1, 1, 1, `{"city": "Sundsvall"}`),
+				claim(2, 2, 1, `{"city": "  sundsvall "}`),
+			},
+			key:     "city",
+			winner:  "Sundsvall",
+			sources: 2,
+		},
+		{
+			name: "numbers and objects compare by value",
+			records: []*database.Provenance{
+				claim(1, 1, 1, `{"share": 0.5, "address": {"a": 1, "b": 2}}`),
+				claim(2, 2, 1, `{"share": 0.50, "address": {"b": 2, "a": 1}}`),
+				claim(3, 3, 1, `{"share": 1}`),
+			},
+			key:        "share",
+			winner:     0.5,
+			sources:    2,
+			dissenting: []any{1.0},
+		},
+		{
+			name: "tie goes to the first asserted",
+			records: []*database.Provenance{
+				claim(2, 1, 1, `{"born": "1812"}`),
+				claim(1, 2, 1, `{"born": "1811"}`),
+			},
+			key:        "born",
+			winner:     "1811",
+			sources:    1,
+			dissenting: []any{"1812"},
+		},
+		{
+			name: "nulls and records without the key abstain",
+			records: []*database.Provenance{
+				claim(1, 1, 1, `{"born": "1811"}`),
+				claim(2, 2, 1, `{"born": null}`),
+				claim(3, 3, 1, `{}`),
+				claim(4, 4, 1, ``),
+			},
+			key:     "born",
+			winner:  "1811",
+			sources: 1,
+		},
+		{
+			name: "rejected claim abstains",
+			records: []*database.Provenance{
+				claim(1, 1, 0.5, `{"born": "1811"}`),
+				rejected(claim(2, 2, 1, `{"born": "1812"}`)),
+				rejected(claim(3, 3, 1, `{"born": "1812"}`)),
+			},
+			key:     "born",
+			winner:  "1811",
+			sources: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := ResolveMajority(tt.records).Properties[tt.key]
+			if r == nil {
+				t.Fatalf("Properties[%q] = nil", tt.key)
+			}
+			if r.Winner.Value.Property != tt.key {
+				t.Errorf("Winner.Value.Property = %q, want %q", r.Winner.Value.Property, tt.key)
+			}
+			if !reflect.DeepEqual(r.Winner.Value.PropertyValue, tt.winner) {
+				t.Errorf("winner = %#v, want %#v", r.Winner.Value.PropertyValue, tt.winner)
+			}
+			if len(r.Winner.Sources) != tt.sources {
+				t.Errorf("winner has %d sources, want %d", len(r.Winner.Sources), tt.sources)
+			}
+			var dissenting []any
+			for _, d := range r.Dissenting {
+				dissenting = append(dissenting, d.Value.PropertyValue)
+			}
+			if !reflect.DeepEqual(dissenting, tt.dissenting) {
+				t.Errorf("dissenting = %#v, want %#v", dissenting, tt.dissenting)
+			}
+		})
+	}
+}
+
+func TestResolveMajorityPropertiesPerKey(t *testing.T) {
+	// A source giving only one property neither supports nor disputes others
+	m := ResolveMajority([]*database.Provenance{
+		claim(1, 1, 1, `{"born": "1811", "role": "vd"}`),
+		claim(2, 2, 1, `{"role": "ägare"}`),
+		claim(3, 3, 1, `{"role": "ägare", "blank": " "}`),
+	})
+	if got := len(m.Properties); got != 2 {
+		t.Fatalf("len(Properties) = %d, want 2", got)
+	}
+	if r := m.Properties["born"]; r == nil || len(r.Dissenting) != 0 {
+		t.Errorf("Properties[born] = %+v, want one undisputed value", r)
+	}
+	if r := m.Properties["role"]; r == nil || r.Winner.Value.PropertyValue != "ägare" {
+		t.Errorf("Properties[role] = %+v, want ägare", r)
+	}
+	if m.Time != nil || m.Geo != nil {
+		t.Errorf("Time, Geo = %v, %v, want nil", m.Time, m.Geo)
+	}
+	if ResolveMajority(nil).Properties != nil {
+		t.Error("Properties with no records is not nil")
+	}
+}
@@ -93,6 +93,9 @@ func (m *Migrator) MigrateChunkToNode(ctx context.Context, chunk *database.Chunk
 	if chunk.ChapterNumber.Valid {
 		properties["chapter_number"] = chunk.ChapterNumber.Int32
 	}
+	if chunk.SectionID.Valid {
+		properties["section_id"] = chunk.SectionID.String
+	}
 
 	// Create chunk node
 	nodeID, err := m.graph.CreateNode(ctx, CreateNodeParams{
@@ -107,6 +110,7 @@ func (m *Migrator) MigrateChunkToNode(ctx context.Context, chunk *database.Chunk
 	// Create provenance
 	location := database.Location{
 		Chapter: chunk.ChapterTitle.String,
+		Section: chunk.SectionID.String,
 	}
 	if chunk.PageStart.Valid {
 		location.Page = int(chunk.PageStart.Int32)
@@ -255,14 +259,15 @@ func (m *Migrator) MigrateEntityToNode(ctx context.Context, entity *database.Ent
 
 	// Create provenance
 	_, err = m.graph.CreateProvenance(ctx, CreateProvenanceParams{
-		TargetType: "node",
-		TargetID:   nodeID,
-		SourceID:   docNodeID,
-		Excerpt:    description,
-		Confidence: entity.Confidence,
-		Trust:      1.0,
-		Modality:   database.ModalityAsserted,
-		Status:     database.ReviewStatus(entity.ReviewStatus),
+		TargetType:        "node",
+		TargetID:          nodeID,
+		SourceID:          docNodeID,
+		Excerpt:           description,
+		Confidence:        entity.Confidence,
+		Trust:             1.0,
+		Modality:          database.ModalityAsserted,
+		Status:            database.ReviewStatus(entity.ReviewStatus),
+		ClaimedProperties: properties,
 	})
 	if err != nil {
 		m.logger.Warn("failed to create provenance for entity", "entity_id", entityID, "error", err)
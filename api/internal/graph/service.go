@@ -93,27 +93,37 @@ func (s *Service) CreateProvenance(ctx context.Context, params CreateProvenanceP
 		return uuid.Nil, fmt.Errorf("failed to marshal location: %w", err)
 	}
 
+	claimedProperties := []byte("{}")
+	if len(params.ClaimedProperties) > 0 {
+		claimedProperties, err = json.Marshal(params.ClaimedProperties)
+		if err != nil {
+			return uuid.Nil, fmt.Errorf("failed to marshal claimed properties: %w", err)
+		}
+	}
+
 	var claimedByUUID pgtype.UUID
 	if params.ClaimedBy != nil {
 		claimedByUUID = database.PgUUID(*params.ClaimedBy)
 	}
 
 	prov, err := s.db.CreateProvenance(ctx, database.CreateProvenanceParams{
-		TargetType:       params.TargetType,
-		TargetID:         database.PgUUID(params.TargetID),
-		SourceID:         database.PgUUID(params.SourceID),
-		Excerpt:          params.Excerpt,
-		Location:         locationJSON,
-		Confidence:       params.Confidence,
-		Trust:            params.Trust,
-		Status:           string(params.Status),
-		Modality:         params.Modality,
-		ClaimedTimeStart: database.PgTimePtr(params.ClaimedTimeStart),
-		ClaimedTimeEnd:   database.PgTimePtr(params.ClaimedTimeEnd),
-		ClaimedTimeText:  database.PgText(params.ClaimedTimeText),
-		ClaimedGeoRegion: database.PgText(params.ClaimedGeoRegion),
-		ClaimedGeoText:   database.PgText(params.ClaimedGeoText),
-		ClaimedBy:        claimedByUUID,
+		ID:                database.PgUUID(uuid.New()),
+		TargetType:        params.TargetType,
+		TargetID:          database.PgUUID(params.TargetID),
+		SourceID:          database.PgUUID(params.SourceID),
+		Excerpt:           params.Excerpt,
+		Location:          locationJSON,
+		Confidence:        params.Confidence,
+		Trust:             params.Trust,
+		Status:            string(params.Status),
+		Modality:          params.Modality,
+		ClaimedTimeStart:  database.PgTimePtr(params.ClaimedTimeStart),
+		ClaimedTimeEnd:    database.PgTimePtr(params.ClaimedTimeEnd),
+		ClaimedTimeText:   database.PgText(params.ClaimedTimeText),
+		ClaimedGeoRegion:  database.PgText(params.ClaimedGeoRegion),
+		ClaimedGeoText:    database.PgText(params.ClaimedGeoText),
+		ClaimedBy:         claimedByUUID,
+		ClaimedProperties: claimedProperties,
 	})
 	if err != nil {
 		return uuid.Nil, fmt.Errorf("failed to create provenance: %w", err)
@@ -0,0 +1,225 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/einarsundgren/sikta/internal/database"
+	"github.com/einarsundgren/sikta/internal/document"
+	"github.com/google/uuid"
+)
+
+// TableMapper writes the rows of CSV/XLSX sources straight into the graph
+// using a column mapping. Structured data needs no LLM: every node and edge
+// is stored with full confidence and provenance pointing at its sheet, row
+// and column.
+type TableMapper struct {
+	db     *database.Queries
+	graph  *Service
+	logger *slog.Logger
+}
+
+// NewTableMapper creates a new table mapper
+func NewTableMapper(db *database.Queries, graph *Service, logger *slog.Logger) *TableMapper {
+	return &TableMapper{
+		db:     db,
+		graph:  graph,
+		logger: logger,
+	}
+}
+
+// TableMapResult summarises what a mapping produced.
+type TableMapResult struct {
+	Rows     int      `json:"rows"`
+	Nodes    int      `json:"nodes"`
+	Edges    int      `json:"edges"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// MapSource maps a source's tables with the given mappings. Each mapping is
+// applied to every sheet it covers; sheets missing a mapped column are
+// skipped with a warning. Entity nodes are shared across rows by type and
+// label, value nodes are created per row.
+func (m *TableMapper) MapSource(ctx context.Context, source *database.Source, tables []*document.Table, mappings []*document.TableMapping) (*TableMapResult, error) {
+	docNodeID, err := m.documentNode(ctx, source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get document node: %w", err)
+	}
+
+	trust := float32(1.0)
+	if source.SourceTrust.Valid {
+		trust = source.SourceTrust.Float32
+	}
+
+	result := &TableMapResult{}
+	entities := make(map[string]uuid.UUID) // node_type|label → node
+
+	for _, mapping := range mappings {
+		for _, t := range tables {
+			rows, err := mapping.Apply(t)
+			if err != nil {
+				result.Warnings = append(result.Warnings, err.Error())
+				continue
+			}
+
+			for _, row := range rows {
+				ids := make(map[string]uuid.UUID, len(row.Nodes))
+				cells := make(map[string]document.MappedNode, len(row.Nodes))
+
+				for _, n := range row.Nodes {
+					id, created, err := m.storeNode(ctx, entities, n)
+					if err != nil {
+						return result, fmt.Errorf("row %d: %w", row.Row, err)
+					}
+					if created {
+						result.Nodes++
+					}
+					if err := m.storeProvenance(ctx, "node", id, docNodeID, trust, row, n, n.Column+": "+n.Cell, cellProperties(n)); err != nil {
+						return result, fmt.Errorf("row %d: %w", row.Row, err)
+					}
+					ids[n.Key] = id
+					cells[n.Key] = n
+				}
+
+				for _, e := range row.Edges {
+					from, to := cells[e.From], cells[e.To]
+					edgeID, err := m.graph.CreateEdge(ctx, CreateEdgeParams{
+						EdgeType:   e.EdgeType,
+						SourceNode: ids[e.From],
+						TargetNode: ids[e.To],
+					})
+					if err != nil {
+						return result, fmt.Errorf("row %d: failed to store %s edge: %w", row.Row, e.EdgeType, err)
+					}
+					excerpt := fmt.Sprintf("%s: %s; %s: %s", from.Column, from.Cell, to.Column, to.Cell)
+					if err := m.storeProvenance(ctx, "edge", edgeID, docNodeID, trust, row, to, excerpt, nil); err != nil {
+						return result, fmt.Errorf("row %d: %w", row.Row, err)
+					}
+					result.Edges++
+				}
+				result.Rows++
+			}
+		}
+	}
+
+	m.logger.Info("mapped table source to graph",
+		"source_id", database.UUIDStr(source.ID),
+		"rows", result.Rows,
+		"nodes", result.Nodes,
+		"edges", result.Edges,
+		"warnings", len(result.Warnings))
+
+	return result, nil
+}
+
+// Mappings returns the column mappings for a source: those stored in the
+// source metadata under "table_mappings" (supplied on upload), or otherwise
+// one inferred mapping per sheet. inferred reports which applied.
+func (m *TableMapper) Mappings(source *database.Source, tables []*document.Table) (mappings []*document.TableMapping, inferred bool, err error) {
+	if len(source.Metadata) > 0 {
+		var meta struct {
+			TableMappings []*document.TableMapping `json:"table_mappings"`
+			Inferred      bool                     `json:"table_mappings_inferred"`
+		}
+		if err := json.Unmarshal(source.Metadata, &meta); err != nil {
+			return nil, false, fmt.Errorf("failed to parse source metadata: %w", err)
+		}
+		if len(meta.TableMappings) > 0 {
+			return meta.TableMappings, meta.Inferred, nil
+		}
+	}
+
+	for _, t := range tables {
+		mapping, err := document.InferTableMapping(t)
+		if err != nil {
+			m.logger.Warn("no column mapping inferred", "sheet", t.Sheet, "error", err)
+			continue
+		}
+		mappings = append(mappings, mapping)
+	}
+	if len(mappings) == 0 {
+		return nil, true, fmt.Errorf("could not infer a column mapping; supply one on upload")
+	}
+	return mappings, true, nil
+}
+
+// storeNode returns the node for a mapped cell, reusing entity nodes already
+// seen in this source.
+func (m *TableMapper) storeNode(ctx context.Context, entities map[string]uuid.UUID, n document.MappedNode) (uuid.UUID, bool, error) {
+	key := n.NodeType + "|" + strings.ToLower(n.Label)
+	if n.NodeType != database.NodeTypeValue {
+		if id, ok := entities[key]; ok {
+			return id, false, nil
+		}
+	}
+
+	id, err := m.graph.CreateNode(ctx, CreateNodeParams{
+		NodeType:   n.NodeType,
+		Label:      n.Label,
+		Properties: cellProperties(n),
+	})
+	if err != nil {
+		return uuid.Nil, false, fmt.Errorf("failed to store node %q: %w", n.Label, err)
+	}
+	if n.NodeType != database.NodeTypeValue {
+		entities[key] = id
+	}
+	return id, true, nil
+}
+
+// cellProperties returns the properties a mapped cell gives its node
+func cellProperties(n document.MappedNode) map[string]interface{} {
+	props := make(map[string]interface{}, len(n.Properties)+1)
+	for k, v := range n.Properties {
+		props[k] = v
+	}
+	props["column"] = n.Column
+	return props
+}
+
+// storeProvenance records the cell a node or edge was read from. The row's
+// date column, if mapped, becomes the claimed time, and claimed holds the
+// property values the row gives the target.
+func (m *TableMapper) storeProvenance(ctx context.Context, targetType string, targetID, docNodeID uuid.UUID, trust float32, row document.MappedRow, n document.MappedNode, excerpt string, claimed map[string]interface{}) error {
+	var claimedStart *time.Time
+	if t, ok := document.ParseTableDate(n.Time); ok {
+		claimedStart = &t
+	}
+
+	_, err := m.graph.CreateProvenance(ctx, CreateProvenanceParams{
+		TargetType: targetType,
+		TargetID:   targetID,
+		SourceID:   docNodeID,
+		Excerpt:    excerpt,
+		Location: database.Location{
+			Sheet:  row.Sheet,
+			Row:    row.Row,
+			Column: n.Column,
+		},
+		Confidence:        1.0,
+		Trust:             trust,
+		Modality:          database.ModalityAsserted,
+		Status:            database.StatusPending,
+		ClaimedTimeStart:  claimedStart,
+		ClaimedTimeText:   n.Time,
+		ClaimedProperties: claimed,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store provenance: %w", err)
+	}
+	return nil
+}
+
+// documentNode returns the source's document node, creating it if the
+// source has not been migrated to the graph yet.
+func (m *TableMapper) documentNode(ctx context.Context, source *database.Source) (uuid.UUID, error) {
+	node, err := m.db.GetDocumentNodeByLegacySourceID(ctx, database.UUIDStr(source.ID))
+	if err == nil {
+		return uuid.FromBytes(node.ID.Bytes[:16])
+	}
+	return NewMigrator(m.db, m.graph, m.logger).MigrateSourceToNode(ctx, source)
+}
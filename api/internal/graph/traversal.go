@@ -0,0 +1,334 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/einarsundgren/sikta/internal/database"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Direction is the way a traversal may follow an edge
+type Direction string
+
+const (
+	DirectionOut  Direction = "out"  // source → target only
+	DirectionIn   Direction = "in"   // target → source only
+	DirectionBoth Direction = "both" // either way
+)
+
+// Traversal limits. Hop counts above these fan out to most of a dense graph
+// and path enumeration grows exponentially with depth. Listing all paths
+// enumerates every simple path up to the depth, so it gets a lower cap than
+// the shortest-path search, which stops at the first depth with a path.
+const (
+	MaxTraversalDepth = 5
+	MaxPathDepth      = 6
+	MaxAllPathsDepth  = 4
+	MaxPaths          = 100
+	MaxStartNodes     = 100
+)
+
+// traversalTimeout bounds a traversal or path search. The query is cancelled
+// in the database when it runs out, so a dense graph cannot tie up a
+// connection.
+const traversalTimeout = 10 * time.Second
+
+var (
+	// ErrTraversalTimeout is returned when a traversal or path search runs
+	// out of time.
+	ErrTraversalTimeout = errors.New("graph traversal took too long")
+	// ErrNodeNotFound is returned when a start node is not in the project's
+	// graph.
+	ErrNodeNotFound = errors.New("node not found in project")
+)
+
+// TraversalOptions restricts which edges a traversal follows
+type TraversalOptions struct {
+	Depth          int                     // hops from the start nodes; 1 if unset
+	EdgeTypes      []string                // all types if empty
+	Direction      Direction               // DirectionBoth if unset
+	Statuses       []database.ReviewStatus // everything but rejected if empty
+	IncludeNegated bool                    // follow negated edges too
+}
+
+// PathOptions restricts a path search
+type PathOptions struct {
+	TraversalOptions
+	MaxDepth int  // longest path in hops; 4 if unset
+	Limit    int  // most paths returned; 25 if unset
+	All      bool // all simple paths rather than only the shortest
+}
+
+// SubgraphNode is a node reached by a traversal with the fewest hops needed
+// to reach it from a start node
+type SubgraphNode struct {
+	Node  *database.Node
+	Depth int
+}
+
+// Subgraph is a set of nodes and the edges among them
+type Subgraph struct {
+	Nodes []SubgraphNode
+	Edges []*database.Edge
+}
+
+// Path is a walk between two nodes: Edges[i] joins Nodes[i] and Nodes[i+1]
+type Path struct {
+	Nodes []*database.Node
+	Edges []*database.Edge
+}
+
+// Neighbors returns the nodes within opts.Depth hops of a node in a
+// project's graph and the edges among them.
+func (s *Service) Neighbors(ctx context.Context, projectID, nodeID uuid.UUID, opts TraversalOptions) (*Subgraph, error) {
+	return s.Subgraph(ctx, projectID, []uuid.UUID{nodeID}, opts)
+}
+
+// Subgraph returns the nodes within opts.Depth hops of any of the given nodes
+// in a project's graph and the edges among them. The given nodes are
+// included at depth 0. It fails with ErrNodeNotFound if a given node is not
+// in the project's graph, and with ErrTraversalTimeout if it takes longer
+// than traversalTimeout.
+func (s *Service) Subgraph(ctx context.Context, projectID uuid.UUID, nodeIDs []uuid.UUID, opts TraversalOptions) (*Subgraph, error) {
+	opts = opts.withDefaults()
+	depth := opts.Depth
+	if depth > MaxTraversalDepth {
+		depth = MaxTraversalDepth
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, traversalTimeout)
+	defer cancel()
+
+	if err := s.checkProjectNodes(ctx, projectID, nodeIDs); err != nil {
+		return nil, err
+	}
+
+	reached, err := s.db.TraverseProjectGraph(ctx, database.TraverseProjectGraphParams{
+		ProjectID:      database.PgUUID(projectID),
+		IncludeNegated: opts.IncludeNegated,
+		EdgeTypes:      opts.EdgeTypes,
+		Statuses:       statusStrings(opts.Statuses),
+		Direction:      string(opts.Direction),
+		StartNodes:     pgUUIDs(nodeIDs),
+		MaxDepth:       int32(depth),
+	})
+	if err != nil {
+		return nil, traversalError(err, "traverse graph")
+	}
+
+	ids := make([]pgtype.UUID, len(reached))
+	for i, r := range reached {
+		ids[i] = r.NodeID
+	}
+	nodes, err := s.db.ListNodesByIDs(ctx, ids)
+	if err != nil {
+		return nil, traversalError(err, "list nodes")
+	}
+	byID := make(map[pgtype.UUID]*database.Node, len(nodes))
+	for _, n := range nodes {
+		byID[n.ID] = n
+	}
+
+	edges, err := s.db.ListProjectEdgesAmong(ctx, database.ListProjectEdgesAmongParams{
+		ProjectID:      database.PgUUID(projectID),
+		NodeIds:        ids,
+		IncludeNegated: opts.IncludeNegated,
+		EdgeTypes:      opts.EdgeTypes,
+		Statuses:       statusStrings(opts.Statuses),
+	})
+	if err != nil {
+		return nil, traversalError(err, "list edges")
+	}
+
+	sub := &Subgraph{Edges: edges}
+	for _, r := range reached {
+		// A node deleted since it was reached is dropped
+		if n, ok := byID[r.NodeID]; ok {
+			sub.Nodes = append(sub.Nodes, SubgraphNode{Node: n, Depth: int(r.Depth)})
+		}
+	}
+	return sub, nil
+}
+
+// Paths returns paths between two nodes in a project's graph, shortest
+// first. Unless opts.All is set only the shortest paths are returned. It
+// fails with ErrNodeNotFound if either node is not in the project's graph,
+// and with ErrTraversalTimeout if the search takes longer than
+// traversalTimeout.
+func (s *Service) Paths(ctx context.Context, projectID, from, to uuid.UUID, opts PathOptions) ([]Path, error) {
+	opts.TraversalOptions = opts.TraversalOptions.withDefaults()
+	if opts.MaxDepth <= 0 {
+		opts.MaxDepth = 4
+	}
+	if opts.MaxDepth > MaxPathDepth {
+		opts.MaxDepth = MaxPathDepth
+	}
+	if opts.All && opts.MaxDepth > MaxAllPathsDepth {
+		opts.MaxDepth = MaxAllPathsDepth
+	}
+	if opts.Limit <= 0 {
+		opts.Limit = 25
+	}
+	if opts.Limit > MaxPaths {
+		opts.Limit = MaxPaths
+	}
+
+	params := database.FindProjectPathsParams{
+		ProjectID:      database.PgUUID(projectID),
+		IncludeNegated: opts.IncludeNegated,
+		EdgeTypes:      opts.EdgeTypes,
+		Statuses:       statusStrings(opts.Statuses),
+		Direction:      string(opts.Direction),
+		FromNode:       database.PgUUID(from),
+		ToNode:         database.PgUUID(to),
+		MaxPaths:       int32(opts.Limit),
+	}
+
+	searchCtx, cancel := context.WithTimeout(ctx, traversalTimeout)
+	defer cancel()
+
+	if err := s.checkProjectNodes(searchCtx, projectID, []uuid.UUID{from, to}); err != nil {
+		return nil, err
+	}
+
+	var found []*database.FindProjectPathsRow
+	if opts.All {
+		params.MaxDepth = int32(opts.MaxDepth)
+		rows, err := s.db.FindProjectPaths(searchCtx, params)
+		if err != nil {
+			return nil, traversalError(err, "find paths")
+		}
+		found = rows
+	} else {
+		// Deepen one hop at a time so that a short path is found without
+		// enumerating every longer one; the first depth with any path gives
+		// the shortest paths
+		for depth := 1; depth <= opts.MaxDepth && len(found) == 0; depth++ {
+			params.MaxDepth = int32(depth)
+			rows, err := s.db.FindProjectPaths(searchCtx, params)
+			if err != nil {
+				return nil, traversalError(err, "find paths")
+			}
+			found = rows
+		}
+	}
+
+	return s.resolvePaths(ctx, found)
+}
+
+// checkProjectNodes returns ErrNodeNotFound unless every given node is in
+// the project's graph
+func (s *Service) checkProjectNodes(ctx context.Context, projectID uuid.UUID, nodeIDs []uuid.UUID) error {
+	unique := make(map[uuid.UUID]bool, len(nodeIDs))
+	for _, id := range nodeIDs {
+		unique[id] = true
+	}
+	found, err := s.db.ListProjectNodesAmong(ctx, database.ListProjectNodesAmongParams{
+		ProjectID: database.PgUUID(projectID),
+		NodeIds:   pgUUIDs(nodeIDs),
+	})
+	if err != nil {
+		return traversalError(err, "check start nodes")
+	}
+	if len(found) < len(unique) {
+		return ErrNodeNotFound
+	}
+	return nil
+}
+
+// traversalError reports a query cut off by the traversal timeout, which
+// shows up as the context's error or as Postgres cancelling the statement,
+// as ErrTraversalTimeout. Other errors are wrapped as failing to do what.
+func traversalError(err error, what string) error {
+	var pgErr *pgconn.PgError
+	if errors.Is(err, context.DeadlineExceeded) || errors.As(err, &pgErr) && pgErr.Code == "57014" {
+		return ErrTraversalTimeout
+	}
+	return fmt.Errorf("failed to %s: %w", what, err)
+}
+
+// resolvePaths loads the nodes and edges named by path rows
+func (s *Service) resolvePaths(ctx context.Context, rows []*database.FindProjectPathsRow) ([]Path, error) {
+	var nodeIDs, edgeIDs []pgtype.UUID
+	for _, row := range rows {
+		nodeIDs = append(nodeIDs, row.NodePath...)
+		edgeIDs = append(edgeIDs, row.EdgePath...)
+	}
+
+	nodes, err := s.db.ListNodesByIDs(ctx, nodeIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	nodeByID := make(map[pgtype.UUID]*database.Node, len(nodes))
+	for _, n := range nodes {
+		nodeByID[n.ID] = n
+	}
+	edges, err := s.db.ListEdgesByIDs(ctx, edgeIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list edges: %w", err)
+	}
+	edgeByID := make(map[pgtype.UUID]*database.Edge, len(edges))
+	for _, e := range edges {
+		edgeByID[e.ID] = e
+	}
+
+	paths := make([]Path, 0, len(rows))
+next:
+	for _, row := range rows {
+		var p Path
+		// A path through a node or edge deleted since it was found is dropped
+		for _, id := range row.NodePath {
+			n, ok := nodeByID[id]
+			if !ok {
+				continue next
+			}
+			p.Nodes = append(p.Nodes, n)
+		}
+		for _, id := range row.EdgePath {
+			e, ok := edgeByID[id]
+			if !ok {
+				continue next
+			}
+			p.Edges = append(p.Edges, e)
+		}
+		paths = append(paths, p)
+	}
+	return paths, nil
+}
+
+func (o TraversalOptions) withDefaults() TraversalOptions {
+	if o.Depth <= 0 {
+		o.Depth = 1
+	}
+	if o.Direction == "" {
+		o.Direction = DirectionBoth
+	}
+	if len(o.Statuses) == 0 {
+		o.Statuses = []database.ReviewStatus{database.StatusPending, database.StatusApproved, database.StatusEdited}
+	}
+	if o.EdgeTypes == nil {
+		o.EdgeTypes = []string{}
+	}
+	return o
+}
+
+func statusStrings(statuses []database.ReviewStatus) []string {
+	out := make([]string, len(statuses))
+	for i, st := range statuses {
+		out[i] = string(st)
+	}
+	return out
+}
+
+func pgUUIDs(ids []uuid.UUID) []pgtype.UUID {
+	out := make([]pgtype.UUID, len(ids))
+	for i, id := range ids {
+		out[i] = database.PgUUID(id)
+	}
+	return out
+}
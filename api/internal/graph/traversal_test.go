@@ -0,0 +1,229 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"reflect"
+	"testing"
+
+	"github.com/einarsundgren/sikta/internal/database"
+	"github.com/einarsundgren/sikta/internal/database/dbtest"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func testService(db *dbtest.DB) *Service {
+	return NewService(database.New(db), slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestTraversalOptionsDefaults(t *testing.T) {
+	visible := []database.ReviewStatus{database.StatusPending, database.StatusApproved, database.StatusEdited}
+
+	tests := []struct {
+		name string
+		opts TraversalOptions
+		want TraversalOptions
+	}{
+		{
+			name: "unset",
+			want: TraversalOptions{Depth: 1, EdgeTypes: []string{}, Direction: DirectionBoth, Statuses: visible},
+		},
+		{
+			name: "negative depth",
+			opts: TraversalOptions{Depth: -2},
+			want: TraversalOptions{Depth: 1, EdgeTypes: []string{}, Direction: DirectionBoth, Statuses: visible},
+		},
+		{
+			name: "set",
+			opts: TraversalOptions{
+				Depth:          3,
+				EdgeTypes:      []string{"employed_by"},
+				Direction:      DirectionOut,
+				Statuses:       []database.ReviewStatus{database.StatusRejected},
+				IncludeNegated: true,
+			},
+			want: TraversalOptions{
+				Depth:          3,
+				EdgeTypes:      []string{"employed_by"},
+				Direction:      DirectionOut,
+				Statuses:       []database.ReviewStatus{database.StatusRejected},
+				IncludeNegated: true,
+			},
+		},
+		{
+			// Depth is capped by the traversal, not here
+			name: "depth above the cap",
+			opts: TraversalOptions{Depth: MaxTraversalDepth + 3},
+			want: TraversalOptions{Depth: MaxTraversalDepth + 3, EdgeTypes: []string{}, Direction: DirectionBoth, Statuses: visible},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.opts.withDefaults(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("withDefaults() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTraversalError(t *testing.T) {
+	dbErr := errors.New("connection reset")
+
+	tests := []struct {
+		name    string
+		err     error
+		timeout bool
+	}{
+		{"deadline", context.DeadlineExceeded, true},
+		{"wrapped deadline", fmt.Errorf("query: %w", context.DeadlineExceeded), true},
+		{"statement cancelled", &pgconn.PgError{Code: "57014"}, true},
+		{"other postgres error", &pgconn.PgError{Code: "42P01"}, false},
+		{"other error", dbErr, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := traversalError(tt.err, "find paths")
+			if got := errors.Is(err, ErrTraversalTimeout); got != tt.timeout {
+				t.Errorf("traversalError() = %v, timeout %v, want %v", err, got, tt.timeout)
+			}
+			if !tt.timeout && !errors.Is(err, tt.err) {
+				t.Errorf("traversalError() = %v, does not wrap %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestResolvePaths(t *testing.T) {
+	db := dbtest.New()
+	// Node 4 and edge 14 have been deleted since the paths were found
+	db.Return("ListNodesByIDs", []*database.Node{{ID: testID(1)}, {ID: testID(2)}, {ID: testID(3)}}, nil)
+	db.Return("ListEdgesByIDs", []*database.Edge{{ID: testID(11)}, {ID: testID(12)}, {ID: testID(13)}}, nil)
+
+	paths, err := testService(db).resolvePaths(context.Background(), []*database.FindProjectPathsRow{
+		{NodePath: ids(1, 2, 3), EdgePath: ids(11, 12)},
+		{NodePath: ids(1, 4, 3), EdgePath: ids(13, 12)},
+		{NodePath: ids(1, 3), EdgePath: ids(14)},
+		{NodePath: ids(1, 3), EdgePath: ids(13)},
+	})
+	if err != nil {
+		t.Fatalf("resolvePaths() error = %v", err)
+	}
+
+	var got [][]pgtype.UUID
+	for _, p := range paths {
+		var path []pgtype.UUID
+		for _, n := range p.Nodes {
+			path = append(path, n.ID)
+		}
+		for _, e := range p.Edges {
+			path = append(path, e.ID)
+		}
+		got = append(got, path)
+	}
+	want := [][]pgtype.UUID{ids(1, 2, 3, 11, 12), ids(1, 3, 13)}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("resolvePaths() = %v, want %v", got, want)
+	}
+}
+
+func TestTraversalStartNodes(t *testing.T) {
+	projectID := uuid.UUID{15: 9}
+	a, b := uuid.UUID(testID(1).Bytes), uuid.UUID(testID(2).Bytes)
+
+	tests := []struct {
+		name    string
+		run     func(s *Service) error
+		inGraph []pgtype.UUID // start nodes in the project's graph
+		wantErr error
+	}{
+		{
+			name: "subgraph",
+			run: func(s *Service) error {
+				_, err := s.Subgraph(context.Background(), projectID, []uuid.UUID{a, b, a}, TraversalOptions{})
+				return err
+			},
+			inGraph: ids(1, 2),
+		},
+		{
+			name: "subgraph with a node from another project",
+			run: func(s *Service) error {
+				_, err := s.Subgraph(context.Background(), projectID, []uuid.UUID{a, b}, TraversalOptions{})
+				return err
+			},
+			inGraph: ids(1),
+			wantErr: ErrNodeNotFound,
+		},
+		{
+			name: "neighbors of a node from another project",
+			run: func(s *Service) error {
+				_, err := s.Neighbors(context.Background(), projectID, b, TraversalOptions{})
+				return err
+			},
+			wantErr: ErrNodeNotFound,
+		},
+		{
+			name: "paths to a node from another project",
+			run: func(s *Service) error {
+				_, err := s.Paths(context.Background(), projectID, a, b, PathOptions{})
+				return err
+			},
+			inGraph: ids(1),
+			wantErr: ErrNodeNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := dbtest.New()
+			db.Return("ListProjectNodesAmong", tt.inGraph, nil)
+
+			if err := tt.run(testService(db)); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			calls := db.Calls("ListProjectNodesAmong")
+			if len(calls) != 1 {
+				t.Fatalf("ListProjectNodesAmong called %d times, want 1", len(calls))
+			}
+			if got := calls[0].Args[0]; got != database.PgUUID(projectID) {
+				t.Errorf("project = %v, want %v", got, projectID)
+			}
+			traversed := len(db.Calls("TraverseProjectGraph")) + len(db.Calls("FindProjectPaths"))
+			if (traversed > 0) != (tt.wantErr == nil) {
+				t.Errorf("traversed = %v, want %v", traversed > 0, tt.wantErr == nil)
+			}
+		})
+	}
+}
+
+func TestSubgraphDepthCap(t *testing.T) {
+	tests := []struct {
+		depth int
+		want  int32
+	}{
+		{0, 1},
+		{2, 2},
+		{MaxTraversalDepth, MaxTraversalDepth},
+		{MaxTraversalDepth + 1, MaxTraversalDepth},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.depth), func(t *testing.T) {
+			db := dbtest.New()
+			db.Return("ListProjectNodesAmong", ids(1), nil)
+
+			_, err := testService(db).Subgraph(context.Background(), uuid.New(), []uuid.UUID{uuid.UUID(testID(1).Bytes)}, TraversalOptions{Depth: tt.depth})
+			if err != nil {
+				t.Fatalf("Subgraph() error = %v", err)
+			}
+			calls := db.Calls("TraverseProjectGraph")
+			if len(calls) != 1 {
+				t.Fatalf("TraverseProjectGraph called %d times, want 1", len(calls))
+			}
+			if got := calls[0].Args[6]; got != tt.want {
+				t.Errorf("max depth = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
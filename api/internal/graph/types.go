@@ -57,6 +57,9 @@ type CreateProvenanceParams struct {
 
 	// Attribution
 	ClaimedBy *uuid.UUID // e.g., a reviewer node
+
+	// Property claims: the values this source asserts about the target
+	ClaimedProperties map[string]any
 }
 
 // TimelineEvent represents an event for the timeline view (compatible with frontend)
@@ -76,6 +79,15 @@ type TimelineEvent struct {
 	Entities              []TimelineEntity
 	SourceReferences      []SourceReference
 	Inconsistencies       []InconsistencyRef
+
+	// SourceID is the legacy source the selected provenance came from, set
+	// for project timelines
+	SourceID string
+	// Majority is the resolution of the event's claimed time and place,
+	// set for ViewStrategyMajority
+	Majority *Majority
+
+	selected *database.Provenance
 }
 
 // TimelineEntity represents an entity in a timeline event
@@ -8,6 +8,7 @@ import (
 
 	"github.com/einarsundgren/sikta/internal/database"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 // Views provides query-time resolution strategies for graph data
@@ -59,66 +60,179 @@ func (v *Views) GetEventsForTimeline(ctx context.Context, sourceID uuid.UUID, st
 			continue
 		}
 
-		// Apply view strategy only to assertion provenance, not ordering records
-		selectedProv := v.selectProvenance(assertionOnly(provenance), strategy)
-		confidence := float32(0)
-		reviewStatus := string(database.StatusPending)
-		if selectedProv != nil {
-			confidence = selectedProv.Confidence
-			reviewStatus = selectedProv.Status
+		events = append(events, v.timelineEvent(node, provenance, strategy))
+	}
+
+	return events, nil
+}
+
+// GetProjectEventsForTimeline returns the event nodes of a whole project,
+// each resolved over the provenance from all of the project's documents.
+// SourceID is set on each event to the legacy source of the record the
+// strategy selected.
+func (v *Views) GetProjectEventsForTimeline(ctx context.Context, projectID uuid.UUID, strategy ViewStrategy) ([]TimelineEvent, error) {
+	p, err := v.loadProject(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []TimelineEvent
+	for _, node := range p.nodes {
+		if node.NodeType == database.NodeTypeEvent {
+			events = append(events, v.projectTimelineEvent(node, p.provenance[node.ID], p.documents, strategy))
 		}
+	}
 
-		// Extract temporal info from all provenance records
-		var dateStart, dateEnd *time.Time
-		var dateText string
-		for _, prov := range provenance {
-			if prov.ClaimedTimeStart.Valid {
-				t := prov.ClaimedTimeStart.Time
-				dateStart = &t
-			}
-			if prov.ClaimedTimeEnd.Valid {
-				t := prov.ClaimedTimeEnd.Time
-				dateEnd = &t
-			}
-			if prov.ClaimedTimeText.Valid && prov.ClaimedTimeText.String != "" {
-				dateText = prov.ClaimedTimeText.String
-			}
+	return events, nil
+}
+
+// GetCanonicalEventsForTimeline is GetProjectEventsForTimeline over the
+// canonical graph: events in one identity cluster become one event,
+// resolved over the provenance of all of them
+func (v *Views) GetCanonicalEventsForTimeline(ctx context.Context, projectID uuid.UUID, strategy ViewStrategy) ([]TimelineEvent, error) {
+	g, err := v.GetCanonicalGraph(ctx, projectID, strategy)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []TimelineEvent
+	for _, n := range g.Nodes {
+		if n.Node.NodeType == database.NodeTypeEvent {
+			events = append(events, v.projectTimelineEvent(n.Node, n.Provenance, g.Documents, strategy))
 		}
+	}
 
-		// Get position from ordering provenance records
-		narrativePos, chronoPos := extractOrderingFromProvenance(provenance)
-
-		// Build source references from assertion provenance records
-		var sourceRefs []SourceReference
-		for _, prov := range assertionOnly(provenance) {
-			if prov.Excerpt != "" {
-				sourceRefs = append(sourceRefs, SourceReference{
-					ID:       database.UUIDStr(prov.ID),
-					Excerpt:  prov.Excerpt,
-					Location: prov.GetLocation(),
-				})
-			}
+	return events, nil
+}
+
+// projectTimelineEvent is timelineEvent with SourceID set from the
+// document the selected provenance came from
+func (v *Views) projectTimelineEvent(node *database.Node, provenance []*database.Provenance, documents map[pgtype.UUID]*database.Node, strategy ViewStrategy) TimelineEvent {
+	event := v.timelineEvent(node, provenance, strategy)
+	if event.selected != nil {
+		if doc := documents[event.selected.SourceID]; doc != nil {
+			event.SourceID, _ = doc.GetProperty("source_id", "").(string)
 		}
+	}
+	return event
+}
 
-		event := TimelineEvent{
-			ID:                    database.UUIDStr(node.ID),
-			Title:                 node.Label,
-			Description:           node.GetProperty("description", "").(string),
-			Type:                  node.GetProperty("event_type", "action").(string),
-			DateText:              dateText,
-			NarrativePosition:     narrativePos,
-			ChronologicalPosition: chronoPos,
-			Confidence:            confidence,
-			ReviewStatus:          reviewStatus,
-			DateStart:             dateStart,
-			DateEnd:               dateEnd,
-			SourceReferences:      sourceRefs,
+// projectGraph is a project's nodes with the provenance from its documents
+type projectGraph struct {
+	nodes      []*database.Node
+	provenance map[pgtype.UUID][]*database.Provenance // by node or edge ID
+	documents  map[pgtype.UUID]*database.Node         // by ID
+}
+
+// loadProject reads a project's nodes, their provenance and the document
+// nodes the provenance comes from
+func (v *Views) loadProject(ctx context.Context, projectID uuid.UUID) (*projectGraph, error) {
+	id := database.PgUUID(projectID)
+	nodes, err := v.db.ListProjectNodes(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get nodes: %w", err)
+	}
+	records, err := v.db.ListProjectProvenance(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get provenance: %w", err)
+	}
+
+	p := &projectGraph{
+		nodes:      nodes,
+		provenance: make(map[pgtype.UUID][]*database.Provenance),
+		documents:  make(map[pgtype.UUID]*database.Node),
+	}
+	seen := make(map[pgtype.UUID]bool)
+	var documentIDs []pgtype.UUID
+	for _, r := range records {
+		p.provenance[r.TargetID] = append(p.provenance[r.TargetID], r)
+		if !seen[r.SourceID] {
+			seen[r.SourceID] = true
+			documentIDs = append(documentIDs, r.SourceID)
 		}
+	}
+	if len(documentIDs) > 0 {
+		documents, err := v.db.ListNodesByIDs(ctx, documentIDs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get document nodes: %w", err)
+		}
+		for _, n := range documents {
+			p.documents[n.ID] = n
+		}
+	}
+	return p, nil
+}
 
-		events = append(events, event)
+// timelineEvent resolves an event node's provenance with strategy
+func (v *Views) timelineEvent(node *database.Node, provenance []*database.Provenance, strategy ViewStrategy) TimelineEvent {
+	// Apply view strategy only to assertion provenance, not ordering records
+	selectedProv := v.selectProvenance(assertionOnly(provenance), strategy)
+	confidence := float32(0)
+	reviewStatus := string(database.StatusPending)
+	if selectedProv != nil {
+		confidence = selectedProv.Confidence
+		reviewStatus = selectedProv.Status
 	}
 
-	return events, nil
+	// Extract temporal info from all provenance records
+	var dateStart, dateEnd *time.Time
+	var dateText string
+	for _, prov := range provenance {
+		if prov.ClaimedTimeStart.Valid {
+			t := prov.ClaimedTimeStart.Time
+			dateStart = &t
+		}
+		if prov.ClaimedTimeEnd.Valid {
+			t := prov.ClaimedTimeEnd.Time
+			dateEnd = &t
+		}
+		if prov.ClaimedTimeText.Valid && prov.ClaimedTimeText.String != "" {
+			dateText = prov.ClaimedTimeText.String
+		}
+	}
+
+	// Majority takes the dates most sources agree on instead
+	var majority *Majority
+	if strategy == ViewStrategyMajority {
+		majority = ResolveMajority(provenance)
+		if majority.Time != nil {
+			dateStart = majority.Time.Winner.Value.TimeStart
+			dateEnd = majority.Time.Winner.Value.TimeEnd
+			dateText = majority.Time.Winner.Value.TimeText
+		}
+	}
+
+	// Get position from ordering provenance records
+	narrativePos, chronoPos := extractOrderingFromProvenance(provenance)
+
+	// Build source references from assertion provenance records
+	var sourceRefs []SourceReference
+	for _, prov := range assertionOnly(provenance) {
+		if prov.Excerpt != "" {
+			sourceRefs = append(sourceRefs, SourceReference{
+				ID:       database.UUIDStr(prov.ID),
+				Excerpt:  prov.Excerpt,
+				Location: prov.GetLocation(),
+			})
+		}
+	}
+
+	return TimelineEvent{
+		ID:                    database.UUIDStr(node.ID),
+		Title:                 node.Label,
+		Description:           node.GetProperty("description", "").(string),
+		Type:                  node.GetProperty("event_type", "action").(string),
+		DateText:              dateText,
+		NarrativePosition:     narrativePos,
+		ChronologicalPosition: chronoPos,
+		Confidence:            confidence,
+		ReviewStatus:          reviewStatus,
+		DateStart:             dateStart,
+		DateEnd:               dateEnd,
+		SourceReferences:      sourceRefs,
+		Majority:              majority,
+		selected:              selectedProv,
+	}
 }
 
 // GetEntitiesForGraph returns entity nodes for the graph view
@@ -183,7 +297,7 @@ func (v *Views) GetEntitiesForGraph(ctx context.Context, sourceID uuid.UUID) ([]
 				if edge.EdgeType == database.EdgeTypeSameAs {
 					entity.SameAsEdges = append(entity.SameAsEdges, SameAsEdge{
 						TargetEntityID: database.UUIDStr(edge.TargetNode),
-						Confidence:     1.0, // Would come from edge properties
+						Confidence:     sameAsConfidence(edge.Properties),
 						Reasoning:      "",
 					})
 				}
@@ -243,33 +357,6 @@ func (v *Views) GetRelationshipsForGraph(ctx context.Context, sourceID uuid.UUID
 	return relationships, nil
 }
 
-// ResolveIdentity returns the "canonical" entity ID based on same_as edges and view strategy
-// For human_decided strategy, returns the approved identity
-// For trust_weighted, returns the highest confidence identity
-func (v *Views) ResolveIdentity(ctx context.Context, entityID uuid.UUID, strategy ViewStrategy) (uuid.UUID, error) {
-	// Get same_as edges for this entity
-	edges, err := v.db.ListEdgesBySource(ctx, database.PgUUID(entityID))
-	if err != nil {
-		return entityID, nil
-	}
-
-	var candidateIDs []uuid.UUID
-	for _, edge := range edges {
-		if edge.EdgeType == database.EdgeTypeSameAs {
-			targetID, _ := uuid.FromBytes(edge.TargetNode.Bytes[:16])
-			candidateIDs = append(candidateIDs, targetID)
-		}
-	}
-
-	if len(candidateIDs) == 0 {
-		return entityID, nil
-	}
-
-	// For now, return the first candidate
-	// In production, this would check provenance, confidence, approval status
-	return candidateIDs[0], nil
-}
-
 // assertionOnly filters out ordering provenance records (modality="inferred",
 // PositionType set), returning only the substantive assertion records.
 // This prevents ordering records from polluting confidence/status selection.
@@ -337,7 +424,8 @@ func (v *Views) selectProvenance(provenance []*database.Provenance, strategy Vie
 		return provenance[0]
 
 	case ViewStrategyConflict:
-		// Return the provenance with lowest confidence (to show disagreement)
+		// Return the provenance with lowest confidence (to flag doubt); the
+		// competing claims themselves come from GetConflicts
 		worst := provenance[0]
 		worstScore := worst.Trust * worst.Confidence
 		for _, p := range provenance[1:] {
@@ -350,8 +438,11 @@ func (v *Views) selectProvenance(provenance []*database.Provenance, strategy Vie
 		return worst
 
 	case ViewStrategyMajority:
-		// For timeline, this would aggregate multiple sources
-		// For now, return trust-weighted
+		// The strongest record for the value most sources agree on; records
+		// that claim no time or place only attest that the target exists
+		if p := ResolveMajority(provenance).Provenance(); p != nil {
+			return p
+		}
 		return v.selectProvenance(provenance, ViewStrategyTrustWeighted)
 
 	default:
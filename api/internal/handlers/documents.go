@@ -1,37 +1,57 @@
 package handlers
 
 import (
+	"archive/zip"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
+	"path"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5"
 
+	"github.com/einarsundgren/sikta/internal/config"
 	"github.com/einarsundgren/sikta/internal/database"
 	"github.com/einarsundgren/sikta/internal/document"
+	"github.com/einarsundgren/sikta/internal/graph"
 	"github.com/einarsundgren/sikta/internal/services"
+	"github.com/einarsundgren/sikta/internal/storage"
 )
 
 // DocumentHandler handles document-related HTTP requests.
 type DocumentHandler struct {
-	pool       *pgxpool.Pool
+	db         *database.Queries
 	repo       *database.Repository
 	docService *services.DocumentService
+	tables     *graph.TableMapper
 	logger     *slog.Logger
 }
 
-// NewDocumentHandler creates a new document handler.
-func NewDocumentHandler(pool *pgxpool.Pool, logger *slog.Logger) *DocumentHandler {
-	repo := database.NewRepository(pool, context.Background())
+// NewDocumentHandler creates a new document handler. Uploaded files are kept
+// in store.
+func NewDocumentHandler(queries *database.Queries, cfg *config.Config, store storage.Storage, logger *slog.Logger) *DocumentHandler {
+	repo := database.NewRepository(queries, context.Background())
+
+	ocr, err := document.NewOCREngine(cfg.OCREngine, cfg.OCRLanguages)
+	if err != nil {
+		logger.Warn("OCR disabled", "error", err)
+	}
+
 	return &DocumentHandler{
-		pool:       pool,
-		repo:       repo,
-		docService: services.NewDocumentService(logger),
-		logger:     logger,
+		db:   queries,
+		repo: repo,
+		docService: services.NewDocumentService(logger, document.PDFOptions{
+			Extractor: cfg.PDFExtractor,
+			OCR:       ocr,
+		}, cfg.MaxUploadSize, store, queries),
+		tables: graph.NewTableMapper(queries, graph.NewService(queries, logger), logger),
+		logger: logger,
 	}
 }
 
@@ -42,7 +62,196 @@ func (h *DocumentHandler) UploadDocument(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Parse multipart form (max 50MB)
+	// Stream the form: the file goes straight to disk however large it is
+	uploadResult, form, err := h.streamUpload(w, r)
+	if err != nil {
+		h.logger.Error("upload validation failed", "error", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Optional column mapping for CSV/XLSX sources
+	mappings, err := uploadMappings(form["mapping"], uploadResult.FileType)
+	if err != nil {
+		h.docService.Cleanup(uploadResult.FilePath)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Create source record; the same file uploaded again returns the source
+	// it created before
+	src, duplicate, err := h.createSource(uploadResult, nil, mappingExtras(mappings))
+	if err != nil {
+		h.logger.Error("failed to create source record", "error", err)
+		http.Error(w, "Failed to create document", http.StatusInternalServerError)
+		return
+	}
+	if duplicate {
+		h.logger.Info("duplicate upload", "id", src.ID, "filename", uploadResult.Filename)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(src)
+		return
+	}
+
+	h.logger.Info("document uploaded", "id", src.ID, "filename", uploadResult.Filename)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(src)
+}
+
+// streamUpload reads a multipart upload form part by part, without
+// buffering it. The "file" part is streamed to disk, hashed and validated;
+// the other parts are returned as form values.
+func (h *DocumentHandler) streamUpload(w http.ResponseWriter, r *http.Request) (*services.UploadResult, map[string]string, error) {
+	allowSlowUpload(w)
+	r.Body = http.MaxBytesReader(w, r.Body, h.docService.MaxUploadSize()+1<<20)
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse form: %w", err)
+	}
+
+	var upload *services.UploadResult
+	form := make(map[string]string)
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if upload != nil {
+				h.docService.Cleanup(upload.FilePath)
+			}
+			return nil, nil, fmt.Errorf("failed to parse form: %w", err)
+		}
+
+		switch {
+		case part.FormName() == "file" && upload == nil:
+			upload, err = h.docService.ValidateUpload(part.FileName(), -1, part)
+			if err != nil {
+				part.Close()
+				return nil, nil, err
+			}
+		case part.FileName() == "":
+			value, _ := io.ReadAll(io.LimitReader(part, 1<<20))
+			form[part.FormName()] = string(value)
+		}
+		part.Close()
+	}
+
+	if upload == nil {
+		return nil, nil, fmt.Errorf("no file provided")
+	}
+	return upload, form, nil
+}
+
+// allowSlowUpload lifts the server's read timeout for a request whose body
+// is a large file.
+func allowSlowUpload(w http.ResponseWriter) {
+	http.NewResponseController(w).SetReadDeadline(time.Time{})
+}
+
+// uploadMappings parses an uploaded column mapping, which may only be given
+// for tabular files. An empty mapping yields nil.
+func uploadMappings(raw, fileType string) ([]*document.TableMapping, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	mappings, err := parseTableMappings(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mapping: %w", err)
+	}
+	if !document.IsTabularType(fileType) {
+		return nil, fmt.Errorf("a mapping can only be given for CSV and XLSX files")
+	}
+	return mappings, nil
+}
+
+// mappingExtras keeps uploaded column mappings in a new source's metadata,
+// where mapTables picks them up.
+func mappingExtras(mappings []*document.TableMapping) sourceExtras {
+	if mappings == nil {
+		return sourceExtras{}
+	}
+	metadata, _ := json.Marshal(map[string]interface{}{"table_mappings": mappings})
+	return sourceExtras{metadata: metadata}
+}
+
+// sourceExtras is what a new source is created with besides its file.
+type sourceExtras struct {
+	metadata    []byte   // initial metadata, or nil
+	trust       *float32 // nil leaves the trust unset
+	trustReason string
+}
+
+// createSource creates the source record for a validated upload in
+// projectID, or in no project if it is nil. The record is queued for
+// processing as it is inserted, so everything a worker reads is written
+// with it. Uploads are deduplicated per project: if the project already has
+// a source with the same content, the upload is discarded and that source
+// is returned unchanged with duplicate set.
+func (h *DocumentHandler) createSource(upload *services.UploadResult, projectID *uuid.UUID, extras sourceExtras) (src *database.Source, duplicate bool, err error) {
+	src, err = h.repo.CreateSource(
+		upload.Title,
+		upload.Filename,
+		upload.FilePath,
+		upload.FileType,
+		"uploaded",
+		false,
+		upload.ContentHash,
+		projectID,
+		extras.metadata,
+		extras.trust,
+		extras.trustReason,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// The insert conflicted on the content hash
+		h.docService.Cleanup(upload.FilePath)
+		existing, err := h.repo.GetSourceByContentHash(upload.ContentHash, projectID)
+		if err != nil {
+			return nil, false, err
+		}
+		return existing, true, nil
+	}
+	if err != nil {
+		h.docService.Cleanup(upload.FilePath)
+		return nil, false, err
+	}
+	return src, false, nil
+}
+
+// maxArchiveSize is the largest zip accepted by UploadProjectArchive.
+const maxArchiveSize = 500 << 20
+
+// ArchiveFileResult is the outcome for one file of a bulk upload.
+type ArchiveFileResult struct {
+	Filename   string `json:"filename"`
+	Status     string `json:"status"` // created, duplicate, error, or missing (listed in the manifest but not in the archive)
+	DocumentID string `json:"document_id,omitempty"`
+	Title      string `json:"title,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// UploadProjectArchive handles POST /api/projects/{id}/documents/archive. The
+// "file" form field is a zip of documents, optionally with a manifest.json
+// (as in corpora/*/manifest.json) giving titles, dates and trust values.
+// Each document becomes a source in the project and is queued for chunking;
+// files the project already has are reported as duplicates. A file uploaded
+// to another project becomes a separate source here, so no project's
+// sources are moved.
+func (h *DocumentHandler) UploadProjectArchive(w http.ResponseWriter, r *http.Request) {
+	projectID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid project ID", http.StatusBadRequest)
+		return
+	}
+	if _, err := h.db.GetProject(r.Context(), database.PgUUID(projectID)); err != nil {
+		http.Error(w, "Project not found", http.StatusNotFound)
+		return
+	}
+
+	allowSlowUpload(w)
+	r.Body = http.MaxBytesReader(w, r.Body, maxArchiveSize)
 	if err := r.ParseMultipartForm(50 << 20); err != nil {
 		h.logger.Error("failed to parse multipart form", "error", err)
 		http.Error(w, "Failed to parse form", http.StatusBadRequest)
@@ -57,35 +266,283 @@ func (h *DocumentHandler) UploadDocument(w http.ResponseWriter, r *http.Request)
 	}
 	defer file.Close()
 
-	// Validate upload
-	uploadResult, err := h.docService.ValidateUpload(header.Filename, header.Size, file)
+	archive, err := document.ReadUploadArchive(file, header.Size)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var results []ArchiveFileResult
+	counts := map[string]int{}
+	seen := make(map[*document.ArchiveDocument]bool)
+	for _, f := range archive.Files {
+		entry := archive.Manifest.Lookup(f.Name)
+		if entry != nil {
+			seen[entry] = true
+		}
+		result := h.uploadArchiveFile(f, entry, projectID)
+		counts[result.Status]++
+		results = append(results, result)
+	}
+	if archive.Manifest != nil {
+		for i := range archive.Manifest.Documents {
+			if d := &archive.Manifest.Documents[i]; !seen[d] {
+				results = append(results, ArchiveFileResult{Filename: d.Filename, Status: "missing", Error: "not found in archive"})
+				counts["missing"]++
+			}
+		}
+	}
+
+	h.logger.Info("archive uploaded", "project", projectID, "files", len(archive.Files),
+		"created", counts["created"], "duplicates", counts["duplicate"], "errors", counts["error"])
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"project_id": projectID,
+		"created":    counts["created"],
+		"duplicates": counts["duplicate"],
+		"errors":     counts["error"] + counts["missing"],
+		"files":      results,
+	})
+}
+
+// uploadArchiveFile stores one file of a bulk upload as a source in the
+// project, applying its manifest entry (if any) to a newly created source.
+// A file the project already has is reported as a duplicate and left as is.
+func (h *DocumentHandler) uploadArchiveFile(f *zip.File, entry *document.ArchiveDocument, projectID uuid.UUID) ArchiveFileResult {
+	result := ArchiveFileResult{Filename: f.Name, Status: "error"}
+
+	rc, err := f.Open()
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	upload, err := h.docService.ValidateUpload(path.Base(f.Name), int64(f.UncompressedSize64), rc)
+	rc.Close()
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	if entry != nil && entry.Title != "" {
+		upload.Title = entry.Title
+	}
+
+	src, duplicate, err := h.createSource(upload, &projectID, manifestExtras(entry))
+	if err != nil {
+		h.logger.Error("failed to create source record", "file", f.Name, "error", err)
+		result.Error = "failed to create document"
+		return result
+	}
+	result.DocumentID = database.UUIDStr(src.ID)
+	result.Title = src.Title
+
+	result.Status = "created"
+	if duplicate {
+		result.Status = "duplicate"
+	}
+	return result
+}
+
+// manifestExtras returns the metadata and trust a manifest entry gives a
+// new source.
+func manifestExtras(entry *document.ArchiveDocument) sourceExtras {
+	var extras sourceExtras
+	if entry == nil {
+		return extras
+	}
+	meta := map[string]interface{}{}
+	if entry.ID != "" {
+		meta["manifest_id"] = entry.ID
+	}
+	if entry.Type != "" {
+		meta["document_type"] = entry.Type
+	}
+	if date, ok := entry.ReferenceDate(); ok {
+		meta["date"] = date.Format(time.RFC3339)
+	}
+	if len(meta) > 0 {
+		extras.metadata, _ = json.Marshal(meta)
+	}
+	if entry.Trust != nil {
+		trust := float32(*entry.Trust)
+		extras.trust, extras.trustReason = &trust, "manifest"
+	}
+	return extras
+}
+
+// UploadDocumentVersion handles POST /api/documents/{id}/versions. The file
+// becomes the next version of the document's latest version, keeping its
+// title and project. A file identical to one of the versions returns that
+// version instead.
+func (h *DocumentHandler) UploadDocumentVersion(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	idStr := strings.TrimPrefix(r.URL.Path, "/api/documents/")
+	idStr = strings.TrimSuffix(idStr, "/versions")
+
+	srcID, err := uuid.Parse(idStr)
+	if err != nil {
+		http.Error(w, "Invalid document ID", http.StatusBadRequest)
+		return
+	}
+
+	versions, err := h.repo.ListSourceVersions(srcID)
+	if err != nil || len(versions) == 0 {
+		http.Error(w, "Document not found", http.StatusNotFound)
+		return
+	}
+	latest := versions[len(versions)-1]
+
+	uploadResult, _, err := h.streamUpload(w, r)
 	if err != nil {
 		h.logger.Error("upload validation failed", "error", err)
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	// Create source record
-	src, err := h.repo.CreateSource(
-		uploadResult.Title,
-		uploadResult.Filename,
-		uploadResult.FilePath,
-		uploadResult.FileType,
-		"uploaded",
-		false,
-	)
+	for _, v := range versions {
+		if v.ContentHash.Valid && v.ContentHash.String == uploadResult.ContentHash {
+			h.docService.Cleanup(uploadResult.FilePath)
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(v)
+			return
+		}
+	}
+
+	src, err := h.repo.CreateSourceVersion(latest, uploadResult.Filename, uploadResult.FilePath, uploadResult.FileType, uploadResult.ContentHash)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Another document in the project has the same content
+		h.docService.Cleanup(uploadResult.FilePath)
+		http.Error(w, "The project already has a document with the same content", http.StatusConflict)
+		return
+	}
 	if err != nil {
-		h.logger.Error("failed to create source record", "error", err)
-		http.Error(w, "Failed to create document", http.StatusInternalServerError)
+		h.logger.Error("failed to create source version", "error", err)
+		h.docService.Cleanup(uploadResult.FilePath)
+		http.Error(w, "Failed to create document version", http.StatusInternalServerError)
 		return
 	}
 
-	h.logger.Info("document uploaded", "id", src.ID, "filename", uploadResult.Filename)
+	h.logger.Info("document version uploaded", "id", src.ID, "previous", latest.ID, "version", src.Version)
 
 	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(src)
 }
 
+// ListDocumentVersions handles GET /api/documents/{id}/versions
+func (h *DocumentHandler) ListDocumentVersions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	idStr := strings.TrimPrefix(r.URL.Path, "/api/documents/")
+	idStr = strings.TrimSuffix(idStr, "/versions")
+
+	srcID, err := uuid.Parse(idStr)
+	if err != nil {
+		http.Error(w, "Invalid document ID", http.StatusBadRequest)
+		return
+	}
+
+	versions, err := h.repo.ListSourceVersions(srcID)
+	if err != nil {
+		h.logger.Error("failed to list source versions", "error", err)
+		http.Error(w, "Failed to list versions", http.StatusInternalServerError)
+		return
+	}
+	if len(versions) == 0 {
+		http.Error(w, "Document not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(versions)
+}
+
+// DiffDocument handles GET /api/documents/{id}/diff. It compares the
+// document's chunks with those of its previous version, or of the version
+// given by ?against={id}.
+func (h *DocumentHandler) DiffDocument(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	idStr := strings.TrimPrefix(r.URL.Path, "/api/documents/")
+	idStr = strings.TrimSuffix(idStr, "/diff")
+
+	srcID, err := uuid.Parse(idStr)
+	if err != nil {
+		http.Error(w, "Invalid document ID", http.StatusBadRequest)
+		return
+	}
+
+	src, err := h.repo.GetSource(srcID)
+	if err != nil {
+		http.Error(w, "Document not found", http.StatusNotFound)
+		return
+	}
+
+	var prevID uuid.UUID
+	if against := r.URL.Query().Get("against"); against != "" {
+		if prevID, err = uuid.Parse(against); err != nil {
+			http.Error(w, "Invalid against ID", http.StatusBadRequest)
+			return
+		}
+	} else if src.PreviousVersionID.Valid {
+		prevID = uuid.UUID(src.PreviousVersionID.Bytes)
+	} else {
+		http.Error(w, "Document has no previous version", http.StatusNotFound)
+		return
+	}
+
+	before, err := h.repo.GetChunks(prevID)
+	if err != nil {
+		h.logger.Error("failed to get chunks", "error", err)
+		http.Error(w, "Failed to get chunks", http.StatusInternalServerError)
+		return
+	}
+	after, err := h.repo.GetChunks(srcID)
+	if err != nil {
+		h.logger.Error("failed to get chunks", "error", err)
+		http.Error(w, "Failed to get chunks", http.StatusInternalServerError)
+		return
+	}
+
+	diffs := document.DiffChunks(chunkContents(before), chunkContents(after))
+	summary := map[string]int{
+		document.DiffUnchanged: 0,
+		document.DiffModified:  0,
+		document.DiffAdded:     0,
+		document.DiffRemoved:   0,
+	}
+	for _, d := range diffs {
+		summary[d.Status]++
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"document_id": srcID,
+		"against_id":  prevID,
+		"summary":     summary,
+		"chunks":      diffs,
+	})
+}
+
+// chunkContents returns the text of each chunk, in order.
+func chunkContents(chunks []*database.Chunk) []string {
+	contents := make([]string, len(chunks))
+	for i, c := range chunks {
+		contents[i] = c.Content
+	}
+	return contents
+}
+
 // GetDocumentStatus handles GET /api/documents/{id}/status
 func (h *DocumentHandler) GetDocumentStatus(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -239,63 +696,40 @@ func (h *DocumentHandler) DeleteDocument(w http.ResponseWriter, r *http.Request)
 	})
 }
 
-// ProcessDocuments is a background worker that processes uploaded documents.
-func (h *DocumentHandler) ProcessDocuments(stopCh <-chan struct{}) {
-	h.logger.Info("starting document processor")
-
-	ticker := time.NewTicker(5 * time.Second)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-stopCh:
-			h.logger.Info("stopping document processor")
-			return
-		case <-ticker.C:
-			h.processQueuedSources()
-		}
-	}
-}
-
-// processQueuedSources processes all sources in "uploaded" status.
-func (h *DocumentHandler) processQueuedSources() {
-	sources, err := h.repo.ListSources()
-	if err != nil {
-		h.logger.Error("failed to list sources", "error", err)
-		return
-	}
-
-	for _, src := range sources {
-		if src.UploadStatus == "uploaded" {
-			h.processSource(src)
-		}
-	}
-}
-
-// processSource processes a single source document.
-func (h *DocumentHandler) processSource(src *database.Source) {
+// processSource chunks a source a worker has claimed (see SourceWorkers) and
+// reports whether it is ready.
+func (h *DocumentHandler) processSource(src *database.Source) bool {
 	h.logger.Info("processing source", "id", src.ID, "filename", src.Filename)
 
 	srcID := uuid.UUID(src.ID.Bytes)
 
-	_, err := h.repo.UpdateSourceStatus(srcID, "processing", nil)
-	if err != nil {
-		h.logger.Error("failed to update source status", "error", err)
-		return
-	}
-
 	result, err := h.docService.ProcessDocument(src.FilePath, src.FileType)
 	if err != nil {
 		h.logger.Error("failed to process source", "error", err)
 		errMsg := err.Error()
 		h.repo.UpdateSourceStatus(srcID, "error", &errMsg)
-		return
+		return false
 	}
 
-	if src.FileType == "pdf" && result.TotalPages > 0 {
+	if result.TotalPages > 0 {
 		h.repo.UpdateSourceTotalPages(srcID, int32(result.TotalPages))
 	}
 
+	if len(result.Metadata) > 0 {
+		if metadata, err := json.Marshal(result.Metadata); err == nil {
+			if err := h.repo.UpdateSourceMetadata(srcID, metadata); err != nil {
+				h.logger.Error("failed to update source metadata", "error", err)
+			}
+		}
+	}
+
+	// A date given in an upload manifest dates chunks that have none
+	var sourceMeta struct {
+		Date string `json:"date"`
+	}
+	json.Unmarshal(src.Metadata, &sourceMeta)
+	sourceDate := sourceMeta.Date
+
 	for _, chunk := range result.Chunks {
 		var chapterTitle *string
 		if chunk.ChapterTitle != "" {
@@ -308,32 +742,139 @@ func (h *DocumentHandler) processSource(src *database.Source) {
 			chapterNumber = &cn
 		}
 
+		var sectionID, sectionName *string
+		if chunk.SectionID != "" {
+			sectionID = &chunk.SectionID
+		}
+		if chunk.SectionName != "" {
+			sectionName = &chunk.SectionName
+		}
+
+		var pageStart, pageEnd *int32
+		if chunk.PageStart != nil {
+			ps := int32(*chunk.PageStart)
+			pageStart = &ps
+		}
+		if chunk.PageEnd != nil {
+			pe := int32(*chunk.PageEnd)
+			pageEnd = &pe
+		}
+
 		wordCount := int32(document.WordCount(chunk.Content))
 
+		metadata := chunkMetadata(chunk, sourceDate)
+
 		_, err := h.repo.CreateChunk(
 			srcID,
 			int32(chunk.ChunkIndex),
 			chunk.Content,
 			chapterTitle,
 			chapterNumber,
-			nil,
-			nil,
+			pageStart,
+			pageEnd,
 			int32(chunk.NarrativePosition),
 			&wordCount,
+			sectionID,
+			sectionName,
+			metadata,
 		)
 		if err != nil {
 			h.logger.Error("failed to create chunk", "error", err)
 			errMsg := err.Error()
 			h.repo.UpdateSourceStatus(srcID, "error", &errMsg)
-			return
+			return false
 		}
 	}
 
+	if result.Tables != nil {
+		h.mapTables(src, result.Tables)
+	}
+
 	_, err = h.repo.UpdateSourceStatus(srcID, "ready", nil)
 	if err != nil {
 		h.logger.Error("failed to update source status", "error", err)
-		return
+		return false
 	}
 
 	h.logger.Info("source processed successfully", "id", src.ID, "chunks", len(result.Chunks))
+	return true
+}
+
+// mapTables writes a tabular source's rows to the graph using its column
+// mapping, inferring one if none was uploaded. The mapping used and the
+// outcome are kept in the source metadata so a bad guess can be corrected
+// and the file re-uploaded with an explicit mapping.
+func (h *DocumentHandler) mapTables(src *database.Source, tables []*document.Table) {
+	srcID := uuid.UUID(src.ID.Bytes)
+	ctx := context.Background()
+	meta := map[string]interface{}{}
+
+	mappings, inferred, err := h.tables.Mappings(src, tables)
+	if err == nil {
+		meta["table_mappings"] = mappings
+		meta["table_mappings_inferred"] = inferred
+		var result *graph.TableMapResult
+		result, err = h.tables.MapSource(ctx, src, tables, mappings)
+		if result != nil {
+			meta["table_map_result"] = result
+		}
+	}
+	if err != nil {
+		h.logger.Error("failed to map table source", "id", src.ID, "error", err)
+		meta["table_map_error"] = err.Error()
+	}
+
+	if metadata, err := json.Marshal(meta); err == nil {
+		if err := h.repo.UpdateSourceMetadata(srcID, metadata); err != nil {
+			h.logger.Error("failed to update source metadata", "error", err)
+		}
+	}
+}
+
+// parseTableMappings decodes an uploaded column mapping: a single mapping
+// object or a list of them.
+func parseTableMappings(raw string) ([]*document.TableMapping, error) {
+	var mappings []*document.TableMapping
+	if strings.HasPrefix(strings.TrimSpace(raw), "[") {
+		if err := json.Unmarshal([]byte(raw), &mappings); err != nil {
+			return nil, err
+		}
+	} else {
+		var m document.TableMapping
+		if err := json.Unmarshal([]byte(raw), &m); err != nil {
+			return nil, err
+		}
+		mappings = append(mappings, &m)
+	}
+	for _, m := range mappings {
+		if err := m.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return mappings, nil
+}
+
+// chunkMetadata serializes format-specific chunk metadata (Markdown heading
+// path, email headers) for the chunks.metadata column. date, the source's
+// date from an upload manifest, is used for chunks that carry none of their
+// own. Returns nil if none.
+func chunkMetadata(chunk document.Chunk, date string) []byte {
+	meta := make(map[string]interface{}, len(chunk.Metadata)+2)
+	for k, v := range chunk.Metadata {
+		meta[k] = v
+	}
+	if len(chunk.HeadingPath) > 0 {
+		meta["heading_path"] = chunk.HeadingPath
+	}
+	if _, ok := meta["date"]; !ok && date != "" {
+		meta["date"] = date
+	}
+	if len(meta) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return nil
+	}
+	return data
 }
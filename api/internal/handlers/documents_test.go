@@ -0,0 +1,338 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/einarsundgren/sikta/internal/database"
+	"github.com/einarsundgren/sikta/internal/database/dbtest"
+	"github.com/einarsundgren/sikta/internal/document"
+	"github.com/einarsundgren/sikta/internal/services"
+	"github.com/einarsundgren/sikta/internal/storage"
+)
+
+// testHandler returns a document handler on a fake database, keeping
+// uploads in a temporary directory, which it also returns.
+func testHandler(t *testing.T) (*DocumentHandler, *dbtest.DB, string) {
+	t.Helper()
+	db := dbtest.New()
+	dir := t.TempDir()
+	queries := database.New(db)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return &DocumentHandler{
+		db:         queries,
+		repo:       database.NewRepository(queries, context.Background()),
+		docService: services.NewDocumentService(logger, document.PDFOptions{}, 0, storage.NewLocal(dir), queries),
+		logger:     logger,
+	}, db, dir
+}
+
+// storedFiles counts the files kept in an upload directory.
+func storedFiles(t *testing.T, dir string) int {
+	t.Helper()
+	var n int
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			n++
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+// uploadRequest is a multipart upload of a text file with the given content.
+func uploadRequest(t *testing.T, target, content string) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", "protokoll.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write([]byte(content))
+	form.Close()
+	r := httptest.NewRequest(http.MethodPost, target, &body)
+	r.Header.Set("Content-Type", form.FormDataContentType())
+	return r
+}
+
+func contentHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+func testSource(n byte, content string) *database.Source {
+	return &database.Source{
+		ID:          pgtype.UUID{Bytes: uuid.UUID{15: n}, Valid: true},
+		Title:       "protokoll",
+		ContentHash: pgtype.Text{String: contentHash(content), Valid: content != ""},
+		Version:     1,
+	}
+}
+
+func TestUploadDocument(t *testing.T) {
+	existing := testSource(1, "Mötet öppnades.")
+
+	tests := []struct {
+		name       string
+		created    *database.Source
+		createErr  error
+		wantStatus int
+		wantID     pgtype.UUID
+		wantFiles  int
+	}{
+		{
+			name:       "created",
+			created:    testSource(2, "Mötet öppnades."),
+			wantStatus: http.StatusCreated,
+			wantID:     testSource(2, "").ID,
+			wantFiles:  1,
+		},
+		{
+			// ON CONFLICT DO NOTHING returns no row
+			name:       "duplicate returns the existing source",
+			wantStatus: http.StatusOK,
+			wantID:     existing.ID,
+		},
+		{
+			name:       "insert fails",
+			createErr:  errors.New("connection reset"),
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, db, dir := testHandler(t)
+			db.Return("CreateSource", tt.created, tt.createErr)
+			db.Return("GetSourceByContentHash", existing, nil)
+
+			w := httptest.NewRecorder()
+			h.UploadDocument(w, uploadRequest(t, "/api/documents", "Mötet öppnades."))
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if n := storedFiles(t, dir); n != tt.wantFiles {
+				t.Errorf("%d files stored, want %d", n, tt.wantFiles)
+			}
+			if tt.wantID.Valid {
+				var got database.Source
+				json.NewDecoder(w.Body).Decode(&got)
+				if got.ID != tt.wantID {
+					t.Errorf("ID = %v, want %v", got.ID, tt.wantID)
+				}
+			}
+		})
+	}
+}
+
+func TestCreateSourceDuplicateInProject(t *testing.T) {
+	h, db, _ := testHandler(t)
+	existing := testSource(1, "Mötet öppnades.")
+	db.Return("GetSourceByContentHash", existing, nil)
+
+	projectID := uuid.UUID{15: 9}
+	upload := &services.UploadResult{FilePath: "uploads/x.txt", ContentHash: existing.ContentHash.String}
+	src, duplicate, err := h.createSource(upload, &projectID, sourceExtras{})
+	if err != nil {
+		t.Fatalf("createSource() error = %v", err)
+	}
+	if !duplicate || src.ID != existing.ID {
+		t.Errorf("createSource() = %v, %v, want the existing source as a duplicate", src.ID, duplicate)
+	}
+
+	// The existing source is looked up in the same project
+	calls := db.Calls("GetSourceByContentHash")
+	if len(calls) != 1 {
+		t.Fatalf("GetSourceByContentHash called %d times, want 1", len(calls))
+	}
+	if got := calls[0].Args[1]; got != database.PgUUID(projectID) {
+		t.Errorf("GetSourceByContentHash project = %v, want %v", got, projectID)
+	}
+}
+
+func TestUploadDocumentVersion(t *testing.T) {
+	v1 := testSource(1, "Mötet öppnades.")
+	v2 := testSource(2, "Mötet öppnades kl. 19.")
+
+	tests := []struct {
+		name        string
+		content     string
+		created     *database.Source
+		createErr   error
+		wantStatus  int
+		wantID      pgtype.UUID
+		wantFiles   int
+		wantCreated bool // whether a version is inserted
+	}{
+		{
+			name:        "new version",
+			content:     "Mötet öppnades kl. 18.",
+			created:     testSource(3, "Mötet öppnades kl. 18."),
+			wantStatus:  http.StatusCreated,
+			wantID:      testSource(3, "").ID,
+			wantFiles:   1,
+			wantCreated: true,
+		},
+		{
+			name:       "same content as an older version",
+			content:    "Mötet öppnades.",
+			wantStatus: http.StatusOK,
+			wantID:     v1.ID,
+		},
+		{
+			name:       "same content as the latest version",
+			content:    "Mötet öppnades kl. 19.",
+			wantStatus: http.StatusOK,
+			wantID:     v2.ID,
+		},
+		{
+			// Another document in the project has the content, so the
+			// insert conflicts
+			name:        "same content as another document",
+			content:     "Dagordningen godkändes.",
+			wantStatus:  http.StatusConflict,
+			wantCreated: true,
+		},
+		{
+			name:        "insert fails",
+			content:     "Dagordningen godkändes.",
+			createErr:   errors.New("connection reset"),
+			wantStatus:  http.StatusInternalServerError,
+			wantCreated: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, db, dir := testHandler(t)
+			db.Return("ListSourceVersions", []*database.Source{v1, v2}, nil)
+			db.Return("CreateSourceVersion", tt.created, tt.createErr)
+
+			w := httptest.NewRecorder()
+			target := "/api/documents/" + uuid.UUID(v1.ID.Bytes).String() + "/versions"
+			h.UploadDocumentVersion(w, uploadRequest(t, target, tt.content))
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if n := storedFiles(t, dir); n != tt.wantFiles {
+				t.Errorf("%d files stored, want %d", n, tt.wantFiles)
+			}
+			calls := db.Calls("CreateSourceVersion")
+			if created := len(calls) > 0; created != tt.wantCreated {
+				t.Errorf("version inserted = %v, want %v", created, tt.wantCreated)
+			}
+			if tt.wantCreated {
+				// The new version follows the latest
+				if got := calls[0].Args[7]; got != v2.ID {
+					t.Errorf("previous_version_id = %v, want %v", got, v2.ID)
+				}
+			}
+			if tt.wantID.Valid {
+				var got database.Source
+				json.NewDecoder(w.Body).Decode(&got)
+				if got.ID != tt.wantID {
+					t.Errorf("ID = %v, want %v", got.ID, tt.wantID)
+				}
+			}
+		})
+	}
+}
+
+func TestUploadDocumentVersionNotFound(t *testing.T) {
+	h, _, dir := testHandler(t)
+
+	w := httptest.NewRecorder()
+	h.UploadDocumentVersion(w, uploadRequest(t, "/api/documents/"+uuid.New().String()+"/versions", "Mötet öppnades."))
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+	if n := storedFiles(t, dir); n != 0 {
+		t.Errorf("%d files stored, want 0", n)
+	}
+}
+
+func TestUploadArchiveFileManifest(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	fw, _ := zw.Create("docs/protokoll.txt")
+	fw.Write([]byte("Mötet öppnades."))
+	zw.Close()
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	trust := 0.8
+	entry := &document.ArchiveDocument{ID: "A1", Title: "Protokoll 1", Type: "protokoll", Trust: &trust, Date: "2024-03-01"}
+
+	tests := []struct {
+		name       string
+		entry      *document.ArchiveDocument
+		wantMeta   string
+		wantTrust  pgtype.Float4
+		wantReason pgtype.Text
+	}{
+		{
+			name:       "manifest entry",
+			entry:      entry,
+			wantMeta:   `{"date":"2024-03-01T00:00:00Z","document_type":"protokoll","manifest_id":"A1"}`,
+			wantTrust:  pgtype.Float4{Float32: 0.8, Valid: true},
+			wantReason: pgtype.Text{String: "manifest", Valid: true},
+		},
+		{
+			name: "no manifest entry",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, db, _ := testHandler(t)
+			db.Return("CreateSource", testSource(2, "Mötet öppnades."), nil)
+
+			result := h.uploadArchiveFile(zr.File[0], tt.entry, uuid.UUID{15: 9})
+			if result.Status != "created" {
+				t.Fatalf("Status = %q, want created: %s", result.Status, result.Error)
+			}
+
+			// Everything a worker reads is written by the insert that
+			// queues the source
+			calls := db.Calls("CreateSource")
+			if len(calls) != 1 {
+				t.Fatalf("CreateSource called %d times, want 1", len(calls))
+			}
+			args := calls[0].Args
+			if got, _ := args[8].([]byte); string(got) != tt.wantMeta {
+				t.Errorf("metadata = %s, want %s", got, tt.wantMeta)
+			}
+			if args[9] != tt.wantTrust || args[10] != tt.wantReason {
+				t.Errorf("trust = %v %v, want %v %v", args[9], args[10], tt.wantTrust, tt.wantReason)
+			}
+			if n := len(db.Calls("UpdateSourceMetadata")) + len(db.Calls("UpdateSourceTrust")); n != 0 {
+				t.Errorf("source updated %d times after the insert, want 0", n)
+			}
+		})
+	}
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/einarsundgren/sikta/internal/config"
 	"github.com/einarsundgren/sikta/internal/database"
+	"github.com/einarsundgren/sikta/internal/document"
 	"github.com/einarsundgren/sikta/internal/extraction"
 	"github.com/einarsundgren/sikta/internal/extraction/claude"
 	"github.com/google/uuid"
@@ -60,7 +61,18 @@ func (h *ExtractionHandler) TriggerExtraction(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	go h.runExtraction(context.Background(), parsedUUID.String())
+	// Tables are mapped to the graph when they are processed, not by the LLM
+	src, err := h.db.GetSource(r.Context(), database.PgUUID(parsedUUID))
+	if err == nil && document.IsTabularType(src.FileType) {
+		http.Error(w, "Tabular sources are mapped to the graph on upload", http.StatusConflict)
+		return
+	}
+
+	// ?only=changed skips chunks whose text is unchanged from the previous
+	// version; their claims stay with that version
+	onlyChanged := r.URL.Query().Get("only") == "changed"
+
+	go h.runExtraction(context.Background(), parsedUUID.String(), onlyChanged)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{
@@ -123,21 +135,28 @@ func (h *ExtractionHandler) StreamProgress(w http.ResponseWriter, r *http.Reques
 	}
 }
 
-// runExtraction runs the full extraction pipeline.
-func (h *ExtractionHandler) runExtraction(ctx context.Context, sourceID string) {
-	h.logger.Info("starting extraction pipeline", "source_id", sourceID)
-
-	// Get chunk count first
-	chunks, err := h.extract.GetChunkCount(ctx, sourceID)
+// runExtraction runs the full extraction pipeline. With onlyChanged, chunks
+// unchanged from the previous version of the document are skipped.
+func (h *ExtractionHandler) runExtraction(ctx context.Context, sourceID string, onlyChanged bool) {
+	h.logger.Info("starting extraction pipeline", "source_id", sourceID, "only_changed", onlyChanged)
+
+	// Get the chunks first
+	var chunks []*database.Chunk
+	var err error
+	if onlyChanged {
+		chunks, err = h.extract.ChangedChunks(ctx, sourceID)
+	} else {
+		chunks, err = h.db.ListChunksBySource(ctx, database.PgUUID(uuid.MustParse(sourceID)))
+	}
 	if err != nil {
 		h.logger.Error("failed to get chunks", "error", err)
 		h.progressTracker.Error(sourceID, err.Error())
 		return
 	}
-	h.progressTracker.Start(sourceID, chunks)
+	h.progressTracker.Start(sourceID, len(chunks))
 
 	var totalEvents, totalEntities, totalRelationships int
-	err = h.extract.ExtractDocument(ctx, sourceID, func(progress extraction.ExtractionProgress) {
+	err = h.extract.ExtractChunks(ctx, sourceID, chunks, func(progress extraction.ExtractionProgress) {
 		if progress.Status == "complete" {
 			return
 		}
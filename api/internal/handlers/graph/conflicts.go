@@ -0,0 +1,141 @@
+package graph
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/einarsundgren/sikta/internal/database"
+	"github.com/einarsundgren/sikta/internal/graph"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ConflictsHandler serves the claims a project's sources disagree on
+type ConflictsHandler struct {
+	db     *database.Queries
+	views  *graph.Views
+	logger *slog.Logger
+}
+
+// NewConflictsHandler creates a new conflict view handler
+func NewConflictsHandler(db *database.Queries, logger *slog.Logger) *ConflictsHandler {
+	return &ConflictsHandler{
+		db:     db,
+		views:  graph.NewViews(db, logger),
+		logger: logger,
+	}
+}
+
+// ConflictNode is an event or entity its sources disagree on
+type ConflictNode struct {
+	ID         string                    `json:"id"`
+	NodeType   string                    `json:"node_type"`
+	Label      string                    `json:"label"`
+	Time       []ConflictSide            `json:"time"`
+	Geo        []ConflictSide            `json:"geo"`
+	Properties map[string][]ConflictSide `json:"properties"` // disputed properties by key
+}
+
+// ConflictSide is one claimed value and the claims asserting it
+type ConflictSide struct {
+	DateStart *string         `json:"date_start,omitempty"`
+	DateEnd   *string         `json:"date_end,omitempty"`
+	DateText  *string         `json:"date_text,omitempty"`
+	GeoRegion *string         `json:"geo_region,omitempty"`
+	GeoText   *string         `json:"geo_text,omitempty"`
+	Value     any             `json:"value,omitempty"` // property value
+	Weight    float64         `json:"weight"`
+	Claims    []ConflictClaim `json:"claims"`
+}
+
+// ConflictClaim is one provenance record on a side
+type ConflictClaim struct {
+	ProvenanceID string  `json:"provenance_id"`
+	DocumentID   string  `json:"document_id"`
+	SourceID     string  `json:"source_id,omitempty"` // legacy source of the document
+	Document     string  `json:"document"`
+	Trust        float64 `json:"trust"`
+	Confidence   float64 `json:"confidence"`
+	Modality     string  `json:"modality"`
+	Status       string  `json:"status"`
+	Excerpt      string  `json:"excerpt"`
+}
+
+// GetConflicts handles GET /api/projects/{id}/conflicts?node_type=
+// Returns every event and entity whose sources claim different times,
+// places or property values, with all claims grouped by value, strongest
+// side first.
+func (h *ConflictsHandler) GetConflicts(w http.ResponseWriter, r *http.Request) {
+	projectID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid project ID", http.StatusBadRequest)
+		return
+	}
+	if _, err := h.db.GetProject(r.Context(), database.PgUUID(projectID)); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			http.Error(w, "Project not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("failed to get project", "error", err)
+		http.Error(w, "Failed to get conflicts", http.StatusInternalServerError)
+		return
+	}
+
+	view, err := h.views.GetConflicts(r.Context(), projectID, r.URL.Query().Get("node_type"))
+	if err != nil {
+		h.logger.Error("failed to get conflicts", "error", err, "project", projectID)
+		http.Error(w, "Failed to get conflicts", http.StatusInternalServerError)
+		return
+	}
+
+	result := make([]ConflictNode, len(view.Conflicts))
+	for i, c := range view.Conflicts {
+		result[i] = ConflictNode{
+			ID:         database.UUIDStr(c.Node.ID),
+			NodeType:   c.Node.NodeType,
+			Label:      c.Node.Label,
+			Time:       conflictSides(c.Time, view.Documents),
+			Geo:        conflictSides(c.Geo, view.Documents),
+			Properties: make(map[string][]ConflictSide, len(c.Properties)),
+		}
+		for k, sides := range c.Properties {
+			result[i].Properties[k] = conflictSides(sides, view.Documents)
+		}
+	}
+	writeJSON(w, result)
+}
+
+func conflictSides(sides []graph.ValueSupport, documents map[pgtype.UUID]*database.Node) []ConflictSide {
+	result := []ConflictSide{}
+	for _, s := range sides {
+		side := ConflictSide{
+			DateStart: timeStringPtr(s.Value.TimeStart),
+			DateEnd:   timeStringPtr(s.Value.TimeEnd),
+			DateText:  stringPtr(s.Value.TimeText),
+			GeoRegion: stringPtr(s.Value.GeoRegion),
+			GeoText:   stringPtr(s.Value.GeoText),
+			Value:     s.Value.PropertyValue,
+			Weight:    float64(s.Weight),
+		}
+		for _, p := range s.Provenance {
+			claim := ConflictClaim{
+				ProvenanceID: database.UUIDStr(p.ID),
+				DocumentID:   database.UUIDStr(p.SourceID),
+				Trust:        float64(p.Trust),
+				Confidence:   float64(p.Confidence),
+				Modality:     p.Modality,
+				Status:       p.Status,
+				Excerpt:      p.Excerpt,
+			}
+			if doc := documents[p.SourceID]; doc != nil {
+				claim.Document = doc.Label
+				claim.SourceID, _ = doc.GetProperty("source_id", "").(string)
+			}
+			side.Claims = append(side.Claims, claim)
+		}
+		result = append(result, side)
+	}
+	return result
+}
@@ -0,0 +1,80 @@
+package graph
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/einarsundgren/sikta/internal/database"
+	"github.com/einarsundgren/sikta/internal/graph"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// IdentitiesHandler serves the identity clusters same_as edges form
+type IdentitiesHandler struct {
+	db     *database.Queries
+	views  *graph.Views
+	logger *slog.Logger
+}
+
+// NewIdentitiesHandler creates a new identity cluster handler
+func NewIdentitiesHandler(db *database.Queries, logger *slog.Logger) *IdentitiesHandler {
+	return &IdentitiesHandler{
+		db:     db,
+		views:  graph.NewViews(db, logger),
+		logger: logger,
+	}
+}
+
+// IdentityClusterResponse is one identity cluster
+type IdentityClusterResponse struct {
+	ID        string          `json:"id"`
+	Canonical TraversalNode   `json:"canonical"`
+	Members   []TraversalNode `json:"members"`
+}
+
+// GetIdentities handles GET /api/projects/{id}/identities?view_strategy=
+// Returns the project's identity clusters, each with the member the view
+// strategy (default trust_weighted) picks as canonical.
+func (h *IdentitiesHandler) GetIdentities(w http.ResponseWriter, r *http.Request) {
+	projectID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid project ID", http.StatusBadRequest)
+		return
+	}
+	if _, err := h.db.GetProject(r.Context(), database.PgUUID(projectID)); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			http.Error(w, "Project not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("failed to get project", "error", err)
+		http.Error(w, "Failed to get identities", http.StatusInternalServerError)
+		return
+	}
+
+	strategy := graph.ViewStrategy(r.URL.Query().Get("view_strategy"))
+	if strategy == "" {
+		strategy = graph.ViewStrategyTrustWeighted
+	}
+
+	clusters, err := h.views.IdentityClusters(r.Context(), projectID, strategy)
+	if err != nil {
+		h.logger.Error("failed to get identity clusters", "error", err, "project", projectID)
+		http.Error(w, "Failed to get identities", http.StatusInternalServerError)
+		return
+	}
+
+	result := make([]IdentityClusterResponse, len(clusters))
+	for i, c := range clusters {
+		result[i] = IdentityClusterResponse{
+			ID:        database.UUIDStr(c.ID),
+			Canonical: traversalNode(c.Canonical, nil),
+			Members:   make([]TraversalNode, len(c.Members)),
+		}
+		for j, m := range c.Members {
+			result[i].Members[j] = traversalNode(m, nil)
+		}
+	}
+	writeJSON(w, result)
+}
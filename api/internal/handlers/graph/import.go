@@ -0,0 +1,86 @@
+package graph
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/einarsundgren/sikta/internal/database"
+	"github.com/einarsundgren/sikta/internal/graph"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// maxImportSize bounds an import file; eval results run to a few megabytes
+const maxImportSize = 64 << 20
+
+// ImportHandler loads graph JSON into a project
+type ImportHandler struct {
+	db       *database.Queries
+	importer *graph.Importer
+	logger   *slog.Logger
+}
+
+// NewImportHandler creates a new graph import handler
+func NewImportHandler(db *database.Queries, logger *slog.Logger) *ImportHandler {
+	return &ImportHandler{
+		db:       db,
+		importer: graph.NewImporter(db, graph.NewService(db, logger), logger),
+		logger:   logger,
+	}
+}
+
+// ImportGraph handles POST /api/projects/{id}/graph/import?source=
+// The body is a GetProjectGraph response or a sikta-eval ExtractionResult
+// (results/*.json). Nodes and edges get new IDs and pending provenance
+// from the source's document node; see graph.Importer.
+func (h *ImportHandler) ImportGraph(w http.ResponseWriter, r *http.Request) {
+	projectID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid project ID", http.StatusBadRequest)
+		return
+	}
+	sourceID, err := uuid.Parse(r.URL.Query().Get("source"))
+	if err != nil {
+		http.Error(w, "source must be a source ID in the project", http.StatusBadRequest)
+		return
+	}
+	source, err := h.db.GetSource(r.Context(), database.PgUUID(sourceID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			http.Error(w, "Source not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("failed to get source", "error", err, "source", sourceID)
+		http.Error(w, "Failed to import graph", http.StatusInternalServerError)
+		return
+	}
+	if source.ProjectID != database.PgUUID(projectID) {
+		http.Error(w, "Source is not in this project", http.StatusBadRequest)
+		return
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxImportSize))
+	if err != nil {
+		http.Error(w, "Import file too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	in, err := graph.ParseImport(data)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.importer.Import(r.Context(), projectID, sourceID, in)
+	if err != nil {
+		h.logger.Error("failed to import graph", "error", err, "project", projectID, "source", sourceID)
+		http.Error(w, "Failed to import graph", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,112 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/einarsundgren/sikta/internal/database"
+	"github.com/einarsundgren/sikta/internal/query"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// queryTimeout bounds how long one graph query may run
+const queryTimeout = 30 * time.Second
+
+// QueryHandler runs graph queries against a project
+type QueryHandler struct {
+	db     *database.Queries
+	logger *slog.Logger
+}
+
+// NewQueryHandler creates a new graph query handler
+func NewQueryHandler(db *database.Queries, logger *slog.Logger) *QueryHandler {
+	return &QueryHandler{db: db, logger: logger}
+}
+
+// QueryRequest is the body for POST /api/projects/{id}/query
+type QueryRequest struct {
+	Query  string         `json:"query"`
+	Params map[string]any `json:"params"`
+}
+
+// QueryResponse holds the result columns, in RETURN order, and one object
+// per row keyed by column
+type QueryResponse struct {
+	Columns []string          `json:"columns"`
+	Rows    []json.RawMessage `json:"rows"`
+}
+
+// RunQuery handles POST /api/projects/{id}/query
+// Runs a Cypher-like pattern query over the project's graph, e.g.
+//
+//	MATCH (p:person {label: $name})-[:involved_in]->(e:event)-[:located_at]->(pl:place)
+//	WHERE pl.label = "Berlin" AND e.claimed_time >= "1920" AND e.claimed_time < "1930"
+//	RETURN e, p.label AS person ORDER BY e.claimed_time LIMIT 50
+//
+// See query.Compile for how fields map onto nodes, edges and provenance.
+func (h *QueryHandler) RunQuery(w http.ResponseWriter, r *http.Request) {
+	projectID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid project ID", http.StatusBadRequest)
+		return
+	}
+	if _, err := h.db.GetProject(r.Context(), database.PgUUID(projectID)); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			http.Error(w, "Project not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("failed to get project", "error", err, "id", projectID)
+		http.Error(w, "Failed to get project", http.StatusInternalServerError)
+		return
+	}
+
+	var req QueryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(req.Query) == "" {
+		http.Error(w, "query is required", http.StatusBadRequest)
+		return
+	}
+
+	parsed, err := query.Parse(req.Query)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	compiled, err := query.Compile(parsed, projectID, req.Params)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), queryTimeout)
+	defer cancel()
+	rows, err := h.db.QueryJSON(ctx, compiled.SQL, compiled.Args...)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		switch {
+		case errors.As(err, &pgErr) && strings.HasPrefix(pgErr.Code, "22"):
+			// Data exceptions come from the query's values, such as a bad
+			// regular expression
+			http.Error(w, pgErr.Message, http.StatusBadRequest)
+		case errors.Is(err, context.DeadlineExceeded) || errors.As(err, &pgErr) && pgErr.Code == "57014":
+			http.Error(w, "Query took too long; narrow the pattern or add conditions", http.StatusGatewayTimeout)
+		default:
+			h.logger.Error("failed to run graph query", "error", err, "project", projectID)
+			http.Error(w, "Failed to run query", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	h.logger.Info("graph query run", "project", projectID, "rows", len(rows))
+	writeJSON(w, QueryResponse{Columns: compiled.Columns, Rows: rows})
+}
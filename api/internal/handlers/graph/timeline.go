@@ -2,6 +2,7 @@ package graph
 
 import (
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"net/http"
 	"strings"
@@ -10,6 +11,7 @@ import (
 	"github.com/einarsundgren/sikta/internal/database"
 	"github.com/einarsundgren/sikta/internal/graph"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 )
 
 // TimelineHandler handles timeline requests using the graph model
@@ -55,6 +57,35 @@ type TimelineEvent struct {
 	Inconsistencies   []TimelineInconsistency `json:"inconsistencies"`
 	ChapterTitle      *string                 `json:"chapter_title"`
 	ChapterNumber     *int32                  `json:"chapter_number"`
+	Majority          *TimelineMajority       `json:"majority,omitempty"`
+}
+
+// TimelineMajority is how the majority view strategy resolved a claim's
+// time, place and properties
+type TimelineMajority struct {
+	Time       *TimelineResolution            `json:"time,omitempty"`
+	Geo        *TimelineResolution            `json:"geo,omitempty"`
+	Properties map[string]*TimelineResolution `json:"properties,omitempty"` // by property key
+}
+
+// TimelineResolution is the winning value and the values that lost to it
+type TimelineResolution struct {
+	Winner     TimelineClaimedValue   `json:"winner"`
+	Dissenting []TimelineClaimedValue `json:"dissenting"`
+}
+
+// TimelineClaimedValue is a claimed time, place or property value and the
+// documents asserting it
+type TimelineClaimedValue struct {
+	DateStart     *string  `json:"date_start,omitempty"`
+	DateEnd       *string  `json:"date_end,omitempty"`
+	DateText      *string  `json:"date_text,omitempty"`
+	GeoRegion     *string  `json:"geo_region,omitempty"`
+	GeoText       *string  `json:"geo_text,omitempty"`
+	Value         any      `json:"value,omitempty"` // property value
+	Sources       []string `json:"sources"`         // document node IDs
+	Weight        float64  `json:"weight"`
+	ProvenanceIDs []string `json:"provenance_ids"`
 }
 
 // TimelineEntity represents an entity connected to a claim
@@ -114,12 +145,66 @@ func (h *TimelineHandler) GetTimeline(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Convert to legacy format
+	timelineEvents := convertEvents(events, idStr)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(timelineEvents)
+}
+
+// GetProjectTimeline handles GET /api/projects/{id}/timeline
+// Events from all of the project's documents, each resolved over every
+// document's provenance. view_strategy=majority picks the time and place
+// most sources agree on and lists the dissenting ones. canonical=true
+// collapses events joined by same_as into one.
+func (h *TimelineHandler) GetProjectTimeline(w http.ResponseWriter, r *http.Request) {
+	projectID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid project ID", http.StatusBadRequest)
+		return
+	}
+	if _, err := h.db.GetProject(r.Context(), database.PgUUID(projectID)); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			http.Error(w, "Project not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("failed to get project", "error", err)
+		http.Error(w, "Failed to get timeline", http.StatusInternalServerError)
+		return
+	}
+
+	strategy := graph.ViewStrategy(r.URL.Query().Get("view_strategy"))
+	if strategy == "" {
+		strategy = graph.ViewStrategyTrustWeighted
+	}
+
+	var events []graph.TimelineEvent
+	if r.URL.Query().Get("canonical") == "true" {
+		events, err = h.views.GetCanonicalEventsForTimeline(r.Context(), projectID, strategy)
+	} else {
+		events, err = h.views.GetProjectEventsForTimeline(r.Context(), projectID, strategy)
+	}
+	if err != nil {
+		h.logger.Error("failed to get project timeline events", "error", err, "project", projectID)
+		http.Error(w, "Failed to get timeline", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(convertEvents(events, ""))
+}
+
+// convertEvents converts graph events to the legacy format. Events without
+// a source of their own get sourceID.
+func convertEvents(events []graph.TimelineEvent, sourceID string) []TimelineEvent {
 	timelineEvents := make([]TimelineEvent, len(events))
 	for i, event := range events {
+		eventSourceID := sourceID
+		if event.SourceID != "" {
+			eventSourceID = event.SourceID
+		}
 		timelineEvents[i] = TimelineEvent{
 			ID:                event.ID,
-			SourceID:          idStr,
+			SourceID:          eventSourceID,
 			Title:             event.Title,
 			Description:       stringPtr(event.Description),
 			EventType:         stringPtr(event.Type),
@@ -134,11 +219,60 @@ func (h *TimelineHandler) GetTimeline(w http.ResponseWriter, r *http.Request) {
 			Entities:          convertEntities(event.Entities),
 			SourceReferences:  []TimelineSource{}, // TODO: populate from provenance
 			Inconsistencies:   []TimelineInconsistency{}, // TODO: map from inconsistency nodes
+			Majority:          convertMajority(event.Majority),
 		}
 	}
+	return timelineEvents
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(timelineEvents)
+func convertMajority(m *graph.Majority) *TimelineMajority {
+	if m == nil {
+		return nil
+	}
+	result := &TimelineMajority{
+		Time: convertResolution(m.Time),
+		Geo:  convertResolution(m.Geo),
+	}
+	if len(m.Properties) > 0 {
+		result.Properties = make(map[string]*TimelineResolution, len(m.Properties))
+		for k, r := range m.Properties {
+			result.Properties[k] = convertResolution(r)
+		}
+	}
+	return result
+}
+
+func convertResolution(r *graph.Resolution) *TimelineResolution {
+	if r == nil {
+		return nil
+	}
+	result := &TimelineResolution{
+		Winner:     convertClaimedValue(r.Winner),
+		Dissenting: []TimelineClaimedValue{},
+	}
+	for _, d := range r.Dissenting {
+		result.Dissenting = append(result.Dissenting, convertClaimedValue(d))
+	}
+	return result
+}
+
+func convertClaimedValue(s graph.ValueSupport) TimelineClaimedValue {
+	v := TimelineClaimedValue{
+		DateStart: timeStringPtr(s.Value.TimeStart),
+		DateEnd:   timeStringPtr(s.Value.TimeEnd),
+		DateText:  stringPtr(s.Value.TimeText),
+		GeoRegion: stringPtr(s.Value.GeoRegion),
+		GeoText:   stringPtr(s.Value.GeoText),
+		Value:     s.Value.PropertyValue,
+		Weight:    float64(s.Weight),
+	}
+	for _, id := range s.Sources {
+		v.Sources = append(v.Sources, database.UUIDStr(id))
+	}
+	for _, p := range s.Provenance {
+		v.ProvenanceIDs = append(v.ProvenanceIDs, database.UUIDStr(p.ID))
+	}
+	return v
 }
 
 func stringPtr(s string) *string {
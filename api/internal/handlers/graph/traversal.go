@@ -0,0 +1,341 @@
+package graph
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/einarsundgren/sikta/internal/database"
+	"github.com/einarsundgren/sikta/internal/graph"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// TraversalHandler serves neighbourhood, subgraph and path queries over a
+// project's graph
+type TraversalHandler struct {
+	db     *database.Queries
+	graph  *graph.Service
+	logger *slog.Logger
+}
+
+// NewTraversalHandler creates a new graph traversal handler
+func NewTraversalHandler(db *database.Queries, logger *slog.Logger) *TraversalHandler {
+	return &TraversalHandler{
+		db:     db,
+		graph:  graph.NewService(db, logger),
+		logger: logger,
+	}
+}
+
+// TraversalNode is a node in a traversal response
+type TraversalNode struct {
+	ID         string         `json:"id"`
+	NodeType   string         `json:"node_type"`
+	Label      string         `json:"label"`
+	Properties map[string]any `json:"properties,omitempty"`
+	Depth      *int           `json:"depth,omitempty"`
+}
+
+// TraversalEdge is an edge in a traversal response
+type TraversalEdge struct {
+	ID         string         `json:"id"`
+	EdgeType   string         `json:"edge_type"`
+	SourceNode string         `json:"source_node"`
+	TargetNode string         `json:"target_node"`
+	Properties map[string]any `json:"properties,omitempty"`
+	IsNegated  bool           `json:"is_negated"`
+}
+
+// SubgraphResponse is the response for neighbour and subgraph queries
+type SubgraphResponse struct {
+	Nodes []TraversalNode `json:"nodes"`
+	Edges []TraversalEdge `json:"edges"`
+}
+
+// PathResponse is one path between two nodes
+type PathResponse struct {
+	Length int             `json:"length"`
+	Nodes  []TraversalNode `json:"nodes"`
+	Edges  []TraversalEdge `json:"edges"`
+}
+
+// SubgraphRequest is the body for POST /api/projects/{id}/graph/subgraph
+type SubgraphRequest struct {
+	NodeIDs        []string `json:"node_ids"`
+	Depth          int      `json:"depth"`
+	EdgeTypes      []string `json:"edge_types"`
+	Direction      string   `json:"direction"`
+	Status         []string `json:"status"`
+	IncludeNegated bool     `json:"include_negated"`
+}
+
+// GetNeighbors handles GET /api/projects/{id}/graph/nodes/{nodeId}/neighbors
+// Query parameters: depth, edge_types and status (comma-separated),
+// direction (out, in or both) and include_negated.
+func (h *TraversalHandler) GetNeighbors(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := h.project(w, r)
+	if !ok {
+		return
+	}
+	nodeID, err := uuid.Parse(r.PathValue("nodeId"))
+	if err != nil {
+		http.Error(w, "Invalid node ID", http.StatusBadRequest)
+		return
+	}
+
+	opts, err := traversalOptionsFromQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if depth := r.URL.Query().Get("depth"); depth != "" {
+		if opts.Depth, err = strconv.Atoi(depth); err != nil || opts.Depth < 1 {
+			http.Error(w, "Invalid depth", http.StatusBadRequest)
+			return
+		}
+	}
+
+	sub, err := h.graph.Neighbors(r.Context(), projectID, nodeID, opts)
+	if err != nil {
+		h.traversalFailed(w, err, "neighbors", "node", nodeID)
+		return
+	}
+	writeJSON(w, subgraphResponse(sub))
+}
+
+// GetSubgraph handles POST /api/projects/{id}/graph/subgraph
+// Returns the nodes within depth hops of any of the given nodes and the
+// edges among them.
+func (h *TraversalHandler) GetSubgraph(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := h.project(w, r)
+	if !ok {
+		return
+	}
+
+	var req SubgraphRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if len(req.NodeIDs) == 0 {
+		http.Error(w, "node_ids is required", http.StatusBadRequest)
+		return
+	}
+	if len(req.NodeIDs) > graph.MaxStartNodes {
+		http.Error(w, "Too many node_ids; at most "+strconv.Itoa(graph.MaxStartNodes)+" are allowed", http.StatusBadRequest)
+		return
+	}
+	if req.Depth < 0 {
+		http.Error(w, "Invalid depth", http.StatusBadRequest)
+		return
+	}
+	nodeIDs := make([]uuid.UUID, len(req.NodeIDs))
+	for i, s := range req.NodeIDs {
+		id, err := uuid.Parse(s)
+		if err != nil {
+			http.Error(w, "Invalid node ID: "+s, http.StatusBadRequest)
+			return
+		}
+		nodeIDs[i] = id
+	}
+
+	opts, err := traversalOptions(req.EdgeTypes, req.Direction, req.Status, req.IncludeNegated)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	opts.Depth = req.Depth
+
+	sub, err := h.graph.Subgraph(r.Context(), projectID, nodeIDs, opts)
+	if err != nil {
+		h.traversalFailed(w, err, "subgraph", "project", projectID)
+		return
+	}
+	writeJSON(w, subgraphResponse(sub))
+}
+
+// GetPaths handles GET /api/projects/{id}/graph/paths?from=&to=
+// Returns the shortest paths between two nodes, or every simple path up to
+// max_depth hops (at most graph.MaxAllPathsDepth) if all=true, at most limit
+// of them. Accepts the same edge filters as GetNeighbors.
+func (h *TraversalHandler) GetPaths(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := h.project(w, r)
+	if !ok {
+		return
+	}
+	q := r.URL.Query()
+	from, err := uuid.Parse(q.Get("from"))
+	if err != nil {
+		http.Error(w, "Invalid from node ID", http.StatusBadRequest)
+		return
+	}
+	to, err := uuid.Parse(q.Get("to"))
+	if err != nil {
+		http.Error(w, "Invalid to node ID", http.StatusBadRequest)
+		return
+	}
+
+	var opts graph.PathOptions
+	if opts.TraversalOptions, err = traversalOptionsFromQuery(q); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if v := q.Get("max_depth"); v != "" {
+		if opts.MaxDepth, err = strconv.Atoi(v); err != nil || opts.MaxDepth < 1 {
+			http.Error(w, "Invalid max_depth", http.StatusBadRequest)
+			return
+		}
+	}
+	if v := q.Get("limit"); v != "" {
+		if opts.Limit, err = strconv.Atoi(v); err != nil || opts.Limit < 1 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+	opts.All = q.Get("all") == "true"
+
+	paths, err := h.graph.Paths(r.Context(), projectID, from, to, opts)
+	if err != nil {
+		h.traversalFailed(w, err, "paths", "from", from, "to", to)
+		return
+	}
+
+	response := make([]PathResponse, len(paths))
+	for i, p := range paths {
+		resp := PathResponse{Length: len(p.Edges)}
+		for _, n := range p.Nodes {
+			resp.Nodes = append(resp.Nodes, traversalNode(n, nil))
+		}
+		for _, e := range p.Edges {
+			resp.Edges = append(resp.Edges, traversalEdge(e))
+		}
+		response[i] = resp
+	}
+	writeJSON(w, response)
+}
+
+// project parses the project ID from the path and checks that the project
+// exists, writing an error response if not
+func (h *TraversalHandler) project(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	projectID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid project ID", http.StatusBadRequest)
+		return uuid.Nil, false
+	}
+	if _, err := h.db.GetProject(r.Context(), database.PgUUID(projectID)); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			http.Error(w, "Project not found", http.StatusNotFound)
+		} else {
+			h.logger.Error("failed to get project", "error", err, "id", projectID)
+			http.Error(w, "Failed to get project", http.StatusInternalServerError)
+		}
+		return uuid.Nil, false
+	}
+	return projectID, true
+}
+
+// traversalFailed writes the error response for a failed traversal: 404 for
+// a start node outside the project's graph, 504 for a traversal that ran out
+// of time and 500 otherwise
+func (h *TraversalHandler) traversalFailed(w http.ResponseWriter, err error, what string, args ...any) {
+	switch {
+	case errors.Is(err, graph.ErrNodeNotFound):
+		http.Error(w, "Node not found", http.StatusNotFound)
+	case errors.Is(err, graph.ErrTraversalTimeout):
+		http.Error(w, "Graph traversal took too long; lower the depth or filter edge types", http.StatusGatewayTimeout)
+	default:
+		h.logger.Error("failed to get "+what, append([]any{"error", err}, args...)...)
+		http.Error(w, "Failed to get "+what, http.StatusInternalServerError)
+	}
+}
+
+// traversalOptionsFromQuery reads the edge filters shared by the GET
+// traversal endpoints
+func traversalOptionsFromQuery(q url.Values) (graph.TraversalOptions, error) {
+	return traversalOptions(splitList(q.Get("edge_types")), q.Get("direction"), splitList(q.Get("status")), q.Get("include_negated") == "true")
+}
+
+func traversalOptions(edgeTypes []string, direction string, statuses []string, includeNegated bool) (graph.TraversalOptions, error) {
+	opts := graph.TraversalOptions{
+		EdgeTypes:      edgeTypes,
+		Direction:      graph.Direction(direction),
+		IncludeNegated: includeNegated,
+	}
+	switch opts.Direction {
+	case "", graph.DirectionOut, graph.DirectionIn, graph.DirectionBoth:
+	default:
+		return opts, errors.New("direction must be out, in or both")
+	}
+	for _, s := range statuses {
+		if !validReviewStatus(s) {
+			return opts, errors.New("invalid status: " + s)
+		}
+		opts.Statuses = append(opts.Statuses, database.ReviewStatus(s))
+	}
+	return opts, nil
+}
+
+// splitList splits a comma-separated query value, dropping empty items
+func splitList(s string) []string {
+	var out []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+func subgraphResponse(sub *graph.Subgraph) SubgraphResponse {
+	resp := SubgraphResponse{
+		Nodes: make([]TraversalNode, 0, len(sub.Nodes)),
+		Edges: make([]TraversalEdge, 0, len(sub.Edges)),
+	}
+	for _, n := range sub.Nodes {
+		depth := n.Depth
+		resp.Nodes = append(resp.Nodes, traversalNode(n.Node, &depth))
+	}
+	for _, e := range sub.Edges {
+		resp.Edges = append(resp.Edges, traversalEdge(e))
+	}
+	return resp
+}
+
+func traversalNode(n *database.Node, depth *int) TraversalNode {
+	props := make(map[string]any)
+	if len(n.Properties) > 0 {
+		_ = json.Unmarshal(n.Properties, &props)
+	}
+	return TraversalNode{
+		ID:         database.UUIDStr(n.ID),
+		NodeType:   n.NodeType,
+		Label:      n.Label,
+		Properties: props,
+		Depth:      depth,
+	}
+}
+
+func traversalEdge(e *database.Edge) TraversalEdge {
+	props := make(map[string]any)
+	if len(e.Properties) > 0 {
+		_ = json.Unmarshal(e.Properties, &props)
+	}
+	return TraversalEdge{
+		ID:         database.UUIDStr(e.ID),
+		EdgeType:   e.EdgeType,
+		SourceNode: database.UUIDStr(e.SourceNode),
+		TargetNode: database.UUIDStr(e.TargetNode),
+		Properties: props,
+		IsNegated:  e.IsNegated,
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,113 @@
+package graph
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/einarsundgren/sikta/internal/database"
+	"github.com/einarsundgren/sikta/internal/database/dbtest"
+	"github.com/einarsundgren/sikta/internal/graph"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const testProject = "5b1c8f8e-0000-4000-8000-000000000009"
+
+// testTraversalHandler returns a traversal handler on a fake database that
+// has the test project and, of the nodes asked about, only those given in
+// its graph.
+func testTraversalHandler(inGraph ...uuid.UUID) (*TraversalHandler, *dbtest.DB) {
+	db := dbtest.New()
+	db.Return("GetProject", &database.Project{ID: database.PgUUID(uuid.MustParse(testProject))}, nil)
+	found := make([]pgtype.UUID, len(inGraph))
+	for i, id := range inGraph {
+		found[i] = database.PgUUID(id)
+	}
+	db.Return("ListProjectNodesAmong", found, nil)
+	return NewTraversalHandler(database.New(db), slog.New(slog.NewTextHandler(io.Discard, nil))), db
+}
+
+func neighborsRequest(nodeID string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/api/projects/"+testProject+"/graph/nodes/"+nodeID+"/neighbors", nil)
+	r.SetPathValue("id", testProject)
+	r.SetPathValue("nodeId", nodeID)
+	return r
+}
+
+func subgraphRequest(nodeIDs []string) *http.Request {
+	body, _ := json.Marshal(SubgraphRequest{NodeIDs: nodeIDs, Depth: 2})
+	r := httptest.NewRequest(http.MethodPost, "/api/projects/"+testProject+"/graph/subgraph", bytes.NewReader(body))
+	r.SetPathValue("id", testProject)
+	return r
+}
+
+func TestTraversalOtherProjectNode(t *testing.T) {
+	// The node exists, but only in another project's graph
+	other := uuid.New()
+
+	tests := []struct {
+		name    string
+		request *http.Request
+		serve   func(h *TraversalHandler) http.HandlerFunc
+	}{
+		{"neighbors", neighborsRequest(other.String()), func(h *TraversalHandler) http.HandlerFunc { return h.GetNeighbors }},
+		{"subgraph", subgraphRequest([]string{other.String()}), func(h *TraversalHandler) http.HandlerFunc { return h.GetSubgraph }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, db := testTraversalHandler()
+			db.Return("GetNode", &database.Node{ID: database.PgUUID(other)}, nil)
+
+			w := httptest.NewRecorder()
+			tt.serve(h)(w, tt.request)
+			if w.Code != http.StatusNotFound {
+				t.Errorf("status = %d, want %d: %s", w.Code, http.StatusNotFound, w.Body)
+			}
+			if n := len(db.Calls("TraverseProjectGraph")); n != 0 {
+				t.Errorf("graph traversed %d times, want 0", n)
+			}
+		})
+	}
+}
+
+func TestGetNeighbors(t *testing.T) {
+	node := uuid.New()
+	h, db := testTraversalHandler(node)
+	db.Return("TraverseProjectGraph", []*database.TraverseProjectGraphRow{{NodeID: database.PgUUID(node)}}, nil)
+	db.Return("ListNodesByIDs", []*database.Node{{ID: database.PgUUID(node), NodeType: "person", Label: "Anna"}}, nil)
+
+	w := httptest.NewRecorder()
+	h.GetNeighbors(w, neighborsRequest(node.String()))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+	var resp SubgraphResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Nodes) != 1 || resp.Nodes[0].ID != node.String() || *resp.Nodes[0].Depth != 0 {
+		t.Errorf("nodes = %+v, want the start node at depth 0", resp.Nodes)
+	}
+}
+
+func TestGetSubgraphTooManyNodes(t *testing.T) {
+	h, db := testTraversalHandler()
+	nodeIDs := make([]string, graph.MaxStartNodes+1)
+	for i := range nodeIDs {
+		nodeIDs[i] = uuid.NewString()
+	}
+
+	w := httptest.NewRecorder()
+	h.GetSubgraph(w, subgraphRequest(nodeIDs))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d: %s", w.Code, http.StatusBadRequest, w.Body)
+	}
+	if n := len(db.Calls("ListProjectNodesAmong")); n != 0 {
+		t.Errorf("start nodes checked %d times, want 0", n)
+	}
+}
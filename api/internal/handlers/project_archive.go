@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/einarsundgren/sikta/internal/archive"
+	"github.com/einarsundgren/sikta/internal/database"
+	"github.com/einarsundgren/sikta/internal/storage"
+)
+
+// maxProjectArchiveSize is the largest archive accepted by ImportProject
+const maxProjectArchiveSize = 2 << 30
+
+// ProjectArchiveHandler exports whole projects as archives and imports them
+type ProjectArchiveHandler struct {
+	db     *database.Queries
+	store  storage.Storage
+	logger *slog.Logger
+}
+
+// NewProjectArchiveHandler creates a new project archive handler. Source
+// files are read from and written to store.
+func NewProjectArchiveHandler(db *database.Queries, store storage.Storage, logger *slog.Logger) *ProjectArchiveHandler {
+	return &ProjectArchiveHandler{db: db, store: store, logger: logger}
+}
+
+// ExportProject handles GET /api/projects/{id}/archive
+// Returns the project's archive: its sources and their files, chunks,
+// graph, provenance and inconsistencies. See package archive.
+func (h *ProjectArchiveHandler) ExportProject(w http.ResponseWriter, r *http.Request) {
+	projectID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid project ID", http.StatusBadRequest)
+		return
+	}
+
+	// The archive is written to a temporary file first so that a failure
+	// is still an error response
+	tmp, err := os.CreateTemp("", "sikta-project-*"+archive.Extension)
+	if err != nil {
+		h.logger.Error("failed to create temporary file", "error", err)
+		http.Error(w, "Failed to export project", http.StatusInternalServerError)
+		return
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	m, err := archive.Export(r.Context(), h.db, h.store, projectID, tmp)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			http.Error(w, "Project not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("failed to export project", "error", err, "project", projectID)
+		http.Error(w, "Failed to export project", http.StatusInternalServerError)
+		return
+	}
+
+	h.logger.Info("project exported", "project", projectID, "sources", m.Counts.Sources, "nodes", m.Counts.Nodes, "files", m.Counts.Files)
+	w.Header().Set("Content-Type", archive.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", archiveFilename(m.Project.Title)+archive.Extension))
+	http.ServeContent(w, r, "", m.ExportedAt, tmp)
+}
+
+// ImportProject handles POST /api/projects/import
+// The "file" form field is an archive from ExportProject; it is restored as
+// a new project under new IDs, titled by the optional "title" field or as
+// the archived project was.
+func (h *ProjectArchiveHandler) ImportProject(w http.ResponseWriter, r *http.Request) {
+	allowSlowUpload(w)
+	r.Body = http.MaxBytesReader(w, r.Body, maxProjectArchiveSize)
+	if err := r.ParseMultipartForm(50 << 20); err != nil {
+		h.logger.Error("failed to parse multipart form", "error", err)
+		http.Error(w, "Failed to parse form", http.StatusBadRequest)
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "No file provided", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	result, err := archive.Import(r.Context(), h.db, h.store, file, header.Size, strings.TrimSpace(r.FormValue("title")))
+	if err != nil {
+		if errors.Is(err, archive.ErrInvalid) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		h.logger.Error("failed to import project", "error", err)
+		http.Error(w, "Failed to import project", http.StatusInternalServerError)
+		return
+	}
+
+	h.logger.Info("project imported", "project", result.ProjectID, "sources", result.Sources, "nodes", result.Nodes, "files", result.Files)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(result)
+}
+
+// archiveFilename turns a project title into a file name
+func archiveFilename(title string) string {
+	name := strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == '"' || r < ' ' {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(title))
+	if name == "" {
+		return "project"
+	}
+	return name
+}
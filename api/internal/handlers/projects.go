@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"net/http"
 
@@ -10,6 +11,7 @@ import (
 	"github.com/einarsundgren/sikta/internal/extraction/claude"
 	"github.com/einarsundgren/sikta/internal/graph"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
@@ -18,6 +20,7 @@ type ProjectHandler struct {
 	db            *database.Queries
 	logger        *slog.Logger
 	postProcessor *graph.PostProcessor
+	views         *graph.Views
 }
 
 // NewProjectHandler creates a new project handler
@@ -41,6 +44,7 @@ func NewProjectHandler(db *database.Queries, cfg *config.Config, logger *slog.Lo
 		db:            db,
 		logger:        logger,
 		postProcessor: postProcessor,
+		views:         graph.NewViews(db, logger),
 	}
 }
 
@@ -48,6 +52,7 @@ func NewProjectHandler(db *database.Queries, cfg *config.Config, logger *slog.Lo
 type CreateProjectRequest struct {
 	Title       string `json:"title"`
 	Description string `json:"description"`
+	AutoExtract bool   `json:"auto_extract"` // extract documents as soon as they are chunked
 }
 
 // ProjectResponse is the response for a single project
@@ -57,6 +62,7 @@ type ProjectResponse struct {
 	Description string            `json:"description"`
 	CreatedAt   string            `json:"created_at"`
 	UpdatedAt   string            `json:"updated_at"`
+	AutoExtract bool              `json:"auto_extract"`
 	Stats       *ProjectStatsDTO  `json:"stats,omitempty"`
 }
 
@@ -84,6 +90,7 @@ func (h *ProjectHandler) ListProjects(w http.ResponseWriter, r *http.Request) {
 			Description: p.Description.String,
 			CreatedAt:   p.CreatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
 			UpdatedAt:   p.UpdatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+			AutoExtract: p.AutoExtract,
 		}
 	}
 
@@ -120,6 +127,7 @@ func (h *ProjectHandler) GetProject(w http.ResponseWriter, r *http.Request) {
 		Description: project.Description.String,
 		CreatedAt:   project.CreatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
 		UpdatedAt:   project.UpdatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+		AutoExtract: project.AutoExtract,
 	}
 
 	if stats != nil {
@@ -155,6 +163,7 @@ func (h *ProjectHandler) CreateProject(w http.ResponseWriter, r *http.Request) {
 	project, err := h.db.CreateProject(r.Context(), database.CreateProjectParams{
 		Title:       req.Title,
 		Description: desc,
+		AutoExtract: req.AutoExtract,
 	})
 	if err != nil {
 		h.logger.Error("failed to create project", "error", err)
@@ -168,6 +177,7 @@ func (h *ProjectHandler) CreateProject(w http.ResponseWriter, r *http.Request) {
 		Description: project.Description.String,
 		CreatedAt:   project.CreatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
 		UpdatedAt:   project.UpdatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+		AutoExtract: project.AutoExtract,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -179,6 +189,7 @@ func (h *ProjectHandler) CreateProject(w http.ResponseWriter, r *http.Request) {
 type UpdateProjectRequest struct {
 	Title       string `json:"title"`
 	Description string `json:"description"`
+	AutoExtract *bool  `json:"auto_extract"` // unchanged if omitted
 }
 
 // UpdateProject handles PUT /api/projects/{id}
@@ -205,6 +216,7 @@ func (h *ProjectHandler) UpdateProject(w http.ResponseWriter, r *http.Request) {
 		ID:          strToPgUUID(id.String()),
 		Title:       req.Title,
 		Description: desc,
+		AutoExtract: database.PgBoolPtr(req.AutoExtract),
 	})
 	if err != nil {
 		h.logger.Error("failed to update project", "error", err, "id", idStr)
@@ -218,6 +230,7 @@ func (h *ProjectHandler) UpdateProject(w http.ResponseWriter, r *http.Request) {
 		Description: project.Description.String,
 		CreatedAt:   project.CreatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
 		UpdatedAt:   project.UpdatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+		AutoExtract: project.AutoExtract,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -307,6 +320,12 @@ func (h *ProjectHandler) AddDocumentToProject(w http.ResponseWriter, r *http.Req
 		ID:        strToPgUUID(docID.String()),
 		ProjectID: strToPgUUID(projectID.String()),
 	})
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		// Uploads are deduplicated per project
+		http.Error(w, "The project already has a document with the same content", http.StatusConflict)
+		return
+	}
 	if err != nil {
 		h.logger.Error("failed to add document to project", "error", err, "project", projectIDStr, "document", docID)
 		http.Error(w, "Failed to add document to project", http.StatusInternalServerError)
@@ -329,6 +348,11 @@ type NodeResponse struct {
 	Label      string                 `json:"label"`
 	Properties map[string]interface{} `json:"properties,omitempty"`
 	CreatedAt  string                 `json:"created_at"`
+
+	// Set in the canonical graph: the nodes this one stands for and the
+	// provenance of all of them
+	Members    []string `json:"members,omitempty"`
+	Provenance []string `json:"provenance,omitempty"`
 }
 
 // EdgeResponse represents an edge in the graph
@@ -339,10 +363,18 @@ type EdgeResponse struct {
 	TargetNode string                 `json:"target_node"`
 	Properties map[string]interface{} `json:"properties,omitempty"`
 	CreatedAt  string                 `json:"created_at"`
+
+	// Set in the canonical graph: the edges this one stands for and the
+	// provenance of all of them
+	Members    []string `json:"members,omitempty"`
+	Provenance []string `json:"provenance,omitempty"`
 }
 
 // GetProjectGraph handles GET /api/projects/{id}/graph
-// Returns merged nodes and edges from all documents in the project
+// Returns merged nodes and edges from all documents in the project.
+// canonical=true collapses each same_as identity cluster into its canonical
+// node (chosen by view_strategy, default trust_weighted) and rewires edges
+// to it.
 func (h *ProjectHandler) GetProjectGraph(w http.ResponseWriter, r *http.Request) {
 	idStr := r.PathValue("id")
 	projectID, err := uuid.Parse(idStr)
@@ -351,6 +383,11 @@ func (h *ProjectHandler) GetProjectGraph(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	if r.URL.Query().Get("canonical") == "true" {
+		h.getCanonicalProjectGraph(w, r, projectID)
+		return
+	}
+
 	// Get all sources for the project
 	sources, err := h.db.GetProjectSources(r.Context(), strToPgUUID(projectID.String()))
 	if err != nil {
@@ -411,6 +448,79 @@ func (h *ProjectHandler) GetProjectGraph(w http.ResponseWriter, r *http.Request)
 	json.NewEncoder(w).Encode(response)
 }
 
+// getCanonicalProjectGraph writes the project's graph with identity
+// clusters collapsed. The stored graph is not changed.
+func (h *ProjectHandler) getCanonicalProjectGraph(w http.ResponseWriter, r *http.Request, projectID uuid.UUID) {
+	strategy := graph.ViewStrategy(r.URL.Query().Get("view_strategy"))
+	if strategy == "" {
+		strategy = graph.ViewStrategyTrustWeighted
+	}
+
+	g, err := h.views.GetCanonicalGraph(r.Context(), projectID, strategy)
+	if err != nil {
+		h.logger.Error("failed to get canonical project graph", "error", err, "id", projectID)
+		http.Error(w, "Failed to get project graph", http.StatusInternalServerError)
+		return
+	}
+
+	response := GraphResponse{
+		Nodes: make([]NodeResponse, len(g.Nodes)),
+		Edges: make([]EdgeResponse, len(g.Edges)),
+	}
+	for i, cn := range g.Nodes {
+		n := cn.Node
+		props := make(map[string]interface{})
+		if len(n.Properties) > 0 {
+			_ = json.Unmarshal(n.Properties, &props)
+		}
+		response.Nodes[i] = NodeResponse{
+			ID:         pgUUIDToStr(n.ID),
+			NodeType:   n.NodeType,
+			Label:      n.Label,
+			Properties: props,
+			CreatedAt:  n.CreatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+			Members:    pgUUIDStrs(cn.Members),
+			Provenance: provenanceIDs(cn.Provenance),
+		}
+	}
+	for i, ce := range g.Edges {
+		e := ce.Edge
+		props := make(map[string]interface{})
+		if len(e.Properties) > 0 {
+			_ = json.Unmarshal(e.Properties, &props)
+		}
+		response.Edges[i] = EdgeResponse{
+			ID:         pgUUIDToStr(e.ID),
+			EdgeType:   e.EdgeType,
+			SourceNode: pgUUIDToStr(e.SourceNode),
+			TargetNode: pgUUIDToStr(e.TargetNode),
+			Properties: props,
+			CreatedAt:  e.CreatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+			Members:    pgUUIDStrs(ce.Members),
+			Provenance: provenanceIDs(ce.Provenance),
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func pgUUIDStrs(ids []pgtype.UUID) []string {
+	result := make([]string, len(ids))
+	for i, id := range ids {
+		result[i] = pgUUIDToStr(id)
+	}
+	return result
+}
+
+func provenanceIDs(provenance []*database.Provenance) []string {
+	result := make([]string, len(provenance))
+	for i, p := range provenance {
+		result[i] = pgUUIDToStr(p.ID)
+	}
+	return result
+}
+
 // DocumentResponse is the response for a document
 type DocumentResponse struct {
 	ID           string `json:"id"`
@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/einarsundgren/sikta/internal/database"
+	"github.com/einarsundgren/sikta/internal/services"
+)
+
+// Resumable uploads speak the core of the tus 1.0 protocol
+// (https://tus.io/protocols/resumable-upload) with the creation and
+// termination extensions, so standard tus clients can upload large files and
+// resume after a dropped connection:
+//
+//	POST   /api/uploads       Upload-Length, Upload-Metadata: filename <base64>[,mapping <base64>]
+//	HEAD   /api/uploads/{id}  reports Upload-Offset to resume from
+//	PATCH  /api/uploads/{id}  appends bytes at Upload-Offset
+//	DELETE /api/uploads/{id}  abandons the upload
+//
+// When the last byte arrives the file becomes a source, as with POST
+// /api/documents; its ID is returned in the Upload-Document-Id header and by
+// GET /api/uploads/{id}.
+
+const tusVersion = "1.0.0"
+
+// CreateUpload handles POST /api/uploads
+func (h *DocumentHandler) CreateUpload(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Tus-Resumable", tusVersion)
+
+	length, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil || length < 0 {
+		http.Error(w, "Invalid Upload-Length", http.StatusBadRequest)
+		return
+	}
+	if length > h.docService.MaxUploadSize() {
+		http.Error(w, "Upload too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	metadata, err := parseUploadMetadata(r.Header.Get("Upload-Metadata"))
+	if err != nil {
+		http.Error(w, "Invalid Upload-Metadata", http.StatusBadRequest)
+		return
+	}
+	filename := metadata["filename"]
+	if filename == "" {
+		http.Error(w, "Upload-Metadata must include filename", http.StatusBadRequest)
+		return
+	}
+	delete(metadata, "filename")
+
+	upload, err := h.docService.CreateResumableUpload(filename, length, metadata)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.logger.Info("resumable upload created", "id", upload.ID, "filename", filename, "length", length)
+
+	w.Header().Set("Location", "/api/uploads/"+upload.ID)
+	w.WriteHeader(http.StatusCreated)
+}
+
+// HeadUpload handles HEAD /api/uploads/{id}
+func (h *DocumentHandler) HeadUpload(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Tus-Resumable", tusVersion)
+	w.Header().Set("Cache-Control", "no-store")
+
+	upload, err := h.docService.ResumableUpload(r.PathValue("id"))
+	if err != nil {
+		h.uploadError(w, err)
+		return
+	}
+	setUploadHeaders(w, upload)
+	w.WriteHeader(http.StatusOK)
+}
+
+// GetUpload handles GET /api/uploads/{id}
+func (h *DocumentHandler) GetUpload(w http.ResponseWriter, r *http.Request) {
+	upload, err := h.docService.ResumableUpload(r.PathValue("id"))
+	if err != nil {
+		h.uploadError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(upload)
+}
+
+// PatchUpload handles PATCH /api/uploads/{id}
+func (h *DocumentHandler) PatchUpload(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Tus-Resumable", tusVersion)
+
+	if r.Header.Get("Content-Type") != "application/offset+octet-stream" {
+		http.Error(w, "Content-Type must be application/offset+octet-stream", http.StatusUnsupportedMediaType)
+		return
+	}
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		http.Error(w, "Invalid Upload-Offset", http.StatusBadRequest)
+		return
+	}
+
+	allowSlowUpload(w)
+	id := r.PathValue("id")
+	upload, err := h.docService.AppendResumableUpload(id, offset, r.Body)
+	if err != nil {
+		if upload != nil && !errors.Is(err, services.ErrUploadOffset) {
+			// The bytes that arrived are kept; the client resumes from here
+			h.logger.Warn("resumable upload interrupted", "id", id, "offset", upload.Offset, "error", err)
+		}
+		h.uploadError(w, err)
+		return
+	}
+
+	if upload.Complete() {
+		upload, err = h.docService.FinishResumableUpload(id, func(result *services.UploadResult) (string, error) {
+			return h.createResumableSource(result, upload.Metadata)
+		})
+		if err != nil {
+			h.logger.Error("resumable upload rejected", "id", id, "error", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		h.logger.Info("resumable upload complete", "id", id, "document", upload.DocumentID)
+	}
+
+	setUploadHeaders(w, upload)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DeleteUpload handles DELETE /api/uploads/{id}
+func (h *DocumentHandler) DeleteUpload(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Tus-Resumable", tusVersion)
+
+	if err := h.docService.CancelResumableUpload(r.PathValue("id")); err != nil {
+		h.uploadError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// createResumableSource creates the source for a finished resumable upload
+// and returns its ID. A "mapping" metadata value is the column mapping of a
+// tabular file.
+func (h *DocumentHandler) createResumableSource(result *services.UploadResult, metadata map[string]string) (string, error) {
+	mappings, err := uploadMappings(metadata["mapping"], result.FileType)
+	if err != nil {
+		return "", err
+	}
+	src, _, err := h.createSource(result, nil, mappingExtras(mappings))
+	if err != nil {
+		return "", err
+	}
+	return database.UUIDStr(src.ID), nil
+}
+
+func (h *DocumentHandler) uploadError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrUploadNotFound):
+		http.Error(w, "Upload not found", http.StatusNotFound)
+	case errors.Is(err, services.ErrUploadOffset):
+		http.Error(w, "Upload-Offset does not match", http.StatusConflict)
+	default:
+		h.logger.Error("resumable upload failed", "error", err)
+		http.Error(w, "Upload failed", http.StatusInternalServerError)
+	}
+}
+
+func setUploadHeaders(w http.ResponseWriter, upload *services.ResumableUpload) {
+	w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	w.Header().Set("Upload-Length", strconv.FormatInt(upload.Length, 10))
+	if upload.DocumentID != "" {
+		w.Header().Set("Upload-Document-Id", upload.DocumentID)
+	}
+}
+
+// parseUploadMetadata decodes a tus Upload-Metadata header: comma-separated
+// pairs of a key and a base64-encoded value.
+func parseUploadMetadata(header string) (map[string]string, error) {
+	metadata := make(map[string]string)
+	for _, pair := range strings.Split(header, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, encoded, _ := strings.Cut(pair, " ")
+		value, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, err
+		}
+		metadata[key] = string(value)
+	}
+	return metadata, nil
+}
@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/einarsundgren/sikta/internal/database/dbtest"
+)
+
+func TestParseUploadMetadata(t *testing.T) {
+	b64 := func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) }
+
+	tests := []struct {
+		name    string
+		header  string
+		want    map[string]string
+		wantErr bool
+	}{
+		{"empty", "", map[string]string{}, false},
+		{"one pair", "filename " + b64("protokoll.txt"), map[string]string{"filename": "protokoll.txt"}, false},
+		{
+			name:   "several pairs with spaces",
+			header: "filename " + b64("Möte 2024.csv") + " , mapping " + b64(`{"nodes":[]}`),
+			want:   map[string]string{"filename": "Möte 2024.csv", "mapping": `{"nodes":[]}`},
+		},
+		{"key without value", "is_confidential", map[string]string{"is_confidential": ""}, false},
+		{"empty pairs skipped", ",filename " + b64("a.txt") + ",,", map[string]string{"filename": "a.txt"}, false},
+		{"invalid base64", "filename not*base64", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseUploadMetadata(tt.header)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseUploadMetadata() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseUploadMetadata() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// tusRequest is a request to the upload API with the tus headers set.
+func tusRequest(method, id string, headers map[string]string, body string) *http.Request {
+	target := "/api/uploads"
+	if id != "" {
+		target += "/" + id
+	}
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	r.SetPathValue("id", id)
+	r.Header.Set("Tus-Resumable", tusVersion)
+	for k, v := range headers {
+		r.Header.Set(k, v)
+	}
+	return r
+}
+
+func patchUpload(h *DocumentHandler, id string, offset int, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.PatchUpload(w, tusRequest(http.MethodPatch, id, map[string]string{
+		"Content-Type":  "application/offset+octet-stream",
+		"Upload-Offset": strconv.Itoa(offset),
+	}, body))
+	return w
+}
+
+func TestResumableUpload(t *testing.T) {
+	const content = "namn,roll\nAnna,ordförande\nJonas,sekreterare\n"
+	const mapping = `{"nodes":[{"key":"p","column":"namn","node_type":"person"}]}`
+
+	h, db, _ := testHandler(t)
+	dbtest.ResumableUploads(db)
+	db.Return("CreateSource", testSource(2, content), nil)
+
+	w := httptest.NewRecorder()
+	h.CreateUpload(w, tusRequest(http.MethodPost, "", map[string]string{
+		"Upload-Length":   strconv.Itoa(len(content)),
+		"Upload-Metadata": "filename " + base64.StdEncoding.EncodeToString([]byte("styrelse.csv")) + ",mapping " + base64.StdEncoding.EncodeToString([]byte(mapping)),
+	}, ""))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("POST status = %d, want %d: %s", w.Code, http.StatusCreated, w.Body)
+	}
+	id := strings.TrimPrefix(w.Header().Get("Location"), "/api/uploads/")
+
+	if w := patchUpload(h, id, 0, content[:12]); w.Code != http.StatusNoContent || w.Header().Get("Upload-Offset") != "12" {
+		t.Fatalf("first PATCH = %d at offset %s, want %d at 12", w.Code, w.Header().Get("Upload-Offset"), http.StatusNoContent)
+	}
+	if w := patchUpload(h, id, 5, content[5:]); w.Code != http.StatusConflict {
+		t.Errorf("PATCH at the wrong offset = %d, want %d", w.Code, http.StatusConflict)
+	}
+
+	w = httptest.NewRecorder()
+	h.HeadUpload(w, tusRequest(http.MethodHead, id, nil, ""))
+	if got := w.Header().Get("Upload-Offset"); got != "12" {
+		t.Errorf("HEAD Upload-Offset = %s, want 12", got)
+	}
+
+	w = patchUpload(h, id, 12, content[12:])
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("last PATCH status = %d, want %d: %s", w.Code, http.StatusNoContent, w.Body)
+	}
+	if got, want := w.Header().Get("Upload-Document-Id"), "00000000-0000-0000-0000-000000000002"; got != want {
+		t.Errorf("Upload-Document-Id = %q, want %q", got, want)
+	}
+
+	// The mapping is written by the insert that queues the source
+	calls := db.Calls("CreateSource")
+	if len(calls) != 1 {
+		t.Fatalf("CreateSource called %d times, want 1", len(calls))
+	}
+	var meta struct {
+		TableMappings []json.RawMessage `json:"table_mappings"`
+	}
+	if err := json.Unmarshal(calls[0].Args[8].([]byte), &meta); err != nil || len(meta.TableMappings) != 1 {
+		t.Errorf("source metadata = %s, want the table mapping", calls[0].Args[8])
+	}
+	if n := len(db.Calls("UpdateSourceMetadata")); n != 0 {
+		t.Errorf("source metadata updated %d times after the insert, want 0", n)
+	}
+}
+
+func TestResumableUploadFinishRetried(t *testing.T) {
+	const content = "Mötet öppnades kl. 19."
+
+	h, db, _ := testHandler(t)
+	dbtest.ResumableUploads(db)
+	upload, err := h.docService.CreateResumableUpload("protokoll.txt", int64(len(content)), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every byte arrived, but the server went away before the source was
+	// created
+	if _, err := h.docService.AppendResumableUpload(upload.ID, 0, strings.NewReader(content)); err != nil {
+		t.Fatal(err)
+	}
+
+	db.Return("CreateSource", testSource(2, content), nil)
+	w := patchUpload(h, upload.ID, len(content), "")
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("empty PATCH status = %d, want %d: %s", w.Code, http.StatusNoContent, w.Body)
+	}
+	if w.Header().Get("Upload-Document-Id") == "" {
+		t.Error("empty PATCH at the end did not finish the upload")
+	}
+	if n := len(db.Calls("CreateSource")); n != 1 {
+		t.Errorf("CreateSource called %d times, want 1", n)
+	}
+
+	// A finished upload takes no more appends
+	w = patchUpload(h, upload.ID, len(content), "")
+	if w.Code != http.StatusConflict {
+		t.Errorf("PATCH after finish status = %d, want %d", w.Code, http.StatusConflict)
+	}
+}
+
+func TestResumableUploadErrors(t *testing.T) {
+	h, db, _ := testHandler(t)
+	dbtest.ResumableUploads(db)
+
+	tests := []struct {
+		name       string
+		do         func(w http.ResponseWriter)
+		wantStatus int
+	}{
+		{
+			name: "missing filename",
+			do: func(w http.ResponseWriter) {
+				h.CreateUpload(w, tusRequest(http.MethodPost, "", map[string]string{"Upload-Length": "10"}, ""))
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "too large",
+			do: func(w http.ResponseWriter) {
+				h.CreateUpload(w, tusRequest(http.MethodPost, "", map[string]string{
+					"Upload-Length":   strconv.FormatInt(h.docService.MaxUploadSize()+1, 10),
+					"Upload-Metadata": "filename " + base64.StdEncoding.EncodeToString([]byte("a.txt")),
+				}, ""))
+			},
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name: "unknown upload",
+			do: func(w http.ResponseWriter) {
+				h.HeadUpload(w, tusRequest(http.MethodHead, "5f0c2c55-9a2f-4d7e-8c3a-0b6f3f2a9e11", nil, ""))
+			},
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name: "wrong content type",
+			do: func(w http.ResponseWriter) {
+				h.PatchUpload(w, tusRequest(http.MethodPatch, "x", map[string]string{"Upload-Offset": "0"}, "x"))
+			},
+			wantStatus: http.StatusUnsupportedMediaType,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			tt.do(w)
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+		})
+	}
+}
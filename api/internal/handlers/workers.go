@@ -0,0 +1,301 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/einarsundgren/sikta/internal/database"
+	"github.com/einarsundgren/sikta/internal/document"
+	"github.com/einarsundgren/sikta/internal/services"
+)
+
+// SourceWorkers chunk uploaded sources and extract queued ones in the
+// background. Postgres announces every source status change on the
+// source_status channel; a new upload or queued extraction wakes a worker,
+// which claims the oldest such source under a row lock, so workers in other
+// server instances never get the same source. When the source's project has
+// auto-extract on, chunking queues an extraction.
+//
+// A worker refreshes its claim with a heartbeat while it works. A source
+// whose heartbeat is older than claimTimeout was left by a worker that died,
+// and is claimed again.
+type SourceWorkers struct {
+	pool       *pgxpool.Pool
+	docs       *DocumentHandler
+	extraction *ExtractionHandler
+	count      int
+	logger     *slog.Logger
+
+	wake chan struct{}
+}
+
+const (
+	// claimTimeout is how long a claim lasts without a heartbeat.
+	claimTimeout = 5 * time.Minute
+	// heartbeatInterval is how often a worker refreshes its claim.
+	heartbeatInterval = claimTimeout / 5
+)
+
+// NewSourceWorkers creates count workers processing sources with docs and
+// extracting them with extraction. pool provides the listening connection.
+func NewSourceWorkers(pool *pgxpool.Pool, docs *DocumentHandler, extraction *ExtractionHandler, count int, logger *slog.Logger) *SourceWorkers {
+	if count < 1 {
+		count = 1
+	}
+	return &SourceWorkers{
+		pool:       pool,
+		docs:       docs,
+		extraction: extraction,
+		count:      count,
+		logger:     logger,
+		wake:       make(chan struct{}, count),
+	}
+}
+
+// Run starts the workers and blocks until stopCh is closed. It wakes the
+// workers every heartbeat interval to pick up stale claims, and expires
+// abandoned resumable uploads every hour.
+func (w *SourceWorkers) Run(stopCh <-chan struct{}) {
+	w.logger.Info("starting document workers", "count", w.count)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for i := 0; i < w.count; i++ {
+		go w.work(ctx)
+	}
+	go w.listen(ctx)
+
+	expiry := time.NewTicker(time.Hour)
+	defer expiry.Stop()
+	// Stale claims are not announced, so they are looked for
+	reclaim := time.NewTicker(heartbeatInterval)
+	defer reclaim.Stop()
+
+	for {
+		select {
+		case <-stopCh:
+			w.logger.Info("stopping document workers")
+			return
+		case <-reclaim.C:
+			w.wakeAll()
+		case <-expiry.C:
+			if n := w.docs.docService.ExpireResumableUploads(services.ResumableUploadExpiry); n > 0 {
+				w.logger.Info("expired resumable uploads", "count", n)
+			}
+		}
+	}
+}
+
+// work processes sources and extractions until none are left, then waits
+// to be woken. Chunking goes first, since it is quick and extraction needs
+// it done.
+func (w *SourceWorkers) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-w.wake:
+		}
+
+		for ctx.Err() == nil {
+			if !w.processNext(ctx) && !w.extractNext(ctx) {
+				break
+			}
+		}
+	}
+}
+
+// processNext claims and chunks an uploaded source. It reports whether it
+// claimed one.
+func (w *SourceWorkers) processNext(ctx context.Context) bool {
+	src, err := w.docs.db.ClaimUploadedSource(ctx, staleBefore())
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false
+	}
+	if err != nil {
+		if ctx.Err() == nil {
+			w.logger.Error("failed to claim source", "error", err)
+		}
+		return false
+	}
+	defer w.heartbeat(ctx, src.ID)()
+
+	// A reclaimed source may have chunks from the worker that died. If they
+	// cannot be cleared the source fails, as it would had processing failed,
+	// rather than staying 'processing' until its claim goes stale.
+	if err := w.docs.db.DeleteChunksBySource(ctx, src.ID); err != nil {
+		w.logger.Error("failed to clear chunks", "id", src.ID, "error", err)
+		errMsg := fmt.Sprintf("failed to clear chunks: %v", err)
+		if _, err := w.docs.repo.UpdateSourceStatus(uuid.UUID(src.ID.Bytes), "error", &errMsg); err != nil {
+			w.logger.Error("failed to update source status", "id", src.ID, "error", err)
+		}
+		return true
+	}
+	if w.docs.processSource(src) {
+		w.autoExtract(ctx, src)
+	}
+	return true
+}
+
+// extractNext claims and extracts a source queued for extraction. It
+// reports whether it claimed one.
+func (w *SourceWorkers) extractNext(ctx context.Context) bool {
+	if w.extraction == nil {
+		return false
+	}
+	src, err := w.docs.db.ClaimQueuedExtraction(ctx, staleBefore())
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false
+	}
+	if err != nil {
+		if ctx.Err() == nil {
+			w.logger.Error("failed to claim extraction", "error", err)
+		}
+		return false
+	}
+	stop := w.heartbeat(ctx, src.ID)
+
+	// A new version only has its changed chunks extracted
+	w.extraction.runExtraction(ctx, database.UUIDStr(src.ID), src.PreviousVersionID.Valid)
+
+	stop()
+	if err := w.docs.db.FinishSourceExtraction(context.Background(), src.ID); err != nil {
+		w.logger.Error("failed to finish extraction", "id", src.ID, "error", err)
+	}
+	return true
+}
+
+// autoExtract queues the extraction of a source that has just been chunked
+// if its project has auto-extract on.
+func (w *SourceWorkers) autoExtract(ctx context.Context, src *database.Source) {
+	if w.extraction == nil || document.IsTabularType(src.FileType) {
+		return
+	}
+	// The project may have been set after the source was claimed, as in a
+	// bulk upload
+	src, err := w.docs.db.GetSource(ctx, src.ID)
+	if err != nil || !src.ProjectID.Valid {
+		return
+	}
+	project, err := w.docs.db.GetProject(ctx, src.ProjectID)
+	if err != nil {
+		w.logger.Error("failed to get project", "id", src.ProjectID, "error", err)
+		return
+	}
+	if !project.AutoExtract {
+		return
+	}
+
+	if err := w.docs.db.QueueSourceExtraction(ctx, src.ID); err != nil {
+		w.logger.Error("failed to queue extraction", "id", src.ID, "error", err)
+		return
+	}
+	w.logger.Info("queued auto-extraction", "id", src.ID, "project", project.Title)
+}
+
+// heartbeat refreshes the claim on a source until the returned func is
+// called.
+func (w *SourceWorkers) heartbeat(ctx context.Context, id pgtype.UUID) func() {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(heartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := w.docs.db.TouchSourceClaim(ctx, id); err != nil && ctx.Err() == nil {
+					w.logger.Warn("failed to refresh source claim", "id", id, "error", err)
+				}
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// staleBefore returns the time before which a claim's last heartbeat makes
+// it stale.
+func staleBefore() pgtype.Timestamptz {
+	return database.PgTime(time.Now().Add(-claimTimeout))
+}
+
+// listen wakes workers when sources are uploaded, reconnecting if the
+// connection drops.
+func (w *SourceWorkers) listen(ctx context.Context) {
+	for {
+		err := w.listenOnce(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		w.logger.Warn("source listener disconnected", "error", err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(5 * time.Second):
+		}
+	}
+}
+
+func (w *SourceWorkers) listenOnce(ctx context.Context) error {
+	pooled, err := w.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	// The connection stays subscribed, so it is taken out of the pool
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	if err := database.New(conn).ListenSourceStatus(ctx); err != nil {
+		return err
+	}
+	// Catch up on work queued while nobody was listening
+	w.wakeAll()
+
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		var event struct {
+			Status           string `json:"status"`
+			ExtractionStatus string `json:"extraction_status"`
+		}
+		if json.Unmarshal([]byte(n.Payload), &event) != nil {
+			continue
+		}
+		if event.Status == "uploaded" || event.ExtractionStatus == "queued" {
+			w.wakeOne()
+		}
+	}
+}
+
+func (w *SourceWorkers) wakeOne() {
+	select {
+	case w.wake <- struct{}{}:
+	default:
+		// Every worker already has a wake-up pending
+	}
+}
+
+func (w *SourceWorkers) wakeAll() {
+	for i := 0; i < w.count; i++ {
+		w.wakeOne()
+	}
+}
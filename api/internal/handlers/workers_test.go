@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/einarsundgren/sikta/internal/database"
+)
+
+func TestProcessNextClearChunksFails(t *testing.T) {
+	h, db, _ := testHandler(t)
+	src := testSource(1, "Mötet öppnades.")
+	src.UploadStatus = "processing"
+	db.Return("ClaimUploadedSource", src, nil)
+	db.Return("DeleteChunksBySource", nil, errors.New("connection reset"))
+
+	w := &SourceWorkers{docs: h, logger: h.logger}
+	if !w.processNext(context.Background()) {
+		t.Fatal("processNext() = false, want true for a claimed source")
+	}
+
+	// The source is not left 'processing' with a live claim
+	calls := db.Calls("UpdateSourceStatus")
+	if len(calls) != 1 {
+		t.Fatalf("UpdateSourceStatus called %d times, want 1", len(calls))
+	}
+	if status := calls[0].Args[1]; status != "error" {
+		t.Errorf("status = %v, want error", status)
+	}
+	if msg := calls[0].Args[2].(pgtype.Text); !msg.Valid || msg.String == "" {
+		t.Errorf("error message = %v, want the failure", msg)
+	}
+	if n := len(db.Calls("CreateChunk")); n != 0 {
+		t.Errorf("%d chunks created, want 0", n)
+	}
+}
+
+// testPool connects to the database named by SIKTA_TEST_DATABASE_URL, or
+// skips the test if it is not set. The database must be migrated and
+// disposable: claims take any waiting source, not just the test's own.
+func testPool(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+	url := os.Getenv("SIKTA_TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("SIKTA_TEST_DATABASE_URL not set")
+	}
+	pool, err := pgxpool.New(context.Background(), url)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	t.Cleanup(pool.Close)
+	return pool
+}
+
+// createTestSource inserts a source with the given status, deleted again
+// when the test ends.
+func createTestSource(t *testing.T, pool *pgxpool.Pool, status string) pgtype.UUID {
+	t.Helper()
+	ctx := context.Background()
+	src, err := database.New(pool).CreateSource(ctx, database.CreateSourceParams{
+		Title:        "claim test",
+		Filename:     "claim.txt",
+		FilePath:     "uploads/claim.txt",
+		FileType:     "txt",
+		UploadStatus: status,
+	})
+	if err != nil {
+		t.Fatalf("CreateSource() error = %v", err)
+	}
+	t.Cleanup(func() { database.New(pool).DeleteSource(context.Background(), src.ID) })
+	return src.ID
+}
+
+// claimAll claims uploaded sources until none are left and returns the
+// IDs claimed.
+func claimAll(t *testing.T, q *database.Queries) []pgtype.UUID {
+	var claimed []pgtype.UUID
+	for {
+		src, err := q.ClaimUploadedSource(context.Background(), staleBefore())
+		if errors.Is(err, pgx.ErrNoRows) {
+			return claimed
+		}
+		if err != nil {
+			t.Errorf("ClaimUploadedSource() error = %v", err)
+			return claimed
+		}
+		claimed = append(claimed, src.ID)
+	}
+}
+
+func TestClaimUploadedSourceOnce(t *testing.T) {
+	pool := testPool(t)
+
+	const sources, workers = 40, 8
+	mine := make(map[pgtype.UUID]bool)
+	for i := 0; i < sources; i++ {
+		mine[createTestSource(t, pool, "uploaded")] = true
+	}
+
+	var mu sync.Mutex
+	claims := make(map[pgtype.UUID]int)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, id := range claimAll(t, database.New(pool)) {
+				mu.Lock()
+				claims[id]++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	for id := range mine {
+		if claims[id] != 1 {
+			t.Errorf("source %s claimed %d times, want 1", database.UUIDStr(id), claims[id])
+		}
+	}
+}
+
+func TestClaimUploadedSourceStale(t *testing.T) {
+	pool := testPool(t)
+	ctx := context.Background()
+
+	stale := createTestSource(t, pool, "processing")
+	live := createTestSource(t, pool, "processing")
+	if _, err := pool.Exec(ctx, `UPDATE sources SET claimed_at = $2 WHERE id = $1`, stale, time.Now().Add(-2*claimTimeout)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pool.Exec(ctx, `UPDATE sources SET claimed_at = NOW() WHERE id = $1`, live); err != nil {
+		t.Fatal(err)
+	}
+
+	claimed := make(map[pgtype.UUID]bool)
+	for _, id := range claimAll(t, database.New(pool)) {
+		claimed[id] = true
+	}
+	if !claimed[stale] {
+		t.Error("source with a stale claim was not claimed again")
+	}
+	if claimed[live] {
+		t.Error("source with a live claim was claimed")
+	}
+
+	// Reclaiming renews the claim, so the source is not claimed a third time
+	var claimedAt time.Time
+	if err := pool.QueryRow(ctx, `SELECT claimed_at FROM sources WHERE id = $1`, stale).Scan(&claimedAt); err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(claimedAt) > time.Minute {
+		t.Errorf("claimed_at = %v after reclaim, want now", claimedAt)
+	}
+}
@@ -0,0 +1,138 @@
+package query
+
+// Query is a parsed graph query:
+//
+//	MATCH pattern [, pattern ...]
+//	[WHERE condition]
+//	RETURN [DISTINCT] item [AS alias] [, ...]
+//	[ORDER BY item [ASC|DESC] [, ...]]
+//	[SKIP n] [LIMIT n]
+type Query struct {
+	Patterns []Pattern
+	Where    Expr // nil if there is no WHERE clause
+	Distinct bool
+	Return   []ReturnItem
+	OrderBy  []OrderItem
+	Skip     int
+	Limit    int // 0 if there is no LIMIT clause
+}
+
+// Pattern is a chain of nodes joined by relationships: Rels[i] joins
+// Nodes[i] and Nodes[i+1].
+type Pattern struct {
+	Nodes []NodePattern
+	Rels  []RelPattern
+}
+
+// NodePattern matches a node: (var:type|type {key: value})
+type NodePattern struct {
+	Var   string // "" if anonymous
+	Types []string
+	Props []PropMatch
+	Pos   int
+}
+
+// Direction is the direction of a relationship pattern
+type Direction int
+
+const (
+	DirectionRight Direction = iota // (a)-[]->(b)
+	DirectionLeft                   // (a)<-[]-(b)
+	DirectionAny                    // (a)-[]-(b)
+)
+
+// RelPattern matches an edge: -[var:type|type {key: value}]->
+type RelPattern struct {
+	Var       string // "" if anonymous
+	Types     []string
+	Props     []PropMatch
+	Direction Direction
+	Pos       int
+}
+
+// PropMatch is a key: value pair in a pattern, shorthand for an equality
+// in WHERE
+type PropMatch struct {
+	Key   string
+	Value Expr // *Literal or *Param
+	Pos   int
+}
+
+// ReturnItem is one column of the result
+type ReturnItem struct {
+	Expr  Expr // *Field or *Count
+	Alias string
+}
+
+// OrderItem is one ORDER BY term
+type OrderItem struct {
+	Expr Expr
+	Desc bool
+}
+
+// Expr is a node of a WHERE condition or a RETURN item
+type Expr interface {
+	expr()
+}
+
+// Logical is AND or OR
+type Logical struct {
+	Op          string // "AND" or "OR"
+	Left, Right Expr
+}
+
+// Not negates a condition
+type Not struct {
+	X Expr
+}
+
+// Comparison compares two operands. Op is one of =, <>, <, <=, >, >=, =~,
+// IN, CONTAINS, STARTS WITH and ENDS WITH.
+type Comparison struct {
+	Op          string
+	Left, Right Expr
+	Pos         int
+}
+
+// IsNull tests an operand for null
+type IsNull struct {
+	X   Expr
+	Not bool
+	Pos int
+}
+
+// Field is a variable (Name "") or a field of one: var.name, or
+// var.properties.name for a property that shares a field's name.
+type Field struct {
+	Var      string
+	Name     string
+	Property bool // an explicit var.properties.name
+	Pos      int
+}
+
+// Literal is a string, float64, bool, nil or []any value
+type Literal struct {
+	Value any
+	Pos   int
+}
+
+// Param is a $name placeholder for a value passed with the query
+type Param struct {
+	Name string
+	Pos  int
+}
+
+// Count is count(var) or count(*) (Var "")
+type Count struct {
+	Var string
+	Pos int
+}
+
+func (*Logical) expr()    {}
+func (*Not) expr()        {}
+func (*Comparison) expr() {}
+func (*IsNull) expr()     {}
+func (*Field) expr()      {}
+func (*Literal) expr()    {}
+func (*Param) expr()      {}
+func (*Count) expr()      {}
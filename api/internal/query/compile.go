@@ -0,0 +1,722 @@
+package query
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Limits on a compiled query
+const (
+	DefaultLimit = 100
+	MaxLimit     = 1000
+	MaxVariables = 12 // nodes and relationships, named or not
+)
+
+// Compiled is a query compiled to SQL. The query returns one JSON object per
+// row, keyed by the names in Columns.
+type Compiled struct {
+	SQL     string
+	Args    []any
+	Columns []string
+}
+
+type kind int
+
+const (
+	kindText kind = iota
+	kindNumber
+	kindTime
+	kindBool
+	kindUUID
+	kindJSON
+)
+
+func (k kind) String() string {
+	return [...]string{"text", "a number", "a time", "a boolean", "an ID", "a property"}[k]
+}
+
+// Fields of nodes and edges that are columns; any other name is a property
+var (
+	nodeColumns = map[string]column{
+		"id":    {"id", kindUUID},
+		"label": {"label", kindText},
+		"type":  {"node_type", kindText},
+	}
+	edgeColumns = map[string]column{
+		"id":      {"id", kindUUID},
+		"type":    {"edge_type", kindText},
+		"source":  {"source_node", kindUUID},
+		"target":  {"target_node", kindUUID},
+		"negated": {"is_negated", kindBool},
+	}
+	// Fields of the provenance behind a node or edge. Excerpts are left out:
+	// they may be encrypted at rest.
+	provenanceColumns = map[string]column{
+		"modality":           {"modality", kindText},
+		"status":             {"status", kindText},
+		"confidence":         {"confidence", kindNumber},
+		"trust":              {"trust", kindNumber},
+		"claimed_time":       {"claimed_time_start", kindTime},
+		"claimed_time_start": {"claimed_time_start", kindTime},
+		"claimed_time_end":   {"claimed_time_end", kindTime},
+		"claimed_time_text":  {"claimed_time_text", kindText},
+		"claimed_geo_region": {"claimed_geo_region", kindText},
+		"claimed_geo_text":   {"claimed_geo_text", kindText},
+	}
+)
+
+type column struct {
+	name string
+	kind kind
+}
+
+// variable is a node or relationship in the MATCH patterns
+type variable struct {
+	alias         string // SQL table alias
+	edge          bool
+	negatedTested bool // the query mentions its negated field
+}
+
+// operand is a compiled field or value
+type operand struct {
+	sql  string
+	text string // the value as text, for string operators; "" if not text
+	kind kind
+	// For provenance fields: the variable whose provenance it is, and sql
+	// refers to provenance row p
+	prov *variable
+	// For values: the value, converted to SQL once the other side's kind
+	// is known
+	value    any
+	isValue  bool
+	valuePos int
+}
+
+type compiler struct {
+	params   map[string]any
+	args     []any
+	vars     map[string]*variable
+	varOrder []*variable
+	conds    []string
+	anon     int
+}
+
+// Compile compiles a query to SQL over the graph of one project. Only nodes
+// and edges with provenance from the project's documents are matched, and
+// rejected provenance is ignored. params holds the values of $name
+// placeholders.
+//
+// A field of a node or edge is one of its columns (id, label and type;
+// type, source, target and negated for edges), a field of its provenance
+// (modality, status, confidence, trust, claimed_time and the other claimed_*
+// fields) or else a property. A condition on a provenance field holds if
+// any of the element's provenance records satisfies it; a returned
+// provenance field comes from the record with the highest trust times
+// confidence. Negated edges are matched only if the query tests the
+// relationship's negated field.
+func Compile(q *Query, projectID uuid.UUID, params map[string]any) (*Compiled, error) {
+	c := &compiler{params: params, vars: make(map[string]*variable)}
+	c.arg(pgtype.UUID{Bytes: projectID, Valid: true}) // $1
+
+	for _, pattern := range q.Patterns {
+		if err := c.pattern(pattern); err != nil {
+			return nil, err
+		}
+	}
+	if len(c.varOrder) > MaxVariables {
+		return nil, errorf(-1, "a query may match at most %d nodes and relationships", MaxVariables)
+	}
+	if q.Where != nil {
+		where, err := c.condition(q.Where)
+		if err != nil {
+			return nil, err
+		}
+		c.conds = append(c.conds, where)
+	}
+
+	// Scope every element to the project, keep relationships distinct and
+	// skip negated edges unless asked about
+	var from []string
+	var edges []*variable
+	for _, v := range c.varOrder {
+		table, targetType := "nodes", "node"
+		if v.edge {
+			table, targetType = "edges", "edge"
+		}
+		from = append(from, table+" "+v.alias)
+		c.conds = append(c.conds, fmt.Sprintf("EXISTS (SELECT 1 FROM provenance p WHERE %s AND p.status <> 'rejected')", provenanceScope(targetType, v.alias)))
+		if v.edge {
+			for _, other := range edges {
+				c.conds = append(c.conds, fmt.Sprintf("%s.id <> %s.id", v.alias, other.alias))
+			}
+			edges = append(edges, v)
+			if !v.negatedTested {
+				c.conds = append(c.conds, "NOT "+v.alias+".is_negated")
+			}
+		}
+	}
+
+	// RETURN
+	var columns, selects, groupBy []string
+	aggregate := false
+	seen := make(map[string]bool)
+	for i, item := range q.Return {
+		if seen[item.Alias] {
+			return nil, errorf(-1, "column %s is returned twice; name one with AS", item.Alias)
+		}
+		seen[item.Alias] = true
+		columns = append(columns, item.Alias)
+
+		var sql string
+		if count, ok := item.Expr.(*Count); ok {
+			aggregate = true
+			sql = "count(*)"
+			if count.Var != "" {
+				v, ok := c.vars[count.Var]
+				if !ok {
+					return nil, errorf(count.Pos, "unknown variable %s", count.Var)
+				}
+				sql = "count(" + v.alias + ".id)"
+			}
+		} else {
+			var err error
+			if sql, err = c.returnField(item.Expr.(*Field)); err != nil {
+				return nil, err
+			}
+			groupBy = append(groupBy, strconv.Itoa(i+1))
+		}
+		selects = append(selects, fmt.Sprintf("%s AS c%d", sql, i))
+	}
+
+	// ORDER BY a returned column, or by any field if rows are neither
+	// grouped nor distinct
+	var orderBy []string
+	for i, item := range q.OrderBy {
+		col := -1
+		for j, ret := range q.Return {
+			if f, ok := item.Expr.(*Field); ok && f.Name == "" && f.Var == ret.Alias || exprString(item.Expr) == exprString(ret.Expr) {
+				col = j
+				break
+			}
+		}
+		var sql string
+		if col >= 0 {
+			sql = fmt.Sprintf("q.c%d", col)
+		} else {
+			f, ok := item.Expr.(*Field)
+			if !ok || aggregate || q.Distinct {
+				return nil, errorf(-1, "ORDER BY %s must name a returned column", exprString(item.Expr))
+			}
+			fieldSQL, err := c.returnField(f)
+			if err != nil {
+				return nil, err
+			}
+			selects = append(selects, fmt.Sprintf("%s AS o%d", fieldSQL, i))
+			sql = fmt.Sprintf("q.o%d", i)
+		}
+		if item.Desc {
+			sql += " DESC"
+		}
+		orderBy = append(orderBy, sql)
+	}
+
+	limit := q.Limit
+	if limit == 0 {
+		limit = DefaultLimit
+	}
+	if limit > MaxLimit {
+		return nil, errorf(-1, "LIMIT is at most %d", MaxLimit)
+	}
+
+	var b strings.Builder
+	b.WriteString("WITH project_documents AS (\n")
+	b.WriteString("    SELECT n.id FROM nodes n\n")
+	b.WriteString("    JOIN sources s ON n.properties->>'source_id' = s.id::text\n")
+	b.WriteString("    WHERE n.node_type = 'document' AND s.project_id = $1\n")
+	b.WriteString(")\n")
+	b.WriteString("SELECT jsonb_build_object(")
+	for i, col := range columns {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		fmt.Fprintf(&b, "%s::text, q.c%d", c.arg(col), i)
+	}
+	b.WriteString(")\nFROM (\n    SELECT ")
+	if q.Distinct {
+		b.WriteString("DISTINCT ")
+	}
+	b.WriteString(strings.Join(selects, ",\n        "))
+	b.WriteString("\n    FROM " + strings.Join(from, ", "))
+	b.WriteString("\n    WHERE " + strings.Join(c.conds, "\n      AND "))
+	if aggregate && len(groupBy) > 0 {
+		b.WriteString("\n    GROUP BY " + strings.Join(groupBy, ", "))
+	}
+	b.WriteString("\n) q")
+	if len(orderBy) > 0 {
+		b.WriteString("\nORDER BY " + strings.Join(orderBy, ", "))
+	}
+	fmt.Fprintf(&b, "\nOFFSET %s LIMIT %s", c.arg(q.Skip), c.arg(limit))
+
+	return &Compiled{SQL: b.String(), Args: c.args, Columns: columns}, nil
+}
+
+// arg adds a query argument and returns its placeholder
+func (c *compiler) arg(v any) string {
+	c.args = append(c.args, v)
+	return "$" + strconv.Itoa(len(c.args))
+}
+
+// declare returns the variable of a pattern element, creating it on first
+// use
+func (c *compiler) declare(name string, edge bool, pos int) (*variable, string, error) {
+	if name == "" {
+		// Anonymous elements get a name no query can spell
+		name = fmt.Sprintf(" anon%d", c.anon)
+		c.anon++
+	}
+	if v, ok := c.vars[name]; ok {
+		if v.edge || edge {
+			return nil, "", errorf(pos, "variable %s is used twice", name)
+		}
+		return v, name, nil
+	}
+	v := &variable{edge: edge}
+	if edge {
+		v.alias = fmt.Sprintf("e%d", len(c.varOrder))
+	} else {
+		v.alias = fmt.Sprintf("n%d", len(c.varOrder))
+	}
+	c.vars[name] = v
+	c.varOrder = append(c.varOrder, v)
+	return v, name, nil
+}
+
+func (c *compiler) pattern(pattern Pattern) error {
+	var prev *variable
+	for i, node := range pattern.Nodes {
+		v, name, err := c.declare(node.Var, false, node.Pos)
+		if err != nil {
+			return err
+		}
+		if len(node.Types) > 0 {
+			c.conds = append(c.conds, c.typeCondition(v.alias+".node_type", node.Types))
+		}
+		if err := c.props(name, node.Props); err != nil {
+			return err
+		}
+
+		if i > 0 {
+			rel := pattern.Rels[i-1]
+			e, name, err := c.declare(rel.Var, true, rel.Pos)
+			if err != nil {
+				return err
+			}
+			if len(rel.Types) > 0 {
+				c.conds = append(c.conds, c.typeCondition(e.alias+".edge_type", rel.Types))
+			}
+			if err := c.props(name, rel.Props); err != nil {
+				return err
+			}
+			forward := fmt.Sprintf("%s.source_node = %s.id AND %s.target_node = %s.id", e.alias, prev.alias, e.alias, v.alias)
+			backward := fmt.Sprintf("%s.source_node = %s.id AND %s.target_node = %s.id", e.alias, v.alias, e.alias, prev.alias)
+			switch rel.Direction {
+			case DirectionRight:
+				c.conds = append(c.conds, forward)
+			case DirectionLeft:
+				c.conds = append(c.conds, backward)
+			default:
+				c.conds = append(c.conds, "(("+forward+") OR ("+backward+"))")
+			}
+		}
+		prev = v
+	}
+	return nil
+}
+
+func (c *compiler) typeCondition(col string, types []string) string {
+	if len(types) == 1 {
+		return col + " = " + c.arg(types[0]) + "::text"
+	}
+	return col + " = ANY(" + c.arg(types) + "::text[])"
+}
+
+// props compiles {key: value} in a pattern as equalities
+func (c *compiler) props(name string, props []PropMatch) error {
+	for _, prop := range props {
+		cond, err := c.condition(&Comparison{
+			Op:    "=",
+			Left:  &Field{Var: name, Name: prop.Key, Pos: prop.Pos},
+			Right: prop.Value,
+			Pos:   prop.Pos,
+		})
+		if err != nil {
+			return err
+		}
+		c.conds = append(c.conds, cond)
+	}
+	return nil
+}
+
+// condition compiles a WHERE condition
+func (c *compiler) condition(e Expr) (string, error) {
+	switch e := e.(type) {
+	case *Logical:
+		left, err := c.condition(e.Left)
+		if err != nil {
+			return "", err
+		}
+		right, err := c.condition(e.Right)
+		if err != nil {
+			return "", err
+		}
+		return "(" + left + " " + e.Op + " " + right + ")", nil
+
+	case *Not:
+		x, err := c.condition(e.X)
+		if err != nil {
+			return "", err
+		}
+		return "NOT (" + x + ")", nil
+
+	case *IsNull:
+		x, err := c.operand(e.X)
+		if err != nil {
+			return "", err
+		}
+		if x.isValue {
+			return "", errorf(e.Pos, "IS NULL tests a field")
+		}
+		cond := x.sql + " IS NULL"
+		if e.Not {
+			cond = x.sql + " IS NOT NULL"
+		}
+		return c.withProvenance(cond, x), nil
+
+	case *Comparison:
+		return c.comparison(e)
+	}
+	return "", errorf(-1, "expected a condition")
+}
+
+func (c *compiler) comparison(e *Comparison) (string, error) {
+	left, err := c.operand(e.Left)
+	if err != nil {
+		return "", err
+	}
+	right, err := c.operand(e.Right)
+	if err != nil {
+		return "", err
+	}
+	if left.isValue && right.isValue {
+		return "", errorf(e.Pos, "a comparison needs at least one field")
+	}
+	if left.prov != nil && right.prov != nil {
+		return "", errorf(e.Pos, "compare one provenance field at a time")
+	}
+	if left.isValue {
+		switch e.Op {
+		case "=", "<>", "<", "<=", ">", ">=":
+			left, right = right, left
+			e = &Comparison{Op: flip(e.Op), Left: e.Right, Right: e.Left, Pos: e.Pos}
+		default:
+			return "", errorf(e.Pos, "%s needs a field on its left", e.Op)
+		}
+	}
+	prov := left
+	if right.prov != nil {
+		prov = right
+	}
+
+	var cond string
+	switch e.Op {
+	case "IN":
+		if !right.isValue {
+			return "", errorf(e.Pos, "IN takes a list")
+		}
+		list, ok := right.value.([]any)
+		if !ok {
+			return "", errorf(right.valuePos, "IN takes a list")
+		}
+		if len(list) == 0 {
+			return "FALSE", nil
+		}
+		var alternatives []string
+		for _, item := range list {
+			v, err := c.value(left.kind, item, right.valuePos)
+			if err != nil {
+				return "", err
+			}
+			alternatives = append(alternatives, left.sql+" = "+v)
+		}
+		cond = "(" + strings.Join(alternatives, " OR ") + ")"
+
+	case "CONTAINS", "STARTS WITH", "ENDS WITH", "=~":
+		if left.text == "" {
+			return "", errorf(e.Pos, "%s needs text, and %s is %s", e.Op, exprString(e.Left), left.kind)
+		}
+		var pattern string
+		if right.isValue {
+			if pattern, err = c.value(kindText, right.value, right.valuePos); err != nil {
+				return "", err
+			}
+		} else if pattern = right.text; pattern == "" {
+			return "", errorf(e.Pos, "%s needs text on both sides", e.Op)
+		}
+		switch e.Op {
+		case "CONTAINS":
+			cond = "strpos(" + left.text + ", " + pattern + ") > 0"
+		case "STARTS WITH":
+			cond = "starts_with(" + left.text + ", " + pattern + ")"
+		case "ENDS WITH":
+			cond = "right(" + left.text + ", length(" + pattern + ")) = " + pattern
+		default:
+			cond = left.text + " ~ " + pattern
+		}
+
+	default:
+		var r string
+		if right.isValue {
+			if right.value == nil {
+				return "", errorf(right.valuePos, "use IS NULL or IS NOT NULL to test for null")
+			}
+			if r, err = c.value(left.kind, right.value, right.valuePos); err != nil {
+				return "", err
+			}
+		} else {
+			if left.kind != right.kind {
+				return "", errorf(e.Pos, "cannot compare %s with %s", left.kind, right.kind)
+			}
+			r = right.sql
+		}
+		if left.kind == kindBool && e.Op != "=" && e.Op != "<>" {
+			return "", errorf(e.Pos, "booleans compare only with = and <>")
+		}
+		cond = left.sql + " " + e.Op + " " + r
+	}
+	return c.withProvenance(cond, prov), nil
+}
+
+// flip mirrors a comparison operator for swapped operands
+func flip(op string) string {
+	switch op {
+	case "<":
+		return ">"
+	case "<=":
+		return ">="
+	case ">":
+		return "<"
+	case ">=":
+		return "<="
+	}
+	return op
+}
+
+// withProvenance wraps a condition on a provenance field in a test for a
+// provenance record of its element that satisfies it
+func (c *compiler) withProvenance(cond string, x operand) string {
+	if x.prov == nil {
+		return cond
+	}
+	targetType := "node"
+	if x.prov.edge {
+		targetType = "edge"
+	}
+	scope := provenanceScope(targetType, x.prov.alias)
+	// Conditions on status itself see rejected records too
+	if x.sql != "p.status" {
+		scope += " AND p.status <> 'rejected'"
+	}
+	return "EXISTS (SELECT 1 FROM provenance p WHERE " + scope + " AND " + cond + ")"
+}
+
+func provenanceScope(targetType, alias string) string {
+	return fmt.Sprintf("p.target_type = '%s' AND p.target_id = %s.id AND p.source_id IN (SELECT id FROM project_documents)", targetType, alias)
+}
+
+// operand compiles a field, or holds a value until the other side of its
+// comparison gives it a kind
+func (c *compiler) operand(e Expr) (operand, error) {
+	switch e := e.(type) {
+	case *Literal:
+		return operand{value: e.Value, isValue: true, valuePos: e.Pos}, nil
+	case *Param:
+		v, ok := c.params[e.Name]
+		if !ok {
+			return operand{}, errorf(e.Pos, "no value for parameter $%s", e.Name)
+		}
+		return operand{value: v, isValue: true, valuePos: e.Pos}, nil
+	case *Field:
+		return c.field(e)
+	case *Count:
+		return operand{}, errorf(e.Pos, "count() can only be returned")
+	}
+	return operand{}, errorf(-1, "expected a field or value")
+}
+
+func (c *compiler) field(f *Field) (operand, error) {
+	v, ok := c.vars[f.Var]
+	if !ok {
+		return operand{}, errorf(f.Pos, "unknown variable %s", f.Var)
+	}
+	if f.Name == "" {
+		// A bare variable compares by identity
+		return operand{sql: v.alias + ".id", kind: kindUUID}, nil
+	}
+	if !f.Property {
+		columns := nodeColumns
+		if v.edge {
+			columns = edgeColumns
+		}
+		if col, ok := columns[f.Name]; ok {
+			if col.name == "is_negated" {
+				v.negatedTested = true
+			}
+			return columnOperand(v.alias+"."+col.name, col.kind), nil
+		}
+		if col, ok := provenanceColumns[f.Name]; ok {
+			op := columnOperand("p."+col.name, col.kind)
+			op.prov = v
+			return op, nil
+		}
+	}
+	key := c.arg(f.Name)
+	return operand{
+		sql:  v.alias + ".properties->" + key + "::text",
+		text: v.alias + ".properties->>" + key + "::text",
+		kind: kindJSON,
+	}, nil
+}
+
+func columnOperand(sql string, k kind) operand {
+	op := operand{sql: sql, kind: k}
+	if k == kindText {
+		op.text = sql
+	}
+	return op
+}
+
+// returnField compiles a returned field: a whole node or edge as an
+// object, or one field's value
+func (c *compiler) returnField(f *Field) (string, error) {
+	v, ok := c.vars[f.Var]
+	if !ok {
+		return "", errorf(f.Pos, "unknown variable %s", f.Var)
+	}
+	a := v.alias
+	if f.Name == "" {
+		if v.edge {
+			return fmt.Sprintf("jsonb_build_object('id', %[1]s.id, 'edge_type', %[1]s.edge_type, 'source_node', %[1]s.source_node, 'target_node', %[1]s.target_node, 'properties', %[1]s.properties, 'is_negated', %[1]s.is_negated)", a), nil
+		}
+		return fmt.Sprintf("jsonb_build_object('id', %[1]s.id, 'node_type', %[1]s.node_type, 'label', %[1]s.label, 'properties', %[1]s.properties)", a), nil
+	}
+
+	x, err := c.field(f)
+	if err != nil {
+		return "", err
+	}
+	if x.prov == nil {
+		return x.sql, nil
+	}
+	targetType := "node"
+	if v.edge {
+		targetType = "edge"
+	}
+	return fmt.Sprintf("(SELECT %s FROM provenance p WHERE %s AND p.status <> 'rejected' ORDER BY p.trust * p.confidence DESC LIMIT 1)", x.sql, provenanceScope(targetType, a)), nil
+}
+
+// value adds a value as an argument of the given kind and returns its
+// placeholder
+func (c *compiler) value(k kind, v any, pos int) (string, error) {
+	wrong := func() (string, error) {
+		return "", errorf(pos, "expected %s, found %s", k, describe(v))
+	}
+	switch k {
+	case kindText:
+		s, ok := v.(string)
+		if !ok {
+			return wrong()
+		}
+		return c.arg(s) + "::text", nil
+
+	case kindNumber:
+		n, ok := v.(float64)
+		if !ok {
+			return wrong()
+		}
+		return c.arg(n) + "::float8", nil
+
+	case kindBool:
+		b, ok := v.(bool)
+		if !ok {
+			return wrong()
+		}
+		return c.arg(b) + "::boolean", nil
+
+	case kindUUID:
+		s, ok := v.(string)
+		if !ok {
+			return wrong()
+		}
+		id, err := uuid.Parse(s)
+		if err != nil {
+			return "", errorf(pos, "invalid ID %q", s)
+		}
+		return c.arg(pgtype.UUID{Bytes: id, Valid: true}) + "::uuid", nil
+
+	case kindTime:
+		s, ok := v.(string)
+		if !ok {
+			return wrong()
+		}
+		t, err := parseTime(s)
+		if err != nil {
+			return "", errorf(pos, "invalid time %q: use YYYY, YYYY-MM, YYYY-MM-DD or RFC 3339", s)
+		}
+		return c.arg(t) + "::timestamptz", nil
+
+	case kindJSON:
+		if _, ok := v.(map[string]any); ok {
+			return wrong()
+		}
+		data, err := json.Marshal(v)
+		if err != nil {
+			return wrong()
+		}
+		// pgx sends a string as raw JSON text
+		return c.arg(string(data)) + "::jsonb", nil
+	}
+	return wrong()
+}
+
+// parseTime reads a year, month, date or full timestamp, in UTC unless
+// the value says otherwise
+func parseTime(s string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02", "2006-01", "2006"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q", s)
+}
+
+func describe(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return strconv.Quote(v)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case []any:
+		return "a list"
+	}
+	return "an object"
+}
@@ -0,0 +1,270 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var testProject = uuid.MustParse("5b1c8f8e-2f57-4f0a-9d3e-7c1a2b3c4d5e")
+
+func compile(t *testing.T, src string, params map[string]any) *Compiled {
+	t.Helper()
+	q, err := Parse(src)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	c, err := Compile(q, testProject, params)
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	return c
+}
+
+func TestCompile(t *testing.T) {
+	// Each node is declared before the relationship that leads to it: in
+	// (a)-[r]->(b), (a) is n0, (b) n1 and [r] e2
+	tests := []struct {
+		name     string
+		src      string
+		params   map[string]any
+		contains []string
+		excludes []string
+	}{
+		{
+			name:     "right",
+			src:      "MATCH (a)-[r]->(b) RETURN r",
+			contains: []string{"e2.source_node = n0.id AND e2.target_node = n1.id"},
+			excludes: []string{"e2.source_node = n1.id"},
+		},
+		{
+			name:     "left",
+			src:      "MATCH (a)<-[r]-(b) RETURN r",
+			contains: []string{"e2.source_node = n1.id AND e2.target_node = n0.id"},
+			excludes: []string{"e2.source_node = n0.id"},
+		},
+		{
+			name:     "any direction",
+			src:      "MATCH (a)-[r]-(b) RETURN r",
+			contains: []string{"((e2.source_node = n0.id AND e2.target_node = n1.id) OR (e2.source_node = n1.id AND e2.target_node = n0.id))"},
+		},
+		{
+			name:     "types",
+			src:      "MATCH (a:person)-[r:PAID|OWES]->(b) RETURN r",
+			contains: []string{"n0.node_type = $2::text", "e2.edge_type = ANY($3::text[])"},
+		},
+		{
+			name: "elements scoped to the project",
+			src:  "MATCH (a)-[r]->(b) RETURN r",
+			contains: []string{
+				"WHERE n.node_type = 'document' AND s.project_id = $1",
+				"EXISTS (SELECT 1 FROM provenance p WHERE p.target_type = 'node' AND p.target_id = n0.id AND p.source_id IN (SELECT id FROM project_documents) AND p.status <> 'rejected')",
+				"EXISTS (SELECT 1 FROM provenance p WHERE p.target_type = 'edge' AND p.target_id = e2.id AND p.source_id IN (SELECT id FROM project_documents) AND p.status <> 'rejected')",
+			},
+		},
+		{
+			name:     "negated edges skipped",
+			src:      "MATCH (a)-[r]->(b)<-[s]-(c) RETURN r",
+			contains: []string{"NOT e2.is_negated", "NOT e4.is_negated", "e4.id <> e2.id"},
+		},
+		{
+			name:     "negated edges matched when tested",
+			src:      "MATCH (a)-[r]->(b)<-[s]-(c) WHERE r.negated RETURN r",
+			contains: []string{"e2.is_negated = $2::boolean", "NOT e4.is_negated"},
+			excludes: []string{"NOT e2.is_negated"},
+		},
+		{
+			name:     "negated edges matched when returned",
+			src:      "MATCH (a)-[r]->(b) WHERE NOT r.negated = false RETURN r.negated",
+			contains: []string{"NOT (e2.is_negated = $2::boolean)", "e2.is_negated AS c0"},
+			excludes: []string{"NOT e2.is_negated"},
+		},
+		{
+			name: "provenance condition",
+			src:  "MATCH (a)-[r]->(b) WHERE r.confidence >= 0.8 AND a.claimed_time < '2021-06' RETURN a",
+			contains: []string{
+				"EXISTS (SELECT 1 FROM provenance p WHERE p.target_type = 'edge' AND p.target_id = e2.id AND p.source_id IN (SELECT id FROM project_documents) AND p.status <> 'rejected' AND p.confidence >= $2::float8)",
+				"EXISTS (SELECT 1 FROM provenance p WHERE p.target_type = 'node' AND p.target_id = n0.id AND p.source_id IN (SELECT id FROM project_documents) AND p.status <> 'rejected' AND p.claimed_time_start < $3::timestamptz)",
+			},
+		},
+		{
+			name:     "provenance status sees rejected records",
+			src:      "MATCH (a) WHERE a.status = 'rejected' RETURN a",
+			contains: []string{"p.target_type = 'node' AND p.target_id = n0.id AND p.source_id IN (SELECT id FROM project_documents) AND p.status = $2::text)"},
+		},
+		{
+			name:     "provenance field on the right",
+			src:      "MATCH (a) WHERE 0.5 < a.trust RETURN a",
+			contains: []string{"AND p.trust > $2::float8)"},
+		},
+		{
+			name:     "returned provenance field",
+			src:      "MATCH (a) RETURN a.modality",
+			contains: []string{"(SELECT p.modality FROM provenance p WHERE p.target_type = 'node' AND p.target_id = n0.id AND p.source_id IN (SELECT id FROM project_documents) AND p.status <> 'rejected' ORDER BY p.trust * p.confidence DESC LIMIT 1) AS c0"},
+		},
+		{
+			name:     "property shadowed by a provenance field",
+			src:      "MATCH (a) WHERE a.properties.status = 'open' RETURN a",
+			contains: []string{"n0.properties->$2::text = $3::jsonb"},
+			excludes: []string{"p.status = "},
+		},
+		{
+			name:     "IN",
+			src:      "MATCH (a) WHERE a.label IN ['Anna', 'Bo'] RETURN a",
+			contains: []string{"(n0.label = $2::text OR n0.label = $3::text)"},
+		},
+		{
+			name:     "IN on a provenance field",
+			src:      "MATCH (a) WHERE a.modality IN ['testimony', 'document'] RETURN a",
+			contains: []string{"AND p.status <> 'rejected' AND (p.modality = $2::text OR p.modality = $3::text))"},
+		},
+		{
+			name:     "IN an empty list",
+			src:      "MATCH (a) WHERE a.label IN [] RETURN a",
+			contains: []string{"WHERE FALSE\n"},
+		},
+		{
+			name:     "ORDER BY a returned column",
+			src:      "MATCH (a) RETURN a.label AS name, a ORDER BY name DESC, a",
+			contains: []string{"ORDER BY q.c0 DESC, q.c1\n"},
+			excludes: []string{" AS o"},
+		},
+		{
+			name:     "ORDER BY another field",
+			src:      "MATCH (a) RETURN a ORDER BY a.label, a.confidence DESC",
+			contains: []string{"n0.label AS o0", "ORDER BY p.trust * p.confidence DESC LIMIT 1) AS o1", "ORDER BY q.o0, q.o1 DESC\n"},
+		},
+		{
+			name:     "count groups by the other columns",
+			src:      "MATCH (a)-[r]->(b) RETURN a.label, count(r)",
+			contains: []string{"count(e2.id) AS c1", "GROUP BY 1\n"},
+		},
+		{
+			name:     "SKIP and LIMIT",
+			src:      "MATCH (a) RETURN a SKIP 20 LIMIT 10",
+			contains: []string{"\nOFFSET $3 LIMIT $4"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := compile(t, tt.src, tt.params)
+			for _, s := range tt.contains {
+				if !strings.Contains(c.SQL, s) {
+					t.Errorf("SQL does not contain %q:\n%s", s, c.SQL)
+				}
+			}
+			for _, s := range tt.excludes {
+				if strings.Contains(c.SQL, s) {
+					t.Errorf("SQL contains %q:\n%s", s, c.SQL)
+				}
+			}
+		})
+	}
+}
+
+func TestCompileArgs(t *testing.T) {
+	c := compile(t, "MATCH (a) WHERE a.label IN ['Anna', 'Bo'] AND a.trust < $trust AND a.confidence > 0.5 RETURN a.label AS name SKIP 20", map[string]any{"trust": 0.9})
+	want := []any{pgtype.UUID{Bytes: testProject, Valid: true}, "Anna", "Bo", 0.9, 0.5, "name", 20, DefaultLimit}
+	if fmt.Sprint(c.Args) != fmt.Sprint(want) {
+		t.Errorf("Args = %v, want %v", c.Args, want)
+	}
+	if len(c.Columns) != 1 || c.Columns[0] != "name" {
+		t.Errorf("Columns = %q, want [name]", c.Columns)
+	}
+}
+
+// TestCompileBindsValues checks that no name or value from a query reaches
+// the SQL text: each is sent as an argument.
+func TestCompileBindsValues(t *testing.T) {
+	const evil = "x'); DROP TABLE nodes; --"
+	src := "MATCH (a:`" + evil + "type` {`" + evil + "key`: \"" + evil + "1\"})" +
+		"-[r:`" + evil + "edge`|PAID {amount: 123.456}]->(b)\n" +
+		"WHERE a.properties.`" + evil + "prop` = \"" + evil + "2\"\n" +
+		"  AND b.label STARTS WITH \"" + evil + "3\"\n" +
+		"  AND b.properties.size IN [\"" + evil + "4\", 7.25]\n" +
+		"  AND a.label CONTAINS $p AND r.claimed_geo_text =~ $q\n" +
+		"  AND r.claimed_time >= '1987-06-05'\n" +
+		"  AND a.id <> '0b0e4c4e-7d0e-4a58-a1a6-3b2f0c1d9e11'\n" +
+		"RETURN a.label AS `" + evil + "alias`, b.`" + evil + "field`\n" +
+		"ORDER BY b.`" + evil + "field` SKIP 31 LIMIT 47"
+	c := compile(t, src, map[string]any{"p": evil + "5", "q": evil + "6"})
+
+	values := []string{
+		evil + "type", evil + "key", evil + "1", evil + "edge", "123.456",
+		evil + "prop", evil + "2", evil + "3", evil + "4", "7.25", evil + "5", evil + "6",
+		"1987", "0b0e4c4e", evil + "alias", evil + "field", "31", "47",
+	}
+	args := fmt.Sprint(c.Args...)
+	for _, v := range values {
+		if strings.Contains(c.SQL, v) {
+			t.Errorf("SQL contains %q", v)
+		}
+		if !strings.Contains(args, v) {
+			t.Errorf("Args do not contain %q: %s", v, args)
+		}
+	}
+	if strings.Contains(c.SQL, "DROP") {
+		t.Errorf("SQL contains DROP:\n%s", c.SQL)
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	// at is the text the error points at; "" for errors without a position
+	tests := []struct {
+		src    string
+		params map[string]any
+		at     string
+		want   string
+	}{
+		{src: "MATCH (a) RETURN b", at: "b", want: "unknown variable b"},
+		{src: "MATCH (a) WHERE b.label = 'x' RETURN a", at: "b.label", want: "unknown variable b"},
+		{src: "MATCH (a) RETURN count(b)", at: "count", want: "unknown variable b"},
+		{src: "MATCH (a)-[a]->(b) RETURN a", at: "-[a]", want: "variable a is used twice"},
+		{src: "MATCH (a) WHERE a.label = $name RETURN a", at: "$name", want: "no value for parameter $name"},
+		{src: "MATCH (a {label: $name}) RETURN a", params: map[string]any{}, at: "$name", want: "no value for parameter $name"},
+		{src: "MATCH (a) WHERE a.label = null RETURN a", at: "null", want: "use IS NULL or IS NOT NULL"},
+		{src: "MATCH (a) WHERE 'x' IS NULL RETURN a", at: "IS", want: "IS NULL tests a field"},
+		{src: "MATCH (a) WHERE 1 = 2 RETURN a", at: "=", want: "needs at least one field"},
+		{src: "MATCH (a) WHERE a.label = a.id RETURN a", at: "=", want: "cannot compare text with an ID"},
+		{src: "MATCH (a)-[r]->(b) WHERE r.negated = 1 RETURN a", at: "1 RETURN", want: "expected a boolean, found 1"},
+		{src: "MATCH (a)-[r]->(b) WHERE r.negated > false RETURN a", at: ">", want: "booleans compare only with = and <>"},
+		{src: "MATCH (a) WHERE a.id = 'not-an-id' RETURN a", at: "'not", want: `invalid ID "not-an-id"`},
+		{src: "MATCH (a) WHERE a.claimed_time > 'last year' RETURN a", at: "'last", want: "invalid time"},
+		{src: "MATCH (a) WHERE a.confidence CONTAINS 'x' RETURN a", at: "CONTAINS", want: "CONTAINS needs text, and a.confidence is a number"},
+		{src: "MATCH (a) WHERE a.label IN 'x' RETURN a", at: "'x'", want: "IN takes a list"},
+		{src: "MATCH (a) WHERE a.label IN [1] RETURN a", at: "[1]", want: "expected text, found 1"},
+		{src: "MATCH (a), (b) WHERE a.trust = b.trust RETURN a", at: "= b", want: "compare one provenance field at a time"},
+		{src: "MATCH (a) WHERE count(a) = 1 RETURN a", at: "count", want: "count() can only be returned"},
+		{src: "MATCH (a) RETURN a.label, a.label", want: "returned twice"},
+		{src: "MATCH (a) RETURN DISTINCT a ORDER BY a.label", want: "ORDER BY a.label must name a returned column"},
+		{src: "MATCH (a) RETURN a LIMIT 1001", want: "LIMIT is at most 1000"},
+		{src: "MATCH (a)-->(b)-->(c)-->(d)-->(e)-->(f)-->(g) RETURN a", want: "at most 12 nodes and relationships"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			q, err := Parse(tt.src)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			_, err = Compile(q, testProject, tt.params)
+			var qerr *Error
+			if !errors.As(err, &qerr) {
+				t.Fatalf("Compile() error = %v, want *Error", err)
+			}
+			if !strings.Contains(qerr.Msg, tt.want) {
+				t.Errorf("Msg = %q, want it to contain %q", qerr.Msg, tt.want)
+			}
+			at := -1
+			if tt.at != "" {
+				at = strings.LastIndex(tt.src, tt.at)
+			}
+			if qerr.Pos != at {
+				t.Errorf("Pos = %d, want %d", qerr.Pos, at)
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokKeyword
+	tokString
+	tokNumber
+	tokParam
+	tokPunct
+)
+
+// token is a lexical token. For keywords text is upper case and raw is as
+// written; for strings text is the unquoted value.
+type token struct {
+	kind tokenKind
+	text string
+	raw  string
+	num  float64
+	pos  int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of query"
+	case tokString:
+		return strconv.Quote(t.text)
+	case tokParam:
+		return "$" + t.text
+	}
+	return "'" + t.text + "'"
+}
+
+var keywords = map[string]bool{
+	"MATCH": true, "WHERE": true, "RETURN": true, "DISTINCT": true, "AS": true,
+	"AND": true, "OR": true, "NOT": true, "IN": true, "IS": true, "NULL": true,
+	"TRUE": true, "FALSE": true, "CONTAINS": true, "STARTS": true, "ENDS": true,
+	"WITH": true, "ORDER": true, "BY": true, "ASC": true, "DESC": true,
+	"SKIP": true, "LIMIT": true, "COUNT": true,
+}
+
+// Error is a syntax or semantic error in a query
+type Error struct {
+	Pos int // byte offset in the query, or -1
+	Msg string
+}
+
+func (e *Error) Error() string {
+	if e.Pos < 0 {
+		return e.Msg
+	}
+	return fmt.Sprintf("%s (at offset %d)", e.Msg, e.Pos)
+}
+
+func errorf(pos int, format string, args ...any) *Error {
+	return &Error{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// lex splits a query into tokens
+func lex(src string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(src) {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+
+		case c == '/' && strings.HasPrefix(src[i:], "//"):
+			// Comment to end of line
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+
+		case isIdentStart(c):
+			start := i
+			for i < len(src) && isIdentPart(src[i]) {
+				i++
+			}
+			word := src[start:i]
+			if upper := strings.ToUpper(word); keywords[upper] {
+				tokens = append(tokens, token{kind: tokKeyword, text: upper, raw: word, pos: start})
+			} else {
+				tokens = append(tokens, token{kind: tokIdent, text: word, pos: start})
+			}
+
+		case c == '`':
+			// Quoted identifier, for names that are keywords or hold spaces
+			end := strings.IndexByte(src[i+1:], '`')
+			if end < 0 {
+				return nil, errorf(i, "unterminated quoted name")
+			}
+			tokens = append(tokens, token{kind: tokIdent, text: src[i+1 : i+1+end], pos: i})
+			i += end + 2
+
+		case c == '\'' || c == '"':
+			s, n, err := lexString(src[i:])
+			if err != nil {
+				return nil, errorf(i, "%s", err)
+			}
+			tokens = append(tokens, token{kind: tokString, text: s, pos: i})
+			i += n
+
+		case c >= '0' && c <= '9':
+			start := i
+			for i < len(src) && (src[i] >= '0' && src[i] <= '9' || src[i] == '.') {
+				i++
+			}
+			num, err := strconv.ParseFloat(src[start:i], 64)
+			if err != nil {
+				return nil, errorf(start, "invalid number %q", src[start:i])
+			}
+			tokens = append(tokens, token{kind: tokNumber, text: src[start:i], num: num, pos: start})
+
+		case c == '$':
+			start := i
+			i++
+			for i < len(src) && isIdentPart(src[i]) {
+				i++
+			}
+			if i == start+1 {
+				return nil, errorf(start, "expected parameter name after '$'")
+			}
+			tokens = append(tokens, token{kind: tokParam, text: src[start+1 : i], pos: start})
+
+		default:
+			punct := string(c)
+			if i+1 < len(src) {
+				switch two := src[i : i+2]; two {
+				case "<=", ">=", "<>", "!=", "=~":
+					punct = two
+				}
+			}
+			if !strings.Contains("()[]{}:,.|-<>=*", punct) && len(punct) == 1 {
+				return nil, errorf(i, "unexpected character %q", c)
+			}
+			tokens = append(tokens, token{kind: tokPunct, text: punct, pos: i})
+			i += len(punct)
+		}
+	}
+	return append(tokens, token{kind: tokEOF, pos: len(src)}), nil
+}
+
+// lexString reads a quoted string at the start of s and returns its value
+// and length in s
+func lexString(s string) (string, int, error) {
+	quote := s[0]
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch c := s[i]; c {
+		case quote:
+			return b.String(), i + 1, nil
+		case '\\':
+			i++
+			if i >= len(s) {
+				return "", 0, fmt.Errorf("unterminated string")
+			}
+			switch s[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			default:
+				b.WriteByte(s[i])
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated string")
+}
+
+// Unquoted names are ASCII; other names can be quoted with backticks
+func isIdentStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isIdentPart(c byte) bool {
+	return isIdentStart(c) || c >= '0' && c <= '9'
+}
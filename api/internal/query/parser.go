@@ -0,0 +1,531 @@
+package query
+
+import "strings"
+
+// Parse parses a graph query
+func Parse(src string) (*Query, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	return p.parseQuery()
+}
+
+type parser struct {
+	tokens []token
+	i      int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.i]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.i]
+	if t.kind != tokEOF {
+		p.i++
+	}
+	return t
+}
+
+// isPunct reports whether the next token is the given punctuation
+func (p *parser) isPunct(s string) bool {
+	t := p.peek()
+	return t.kind == tokPunct && t.text == s
+}
+
+// isKeyword reports whether the next token is the given keyword
+func (p *parser) isKeyword(s string) bool {
+	t := p.peek()
+	return t.kind == tokKeyword && t.text == s
+}
+
+// accept consumes the next token if it is the given punctuation or keyword
+func (p *parser) accept(s string) bool {
+	if p.isPunct(s) || p.isKeyword(s) {
+		p.next()
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(s string) error {
+	if !p.accept(s) {
+		t := p.peek()
+		return errorf(t.pos, "expected %s, found %s", s, t)
+	}
+	return nil
+}
+
+// name reads an identifier. Keywords are accepted as names where a name is
+// expected, such as after a dot.
+func (p *parser) name(what string) (string, error) {
+	t := p.peek()
+	switch t.kind {
+	case tokIdent:
+		p.next()
+		return t.text, nil
+	case tokKeyword:
+		p.next()
+		return t.raw, nil
+	}
+	return "", errorf(t.pos, "expected %s, found %s", what, t)
+}
+
+func (p *parser) parseQuery() (*Query, error) {
+	q := &Query{}
+	if err := p.expect("MATCH"); err != nil {
+		return nil, err
+	}
+	for {
+		pattern, err := p.parsePattern()
+		if err != nil {
+			return nil, err
+		}
+		q.Patterns = append(q.Patterns, pattern)
+		// Further patterns follow a comma or another MATCH
+		if !p.accept(",") && !p.accept("MATCH") {
+			break
+		}
+	}
+
+	if p.accept("WHERE") {
+		where, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		q.Where = where
+	}
+
+	if err := p.expect("RETURN"); err != nil {
+		return nil, err
+	}
+	q.Distinct = p.accept("DISTINCT")
+	for {
+		item, err := p.parseReturnItem()
+		if err != nil {
+			return nil, err
+		}
+		q.Return = append(q.Return, item)
+		if !p.accept(",") {
+			break
+		}
+	}
+
+	if p.accept("ORDER") {
+		if err := p.expect("BY"); err != nil {
+			return nil, err
+		}
+		for {
+			expr, err := p.parseOperand()
+			if err != nil {
+				return nil, err
+			}
+			item := OrderItem{Expr: expr}
+			if p.accept("DESC") {
+				item.Desc = true
+			} else {
+				p.accept("ASC")
+			}
+			q.OrderBy = append(q.OrderBy, item)
+			if !p.accept(",") {
+				break
+			}
+		}
+	}
+
+	if p.accept("SKIP") {
+		n, err := p.count("SKIP")
+		if err != nil {
+			return nil, err
+		}
+		q.Skip = n
+	}
+	if p.accept("LIMIT") {
+		n, err := p.count("LIMIT")
+		if err != nil {
+			return nil, err
+		}
+		q.Limit = n
+	}
+
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, errorf(t.pos, "unexpected %s", t)
+	}
+	return q, nil
+}
+
+// count reads the non-negative integer after SKIP or LIMIT
+func (p *parser) count(clause string) (int, error) {
+	t := p.next()
+	if t.kind != tokNumber || t.num != float64(int(t.num)) || t.num < 0 {
+		return 0, errorf(t.pos, "%s needs a whole number, found %s", clause, t)
+	}
+	return int(t.num), nil
+}
+
+func (p *parser) parsePattern() (Pattern, error) {
+	var pattern Pattern
+	node, err := p.parseNode()
+	if err != nil {
+		return pattern, err
+	}
+	pattern.Nodes = append(pattern.Nodes, node)
+	for p.isPunct("-") || p.isPunct("<") {
+		rel, err := p.parseRel()
+		if err != nil {
+			return pattern, err
+		}
+		node, err := p.parseNode()
+		if err != nil {
+			return pattern, err
+		}
+		pattern.Rels = append(pattern.Rels, rel)
+		pattern.Nodes = append(pattern.Nodes, node)
+	}
+	return pattern, nil
+}
+
+// parseNode reads (var:type|type {key: value})
+func (p *parser) parseNode() (NodePattern, error) {
+	node := NodePattern{Pos: p.peek().pos}
+	if err := p.expect("("); err != nil {
+		return node, err
+	}
+	var err error
+	if p.peek().kind == tokIdent {
+		node.Var = p.next().text
+	}
+	if p.accept(":") {
+		if node.Types, err = p.parseTypes(); err != nil {
+			return node, err
+		}
+	}
+	if p.isPunct("{") {
+		if node.Props, err = p.parseProps(); err != nil {
+			return node, err
+		}
+	}
+	return node, p.expect(")")
+}
+
+// parseRel reads -[var:type|type {key: value}]-> and its other directions,
+// or the shorthand --> without brackets
+func (p *parser) parseRel() (RelPattern, error) {
+	rel := RelPattern{Pos: p.peek().pos}
+	left := p.accept("<")
+	if err := p.expect("-"); err != nil {
+		return rel, err
+	}
+	if p.accept("[") {
+		var err error
+		if p.peek().kind == tokIdent {
+			rel.Var = p.next().text
+		}
+		if p.accept(":") {
+			if rel.Types, err = p.parseTypes(); err != nil {
+				return rel, err
+			}
+		}
+		if p.isPunct("{") {
+			if rel.Props, err = p.parseProps(); err != nil {
+				return rel, err
+			}
+		}
+		if err := p.expect("]"); err != nil {
+			return rel, err
+		}
+	}
+	if err := p.expect("-"); err != nil {
+		return rel, err
+	}
+	right := p.accept(">")
+
+	switch {
+	case left && right:
+		return rel, errorf(rel.Pos, "a relationship cannot point both ways")
+	case left:
+		rel.Direction = DirectionLeft
+	case right:
+		rel.Direction = DirectionRight
+	default:
+		rel.Direction = DirectionAny
+	}
+	return rel, nil
+}
+
+// parseTypes reads type names separated by |
+func (p *parser) parseTypes() ([]string, error) {
+	var types []string
+	for {
+		t, err := p.name("a type name")
+		if err != nil {
+			return nil, err
+		}
+		types = append(types, t)
+		if !p.accept("|") {
+			return types, nil
+		}
+		p.accept(":")
+	}
+}
+
+// parseProps reads {key: value, ...}
+func (p *parser) parseProps() ([]PropMatch, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	var props []PropMatch
+	for !p.isPunct("}") {
+		pos := p.peek().pos
+		key, err := p.name("a property name")
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		value, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		switch value.(type) {
+		case *Literal, *Param:
+		default:
+			return nil, errorf(pos, "pattern property %s must be a value or parameter", key)
+		}
+		props = append(props, PropMatch{Key: key, Value: value, Pos: pos})
+		if !p.accept(",") {
+			break
+		}
+	}
+	return props, p.expect("}")
+}
+
+func (p *parser) parseReturnItem() (ReturnItem, error) {
+	start := p.peek().pos
+	expr, err := p.parseOperand()
+	if err != nil {
+		return ReturnItem{}, err
+	}
+	switch expr.(type) {
+	case *Field, *Count:
+	default:
+		return ReturnItem{}, errorf(start, "RETURN takes variables, fields and count()")
+	}
+	item := ReturnItem{Expr: expr, Alias: exprString(expr)}
+	if p.accept("AS") {
+		if item.Alias, err = p.name("an alias"); err != nil {
+			return item, err
+		}
+	}
+	return item, nil
+}
+
+func (p *parser) parseOr() (Expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("OR") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &Logical{Op: "OR", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (Expr, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("AND") {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &Logical{Op: "AND", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot() (Expr, error) {
+	if p.accept("NOT") {
+		x, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &Not{X: x}, nil
+	}
+	return p.parseCondition()
+}
+
+// parseCondition reads a parenthesised condition or a comparison
+func (p *parser) parseCondition() (Expr, error) {
+	if p.accept("(") {
+		x, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		return x, p.expect(")")
+	}
+
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	t := p.peek()
+
+	if p.accept("IS") {
+		not := p.accept("NOT")
+		if err := p.expect("NULL"); err != nil {
+			return nil, err
+		}
+		return &IsNull{X: left, Not: not, Pos: t.pos}, nil
+	}
+
+	var op string
+	switch {
+	case t.kind == tokPunct && strings.Contains(" = <> != < <= > >= =~ ", " "+t.text+" "):
+		op = t.text
+		if op == "!=" {
+			op = "<>"
+		}
+		p.next()
+	case p.accept("IN"):
+		op = "IN"
+	case p.accept("CONTAINS"):
+		op = "CONTAINS"
+	case p.accept("STARTS"):
+		if err := p.expect("WITH"); err != nil {
+			return nil, err
+		}
+		op = "STARTS WITH"
+	case p.accept("ENDS"):
+		if err := p.expect("WITH"); err != nil {
+			return nil, err
+		}
+		op = "ENDS WITH"
+	default:
+		// A bare field is a boolean test, as in WHERE r.negated
+		if f, ok := left.(*Field); ok && f.Name != "" {
+			return &Comparison{Op: "=", Left: left, Right: &Literal{Value: true, Pos: t.pos}, Pos: t.pos}, nil
+		}
+		return nil, errorf(t.pos, "expected a comparison, found %s", t)
+	}
+
+	right, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	return &Comparison{Op: op, Left: left, Right: right, Pos: t.pos}, nil
+}
+
+// parseOperand reads a field, literal, list, parameter or count()
+func (p *parser) parseOperand() (Expr, error) {
+	t := p.peek()
+	switch {
+	case t.kind == tokIdent:
+		p.next()
+		f := &Field{Var: t.text, Pos: t.pos}
+		if !p.accept(".") {
+			return f, nil
+		}
+		name, err := p.name("a field name")
+		if err != nil {
+			return nil, err
+		}
+		f.Name = name
+		if name == "properties" && p.accept(".") {
+			if f.Name, err = p.name("a property name"); err != nil {
+				return nil, err
+			}
+			f.Property = true
+		}
+		return f, nil
+
+	case t.kind == tokString:
+		p.next()
+		return &Literal{Value: t.text, Pos: t.pos}, nil
+
+	case t.kind == tokNumber:
+		p.next()
+		return &Literal{Value: t.num, Pos: t.pos}, nil
+
+	case t.kind == tokParam:
+		p.next()
+		return &Param{Name: t.text, Pos: t.pos}, nil
+
+	case p.isPunct("-"):
+		p.next()
+		n := p.next()
+		if n.kind != tokNumber {
+			return nil, errorf(n.pos, "expected a number after '-', found %s", n)
+		}
+		return &Literal{Value: -n.num, Pos: t.pos}, nil
+
+	case p.accept("TRUE"):
+		return &Literal{Value: true, Pos: t.pos}, nil
+	case p.accept("FALSE"):
+		return &Literal{Value: false, Pos: t.pos}, nil
+	case p.accept("NULL"):
+		return &Literal{Value: nil, Pos: t.pos}, nil
+
+	case p.accept("["):
+		list := []any{}
+		for !p.isPunct("]") {
+			item, err := p.parseOperand()
+			if err != nil {
+				return nil, err
+			}
+			lit, ok := item.(*Literal)
+			if !ok {
+				return nil, errorf(t.pos, "lists hold only values")
+			}
+			list = append(list, lit.Value)
+			if !p.accept(",") {
+				break
+			}
+		}
+		return &Literal{Value: list, Pos: t.pos}, p.expect("]")
+
+	case p.accept("COUNT"):
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		c := &Count{Pos: t.pos}
+		if !p.accept("*") {
+			v := p.next()
+			if v.kind != tokIdent {
+				return nil, errorf(v.pos, "expected a variable or * in count(), found %s", v)
+			}
+			c.Var = v.text
+		}
+		return c, p.expect(")")
+	}
+	return nil, errorf(t.pos, "expected a value, found %s", t)
+}
+
+// exprString renders a RETURN item as written, for its default alias
+func exprString(e Expr) string {
+	switch e := e.(type) {
+	case *Field:
+		switch {
+		case e.Name == "":
+			return e.Var
+		case e.Property:
+			return e.Var + ".properties." + e.Name
+		}
+		return e.Var + "." + e.Name
+	case *Count:
+		if e.Var == "" {
+			return "count(*)"
+		}
+		return "count(" + e.Var + ")"
+	}
+	return ""
+}
@@ -0,0 +1,184 @@
+package query
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseRelationships(t *testing.T) {
+	tests := []struct {
+		src       string
+		direction Direction
+		variable  string
+		types     []string
+	}{
+		{"MATCH (a)-->(b) RETURN a", DirectionRight, "", nil},
+		{"MATCH (a)<--(b) RETURN a", DirectionLeft, "", nil},
+		{"MATCH (a)--(b) RETURN a", DirectionAny, "", nil},
+		{"MATCH (a)-[r]->(b) RETURN r", DirectionRight, "r", nil},
+		{"MATCH (a)<-[r:PAID]-(b) RETURN r", DirectionLeft, "r", []string{"PAID"}},
+		{"MATCH (a)-[:PAID|:OWES]-(b) RETURN a", DirectionAny, "", []string{"PAID", "OWES"}},
+		{"MATCH (a)-[r:PAID|OWES]->(b) RETURN r", DirectionRight, "r", []string{"PAID", "OWES"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			q, err := Parse(tt.src)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if len(q.Patterns) != 1 || len(q.Patterns[0].Rels) != 1 {
+				t.Fatalf("Patterns = %+v, want one relationship", q.Patterns)
+			}
+			rel := q.Patterns[0].Rels[0]
+			if rel.Direction != tt.direction {
+				t.Errorf("Direction = %v, want %v", rel.Direction, tt.direction)
+			}
+			if rel.Var != tt.variable {
+				t.Errorf("Var = %q, want %q", rel.Var, tt.variable)
+			}
+			if !reflect.DeepEqual(rel.Types, tt.types) {
+				t.Errorf("Types = %q, want %q", rel.Types, tt.types)
+			}
+			if want := strings.IndexAny(tt.src, "<-"); rel.Pos != want {
+				t.Errorf("Pos = %d, want %d", rel.Pos, want)
+			}
+		})
+	}
+}
+
+func TestParseQuery(t *testing.T) {
+	src := `MATCH (p:person {name: "Anna", born: $year})-[r:PAID]->(c:company), (c)<--(d:document)
+WHERE r.properties.amount > -1.5 AND NOT r.negated AND r.claimed_time >= "2021"
+  AND c.label IN ["Bygg AB", 'Måleri AB'] OR p.status IS NOT NULL
+RETURN DISTINCT p.label AS name, count(r), c
+ORDER BY name DESC, c.label
+SKIP 10 LIMIT 20`
+	q, err := Parse(src)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	if len(q.Patterns) != 2 || len(q.Patterns[0].Nodes) != 2 || len(q.Patterns[1].Nodes) != 2 {
+		t.Fatalf("Patterns = %+v, want two patterns of two nodes", q.Patterns)
+	}
+	person := q.Patterns[0].Nodes[0]
+	if person.Var != "p" || !reflect.DeepEqual(person.Types, []string{"person"}) {
+		t.Errorf("first node = %+v", person)
+	}
+	wantProps := []PropMatch{
+		{Key: "name", Value: &Literal{Value: "Anna", Pos: strings.Index(src, `"Anna"`)}, Pos: strings.Index(src, "name:")},
+		{Key: "born", Value: &Param{Name: "year", Pos: strings.Index(src, "$year")}, Pos: strings.Index(src, "born:")},
+	}
+	if !reflect.DeepEqual(person.Props, wantProps) {
+		t.Errorf("Props = %+v, want %+v", person.Props, wantProps)
+	}
+
+	// (r.amount > -1.5 AND NOT r.negated AND r.claimed_time >= "2021" AND c.label IN [...]) OR p.status IS NOT NULL
+	or, ok := q.Where.(*Logical)
+	if !ok || or.Op != "OR" {
+		t.Fatalf("Where = %#v, want OR at the top", q.Where)
+	}
+	if isNull, ok := or.Right.(*IsNull); !ok || !isNull.Not {
+		t.Errorf("right of OR = %#v, want IS NOT NULL", or.Right)
+	}
+	and, ok := or.Left.(*Logical)
+	if !ok || and.Op != "AND" {
+		t.Fatalf("left of OR = %#v, want AND", or.Left)
+	}
+	in, ok := and.Right.(*Comparison)
+	if !ok || in.Op != "IN" || !reflect.DeepEqual(in.Right.(*Literal).Value, []any{"Bygg AB", "Måleri AB"}) {
+		t.Errorf("IN = %#v", and.Right)
+	}
+	var comparisons []*Comparison
+	var walk func(Expr)
+	walk = func(e Expr) {
+		switch e := e.(type) {
+		case *Logical:
+			walk(e.Left)
+			walk(e.Right)
+		case *Not:
+			walk(e.X)
+		case *Comparison:
+			comparisons = append(comparisons, e)
+		}
+	}
+	walk(and.Left)
+	if len(comparisons) != 3 {
+		t.Fatalf("comparisons = %d, want 3", len(comparisons))
+	}
+	amount := comparisons[0]
+	if f := amount.Left.(*Field); f.Var != "r" || f.Name != "amount" || !f.Property {
+		t.Errorf("amount field = %+v, want property amount of r", f)
+	}
+	if v := amount.Right.(*Literal).Value; v != -1.5 {
+		t.Errorf("amount value = %v, want -1.5", v)
+	}
+	// A bare field tests for true
+	if negated := comparisons[1]; negated.Op != "=" || negated.Right.(*Literal).Value != true {
+		t.Errorf("negated test = %+v, want = true", negated)
+	}
+	if claimed := comparisons[2]; claimed.Op != ">=" || claimed.Left.(*Field).Name != "claimed_time" {
+		t.Errorf("claimed_time test = %+v", claimed)
+	}
+
+	if !q.Distinct || len(q.Return) != 3 {
+		t.Fatalf("Return = %+v, want three distinct items", q.Return)
+	}
+	for i, alias := range []string{"name", "count(r)", "c"} {
+		if q.Return[i].Alias != alias {
+			t.Errorf("Return[%d].Alias = %q, want %q", i, q.Return[i].Alias, alias)
+		}
+	}
+	if len(q.OrderBy) != 2 || !q.OrderBy[0].Desc || q.OrderBy[1].Desc {
+		t.Errorf("OrderBy = %+v, want name DESC, c.label", q.OrderBy)
+	}
+	if q.Skip != 10 || q.Limit != 20 {
+		t.Errorf("Skip, Limit = %d, %d, want 10, 20", q.Skip, q.Limit)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	// at is the text the error points at
+	tests := []struct {
+		src  string
+		at   string
+		want string
+	}{
+		{"RETURN a", "RETURN", "expected MATCH"},
+		{"MATCH (a)<-->(b) RETURN a", "<-->", "cannot point both ways"},
+		{"MATCH (a)<-[r]->(b) RETURN a", "<-[r]", "cannot point both ways"},
+		{"MATCH (a)-[r:]->(b) RETURN a", "]->", "expected a type name"},
+		{"MATCH (a {name: b.label}) RETURN a", "name:", "must be a value or parameter"},
+		{`MATCH (a) WHERE a.label = "open RETURN a`, `"open`, "unterminated string"},
+		{"MATCH (a) WHERE a RETURN a", "RETURN", "expected a comparison"},
+		{"MATCH (a) WHERE a.label IN [b, 1] RETURN a", "[b", "lists hold only values"},
+		{"MATCH (a) WHERE a.label = - x RETURN a", "x RETURN", "expected a number after '-'"},
+		{"MATCH (a) WHERE a.label = 1 RETURN a.label = 1", "= 1\x00", "unexpected"},
+		{`MATCH (a) RETURN "label"`, `"label"`, "RETURN takes variables, fields and count()"},
+		{"MATCH (a) RETURN count(1)", "1)", "expected a variable or * in count()"},
+		{"MATCH (a) RETURN a LIMIT 1.5", "1.5", "LIMIT needs a whole number"},
+		{"MATCH (a) RETURN a SKIP -1", "-1", "SKIP needs a whole number"},
+		{"MATCH (a) RETURN a LIMIT 10 a", "a\x00", "unexpected"},
+		{"MATCH (a) WHERE a.label = ? RETURN a", "?", "unexpected character"},
+		{"MATCH (a) WHERE a.label = $ RETURN a", "$", "expected parameter name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			_, err := Parse(tt.src)
+			var qerr *Error
+			if !errors.As(err, &qerr) {
+				t.Fatalf("Parse() error = %v, want *Error", err)
+			}
+			if !strings.Contains(qerr.Msg, tt.want) {
+				t.Errorf("Msg = %q, want it to contain %q", qerr.Msg, tt.want)
+			}
+			// A trailing NUL in at anchors it to the end of src
+			at := strings.LastIndex(tt.src+"\x00", tt.at)
+			if qerr.Pos != at {
+				t.Errorf("Pos = %d, want %d (%q)", qerr.Pos, at, tt.src[min(at, len(tt.src)):])
+			}
+		})
+	}
+}
@@ -1,100 +1,179 @@
 package services
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"log/slog"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/einarsundgren/sikta/internal/database"
 	"github.com/einarsundgren/sikta/internal/document"
+	"github.com/einarsundgren/sikta/internal/storage"
 )
 
 const (
-	maxFileSize      = 50 * 1024 * 1024 // 50MB
-	uploadsDir       = "uploads"
-	uploadsDemoDir   = "uploads/demo"
-	statusUploaded   = "uploaded"
-	statusProcessing = "processing"
-	statusReady      = "ready"
-	statusError      = "error"
+	defaultMaxUploadSize = 1024 * 1024 * 1024 // 1GB
+	uploadsDir           = "uploads"
+	uploadsDemoDir       = "uploads/demo"
+	statusUploaded       = "uploaded"
+	statusProcessing     = "processing"
+	statusReady          = "ready"
+	statusError          = "error"
 )
 
+// uploadFileTypes maps accepted file extensions to source file types.
+var uploadFileTypes = map[string]string{
+	".txt":     "txt",
+	".pdf":     "pdf",
+	".md":      "md",
+	".docx":    "docx",
+	".eml":     "eml",
+	".mbox":    "mbox",
+	".html":    "html",
+	".htm":     "html",
+	".warc":    "warc",
+	".warc.gz": "warc",
+	".csv":     "csv",
+	".xlsx":    "xlsx",
+}
+
+// textFileTypes are formats stored as plain text that are parsed by extension.
+var textFileTypes = map[string]bool{
+	"md":   true,
+	"eml":  true,
+	"mbox": true,
+	"html": true,
+	"csv":  true,
+}
+
 // DocumentService handles document processing business logic.
 type DocumentService struct {
-	logger *slog.Logger
+	logger        *slog.Logger
+	pdf           document.PDFOptions
+	maxUploadSize int64
+	store         storage.Storage
+	db            *database.Queries // resumable upload state
 }
 
-// NewDocumentService creates a new document service.
-func NewDocumentService(logger *slog.Logger) *DocumentService {
+// NewDocumentService creates a new document service. pdf selects how PDF
+// text is extracted and which OCR engine reads scanned pages; maxUploadSize
+// is the largest accepted file in bytes (0 for the default); store keeps the
+// uploaded files and db the state of resumable uploads.
+func NewDocumentService(logger *slog.Logger, pdf document.PDFOptions, maxUploadSize int64, store storage.Storage, db *database.Queries) *DocumentService {
+	if maxUploadSize <= 0 {
+		maxUploadSize = defaultMaxUploadSize
+	}
 	return &DocumentService{
-		logger: logger,
+		logger:        logger,
+		pdf:           pdf,
+		maxUploadSize: maxUploadSize,
+		store:         store,
+		db:            db,
 	}
 }
 
+// MaxUploadSize returns the largest accepted file in bytes.
+func (s *DocumentService) MaxUploadSize() int64 {
+	return s.maxUploadSize
+}
+
 // UploadResult contains the result of a document upload.
 type UploadResult struct {
-	Filename string
-	FilePath string
-	FileType string
-	Title    string
+	Filename    string
+	FilePath    string // storage key of the file
+	FileType    string
+	Title       string
+	ContentHash string // hex SHA-256 of the file
 }
 
-// ValidateUpload validates an uploaded file.
+// ValidateUpload streams an uploaded file to local disk, hashing it on the
+// way, validates it and moves it into storage. size is the declared length, or -1 if unknown (as for
+// a streamed multipart part); either way no more than the upload limit is
+// read.
 func (s *DocumentService) ValidateUpload(filename string, size int64, reader io.Reader) (*UploadResult, error) {
 	// Check file size
-	if size > maxFileSize {
-		return nil, fmt.Errorf("file exceeds %dMB limit", maxFileSize/(1024*1024))
+	if size > s.maxUploadSize {
+		return nil, fmt.Errorf("file exceeds %dMB limit", s.maxUploadSize/(1024*1024))
 	}
 
 	if size == 0 {
 		return nil, fmt.Errorf("file is empty")
 	}
 
-	// Create uploads directory if it doesn't exist
-	if err := os.MkdirAll(uploadsDir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create uploads directory: %w", err)
-	}
-
-	// Generate unique filename
-	ext := strings.ToLower(filepath.Ext(filename))
-	if ext == "" {
-		return nil, fmt.Errorf("file has no extension")
-	}
-
-	// Validate file type by extension
-	if ext != ".txt" && ext != ".pdf" && ext != ".md" {
-		return nil, fmt.Errorf("only PDF, TXT, and Markdown files supported")
+	ext, _, err := uploadFileType(filename)
+	if err != nil {
+		return nil, err
 	}
 
-	fileType := strings.TrimPrefix(ext, ".")
-
-	// Generate unique filename
-	uniqueFilename := fmt.Sprintf("%s_%s%s", uuid.New().String(), filename, ext)
-	filePath := filepath.Join(uploadsDir, uniqueFilename)
-
-	// Create the file
-	file, err := os.Create(filePath)
+	// The file is staged in a temporary file for the length of the
+	// request, since its type is detected from the content before it goes
+	// into storage
+	file, err := os.CreateTemp("", "sikta-upload-*"+ext)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create file: %w", err)
 	}
 	defer file.Close()
+	filePath := file.Name()
 
-	// Copy content
-	copied, err := io.Copy(file, reader)
+	// Copy content, hashing it on the way
+	hash := sha256.New()
+	copied, err := io.Copy(io.MultiWriter(file, hash), io.LimitReader(reader, s.maxUploadSize+1))
 	if err != nil {
 		os.Remove(filePath) // Clean up on error
 		return nil, fmt.Errorf("failed to save file: %w", err)
 	}
 
-	if copied != size {
+	switch {
+	case copied > s.maxUploadSize:
+		os.Remove(filePath) // Clean up on error
+		return nil, fmt.Errorf("file exceeds %dMB limit", s.maxUploadSize/(1024*1024))
+	case copied == 0:
+		os.Remove(filePath) // Clean up on error
+		return nil, fmt.Errorf("file is empty")
+	case size >= 0 && copied != size:
 		os.Remove(filePath) // Clean up on error
 		return nil, fmt.Errorf("file size mismatch")
 	}
 
+	return s.checkUpload(filename, filePath, hex.EncodeToString(hash.Sum(nil)))
+}
+
+// uploadFileType returns the extension and source file type of an upload's
+// filename, or an error if the type is not accepted.
+func uploadFileType(filename string) (ext, fileType string, err error) {
+	ext = strings.ToLower(filepath.Ext(filename))
+	if ext == "" {
+		return "", "", fmt.Errorf("file has no extension")
+	}
+	if ext == ".gz" && strings.HasSuffix(strings.ToLower(filename), ".warc.gz") {
+		ext = ".warc.gz"
+	}
+
+	// Validate file type by extension
+	fileType, ok := uploadFileTypes[ext]
+	if !ok {
+		return "", "", fmt.Errorf("only PDF, TXT, Markdown, DOCX, email (EML, MBOX), web (HTML, WARC), and table (CSV, XLSX) files supported")
+	}
+	return ext, fileType, nil
+}
+
+// checkUpload verifies that a staged upload's content matches its extension
+// and moves it into storage. The staged file is removed either way.
+func (s *DocumentService) checkUpload(filename, filePath, contentHash string) (*UploadResult, error) {
+	ext, fileType, err := uploadFileType(filename)
+	if err != nil {
+		os.Remove(filePath)
+		return nil, err
+	}
+
 	// Detect actual file type from content
 	detectedType, err := document.DetectFileType(filePath)
 	if err != nil {
@@ -103,9 +182,9 @@ func (s *DocumentService) ValidateUpload(filename string, size int64, reader io.
 	}
 
 	// Verify extension matches detected type
-	// Treat "md" as equivalent to "txt" since markdown is plain text
+	// Markdown, email, HTML and CSV files are plain text, so they detect as "txt"
 	isValid := detectedType == fileType ||
-		(fileType == "md" && detectedType == "txt")
+		(textFileTypes[fileType] && detectedType == "txt")
 	if !isValid {
 		os.Remove(filePath) // Clean up on error
 		return nil, fmt.Errorf("file extension (%s) doesn't match detected type (%s)", fileType, detectedType)
@@ -116,11 +195,20 @@ func (s *DocumentService) ValidateUpload(filename string, size int64, reader io.
 	title = strings.ReplaceAll(title, "_", " ")
 	title = strings.ReplaceAll(title, "-", " ")
 
+	// Generate unique filename
+	uniqueFilename := fmt.Sprintf("%s_%s%s", uuid.New().String(), filename, ext)
+	key := path.Join(uploadsDir, uniqueFilename)
+	if err := s.store.PutFile(context.Background(), key, filePath); err != nil {
+		os.Remove(filePath)
+		return nil, fmt.Errorf("failed to store file: %w", err)
+	}
+
 	return &UploadResult{
-		Filename: uniqueFilename,
-		FilePath: filePath,
-		FileType: detectedType,
-		Title:    title,
+		Filename:    uniqueFilename,
+		FilePath:    key,
+		FileType:    fileType,
+		Title:       title,
+		ContentHash: contentHash,
 	}, nil
 }
 
@@ -129,16 +217,27 @@ type ProcessResult struct {
 	Chunks    []document.Chunk
 	TotalPages int
 	Warnings  []string
+	Metadata  map[string]interface{} // source-level metadata (page title, canonical URL, ...)
+	Tables    []*document.Table      // parsed sheets of CSV/XLSX sources
 }
 
-// ProcessDocument processes a document file and extracts chunks.
-func (s *DocumentService) ProcessDocument(filePath, fileType string) (*ProcessResult, error) {
+// ProcessDocument processes a stored document file and extracts chunks.
+func (s *DocumentService) ProcessDocument(key, fileType string) (*ProcessResult, error) {
+	// The parsers read files, so a remote blob is fetched to a local copy
+	filePath, release, err := s.store.LocalPath(context.Background(), key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer release()
+
 	var chunks []document.Chunk
 	var warnings []string
 	var totalPages int
+	var metadata map[string]interface{}
+	var tables []*document.Table
 
 	switch fileType {
-	case "txt", "md":
+	case "txt":
 		content, err := s.readTXTFile(filePath)
 		if err != nil {
 			return nil, err
@@ -149,24 +248,103 @@ func (s *DocumentService) ProcessDocument(filePath, fileType string) (*ProcessRe
 			return nil, fmt.Errorf("failed to parse file: %w", err)
 		}
 
+	case "md":
+		content, err := s.readTXTFile(filePath)
+		if err != nil {
+			return nil, err
+		}
+
+		chunks, err = document.ParseMarkdown(content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse Markdown: %w", err)
+		}
+
 	case "pdf":
 		var err error
-		chunks, err = document.ParsePDFWithChunks(filePath)
+		var pdfWarnings []string
+		chunks, pdfWarnings, err = document.ParsePDFWithChunks(filePath, s.pdf)
+		for _, w := range pdfWarnings {
+			s.logger.Warn("PDF page without text", "file", filepath.Base(filePath), "warning", w)
+		}
+		warnings = append(warnings, pdfWarnings...)
 		if err != nil {
 			return nil, fmt.Errorf("failed to parse PDF: %w", err)
 		}
 
-		// Calculate total pages from chunks
-		for _, chunk := range chunks {
-			if chunk.PageEnd != nil && *chunk.PageEnd > totalPages {
-				totalPages = *chunk.PageEnd
+	case "eml", "mbox":
+		file, err := os.Open(filePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open file: %w", err)
+		}
+		defer file.Close()
+
+		if fileType == "eml" {
+			chunks, err = document.ParseEMLWithChunks(file)
+		} else {
+			var mboxWarnings []string
+			chunks, mboxWarnings, err = document.ParseMBOXWithChunks(file)
+			for _, w := range mboxWarnings {
+				s.logger.Warn("mbox message skipped", "file", filepath.Base(filePath), "warning", w)
 			}
+			warnings = append(warnings, mboxWarnings...)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse email: %w", err)
+		}
+
+	case "html":
+		content, err := s.readTXTFile(filePath)
+		if err != nil {
+			return nil, err
+		}
+
+		var page *document.HTMLPage
+		chunks, page = document.ParseHTMLWithChunks(content)
+		metadata = page.Metadata()
+
+	case "warc":
+		file, err := os.Open(filePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open file: %w", err)
+		}
+		defer file.Close()
+
+		var pages []*document.HTMLPage
+		chunks, pages, err = document.ParseWARCWithChunks(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse WARC: %w", err)
+		}
+		pageMeta := make([]map[string]interface{}, len(pages))
+		for i, page := range pages {
+			pageMeta[i] = page.Metadata()
+		}
+		metadata = map[string]interface{}{"pages": pageMeta}
+
+	case "docx":
+		var err error
+		chunks, err = document.ParseDOCXWithChunks(filePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse DOCX: %w", err)
+		}
+
+	case "csv", "xlsx":
+		var err error
+		chunks, tables, err = document.ParseTablesWithChunks(filePath, fileType)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse table: %w", err)
 		}
 
 	default:
 		return nil, fmt.Errorf("unsupported file type: %s", fileType)
 	}
 
+	// Calculate total pages from chunks (PDF, and DOCX with page hints)
+	for _, chunk := range chunks {
+		if chunk.PageEnd != nil && *chunk.PageEnd > totalPages {
+			totalPages = *chunk.PageEnd
+		}
+	}
+
 	// Check for warnings
 	if len(chunks) == 1 && chunks[0].ChapterNumber == 0 {
 		warnings = append(warnings, "Document produced a single chunk (short text)")
@@ -181,6 +359,8 @@ func (s *DocumentService) ProcessDocument(filePath, fileType string) (*ProcessRe
 		Chunks:     chunks,
 		TotalPages: totalPages,
 		Warnings:   warnings,
+		Metadata:   metadata,
+		Tables:     tables,
 	}, nil
 }
 
@@ -200,9 +380,9 @@ func (s *DocumentService) readTXTFile(filePath string) (string, error) {
 	return content, nil
 }
 
-// Cleanup removes a document file from disk.
-func (s *DocumentService) Cleanup(filePath string) error {
-	if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
+// Cleanup removes a document file from storage.
+func (s *DocumentService) Cleanup(key string) error {
+	if err := s.store.Delete(context.Background(), key); err != nil {
 		return fmt.Errorf("failed to remove file: %w", err)
 	}
 	return nil
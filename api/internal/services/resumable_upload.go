@@ -0,0 +1,302 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"time"
+
+	"github.com/einarsundgren/sikta/internal/database"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Resumable uploads follow the core of the tus 1.0 protocol: a client
+// creates an upload with its total length, then sends the bytes in one or
+// more appends, each starting at the offset the server reports. An
+// interrupted append keeps the bytes that arrived, so the client resumes from
+// the new offset instead of starting over.
+//
+// Upload state lives in the resumable_uploads table and the bytes of each
+// append are stored as a part in the storage backend, so any replica can
+// take the next append. The parts are joined and hashed when the last byte
+// arrives.
+
+const partialUploadsPrefix = "uploads/partial"
+
+// ResumableUploadExpiry is how long an unfinished upload is kept.
+const ResumableUploadExpiry = 24 * time.Hour
+
+var (
+	// ErrUploadNotFound is returned for unknown or expired upload IDs.
+	ErrUploadNotFound = errors.New("upload not found")
+	// ErrUploadOffset is returned when an append does not start where the
+	// upload left off.
+	ErrUploadOffset = errors.New("upload offset mismatch")
+	// ErrUploadIncomplete is returned when finishing an upload that has not
+	// received all of its bytes.
+	ErrUploadIncomplete = errors.New("upload incomplete")
+)
+
+// ResumableUpload is the state of a resumable upload.
+type ResumableUpload struct {
+	ID         string            `json:"id"`
+	Filename   string            `json:"filename"`
+	Length     int64             `json:"length"`
+	Offset     int64             `json:"offset"`
+	Metadata   map[string]string `json:"metadata,omitempty"`
+	DocumentID string            `json:"document_id,omitempty"` // set once finished
+	CreatedAt  time.Time         `json:"created_at"`
+
+	parts []string // storage keys of the received bytes
+}
+
+// Complete reports whether every byte has been received.
+func (u *ResumableUpload) Complete() bool {
+	return u.Offset == u.Length
+}
+
+// CreateResumableUpload starts a resumable upload of a file of the given
+// length. metadata holds client-supplied values kept with the upload.
+func (s *DocumentService) CreateResumableUpload(filename string, length int64, metadata map[string]string) (*ResumableUpload, error) {
+	if length <= 0 {
+		return nil, fmt.Errorf("file is empty")
+	}
+	if length > s.maxUploadSize {
+		return nil, fmt.Errorf("file exceeds %dMB limit", s.maxUploadSize/(1024*1024))
+	}
+	if _, _, err := uploadFileType(filename); err != nil {
+		return nil, err
+	}
+
+	meta, err := json.Marshal(metadata)
+	if err != nil || metadata == nil {
+		meta = []byte("{}")
+	}
+	row, err := s.db.CreateResumableUpload(context.Background(), database.CreateResumableUploadParams{
+		Filename: filename,
+		Length:   length,
+		Metadata: meta,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save upload: %w", err)
+	}
+	return resumableUploadFromRow(row), nil
+}
+
+// ResumableUpload returns the state of an upload.
+func (s *DocumentService) ResumableUpload(id string) (*ResumableUpload, error) {
+	// IDs are UUIDs; anything else cannot name an upload
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		return nil, ErrUploadNotFound
+	}
+	row, err := s.db.GetResumableUpload(context.Background(), database.PgUUID(uid))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrUploadNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read upload: %w", err)
+	}
+	return resumableUploadFromRow(row), nil
+}
+
+// AppendResumableUpload writes bytes from r to the upload, starting at
+// offset, until r ends or the upload is complete. The bytes that arrived
+// are kept even if r fails part way, and the returned upload always holds
+// the offset to resume from.
+func (s *DocumentService) AppendResumableUpload(id string, offset int64, r io.Reader) (*ResumableUpload, error) {
+	ctx := context.Background()
+	u, err := s.ResumableUpload(id)
+	if err != nil {
+		return nil, err
+	}
+	if u.DocumentID != "" || offset != u.Offset {
+		return u, ErrUploadOffset
+	}
+
+	// The request body is spooled to a temporary file, so that the bytes of
+	// an interrupted request can still be stored as a part
+	tmp, err := os.CreateTemp("", "sikta-part-*")
+	if err != nil {
+		return u, fmt.Errorf("failed to create file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	n, copyErr := io.Copy(tmp, io.LimitReader(r, u.Length-u.Offset))
+	if err := tmp.Close(); err != nil && copyErr == nil {
+		copyErr = err
+	}
+	if n == 0 {
+		if copyErr != nil {
+			return u, fmt.Errorf("upload interrupted at offset %d: %w", u.Offset, copyErr)
+		}
+		return u, nil
+	}
+
+	// The part key is unique, so concurrent appends at the same offset do
+	// not overwrite each other's bytes; only one of them is recorded
+	key := path.Join(partialUploadsPrefix, u.ID, fmt.Sprintf("%020d-%s", u.Offset, uuid.New()))
+	if err := s.store.PutFile(ctx, key, tmp.Name()); err != nil {
+		return u, fmt.Errorf("failed to store upload: %w", err)
+	}
+	row, err := s.db.AppendResumableUploadPart(ctx, database.AppendResumableUploadPartParams{
+		Received:     n,
+		Part:         key,
+		ID:           database.PgUUID(uuid.MustParse(u.ID)),
+		UploadOffset: u.Offset,
+	})
+	if err != nil {
+		s.store.Delete(ctx, key)
+		if errors.Is(err, pgx.ErrNoRows) {
+			if current, err := s.ResumableUpload(id); err == nil {
+				u = current
+			}
+			return u, ErrUploadOffset
+		}
+		return u, fmt.Errorf("failed to save upload: %w", err)
+	}
+	u = resumableUploadFromRow(row)
+	if copyErr != nil {
+		return u, fmt.Errorf("upload interrupted at offset %d: %w", u.Offset, copyErr)
+	}
+	return u, nil
+}
+
+// FinishResumableUpload joins a complete upload's parts, validates the file
+// and moves it into storage. create is called with the result to store the
+// source and returns its ID, which is recorded on the upload so that the
+// client can look it up. If validation or create fails, the upload is
+// removed.
+func (s *DocumentService) FinishResumableUpload(id string, create func(*UploadResult) (string, error)) (*ResumableUpload, error) {
+	ctx := context.Background()
+	u, err := s.ResumableUpload(id)
+	if err != nil {
+		return nil, err
+	}
+	if u.DocumentID != "" {
+		return u, nil
+	}
+	if !u.Complete() {
+		return u, ErrUploadIncomplete
+	}
+
+	filePath, contentHash, err := s.joinParts(ctx, u)
+	if err != nil {
+		return u, err
+	}
+	result, err := s.checkUpload(u.Filename, filePath, contentHash)
+	if err != nil {
+		s.removeResumableUpload(ctx, u.ID)
+		return u, err
+	}
+	documentID, err := create(result)
+	if err != nil {
+		s.Cleanup(result.FilePath)
+		s.removeResumableUpload(ctx, u.ID)
+		return u, err
+	}
+
+	row, err := s.db.FinishResumableUpload(ctx, database.FinishResumableUploadParams{
+		ID:         database.PgUUID(uuid.MustParse(u.ID)),
+		DocumentID: database.PgUUID(uuid.MustParse(documentID)),
+	})
+	if err != nil {
+		return u, fmt.Errorf("failed to save upload: %w", err)
+	}
+	s.deleteParts(ctx, u.parts)
+	return resumableUploadFromRow(row), nil
+}
+
+// joinParts copies an upload's parts, in order, into a local file for
+// validation and returns its path and hex SHA-256.
+func (s *DocumentService) joinParts(ctx context.Context, u *ResumableUpload) (string, string, error) {
+	ext, _, err := uploadFileType(u.Filename)
+	if err != nil {
+		return "", "", err
+	}
+	file, err := os.CreateTemp("", "sikta-upload-*"+ext)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create file: %w", err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	for _, key := range u.parts {
+		part, err := s.store.Open(ctx, key)
+		if err != nil {
+			os.Remove(file.Name())
+			return "", "", fmt.Errorf("failed to read upload: %w", err)
+		}
+		_, err = io.Copy(io.MultiWriter(file, hash), part)
+		part.Close()
+		if err != nil {
+			os.Remove(file.Name())
+			return "", "", fmt.Errorf("failed to read upload: %w", err)
+		}
+	}
+	return file.Name(), hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// CancelResumableUpload removes an upload and its bytes.
+func (s *DocumentService) CancelResumableUpload(id string) error {
+	if _, err := s.ResumableUpload(id); err != nil {
+		return err
+	}
+	s.removeResumableUpload(context.Background(), id)
+	return nil
+}
+
+// ExpireResumableUploads removes uploads created more than maxAge ago,
+// finished or not. It returns the number removed.
+func (s *DocumentService) ExpireResumableUploads(maxAge time.Duration) int {
+	ctx := context.Background()
+	rows, err := s.db.ExpireResumableUploads(ctx, database.PgTime(time.Now().Add(-maxAge)))
+	if err != nil {
+		s.logger.Error("failed to expire resumable uploads", "error", err)
+		return 0
+	}
+	for _, row := range rows {
+		s.deleteParts(ctx, row.Parts)
+	}
+	return len(rows)
+}
+
+func (s *DocumentService) removeResumableUpload(ctx context.Context, id string) {
+	row, err := s.db.DeleteResumableUpload(ctx, database.PgUUID(uuid.MustParse(id)))
+	if err != nil {
+		return
+	}
+	s.deleteParts(ctx, row.Parts)
+}
+
+func (s *DocumentService) deleteParts(ctx context.Context, parts []string) {
+	for _, key := range parts {
+		if err := s.store.Delete(ctx, key); err != nil {
+			s.logger.Warn("failed to remove upload part", "key", key, "error", err)
+		}
+	}
+}
+
+func resumableUploadFromRow(row *database.ResumableUpload) *ResumableUpload {
+	u := &ResumableUpload{
+		ID:        database.UUIDStr(row.ID),
+		Filename:  row.Filename,
+		Length:    row.Length,
+		Offset:    row.UploadOffset,
+		CreatedAt: row.CreatedAt.Time,
+		parts:     row.Parts,
+	}
+	if len(row.Metadata) > 0 {
+		_ = json.Unmarshal(row.Metadata, &u.Metadata)
+	}
+	if row.DocumentID.Valid {
+		u.DocumentID = database.UUIDStr(row.DocumentID)
+	}
+	return u
+}
@@ -0,0 +1,322 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/einarsundgren/sikta/internal/database"
+	"github.com/einarsundgren/sikta/internal/database/dbtest"
+	"github.com/einarsundgren/sikta/internal/document"
+	"github.com/einarsundgren/sikta/internal/storage"
+)
+
+const uploadText = "Mötet öppnades kl. 19. Dagordningen godkändes."
+
+// testService returns a document service keeping files in a temporary
+// directory, which it also returns, and resumable upload state in memory.
+func testService(t *testing.T) (*DocumentService, *dbtest.Uploads, string) {
+	t.Helper()
+	db := dbtest.New()
+	uploads := dbtest.ResumableUploads(db)
+	dir := t.TempDir()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return NewDocumentService(logger, document.PDFOptions{}, 0, storage.NewLocal(dir), database.New(db)), uploads, dir
+}
+
+// partFiles returns the parts stored for unfinished uploads.
+func partFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	var parts []string
+	root := filepath.Join(dir, filepath.FromSlash(partialUploadsPrefix))
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err == nil && !d.IsDir() {
+			parts = append(parts, path)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parts
+}
+
+// storedText returns the content of a file in storage.
+func storedText(t *testing.T, s *DocumentService, key string) string {
+	t.Helper()
+	r, err := s.store.Open(context.Background(), key)
+	if err != nil {
+		t.Fatalf("Open(%q) error = %v", key, err)
+	}
+	defer r.Close()
+	data, _ := io.ReadAll(r)
+	return string(data)
+}
+
+func newUpload(t *testing.T, s *DocumentService) *ResumableUpload {
+	t.Helper()
+	u, err := s.CreateResumableUpload("protokoll.txt", int64(len(uploadText)), map[string]string{"mapping": "[]"})
+	if err != nil {
+		t.Fatalf("CreateResumableUpload() error = %v", err)
+	}
+	return u
+}
+
+// finish finishes an upload, returning the result create was given.
+func finish(t *testing.T, s *DocumentService, id string) (*ResumableUpload, *UploadResult) {
+	t.Helper()
+	var result *UploadResult
+	u, err := s.FinishResumableUpload(id, func(r *UploadResult) (string, error) {
+		result = r
+		return uuid.New().String(), nil
+	})
+	if err != nil {
+		t.Fatalf("FinishResumableUpload() error = %v", err)
+	}
+	return u, result
+}
+
+func TestCreateResumableUpload(t *testing.T) {
+	s, _, _ := testService(t)
+
+	tests := []struct {
+		name     string
+		filename string
+		length   int64
+		wantErr  bool
+	}{
+		{"text file", "protokoll.txt", 10, false},
+		{"empty", "protokoll.txt", 0, true},
+		{"over the limit", "protokoll.txt", defaultMaxUploadSize + 1, true},
+		{"unsupported type", "protokoll.exe", 10, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := s.CreateResumableUpload(tt.filename, tt.length, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CreateResumableUpload() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			got, err := s.ResumableUpload(u.ID)
+			if err != nil || got.Length != tt.length || got.Offset != 0 {
+				t.Errorf("ResumableUpload() = %+v, %v, want length %d at offset 0", got, err, tt.length)
+			}
+		})
+	}
+
+	if _, err := s.ResumableUpload("not-a-uuid"); !errors.Is(err, ErrUploadNotFound) {
+		t.Errorf("ResumableUpload(not-a-uuid) error = %v, want ErrUploadNotFound", err)
+	}
+	if _, err := s.ResumableUpload(uuid.New().String()); !errors.Is(err, ErrUploadNotFound) {
+		t.Errorf("ResumableUpload(unknown) error = %v, want ErrUploadNotFound", err)
+	}
+}
+
+func TestAppendResumableUploadOffsetMismatch(t *testing.T) {
+	s, _, dir := testService(t)
+	u := newUpload(t, s)
+	if _, err := s.AppendResumableUpload(u.ID, 0, strings.NewReader(uploadText[:10])); err != nil {
+		t.Fatalf("AppendResumableUpload() error = %v", err)
+	}
+
+	for _, offset := range []int64{0, 5, 11} {
+		got, err := s.AppendResumableUpload(u.ID, offset, strings.NewReader("x"))
+		if !errors.Is(err, ErrUploadOffset) {
+			t.Errorf("append at %d: error = %v, want ErrUploadOffset", offset, err)
+		}
+		if got == nil || got.Offset != 10 {
+			t.Errorf("append at %d: upload = %+v, want offset 10 to resume from", offset, got)
+		}
+	}
+	if parts := partFiles(t, dir); len(parts) != 1 {
+		t.Errorf("%d parts stored, want 1", len(parts))
+	}
+}
+
+func TestAppendResumableUploadInterrupted(t *testing.T) {
+	s, _, dir := testService(t)
+	u := newUpload(t, s)
+
+	// The connection drops after 10 bytes
+	body := io.MultiReader(strings.NewReader(uploadText[:10]), iotest.ErrReader(errors.New("connection reset")))
+	got, err := s.AppendResumableUpload(u.ID, 0, body)
+	if err == nil || errors.Is(err, ErrUploadOffset) {
+		t.Fatalf("AppendResumableUpload() error = %v, want the read error", err)
+	}
+	if got == nil || got.Offset != 10 {
+		t.Fatalf("upload = %+v, want the 10 bytes that arrived kept", got)
+	}
+	if parts := partFiles(t, dir); len(parts) != 1 {
+		t.Fatalf("%d parts stored, want 1", len(parts))
+	}
+
+	// A drop before any byte arrives changes nothing
+	got, err = s.AppendResumableUpload(u.ID, 10, iotest.ErrReader(errors.New("connection reset")))
+	if err == nil || got.Offset != 10 {
+		t.Errorf("empty interrupted append = %+v, %v, want an error at offset 10", got, err)
+	}
+
+	// The client resumes from the reported offset
+	got, err = s.AppendResumableUpload(u.ID, 10, strings.NewReader(uploadText[10:]))
+	if err != nil {
+		t.Fatalf("AppendResumableUpload() error = %v", err)
+	}
+	if !got.Complete() {
+		t.Fatalf("upload at %d of %d, want complete", got.Offset, got.Length)
+	}
+
+	done, result := finish(t, s, u.ID)
+	if text := storedText(t, s, result.FilePath); text != uploadText {
+		t.Errorf("stored file = %q, want %q", text, uploadText)
+	}
+	if result.ContentHash != sha256Hex(uploadText) {
+		t.Errorf("ContentHash = %s, want the hash of the whole file", result.ContentHash)
+	}
+	if done.DocumentID == "" {
+		t.Error("DocumentID is empty, want the created source")
+	}
+	if parts := partFiles(t, dir); len(parts) != 0 {
+		t.Errorf("%d parts left after finishing, want 0", len(parts))
+	}
+}
+
+func TestAppendResumableUploadBeyondLength(t *testing.T) {
+	s, _, _ := testService(t)
+	u := newUpload(t, s)
+
+	// Bytes past the declared length are not read
+	got, err := s.AppendResumableUpload(u.ID, 0, strings.NewReader(uploadText+"extra"))
+	if err != nil {
+		t.Fatalf("AppendResumableUpload() error = %v", err)
+	}
+	if got.Offset != got.Length {
+		t.Errorf("Offset = %d, want %d", got.Offset, got.Length)
+	}
+}
+
+func TestJoinParts(t *testing.T) {
+	s, _, _ := testService(t)
+	ctx := context.Background()
+
+	// Parts are joined in the order they were recorded, whatever their keys
+	pieces := map[string]string{"c": "Mötet ", "a": "öppnades ", "b": "kl. 19."}
+	for key, text := range pieces {
+		if err := s.store.Put(ctx, key, strings.NewReader(text), int64(len(text))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	u := &ResumableUpload{Filename: "protokoll.txt", parts: []string{"c", "a", "b"}}
+
+	filePath, hash, err := s.joinParts(ctx, u)
+	if err != nil {
+		t.Fatalf("joinParts() error = %v", err)
+	}
+	defer os.Remove(filePath)
+	data, _ := os.ReadFile(filePath)
+	if want := "Mötet öppnades kl. 19."; string(data) != want {
+		t.Errorf("joined = %q, want %q", data, want)
+	}
+	if hash != sha256Hex(string(data)) {
+		t.Errorf("hash = %s, want the hash of the joined file", hash)
+	}
+
+	u.parts = append(u.parts, "missing")
+	if _, _, err := s.joinParts(ctx, u); err == nil {
+		t.Error("joinParts() with a missing part succeeded, want an error")
+	}
+}
+
+func TestFinishResumableUpload(t *testing.T) {
+	t.Run("incomplete", func(t *testing.T) {
+		s, _, _ := testService(t)
+		u := newUpload(t, s)
+		s.AppendResumableUpload(u.ID, 0, strings.NewReader(uploadText[:10]))
+		_, err := s.FinishResumableUpload(u.ID, func(*UploadResult) (string, error) {
+			t.Error("create called for an incomplete upload")
+			return "", nil
+		})
+		if !errors.Is(err, ErrUploadIncomplete) {
+			t.Errorf("error = %v, want ErrUploadIncomplete", err)
+		}
+	})
+
+	t.Run("finished twice", func(t *testing.T) {
+		s, _, _ := testService(t)
+		u := newUpload(t, s)
+		s.AppendResumableUpload(u.ID, 0, strings.NewReader(uploadText))
+		first, _ := finish(t, s, u.ID)
+		second, err := s.FinishResumableUpload(u.ID, func(*UploadResult) (string, error) {
+			t.Error("create called again for a finished upload")
+			return "", nil
+		})
+		if err != nil || second.DocumentID != first.DocumentID {
+			t.Errorf("second finish = %+v, %v, want the first document", second, err)
+		}
+		// A finished upload takes no more bytes
+		if _, err := s.AppendResumableUpload(u.ID, second.Offset, strings.NewReader("x")); !errors.Is(err, ErrUploadOffset) {
+			t.Errorf("append after finish error = %v, want ErrUploadOffset", err)
+		}
+	})
+
+	t.Run("create fails", func(t *testing.T) {
+		s, _, dir := testService(t)
+		u := newUpload(t, s)
+		s.AppendResumableUpload(u.ID, 0, strings.NewReader(uploadText))
+		_, err := s.FinishResumableUpload(u.ID, func(*UploadResult) (string, error) {
+			return "", errors.New("invalid mapping")
+		})
+		if err == nil {
+			t.Fatal("FinishResumableUpload() succeeded, want the create error")
+		}
+		if _, err := s.ResumableUpload(u.ID); !errors.Is(err, ErrUploadNotFound) {
+			t.Errorf("upload kept after a failed create: %v", err)
+		}
+		if parts := partFiles(t, dir); len(parts) != 0 {
+			t.Errorf("%d parts left, want 0", len(parts))
+		}
+	})
+}
+
+func TestExpireResumableUploads(t *testing.T) {
+	s, uploads, dir := testService(t)
+	old := newUpload(t, s)
+	s.AppendResumableUpload(old.ID, 0, strings.NewReader(uploadText[:10]))
+	uploads.Backdate(ResumableUploadExpiry + time.Minute)
+	fresh := newUpload(t, s)
+	s.AppendResumableUpload(fresh.ID, 0, strings.NewReader(uploadText[:10]))
+
+	if n := s.ExpireResumableUploads(ResumableUploadExpiry); n != 1 {
+		t.Errorf("ExpireResumableUploads() = %d, want 1", n)
+	}
+	if _, err := s.ResumableUpload(old.ID); !errors.Is(err, ErrUploadNotFound) {
+		t.Errorf("expired upload error = %v, want ErrUploadNotFound", err)
+	}
+	if _, err := s.ResumableUpload(fresh.ID); err != nil {
+		t.Errorf("fresh upload error = %v, want it kept", err)
+	}
+	if parts := partFiles(t, dir); len(parts) != 1 {
+		t.Errorf("%d parts left, want the fresh upload's 1", len(parts))
+	}
+}
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
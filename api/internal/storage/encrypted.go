@@ -0,0 +1,115 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+
+	"github.com/einarsundgren/sikta/internal/encryption"
+)
+
+// Encrypted encrypts blobs before they reach another Storage and decrypts
+// them on the way out. Blobs stored before encryption was turned on are
+// read as they are.
+type Encrypted struct {
+	inner Storage
+	env   *encryption.Envelope
+}
+
+// NewEncrypted returns storage that keeps blobs in inner, encrypted by env.
+func NewEncrypted(inner Storage, env *encryption.Envelope) *Encrypted {
+	return &Encrypted{inner: inner, env: env}
+}
+
+// Put implements Storage. The blob is encrypted into a temporary file,
+// which is then moved into the inner storage.
+func (e *Encrypted) Put(ctx context.Context, key string, r io.Reader, size int64) error {
+	tmp, err := os.CreateTemp("", "sikta-enc-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	ew, err := e.env.EncryptWriter(ctx, tmp)
+	if err == nil {
+		var n int64
+		n, err = io.Copy(ew, r)
+		if err == nil && size >= 0 && n != size {
+			err = fmt.Errorf("wrote %d of %d bytes", n, size)
+		}
+		if cerr := ew.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to encrypt %s: %w", key, err)
+	}
+	return e.inner.PutFile(ctx, key, tmp.Name())
+}
+
+// PutFile implements Storage.
+func (e *Encrypted) PutFile(ctx context.Context, key, localPath string) error {
+	f, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := e.Put(ctx, key, f, -1); err != nil {
+		return err
+	}
+	f.Close()
+	return os.Remove(localPath)
+}
+
+// Open implements Storage.
+func (e *Encrypted) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	body, err := e.inner.Open(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := e.env.DecryptReader(ctx, body)
+	if err != nil {
+		body.Close()
+		return nil, fmt.Errorf("%s: %w", key, err)
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{plain, body}, nil
+}
+
+// Delete implements Storage.
+func (e *Encrypted) Delete(ctx context.Context, key string) error {
+	return e.inner.Delete(ctx, key)
+}
+
+// LocalPath implements Storage by decrypting the blob to a temporary file,
+// which release removes. The plaintext copy exists only while the caller
+// holds it.
+func (e *Encrypted) LocalPath(ctx context.Context, key string) (string, func(), error) {
+	body, err := e.Open(ctx, key)
+	if err != nil {
+		return "", nil, err
+	}
+	defer body.Close()
+
+	tmp, err := os.CreateTemp("", "sikta-*"+path.Ext(key))
+	if err != nil {
+		return "", nil, err
+	}
+	release := func() { os.Remove(tmp.Name()) }
+	_, err = io.Copy(tmp, body)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		release()
+		return "", nil, fmt.Errorf("failed to decrypt %s: %w", key, err)
+	}
+	return tmp.Name(), release, nil
+}
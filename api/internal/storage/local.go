@@ -0,0 +1,126 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Local stores blobs as files under a directory.
+type Local struct {
+	dir string
+}
+
+// NewLocal returns storage rooted at dir; an empty dir is the working
+// directory, where uploads have always been kept.
+func NewLocal(dir string) *Local {
+	if dir == "" {
+		dir = "."
+	}
+	return &Local{dir: dir}
+}
+
+func (l *Local) path(key string) (string, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(l.dir, filepath.FromSlash(key)), nil
+}
+
+// Put implements Storage.
+func (l *Local) Put(ctx context.Context, key string, r io.Reader, size int64) error {
+	dst, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	// Write beside the destination and rename, so readers never see a
+	// partial file
+	tmp, err := os.CreateTemp(filepath.Dir(dst), ".put-*")
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	n, err := io.Copy(tmp, r)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", key, err)
+	}
+	if size >= 0 && n != size {
+		return fmt.Errorf("failed to write %s: wrote %d of %d bytes", key, n, size)
+	}
+	return os.Rename(tmp.Name(), dst)
+}
+
+// PutFile implements Storage. The file is renamed into place when it is on
+// the same file system, and copied otherwise.
+func (l *Local) PutFile(ctx context.Context, key, localPath string) error {
+	dst, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	if err := os.Rename(localPath, dst); err == nil {
+		return nil
+	}
+
+	f, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := l.Put(ctx, key, f, -1); err != nil {
+		return err
+	}
+	return os.Remove(localPath)
+}
+
+// Open implements Storage.
+func (l *Local) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	p, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(p)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%s: %w", key, ErrNotFound)
+	}
+	return f, err
+}
+
+// Delete implements Storage.
+func (l *Local) Delete(ctx context.Context, key string) error {
+	p, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// LocalPath implements Storage: the blob already is a local file.
+func (l *Local) LocalPath(ctx context.Context, key string) (string, func(), error) {
+	p, err := l.path(key)
+	if err != nil {
+		return "", nil, err
+	}
+	if _, err := os.Stat(p); err != nil {
+		if os.IsNotExist(err) {
+			return "", nil, fmt.Errorf("%s: %w", key, ErrNotFound)
+		}
+		return "", nil, err
+	}
+	return p, func() {}, nil
+}
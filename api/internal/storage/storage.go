@@ -0,0 +1,79 @@
+// Package storage keeps uploaded files in a blob store: a directory on local
+// disk, or an S3-compatible bucket so that API replicas share uploads without
+// a shared volume.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// Storage backends.
+const (
+	BackendLocal = "local"
+	BackendS3    = "s3"
+)
+
+// ErrNotFound is returned when no blob is stored under a key.
+var ErrNotFound = errors.New("blob not found")
+
+// Storage stores blobs under slash-separated keys such as
+// "uploads/<id>_report.pdf".
+type Storage interface {
+	// Put stores size bytes read from r under key. A negative size means
+	// the length is unknown.
+	Put(ctx context.Context, key string, r io.Reader, size int64) error
+	// PutFile moves a local file into storage under key; the local file is
+	// gone afterwards.
+	PutFile(ctx context.Context, key, localPath string) error
+	// Open reads the blob stored under key.
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the blob under key. Deleting a missing blob is not an
+	// error.
+	Delete(ctx context.Context, key string) error
+	// LocalPath returns a local file holding the blob, for parsers that
+	// need a file on disk. release removes any temporary copy.
+	LocalPath(ctx context.Context, key string) (localPath string, release func(), err error)
+}
+
+// Config selects and configures a storage backend.
+type Config struct {
+	Backend string // BackendLocal (default) or BackendS3
+	Dir     string // local: directory keys are relative to
+
+	Endpoint  string // s3: e.g. https://s3.eu-north-1.amazonaws.com, or http://localhost:9000 for MinIO
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	PathStyle bool // s3: address the bucket in the path rather than the host name, as MinIO does
+}
+
+// New returns the configured storage backend.
+func New(cfg Config) (Storage, error) {
+	switch cfg.Backend {
+	case BackendLocal, "":
+		return NewLocal(cfg.Dir), nil
+	case BackendS3:
+		return NewS3(cfg)
+	}
+	return nil, fmt.Errorf("unknown storage backend: %s", cfg.Backend)
+}
+
+// cleanKey validates a key: relative, slash-separated and without ".."
+// elements, so that no backend can be led outside its root.
+func cleanKey(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return "", fmt.Errorf("invalid storage key %q", key)
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == ".." {
+			return "", fmt.Errorf("invalid storage key %q", key)
+		}
+	}
+	return path.Clean(key), nil
+}
@@ -0,0 +1,50 @@
+-- Project archives: reads that complete a project's graph, and inserts that
+-- restore archived rows under new IDs with their timestamps and review state.
+
+-- name: ListEdgesAmongNodes :many
+-- Every edge between the given nodes, with or without provenance, such as
+-- same_as and contradicts edges from post-processing.
+SELECT * FROM edges
+WHERE source_node = ANY(@node_ids::uuid[])
+  AND target_node = ANY(@node_ids::uuid[])
+ORDER BY created_at, id;
+
+-- name: RestoreSource :exec
+INSERT INTO sources (
+    id, title, filename, file_path, file_type, total_pages, upload_status,
+    error_message, is_demo, metadata, created_at, updated_at, source_trust,
+    trust_reason, project_id, content_hash, previous_version_id, version
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18);
+
+-- name: RestoreChunk :exec
+INSERT INTO chunks (
+    id, source_id, chunk_index, content, chapter_title, chapter_number,
+    page_start, page_end, narrative_position, word_count, created_at,
+    section_id, section_name, metadata
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14);
+
+-- name: RestoreNode :exec
+INSERT INTO nodes (id, node_type, label, properties, created_at, updated_at)
+VALUES ($1, $2, $3, $4, $5, $6);
+
+-- name: RestoreEdge :exec
+INSERT INTO edges (id, edge_type, source_node, target_node, properties, is_negated, created_at, updated_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8);
+
+-- name: RestoreProvenance :exec
+INSERT INTO provenance (
+    id, target_type, target_id, source_id, excerpt, location, confidence,
+    trust, status, modality, claimed_time_start, claimed_time_end,
+    claimed_time_text, claimed_geo_region, claimed_geo_text, claimed_by,
+    created_at, updated_at, claimed_properties
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19);
+
+-- name: RestoreInconsistency :exec
+INSERT INTO inconsistencies (
+    id, source_id, inconsistency_type, severity, title, description,
+    resolution_status, resolution_note, metadata, created_at, updated_at
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11);
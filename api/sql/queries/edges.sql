@@ -65,3 +65,7 @@ WHERE p.source_id IN (
     AND properties->>'source_id' = $1::text
 )
 ORDER BY e.created_at DESC;
+
+-- name: ListEdgesByIDs :many
+SELECT * FROM edges
+WHERE id = ANY(@ids::uuid[]);
@@ -0,0 +1,51 @@
+-- Whole-project reads for exporting a project's graph. A project's nodes
+-- and edges are those with provenance from one of its documents, plus the
+-- nodes at either end of its edges.
+
+-- name: ListProjectNodes :many
+WITH project_documents AS (
+    SELECT n.id FROM nodes n
+    JOIN sources s ON n.properties->>'source_id' = s.id::text
+    WHERE n.node_type = 'document' AND s.project_id = @project_id
+),
+project_edges AS (
+    SELECT e.source_node, e.target_node FROM edges e
+    WHERE EXISTS (
+        SELECT 1 FROM provenance p
+        WHERE p.target_type = 'edge' AND p.target_id = e.id
+          AND p.source_id IN (SELECT id FROM project_documents)
+    )
+)
+SELECT n.* FROM nodes n
+WHERE EXISTS (
+        SELECT 1 FROM provenance p
+        WHERE p.target_type = 'node' AND p.target_id = n.id
+          AND p.source_id IN (SELECT id FROM project_documents)
+    )
+   OR n.id IN (SELECT source_node FROM project_edges)
+   OR n.id IN (SELECT target_node FROM project_edges)
+ORDER BY n.created_at, n.id;
+
+-- name: ListProjectEdges :many
+WITH project_documents AS (
+    SELECT n.id FROM nodes n
+    JOIN sources s ON n.properties->>'source_id' = s.id::text
+    WHERE n.node_type = 'document' AND s.project_id = @project_id
+)
+SELECT e.* FROM edges e
+WHERE EXISTS (
+    SELECT 1 FROM provenance p
+    WHERE p.target_type = 'edge' AND p.target_id = e.id
+      AND p.source_id IN (SELECT id FROM project_documents)
+)
+ORDER BY e.created_at, e.id;
+
+-- name: ListProjectProvenance :many
+-- Every provenance record from the project's documents.
+SELECT p.* FROM provenance p
+WHERE p.source_id IN (
+    SELECT n.id FROM nodes n
+    JOIN sources s ON n.properties->>'source_id' = s.id::text
+    WHERE n.node_type = 'document' AND s.project_id = @project_id
+)
+ORDER BY p.target_id, p.created_at;
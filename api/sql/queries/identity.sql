@@ -0,0 +1,45 @@
+-- Identity clusters over same_as edges, cached per project
+
+-- name: ListProjectSameAsEdges :many
+-- The same_as edges between two of the project's nodes, with whether any of
+-- their provenance is approved and whether all of it is rejected. Edges
+-- without provenance, as deduplication creates them, are neither.
+WITH project_documents AS (
+    SELECT n.id FROM nodes n
+    JOIN sources s ON n.properties->>'source_id' = s.id::text
+    WHERE n.node_type = 'document' AND s.project_id = @project_id
+),
+project_nodes AS (
+    SELECT DISTINCT p.target_id AS id FROM provenance p
+    WHERE p.target_type = 'node' AND p.source_id IN (SELECT id FROM project_documents)
+)
+SELECT e.id, e.source_node, e.target_node, e.properties, e.is_negated,
+       COALESCE(bool_or(p.status = 'approved'), false)::boolean AS approved,
+       (COUNT(p.id) > 0 AND COALESCE(bool_and(p.status = 'rejected'), false))::boolean AS rejected
+FROM edges e
+LEFT JOIN provenance p ON p.target_type = 'edge' AND p.target_id = e.id
+WHERE e.edge_type = 'same_as'
+  AND e.source_node IN (SELECT id FROM project_nodes)
+  AND e.target_node IN (SELECT id FROM project_nodes)
+GROUP BY e.id
+ORDER BY e.created_at, e.id;
+
+-- name: GetIdentityClusterState :one
+SELECT * FROM identity_cluster_state WHERE project_id = $1;
+
+-- name: ListIdentityClusters :many
+SELECT * FROM identity_clusters
+WHERE project_id = $1
+ORDER BY cluster_id, node_id;
+
+-- name: DeleteIdentityClusters :exec
+DELETE FROM identity_clusters WHERE project_id = $1;
+
+-- name: CreateIdentityClusterMember :exec
+INSERT INTO identity_clusters (project_id, node_id, cluster_id)
+VALUES ($1, $2, $3);
+
+-- name: MarkIdentityClustersFresh :exec
+INSERT INTO identity_cluster_state (project_id, stale, computed_at)
+VALUES ($1, false, now())
+ON CONFLICT (project_id) DO UPDATE SET stale = false, computed_at = now();
@@ -53,3 +53,7 @@ SELECT * FROM nodes
 WHERE node_type = 'document'
   AND properties->>'source_id' = $1::text
 LIMIT 1;
+
+-- name: ListNodesByIDs :many
+SELECT * FROM nodes
+WHERE id = ANY(@ids::uuid[]);
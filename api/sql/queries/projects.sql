@@ -1,6 +1,6 @@
 -- name: CreateProject :one
-INSERT INTO projects (title, description)
-VALUES ($1, $2)
+INSERT INTO projects (title, description, auto_extract)
+VALUES ($1, $2, $3)
 RETURNING *;
 
 -- name: GetProject :one
@@ -13,6 +13,7 @@ SELECT * FROM projects ORDER BY created_at DESC;
 UPDATE projects
 SET title = $2,
     description = $3,
+    auto_extract = COALESCE(sqlc.narg('auto_extract'), auto_extract),
     updated_at = NOW()
 WHERE id = $1
 RETURNING *;
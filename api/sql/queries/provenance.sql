@@ -1,11 +1,11 @@
 -- name: CreateProvenance :one
 INSERT INTO provenance (
-    target_type, target_id, source_id, excerpt, location,
+    id, target_type, target_id, source_id, excerpt, location,
     confidence, trust, status, modality,
     claimed_time_start, claimed_time_end, claimed_time_text,
-    claimed_geo_region, claimed_geo_text, claimed_by
+    claimed_geo_region, claimed_geo_text, claimed_by, claimed_properties
 )
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
 RETURNING *;
 
 -- name: GetProvenance :one
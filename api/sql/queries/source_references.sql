@@ -1,8 +1,8 @@
 -- name: CreateSourceReference :one
 INSERT INTO source_references (
-    chunk_id, claim_id, entity_id, relationship_id, excerpt, char_start, char_end
+    id, chunk_id, claim_id, entity_id, relationship_id, excerpt, char_start, char_end
 )
-VALUES ($1, $2, $3, $4, $5, $6, $7)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 RETURNING *;
 
 -- name: ListSourceReferencesByClaim :many
@@ -1,11 +1,40 @@
 -- name: CreateSource :one
-INSERT INTO sources (title, filename, file_path, file_type, upload_status, is_demo)
-VALUES ($1, $2, $3, $4, $5, $6)
+-- Returns no row if the project already has a source with the content hash.
+-- Metadata and trust are written with the row, so a worker woken by the
+-- insert never sees the source without them.
+INSERT INTO sources (title, filename, file_path, file_type, upload_status, is_demo, content_hash, project_id, metadata, source_trust, trust_reason)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+ON CONFLICT (project_id, content_hash) WHERE content_hash IS NOT NULL AND upload_status <> 'error' DO NOTHING
+RETURNING *;
+
+-- name: CreateSourceVersion :one
+-- Returns no row if the project already has a source with the content hash.
+INSERT INTO sources (title, filename, file_path, file_type, upload_status, is_demo, content_hash, previous_version_id, version, project_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+ON CONFLICT (project_id, content_hash) WHERE content_hash IS NOT NULL AND upload_status <> 'error' DO NOTHING
 RETURNING *;
 
 -- name: GetSource :one
 SELECT * FROM sources WHERE id = $1;
 
+-- name: GetSourceByContentHash :one
+-- Sources are deduplicated per project; a NULL project is the sources in none.
+SELECT * FROM sources
+WHERE content_hash = $1 AND project_id IS NOT DISTINCT FROM $2 AND upload_status <> 'error';
+
+-- name: ListSourceVersions :many
+WITH RECURSIVE back AS (
+    SELECT s.id, s.previous_version_id FROM sources s WHERE s.id = $1
+    UNION
+    SELECT p.id, p.previous_version_id FROM sources p JOIN back b ON p.id = b.previous_version_id
+), chain AS (
+    SELECT b.id FROM back b WHERE b.previous_version_id IS NULL
+    UNION
+    SELECT n.id FROM sources n JOIN chain c ON n.previous_version_id = c.id
+)
+SELECT s.* FROM sources s JOIN chain c ON s.id = c.id
+ORDER BY s.version, s.created_at;
+
 -- name: ListSources :many
 SELECT * FROM sources ORDER BY created_at DESC;
 
@@ -17,11 +46,81 @@ SET upload_status = $2,
 WHERE id = $1
 RETURNING *;
 
+-- name: UpdateSourceMetadata :exec
+UPDATE sources
+SET metadata   = COALESCE(metadata, '{}'::jsonb) || $2,
+    updated_at = NOW()
+WHERE id = $1;
+
 -- name: UpdateSourceTotalPages :exec
 UPDATE sources
 SET total_pages = $2,
     updated_at  = NOW()
 WHERE id = $1;
 
+-- name: UpdateSourceTrust :exec
+UPDATE sources
+SET source_trust = $2,
+    trust_reason = $3,
+    updated_at   = NOW()
+WHERE id = $1;
+
+-- name: ClaimUploadedSource :one
+-- Marks the oldest uploaded source as processing and returns it. Sources
+-- locked by another worker's claim are skipped, so no two workers get the
+-- same source. A source whose worker last sent a heartbeat before
+-- stale_before is claimed again.
+UPDATE sources
+SET upload_status = 'processing',
+    claimed_at = NOW(),
+    updated_at = NOW()
+WHERE id = (
+    SELECT id FROM sources
+    WHERE upload_status = 'uploaded'
+       OR (upload_status = 'processing' AND claimed_at < @stale_before)
+    ORDER BY created_at
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: QueueSourceExtraction :exec
+-- Queues a source for extraction by a document worker.
+UPDATE sources
+SET extraction_status = 'queued'
+WHERE id = $1;
+
+-- name: ClaimQueuedExtraction :one
+-- Marks the oldest queued extraction as running and returns its source,
+-- skipping locked rows as ClaimUploadedSource does. An extraction whose
+-- worker last sent a heartbeat before stale_before is claimed again.
+UPDATE sources
+SET extraction_status = 'running',
+    claimed_at = NOW()
+WHERE id = (
+    SELECT id FROM sources
+    WHERE extraction_status = 'queued'
+       OR (extraction_status = 'running' AND claimed_at < @stale_before)
+    ORDER BY updated_at
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: TouchSourceClaim :exec
+-- Heartbeat of the worker holding a source.
+UPDATE sources
+SET claimed_at = NOW()
+WHERE id = $1;
+
+-- name: FinishSourceExtraction :exec
+UPDATE sources
+SET extraction_status = NULL,
+    claimed_at = NULL
+WHERE id = $1;
+
+-- name: ListenSourceStatus :exec
+LISTEN source_status;
+
 -- name: DeleteSource :exec
 DELETE FROM sources WHERE id = $1;
@@ -0,0 +1,127 @@
+-- Traversal of a project's graph. A project's edges are those with
+-- provenance from one of its documents; an edge is traversed if that
+-- provenance has one of the given review statuses, its type is one of the
+-- given edge types (all types if none are given), and it is not negated
+-- unless include_negated is set. direction is 'out', 'in' or 'both'.
+
+-- name: TraverseProjectGraph :many
+-- Nodes within max_depth hops of the start nodes, each with the fewest hops
+-- needed to reach it.
+WITH RECURSIVE project_documents AS (
+    SELECT n.id FROM nodes n
+    JOIN sources s ON n.properties->>'source_id' = s.id::text
+    WHERE n.node_type = 'document' AND s.project_id = @project_id
+),
+project_edges AS (
+    SELECT e.id, e.source_node, e.target_node FROM edges e
+    WHERE (@include_negated::boolean OR NOT e.is_negated)
+      AND (cardinality(@edge_types::text[]) = 0 OR e.edge_type = ANY(@edge_types::text[]))
+      AND EXISTS (
+          SELECT 1 FROM provenance p
+          WHERE p.target_type = 'edge' AND p.target_id = e.id
+            AND p.source_id IN (SELECT id FROM project_documents)
+            AND p.status = ANY(@statuses::text[])
+      )
+),
+steps AS (
+    SELECT id AS edge_id, source_node AS from_node, target_node AS to_node
+    FROM project_edges WHERE @direction::text IN ('out', 'both')
+    UNION ALL
+    SELECT id, target_node, source_node
+    FROM project_edges WHERE @direction::text IN ('in', 'both')
+),
+walk(node_id, depth) AS (
+    SELECT unnest(@start_nodes::uuid[]), 0
+    UNION
+    SELECT s.to_node, w.depth + 1
+    FROM walk w
+    JOIN steps s ON s.from_node = w.node_id
+    WHERE w.depth < @max_depth::int
+)
+SELECT node_id::uuid AS node_id, MIN(depth)::int AS depth
+FROM walk
+GROUP BY node_id
+ORDER BY depth, node_id;
+
+-- name: ListProjectEdgesAmong :many
+-- The project's traversable edges that join two of the given nodes, in
+-- either direction.
+WITH project_documents AS (
+    SELECT n.id FROM nodes n
+    JOIN sources s ON n.properties->>'source_id' = s.id::text
+    WHERE n.node_type = 'document' AND s.project_id = @project_id
+)
+SELECT e.* FROM edges e
+WHERE e.source_node = ANY(@node_ids::uuid[])
+  AND e.target_node = ANY(@node_ids::uuid[])
+  AND (@include_negated::boolean OR NOT e.is_negated)
+  AND (cardinality(@edge_types::text[]) = 0 OR e.edge_type = ANY(@edge_types::text[]))
+  AND EXISTS (
+      SELECT 1 FROM provenance p
+      WHERE p.target_type = 'edge' AND p.target_id = e.id
+        AND p.source_id IN (SELECT id FROM project_documents)
+        AND p.status = ANY(@statuses::text[])
+  )
+ORDER BY e.created_at;
+
+-- name: ListProjectNodesAmong :many
+-- The given nodes that are in the project's graph: its document nodes and
+-- the nodes its documents give provenance, whatever its review status.
+WITH project_documents AS (
+    SELECT n.id FROM nodes n
+    JOIN sources s ON n.properties->>'source_id' = s.id::text
+    WHERE n.node_type = 'document' AND s.project_id = @project_id
+)
+SELECT n.id FROM nodes n
+WHERE n.id = ANY(@node_ids::uuid[])
+  AND (
+      n.id IN (SELECT id FROM project_documents)
+      OR EXISTS (
+          SELECT 1 FROM provenance p
+          WHERE p.target_type = 'node' AND p.target_id = n.id
+            AND p.source_id IN (SELECT id FROM project_documents)
+      )
+  );
+
+-- name: FindProjectPaths :many
+-- Simple paths of at most max_depth hops from one node to another, shortest
+-- first. Each path is its nodes, from first to last, and the edges between
+-- them.
+WITH RECURSIVE project_documents AS (
+    SELECT n.id FROM nodes n
+    JOIN sources s ON n.properties->>'source_id' = s.id::text
+    WHERE n.node_type = 'document' AND s.project_id = @project_id
+),
+project_edges AS (
+    SELECT e.id, e.source_node, e.target_node FROM edges e
+    WHERE (@include_negated::boolean OR NOT e.is_negated)
+      AND (cardinality(@edge_types::text[]) = 0 OR e.edge_type = ANY(@edge_types::text[]))
+      AND EXISTS (
+          SELECT 1 FROM provenance p
+          WHERE p.target_type = 'edge' AND p.target_id = e.id
+            AND p.source_id IN (SELECT id FROM project_documents)
+            AND p.status = ANY(@statuses::text[])
+      )
+),
+steps AS (
+    SELECT id AS edge_id, source_node AS from_node, target_node AS to_node
+    FROM project_edges WHERE @direction::text IN ('out', 'both')
+    UNION ALL
+    SELECT id, target_node, source_node
+    FROM project_edges WHERE @direction::text IN ('in', 'both')
+),
+walk(node_id, node_path, edge_path) AS (
+    SELECT @from_node::uuid, ARRAY[@from_node::uuid], ARRAY[]::uuid[]
+    UNION ALL
+    SELECT s.to_node, w.node_path || s.to_node, w.edge_path || s.edge_id
+    FROM walk w
+    JOIN steps s ON s.from_node = w.node_id
+    WHERE cardinality(w.edge_path) < @max_depth::int
+      AND w.node_id <> @to_node::uuid
+      AND NOT s.to_node = ANY(w.node_path)
+)
+SELECT node_path::uuid[] AS node_path, edge_path::uuid[] AS edge_path
+FROM walk
+WHERE node_id = @to_node::uuid
+ORDER BY cardinality(edge_path)
+LIMIT @max_paths::int;
@@ -0,0 +1,35 @@
+-- Resumable uploads
+
+-- name: CreateResumableUpload :one
+INSERT INTO resumable_uploads (filename, length, metadata)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: GetResumableUpload :one
+SELECT * FROM resumable_uploads WHERE id = $1;
+
+-- name: AppendResumableUploadPart :one
+-- Records a stored part if the upload is still at the offset the part
+-- starts at. Returns no row if another append got there first.
+UPDATE resumable_uploads
+SET upload_offset = upload_offset + @received::bigint,
+    parts = array_append(parts, @part::text)
+WHERE id = @id AND upload_offset = @upload_offset AND document_id IS NULL
+RETURNING *;
+
+-- name: FinishResumableUpload :one
+UPDATE resumable_uploads
+SET document_id = $2,
+    parts = '{}'
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteResumableUpload :one
+DELETE FROM resumable_uploads WHERE id = $1
+RETURNING *;
+
+-- name: ExpireResumableUploads :many
+-- Removes uploads created before the cutoff, finished or not, returning
+-- them so that their parts can be deleted.
+DELETE FROM resumable_uploads WHERE created_at < $1
+RETURNING *;
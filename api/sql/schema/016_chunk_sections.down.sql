@@ -0,0 +1,3 @@
+-- Remove chunk section columns
+ALTER TABLE chunks DROP COLUMN IF EXISTS section_name;
+ALTER TABLE chunks DROP COLUMN IF EXISTS section_id;
@@ -0,0 +1,4 @@
+-- Section identifiers detected by the chunker (e.g. "§5", "3.1", "HÄNDELSEFÖRLOPP").
+-- Copied into provenance location so claims can be traced to a section.
+ALTER TABLE chunks ADD COLUMN IF NOT EXISTS section_id TEXT;
+ALTER TABLE chunks ADD COLUMN IF NOT EXISTS section_name TEXT;
@@ -0,0 +1,4 @@
+ALTER TABLE sources ADD CONSTRAINT documents_file_type_check
+CHECK (file_type IN ('pdf', 'txt'));
+
+ALTER TABLE chunks DROP COLUMN IF EXISTS metadata;
@@ -0,0 +1,6 @@
+-- Chunk metadata for structured formats (e.g. Markdown heading path).
+ALTER TABLE chunks ADD COLUMN IF NOT EXISTS metadata JSONB;
+
+-- File type is enforced at application level so new formats (md, ...) do not
+-- need a migration each.
+ALTER TABLE sources DROP CONSTRAINT IF EXISTS documents_file_type_check;
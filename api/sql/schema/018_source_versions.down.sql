@@ -0,0 +1,6 @@
+-- Remove source hashing and versions
+DROP INDEX IF EXISTS idx_sources_previous_version;
+DROP INDEX IF EXISTS idx_sources_content_hash;
+ALTER TABLE sources DROP COLUMN IF EXISTS version;
+ALTER TABLE sources DROP COLUMN IF EXISTS previous_version_id;
+ALTER TABLE sources DROP COLUMN IF EXISTS content_hash;
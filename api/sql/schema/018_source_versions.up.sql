@@ -0,0 +1,10 @@
+-- SHA-256 of the uploaded file; an upload matching an existing source
+-- returns that source instead of creating a duplicate.
+ALTER TABLE sources ADD COLUMN IF NOT EXISTS content_hash TEXT;
+CREATE INDEX IF NOT EXISTS idx_sources_content_hash ON sources(content_hash);
+
+-- Versions of a source form a chain: each new version points at the one it
+-- replaces.
+ALTER TABLE sources ADD COLUMN IF NOT EXISTS previous_version_id UUID REFERENCES sources(id) ON DELETE SET NULL;
+ALTER TABLE sources ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+CREATE INDEX IF NOT EXISTS idx_sources_previous_version ON sources(previous_version_id);
@@ -0,0 +1,4 @@
+-- Remove source status notifications and project auto-extraction
+ALTER TABLE projects DROP COLUMN IF EXISTS auto_extract;
+DROP TRIGGER IF EXISTS sources_notify_status ON sources;
+DROP FUNCTION IF EXISTS notify_source_status();
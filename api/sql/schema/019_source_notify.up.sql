@@ -0,0 +1,26 @@
+-- Announce source status changes on the source_status channel, so that
+-- document workers wake up as soon as there is work instead of polling.
+-- The payload is {"id", "status", "project_id"}.
+CREATE OR REPLACE FUNCTION notify_source_status()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'UPDATE' AND OLD.upload_status IS NOT DISTINCT FROM NEW.upload_status THEN
+        RETURN NEW;
+    END IF;
+    PERFORM pg_notify('source_status', json_build_object(
+        'id', NEW.id,
+        'status', NEW.upload_status,
+        'project_id', NEW.project_id
+    )::text);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS sources_notify_status ON sources;
+CREATE TRIGGER sources_notify_status
+    AFTER INSERT OR UPDATE OF upload_status ON sources
+    FOR EACH ROW
+    EXECUTE FUNCTION notify_source_status();
+
+-- Projects can have their documents extracted as soon as they are chunked
+ALTER TABLE projects ADD COLUMN IF NOT EXISTS auto_extract BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,6 @@
+-- Remove the identity cluster cache
+DROP TRIGGER IF EXISTS provenance_identity_clusters ON provenance;
+DROP TRIGGER IF EXISTS edges_identity_clusters ON edges;
+DROP FUNCTION IF EXISTS mark_identity_clusters_stale();
+DROP TABLE IF EXISTS identity_cluster_state;
+DROP TABLE IF EXISTS identity_clusters;
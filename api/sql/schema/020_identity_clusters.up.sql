@@ -0,0 +1,58 @@
+-- Identity clusters: the nodes same_as edges join, transitively, cached per
+-- project. cluster_id is the cluster's lowest node ID, so it does not change
+-- with the view strategy that picks the canonical member.
+CREATE TABLE IF NOT EXISTS identity_clusters (
+    project_id UUID NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+    node_id UUID NOT NULL REFERENCES nodes(id) ON DELETE CASCADE,
+    cluster_id UUID NOT NULL,
+    PRIMARY KEY (project_id, node_id)
+);
+CREATE INDEX IF NOT EXISTS idx_identity_clusters_cluster ON identity_clusters(project_id, cluster_id);
+
+-- When each project's clusters were computed, and whether a same_as edge
+-- or its review has changed since
+CREATE TABLE IF NOT EXISTS identity_cluster_state (
+    project_id UUID PRIMARY KEY REFERENCES projects(id) ON DELETE CASCADE,
+    stale BOOLEAN NOT NULL DEFAULT false,
+    computed_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Mark every project's clusters stale when a same_as edge, or the
+-- provenance of one, is added, changed or removed. Which projects an edge
+-- touches is not known here; same_as edges change rarely enough that
+-- recomputing the others on their next read is cheap.
+CREATE OR REPLACE FUNCTION mark_identity_clusters_stale()
+RETURNS TRIGGER AS $$
+DECLARE
+    changed edges%ROWTYPE;
+    claim provenance%ROWTYPE;
+BEGIN
+    IF TG_TABLE_NAME = 'edges' THEN
+        IF TG_OP = 'DELETE' THEN changed := OLD; ELSE changed := NEW; END IF;
+        IF changed.edge_type <> 'same_as' AND (TG_OP <> 'UPDATE' OR OLD.edge_type <> 'same_as') THEN
+            RETURN NULL;
+        END IF;
+    ELSE
+        IF TG_OP = 'DELETE' THEN claim := OLD; ELSE claim := NEW; END IF;
+        IF claim.target_type <> 'edge' OR NOT EXISTS (
+            SELECT 1 FROM edges WHERE id = claim.target_id AND edge_type = 'same_as'
+        ) THEN
+            RETURN NULL;
+        END IF;
+    END IF;
+    UPDATE identity_cluster_state SET stale = true WHERE NOT stale;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS edges_identity_clusters ON edges;
+CREATE TRIGGER edges_identity_clusters
+    AFTER INSERT OR UPDATE OR DELETE ON edges
+    FOR EACH ROW
+    EXECUTE FUNCTION mark_identity_clusters_stale();
+
+DROP TRIGGER IF EXISTS provenance_identity_clusters ON provenance;
+CREATE TRIGGER provenance_identity_clusters
+    AFTER INSERT OR UPDATE OF status OR DELETE ON provenance
+    FOR EACH ROW
+    EXECUTE FUNCTION mark_identity_clusters_stale();
@@ -0,0 +1,3 @@
+-- Restore the non-unique content hash index
+DROP INDEX IF EXISTS idx_sources_project_content_hash;
+CREATE INDEX IF NOT EXISTS idx_sources_content_hash ON sources(content_hash);
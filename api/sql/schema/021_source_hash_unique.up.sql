@@ -0,0 +1,23 @@
+-- Deduplicate uploads per project: within a project (or among sources in
+-- no project) a file's content may appear only once. The same file
+-- uploaded to two projects is two sources, so projects never share one.
+-- Sources that failed to process do not count, so a failed file can be
+-- uploaded again.
+
+-- Keep the oldest of any existing duplicates; the others lose their hash
+UPDATE sources s
+SET content_hash = NULL
+WHERE s.content_hash IS NOT NULL
+  AND s.upload_status <> 'error'
+  AND EXISTS (
+      SELECT 1 FROM sources o
+      WHERE o.content_hash = s.content_hash
+        AND o.project_id IS NOT DISTINCT FROM s.project_id
+        AND o.upload_status <> 'error'
+        AND (o.created_at, o.id) < (s.created_at, s.id)
+  );
+
+DROP INDEX IF EXISTS idx_sources_content_hash;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_sources_project_content_hash
+    ON sources (project_id, content_hash) NULLS NOT DISTINCT
+    WHERE content_hash IS NOT NULL AND upload_status <> 'error';
@@ -0,0 +1,2 @@
+-- Remove resumable upload state
+DROP TABLE IF EXISTS resumable_uploads;
@@ -0,0 +1,16 @@
+-- Resumable upload state, shared by every API replica. The bytes of each
+-- append are stored as a part in the storage backend under
+-- uploads/partial/<id>/, and the parts are joined when the upload finishes,
+-- so a client can resume against any replica.
+CREATE TABLE IF NOT EXISTS resumable_uploads (
+    id            UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    filename      TEXT NOT NULL,
+    length        BIGINT NOT NULL,
+    upload_offset BIGINT NOT NULL DEFAULT 0,
+    metadata      JSONB NOT NULL DEFAULT '{}',
+    parts         TEXT[] NOT NULL DEFAULT '{}', -- storage keys, in offset order
+    document_id   UUID REFERENCES sources(id) ON DELETE SET NULL, -- set once finished
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_resumable_uploads_created ON resumable_uploads(created_at);
@@ -0,0 +1,25 @@
+-- Restore the notification of 019 and remove source claims
+CREATE OR REPLACE FUNCTION notify_source_status()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'UPDATE' AND OLD.upload_status IS NOT DISTINCT FROM NEW.upload_status THEN
+        RETURN NEW;
+    END IF;
+    PERFORM pg_notify('source_status', json_build_object(
+        'id', NEW.id,
+        'status', NEW.upload_status,
+        'project_id', NEW.project_id
+    )::text);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS sources_notify_status ON sources;
+CREATE TRIGGER sources_notify_status
+    AFTER INSERT OR UPDATE OF upload_status ON sources
+    FOR EACH ROW
+    EXECUTE FUNCTION notify_source_status();
+
+DROP INDEX IF EXISTS idx_sources_extraction_status;
+ALTER TABLE sources DROP COLUMN IF EXISTS extraction_status;
+ALTER TABLE sources DROP COLUMN IF EXISTS claimed_at;
@@ -0,0 +1,42 @@
+-- Document workers record when they claimed a source and refresh it with a
+-- heartbeat while they work, so that a source left 'processing' by a worker
+-- that died is claimed again once its heartbeat goes stale.
+ALTER TABLE sources ADD COLUMN IF NOT EXISTS claimed_at TIMESTAMPTZ;
+
+-- Extraction is queued as a job of its own: 'queued' until a worker claims
+-- it, 'running' while one extracts, NULL otherwise
+ALTER TABLE sources ADD COLUMN IF NOT EXISTS extraction_status TEXT
+    CHECK (extraction_status IN ('queued', 'running'));
+
+-- Sources processing when this runs have no heartbeat; date their claim so
+-- that they are picked up again if nobody is working on them
+UPDATE sources SET claimed_at = updated_at WHERE upload_status = 'processing';
+
+CREATE INDEX IF NOT EXISTS idx_sources_extraction_status ON sources(extraction_status)
+    WHERE extraction_status IS NOT NULL;
+
+-- Announce queued extractions on source_status too. The payload is
+-- {"id", "status", "extraction_status", "project_id"}.
+CREATE OR REPLACE FUNCTION notify_source_status()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'UPDATE'
+        AND OLD.upload_status IS NOT DISTINCT FROM NEW.upload_status
+        AND OLD.extraction_status IS NOT DISTINCT FROM NEW.extraction_status THEN
+        RETURN NEW;
+    END IF;
+    PERFORM pg_notify('source_status', json_build_object(
+        'id', NEW.id,
+        'status', NEW.upload_status,
+        'extraction_status', NEW.extraction_status,
+        'project_id', NEW.project_id
+    )::text);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS sources_notify_status ON sources;
+CREATE TRIGGER sources_notify_status
+    AFTER INSERT OR UPDATE OF upload_status, extraction_status ON sources
+    FOR EACH ROW
+    EXECUTE FUNCTION notify_source_status();
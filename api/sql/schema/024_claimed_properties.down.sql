@@ -0,0 +1 @@
+ALTER TABLE provenance DROP COLUMN IF EXISTS claimed_properties;
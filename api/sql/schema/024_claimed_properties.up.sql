@@ -0,0 +1,34 @@
+-- Each provenance record keeps the property values its source asserted
+-- about the target. The target's properties column holds one merged value
+-- per key, so without this there is nothing per source to vote on once
+-- identity merges put claims from several documents on one node.
+ALTER TABLE provenance ADD COLUMN IF NOT EXISTS claimed_properties JSONB NOT NULL DEFAULT '{}';
+
+-- Where a node or edge has a single record, that record asserted all of
+-- its properties. Targets with several records cannot be split after the
+-- fact and keep empty claims. The backfill is not an edit, so it keeps
+-- updated_at; document nodes' own records claim nothing.
+ALTER TABLE provenance DISABLE TRIGGER provenance_updated_at;
+
+UPDATE provenance p
+SET claimed_properties = n.properties
+FROM nodes n
+WHERE p.target_type = 'node' AND p.target_id = n.id
+  AND p.source_id <> p.target_id
+  AND n.properties IS NOT NULL
+  AND NOT EXISTS (
+      SELECT 1 FROM provenance q
+      WHERE q.target_type = p.target_type AND q.target_id = p.target_id AND q.id <> p.id
+  );
+
+UPDATE provenance p
+SET claimed_properties = e.properties
+FROM edges e
+WHERE p.target_type = 'edge' AND p.target_id = e.id
+  AND e.properties IS NOT NULL
+  AND NOT EXISTS (
+      SELECT 1 FROM provenance q
+      WHERE q.target_type = p.target_type AND q.target_id = p.target_id AND q.id <> p.id
+  );
+
+ALTER TABLE provenance ENABLE TRIGGER provenance_updated_at;
@@ -0,0 +1,26 @@
+-- Restore 020's trigger function, which marks every project stale
+CREATE OR REPLACE FUNCTION mark_identity_clusters_stale()
+RETURNS TRIGGER AS $$
+DECLARE
+    changed edges%ROWTYPE;
+    claim provenance%ROWTYPE;
+BEGIN
+    IF TG_TABLE_NAME = 'edges' THEN
+        IF TG_OP = 'DELETE' THEN changed := OLD; ELSE changed := NEW; END IF;
+        IF changed.edge_type <> 'same_as' AND (TG_OP <> 'UPDATE' OR OLD.edge_type <> 'same_as') THEN
+            RETURN NULL;
+        END IF;
+    ELSE
+        IF TG_OP = 'DELETE' THEN claim := OLD; ELSE claim := NEW; END IF;
+        IF claim.target_type <> 'edge' OR NOT EXISTS (
+            SELECT 1 FROM edges WHERE id = claim.target_id AND edge_type = 'same_as'
+        ) THEN
+            RETURN NULL;
+        END IF;
+    END IF;
+    UPDATE identity_cluster_state SET stale = true WHERE NOT stale;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP INDEX IF EXISTS idx_identity_clusters_node;
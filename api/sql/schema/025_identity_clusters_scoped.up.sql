@@ -0,0 +1,53 @@
+-- Mark only the projects a same_as change can affect as stale, rather than
+-- every project. A project's same_as edges are those between two nodes its
+-- documents assert, so the affected projects are those asserting an
+-- endpoint, plus those whose cached clusters hold one: once a node is
+-- deleted its provenance may be gone before its edges are.
+CREATE INDEX IF NOT EXISTS idx_identity_clusters_node ON identity_clusters(node_id);
+
+CREATE OR REPLACE FUNCTION mark_identity_clusters_stale()
+RETURNS TRIGGER AS $$
+DECLARE
+    endpoints UUID[];
+    claim provenance%ROWTYPE;
+BEGIN
+    IF TG_TABLE_NAME = 'edges' THEN
+        IF TG_OP <> 'DELETE' THEN
+            IF NEW.edge_type = 'same_as' THEN
+                endpoints := ARRAY[NEW.source_node, NEW.target_node];
+            END IF;
+        END IF;
+        IF TG_OP <> 'INSERT' THEN
+            IF OLD.edge_type = 'same_as' THEN
+                endpoints := COALESCE(endpoints, '{}') || ARRAY[OLD.source_node, OLD.target_node];
+            END IF;
+        END IF;
+    ELSE
+        IF TG_OP = 'DELETE' THEN claim := OLD; ELSE claim := NEW; END IF;
+        IF claim.target_type = 'edge' THEN
+            SELECT ARRAY[e.source_node, e.target_node] INTO endpoints
+            FROM edges e WHERE e.id = claim.target_id AND e.edge_type = 'same_as';
+        ELSIF TG_OP <> 'UPDATE' AND EXISTS (
+            SELECT 1 FROM edges e
+            WHERE e.edge_type = 'same_as' AND claim.target_id IN (e.source_node, e.target_node)
+        ) THEN
+            -- A node entering or leaving a project brings its same_as edges
+            endpoints := ARRAY[claim.target_id];
+        END IF;
+    END IF;
+    IF endpoints IS NULL THEN
+        RETURN NULL;
+    END IF;
+
+    UPDATE identity_cluster_state st SET stale = true
+    WHERE NOT st.stale AND st.project_id IN (
+        SELECT s.project_id FROM provenance p
+        JOIN nodes d ON d.id = p.source_id AND d.node_type = 'document'
+        JOIN sources s ON d.properties->>'source_id' = s.id::text
+        WHERE p.target_type = 'node' AND p.target_id = ANY(endpoints)
+        UNION
+        SELECT c.project_id FROM identity_clusters c WHERE c.node_id = ANY(endpoints)
+    );
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
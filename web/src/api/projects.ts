@@ -6,6 +6,7 @@ export interface Project {
   description: string;
   created_at: string;
   updated_at: string;
+  auto_extract: boolean;
   stats?: {
     doc_count: number;
     node_count: number;
@@ -66,11 +67,11 @@ export async function getProject(id: string): Promise<Project> {
   return res.json();
 }
 
-export async function createProject(title: string, description: string): Promise<Project> {
+export async function createProject(title: string, description: string, autoExtract = false): Promise<Project> {
   const res = await fetch(`${API_BASE}/api/projects`, {
     method: 'POST',
     headers: { 'Content-Type': 'application/json' },
-    body: JSON.stringify({ title, description }),
+    body: JSON.stringify({ title, description, auto_extract: autoExtract }),
   });
   if (!res.ok) throw new Error('Failed to create project');
   return res.json();
@@ -91,6 +92,33 @@ export async function addDocumentToProject(projectId: string, documentId: string
   if (!res.ok) throw new Error('Failed to add document to project');
 }
 
+export interface ArchiveFileResult {
+  filename: string;
+  status: 'created' | 'duplicate' | 'error' | 'missing';
+  document_id?: string;
+  title?: string;
+  error?: string;
+}
+
+export interface ArchiveUploadResult {
+  project_id: string;
+  created: number;
+  duplicates: number;
+  errors: number;
+  files: ArchiveFileResult[];
+}
+
+export async function uploadProjectArchive(projectId: string, archive: File): Promise<ArchiveUploadResult> {
+  const formData = new FormData();
+  formData.append('file', archive);
+  const res = await fetch(`${API_BASE}/api/projects/${projectId}/documents/archive`, {
+    method: 'POST',
+    body: formData,
+  });
+  if (!res.ok) throw new Error((await res.text()) || 'Failed to upload archive');
+  return res.json();
+}
+
 export async function getProjectGraph(projectId: string): Promise<Graph> {
   const res = await fetch(`${API_BASE}/api/projects/${projectId}/graph`);
   if (!res.ok) throw new Error('Failed to fetch project graph');
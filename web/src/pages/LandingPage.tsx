@@ -184,12 +184,12 @@ export default function LandingPage({ onNavigateToProject }: Props) {
   }, [onNavigateToProject]);
 
   const processFile = useCallback(async (file: File, projectId: string) => {
-    if (!file.name.match(/\.(txt|pdf|md)$/i)) {
-      setPhase({ name: 'error', message: 'Only .txt, .pdf, and .md files are supported.' });
+    if (!file.name.match(/\.(txt|pdf|md|docx|eml|mbox|html?|warc|warc\.gz|csv|xlsx)$/i)) {
+      setPhase({ name: 'error', message: 'Only .txt, .pdf, .md, .docx, .eml, .mbox, .html, .warc, .csv, and .xlsx files are supported.' });
       return;
     }
-    if (file.size > 50 * 1024 * 1024) {
-      setPhase({ name: 'error', message: 'File must be under 50 MB.' });
+    if (file.size > 1024 * 1024 * 1024) {
+      setPhase({ name: 'error', message: 'File must be under 1 GB.' });
       return;
     }
 
@@ -207,6 +207,15 @@ export default function LandingPage({ onNavigateToProject }: Props) {
       const doc = await uploadRes.json() as { id: string };
       const docId = doc.id;
 
+      // 200 instead of 201: the same file was uploaded before, so reuse it
+      // rather than extracting it again
+      if (uploadRes.status === 200) {
+        await addDocumentToProject(projectId, docId);
+        setPhase({ name: 'idle' });
+        onNavigateToProject(projectId);
+        return;
+      }
+
       setPhase({ name: 'chunking', docId });
 
       // Poll until chunking complete
@@ -245,7 +254,7 @@ export default function LandingPage({ onNavigateToProject }: Props) {
     } catch (err) {
       setPhase({ name: 'error', message: err instanceof Error ? err.message : 'Upload failed' });
     }
-  }, [streamExtractionProgress]);
+  }, [streamExtractionProgress, onNavigateToProject]);
 
   async function handleAddExistingDoc(docId: string, projectId: string) {
     try {
@@ -573,7 +582,7 @@ export default function LandingPage({ onNavigateToProject }: Props) {
                 <input
                   ref={fileInputRef}
                   type="file"
-                  accept=".txt,.pdf,.md"
+                  accept=".txt,.pdf,.md,.docx,.eml,.mbox,.html,.htm,.warc,.gz,.csv,.xlsx"
                   style={{ display: 'none' }}
                   onChange={(e) => {
                     const file = e.target.files?.[0];
@@ -585,7 +594,7 @@ export default function LandingPage({ onNavigateToProject }: Props) {
                 />
                 <div style={{ fontSize: 24, marginBottom: 8 }}>📄</div>
                 <p style={{ color: tokens.textPrimary, fontSize: 14, fontWeight: 500 }}>Click or drag to upload</p>
-                <p style={{ fontSize: 12, color: tokens.textTertiary }}>.txt, .pdf, or .md up to 50 MB</p>
+                <p style={{ fontSize: 12, color: tokens.textTertiary }}>.txt, .pdf, .md, .docx, .eml, .mbox, .html, .warc, .csv, or .xlsx up to 1 GB</p>
               </div>
 
               {/* Existing documents */}
@@ -249,7 +249,7 @@ export default function ProjectView({ projectId, onNavigateBack }: Props) {
                   </label>
                   <input
                     type="file"
-                    accept=".txt,.pdf"
+                    accept=".txt,.pdf,.md,.docx,.eml,.mbox,.html,.htm,.warc,.gz,.csv,.xlsx"
                     onChange={handleUploadAndAdd}
                     disabled={uploading}
                     className="block w-full text-sm text-slate-500 file:mr-4 file:py-2 file:px-4 file:rounded-lg file:border-0 file:text-sm file:font-medium file:bg-blue-50 file:text-blue-700 hover:file:bg-blue-100"